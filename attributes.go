@@ -0,0 +1,239 @@
+package ldap_redhat
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LDAP attribute names read by the library. Use these rather than string
+// literals in RequestScope.Attributes, RawEntry lookups and filters; a
+// misspelt attribute is not an error to the server, it just comes back
+// empty. CheckAttributes validates names taken from configuration.
+const (
+	AttrUID                  = "uid"
+	AttrMail                 = "mail"
+	AttrCN                   = "cn"
+	AttrSN                   = "sn"
+	AttrTitle                = "title"
+	AttrManager              = "manager"
+	AttrCo                   = "co"
+	AttrOU                   = "ou"
+	AttrEmployeeNumber       = "employeeNumber"
+	AttrKrbPrincipalName     = "krbPrincipalName"
+	AttrMobile               = "mobile"
+	AttrTelephoneNumber      = "telephoneNumber"
+	AttrMailAlternateAddress = "mailAlternateAddress"
+	AttrJpegPhoto            = "jpegPhoto"
+	AttrThumbnailPhoto       = "thumbnailPhoto"
+	AttrModifyTimestamp      = "modifyTimestamp"
+
+	AttrRhatCostCenter     = "rhatCostCenter"
+	AttrRhatCostCenterDesc = "rhatCostCenterDesc"
+	AttrRhatLocation       = "rhatLocation"
+	AttrRhatJobCode        = "rhatJobCode"
+	AttrRhatUUID           = "rhatUUID"
+	AttrRhatHireDate       = "rhatHireDate"
+	AttrRhatTermDate       = "rhatTermDate"
+	AttrRhatAdjSvcDate     = "rhatAdjSvcDate"
+	AttrRhatGeo            = "rhatGeo"
+	AttrRhatOrgCharTitle   = "rhatOrgCharTitle"
+	AttrRhatPersonType     = "rhatPersonType"
+	AttrRhatBuilding       = "rhatBuilding"
+	AttrRhatPrimaryMail    = "rhatPrimaryMail"
+
+	AttrDescription  = "description"
+	AttrUniqueMember = "uniqueMember"
+	AttrMember       = "member"
+	AttrMemberUID    = "memberUid"
+)
+
+// AttributeType is how the values of an attribute are encoded
+type AttributeType string
+
+const (
+	AttributeString AttributeType = "string"
+	AttributeTime   AttributeType = "time"   // GeneralizedTime, parsed with ParseLDAPTime
+	AttributeDN     AttributeType = "dn"     // a distinguished name
+	AttributeBinary AttributeType = "binary" // raw bytes, e.g. a JPEG photo
+)
+
+// AttributeSchema describes an LDAP attribute the library reads.
+type AttributeSchema struct {
+	Name        string        `json:"name"`
+	Type        AttributeType `json:"type"`
+	MultiValued bool          `json:"multi_valued,omitempty"`
+	// Field is the UserRecord field holding the attribute's raw value, empty
+	// for attributes read elsewhere (groups, photos, email mapping). Time
+	// attributes are also parsed into a time.Time field, e.g. rhatHireDate
+	// into HireDate.
+	Field string `json:"field,omitempty"`
+
+	value  func(u *UserRecord) *string    // the Field of u
+	parsed func(u *UserRecord) *time.Time // the parsed field of u, for time attributes
+}
+
+// clearFrom clears the fields of u populated from the attribute
+func (a AttributeSchema) clearFrom(u *UserRecord) {
+	switch a.Name {
+	case AttrManager:
+		u.ManagerUID = ""
+	case AttrRhatTermDate:
+		clearTermDate(u)
+		return
+	}
+	*a.value(u) = ""
+	if a.parsed != nil {
+		*a.parsed(u) = time.Time{}
+	}
+}
+
+// attributeSchemas lists every attribute the library reads, those mapped to
+// UserRecord fields first
+var attributeSchemas = []AttributeSchema{
+	{Name: AttrUID, Type: AttributeString, Field: "UID", value: func(u *UserRecord) *string { return &u.UID }},
+	{Name: AttrMail, Type: AttributeString, Field: "Email", value: func(u *UserRecord) *string { return &u.Email }},
+	{Name: AttrCN, Type: AttributeString, Field: "DisplayName", value: func(u *UserRecord) *string { return &u.DisplayName }},
+	{Name: AttrSN, Type: AttributeString, Field: "Surname", value: func(u *UserRecord) *string { return &u.Surname }},
+	{Name: AttrTitle, Type: AttributeString, Field: "Title", value: func(u *UserRecord) *string { return &u.Title }},
+	{Name: AttrManager, Type: AttributeDN, Field: "ManagerDN", value: func(u *UserRecord) *string { return &u.ManagerDN }},
+	{Name: AttrRhatCostCenter, Type: AttributeString, Field: "CostCenter", value: func(u *UserRecord) *string { return &u.CostCenter }},
+	{Name: AttrRhatCostCenterDesc, Type: AttributeString, Field: "CostCenterDesc", value: func(u *UserRecord) *string { return &u.CostCenterDesc }},
+	{Name: AttrRhatLocation, Type: AttributeString, Field: "RhatLocation", value: func(u *UserRecord) *string { return &u.RhatLocation }},
+	{Name: AttrRhatJobCode, Type: AttributeString, Field: "RhatJobCode", value: func(u *UserRecord) *string { return &u.RhatJobCode }},
+	{Name: AttrRhatUUID, Type: AttributeString, Field: "RhatUUID", value: func(u *UserRecord) *string { return &u.RhatUUID }},
+	{
+		Name: AttrRhatHireDate, Type: AttributeTime, Field: "RhatHireDate",
+		value:  func(u *UserRecord) *string { return &u.RhatHireDate },
+		parsed: func(u *UserRecord) *time.Time { return &u.HireDate },
+	},
+	{
+		Name: AttrRhatTermDate, Type: AttributeTime, Field: "RhatTermDate",
+		value:  func(u *UserRecord) *string { return &u.RhatTermDate },
+		parsed: func(u *UserRecord) *time.Time { return &u.TermDate },
+	},
+	{
+		Name: AttrRhatAdjSvcDate, Type: AttributeTime, Field: "RhatAdjSvcDate",
+		value:  func(u *UserRecord) *string { return &u.RhatAdjSvcDate },
+		parsed: func(u *UserRecord) *time.Time { return &u.AdjServiceDate },
+	},
+	{Name: AttrCo, Type: AttributeString, Field: "Country", value: func(u *UserRecord) *string { return &u.Country }},
+	{Name: AttrOU, Type: AttributeString, Field: "Department", value: func(u *UserRecord) *string { return &u.Department }},
+	{Name: AttrEmployeeNumber, Type: AttributeString, Field: "EmployeeNumber", value: func(u *UserRecord) *string { return &u.EmployeeNumber }},
+	{Name: AttrKrbPrincipalName, Type: AttributeString, Field: "KerberosPrincipal", value: func(u *UserRecord) *string { return &u.KerberosPrincipal }},
+	{Name: AttrRhatGeo, Type: AttributeString, Field: "Geo", value: func(u *UserRecord) *string { return &u.Geo }},
+	{Name: AttrRhatOrgCharTitle, Type: AttributeString, Field: "OrgChartTitle", value: func(u *UserRecord) *string { return &u.OrgChartTitle }},
+	{Name: AttrRhatPersonType, Type: AttributeString, Field: "PersonType", value: func(u *UserRecord) *string { return &u.PersonType }},
+	{Name: AttrRhatBuilding, Type: AttributeString, Field: "Building", value: func(u *UserRecord) *string { return &u.Building }},
+	{Name: AttrMobile, Type: AttributeString, Field: "Mobile", value: func(u *UserRecord) *string { return &u.Mobile }},
+	{Name: AttrTelephoneNumber, Type: AttributeString, Field: "TelephoneNumber", value: func(u *UserRecord) *string { return &u.TelephoneNumber }},
+
+	{Name: AttrRhatPrimaryMail, Type: AttributeString},
+	{Name: AttrMailAlternateAddress, Type: AttributeString, MultiValued: true},
+	{Name: AttrJpegPhoto, Type: AttributeBinary},
+	{Name: AttrThumbnailPhoto, Type: AttributeBinary},
+	{Name: AttrModifyTimestamp, Type: AttributeTime},
+	{Name: AttrDescription, Type: AttributeString},
+	{Name: AttrUniqueMember, Type: AttributeDN, MultiValued: true},
+	{Name: AttrMember, Type: AttributeDN, MultiValued: true},
+	{Name: AttrMemberUID, Type: AttributeString, MultiValued: true},
+}
+
+// userAttributes is the canonical list of LDAP attributes fetched for user
+// lookups: those populating a UserRecord field.
+var userAttributes = func() []string {
+	var names []string
+	for _, a := range attributeSchemas {
+		if a.value != nil {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}()
+
+// withAttributes returns attrs plus those of names it lacks, without
+// modifying attrs
+func withAttributes(attrs []string, names ...string) []string {
+	out := slices.Clip(attrs)
+	for _, name := range names {
+		if !slices.ContainsFunc(out, func(a string) bool { return strings.EqualFold(a, name) }) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// attributesByName indexes attributeSchemas by lowercased name, as LDAP
+// attribute names are case-insensitive
+var attributesByName = func() map[string]AttributeSchema {
+	m := make(map[string]AttributeSchema, len(attributeSchemas))
+	for _, a := range attributeSchemas {
+		m[strings.ToLower(a.Name)] = a
+	}
+	return m
+}()
+
+// LookupAttribute returns the schema of the attribute name, matched
+// case-insensitively, and whether the library knows it.
+func LookupAttribute(name string) (AttributeSchema, bool) {
+	a, ok := attributesByName[strings.ToLower(name)]
+	return a, ok
+}
+
+// AttributeSchemas returns the schema of every attribute the library reads,
+// sorted by name.
+func AttributeSchemas() []AttributeSchema {
+	out := append([]AttributeSchema(nil), attributeSchemas...)
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out
+}
+
+// CheckAttributes returns an ErrInvalidConfig error naming the first of
+// names the library does not know, with the closest known name when one is
+// near, e.g. for attribute lists read from configuration files.
+func CheckAttributes(names []string) error {
+	for _, name := range names {
+		if _, ok := LookupAttribute(name); ok {
+			continue
+		}
+		if suggestion := closestAttribute(name); suggestion != "" {
+			return newError(ErrInvalidConfig, "unknown LDAP attribute %q (did you mean %q?)", name, suggestion)
+		}
+		return newError(ErrInvalidConfig, "unknown LDAP attribute %q", name)
+	}
+	return nil
+}
+
+// closestAttribute returns the known attribute nearest to name by edit
+// distance, or "" if none is within a few typos
+func closestAttribute(name string) string {
+	best, bestDist := "", 3
+	for _, a := range attributeSchemas {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(a.Name)); d < bestDist {
+			best, bestDist = a.Name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
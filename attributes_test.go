@@ -0,0 +1,64 @@
+package ldap_redhat_test
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestLookupAttribute(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		typ   ldap_redhat.AttributeType
+		field string
+	}{
+		{ldap_redhat.AttrUID, ldap_redhat.AttributeString, "UID"},
+		{"RHATHIREDATE", ldap_redhat.AttributeTime, "RhatHireDate"},
+		{ldap_redhat.AttrManager, ldap_redhat.AttributeDN, "ManagerDN"},
+		{ldap_redhat.AttrJpegPhoto, ldap_redhat.AttributeBinary, ""},
+	} {
+		a, ok := ldap_redhat.LookupAttribute(tt.name)
+		if !ok || a.Type != tt.typ || a.Field != tt.field {
+			t.Errorf("LookupAttribute(%q) = %+v, %v, want type %s and field %q", tt.name, a, ok, tt.typ, tt.field)
+		}
+	}
+	if a, ok := ldap_redhat.LookupAttribute(ldap_redhat.AttrMember); !ok || !a.MultiValued {
+		t.Errorf("member is not multi-valued: %+v", a)
+	}
+	if _, ok := ldap_redhat.LookupAttribute("rhatCostCentre"); ok {
+		t.Errorf("LookupAttribute found an unknown attribute")
+	}
+}
+
+func TestAttributeSchemas(t *testing.T) {
+	schemas := ldap_redhat.AttributeSchemas()
+	if !sort.SliceIsSorted(schemas, func(i, j int) bool {
+		return strings.ToLower(schemas[i].Name) < strings.ToLower(schemas[j].Name)
+	}) {
+		t.Errorf("AttributeSchemas is not sorted by name")
+	}
+	seen := map[string]bool{}
+	for _, a := range schemas {
+		if seen[strings.ToLower(a.Name)] {
+			t.Errorf("Attribute %s is registered twice", a.Name)
+		}
+		seen[strings.ToLower(a.Name)] = true
+	}
+}
+
+func TestCheckAttributes(t *testing.T) {
+	if err := ldap_redhat.CheckAttributes([]string{"cn", "TITLE", ldap_redhat.AttrRhatCostCenter}); err != nil {
+		t.Errorf("CheckAttributes rejected known attributes: %v", err)
+	}
+	err := ldap_redhat.CheckAttributes([]string{"cn", "rhatCostCentre"})
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) || !strings.Contains(err.Error(), `did you mean "rhatCostCenter"`) {
+		t.Errorf("CheckAttributes returned %v, want ErrInvalidConfig suggesting rhatCostCenter", err)
+	}
+	err = ldap_redhat.CheckAttributes([]string{"favouriteColour"})
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("CheckAttributes returned %v, want ErrInvalidConfig without a suggestion", err)
+	}
+}
@@ -0,0 +1,366 @@
+package ldap_redhat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditStatus is the outcome of an audited lookup.
+type AuditStatus string
+
+// Audit statuses
+const (
+	AuditFound    AuditStatus = "found"     // the lookup returned at least one user
+	AuditNotFound AuditStatus = "not_found" // the lookup matched no user
+	AuditFailed   AuditStatus = "error"     // the lookup failed
+)
+
+// Audit operations, the Searcher methods an AuditEvent records. Lookups made
+// through other methods are recorded as the one they use: SearchUsers,
+// Users, TakeSnapshot, GetUsersModifiedSince, GetUsersByCostCenter and
+// GetUsersByLocation as ForEachUser, ManagerChain and
+// GetUserIncludingDeleted as GetUser. CachedSearcher records its cache hits
+// as the GetUser and GetUsers lookups they answer.
+const (
+	AuditGetUser           = "GetUser"
+	AuditGetUsers          = "GetUsers"
+	AuditForEachUser       = "ForEachUser"
+	AuditGetRawEntry       = "GetRawEntry"
+	AuditGetUserPhoto      = "GetUserPhoto"
+	AuditSearchUsersPage   = "SearchUsersPage"
+	AuditListReportsFlat   = "ListReportsFlat"
+	AuditFindDirectReports = "FindDirectReports"
+	AuditIsPeopleManager   = "IsPeopleManager"
+	AuditMapEmailsToUIDs   = "MapEmailsToUIDs"
+	AuditMapUIDsToEmails   = "MapUIDsToEmails"
+	AuditGetUserGroups     = "GetUserGroups"
+	AuditGetGroupMembers   = "GetGroupMembers"
+	AuditSync              = "Sync"
+	AuditAuthenticate      = "Authenticate"
+)
+
+// AuditEvent records who looked up which users, for compliance reviews of
+// tools reading employee data.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Principal is the caller the lookup was made for, attached to the
+	// context with WithPrincipal. It is empty when none was attached.
+	Principal string `json:"principal,omitempty"`
+	Operation string `json:"operation"`
+	// IdentifierType and Identifier are the identifier looked up by
+	// GetUser, GetRawEntry, GetUserPhoto or Authenticate, or by one item of
+	// GetUsers, MapEmailsToUIDs or MapUIDsToEmails, which record an event
+	// per item. IdentifierType is the Config.FilterTemplates name of the
+	// type, e.g. "uid" or "email". ListReportsFlat, FindDirectReports and
+	// IsPeopleManager record the manager's UID, GetUserGroups the member's
+	// UID, and GetGroupMembers the group's name, with type "group".
+	IdentifierType string `json:"identifier_type,omitempty"`
+	Identifier     string `json:"identifier,omitempty"`
+	// Filter is the LDAP filter of a ForEachUser, SearchUsersPage or Sync
+	// listing.
+	Filter string      `json:"filter,omitempty"`
+	Status AuditStatus `json:"status"`
+	// Results is the number of users returned, or of events handled by
+	// Sync, of groups found by GetUserGroups, or of members returned by
+	// GetGroupMembers. GetUserPhoto records not_found and no results for
+	// users without a photo, IsPeopleManager records found and one result
+	// for managers, and Authenticate records a failed lookup for rejected
+	// credentials.
+	Results int `json:"results"`
+	// Cached is set when CachedSearcher answered the lookup from its cache,
+	// without searching the directory.
+	Cached bool `json:"cached,omitempty"`
+	// Error is the kind of error a failed lookup returned, such as "LDAP
+	// operation timed out". The full message is left out because it may
+	// quote the identifier.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receives an AuditEvent for every lookup of a searcher with
+// Config.AuditSink set. Audit is called synchronously by the lookup, after
+// it finished, so it must not block; sinks handle their own delivery
+// failures.
+type AuditSink interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// AuditFunc adapts a function to an AuditSink.
+type AuditFunc func(ctx context.Context, event AuditEvent)
+
+// Audit calls f.
+func (f AuditFunc) Audit(ctx context.Context, event AuditEvent) {
+	f(ctx, event)
+}
+
+// AuditRedaction masks the identifiers and filters of audit events, so that
+// an audit trail can be kept where the identifiers themselves may not be.
+// Fields are keyed by identifier type ("uid", "email", "uuid",
+// "employee_number", "kerberos"), "group" for group names, "filter" for
+// ForEachUser filters, and "*" for any of these without an entry of their
+// own. Values without a mask are
+// recorded as they are.
+//
+//	ldap_redhat.AuditRedaction{
+//		Salt: auditSalt,
+//		Fields: map[string]ldap_redhat.FieldMask{
+//			"*":   {Action: ldap_redhat.MaskHash},
+//			"uid": {Action: ldap_redhat.MaskTruncate, Length: 2},
+//		},
+//	}
+type AuditRedaction struct {
+	Fields map[string]FieldMask `yaml:"fields" json:"fields"`
+	// Salt keys MaskHash, which requires one, as in MaskingPolicy. Without
+	// a salt, hashed identifiers could be recovered by hashing guesses.
+	Salt string `yaml:"salt" json:"-"`
+}
+
+// auditRedactionFilter, auditRedactionGroup and auditRedactionDefault are
+// the AuditRedaction keys of filters, of group names, and of values without
+// a mask of their own
+const (
+	auditRedactionFilter  = "filter"
+	auditRedactionGroup   = "group"
+	auditRedactionDefault = "*"
+)
+
+// Validate rejects unknown fields and actions, non-positive truncation
+// lengths, and hashing without a salt.
+func (r AuditRedaction) Validate() error {
+	for field, mask := range r.Fields {
+		if !auditRedactionField(field) {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if err := mask.validate(field, r.Salt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditRedactionField reports whether field is an AuditRedaction key
+func auditRedactionField(field string) bool {
+	if field == auditRedactionFilter || field == auditRedactionGroup || field == auditRedactionDefault {
+		return true
+	}
+	for _, kind := range identifierKinds {
+		if kind.name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// redact masks value as the mask of field, or the default mask, says
+func (r AuditRedaction) redact(field, value string) string {
+	mask, ok := r.Fields[field]
+	if !ok {
+		mask, ok = r.Fields[auditRedactionDefault]
+	}
+	if !ok || value == "" {
+		return value
+	}
+	if mask.Action == MaskDrop {
+		return ""
+	}
+	return mask.apply(value, r.Salt)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context whose lookups are audited as made for
+// principal, e.g. the user or service calling a bridge that searches on
+// their behalf.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal attached with WithPrincipal.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok
+}
+
+// JSONAuditSink writes audit events as JSON Lines. It is safe for
+// concurrent use.
+type JSONAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONAuditSink returns a sink writing one JSON object per event to w.
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{enc: json.NewEncoder(w)}
+}
+
+// Audit writes event. After a write fails, events are dropped and Err
+// reports the failure.
+func (j *JSONAuditSink) Audit(_ context.Context, event AuditEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err == nil {
+		j.err = j.enc.Encode(event)
+	}
+}
+
+// Err returns the error of the first write that failed, if any.
+func (j *JSONAuditSink) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// audit sends event to the configured sink, if any, after filling in the
+// time and principal and redacting it
+func (s *Searcher) audit(ctx context.Context, event AuditEvent) {
+	config := s.config()
+	if config.AuditSink == nil {
+		return
+	}
+	event.Time = time.Now().UTC()
+	event.Principal, _ = PrincipalFromContext(ctx)
+	event.Identifier = config.AuditRedaction.redact(event.IdentifierType, event.Identifier)
+	event.Filter = config.AuditRedaction.redact(auditRedactionFilter, event.Filter)
+	config.AuditSink.Audit(ctx, event)
+}
+
+// auditLookup audits the op lookup of id
+func (s *Searcher) auditLookup(ctx context.Context, op string, id Identifier, err error) {
+	event := AuditEvent{Operation: op, IdentifierType: auditIdentifierType(id.Type), Identifier: id.Value}
+	event.Status, event.Error = auditOutcome(err)
+	if event.Status == AuditFound {
+		event.Results = 1
+	}
+	s.audit(ctx, event)
+}
+
+// auditCached audits the op lookup of id, answered from CachedSearcher's
+// cache
+func (s *Searcher) auditCached(ctx context.Context, op string, id Identifier) {
+	s.audit(ctx, AuditEvent{
+		Operation: op, IdentifierType: auditIdentifierType(id.Type), Identifier: id.Value,
+		Status: AuditFound, Results: 1, Cached: true,
+	})
+}
+
+// auditBatch audits each item of the GetUsers lookup of ids, which returned
+// out and err
+func (s *Searcher) auditBatch(ctx context.Context, ids []Identifier, out []UserRecord, err error) {
+	if s.config().AuditSink == nil {
+		return
+	}
+	var batch *BatchError
+	failed := map[int]error{}
+	if errors.As(err, &batch) {
+		for _, item := range batch.Errors {
+			failed[item.Index] = item.Err
+		}
+	}
+	for i, id := range ids {
+		event := AuditEvent{Operation: AuditGetUsers, IdentifierType: auditIdentifierType(id.Type), Identifier: id.Value}
+		switch {
+		case len(out) != len(ids): // the whole batch failed
+			event.Status, event.Error = auditOutcome(err)
+		case failed[i] != nil:
+			event.Status, event.Error = auditOutcome(failed[i])
+		case out[i].UID != "":
+			event.Status, event.Results = AuditFound, 1
+		default:
+			event.Status = AuditNotFound
+		}
+		s.audit(ctx, event)
+	}
+}
+
+// auditListing audits the op listing of filter, which returned results
+// users and err
+func (s *Searcher) auditListing(ctx context.Context, op, filter string, results int, err error) {
+	event := AuditEvent{Operation: op, Filter: filter, Results: results}
+	if asPartial(err) != nil || errors.Is(err, errStopIteration) {
+		err = nil
+	}
+	event.Status, event.Error = auditOutcome(err)
+	if event.Status == AuditFound && results == 0 {
+		event.Status = AuditNotFound
+	}
+	s.audit(ctx, event)
+}
+
+// auditReports audits the op listing of managerUID's reports, which
+// returned results reports and err
+func (s *Searcher) auditReports(ctx context.Context, op, managerUID string, results int, err error) {
+	s.auditResults(ctx, op, auditIdentifierType(IDTUID), managerUID, results, err)
+}
+
+// auditResults audits the op lookup of identifier, of type idType, which
+// returned results items and err. Like listings, lookups returning partial
+// results count as found.
+func (s *Searcher) auditResults(ctx context.Context, op, idType, identifier string, results int, err error) {
+	event := AuditEvent{Operation: op, IdentifierType: idType, Identifier: identifier, Results: results}
+	if asPartial(err) != nil {
+		err = nil
+	}
+	event.Status, event.Error = auditOutcome(err)
+	if event.Status == AuditFound && results == 0 {
+		event.Status = AuditNotFound
+	}
+	s.audit(ctx, event)
+}
+
+// auditMapping audits each non-blank value of the op mapping of values, of
+// type idType, which found the values in found, as given, unless it failed
+// with err
+func (s *Searcher) auditMapping(ctx context.Context, op string, idType int, values []string, found func(string) bool, err error) {
+	if s.config().AuditSink == nil {
+		return
+	}
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		event := AuditEvent{Operation: op, IdentifierType: auditIdentifierType(idType), Identifier: value}
+		switch {
+		case err != nil:
+			event.Status, event.Error = auditOutcome(err)
+		case found(value):
+			event.Status, event.Results = AuditFound, 1
+		default:
+			event.Status = AuditNotFound
+		}
+		s.audit(ctx, event)
+	}
+}
+
+// auditIdentifierType returns the FilterTemplates name of an identifier
+// type, or its number for unknown types
+func auditIdentifierType(idType int) string {
+	if kind, ok := identifierKinds[idType]; ok {
+		return kind.name
+	}
+	return fmt.Sprint(idType)
+}
+
+// auditOutcome returns the status of a lookup that returned err, and the
+// kind of err for AuditFailed
+func auditOutcome(err error) (AuditStatus, string) {
+	var lib *libError
+	switch {
+	case err == nil:
+		return AuditFound, ""
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrNoPhoto), errors.Is(err, errGroupNotFound):
+		return AuditNotFound, ""
+	case errors.As(err, &lib) && lib.kind != nil:
+		return AuditFailed, lib.kind.Error()
+	case errors.Is(err, context.Canceled):
+		return AuditFailed, context.Canceled.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return AuditFailed, context.DeadlineExceeded.Error()
+	}
+	return AuditFailed, "error"
+}
@@ -0,0 +1,387 @@
+package ldap_redhat_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// auditLog collects audit events
+type auditLog struct {
+	mu     sync.Mutex
+	events []ldap_redhat.AuditEvent
+}
+
+func (l *auditLog) Audit(_ context.Context, event ldap_redhat.AuditEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *auditLog) take() []ldap_redhat.AuditEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := l.events
+	l.events = nil
+	return events
+}
+
+func TestAuditLookups(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	log := &auditLog{}
+	searcher, err := ldap_redhat.NewSearcherWithOptions(context.Background(),
+		ldap_redhat.WithServers(srv.URL()),
+		ldap_redhat.WithBind(embeddedBindDN, embeddedPassword),
+		ldap_redhat.WithBaseDN(testserver.UsersBaseDN),
+		ldap_redhat.WithAudit(log, ldap_redhat.AuditRedaction{
+			Salt: "audit-salt",
+			Fields: map[string]ldap_redhat.FieldMask{
+				"email":  {Action: ldap_redhat.MaskHash},
+				"filter": {Action: ldap_redhat.MaskDrop},
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewSearcherWithOptions failed: %v", err)
+	}
+	defer searcher.Close()
+	ctx := ldap_redhat.WithPrincipal(context.Background(), "alice@example.com")
+
+	uid := testserver.UserUID(1)
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "nobody@example.com"})
+	searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUUID, Value: "not-a-uuid"})
+	events := log.take()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %+v", events)
+	}
+	if e := events[0]; e.Principal != "alice@example.com" || e.Operation != ldap_redhat.AuditGetUser ||
+		e.IdentifierType != "uid" || e.Identifier != uid || e.Status != ldap_redhat.AuditFound || e.Results != 1 || e.Time.IsZero() {
+		t.Errorf("Unexpected event for a found user: %+v", e)
+	}
+	if e := events[1]; e.Status != ldap_redhat.AuditNotFound || e.IdentifierType != "email" ||
+		len(e.Identifier) != 64 || strings.Contains(e.Identifier, "nobody") {
+		t.Errorf("Expected a hashed email and not_found, got %+v", e)
+	}
+	if e := events[2]; e.Principal != "" || e.Status != ldap_redhat.AuditFailed || e.Error != ldap_redhat.ErrInvalidIdentifier.Error() {
+		t.Errorf("Expected a failed lookup without principal, got %+v", e)
+	}
+
+	ids := []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)},
+		{Type: ldap_redhat.IDTUID, Value: "missing"},
+		{Type: 99, Value: "x"},
+	}
+	searcher.GetUsers(ctx, ids)
+	events = log.take()
+	if len(events) != 3 {
+		t.Fatalf("Expected an event per identifier, got %+v", events)
+	}
+	for i, want := range []ldap_redhat.AuditStatus{ldap_redhat.AuditFound, ldap_redhat.AuditNotFound, ldap_redhat.AuditFailed} {
+		if e := events[i]; e.Operation != ldap_redhat.AuditGetUsers || e.Status != want || e.Identifier != ids[i].Value {
+			t.Errorf("Item %d: expected %s, got %+v", i, want, e)
+		}
+	}
+
+	users, err := searcher.SearchUsers(ctx, "(uid=*)")
+	if err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	events = log.take()
+	if len(events) != 1 {
+		t.Fatalf("Expected one listing event, got %+v", events)
+	}
+	if e := events[0]; e.Operation != ldap_redhat.AuditForEachUser || e.Filter != "" || e.Results != len(users) || e.Status != ldap_redhat.AuditFound {
+		t.Errorf("Expected a listing of %d users with its filter dropped, got %+v", len(users), e)
+	}
+}
+
+func TestAuditRedactionValidate(t *testing.T) {
+	for _, fields := range []map[string]ldap_redhat.FieldMask{
+		{"cost_center": {Action: ldap_redhat.MaskDrop}},
+		{"uid": {Action: "scramble"}},
+		{"*": {Action: ldap_redhat.MaskTruncate}},
+		{"email": {Action: ldap_redhat.MaskHash}},
+	} {
+		_, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+			LdapServers:    []string{"ldap://ldap.example.com"},
+			LazyConnect:    true,
+			AuditSink:      &auditLog{},
+			AuditRedaction: ldap_redhat.AuditRedaction{Fields: fields},
+		})
+		if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+			t.Errorf("Expected ErrInvalidConfig for %v, got %v", fields, err)
+		}
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := ldap_redhat.NewJSONAuditSink(&buf)
+	sink.Audit(context.Background(), ldap_redhat.AuditEvent{Operation: ldap_redhat.AuditGetUser, IdentifierType: "uid", Identifier: "jdoe", Status: ldap_redhat.AuditFound, Results: 1})
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if got["identifier"] != "jdoe" || got["status"] != "found" || got["operation"] != "GetUser" {
+		t.Errorf("Unexpected event %v", got)
+	}
+	if sink.Err() != nil {
+		t.Errorf("Unexpected error %v", sink.Err())
+	}
+
+	failing := ldap_redhat.NewJSONAuditSink(failingWriter{})
+	failing.Audit(context.Background(), ldap_redhat.AuditEvent{})
+	if err := failing.Err(); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+}
+
+// newAuditedSearcher returns a searcher connected to an embedded server
+// seeded with n users, auditing to the returned log
+func newAuditedSearcher(t *testing.T, n int) (*ldap_redhat.Searcher, *ldapserver.Server, *auditLog) {
+	t.Helper()
+	srv := startEmbeddedServer(t, n)
+	log := &auditLog{}
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+		AuditSink:   log,
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	t.Cleanup(func() { searcher.Close() })
+	return searcher, srv, log
+}
+
+// oneAuditEvent returns the only event in log, failing t otherwise
+func oneAuditEvent(t *testing.T, log *auditLog) ldap_redhat.AuditEvent {
+	t.Helper()
+	events := log.take()
+	if len(events) != 1 {
+		t.Fatalf("Expected one event, got %+v", events)
+	}
+	return events[0]
+}
+
+func TestAuditGetRawEntry(t *testing.T) {
+	searcher, _, log := newAuditedSearcher(t, 3)
+	ctx := ldap_redhat.WithPrincipal(context.Background(), "alice@example.com")
+
+	if _, err := searcher.GetRawEntry(ctx, uidIdentifier(1)); err != nil {
+		t.Fatalf("GetRawEntry failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditGetRawEntry || e.Principal != "alice@example.com" ||
+		e.Identifier != testserver.UserUID(1) || e.Status != ldap_redhat.AuditFound || e.Results != 1 {
+		t.Errorf("Unexpected event for a raw entry: %+v", e)
+	}
+	searcher.GetRawEntry(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nobody"})
+	if e := oneAuditEvent(t, log); e.Status != ldap_redhat.AuditNotFound || e.Results != 0 {
+		t.Errorf("Expected not_found for an unknown user, got %+v", e)
+	}
+}
+
+func TestAuditGetUserPhoto(t *testing.T) {
+	searcher, srv, log := newAuditedSearcher(t, 3)
+	srv.SetAttribute(testserver.UserDN(1), "jpegPhoto", string(jpegHeader))
+	ctx := context.Background()
+
+	if _, err := searcher.GetUserPhoto(ctx, uidIdentifier(1)); err != nil {
+		t.Fatalf("GetUserPhoto failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditGetUserPhoto || e.Identifier != testserver.UserUID(1) ||
+		e.Status != ldap_redhat.AuditFound || e.Results != 1 {
+		t.Errorf("Unexpected event for a photo: %+v", e)
+	}
+	searcher.GetUserPhoto(ctx, uidIdentifier(2))
+	if e := oneAuditEvent(t, log); e.Status != ldap_redhat.AuditNotFound || e.Error != "" {
+		t.Errorf("Expected not_found for a user without a photo, got %+v", e)
+	}
+}
+
+func TestAuditSearchUsersPage(t *testing.T) {
+	searcher, _, log := newAuditedSearcher(t, 12)
+	enableVLV(searcher)
+
+	page, err := searcher.SearchUsersPage(context.Background(), "(uid=*)", ldap_redhat.VLVOptions{PageSize: 5})
+	if err != nil {
+		t.Fatalf("SearchUsersPage failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditSearchUsersPage || e.Filter != "(uid=*)" ||
+		e.Results != len(page.Users) || e.Results != 5 || e.Status != ldap_redhat.AuditFound {
+		t.Errorf("Expected a page of 5 users, got %+v", e)
+	}
+	searcher.SearchUsersPage(context.Background(), "", ldap_redhat.VLVOptions{Offset: -1})
+	if e := oneAuditEvent(t, log); e.Status != ldap_redhat.AuditFailed {
+		t.Errorf("Expected a failed page, got %+v", e)
+	}
+}
+
+func TestAuditListReportsFlat(t *testing.T) {
+	searcher, _, log := newAuditedSearcher(t, 20)
+	manager := testserver.UserUID(0)
+
+	page, err := searcher.ListReportsFlat(context.Background(), manager, ldap_redhat.ReportListOptions{PageSize: 5})
+	if err != nil {
+		t.Fatalf("ListReportsFlat failed: %v", err)
+	}
+	// The walk and the fetch of the page's records are one event
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditListReportsFlat || e.IdentifierType != "uid" ||
+		e.Identifier != manager || e.Results != len(page.Reports) || e.Results != 5 || e.Status != ldap_redhat.AuditFound {
+		t.Errorf("Expected a page of 5 reports of %s, got %+v", manager, e)
+	}
+	searcher.ListReportsFlat(context.Background(), testserver.UserUID(19), ldap_redhat.ReportListOptions{})
+	if e := oneAuditEvent(t, log); e.Status != ldap_redhat.AuditNotFound || e.Results != 0 {
+		t.Errorf("Expected not_found for a manager without reports, got %+v", e)
+	}
+}
+
+func TestAuditSync(t *testing.T) {
+	searcher, _, log := newAuditedSearcher(t, 5)
+	stop := errors.New("stop")
+	handled := 0
+	err := searcher.Sync(context.Background(), "(uid=*)", func(ldap_redhat.SyncEvent) error {
+		if handled++; handled == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("Sync returned %v, want the handler's error", err)
+	}
+	// Stopping through the handler is how Sync ends, not a failure
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditSync || e.Filter != "(uid=*)" ||
+		e.Results != 2 || e.Status != ldap_redhat.AuditFound || e.Error != "" {
+		t.Errorf("Expected 2 events handled, got %+v", e)
+	}
+}
+
+func TestAuditAuthenticate(t *testing.T) {
+	log := &auditLog{}
+	searcher, srv := newLDAPSSearcher(t, ldap_redhat.Config{AuditSink: log})
+	srv.AddBind(testserver.UserDN(3), "correct horse")
+	ctx := context.Background()
+
+	if _, err := searcher.Authenticate(ctx, uidIdentifier(3), "correct horse"); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditAuthenticate || e.Identifier != testserver.UserUID(3) ||
+		e.Status != ldap_redhat.AuditFound || e.Results != 1 {
+		t.Errorf("Unexpected event for a login: %+v", e)
+	}
+	searcher.Authenticate(ctx, uidIdentifier(3), "wrong")
+	if e := oneAuditEvent(t, log); e.Status != ldap_redhat.AuditFailed || e.Error != ldap_redhat.ErrInvalidCredentials.Error() {
+		t.Errorf("Expected a failed login, got %+v", e)
+	}
+}
+
+func TestAuditReportsAndMappings(t *testing.T) {
+	searcher, _, log := newAuditedSearcher(t, 20)
+	ctx := context.Background()
+	manager := testserver.UserUID(0)
+
+	reports, err := searcher.FindDirectReports(ctx, manager)
+	if err != nil {
+		t.Fatalf("FindDirectReports failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditFindDirectReports || e.IdentifierType != "uid" ||
+		e.Identifier != manager || e.Results != len(reports) || e.Status != ldap_redhat.AuditFound {
+		t.Errorf("Expected the direct reports of %s, got %+v", manager, e)
+	}
+
+	if _, err := searcher.IsPeopleManager(ctx, testserver.UserUID(19)); err != nil {
+		t.Fatalf("IsPeopleManager failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditIsPeopleManager || e.Status != ldap_redhat.AuditNotFound || e.Results != 0 {
+		t.Errorf("Expected not_found for a user without reports, got %+v", e)
+	}
+
+	emails := []string{testserver.UserUID(1) + "@redhat.com", " ", "nobody@redhat.com"}
+	if _, _, err := searcher.MapEmailsToUIDs(ctx, emails); err != nil {
+		t.Fatalf("MapEmailsToUIDs failed: %v", err)
+	}
+	events := log.take()
+	if len(events) != 2 || events[0].Operation != ldap_redhat.AuditMapEmailsToUIDs || events[0].IdentifierType != "email" ||
+		events[0].Status != ldap_redhat.AuditFound || events[1].Identifier != "nobody@redhat.com" || events[1].Status != ldap_redhat.AuditNotFound {
+		t.Errorf("Expected an event per non-blank email, got %+v", events)
+	}
+
+	if _, _, err := searcher.MapUIDsToEmails(ctx, []string{testserver.UserUID(2)}); err != nil {
+		t.Fatalf("MapUIDsToEmails failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditMapUIDsToEmails || e.Identifier != testserver.UserUID(2) || e.Status != ldap_redhat.AuditFound {
+		t.Errorf("Unexpected event for a UID mapping: %+v", e)
+	}
+}
+
+func TestAuditGroups(t *testing.T) {
+	searcher := newGroupSearcher(t)
+	log := &auditLog{}
+	searcher.Config.AuditSink = log
+	ctx := context.Background()
+
+	if _, err := searcher.GetUserGroups(ctx, testserver.UserUID(2)); err != nil {
+		t.Fatalf("GetUserGroups failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditGetUserGroups || e.Identifier != testserver.UserUID(2) ||
+		e.Results != 2 || e.Status != ldap_redhat.AuditFound {
+		t.Errorf("Expected two groups, got %+v", e)
+	}
+	if _, err := searcher.GetGroupMembers(ctx, "devs"); err != nil {
+		t.Fatalf("GetGroupMembers failed: %v", err)
+	}
+	if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditGetGroupMembers || e.IdentifierType != "group" ||
+		e.Identifier != "devs" || e.Results != 2 || e.Status != ldap_redhat.AuditFound {
+		t.Errorf("Expected two members, got %+v", e)
+	}
+	searcher.GetGroupMembers(ctx, "nobody")
+	if e := oneAuditEvent(t, log); e.Status != ldap_redhat.AuditNotFound || e.Error != "" {
+		t.Errorf("Expected not_found for an unknown group, got %+v", e)
+	}
+}
+
+func TestAuditCachedSearcher(t *testing.T) {
+	searcher, _, log := newAuditedSearcher(t, 3)
+	cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), 0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cached.GetUser(ctx, uidIdentifier(1)); err != nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+		if e := oneAuditEvent(t, log); e.Operation != ldap_redhat.AuditGetUser || e.Status != ldap_redhat.AuditFound || e.Cached != (i == 1) {
+			t.Errorf("Lookup %d: unexpected event %+v", i, e)
+		}
+	}
+
+	if _, err := cached.GetUsers(ctx, []ldap_redhat.Identifier{uidIdentifier(1), uidIdentifier(2)}); err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	events := log.take()
+	if len(events) != 2 {
+		t.Fatalf("Expected an event per identifier, got %+v", events)
+	}
+	for _, e := range events {
+		if e.Operation != ldap_redhat.AuditGetUsers || e.Cached != (e.Identifier == testserver.UserUID(1)) {
+			t.Errorf("Only the first identifier should be a cache hit, got %+v", e)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Authenticate verifies a user's password, for applications that log users
+// in against the directory. It finds the user id identifies with the
+// searcher's connection, then binds as their DN with password on a new
+// connection of its own, closed before it returns, so the searcher's
+// service account binding is never replaced. On success it returns the
+// user's record as GetUser would.
+//
+// The password is only sent over an encrypted or local connection: unless
+// the searcher's server is ldaps://, uses StartTLS or is an ldapi:// socket,
+// Authenticate fails with ErrInvalidConfig without contacting it.
+//
+// A wrong password, an empty password (which LDAP would accept as an
+// unauthenticated bind), an unknown identifier and a terminated user all
+// return an error matching ErrInvalidCredentials, so that login forms do not
+// reveal which accounts exist. Failures to reach the directory match
+// ErrNotConnected or ErrTimeout as for lookups. Authenticate is not served
+// from the offline snapshot.
+//
+// The termination date is the one exception to the request scope and the
+// attribute profile: Authenticate fetches it even when they leave it out, so
+// that terminated users are refused whatever the caller may see, and clears
+// it from the record it returns.
+func (s *Searcher) Authenticate(ctx context.Context, id Identifier, password string) (UserRecord, error) {
+	rec, err := s.authenticate(ctx, id, password)
+	s.auditLookup(ctx, AuditAuthenticate, id, err)
+	return rec, err
+}
+
+// authenticate is Authenticate without auditing
+func (s *Searcher) authenticate(ctx context.Context, id Identifier, password string) (UserRecord, error) {
+	if err := s.checkPasswordTransport(); err != nil {
+		return UserRecord{}, err
+	}
+	if password == "" {
+		return UserRecord{}, newError(ErrInvalidCredentials, "invalid credentials for %s", id.Value)
+	}
+	start := time.Now()
+	// The documented exception to the scope and profile; s.redact clears it
+	entry, err := s.lookupEntry(ctx, id, withAttributes(s.attributes(ctx), AttrRhatTermDate))
+	if errors.Is(err, ErrUserNotFound) {
+		return UserRecord{}, newError(ErrInvalidCredentials, "invalid credentials for %s", id.Value)
+	}
+	if err != nil {
+		return UserRecord{}, err
+	}
+	rec := s.userRecord(ctx, entry)
+	if !rec.IsActive() {
+		// The same error as a wrong password, so that it does not reveal
+		// that the account exists
+		return UserRecord{}, newError(ErrInvalidCredentials, "invalid credentials for %s", id.Value)
+	}
+
+	if err := s.verifyPassword(ctx, entry.DN, password); err != nil {
+		if ldap.IsErrorAnyOf(err, ldap.LDAPResultInvalidCredentials, ldap.LDAPResultInappropriateAuthentication) {
+			return UserRecord{}, newError(ErrInvalidCredentials, "invalid credentials for %s", id.Value)
+		}
+		return UserRecord{}, err
+	}
+
+	rec.meta = s.recordMeta(start)
+	if err := s.resolvePeopleManager(ctx, entry, &rec); err != nil {
+		return UserRecord{}, err
+	}
+	s.redact(ctx, &rec)
+	return rec, nil
+}
+
+// checkPasswordTransport refuses to send users' passwords to the searcher's
+// server over a connection that is neither encrypted nor local
+func (s *Searcher) checkPasswordTransport() error {
+	server := s.serverURL()
+	scheme := strings.ToLower(server)
+	if strings.HasPrefix(scheme, "ldaps://") || isLDAPI(server) || s.config().UseStartTLS {
+		return nil
+	}
+	return newError(ErrInvalidConfig, "refusing to send passwords to %s in clear text: use an ldaps:// or ldapi:// URL, or set use_start_tls", server)
+}
+
+// verifyPassword dials a connection of its own to the server the searcher
+// uses, through its circuit breaker and with its TLS settings and timeouts,
+// binds it as dn with password and closes it
+func (s *Searcher) verifyPassword(ctx context.Context, dn, password string) error {
+	config := s.config()
+	config.LdapServers = []string{s.serverURL()}
+	config.AuthMode = AuthSimple
+	config.Username = dn
+	config.Password = ""
+	config.Credentials = StaticPassword(password)
+	if err := s.breaker.allow(); err != nil {
+		return err
+	}
+	conn, _, err := dial(ctx, config)
+	s.breaker.record(err != nil && s.unreachable(err))
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
@@ -0,0 +1,104 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// newLDAPSSearcher returns a searcher connected to an embedded ldaps://
+// server seeded with 5 users, since Authenticate refuses plain ldap://
+func newLDAPSSearcher(t *testing.T, config ldap_redhat.Config) (*ldap_redhat.Searcher, *ldapserver.Server) {
+	t.Helper()
+	srv, caFile := startLDAPSServer(t, nil)
+	config.LdapServers = []string{srv.URL()}
+	config.Username = embeddedBindDN
+	config.Password = embeddedPassword
+	config.BaseDN = testserver.UsersBaseDN
+	config.VerifySSL = true
+	config.CAFile = caFile
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	t.Cleanup(func() { searcher.Close() })
+	return searcher, srv
+}
+
+func TestAuthenticate(t *testing.T) {
+	searcher, srv := newLDAPSSearcher(t, ldap_redhat.Config{})
+	srv.AddBind(testserver.UserDN(3), "correct horse")
+	srv.AddBind(testserver.UserDN(4), "battery staple")
+	srv.SetAttribute(testserver.UserDN(4), "rhatTermDate", "20200131000000Z")
+	ctx := context.Background()
+
+	user, err := searcher.Authenticate(ctx, uidIdentifier(3), "correct horse")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if user.UID != testserver.UserUID(3) || user.Email == "" {
+		t.Errorf("Authenticate returned %+v, want the full record of %s", user, testserver.UserUID(3))
+	}
+
+	byEmail := ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: user.Email}
+	if _, err := searcher.Authenticate(ctx, byEmail, "correct horse"); err != nil {
+		t.Errorf("Authenticate by email failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		id       ldap_redhat.Identifier
+		password string
+	}{
+		{"wrong password", uidIdentifier(3), "incorrect horse"},
+		{"empty password", uidIdentifier(3), ""},
+		{"unknown user", ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nobody"}, "correct horse"},
+		{"terminated user", uidIdentifier(4), "battery staple"},
+		{"user without a password", uidIdentifier(2), "anything"},
+	} {
+		_, err := searcher.Authenticate(ctx, tt.id, tt.password)
+		if !errors.Is(err, ldap_redhat.ErrInvalidCredentials) {
+			t.Errorf("%s: got %v, want ErrInvalidCredentials", tt.name, err)
+		}
+		if errors.Is(err, ldap_redhat.ErrUserNotFound) {
+			t.Errorf("%s: error reveals whether the user exists: %v", tt.name, err)
+		}
+	}
+
+	// A terminated user is refused with the message of a wrong password
+	_, terminated := searcher.Authenticate(ctx, uidIdentifier(4), "battery staple")
+	_, wrong := searcher.Authenticate(ctx, uidIdentifier(4), "wrong")
+	if terminated == nil || wrong == nil || terminated.Error() != wrong.Error() {
+		t.Errorf("Expected the same error for a terminated user and a wrong password, got %v and %v", terminated, wrong)
+	}
+
+	// The searcher keeps its own binding
+	if _, err := searcher.GetUser(ctx, uidIdentifier(1)); err != nil {
+		t.Errorf("GetUser after Authenticate failed: %v", err)
+	}
+}
+
+func TestAuthenticateDisconnected(t *testing.T) {
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{LdapServers: []string{"ldap://127.0.0.1:1"}, LazyConnect: true, DialTimeout: -1})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	searcher.Close()
+	_, err = searcher.Authenticate(context.Background(), uidIdentifier(1), "pw")
+	if err == nil || errors.Is(err, ldap_redhat.ErrInvalidCredentials) {
+		t.Errorf("Expected a connection error, got %v", err)
+	}
+}
+
+func TestAuthenticateRequiresEncryption(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 3)
+	srv.AddBind(testserver.UserDN(1), "correct horse")
+	_, err := searcher.Authenticate(context.Background(), uidIdentifier(1), "correct horse")
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig over plain ldap://, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package ldap_redhat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ItemError is the failure of one item of a batch operation.
+type ItemError struct {
+	Index int    // position of the item in the batch's input
+	Item  string // the item, e.g. an identifier value or a watch name
+	Err   error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Item, e.Err)
+}
+
+func (e ItemError) Unwrap() error { return e.Err }
+
+// BatchError reports the items of a batch operation that failed while the
+// others succeeded. The operation's results are still returned alongside it,
+// with zero values for the failed items. errors.Is and errors.As match any
+// item's error.
+type BatchError struct {
+	Total  int // number of items in the batch
+	Errors []ItemError
+}
+
+// newBatchError returns a *BatchError for errs, or nil if there are none, so
+// that a batch without failures returns a nil error interface
+func newBatchError(total int, errs []ItemError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &BatchError{Total: total, Errors: errs}
+}
+
+func (e *BatchError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("1 of %d items failed: %v", e.Total, e.Errors[0])
+	}
+	return fmt.Sprintf("%d of %d items failed, first: %v", len(e.Errors), e.Total, e.Errors[0])
+}
+
+func (e *BatchError) Unwrap() []error {
+	out := make([]error, len(e.Errors))
+	for i, item := range e.Errors {
+		out[i] = item
+	}
+	return out
+}
+
+// Failed returns the input positions of the failed items, in order.
+func (e *BatchError) Failed() []int {
+	out := make([]int, len(e.Errors))
+	for i, item := range e.Errors {
+		out[i] = item.Index
+	}
+	return out
+}
+
+// Succeeded returns the input positions of the items that did not fail, in
+// order.
+func (e *BatchError) Succeeded() []int {
+	failed := make(map[int]bool, len(e.Errors))
+	for _, item := range e.Errors {
+		failed[item.Index] = true
+	}
+	var out []int
+	for i := 0; i < e.Total; i++ {
+		if !failed[i] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// errorCategories are the sentinels ByCategory groups by
+var errorCategories = []error{
+	ErrUserNotFound, ErrMultipleMatches, ErrInvalidIdentifier,
+	ErrAuthFailed, ErrTimeout, ErrNotConnected, ErrCircuitOpen,
+}
+
+// ByCategory groups the failed items by the sentinel error they match, such
+// as ErrTimeout or ErrInvalidIdentifier. Items matching none are grouped
+// under nil.
+func (e *BatchError) ByCategory() map[error][]ItemError {
+	out := map[error][]ItemError{}
+	for _, item := range e.Errors {
+		var category error
+		for _, sentinel := range errorCategories {
+			if errors.Is(item.Err, sentinel) {
+				category = sentinel
+				break
+			}
+		}
+		out[category] = append(out[category], item)
+	}
+	return out
+}
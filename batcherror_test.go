@@ -0,0 +1,76 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestGetUsersPartialFailure(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	ctx := context.Background()
+	ids := []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)},
+		{Type: 99, Value: "bogus"},
+		{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)},
+	}
+
+	users, err := searcher.GetUsers(ctx, ids)
+	var batchErr *ldap_redhat.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a *BatchError, got %v", err)
+	}
+	if len(users) != 3 || users[0].UID != testserver.UserUID(1) || users[1].UID != "" || users[2].UID != testserver.UserUID(2) {
+		t.Errorf("Expected the other users despite the failure, got %+v", users)
+	}
+	if !errors.Is(err, ldap_redhat.ErrInvalidIdentifier) {
+		t.Error("Expected errors.Is to match the item's error")
+	}
+	if got := batchErr.Failed(); !slices.Equal(got, []int{1}) {
+		t.Errorf("Expected Failed() = [1], got %v", got)
+	}
+	if got := batchErr.Succeeded(); !slices.Equal(got, []int{0, 2}) {
+		t.Errorf("Expected Succeeded() = [0 2], got %v", got)
+	}
+	if items := batchErr.ByCategory()[ldap_redhat.ErrInvalidIdentifier]; len(items) != 1 || items[0].Item != "bogus" {
+		t.Errorf("Expected the item under ErrInvalidIdentifier, got %+v", batchErr.ByCategory())
+	}
+
+	// The cache reports failures by their position in its own input
+	cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), 0)
+	if _, err := cached.GetUser(ctx, ids[0]); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	users, err = cached.GetUsers(ctx, []ldap_redhat.Identifier{ids[0], ids[2], ids[1]})
+	if !errors.As(err, &batchErr) || !slices.Equal(batchErr.Failed(), []int{2}) {
+		t.Errorf("Expected the cached searcher to report item 2, got %v", err)
+	}
+	if users[0].UID == "" || users[1].UID == "" {
+		t.Errorf("Expected the cached and fetched users, got %+v", users)
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	boom := errors.New("boom")
+	err := &ldap_redhat.BatchError{Total: 4, Errors: []ldap_redhat.ItemError{
+		{Index: 0, Item: "a", Err: boom},
+		{Index: 3, Item: "d", Err: ldap_redhat.ErrTimeout},
+	}}
+	if got := err.Error(); got != "2 of 4 items failed, first: a: boom" {
+		t.Errorf("Unexpected message %q", got)
+	}
+	if !errors.Is(err, boom) || !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Error("Expected errors.Is to match every item")
+	}
+	byCategory := err.ByCategory()
+	if len(byCategory[nil]) != 1 || len(byCategory[ldap_redhat.ErrTimeout]) != 1 {
+		t.Errorf("Expected one uncategorized and one timeout item, got %+v", byCategory)
+	}
+	if got := err.Succeeded(); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Expected Succeeded() = [1 2], got %v", got)
+	}
+}
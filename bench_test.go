@@ -0,0 +1,246 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// benchDirectorySize is the number of synthetic users seeded into the
+// embedded server, roughly the size of the Red Hat people directory.
+const benchDirectorySize = 100000
+
+var (
+	benchOnce   sync.Once
+	benchServer *ldapserver.Server
+	benchErr    error
+)
+
+// benchSearcher returns a searcher bound to a shared embedded server seeded
+// with benchDirectorySize users. The server lives for the whole test binary.
+func benchSearcher(b *testing.B) *ldap_redhat.Searcher {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchServer = ldapserver.New()
+		benchServer.AddEntries(testserver.GenerateUsers(benchDirectorySize))
+		benchServer.AddBind("uid=bench,ou=users,dc=redhat,dc=com", "bench")
+		benchErr = benchServer.Start()
+	})
+	if benchErr != nil {
+		b.Fatalf("Failed to start embedded LDAP server: %v", benchErr)
+	}
+
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{benchServer.URL()},
+		Username:    "uid=bench,ou=users,dc=redhat,dc=com",
+		Password:    "bench",
+		BaseDN:      testserver.UsersBaseDN,
+	})
+	if err != nil {
+		b.Fatalf("Failed to create searcher: %v", err)
+	}
+	b.Cleanup(func() { searcher.Close() })
+	return searcher
+}
+
+// BenchmarkGetUserEmbedded benchmarks single-user lookups by UID
+func BenchmarkGetUserEmbedded(b *testing.B) {
+	searcher := benchSearcher(b)
+	ctx := context.Background()
+
+	i := 0
+	b.ResetTimer()
+	for b.Loop() {
+		id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(i % benchDirectorySize)}
+		if _, err := searcher.GetUser(ctx, id); err != nil {
+			b.Fatalf("GetUser failed: %v", err)
+		}
+		i += 7919
+	}
+}
+
+// BenchmarkGetUserByEmailEmbedded benchmarks single-user lookups by email
+func BenchmarkGetUserByEmailEmbedded(b *testing.B) {
+	searcher := benchSearcher(b)
+	ctx := context.Background()
+
+	i := 0
+	b.ResetTimer()
+	for b.Loop() {
+		id := ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: testserver.UserUID(i%benchDirectorySize) + "@redhat.com"}
+		if _, err := searcher.GetUser(ctx, id); err != nil {
+			b.Fatalf("GetUser failed: %v", err)
+		}
+		i += 7919
+	}
+}
+
+// BenchmarkGetUsersBatchEmbedded benchmarks batched lookups of mixed identifiers
+func BenchmarkGetUsersBatchEmbedded(b *testing.B) {
+	for _, size := range []int{10, 50, 200} {
+		b.Run(fmt.Sprintf("Batch%d", size), func(b *testing.B) {
+			searcher := benchSearcher(b)
+			ctx := context.Background()
+
+			ids := make([]ldap_redhat.Identifier, size)
+			for i := range ids {
+				uid := testserver.UserUID((i * 7919) % benchDirectorySize)
+				if i%2 == 0 {
+					ids[i] = ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}
+				} else {
+					ids[i] = ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: uid + "@redhat.com"}
+				}
+			}
+
+			b.ResetTimer()
+			for b.Loop() {
+				if _, err := searcher.GetUsers(ctx, ids); err != nil {
+					b.Fatalf("GetUsers failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFindDirectReportsEmbedded benchmarks direct and recursive report searches
+func BenchmarkFindDirectReportsEmbedded(b *testing.B) {
+	searcher := benchSearcher(b)
+	ctx := context.Background()
+
+	b.Run("Direct", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := searcher.FindDirectReports(ctx, testserver.UserUID(1)); err != nil {
+				b.Fatalf("FindDirectReports failed: %v", err)
+			}
+		}
+	})
+
+	b.Run("RecursiveDepth3", func(b *testing.B) {
+		opts := ldap_redhat.ReportSearchOptions{Recursive: true, MaxDepth: 3}
+		for b.Loop() {
+			if _, err := searcher.FindDirectReports(ctx, testserver.UserUID(1), opts); err != nil {
+				b.Fatalf("FindDirectReports failed: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkForEachUserEmbedded benchmarks paged searches streaming a cost
+// center (4 pages) and a location (25 pages). The embedded server filters the
+// whole directory for every page, so streaming all of it takes half a minute.
+func BenchmarkForEachUserEmbedded(b *testing.B) {
+	searcher := benchSearcher(b)
+	ctx := context.Background()
+
+	for _, bc := range []struct {
+		name   string
+		filter string
+	}{
+		{"CostCenter", "(rhatCostCenter=105)"},
+		{"Location", "(rhatLocation=Brno)"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			users := 0
+			for b.Loop() {
+				users = 0
+				err := searcher.ForEachUser(ctx, bc.filter, func(ldap_redhat.UserRecord) error {
+					users++
+					return nil
+				})
+				if err != nil {
+					b.Fatalf("ForEachUser failed: %v", err)
+				}
+			}
+			b.ReportMetric(float64(users), "users/op")
+		})
+	}
+}
+
+// BenchmarkSearchUsersPageEmbedded benchmarks virtual list view pages at the
+// start and in the middle of the sorted directory
+func BenchmarkSearchUsersPageEmbedded(b *testing.B) {
+	searcher := benchSearcher(b)
+	enableVLV(searcher)
+	ctx := context.Background()
+
+	for _, bc := range []struct {
+		name   string
+		offset int
+	}{
+		{"FirstPage", 0},
+		{"MiddlePage", benchDirectorySize / 2},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := ldap_redhat.VLVOptions{Offset: bc.offset, PageSize: 50}
+			for b.Loop() {
+				if _, err := searcher.SearchUsersPage(ctx, "", opts); err != nil {
+					b.Fatalf("SearchUsersPage failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// missCache is a Cache that never holds anything, so every lookup misses
+type missCache struct{}
+
+func (missCache) Get(context.Context, string) ([]byte, bool, error) { return nil, false, nil }
+func (missCache) Set(context.Context, string, []byte, time.Duration) error {
+	return nil
+}
+func (missCache) Delete(context.Context, string) error { return nil }
+
+// BenchmarkCachedSearcherEmbedded benchmarks CachedSearcher lookups answered
+// from the cache and ones that miss it and search the directory
+func BenchmarkCachedSearcherEmbedded(b *testing.B) {
+	searcher := benchSearcher(b)
+	ctx := context.Background()
+	ids := make([]ldap_redhat.Identifier, 1000)
+	for i := range ids {
+		ids[i] = ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID((i * 7919) % benchDirectorySize)}
+	}
+
+	b.Run("Hit", func(b *testing.B) {
+		cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), time.Hour)
+		if _, err := cached.GetUsers(ctx, ids); err != nil {
+			b.Fatalf("GetUsers failed: %v", err)
+		}
+		i := 0
+		for b.Loop() {
+			if _, err := cached.GetUser(ctx, ids[i%len(ids)]); err != nil {
+				b.Fatalf("GetUser failed: %v", err)
+			}
+			i++
+		}
+	})
+
+	b.Run("Miss", func(b *testing.B) {
+		cached := ldap_redhat.NewCachedSearcher(searcher, missCache{}, time.Hour)
+		i := 0
+		for b.Loop() {
+			if _, err := cached.GetUser(ctx, ids[i%len(ids)]); err != nil {
+				b.Fatalf("GetUser failed: %v", err)
+			}
+			i++
+		}
+	})
+
+	b.Run("BatchHit", func(b *testing.B) {
+		cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), time.Hour)
+		batch := ids[:50]
+		if _, err := cached.GetUsers(ctx, batch); err != nil {
+			b.Fatalf("GetUsers failed: %v", err)
+		}
+		for b.Loop() {
+			if _, err := cached.GetUsers(ctx, batch); err != nil {
+				b.Fatalf("GetUsers failed: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,230 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrCircuitOpen is returned without contacting the directory while the
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("LDAP circuit breaker is open")
+
+// DefaultBreakerOpenTimeout is how long the breaker stays open when
+// Config.BreakerOpenTimeout is zero
+const DefaultBreakerOpenTimeout = 30 * time.Second
+
+// CircuitState is the state of a searcher's circuit breaker
+type CircuitState int
+
+// Circuit breaker states
+const (
+	CircuitClosed   CircuitState = iota // requests flow normally
+	CircuitOpen                         // requests fail fast with ErrCircuitOpen
+	CircuitHalfOpen                     // one trial request decides whether to close
+)
+
+func (c CircuitState) String() string {
+	switch c {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker opens after threshold consecutive unreachable-directory failures,
+// fails fast for openTimeout, then lets a single trial request through. A nil
+// breaker allows everything.
+type breaker struct {
+	threshold   int
+	openTimeout time.Duration
+	onChange    func(Degradation) // Config.OnDegradationChange
+
+	mu            sync.Mutex
+	state         CircuitState
+	failures      int
+	openedAt      time.Time
+	degradedSince time.Time // when the breaker last opened from closed
+	trial         bool      // a half-open trial request is in flight
+}
+
+// newBreaker returns the breaker configured by config, or nil if disabled
+func newBreaker(config Config) *breaker {
+	if config.BreakerFailureThreshold <= 0 {
+		return nil
+	}
+	timeout := config.BreakerOpenTimeout
+	if timeout <= 0 {
+		timeout = DefaultBreakerOpenTimeout
+	}
+	return &breaker{threshold: config.BreakerFailureThreshold, openTimeout: timeout, onChange: config.OnDegradationChange}
+}
+
+// allow reports whether a request may proceed. Every allowed request must be
+// followed by record.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+		return nil
+	case CircuitHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// record notes the outcome of an allowed request
+func (b *breaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	wasDegraded := b.state != CircuitClosed
+	b.trial = false
+	if !failed {
+		b.state = CircuitClosed
+		b.failures = 0
+	} else {
+		b.failures++
+		if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+			if b.state == CircuitClosed {
+				b.degradedSince = time.Now()
+			}
+			b.state = CircuitOpen
+			b.openedAt = time.Now()
+		}
+	}
+	changed := wasDegraded != (b.state != CircuitClosed)
+	d := b.degradationLocked()
+	b.mu.Unlock()
+
+	// Outside the lock, so the callback may call Searcher.Degraded
+	if changed && b.onChange != nil {
+		b.onChange(d)
+	}
+}
+
+func (b *breaker) current() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentLocked()
+}
+
+func (b *breaker) currentLocked() CircuitState {
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.openTimeout {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Degradation describes whether a searcher is failing fast because the
+// directory is unreachable. Embedding services can use it to shed load or
+// tell users the directory is temporarily unavailable instead of letting
+// requests time out.
+type Degradation struct {
+	Degraded   bool          `json:"degraded" yaml:"degraded"`
+	Reason     string        `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Since      time.Time     `json:"since,omitempty" yaml:"since,omitempty"`             // when the searcher became degraded
+	RetryAfter time.Duration `json:"retry_after,omitempty" yaml:"retry_after,omitempty"` // until the next trial request; zero once one is due
+}
+
+// degradationLocked returns the breaker's degradation. b.mu must be held.
+func (b *breaker) degradationLocked() Degradation {
+	state := b.currentLocked()
+	if state == CircuitClosed {
+		return Degradation{}
+	}
+	d := Degradation{
+		Degraded: true,
+		Reason:   fmt.Sprintf("LDAP directory unreachable: circuit breaker %s after %d consecutive failures", state, b.failures),
+		Since:    b.degradedSince,
+	}
+	if state == CircuitOpen {
+		d.RetryAfter = b.openTimeout - time.Since(b.openedAt)
+	}
+	return d
+}
+
+// Degraded reports whether the searcher is degraded, i.e. its circuit breaker
+// is open or half-open, and why. Searches fail fast with ErrCircuitOpen, or
+// are served from the offline snapshot, while it is. It never reports
+// degradation when Config.BreakerFailureThreshold is zero.
+func (s *Searcher) Degraded() Degradation {
+	if s.breaker == nil {
+		return Degradation{}
+	}
+	s.breaker.mu.Lock()
+	defer s.breaker.mu.Unlock()
+	return s.breaker.degradationLocked()
+}
+
+// CircuitState reports the state of the searcher's circuit breaker. It is
+// always CircuitClosed when Config.BreakerFailureThreshold is zero.
+func (s *Searcher) CircuitState() CircuitState {
+	return s.breaker.current()
+}
+
+// searchLDAP runs req against the directory through the circuit breaker.
+// Only failures that mean the directory is unreachable count towards opening
+// it; LDAP result errors such as "no such object" do not. Referrals are
+// followed as the SearchOptions of ctx allow.
+func (s *Searcher) searchLDAP(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	opts, err := s.searchOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, connName, flow, err := s.queryConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	if flow != nil {
+		req.Attributes = flow.request(req.Attributes)
+	}
+	req.Controls = withSessionTracking(req.Controls, connName)
+	setTimeLimit(req, opts.TimeLimit)
+	start := time.Now()
+	_, span := startSpan(ctx, s.config(), "ldap.search", searchSpanAttributes(req)...)
+	result, err := conn.Search(req)
+	s.breaker.record(err != nil && s.unreachable(err))
+	entries := 0
+	if result != nil {
+		entries = len(result.Entries)
+		if flow != nil {
+			flow.observe(ctx, result.Entries...)
+		}
+		err = partialResults(ctx, req, entries, err)
+	}
+	span.SetAttributes(attribute.Int("ldap.result.count", entries))
+	endSpan(span, err)
+	s.logSearch("ldap search", req, start, entries, err)
+	if opts.MaxReferralHops > 0 && (err == nil || referralURLs(err) != nil) {
+		return s.chaseReferrals(ctx, req, result, err, opts.MaxReferralHops)
+	}
+	return result, err
+}
@@ -0,0 +1,203 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:             []string{srv.URL()},
+		Username:                embeddedBindDN,
+		Password:                embeddedPassword,
+		BaseDN:                  testserver.UsersBaseDN,
+		BreakerFailureThreshold: 2,
+		BreakerOpenTimeout:      100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+
+	// Directory errors that are not outages leave the breaker closed
+	for i := 0; i < 3; i++ {
+		searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nonexistent"})
+	}
+	if state := searcher.CircuitState(); state != ldap_redhat.CircuitClosed {
+		t.Fatalf("Expected closed breaker after not-found results, got %s", state)
+	}
+
+	srv.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for !searcher.Conn.IsClosing() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := searcher.GetUser(ctx, id); err == nil || errors.Is(err, ldap_redhat.ErrCircuitOpen) {
+			t.Fatalf("Expected failure %d to reach the directory, got %v", i+1, err)
+		}
+	}
+	if _, err := searcher.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen once the threshold is reached, got %v", err)
+	}
+	if err := searcher.Ping(ctx); !errors.Is(err, ldap_redhat.ErrCircuitOpen) {
+		t.Errorf("Expected Ping to fail fast while open, got %v", err)
+	}
+
+	// After the open timeout a trial reconnect to a healthy server closes it
+	time.Sleep(100 * time.Millisecond)
+	if state := searcher.CircuitState(); state != ldap_redhat.CircuitHalfOpen {
+		t.Errorf("Expected half-open breaker after the timeout, got %s", state)
+	}
+	searcher.Config.LdapServers = []string{startEmbeddedServer(t, 5).URL()}
+	if err := searcher.Ping(ctx); err != nil {
+		t.Fatalf("Expected trial reconnect to succeed, got %v", err)
+	}
+	if state := searcher.CircuitState(); state != ldap_redhat.CircuitClosed {
+		t.Errorf("Expected closed breaker after a successful trial, got %s", state)
+	}
+	if _, err := searcher.GetUser(ctx, id); err != nil {
+		t.Errorf("GetUser after recovery failed: %v", err)
+	}
+}
+
+func TestDegradationCallback(t *testing.T) {
+	srv := startEmbeddedServer(t, 3)
+	var changes []ldap_redhat.Degradation
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:             []string{srv.URL()},
+		Username:                embeddedBindDN,
+		Password:                embeddedPassword,
+		BaseDN:                  testserver.UsersBaseDN,
+		BreakerFailureThreshold: 1,
+		BreakerOpenTimeout:      time.Hour,
+		OnDegradationChange:     func(d ldap_redhat.Degradation) { changes = append(changes, d) },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+	if d := searcher.Degraded(); d.Degraded {
+		t.Fatalf("Expected a healthy searcher, got %+v", d)
+	}
+
+	srv.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for !searcher.Connection().IsClosing() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)})
+	searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)})
+
+	d := searcher.Degraded()
+	if !d.Degraded || d.Reason == "" || d.Since.IsZero() || d.RetryAfter <= 0 || d.RetryAfter > time.Hour {
+		t.Errorf("Expected an open breaker to report degradation, got %+v", d)
+	}
+	if len(changes) != 1 || !changes[0].Degraded {
+		t.Fatalf("Expected one degraded notification, got %+v", changes)
+	}
+}
+
+func TestDegradationRecovery(t *testing.T) {
+	srv := startEmbeddedServer(t, 3)
+	var changes []ldap_redhat.Degradation
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:             []string{srv.URL()},
+		Username:                embeddedBindDN,
+		Password:                embeddedPassword,
+		BaseDN:                  testserver.UsersBaseDN,
+		BreakerFailureThreshold: 1,
+		BreakerOpenTimeout:      50 * time.Millisecond,
+		OnDegradationChange:     func(d ldap_redhat.Degradation) { changes = append(changes, d) },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	srv.InjectFault(ldapserver.OpBind, ldapserver.Fault{Drop: true, Times: 1})
+	srv.DropConnections()
+	if err := searcher.Ping(ctx); err == nil {
+		t.Fatal("Expected Ping to fail while binds are dropped")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if d := searcher.Degraded(); !d.Degraded || d.RetryAfter != 0 {
+		t.Errorf("Expected a half-open breaker with a trial due, got %+v", d)
+	}
+	if err := searcher.Ping(ctx); err != nil {
+		t.Fatalf("Expected the trial reconnect to succeed, got %v", err)
+	}
+
+	if d := searcher.Degraded(); d.Degraded {
+		t.Errorf("Expected recovery, got %+v", d)
+	}
+	if len(changes) != 2 || !changes[0].Degraded || changes[1].Degraded {
+		t.Errorf("Expected degraded then recovered notifications, got %+v", changes)
+	}
+}
+
+func TestCircuitBreakerTrialStoppedEarly(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:             []string{srv.URL()},
+		Username:                embeddedBindDN,
+		Password:                embeddedPassword,
+		BaseDN:                  testserver.UsersBaseDN,
+		BreakerFailureThreshold: 1,
+		BreakerOpenTimeout:      50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+	stop := errors.New("stop")
+
+	open := func() {
+		t.Helper()
+		searcher.Conn.SetTimeout(50 * time.Millisecond)
+		srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Delay: 200 * time.Millisecond, Times: 1})
+		searcher.GetUser(ctx, id)
+		searcher.Conn.SetTimeout(time.Minute)
+		if state := searcher.CircuitState(); state != ldap_redhat.CircuitOpen {
+			t.Fatalf("Expected an open breaker after the timeout, got %s", state)
+		}
+		time.Sleep(60 * time.Millisecond)
+	}
+
+	// A trial stopped by the callback still closes the breaker
+	open()
+	err = searcher.ForEachUser(ctx, "(uid=*)", func(ldap_redhat.UserRecord) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("Expected the callback's error, got %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, id); err != nil {
+		t.Errorf("Expected the next call to be allowed, got %v", err)
+	}
+
+	// So does a trial whose context is cancelled
+	open()
+	cancelled, cancel := context.WithCancel(ctx)
+	err = searcher.ForEachUser(cancelled, "(uid=*)", func(ldap_redhat.UserRecord) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, id); err != nil {
+		t.Errorf("Expected the next call to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package ldap_redhat
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/openshift-eng/go-ldap-redhat/internal/buildfeature"
+)
+
+// modulePath is this library's module path, used to find it in build info
+const modulePath = "github.com/openshift-eng/go-ldap-redhat"
+
+// ldapModulePath is the go-ldap module path
+const ldapModulePath = "github.com/go-ldap/ldap/v3"
+
+// Commit is the VCS revision the library was built from. It is filled in from
+// the Go build info when this module is the main module; other builds can set
+// it with -ldflags "-X github.com/openshift-eng/go-ldap-redhat.Commit=<sha>".
+var Commit string
+
+// VersionInfo describes the library build, as returned by BuildInfo.
+type VersionInfo struct {
+	Version     string   `json:"version" yaml:"version"`
+	Commit      string   `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Modified    bool     `json:"modified,omitempty" yaml:"modified,omitempty"` // built from a dirty working tree
+	GoVersion   string   `json:"go_version" yaml:"go_version"`
+	LDAPVersion string   `json:"go_ldap_version,omitempty" yaml:"go_ldap_version,omitempty"`
+	Features    []string `json:"features" yaml:"features"` // optional packages linked in, such as "vault" or "grpc"
+
+	FeatureGates map[Feature]bool `json:"feature_gates" yaml:"feature_gates"` // process-wide experimental feature gates
+}
+
+// BuildInfo reports the library version, VCS commit, Go and go-ldap versions,
+// the optional packages linked into the running binary (vault, grpc,
+// rediscache, localstore/boltstore, localstore/sqlitestore) and the state of
+// the experimental feature gates.
+func BuildInfo() VersionInfo {
+	info := VersionInfo{
+		Version:      Version,
+		Commit:       Commit,
+		GoVersion:    runtime.Version(),
+		Features:     buildfeature.Names(),
+		FeatureGates: FeatureGates(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if bi.Main.Path == modulePath {
+			for _, setting := range bi.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = setting.Value
+					}
+				case "vcs.modified":
+					info.Modified = setting.Value == "true"
+				}
+			}
+		}
+		for _, dep := range bi.Deps {
+			if dep.Path != ldapModulePath {
+				continue
+			}
+			info.LDAPVersion = dep.Version
+			if dep.Replace != nil {
+				info.LDAPVersion = dep.Replace.Version
+			}
+		}
+	}
+	return info
+}
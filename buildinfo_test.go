@@ -0,0 +1,47 @@
+package ldap_redhat_test
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestBuildInfo(t *testing.T) {
+	info := ldap_redhat.BuildInfo()
+
+	if info.Version != ldap_redhat.Version {
+		t.Errorf("Expected version %s, got %s", ldap_redhat.Version, info.Version)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+	if !strings.HasPrefix(info.LDAPVersion, "v3.") {
+		t.Errorf("Expected go-ldap v3 version, got %q", info.LDAPVersion)
+	}
+	if info.Features == nil {
+		t.Error("Features should be an empty list, not nil")
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, key := range []string{`"version"`, `"go_version"`, `"go_ldap_version"`, `"features":[`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("Expected %s in %s", key, data)
+		}
+	}
+}
+
+func TestBuildInfoCommitOverride(t *testing.T) {
+	original := ldap_redhat.Commit
+	defer func() { ldap_redhat.Commit = original }()
+
+	ldap_redhat.Commit = "0123abcd"
+	if got := ldap_redhat.BuildInfo().Commit; got != "0123abcd" {
+		t.Errorf("Expected commit override, got %q", got)
+	}
+}
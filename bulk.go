@@ -0,0 +1,152 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Defaults of BulkOptions
+const (
+	DefaultBulkConcurrency = 8
+	DefaultBulkBatchSize   = 50
+)
+
+// BulkOptions configures a BulkResolver.
+type BulkOptions struct {
+	Concurrency int // GetUsers calls in flight at once (0 = DefaultBulkConcurrency)
+	BatchSize   int // identifiers per GetUsers call (0 = DefaultBulkBatchSize)
+	// Progress, when set, is called after each batch with the number of
+	// distinct identifiers resolved so far and their total. Calls are
+	// serialized.
+	Progress func(done, total int)
+}
+
+// BulkResolver resolves large identifier lists, such as the accounts of a
+// nightly sync, by splitting them into GetUsers batches and running several
+// batches at once. go-ldap multiplexes concurrent searches over the
+// searcher's connection, so the batches overlap their round trips instead of
+// waiting for each other.
+type BulkResolver struct {
+	searcher UserSearcher
+	opts     BulkOptions
+}
+
+// NewBulkResolver returns a BulkResolver looking users up with s, which may
+// be a *Searcher, a *CachedSearcher or a fake.
+func NewBulkResolver(s UserSearcher, opts BulkOptions) *BulkResolver {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultBulkConcurrency
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBulkBatchSize
+	}
+	return &BulkResolver{searcher: s, opts: opts}
+}
+
+// Resolve looks up ids and returns one record per identifier, in input order.
+// Identifiers are deduplicated first, matching emails case-insensitively as
+// GetUser does, so repeated identifiers cost one lookup. Unlike GetUsers,
+// identifiers matching no user are failures: the error is a *BatchError
+// with an item matching ErrUserNotFound for each of them, alongside those
+// that were invalid or whose batch failed, and zero records in their
+// positions. BatchError.ByCategory separates the two. When ctx is cancelled,
+// the identifiers not yet resolved fail with ctx.Err().
+func (r *BulkResolver) Resolve(ctx context.Context, ids []Identifier) ([]UserRecord, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// unique holds the first of each set of equal identifiers, and pos the
+	// index in unique of each of ids
+	type key struct {
+		idType int
+		value  string
+	}
+	var unique []Identifier
+	pos := make([]int, len(ids))
+	seen := map[key]int{}
+	for i, id := range ids {
+		k := key{idType: id.Type, value: id.Value}
+		if v, ok := identifierKey(id.Type, id.Value); ok {
+			k.value = v
+		}
+		j, ok := seen[k]
+		if !ok {
+			j = len(unique)
+			seen[k] = j
+			unique = append(unique, id)
+		}
+		pos[i] = j
+	}
+
+	users := make([]UserRecord, len(unique))
+	errs := make([]error, len(unique))
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex // serializes Progress
+		done int
+	)
+	sem := make(chan struct{}, r.opts.Concurrency)
+	for start := 0; start < len(unique); start += r.opts.BatchSize {
+		end := min(start+r.opts.BatchSize, len(unique))
+		sem <- struct{}{}
+		if err := ctx.Err(); err != nil {
+			<-sem
+			for i := start; i < len(unique); i++ {
+				errs[i] = err
+			}
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			r.resolveBatch(ctx, unique[start:end], users[start:end], errs[start:end])
+			if r.opts.Progress != nil {
+				mu.Lock()
+				done += end - start
+				r.opts.Progress(done, len(unique))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	out := make([]UserRecord, len(ids))
+	var failed []ItemError
+	for i, id := range ids {
+		if err := errs[pos[i]]; err != nil {
+			failed = append(failed, ItemError{Index: i, Item: id.Value, Err: err})
+			continue
+		}
+		out[i] = users[pos[i]]
+	}
+	return out, newBatchError(len(ids), failed)
+}
+
+// resolveBatch looks up ids with one GetUsers call, filling users and errs,
+// which are parallel to ids
+func (r *BulkResolver) resolveBatch(ctx context.Context, ids []Identifier, users []UserRecord, errs []error) {
+	found, err := r.searcher.GetUsers(ctx, ids)
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		for i := range ids {
+			errs[i] = err
+		}
+		return
+	}
+	if batchErr != nil {
+		for _, item := range batchErr.Errors {
+			errs[item.Index] = item.Err
+		}
+	}
+	for i, id := range ids {
+		switch {
+		case errs[i] != nil:
+		case i >= len(found) || found[i].UID == "":
+			errs[i] = newError(ErrUserNotFound, "user not found in LDAP directory: %s", id.Value)
+		default:
+			users[i] = found[i]
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// countingSearcher counts the GetUsers calls made through it and the most
+// in flight at once
+type countingSearcher struct {
+	*ldap_redhat.Searcher
+	calls, inFlight, maxInFlight atomic.Int64
+}
+
+func (c *countingSearcher) GetUsers(ctx context.Context, ids []ldap_redhat.Identifier) ([]ldap_redhat.UserRecord, error) {
+	c.calls.Add(1)
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for m := c.maxInFlight.Load(); n > m && !c.maxInFlight.CompareAndSwap(m, n); m = c.maxInFlight.Load() {
+	}
+	return c.Searcher.GetUsers(ctx, ids)
+}
+
+func TestBulkResolver(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 300)
+	counting := &countingSearcher{Searcher: searcher}
+
+	var ids []ldap_redhat.Identifier
+	for i := range 300 {
+		ids = append(ids, uidIdentifier(i))
+	}
+	// Duplicates, an email differing only in case, an unknown user and an
+	// invalid identifier
+	ids = append(ids, uidIdentifier(7), ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: strings.ToUpper(testserver.UserUID(8)) + "@REDHAT.COM"})
+	ids = append(ids, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nobody"}, ldap_redhat.Identifier{Type: 99, Value: "unknown-type"})
+
+	var calls, lastDone, lastTotal atomic.Int64
+	resolver := ldap_redhat.NewBulkResolver(counting, ldap_redhat.BulkOptions{
+		Concurrency: 4,
+		BatchSize:   40,
+		Progress: func(done, total int) {
+			calls.Add(1)
+			lastDone.Store(int64(done))
+			lastTotal.Store(int64(total))
+		},
+	})
+	users, err := resolver.Resolve(context.Background(), ids)
+
+	var batch *ldap_redhat.BatchError
+	if !errors.As(err, &batch) {
+		t.Fatalf("Resolve returned %v, want a *BatchError", err)
+	}
+	if got := batch.Failed(); len(got) != 2 || got[0] != 302 || got[1] != 303 {
+		t.Errorf("Failed identifiers at %v, want 302 and 303", got)
+	}
+	categories := batch.ByCategory()
+	if len(categories[ldap_redhat.ErrUserNotFound]) != 1 || len(categories[ldap_redhat.ErrInvalidIdentifier]) != 1 {
+		t.Errorf("Got failures %v, want one unknown and one invalid identifier", categories)
+	}
+	if len(users) != len(ids) {
+		t.Fatalf("Got %d records for %d identifiers", len(users), len(ids))
+	}
+	for i := range 300 {
+		if users[i].UID != testserver.UserUID(i) {
+			t.Fatalf("Record %d is %q, want %s", i, users[i].UID, testserver.UserUID(i))
+		}
+	}
+	if users[300].UID != testserver.UserUID(7) || users[301].UID != testserver.UserUID(8) {
+		t.Errorf("Duplicate identifiers resolved to %q and %q", users[300].UID, users[301].UID)
+	}
+
+	// 303 distinct identifiers in batches of 40
+	if n := counting.calls.Load(); n != 8 {
+		t.Errorf("Resolve made %d GetUsers calls, want 8", n)
+	}
+	if n := counting.maxInFlight.Load(); n > 4 {
+		t.Errorf("Resolve made %d GetUsers calls at once, want at most 4", n)
+	}
+	if calls.Load() != 8 || lastDone.Load() != 303 || lastTotal.Load() != 303 {
+		t.Errorf("Got %d progress calls ending at %d/%d, want 8 ending at 303/303", calls.Load(), lastDone.Load(), lastTotal.Load())
+	}
+}
+
+func TestBulkResolverCancelled(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	users, err := ldap_redhat.NewBulkResolver(searcher, ldap_redhat.BulkOptions{}).Resolve(ctx, []ldap_redhat.Identifier{uidIdentifier(1), uidIdentifier(2)})
+	if !errors.Is(err, context.Canceled) || len(users) != 2 || users[0].UID != "" {
+		t.Errorf("Resolve with a cancelled context returned %v, %v, want context.Canceled for each identifier", users, err)
+	}
+}
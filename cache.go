@@ -0,0 +1,280 @@
+package ldap_redhat
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Cache stores serialized user records with a TTL. Implementations must be
+// safe for concurrent use. MemoryCache keeps entries in process; the rediscache
+// package shares them between replicas.
+type Cache interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// DefaultMemoryCacheEntries bounds the entries of NewMemoryCache. Each record
+// takes one entry per identifier it is cached under.
+const DefaultMemoryCacheEntries = 10000
+
+// MemoryCache is an in-process Cache holding at most a fixed number of
+// entries. When it is full, Set evicts the least recently used entry, so
+// entries that expired without being read again go first; expired entries
+// that are read are dropped on access.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element // of *memoryEntry
+	lru        *list.List               // most recently used first
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache returns an empty in-process cache of
+// DefaultMemoryCacheEntries entries.
+func NewMemoryCache() *MemoryCache {
+	return NewMemoryCacheSize(DefaultMemoryCacheEntries)
+}
+
+// NewMemoryCacheSize returns an empty in-process cache of maxEntries
+// entries; zero or less means DefaultMemoryCacheEntries.
+func NewMemoryCacheSize(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMemoryCacheEntries
+	}
+	return &MemoryCache{maxEntries: maxEntries, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := elem.Value.(*memoryEntry)
+	if time.Now().After(e.expires) {
+		m.remove(elem)
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(elem)
+	return e.value, true, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &memoryEntry{key: key, value: append([]byte(nil), value...), expires: time.Now().Add(ttl)}
+	if elem, ok := m.entries[key]; ok {
+		elem.Value = e
+		m.lru.MoveToFront(elem)
+		return nil
+	}
+	m.entries[key] = m.lru.PushFront(e)
+	for m.lru.Len() > m.maxEntries {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[key]; ok {
+		m.remove(elem)
+	}
+	return nil
+}
+
+// Len returns the number of entries held, including expired ones not yet
+// dropped.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// remove drops elem; m.mu must be held
+func (m *MemoryCache) remove(elem *list.Element) {
+	m.lru.Remove(elem)
+	delete(m.entries, elem.Value.(*memoryEntry).key)
+}
+
+// DefaultCacheTTL is used by NewCachedSearcher when ttl is zero
+const DefaultCacheTTL = 15 * time.Minute
+
+// defaultCachePrefix namespaces cache keys so a shared cache can hold other data
+const defaultCachePrefix = "ldap-redhat:user:"
+
+// CachedSearcher answers GetUser and GetUsers from a Cache, falling back to
+// the directory on a miss. Cache errors are treated as misses so an
+// unavailable cache never fails a lookup. Requests carrying a RequestScope,
+// SearchOptions.IncludeDeleted or SearchOptions.AttributeProfile bypass the
+// cache, as do stale offline results. Records fetched with a restricted
+// Config.AttributeProfile are cached under keys of their own.
+type CachedSearcher struct {
+	*Searcher
+	Cache  Cache
+	TTL    time.Duration
+	Prefix string // key prefix, "ldap-redhat:user:" by default
+}
+
+// NewCachedSearcher wraps s with cache. A zero ttl means DefaultCacheTTL.
+func NewCachedSearcher(s *Searcher, cache Cache, ttl time.Duration) *CachedSearcher {
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedSearcher{Searcher: s, Cache: cache, TTL: ttl, Prefix: defaultCachePrefix}
+}
+
+// cacheKey returns the key for id, such as "ldap-redhat:user:email:" and the
+// lowercased address, or "ldap-redhat:user:minimal:email:..." for records
+// of a restricted attribute profile.
+func (c *CachedSearcher) cacheKey(id Identifier) string {
+	prefix := c.Prefix
+	if profile, _ := c.config().AttributeProfile.normalize(); profile != ProfileFull {
+		prefix += string(profile) + ":"
+	}
+	key, ok := identifierKey(id.Type, id.Value)
+	if !ok {
+		return prefix + "uid:" + id.Value
+	}
+	return prefix + identifierKinds[id.Type].name + ":" + key
+}
+
+// recordCacheKeys returns the keys rec is cached under, one per identifier
+// it has
+func (c *CachedSearcher) recordCacheKeys(rec UserRecord) []string {
+	var keys []string
+	for idType := range recordIdentifiers(&rec) {
+		keys = append(keys, c.cacheKey(Identifier{Type: idType, Value: identifierKinds[idType].field(&rec)}))
+	}
+	return keys
+}
+
+// cacheable reports whether results for ctx may be read from and stored in
+// the cache
+func cacheable(ctx context.Context) bool {
+	_, scoped := RequestScopeFromContext(ctx)
+	opts, _ := SearchOptionsFromContext(ctx)
+	return !scoped && !opts.IncludeDeleted && opts.AttributeProfile == ""
+}
+
+// cacheEntry is the cached form of a record, keeping its metadata
+type cacheEntry struct {
+	Record UserRecord `json:"record"`
+	Meta   RecordMeta `json:"meta"`
+}
+
+func (c *CachedSearcher) lookup(ctx context.Context, id Identifier) (UserRecord, bool) {
+	data, ok, err := c.Cache.Get(ctx, c.cacheKey(id))
+	if err != nil || !ok {
+		return UserRecord{}, false
+	}
+	var entry cacheEntry
+	// Entries in an older format decode without a record and count as misses
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Record.UID == "" {
+		return UserRecord{}, false
+	}
+	entry.Record.meta = entry.Meta
+	return entry.Record, true
+}
+
+// store caches rec under every identifier it has
+func (c *CachedSearcher) store(ctx context.Context, rec UserRecord) {
+	if rec.UID == "" || rec.Stale {
+		return
+	}
+	data, err := json.Marshal(cacheEntry{Record: rec, Meta: rec.meta})
+	if err != nil {
+		return
+	}
+	for _, key := range c.recordCacheKeys(rec) {
+		c.Cache.Set(ctx, key, data, c.TTL)
+	}
+}
+
+// GetUser returns the cached record for id, or looks it up and caches it.
+func (c *CachedSearcher) GetUser(ctx context.Context, id Identifier) (UserRecord, error) {
+	if !cacheable(ctx) {
+		return c.Searcher.GetUser(ctx, id)
+	}
+	if rec, ok := c.lookup(ctx, id); ok {
+		c.auditCached(ctx, AuditGetUser, id)
+		return rec, nil
+	}
+	rec, err := c.Searcher.GetUser(ctx, id)
+	if err != nil {
+		return UserRecord{}, err
+	}
+	c.store(ctx, rec)
+	return rec, nil
+}
+
+// GetUsers serves cached identifiers and looks up the rest in one batch.
+// Results are in input order; missing users have an empty UID.
+func (c *CachedSearcher) GetUsers(ctx context.Context, ids []Identifier) ([]UserRecord, error) {
+	if !cacheable(ctx) {
+		return c.Searcher.GetUsers(ctx, ids)
+	}
+	out := make([]UserRecord, len(ids))
+	var missing []Identifier
+	var missingIdx []int
+	for i, id := range ids {
+		if rec, ok := c.lookup(ctx, id); ok {
+			c.auditCached(ctx, AuditGetUsers, id)
+			out[i] = rec
+			continue
+		}
+		missing = append(missing, id)
+		missingIdx = append(missingIdx, i)
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+	recs, err := c.Searcher.GetUsers(ctx, missing)
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return nil, err
+	}
+	for j, rec := range recs {
+		out[missingIdx[j]] = rec
+		c.store(ctx, rec)
+	}
+	if batchErr != nil {
+		// Report failures by their position in ids rather than in missing
+		failed := make([]ItemError, len(batchErr.Errors))
+		for i, item := range batchErr.Errors {
+			item.Index = missingIdx[item.Index]
+			failed[i] = item
+		}
+		return out, newBatchError(len(ids), failed)
+	}
+	return out, nil
+}
+
+// Invalidate removes the cached record for id under every identifier it has.
+func (c *CachedSearcher) Invalidate(ctx context.Context, id Identifier) error {
+	keys := []string{c.cacheKey(id)}
+	if rec, ok := c.lookup(ctx, id); ok {
+		keys = append(keys, c.recordCacheKeys(rec)...)
+	}
+	for _, key := range keys {
+		if err := c.Cache.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cache := ldap_redhat.NewMemoryCache()
+	ctx := context.Background()
+
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+	cache.Set(ctx, "b", []byte("2"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if v, ok, _ := cache.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Errorf("Expected a=1, got %q (found %v)", v, ok)
+	}
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("Expected expired entry to be missing")
+	}
+	cache.Delete(ctx, "a")
+	if _, ok, _ := cache.Get(ctx, "a"); ok {
+		t.Error("Expected deleted entry to be missing")
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := ldap_redhat.NewMemoryCacheSize(2)
+	ctx := context.Background()
+
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+	cache.Set(ctx, "b", []byte("2"), time.Minute)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("Expected least recently used entry b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := cache.Get(ctx, key); !ok {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+
+	cache.Set(ctx, "a", []byte("4"), time.Minute)
+	if v, _, _ := cache.Get(ctx, "a"); string(v) != "4" || cache.Len() != 2 {
+		t.Errorf("Expected a=4 replaced in place, got %q with %d entries", v, cache.Len())
+	}
+}
+
+func TestCachedSearcher(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), 0)
+	ctx := context.Background()
+	byUID := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+	byEmail := ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: testserver.UserUID(1) + "@REDHAT.COM"}
+
+	if _, err := cached.GetUser(ctx, byUID); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if _, err := cached.GetUsers(ctx, []ldap_redhat.Identifier{{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)}}); err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+
+	// Cached records are served without the directory
+	srv.Close()
+	user, err := cached.GetUser(ctx, byEmail)
+	if err != nil || user.UID != testserver.UserUID(1) {
+		t.Errorf("Expected cached %s by email, got %+v (err %v)", testserver.UserUID(1), user, err)
+	}
+	users, err := cached.GetUsers(ctx, []ldap_redhat.Identifier{byUID, {Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)}})
+	if err != nil || users[0].UID != testserver.UserUID(1) || users[1].UID != testserver.UserUID(2) {
+		t.Errorf("Expected cached batch, got %+v (err %v)", users, err)
+	}
+
+	if err := cached.Invalidate(ctx, byUID); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+	if _, err := cached.GetUser(ctx, byEmail); err == nil {
+		t.Error("Expected lookup by email to miss after invalidating the UID")
+	}
+}
@@ -0,0 +1,112 @@
+package ldap_redhat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// SessionTrackingControlOID is the session tracking control
+// (draft-wahl-ldap-session) sent with binds and searches to identify the
+// client. Servers that do not implement it ignore it; OpenLDAP logs it with
+// each operation.
+const SessionTrackingControlOID = "1.3.6.1.4.1.21008.108.63.1"
+
+// sessionTrackingUsernameOID is the identifier format for a user or
+// application name
+const sessionTrackingUsernameOID = SessionTrackingControlOID + ".3"
+
+// connSeq numbers the connections the process dials
+var connSeq atomic.Uint64
+
+var hostname = sync.OnceValue(func() string {
+	name, _ := os.Hostname()
+	return name
+})
+
+// clientName returns Config.ClientName, or the program name when unset
+func (c Config) clientName() string {
+	if c.ClientName != "" {
+		return c.ClientName
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// newConnName names a connection about to be dialed, e.g. "billing/3", for
+// logs and the session tracking control. The number is unique in the
+// process, so that one connection's operations can be told apart from
+// another's.
+func (c Config) newConnName() string {
+	return fmt.Sprintf("%s/%d", c.clientName(), connSeq.Add(1))
+}
+
+// sessionTracking returns the session tracking control naming connection
+// connName
+func sessionTracking(connName string) ldap.Control {
+	return &sessionTrackingControl{sourceName: hostname(), identifier: connName}
+}
+
+// withSessionTracking returns controls with the session tracking control
+// for connName, replacing any there already, e.g. when a request is re-sent
+// on another connection to follow a referral
+func withSessionTracking(controls []ldap.Control, connName string) []ldap.Control {
+	if connName == "" {
+		return controls
+	}
+	tracked := make([]ldap.Control, 0, len(controls)+1)
+	for _, c := range controls {
+		if c.GetControlType() != SessionTrackingControlOID {
+			tracked = append(tracked, c)
+		}
+	}
+	return append(tracked, sessionTracking(connName))
+}
+
+// trackedConnName returns the connection named by the session tracking
+// control in controls, or ""
+func trackedConnName(controls []ldap.Control) string {
+	if c, ok := ldap.FindControl(controls, SessionTrackingControlOID).(*sessionTrackingControl); ok {
+		return c.identifier
+	}
+	return ""
+}
+
+// sessionTrackingControl implements ldap.Control for the session tracking
+// control. It is never critical.
+type sessionTrackingControl struct {
+	sourceName string
+	identifier string
+}
+
+func (c *sessionTrackingControl) GetControlType() string {
+	return SessionTrackingControlOID
+}
+
+// Encode returns the control with its value,
+//
+//	SEQUENCE { sessionSourceIp, sessionSourceName, formatOID, sessionTrackingIdentifier }
+//
+// leaving the source IP empty: the server sees the connection's address.
+func (c *sessionTrackingControl) Encode() *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, SessionTrackingControlOID, "Control Type (Session Tracking)"))
+
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Session Identifier")
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Session Source IP"))
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, c.sourceName, "Session Source Name"))
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, sessionTrackingUsernameOID, "Format OID"))
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, c.identifier, "Session Tracking Identifier"))
+	wrapper := ber.Encode(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, nil, "Control Value (Session Tracking)")
+	wrapper.AppendChild(value)
+	packet.AppendChild(wrapper)
+	return packet
+}
+
+func (c *sessionTrackingControl) String() string {
+	return fmt.Sprintf("Control Type: Session Tracking (%q)  Source Name: %s  Identifier: %s", SessionTrackingControlOID, c.sourceName, c.identifier)
+}
@@ -0,0 +1,60 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestClientName(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+		ClientName:  "billing",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+
+	ctx := context.Background()
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	ids := srv.SessionIDs()
+	if len(ids) != 1 || !strings.HasPrefix(ids[0], "billing/") {
+		t.Fatalf("Expected one session named billing/<n>, got %v", ids)
+	}
+
+	// A new connection gets a new name; its searches carry it
+	srv.DropConnections()
+	if err := searcher.Ping(ctx); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)}); err != nil {
+		t.Fatalf("GetUser after reconnect failed: %v", err)
+	}
+	ids = srv.SessionIDs()
+	if len(ids) != 2 || !slices.ContainsFunc(ids, func(id string) bool { return id != ids[0] && strings.HasPrefix(id, "billing/") }) {
+		t.Errorf("Expected a second billing/<n> session after reconnecting, got %v", ids)
+	}
+}
+
+func TestClientNameDefault(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 1)
+	if _, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(0)}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	// The test binary's name
+	ids := srv.SessionIDs()
+	if len(ids) != 1 || !strings.HasPrefix(ids[0], "go-ldap-redhat.test/") {
+		t.Errorf("Expected a session named after the program, got %v", ids)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/ldif"
+	"gopkg.in/yaml.v3"
+)
+
+// batchChunkSize is the number of identifiers resolved per GetUsers call
+const batchChunkSize = 50
+
+// batchResult is one line of batch output: the user found for Input, or the
+// reason it could not be resolved
+type batchResult struct {
+	Input string                  `json:"input" yaml:"input"`
+	User  *ldap_redhat.UserRecord `json:"user,omitempty" yaml:"user,omitempty"`
+	Error string                  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// readIdentifiers reads newline-delimited identifiers, skipping blank lines
+// and # comments
+func readIdentifiers(r io.Reader) ([]string, error) {
+	var out []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, scanner.Err()
+}
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// resolveBatch looks up inputs in chunks and calls emit with one result per
+// input, in input order. Inputs ParseIdentifier rejects are reported without
+// a lookup.
+func resolveBatch(ctx context.Context, s *ldap_redhat.Searcher, inputs []string, emit func(batchResult) error) error {
+	for start := 0; start < len(inputs); start += batchChunkSize {
+		chunk := inputs[start:min(start+batchChunkSize, len(inputs))]
+		var ids []ldap_redhat.Identifier
+		idx := make([]int, len(chunk)) // index in ids, -1 if invalid
+		parseErrs := make([]error, len(chunk))
+		for i, input := range chunk {
+			id, err := ldap_redhat.ParseIdentifier(input)
+			if err != nil {
+				idx[i], parseErrs[i] = -1, err
+				continue
+			}
+			idx[i] = len(ids)
+			ids = append(ids, id)
+		}
+
+		users, err := s.GetUsers(ctx, ids)
+		itemErrs := map[int]error{} // by index in ids
+		var batchErr *ldap_redhat.BatchError
+		if errors.As(err, &batchErr) {
+			for _, item := range batchErr.Errors {
+				itemErrs[item.Index] = item.Err
+			}
+			err = nil
+		}
+		for i, input := range chunk {
+			r := batchResult{Input: input}
+			switch {
+			case parseErrs[i] != nil:
+				r.Error = parseErrs[i].Error()
+			case err != nil:
+				r.Error = err.Error()
+			case itemErrs[idx[i]] != nil:
+				r.Error = itemErrs[idx[i]].Error()
+			case users[idx[i]].UID == "":
+				r.Error = "user not found in LDAP directory"
+			default:
+				r.User = &users[idx[i]]
+			}
+			if err := emit(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runBatch resolves every input and writes one result per line: JSON Lines,
+// a YAML document stream, or tab-separated text. With LDIF, the users found
+// are written as entries and unresolved inputs are reported on stderr. It
+// returns the exit code, which is 1 if any input could not be resolved.
+func runBatch(ctx context.Context, s *ldap_redhat.Searcher, inputs []string, format string) int {
+	out := bufio.NewWriter(os.Stdout)
+	var yamlEnc *yaml.Encoder
+	if format == formatYAML {
+		yamlEnc = yaml.NewEncoder(out)
+		yamlEnc.SetIndent(2)
+	}
+	ldifEnc := ldif.NewEncoder(out)
+
+	failed := 0
+	err := resolveBatch(ctx, s, inputs, func(r batchResult) error {
+		if r.Error != "" {
+			failed++
+		}
+		switch format {
+		case formatJSON:
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(out, "%s\n", data)
+			return err
+		case formatYAML:
+			return yamlEnc.Encode(r)
+		case formatLDIF:
+			if r.Error != "" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", r.Input, r.Error)
+				return nil
+			}
+			return ldifEnc.Encode(*r.User)
+		default:
+			if r.Error != "" {
+				_, err := fmt.Fprintf(out, "%s\terror: %s\n", r.Input, r.Error)
+				return err
+			}
+			u := r.User
+			_, err := fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n", r.Input, u.UID, u.Email, u.DisplayName, u.Title)
+			return err
+		}
+	})
+	if err == nil && yamlEnc != nil {
+		err = yamlEnc.Close()
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write result: %v\n", err)
+		return 1
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d identifiers could not be resolved\n", failed, len(inputs))
+		return 1
+	}
+	return 0
+}
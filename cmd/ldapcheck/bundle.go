@@ -0,0 +1,135 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// bundleVersions records the versions of everything that went into the binary
+type bundleVersions struct {
+	Build   ldap_redhat.VersionInfo `json:"build"`
+	OS      string                  `json:"os"`
+	Arch    string                  `json:"arch"`
+	Modules map[string]string       `json:"modules,omitempty"`
+}
+
+// bundleConfig is the redacted effective configuration and its sources
+type bundleConfig struct {
+	Environment string             `json:"environment"`
+	Config      ldap_redhat.Config `json:"config"`
+	EnvVars     map[string]string  `json:"env_vars"`
+}
+
+// writeSupportBundle writes a gzipped tarball with the doctor results, the
+// redacted configuration, version information and an error log.
+func writeSupportBundle(path string, config ldap_redhat.Config, results []checkResult, started time.Time) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create support bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"checks.json", results},
+		{"config.json", bundleConfig{
+			Environment: ldap_redhat.GetEnvironment(),
+			Config:      config.Redacted(),
+			EnvVars:     redactedEnv(),
+		}},
+		{"versions.json", collectVersions()},
+	}
+	for _, file := range files {
+		data, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+		if err := addBundleFile(tw, file.name, append(data, '\n'), started); err != nil {
+			return err
+		}
+	}
+	if err := addBundleFile(tw, "errors.log", errorLog(results, started), started); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write support bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write support bundle: %w", err)
+	}
+	return f.Close()
+}
+
+func addBundleFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s to support bundle: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to support bundle: %w", name, err)
+	}
+	return nil
+}
+
+// redactedEnv returns the LDAP_* environment, with anything that looks like a
+// secret replaced
+func redactedEnv() map[string]string {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "LDAP_") && name != "ENV" {
+			continue
+		}
+		upper := strings.ToUpper(name)
+		if (strings.Contains(upper, "PASSWORD") || strings.Contains(upper, "SECRET") || strings.Contains(upper, "TOKEN")) &&
+			!strings.HasSuffix(upper, "_FILE") {
+			value = "REDACTED"
+		}
+		env[name] = value
+	}
+	return env
+}
+
+func collectVersions() bundleVersions {
+	v := bundleVersions{
+		Build: ldap_redhat.BuildInfo(),
+		OS:    runtime.GOOS,
+		Arch:  runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		v.Modules = map[string]string{}
+		for _, dep := range info.Deps {
+			v.Modules[dep.Path] = dep.Version
+		}
+	}
+	return v
+}
+
+// errorLog lists the failed checks, one per line
+func errorLog(results []checkResult, started time.Time) []byte {
+	var lines []string
+	for _, r := range results {
+		if r.Status == "fail" {
+			lines = append(lines, fmt.Sprintf("%s %s: %s", started.UTC().Format(time.RFC3339), r.Name, r.Detail))
+		}
+	}
+	if len(lines) == 0 {
+		return []byte("no errors\n")
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// command is an ldapcheck subcommand
+type command struct {
+	name    string
+	args    string // argument synopsis shown in usage
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"user", "[-file users.txt] <uid_or_email... | ->", "look up users", runUser},
+	{"groups", "<uid_or_email>", "list the groups a user belongs to", runGroups},
+	{"manager-chain", "<uid_or_email>", "list a user's managers up to the top", runManagerChain},
+	{"members", "<group>", "list the UIDs of a group's members", runMembers},
+	{"export", "[-cost-center id] [filter]", "write matching users to CSV", runExport},
+	{"serve", "[-listen :8080] [-token-file path | -clients path | -no-auth]", "serve user and group lookups over HTTP", runServe},
+	{"info", "", "show the server's capabilities from its root DSE", runInfo},
+	{"doctor", "", "check configuration and connectivity", runDoctor},
+	{"support-bundle", "[-o file.tar.gz]", "package doctor results for an issue", runSupportBundle},
+	{"config", "schema", "list every YAML key, environment variable and default", runConfig},
+	{"snapshot", "diff <old.jsonl> <new.jsonl>", "compare two snapshot files offline", runSnapshot},
+	{"version", "", "print version information", runVersion},
+}
+
+// newFlagSet returns a flag set for a subcommand with the shared -o flag
+func newFlagSet(name, args string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	output := formatValue(formatText)
+	fs.Var(&output, "o", "output format: text, json or yaml")
+	fs.Var(&output, "output", "same as -o")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: ldapcheck %s [-o text|json|yaml] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs, (*string)(&output)
+}
+
+// oneArg returns the single positional argument of fs, or exits with usage
+func oneArg(fs *flag.FlagSet) string {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return fs.Arg(0)
+}
+
+// resolveUID returns the UID for any identifier argument
+func resolveUID(ctx context.Context, s *ldap_redhat.Searcher, input string) (string, error) {
+	id, err := ldap_redhat.ParseIdentifier(input)
+	if err != nil {
+		return "", err
+	}
+	if id.Type == ldap_redhat.IDTUID {
+		return id.Value, nil
+	}
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return user.UID, nil
+}
+
+// runGroups lists the groups a user belongs to
+func runGroups(args []string) int {
+	fs, output := newFlagSet("groups", "<uid_or_email>")
+	over := addOverrideFlags(fs)
+	fs.Parse(args)
+	input := oneArg(fs)
+	ctx, cancel := over.context()
+	defer cancel()
+
+	s := over.openSearcher()
+	defer s.Close()
+
+	uid, err := resolveUID(ctx, s, input)
+	if err != nil {
+		log.Fatalf("User lookup failed: %v", err)
+	}
+	groups, err := s.GetUserGroups(ctx, uid)
+	if err != nil {
+		log.Fatalf("Group lookup failed: %v", err)
+	}
+	err = writeResult(*output, groups, func(w io.Writer) {
+		for _, g := range groups {
+			if g.Description != "" {
+				fmt.Fprintf(w, "%s\t%s\n", g.Name, g.Description)
+			} else {
+				fmt.Fprintln(w, g.Name)
+			}
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	return 0
+}
+
+// runManagerChain prints the management chain above a user
+func runManagerChain(args []string) int {
+	fs, output := newFlagSet("manager-chain", "<uid_or_email>")
+	over := addOverrideFlags(fs)
+	fs.Parse(args)
+	input := oneArg(fs)
+	ctx, cancel := over.context()
+	defer cancel()
+
+	s := over.openSearcher()
+	defer s.Close()
+
+	uid, err := resolveUID(ctx, s, input)
+	if err != nil {
+		log.Fatalf("User lookup failed: %v", err)
+	}
+	chain, err := s.ManagerChain(ctx, uid)
+	if err != nil {
+		log.Fatalf("Manager chain lookup failed: %v", err)
+	}
+	err = writeResult(*output, chain, func(w io.Writer) {
+		fmt.Fprintln(w, uid)
+		for i, m := range chain {
+			fmt.Fprintf(w, "%s└ %s\t%s\t%s\n", strings.Repeat("  ", i), m.UID, m.DisplayName, m.Title)
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	return 0
+}
+
+// runMembers lists the members of a group
+func runMembers(args []string) int {
+	fs, output := newFlagSet("members", "<group>")
+	over := addOverrideFlags(fs)
+	fs.Parse(args)
+	group := oneArg(fs)
+	ctx, cancel := over.context()
+	defer cancel()
+
+	s := over.openSearcher()
+	defer s.Close()
+
+	members, err := s.GetGroupMembers(ctx, group)
+	if err != nil {
+		log.Fatalf("Group lookup failed: %v", err)
+	}
+	err = writeResult(*output, members, func(w io.Writer) {
+		for _, uid := range members {
+			fmt.Fprintln(w, uid)
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	return 0
+}
+
+// runConfig handles the config subcommands; only "schema" exists today
+func runConfig(args []string) int {
+	fs, output := newFlagSet("config", "schema")
+	fs.Parse(args)
+	if oneArg(fs) != "schema" {
+		fs.Usage()
+		return 2
+	}
+
+	schema := ldap_redhat.ConfigSchema()
+	err := writeResult(*output, schema, func(w io.Writer) {
+		fmt.Fprintf(w, "Settings are read from config.yaml under environments.<%s>, then from\n", ldap_redhat.GetEnvironment())
+		fmt.Fprintln(w, "the environment for anything YAML leaves empty (LDAP_ENV selects the environment).")
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "YAML KEY\tENV\tTYPE\tDEFAULT\tDESCRIPTION")
+		for _, f := range schema {
+			key := f.YAML
+			if key == "" {
+				key = "(Config." + f.Field + ")"
+			}
+			env := "-"
+			if len(f.Env) > 0 {
+				env = strings.Join(f.Env, ", ")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", key, env, f.Type, f.Default, f.Description)
+		}
+		tw.Flush()
+	})
+	if err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	return 0
+}
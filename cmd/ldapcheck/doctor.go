@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// dialTimeout bounds the DNS and TCP checks for each server
+const dialTimeout = 5 * time.Second
+
+// checkResult is the outcome of one doctor check
+type checkResult struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"` // "ok", "fail" or "skip"
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// runDoctorChecks checks configuration, name resolution, reachability, TLS +
+// bind, a root DSE read, the bound identity and that the server schema
+// defines the attributes requested, in that order. Connection checks are
+// skipped once an earlier step has failed.
+func runDoctorChecks(ctx context.Context, config ldap_redhat.Config) []checkResult {
+	var results []checkResult
+	run := func(name string, fn func() (string, error)) bool {
+		start := time.Now()
+		detail, err := fn()
+		r := checkResult{Name: name, Status: "ok", Detail: detail, Duration: time.Since(start)}
+		if err != nil {
+			r.Status, r.Detail = "fail", err.Error()
+		}
+		results = append(results, r)
+		return err == nil
+	}
+	skip := func(name, reason string) {
+		results = append(results, checkResult{Name: name, Status: "skip", Detail: reason})
+	}
+
+	ok := run("config", func() (string, error) {
+		if err := config.Validate(); err != nil {
+			return "", err
+		}
+		if _, err := ldap_redhat.FeatureGatesFromEnv(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("environment %s, %d server(s)", ldap_redhat.GetEnvironment(), len(config.LdapServers)), nil
+	})
+	for _, path := range []string{config.CAFile, config.ClientCertFile, config.ClientKeyFile, config.SnapshotFile, os.Getenv("LDAP_PASSWORD_FILE")} {
+		if path == "" {
+			continue
+		}
+		secret := path == config.ClientKeyFile || path == os.Getenv("LDAP_PASSWORD_FILE")
+		run("file "+path, func() (string, error) {
+			info, err := os.Stat(path)
+			if err != nil {
+				return "", err
+			}
+			if secret {
+				if err := ldap_redhat.CheckSecretFile(path); err != nil {
+					return "", err
+				}
+			}
+			return info.Mode().String(), nil
+		})
+	}
+
+	for _, server := range config.LdapServers {
+		if path, err := ldap_redhat.LDAPISocketPath(server); err == nil {
+			run("socket "+path, func() (string, error) {
+				conn, err := net.DialTimeout("unix", path, dialTimeout)
+				if err != nil {
+					return "", err
+				}
+				defer conn.Close()
+				return "reachable", nil
+			})
+			continue
+		}
+		host, port, err := serverAddress(server)
+		if !ok || err != nil {
+			reason := "configuration check failed"
+			if err != nil {
+				reason = err.Error()
+			}
+			skip("dns "+server, reason)
+			continue
+		}
+		resolved := run("dns "+host, func() (string, error) {
+			dnsCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+			defer cancel()
+			addrs, err := net.DefaultResolver.LookupHost(dnsCtx, host)
+			return strings.Join(addrs, ", "), err
+		})
+		if !resolved {
+			skip("tcp "+net.JoinHostPort(host, port), "name resolution failed")
+			continue
+		}
+		run("tcp "+net.JoinHostPort(host, port), func() (string, error) {
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), dialTimeout)
+			if err != nil {
+				return "", err
+			}
+			defer conn.Close()
+			return conn.RemoteAddr().String(), nil
+		})
+	}
+
+	if !ok {
+		skip("connect", "configuration check failed")
+		return results
+	}
+	var searcher *ldap_redhat.Searcher
+	connected := run("connect", func() (string, error) {
+		var err error
+		searcher, err = ldap_redhat.NewSearcher(config)
+		if err != nil {
+			return "", err
+		}
+		if searcher.Connection() == nil {
+			return "", fmt.Errorf("serving from offline snapshot, directory unreachable")
+		}
+		if config.AuthMode == ldap_redhat.AuthExternal {
+			return "bound with SASL EXTERNAL", nil
+		}
+		if config.Username == "" {
+			return "anonymous", nil
+		}
+		return "bound as " + config.Username, nil
+	})
+	if !connected {
+		skip("ping", "connect failed")
+		return results
+	}
+	defer searcher.Close()
+	if !run("ping", func() (string, error) {
+		return "", searcher.Ping(ctx)
+	}) {
+		skip("whoami", "ping failed")
+		return results
+	}
+	run("whoami", func() (string, error) {
+		id, err := searcher.WhoAmI(ctx)
+		if err != nil {
+			return "", err
+		}
+		detail := describeIdentity(id)
+		if expiring(id.ServerCertNotAfter, id.ServerCertExpiresIn) || expiring(id.ClientCertNotAfter, id.ClientCertExpiresIn) {
+			return "", fmt.Errorf("%s: certificate expires within %s", detail, formatValidity(certExpiryWarning))
+		}
+		return detail, nil
+	})
+	run("schema", func() (string, error) {
+		warnings, err := searcher.ValidateAttributes(ctx, nil)
+		if err != nil {
+			return "", err
+		}
+		if len(warnings) > 0 {
+			var missing []string
+			for _, w := range warnings {
+				missing = append(missing, w.Attributes...)
+			}
+			return "", fmt.Errorf("server schema does not define %s", strings.Join(missing, ", "))
+		}
+		return "all user attributes defined", nil
+	})
+	return results
+}
+
+// certExpiryWarning is how soon before a certificate expires the whoami
+// check fails, so rotation problems surface before the connection does
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// describeIdentity summarizes a bind identity for the whoami check
+func describeIdentity(id ldap_redhat.BindIdentity) string {
+	parts := []string{"anonymous"}
+	if !id.Anonymous() {
+		parts[0] = id.AuthzID
+	}
+	if id.TLS {
+		parts = append(parts, id.TLSVersion)
+	}
+	if !id.ServerCertNotAfter.IsZero() {
+		parts = append(parts, "server certificate expires in "+formatValidity(id.ServerCertExpiresIn))
+	}
+	if !id.ClientCertNotAfter.IsZero() {
+		parts = append(parts, "client certificate expires in "+formatValidity(id.ClientCertExpiresIn))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// expiring reports whether a certificate with that expiry has less than
+// certExpiryWarning left
+func expiring(notAfter time.Time, left time.Duration) bool {
+	return !notAfter.IsZero() && left < certExpiryWarning
+}
+
+// formatValidity renders remaining certificate validity in days, or hours
+// when less than a day is left
+func formatValidity(d time.Duration) string {
+	if d < 24*time.Hour {
+		return d.Round(time.Hour).String()
+	}
+	return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+}
+
+// serverAddress returns the host and port of an LDAP URL, defaulting the port
+// from the scheme
+func serverAddress(server string) (string, string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid LDAP URL %s: %w", server, err)
+	}
+	if u.Hostname() == "" {
+		return "", "", fmt.Errorf("LDAP URL %s has no host", server)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "389"
+		if strings.EqualFold(u.Scheme, "ldaps") {
+			port = "636"
+		}
+	}
+	return u.Hostname(), port, nil
+}
+
+// healthy reports whether every check that ran passed
+func healthy(results []checkResult) bool {
+	for _, r := range results {
+		if r.Status == "fail" {
+			return false
+		}
+	}
+	return true
+}
+
+// printResults writes a human-readable check report
+func printResults(w io.Writer, results []checkResult) {
+	for _, r := range results {
+		mark := "OK  "
+		switch r.Status {
+		case "fail":
+			mark = "FAIL"
+		case "skip":
+			mark = "SKIP"
+		}
+		// Align the lines of multi-line details, such as every config problem
+		detail := strings.ReplaceAll(r.Detail, "\n", "\n"+strings.Repeat(" ", 58))
+		fmt.Fprintf(w, "[%s] %-40s %8s  %s\n", mark, r.Name, r.Duration.Round(time.Millisecond), detail)
+	}
+}
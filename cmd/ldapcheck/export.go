@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/ldif"
+)
+
+// runExport writes the users matching a filter, a cost center or both to CSV
+// or LDIF, optionally masked
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "", "output file (default: stdout)")
+	columns := fs.String("columns", strings.Join(ldap_redhat.DefaultCSVColumns, ","), "comma-separated columns to write")
+	costCenter := fs.String("cost-center", "", "only users in this cost center")
+	terminated := fs.Bool("terminated", false, "include terminated users")
+	modifiedSince := fs.String("modified-since", "", "only users modified since a date (2006-01-02 or RFC 3339) or for a duration (e.g. 24h)")
+	asLDIF := fs.Bool("ldif", false, "write LDIF (RFC 2849) instead of CSV; -columns is ignored")
+	maskingPolicy := fs.String("masking-policy", "", "YAML masking policy applied to every exported user")
+	over := addOverrideFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ldapcheck export [-o file.csv] [-columns uid,email,...] [-ldif] [-masking-policy file] [-cost-center id] [-modified-since 24h] [filter]")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nColumns: %s\n", strings.Join(ldap_redhat.CSVColumns(), ", "))
+	}
+	positional := parseInterleaved(fs, args)
+	if len(positional) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var clauses []string
+	if len(positional) == 1 {
+		clauses = append(clauses, positional[0])
+	}
+	if *costCenter != "" {
+		clauses = append(clauses, fmt.Sprintf("(rhatCostCenter=%s)", ldap.EscapeFilter(*costCenter)))
+	}
+	if *modifiedSince != "" {
+		since, err := parseSince(*modifiedSince, time.Now())
+		if err != nil {
+			log.Fatalf("Invalid -modified-since: %v", err)
+		}
+		clauses = append(clauses, ldap_redhat.ModifiedSinceFilter(since))
+	}
+	filter := ""
+	switch len(clauses) {
+	case 0:
+	case 1:
+		filter = clauses[0]
+	default:
+		filter = "(&" + strings.Join(clauses, "") + ")"
+	}
+
+	var cols []string
+	for _, c := range strings.Split(*columns, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	var policy ldap_redhat.MaskingPolicy
+	if *maskingPolicy != "" {
+		var err error
+		if policy, err = ldap_redhat.LoadMaskingPolicy(*maskingPolicy); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// an unknown column or a policy the output cannot apply, before connecting
+	if *asLDIF {
+		if err := ldif.WriteLDIFMasked(io.Discard, nil, policy); err != nil {
+			log.Fatal(err)
+		}
+	} else if err := ldap_redhat.WriteCSVMasked(io.Discard, nil, cols, policy); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := over.context()
+	defer cancel()
+	s := over.openSearcher()
+	defer s.Close()
+
+	users, err := s.SearchUsers(ctx, filter)
+	if err != nil {
+		log.Fatalf("Search failed: %v", err)
+	}
+	if !*terminated {
+		active := users[:0]
+		for _, u := range users {
+			if u.IsActive() {
+				active = append(active, u)
+			}
+		}
+		users = active
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *asLDIF {
+		err = ldif.WriteLDIFMasked(w, users, policy)
+	} else {
+		err = ldap_redhat.WriteCSVMasked(w, users, cols, policy)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write export: %v", err)
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "%d users written to %s\n", len(users), *output)
+	}
+	return 0
+}
+
+// parseSince parses a -modified-since value: a duration before now, a date
+// or an RFC 3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a duration, a date or an RFC 3339 time", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// oidNames names the controls, extended operations and features commonly
+// listed in root DSEs
+var oidNames = map[string]string{
+	"1.2.840.113556.1.4.319":     "paged results",
+	"1.2.840.113556.1.4.473":     "server-side sort",
+	"1.2.840.113556.1.4.474":     "server-side sort response",
+	"2.16.840.1.113730.3.4.9":    "virtual list view",
+	"2.16.840.1.113730.3.4.2":    "ManageDsaIT",
+	"2.16.840.1.113730.3.4.3":    "persistent search",
+	"2.16.840.1.113730.3.4.18":   "proxied authorization v2",
+	"1.3.6.1.4.1.4203.1.9.1.1":   "content synchronization",
+	"1.3.6.1.4.1.21008.108.63.1": "session tracking",
+	"1.3.6.1.4.1.4203.1.10.1":    "subentries",
+	"1.3.6.1.1.12":               "assertion",
+	"1.3.6.1.1.13.1":             "pre-read",
+	"1.3.6.1.1.13.2":             "post-read",
+	"1.3.6.1.4.1.42.2.27.8.5.1":  "password policy",
+	"1.3.6.1.4.1.1466.20037":     "StartTLS",
+	"1.3.6.1.4.1.4203.1.11.1":    "password modify",
+	"1.3.6.1.4.1.4203.1.11.3":    "Who am I?",
+	"1.3.6.1.1.8":                "cancel",
+	"1.3.6.1.4.1.4203.1.5.1":     "all operational attributes",
+	"1.3.6.1.4.1.4203.1.5.2":     "attributes by object class",
+	"1.3.6.1.4.1.4203.1.5.3":     "absolute true and false filters",
+	"1.3.6.1.1.14":               "modify-increment",
+}
+
+// runInfo prints the server's capabilities from its root DSE
+func runInfo(args []string) int {
+	fs, output := newFlagSet("info", "")
+	over := addOverrideFlags(fs)
+	fs.Parse(args)
+	ctx, cancel := over.context()
+	defer cancel()
+
+	s := over.openSearcher()
+	defer s.Close()
+
+	dse, err := s.RootDSE(ctx)
+	if err != nil {
+		log.Fatalf("Root DSE read failed: %v", err)
+	}
+	err = writeResult(*output, dse, func(w io.Writer) { printRootDSE(w, dse) })
+	if err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	return 0
+}
+
+// printRootDSE writes the human-readable summary of a root DSE
+func printRootDSE(w io.Writer, dse ldap_redhat.RootDSE) {
+	fmt.Fprintf(w, "Server: %s\n", dse.Server)
+	if dse.VendorName != "" {
+		fmt.Fprintf(w, "Vendor: %s\n", strings.TrimSpace(dse.VendorName+" "+dse.VendorVersion))
+	}
+	fmt.Fprintf(w, "LDAP versions: %s\n", orNone(dse.SupportedLDAPVersions))
+	fmt.Fprintf(w, "Naming contexts: %s\n", orNone(dse.NamingContexts))
+	if dse.SubschemaSubentry != "" {
+		fmt.Fprintf(w, "Subschema: %s\n", dse.SubschemaSubentry)
+	}
+	fmt.Fprintf(w, "SASL mechanisms: %s\n", orNone(dse.SupportedSASLMechanisms))
+	fmt.Fprintf(w, "Paged results: %s, StartTLS: %s\n", yesNo(dse.SupportsPaging()), yesNo(dse.SupportsStartTLS()))
+	printOIDs(w, "Controls", dse.SupportedControls)
+	printOIDs(w, "Extended operations", dse.SupportedExtensions)
+	printOIDs(w, "Features", dse.SupportedFeatures)
+}
+
+// printOIDs lists oids under heading, with their names where known
+func printOIDs(w io.Writer, heading string, oids []string) {
+	if len(oids) == 0 {
+		fmt.Fprintf(w, "%s: none\n", heading)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", heading)
+	for _, oid := range oids {
+		if name, ok := oidNames[oid]; ok {
+			fmt.Fprintf(w, "  %s (%s)\n", oid, name)
+		} else {
+			fmt.Fprintf(w, "  %s\n", oid)
+		}
+	}
+}
+
+func orNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -2,54 +2,287 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/ldif"
+	_ "github.com/openshift-eng/go-ldap-redhat/vault" // registers the vault: credentials provider
 )
 
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: ldapcheck <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-45s %s\n", c.name+" "+c.args, c.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'ldapcheck <command> -h' for the flags of a command. For compatibility,")
+	fmt.Fprintln(os.Stderr, "'ldapcheck <uid_or_email>' is the same as 'ldapcheck user <uid_or_email>'.")
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: ldapcheck <uid_or_email>")
+	if len(os.Args) < 2 && !stdinIsPiped() {
+		usage()
 		os.Exit(1)
 	}
+	if len(os.Args) >= 2 {
+		switch os.Args[1] {
+		case "-h", "-help", "--help", "help":
+			usage()
+			return
+		}
+		for _, c := range commands {
+			if os.Args[1] == c.name {
+				os.Exit(c.run(os.Args[2:]))
+			}
+		}
+	}
+	// Anything else is a user lookup, as before subcommands existed
+	os.Exit(runUser(os.Args[1:]))
+}
+
+// runUser looks up one or more users by UID or email
+func runUser(args []string) int {
+	fs, output := newFlagSet("user", "[-file users.txt] [uid_or_email... | -]")
+	version := fs.Bool("version", false, "print version information (same as 'ldapcheck version')")
+	file := fs.String("file", "", "read newline-delimited UIDs/emails from this file")
+	asLDIF := fs.Bool("ldif", false, "write the users found as LDIF (RFC 2849) instead of -o")
+	photo := fs.String("photo", "", "also save the user's photo to this file, e.g. out.jpg")
+	over := addOverrideFlags(fs)
+	fs.Parse(args)
+	if *asLDIF {
+		*output = formatLDIF
+	}
 
-	uid := os.Args[1]
-	ctx := context.Background()
+	if *version {
+		return runVersion([]string{"-o", *output})
+	}
 
-	// Create searcher using default configuration (YAML + env vars)
-	s, err := ldap_redhat.NewSearcherWithDefaults()
+	inputs, batch, err := collectInputs(fs.Args(), *file)
 	if err != nil {
-		log.Fatalf("Failed to create searcher: %v", err)
+		log.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		fs.Usage()
+		return 1
 	}
+	ctx, cancel := over.context()
+	defer cancel()
+
+	s := over.openSearcher()
 	defer s.Close()
 
-	fmt.Printf("LDAP connection successful! Searching for: %s\n", uid)
+	if batch {
+		if *photo != "" {
+			log.Fatal("-photo needs a single identifier")
+		}
+		return runBatch(ctx, s, inputs, *output)
+	}
+
+	uid := inputs[0]
+	progress(*output, "LDAP connection successful! Searching for: %s\n", uid)
 
 	// Determine search type
-	var id ldap_redhat.Identifier
-	if strings.Contains(uid, "@") {
-		id = ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: uid}
-		fmt.Printf("Searching by email: %s\n", uid)
-	} else {
-		id = ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}
-		fmt.Printf("Searching by UID: %s\n", uid)
+	id, err := ldap_redhat.ParseIdentifier(uid)
+	if err != nil {
+		log.Fatal(err)
 	}
+	progress(*output, "Searching by %s: %s\n", identifierLabel(id.Type), id.Value)
 
-	// Search by UID or email
 	user, err := s.GetUser(ctx, id)
 	if err != nil {
 		log.Fatalf("User lookup failed: %v", err)
 	}
 
-	fmt.Printf("Found user: %s (%s)\n", user.UID, user.Email)
-	fmt.Printf("Name: %s %s\n", user.DisplayName, user.Surname)
-	fmt.Printf("Title: %s\n", user.Title)
-	fmt.Printf("Location: %s\n", user.RhatLocation)
-	fmt.Printf("Cost Center: %s\n", user.CostCenter)
-	if user.RhatTermDate != "" {
-		fmt.Printf("  Terminated: %s\n", user.RhatTermDate)
+	if *output == formatLDIF {
+		err = ldif.WriteLDIF(os.Stdout, []ldap_redhat.UserRecord{user})
+	} else {
+		err = writeResult(*output, user, func(w io.Writer) { printUser(w, user) })
+	}
+	if err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	if *photo != "" {
+		savePhoto(ctx, s, id, *photo)
+	}
+	return 0
+}
+
+// savePhoto writes the photo of the user id identifies to path
+func savePhoto(ctx context.Context, s *ldap_redhat.Searcher, id ldap_redhat.Identifier, path string) {
+	p, err := s.GetUserPhoto(ctx, id)
+	if err != nil {
+		log.Fatalf("Photo lookup failed: %v", err)
+	}
+	if err := os.WriteFile(path, p.Data, 0o644); err != nil {
+		log.Fatalf("Failed to save photo: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Photo saved to %s (%s, %d bytes)\n", path, p.ContentType, len(p.Data))
+}
+
+// identifierLabel names an identifier type in progress messages
+func identifierLabel(idType int) string {
+	switch idType {
+	case ldap_redhat.IDTEmail:
+		return "email"
+	case ldap_redhat.IDTUUID:
+		return "UUID"
+	case ldap_redhat.IDTEmployeeNumber:
+		return "employee number"
+	case ldap_redhat.IDTKerberos:
+		return "Kerberos principal"
+	}
+	return "UID"
+}
+
+// collectInputs gathers identifiers from the arguments, --file and stdin
+// ("-", or no arguments with stdin piped). batch is false only for a single
+// identifier given as an argument, which keeps the detailed output.
+func collectInputs(args []string, file string) (inputs []string, batch bool, err error) {
+	readStdin := len(args) == 0 && file == "" && stdinIsPiped()
+	for _, arg := range args {
+		if arg == "-" {
+			readStdin = true
+			continue
+		}
+		inputs = append(inputs, arg)
+	}
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to open %s: %w", file, err)
+		}
+		defer f.Close()
+		lines, err := readIdentifiers(f)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		inputs = append(inputs, lines...)
+	}
+	if readStdin {
+		lines, err := readIdentifiers(os.Stdin)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		inputs = append(inputs, lines...)
+	}
+	batch = file != "" || readStdin || len(inputs) > 1
+	return inputs, batch, nil
+}
+
+// printUser writes the human-readable summary of a user
+func printUser(w io.Writer, user ldap_redhat.UserRecord) {
+	fmt.Fprintf(w, "Found user: %s (%s)\n", user.UID, user.Email)
+	fmt.Fprintf(w, "Name: %s %s\n", user.DisplayName, user.Surname)
+	fmt.Fprintf(w, "Title: %s\n", user.Title)
+	fmt.Fprintf(w, "Location: %s\n", user.RhatLocation)
+	fmt.Fprintf(w, "Cost Center: %s\n", user.CostCenter)
+	if user.ManagerUID != "" {
+		fmt.Fprintf(w, "Manager: %s\n", user.ManagerUID)
+	}
+	if !user.HireDate.IsZero() {
+		fmt.Fprintf(w, "Hire Date: %s\n", user.HireDate.Format("2006-01-02"))
+	}
+	if !user.IsActive() {
+		fmt.Fprintf(w, "  Terminated: %s\n", formatDate(user.TermDate, user.RhatTermDate))
+	}
+	if meta := user.Meta(); meta.Server != "" {
+		fmt.Fprintf(w, "Retrieved: %s from %s in %s\n", meta.RetrievedAt.Format(time.RFC3339), meta.Server, meta.Duration.Round(time.Millisecond))
+	}
+}
+
+// runVersion prints the library build metadata
+func runVersion(args []string) int {
+	fs, output := newFlagSet("version", "")
+	fs.Parse(args)
+
+	info := ldap_redhat.BuildInfo()
+	err := writeResult(*output, info, func(w io.Writer) {
+		fmt.Fprintf(w, "ldapcheck %s\n", info.Version)
+		commit := info.Commit
+		if commit == "" {
+			commit = "unknown"
+		} else if info.Modified {
+			commit += " (modified)"
+		}
+		fmt.Fprintf(w, "  commit:   %s\n", commit)
+		fmt.Fprintf(w, "  go:       %s\n", info.GoVersion)
+		fmt.Fprintf(w, "  go-ldap:  %s\n", info.LDAPVersion)
+		features := "none"
+		if len(info.Features) > 0 {
+			features = strings.Join(info.Features, ", ")
+		}
+		fmt.Fprintf(w, "  features: %s\n", features)
+		var enabled []string
+		for _, f := range ldap_redhat.KnownFeatures() {
+			if info.FeatureGates[f] {
+				enabled = append(enabled, string(f))
+			}
+		}
+		gates := "none"
+		if len(enabled) > 0 {
+			gates = strings.Join(enabled, ", ")
+		}
+		fmt.Fprintf(w, "  gates:    %s\n", gates)
+	})
+	if err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	return 0
+}
+
+// runDoctor runs the connectivity checks and prints a report
+func runDoctor(args []string) int {
+	fs, output := newFlagSet("doctor", "")
+	over := addOverrideFlags(fs)
+	fs.Parse(args)
+
+	ctx, cancel := over.context()
+	defer cancel()
+	results := runDoctorChecks(ctx, over.config())
+	if err := writeResult(*output, results, func(w io.Writer) { printResults(w, results) }); err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	if !healthy(results) {
+		return 1
+	}
+	return 0
+}
+
+// runSupportBundle runs the doctor checks and packages the results with the
+// redacted configuration and versions into a tarball to attach to issues
+func runSupportBundle(args []string) int {
+	started := time.Now()
+	fs := flag.NewFlagSet("support-bundle", flag.ExitOnError)
+	output := fs.String("o", fmt.Sprintf("ldapcheck-support-%s.tar.gz", started.UTC().Format("20060102-150405")), "output file")
+	over := addOverrideFlags(fs)
+	fs.Parse(args)
+
+	ctx, cancel := over.context()
+	defer cancel()
+	config := over.config()
+	results := runDoctorChecks(ctx, config)
+	printResults(os.Stdout, results)
+	if err := writeSupportBundle(*output, config, results, started); err != nil {
+		log.Printf("Failed to write support bundle: %v", err)
+		return 1
+	}
+	fmt.Printf("Support bundle written to %s (passwords redacted)\n", *output)
+	return 0
+}
+
+// formatDate renders a parsed date as a calendar date, falling back to the raw
+// LDAP value if it could not be parsed
+func formatDate(t time.Time, raw string) string {
+	if t.IsZero() {
+		return raw
 	}
+	return t.Format("2006-01-02")
 }
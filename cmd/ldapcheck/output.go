@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by -o
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// formatLDIF is selected by the user command's --ldif flag rather than -o
+const formatLDIF = "ldif"
+
+// checkFormat rejects unknown -o values
+func checkFormat(format string) error {
+	switch format {
+	case formatText, formatJSON, formatYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (expected text, json or yaml)", format)
+}
+
+// formatValue is a flag.Value that only accepts the known output formats
+type formatValue string
+
+func (f *formatValue) String() string { return string(*f) }
+
+func (f *formatValue) Set(value string) error {
+	if err := checkFormat(value); err != nil {
+		return err
+	}
+	*f = formatValue(value)
+	return nil
+}
+
+// writeResult writes v to stdout as JSON or YAML, or calls text for the
+// human-readable format
+func writeResult(format string, v any, text func(w io.Writer)) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatYAML:
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		text(os.Stdout)
+		return nil
+	}
+}
+
+// progress prints status chatter in text mode only, so JSON and YAML output
+// can be piped as-is
+func progress(format, msg string, args ...any) {
+	if format == formatText {
+		fmt.Printf(msg, args...)
+	}
+}
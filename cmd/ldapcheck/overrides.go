@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// overrides are connection settings given as flags, which take precedence over
+// config.yaml and LDAP_* variables so a problem can be narrowed down to the
+// configuration or the server without editing either
+type overrides struct {
+	servers string
+	baseDN  string
+	scope   string
+	bindDN  string
+	timeout time.Duration
+}
+
+// addOverrideFlags registers the override flags on fs
+func addOverrideFlags(fs *flag.FlagSet) *overrides {
+	o := &overrides{}
+	fs.StringVar(&o.servers, "server", "", "LDAP server URL(s), comma-separated, instead of the configured ones")
+	fs.StringVar(&o.baseDN, "base-dn", "", "search base DN instead of the configured one")
+	fs.StringVar(&o.scope, "scope", "", "search scope below the base DN: base, one or sub (default: the configured one)")
+	fs.StringVar(&o.bindDN, "bind-dn", "", "bind DN instead of the configured one (the configured password is used)")
+	fs.DurationVar(&o.timeout, "timeout", 0, fmt.Sprintf(
+		"give up after this long, e.g. 10s (default: the configured dial_timeout, bind_timeout and search_timeout, %s, %s and %s unless set)",
+		ldap_redhat.DefaultDialTimeout, ldap_redhat.DefaultBindTimeout, ldap_redhat.DefaultSearchTimeout))
+	return o
+}
+
+// config returns the default configuration with the overrides applied
+func (o *overrides) config() ldap_redhat.Config {
+	config := ldap_redhat.LoadDefaultConfig()
+	if o.servers != "" {
+		config.LdapServers = nil
+		for _, server := range strings.Split(o.servers, ",") {
+			if server = strings.TrimSpace(server); server != "" {
+				config.LdapServers = append(config.LdapServers, server)
+			}
+		}
+	}
+	if o.baseDN != "" {
+		config.BaseDN = o.baseDN
+	}
+	if o.scope != "" {
+		config.SearchScope = ldap_redhat.SearchScope(o.scope)
+	}
+	if o.bindDN != "" {
+		config.Username = o.bindDN
+	}
+	if o.timeout > 0 {
+		config.DialTimeout = o.timeout
+		config.BindTimeout = o.timeout
+		config.SearchTimeout = o.timeout
+	}
+	return config
+}
+
+// context returns the context for a command. With -timeout it expires at the
+// deadline, which also bounds each dial, bind and search through config, and
+// a watchdog exits once it has passed in case the steps add up.
+func (o *overrides) context() (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	time.AfterFunc(o.timeout+time.Second, func() {
+		log.Printf("Timed out after %s", o.timeout)
+		os.Exit(1)
+	})
+	return context.WithTimeout(context.Background(), o.timeout)
+}
+
+// openSearcher connects using the default configuration (YAML + env vars)
+// with the overrides applied
+func (o *overrides) openSearcher() *ldap_redhat.Searcher {
+	ldap_redhat.SetDefaultConfig(o.config())
+	s, err := ldap_redhat.NewSearcherWithDefaults()
+	if err != nil {
+		log.Fatalf("Failed to create searcher: %v", err)
+	}
+	return s
+}
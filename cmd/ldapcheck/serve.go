@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// serveTokenEnv holds the bearer token clients of ldapcheck serve must send,
+// when -token-file is not given
+const serveTokenEnv = "LDAPCHECK_SERVE_TOKEN"
+
+// serveShutdownTimeout bounds how long in-flight requests may take on exit
+const serveShutdownTimeout = 10 * time.Second
+
+// defaultServePoolSize is the number of LDAP connections of ldapcheck serve
+const defaultServePoolSize = 4
+
+// runServe serves lookups over HTTP from a pool of searchers, each with a
+// connection that a keepalive re-dials when the server drops it
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "HTTP listen address")
+	tokenFile := fs.String("token-file", "", "file holding the bearer token clients must send (default: $"+serveTokenEnv+")")
+	clientsFile := fs.String("clients", "", "YAML file of clients, each with a bearer token and scope claims, and the scope policy resolving the claims")
+	noAuth := fs.Bool("no-auth", false, "serve without a bearer token, e.g. behind an authenticating proxy")
+	whoami := fs.Bool("whoami", false, "serve GET /v1/whoami, the bound identity and certificate expiry of the service")
+	poolSize := fs.Int("pool-size", defaultServePoolSize, "LDAP connections requests are spread over")
+	pingInterval := fs.Duration("ping-interval", 30*time.Second, "how often to check the LDAP connections and re-dial dropped ones")
+	over := addOverrideFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ldapcheck serve [-listen :8080] [-token-file path | -clients path | -no-auth]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var callers map[string]caller
+	switch {
+	case *clientsFile != "" && (*tokenFile != "" || *noAuth):
+		log.Fatalf("-clients cannot be combined with -token-file or -no-auth")
+	case *clientsFile != "":
+		var err error
+		if callers, err = loadServeClients(*clientsFile); err != nil {
+			log.Fatalf("Failed to load clients: %v", err)
+		}
+	default:
+		token := os.Getenv(serveTokenEnv)
+		if *tokenFile != "" {
+			var err error
+			if token, err = ldap_redhat.ReadSecretFileWithPolicy(*tokenFile, ldap_redhat.SecretFileStrict); err != nil {
+				log.Fatalf("Failed to read token file: %v", err)
+			}
+		}
+		if token == "" && !*noAuth {
+			log.Fatalf("No bearer token: set %s, -token-file or -clients, or pass -no-auth to serve without one", serveTokenEnv)
+		}
+		if token != "" {
+			callers = map[string]caller{token: {name: "default"}}
+		}
+	}
+	if *poolSize < 1 {
+		log.Fatalf("-pool-size must be at least 1")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	pool := &searcherPool{}
+	for range *poolSize {
+		pool.searchers = append(pool.searchers, over.openSearcher())
+	}
+	defer pool.Close()
+	go pool.keepalive(ctx, *pingInterval)
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           newServeHandler(pool, serveOptions{callers: callers, whoami: *whoami}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Serving on %s with %d LDAP connections", *listen, *poolSize)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("HTTP server failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// serveOptions configures newServeHandler
+type serveOptions struct {
+	callers map[string]caller // by bearer token; nil serves everyone, unrestricted
+	whoami  bool              // serve GET /v1/whoami
+}
+
+// newServeHandler serves lookups from pool:
+//
+//	GET /livez                  200 while the process runs (no token needed)
+//	GET /readyz                 the Healthz report, 200 when healthy (no token needed)
+//	GET /version                the BuildInfo of the binary (no token needed)
+//	GET /v1/users/{id}          the user a UID, email, UUID, employee number or principal identifies
+//	GET /v1/users/{id}/groups   the groups of that user
+//	GET /v1/whoami              the identity and TLS certificates of the service's binding, with opts.whoami
+//
+// Requests under /v1 must carry "Authorization: Bearer <token>" with the
+// token of one of opts.callers, unless there are none, and are restricted
+// to that caller's scope.
+func newServeHandler(pool *searcherPool, opts serveOptions) http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := ldap_redhat.ParseIdentifier(r.PathValue("id"))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		user, err := pool.get().GetUser(r.Context(), id)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, user)
+	})
+	api.HandleFunc("GET /v1/users/{id}/groups", func(w http.ResponseWriter, r *http.Request) {
+		s := pool.get()
+		uid, err := resolveUID(r.Context(), s, r.PathValue("id"))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		groups, err := s.GetUserGroups(r.Context(), uid)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeAPIJSON(w, http.StatusOK, nonNil(groups))
+	})
+	if opts.whoami {
+		api.HandleFunc("GET /v1/whoami", func(w http.ResponseWriter, r *http.Request) {
+			id, err := pool.get().WhoAmI(r.Context())
+			if err != nil {
+				writeAPIError(w, err)
+				return
+			}
+			writeAPIJSON(w, http.StatusOK, id)
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /livez", pool.searchers[0].LivenessHandler())
+	// The searchers share one configuration, so the first stands for all;
+	// keepalive re-dials any whose connection drops
+	mux.Handle("GET /readyz", pool.searchers[0].ReadinessHandler(0))
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, http.StatusOK, ldap_redhat.BuildInfo())
+	})
+	mux.Handle("/v1/", requireToken(opts.callers, api))
+	return mux
+}
+
+// requireToken rejects requests without the bearer token of one of callers,
+// if there are any, and passes the others on as that caller: recorded as the
+// audit principal and restricted to its scope
+func requireToken(callers map[string]caller, next http.Handler) http.Handler {
+	if len(callers) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		var c caller
+		found := false
+		for token, candidate := range callers {
+			if ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				c, found = candidate, true
+			}
+		}
+		if !found {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ldapcheck"`)
+			writeAPIJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(c.context(r.Context())))
+	})
+}
+
+// apiStatus returns the HTTP status for a lookup error
+func apiStatus(err error) int {
+	switch {
+	case errors.Is(err, ldap_redhat.ErrInvalidIdentifier):
+		return http.StatusBadRequest
+	case errors.Is(err, ldap_redhat.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ldap_redhat.ErrMultipleMatches):
+		return http.StatusConflict
+	case errors.Is(err, ldap_redhat.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ldap_redhat.ErrNotConnected), errors.Is(err, ldap_redhat.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+// writeAPIError writes err as a JSON error with its HTTP status
+func writeAPIError(w http.ResponseWriter, err error) {
+	writeAPIJSON(w, apiStatus(err), map[string]string{"error": err.Error()})
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+const (
+	serveBindDN   = "uid=svc,ou=users,dc=redhat,dc=com"
+	servePassword = "secret"
+)
+
+// newServePool starts an embedded LDAP server seeded with ten generated
+// users, of whom the first two are in the openshift-eng group, and returns a
+// pool of two searchers connected to it
+func newServePool(t *testing.T) *searcherPool {
+	t.Helper()
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(10))
+	srv.AddBind(serveBindDN, servePassword)
+	srv.AddEntry("cn=openshift-eng,ou=adhoc,ou=managedGroups,dc=redhat,dc=com", map[string][]string{
+		"objectClass":  {"top", "groupOfUniqueNames"},
+		"cn":           {"openshift-eng"},
+		"description":  {"OpenShift engineering"},
+		"uniqueMember": {testserver.UserDN(1), testserver.UserDN(2)},
+	})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start embedded LDAP server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	pool := &searcherPool{}
+	for range 2 {
+		s, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+			LdapServers: []string{srv.URL()},
+			Username:    serveBindDN,
+			Password:    servePassword,
+			BaseDN:      "dc=redhat,dc=com",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create searcher: %v", err)
+		}
+		pool.searchers = append(pool.searchers, s)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// get requests path from h with token, if any, and decodes the JSON
+// response into v unless it is nil
+func get(t *testing.T, h http.Handler, path, token string, v any) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if v != nil {
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("GET %s: Content-Type is %q, want application/json", path, ct)
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: invalid JSON %q: %v", path, rec.Body, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestRequireToken(t *testing.T) {
+	pool := newServePool(t)
+	h := newServeHandler(pool, serveOptions{callers: map[string]caller{"s3cret": {name: "default"}}})
+	path := "/v1/users/" + testserver.UserUID(1)
+
+	for _, tt := range []struct {
+		name, token string
+		want        int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "guess", http.StatusUnauthorized},
+		{"prefix", "s3cre", http.StatusUnauthorized},
+		{"correct", "s3cret", http.StatusOK},
+	} {
+		if got := get(t, h, path, tt.token, nil); got != tt.want {
+			t.Errorf("%s token: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// Probes are served without a token
+	if got := get(t, h, "/livez", "", nil); got != http.StatusOK {
+		t.Errorf("GET /livez without a token: got %d, want 200", got)
+	}
+
+	// -no-auth leaves no callers to check against
+	noAuth := newServeHandler(pool, serveOptions{})
+	if got := get(t, noAuth, path, "", nil); got != http.StatusOK {
+		t.Errorf("Without auth: got %d, want 200", got)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	h := newServeHandler(newServePool(t), serveOptions{})
+
+	for _, tt := range []struct {
+		path string
+		want int
+	}{
+		{"/v1/users/nobody", http.StatusNotFound},
+		{"/v1/users/nobody@redhat.com", http.StatusNotFound},
+		{"/v1/users/not%20an%20id!", http.StatusBadRequest},
+		{"/v1/users/nobody@redhat.com/groups", http.StatusNotFound},
+		{"/v1/users/not%20an%20id!/groups", http.StatusBadRequest},
+	} {
+		var body map[string]string
+		if got := get(t, h, tt.path, "", &body); got != tt.want || body["error"] == "" {
+			t.Errorf("GET %s: got %d %v, want %d with an error", tt.path, got, body, tt.want)
+		}
+	}
+	if got := get(t, h, "/v1/whoami", "", nil); got != http.StatusNotFound {
+		t.Errorf("GET /v1/whoami without -whoami: got %d, want 404", got)
+	}
+}
+
+func TestServeUser(t *testing.T) {
+	h := newServeHandler(newServePool(t), serveOptions{whoami: true})
+
+	var user map[string]any
+	if code := get(t, h, "/v1/users/"+testserver.UserUID(2)+"@redhat.com", "", &user); code != http.StatusOK {
+		t.Fatalf("GET user: got %d %v", code, user)
+	}
+	want := map[string]any{
+		"uid":         testserver.UserUID(2),
+		"email":       testserver.UserUID(2) + "@redhat.com",
+		"title":       "Principal Software Engineer",
+		"manager_uid": testserver.UserUID(0),
+		"status":      "active",
+	}
+	for key, value := range want {
+		if user[key] != value {
+			t.Errorf("User %s is %v, want %v", key, user[key], value)
+		}
+	}
+
+	var groups []map[string]any
+	if code := get(t, h, "/v1/users/"+testserver.UserUID(2)+"/groups", "", &groups); code != http.StatusOK {
+		t.Fatalf("GET groups: got %d %v", code, groups)
+	}
+	if len(groups) != 1 || groups[0]["name"] != "openshift-eng" || groups[0]["description"] != "OpenShift engineering" ||
+		groups[0]["dn"] != "cn=openshift-eng,ou=adhoc,ou=managedGroups,dc=redhat,dc=com" {
+		t.Errorf("Got groups %v, want openshift-eng", groups)
+	}
+
+	// Users without groups get an empty list, not null
+	var none []map[string]any
+	if code := get(t, h, "/v1/users/"+testserver.UserUID(5)+"/groups", "", &none); code != http.StatusOK || none == nil || len(none) != 0 {
+		t.Errorf("GET groups of a user without any: got %d %v, want 200 []", code, none)
+	}
+
+	var id map[string]any
+	if code := get(t, h, "/v1/whoami", "", &id); code != http.StatusOK {
+		t.Errorf("GET /v1/whoami with -whoami: got %d %v, want 200", code, id)
+	}
+}
+
+func TestServeScopes(t *testing.T) {
+	dir := t.TempDir()
+	writeToken := func(name, token string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	clients := filepath.Join(dir, "clients.yaml")
+	content := `scopes:
+  directory.basic: {attributes: [cn, mail]}
+  directory.full: {}
+clients:
+  - name: billing
+    token_file: ` + writeToken("billing", "billing-token") + `
+    claims: [directory.basic]
+  - name: hr
+    token_file: ` + writeToken("hr", "hr-token") + `
+    claims: [directory.basic, directory.full]
+`
+	if err := os.WriteFile(clients, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	callers, err := loadServeClients(clients)
+	if err != nil {
+		t.Fatalf("loadServeClients failed: %v", err)
+	}
+	h := newServeHandler(newServePool(t), serveOptions{callers: callers})
+	path := "/v1/users/" + testserver.UserUID(2)
+
+	var basic ldap_redhat.UserRecord
+	if code := get(t, h, path, "billing-token", &basic); code != http.StatusOK {
+		t.Fatalf("GET as billing: got %d", code)
+	}
+	if basic.UID != testserver.UserUID(2) || basic.DisplayName == "" || basic.Email == "" || basic.Title != "" || basic.CostCenter != "" {
+		t.Errorf("Billing got %+v, want only uid, cn and mail", basic)
+	}
+
+	var full ldap_redhat.UserRecord
+	if code := get(t, h, path, "hr-token", &full); code != http.StatusOK || full.Title == "" || full.CostCenter == "" {
+		t.Errorf("HR got %d %+v, want every attribute", code, full)
+	}
+
+	// Clients whose claims the policy does not grant are rejected at load
+	bad := filepath.Join(dir, "bad.yaml")
+	os.WriteFile(bad, []byte("clients:\n  - name: x\n    token_file: "+writeToken("x", "x-token")+"\n    claims: [unknown]\n"), 0o600)
+	if _, err := loadServeClients(bad); err == nil {
+		t.Error("Expected an error for a client without recognized claims")
+	}
+}
+
+func TestSearcherPool(t *testing.T) {
+	pool := newServePool(t)
+	for i := range 4 {
+		if got, want := pool.get(), pool.searchers[i%2]; got != want {
+			t.Errorf("Request %d went to another searcher than %d", i, i%2)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"gopkg.in/yaml.v3"
+)
+
+// searcherPool spreads the requests of ldapcheck serve round-robin over
+// searchers with a connection each, so that concurrent requests are not all
+// multiplexed over, and held up by, a single connection
+type searcherPool struct {
+	searchers []*ldap_redhat.Searcher
+	next      atomic.Uint64
+}
+
+// get returns the searcher for the next request
+func (p *searcherPool) get() *ldap_redhat.Searcher {
+	n := p.next.Add(1) - 1
+	return p.searchers[n%uint64(len(p.searchers))]
+}
+
+// keepalive pings every searcher each interval until ctx is done, which
+// re-dials dropped connections
+func (p *searcherPool) keepalive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for i, s := range p.searchers {
+				if err := s.Ping(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("LDAP keepalive of connection %d failed: %v", i, err)
+				}
+			}
+		}
+	}
+}
+
+// Close closes every searcher
+func (p *searcherPool) Close() {
+	for _, s := range p.searchers {
+		s.Close()
+	}
+}
+
+// caller is a client of ldapcheck serve, identified by its bearer token
+type caller struct {
+	name  string
+	scope *ldap_redhat.RequestScope // nil when unrestricted
+}
+
+// context returns ctx recording c as the audit principal and restricted to
+// its scope
+func (c caller) context(ctx context.Context) context.Context {
+	ctx = ldap_redhat.WithPrincipal(ctx, c.name)
+	if c.scope != nil {
+		ctx = ldap_redhat.WithRequestScope(ctx, *c.scope)
+	}
+	return ctx
+}
+
+// serveClients is the -clients file of ldapcheck serve:
+//
+//	scopes:
+//	  directory.basic: {attributes: [cn, mail, title]}
+//	  directory.full: {}
+//	clients:
+//	  - name: billing
+//	    token_file: /etc/ldapcheck/billing.token
+//	    claims: [directory.basic]
+type serveClients struct {
+	Scopes  ldap_redhat.ScopePolicy `yaml:"scopes"`
+	Clients []serveClient           `yaml:"clients"`
+}
+
+type serveClient struct {
+	Name      string   `yaml:"name"`
+	TokenFile string   `yaml:"token_file"`
+	Claims    []string `yaml:"claims"`
+}
+
+// loadServeClients reads the clients file at path and resolves the claims of
+// each client with its scope policy, returning the clients by bearer token
+func loadServeClients(path string) (map[string]caller, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var f serveClients
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(f.Clients) == 0 {
+		return nil, fmt.Errorf("%s lists no clients", path)
+	}
+	callers := make(map[string]caller, len(f.Clients))
+	for _, c := range f.Clients {
+		if c.Name == "" || c.TokenFile == "" {
+			return nil, fmt.Errorf("every client in %s needs a name and a token_file", path)
+		}
+		token, err := ldap_redhat.ReadSecretFileWithPolicy(c.TokenFile, ldap_redhat.SecretFileStrict)
+		if err != nil {
+			return nil, fmt.Errorf("client %s: %w", c.Name, err)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("client %s: token file %s is empty", c.Name, c.TokenFile)
+		}
+		if _, dup := callers[token]; dup {
+			return nil, fmt.Errorf("client %s: token shared with another client", c.Name)
+		}
+		scope, err := f.Scopes.Resolve(c.Claims)
+		if err != nil {
+			return nil, fmt.Errorf("client %s: %w", c.Name, err)
+		}
+		callers[token] = caller{name: c.Name, scope: &scope}
+	}
+	return callers, nil
+}
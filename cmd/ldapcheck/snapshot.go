@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// snapshotDiffReport is the result of "snapshot diff"
+type snapshotDiffReport struct {
+	Old          snapshotInfo             `json:"old" yaml:"old"`
+	New          snapshotInfo             `json:"new" yaml:"new"`
+	Hires        []ldap_redhat.UserRecord `json:"hires" yaml:"hires"`
+	Terminations []ldap_redhat.UserRecord `json:"terminations" yaml:"terminations"`
+	Added        []ldap_redhat.UserRecord `json:"added" yaml:"added"`
+	Removed      []ldap_redhat.UserRecord `json:"removed" yaml:"removed"`
+	Changed      []ldap_redhat.UserChange `json:"changed" yaml:"changed"`
+}
+
+// snapshotInfo identifies one of the compared snapshot files
+type snapshotInfo struct {
+	File    string    `json:"file" yaml:"file"`
+	TakenAt time.Time `json:"taken_at" yaml:"taken_at"` // the file's modification time
+	Users   int       `json:"users" yaml:"users"`
+}
+
+// runSnapshot handles the snapshot subcommands; only "diff" exists today
+func runSnapshot(args []string) int {
+	fs, output := newFlagSet("snapshot", "diff <old.jsonl> <new.jsonl>")
+	positional := parseInterleaved(fs, args)
+	if len(positional) != 3 || positional[0] != "diff" {
+		fs.Usage()
+		return 2
+	}
+
+	oldFile, newFile := positional[1], positional[2]
+	old, err := ldap_redhat.LoadSnapshotFile(oldFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	new, err := ldap_redhat.LoadSnapshotFile(newFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d := ldap_redhat.DiffSnapshots(old, new)
+	report := snapshotDiffReport{
+		Old:          snapshotInfo{File: oldFile, TakenAt: old.TakenAt, Users: len(old.Users)},
+		New:          snapshotInfo{File: newFile, TakenAt: new.TakenAt, Users: len(new.Users)},
+		Hires:        nonNil(d.Hires),
+		Terminations: nonNil(d.Terminations),
+		Added:        nonNil(d.Added),
+		Removed:      nonNil(d.Removed),
+		Changed:      nonNil(d.Changed),
+	}
+	if err := writeResult(*output, report, func(w io.Writer) { printSnapshotDiff(w, report) }); err != nil {
+		log.Fatalf("Failed to write result: %v", err)
+	}
+	return 0
+}
+
+// printSnapshotDiff writes the human-readable diff report
+func printSnapshotDiff(w io.Writer, r snapshotDiffReport) {
+	fmt.Fprintf(w, "%s (%d users) -> %s (%d users)\n", r.Old.File, r.Old.Users, r.New.File, r.New.Users)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	section := func(title string, users []ldap_redhat.UserRecord) {
+		fmt.Fprintf(tw, "\n%s: %d\n", title, len(users))
+		for _, u := range users {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", u.UID, u.DisplayName, u.Title)
+		}
+	}
+	section("Hires", r.Hires)
+	section("Terminations", r.Terminations)
+	section("Added", r.Added)
+	section("Removed", r.Removed)
+	fmt.Fprintf(tw, "\nChanged: %d\n", len(r.Changed))
+	for _, c := range r.Changed {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.UID, strings.Join(c.Fields, ", "))
+	}
+	tw.Flush()
+}
+
+// parseInterleaved parses args allowing flags after positional arguments, as
+// in "snapshot diff old.jsonl new.jsonl -o json", and returns the positional
+// arguments
+func parseInterleaved(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// nonNil returns s, or an empty slice if s is nil, so that empty sections
+// encode as [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+// Command ldapmigrate rewrites common uses of older go-ldap-redhat APIs in a
+// consumer's Go source, in the spirit of go fix:
+//
+//	idtype    Identifier literals with numeric types use the IDT constants
+//	errors    strings.Contains(err.Error(), "user not found") and similar
+//	          message matching become errors.Is with the library's sentinels
+//	atsign    strings.Contains(x, "@") in packages importing the library is
+//	          reported for a manual switch to ParseIdentifier
+//	password  Config.Password fields become Credentials, GetPasswordFromEnv
+//	          CredentialsFromEnv, and WithBind with it WithCredentials
+//	ctor      NewSearcherFromEnv() becomes NewSearcherWithOptions with WithEnv
+//
+// Without -w it prints what it would change and exits 1 if there is anything
+// to do, so it can gate CI. Only files importing the library are touched.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	write := flag.Bool("w", false, "write rewritten files in place instead of reporting")
+	ruleList := flag.String("r", strings.Join(ruleNames(), ","), "comma-separated rules to apply")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ldapmigrate [-w] [-r rules] [path ...]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Rules:")
+		for _, r := range rules {
+			fmt.Fprintf(os.Stderr, "  %-9s %s\n", r.name, r.summary)
+		}
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	enabled, err := selectRules(*ruleList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	pending := false
+	for _, path := range paths {
+		files, err := goFiles(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, file := range files {
+			changed, err := migrateFile(file, enabled, *write)
+			if err != nil {
+				log.Fatal(err)
+			}
+			pending = pending || changed
+		}
+	}
+	if pending && !*write {
+		os.Exit(1)
+	}
+}
+
+// goFiles returns path if it is a file, or the Go files under it, skipping
+// vendor, testdata and hidden directories. A trailing /... is accepted, as
+// directories are always walked recursively.
+func goFiles(path string) ([]string, error) {
+	if path = strings.TrimSuffix(path, "..."); path == "" {
+		path = "."
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != path && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(p, ".go") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// migrateFile applies the enabled rules to one file, printing every change
+// and finding. It reports whether the file has changes to write.
+func migrateFile(path string, enabled []rule, write bool) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	out, changes, findings, err := migrate(path, src, enabled)
+	if err != nil {
+		return false, err
+	}
+	for i := range findings {
+		findings[i].msg += " (manual)"
+	}
+	notes := append(changes, findings...)
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].pos.Offset < notes[j].pos.Offset })
+	for _, n := range notes {
+		fmt.Printf("%s: %s\n", n.pos, n.msg)
+	}
+	if len(changes) == 0 || bytes.Equal(out, src) {
+		return false, nil
+	}
+	if write {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
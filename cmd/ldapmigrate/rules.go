@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// libraryPath is the import path of the library being migrated to
+const libraryPath = "github.com/openshift-eng/go-ldap-redhat"
+
+// note is a change made, or a finding left to the user, at a position
+type note struct {
+	pos token.Position
+	msg string
+}
+
+// fileState is what a rule sees of the file being migrated
+type fileState struct {
+	fset *token.FileSet
+	file *ast.File
+	lib  string // local name of the library import
+
+	changes      []note
+	findings     []note
+	needsImports []string // standard library imports to add, e.g. "errors"
+	dropsStrings bool     // a strings call was removed, so the import may be unused
+}
+
+func (st *fileState) change(pos token.Pos, format string, args ...any) {
+	st.changes = append(st.changes, note{st.fset.Position(pos), fmt.Sprintf(format, args...)})
+}
+
+// needsImport has path imported if the file does not already
+func (st *fileState) needsImport(path string) {
+	if !slices.Contains(st.needsImports, path) {
+		st.needsImports = append(st.needsImports, path)
+	}
+}
+
+func (st *fileState) finding(pos token.Pos, format string, args ...any) {
+	st.findings = append(st.findings, note{st.fset.Position(pos), fmt.Sprintf(format, args...)})
+}
+
+// rule is one rewrite
+type rule struct {
+	name    string
+	summary string
+	apply   func(st *fileState)
+}
+
+var rules = []rule{
+	{"idtype", "use IDT constants for numeric Identifier types", rewriteIdentifierTypes},
+	{"errors", "match error sentinels with errors.Is instead of message text", rewriteErrorMatching},
+	{"atsign", "report \"@\" heuristics that ParseIdentifier replaces", reportAtSignHeuristics},
+	{"password", "bind with Credentials instead of Config.Password and GetPasswordFromEnv", rewritePasswords},
+	{"ctor", "build searchers with NewSearcherWithOptions instead of NewSearcherFromEnv", rewriteConstructors},
+}
+
+func ruleNames() []string {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.name
+	}
+	return names
+}
+
+// selectRules returns the rules named in a comma-separated list
+func selectRules(list string) ([]rule, error) {
+	var out []rule
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, r := range rules {
+			if r.name == name {
+				out = append(out, r)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown rule %q: expected one of %s", name, strings.Join(ruleNames(), ", "))
+		}
+	}
+	return out, nil
+}
+
+// migrate applies rules to src and returns the formatted result. Files that
+// do not import the library are returned unchanged.
+func migrate(filename string, src []byte, enabled []rule) ([]byte, []note, []note, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	lib, ok := importName(file, libraryPath, "ldap_redhat")
+	if !ok {
+		return src, nil, nil, nil
+	}
+	st := &fileState{fset: fset, file: file, lib: lib}
+	for _, r := range enabled {
+		r.apply(st)
+	}
+	if len(st.changes) == 0 {
+		return src, nil, st.findings, nil
+	}
+	if st.dropsStrings && !usesPackage(file, "strings") {
+		deleteImport(file, "strings")
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, nil, nil, err
+	}
+	out := buf.Bytes()
+	for _, path := range st.needsImports {
+		if _, ok := importName(file, path, path); !ok {
+			if out, err = addImport(filename, out, path); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	return out, st.changes, st.findings, nil
+}
+
+// importName returns the local name under which file imports path
+func importName(file *ast.File, path, defaultName string) (string, bool) {
+	for _, spec := range file.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p != path {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name, true
+		}
+		return defaultName, true
+	}
+	return "", false
+}
+
+// usesPackage reports whether file refers to name.X anywhere
+func usesPackage(file *ast.File, name string) bool {
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && isIdent(sel.X, name) {
+			used = true
+		}
+		return !used
+	})
+	return used
+}
+
+// deleteImport removes the unnamed import of path
+func deleteImport(file *ast.File, path string) {
+	quoted := strconv.Quote(path)
+	for i := 0; i < len(file.Decls); i++ {
+		decl, ok := file.Decls[i].(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		for j, spec := range decl.Specs {
+			is := spec.(*ast.ImportSpec)
+			if is.Name == nil && is.Path.Value == quoted {
+				decl.Specs = append(decl.Specs[:j], decl.Specs[j+1:]...)
+				break
+			}
+		}
+		if len(decl.Specs) == 0 {
+			file.Decls = append(file.Decls[:i], file.Decls[i+1:]...)
+			i--
+		}
+	}
+	for i, spec := range file.Imports {
+		if spec.Name == nil && spec.Path.Value == quoted {
+			file.Imports = append(file.Imports[:i], file.Imports[i+1:]...)
+			break
+		}
+	}
+}
+
+// addImport adds an import of path to formatted source and sorts the first
+// import block. It works on text because go/ast has no import editing
+// outside golang.org/x/tools.
+func addImport(filename string, src []byte, path string) ([]byte, error) {
+	line := "\t" + strconv.Quote(path) + "\n"
+	text := string(src)
+	if i := strings.Index(text, "\nimport (\n"); i >= 0 {
+		at := i + len("\nimport (\n")
+		text = text[:at] + line + text[at:]
+	} else if i := strings.Index(text, "\nimport "); i >= 0 {
+		end := strings.Index(text[i+1:], "\n") + i + 1
+		spec := strings.TrimPrefix(text[i+1:end], "import ")
+		if strings.Contains(strings.SplitN(spec, "/", 2)[0], ".") {
+			line += "\n" // keep non-standard imports in their own group
+		}
+		text = text[:i+1] + "import (\n" + line + "\t" + spec + "\n)" + text[end:]
+	} else {
+		return nil, fmt.Errorf("%s: no import declaration to add %q to", filename, path)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, text, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	ast.SortImports(fset, file)
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && id.Name == name
+}
+
+// isLibSelector reports whether expr is lib.name
+func isLibSelector(expr ast.Expr, lib, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	return ok && isIdent(sel.X, lib) && sel.Sel.Name == name
+}
+
+// isStringsContains returns n if it is a strings.Contains call
+func isStringsContains(n ast.Node) (*ast.CallExpr, bool) {
+	call, ok := n.(*ast.CallExpr)
+	if !ok || len(call.Args) != 2 {
+		return nil, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !isIdent(sel.X, "strings") || sel.Sel.Name != "Contains" {
+		return nil, false
+	}
+	return call, true
+}
+
+// identifierTypes are the IDT constants by value
+var identifierTypes = []string{"IDTUID", "IDTEmail", "IDTUUID", "IDTEmployeeNumber", "IDTKerberos"}
+
+// rewriteIdentifierTypes replaces integer literals in the Type field of
+// Identifier literals, keyed or positional, with the IDT constants
+func rewriteIdentifierTypes(st *fileState) {
+	fix := func(lit *ast.CompositeLit) {
+		if len(lit.Elts) == 0 {
+			return
+		}
+		target := &lit.Elts[0]
+		if kv, ok := lit.Elts[0].(*ast.KeyValueExpr); ok {
+			target = nil
+			for _, elt := range lit.Elts {
+				if kv = elt.(*ast.KeyValueExpr); isIdent(kv.Key, "Type") {
+					target = &kv.Value
+				}
+			}
+			if target == nil {
+				return
+			}
+		}
+		basic, ok := (*target).(*ast.BasicLit)
+		if !ok || basic.Kind != token.INT {
+			return
+		}
+		v, err := strconv.Atoi(basic.Value)
+		if err != nil || v < 0 || v >= len(identifierTypes) {
+			st.finding(basic.Pos(), "idtype: unknown identifier type %s", basic.Value)
+			return
+		}
+		name := identifierTypes[v]
+		*target = &ast.SelectorExpr{X: ast.NewIdent(st.lib), Sel: &ast.Ident{Name: name, NamePos: basic.Pos()}}
+		st.change(basic.Pos(), "idtype: identifier type %s is %s.%s", basic.Value, st.lib, name)
+	}
+	ast.Inspect(st.file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		if isLibSelector(lit.Type, st.lib, "Identifier") {
+			fix(lit)
+			return true
+		}
+		for _, inner := range elidedLiterals(lit, st.lib, "Identifier") {
+			fix(inner)
+		}
+		return true
+	})
+}
+
+// elidedLiterals returns the elements of a []lib.name or map[...]lib.name
+// literal that elide their type
+func elidedLiterals(lit *ast.CompositeLit, lib, name string) []*ast.CompositeLit {
+	var elt ast.Expr
+	switch t := lit.Type.(type) {
+	case *ast.ArrayType:
+		elt = t.Elt
+	case *ast.MapType:
+		elt = t.Value
+	}
+	if elt == nil || !isLibSelector(elt, lib, name) {
+		return nil
+	}
+	var out []*ast.CompositeLit
+	for _, e := range lit.Elts {
+		if kv, ok := e.(*ast.KeyValueExpr); ok {
+			e = kv.Value
+		}
+		if inner, ok := e.(*ast.CompositeLit); ok && inner.Type == nil {
+			out = append(out, inner)
+		}
+	}
+	return out
+}
+
+// errorMessages maps fragments of the library's error messages, old and
+// current, to the sentinel that identifies them
+var errorMessages = []struct {
+	fragment string
+	sentinel string
+}{
+	{"user not found in LDAP directory", "ErrUserNotFound"},
+	{"user not found in offline snapshot", "ErrUserNotFound"},
+	{"LDAP connection not established", "ErrNotConnected"},
+	{"Invalid Credentials", "ErrAuthFailed"},
+	{"LDAP entries match", "ErrMultipleMatches"},
+	{"Time Limit Exceeded", "ErrTimeout"},
+	{"LDAP circuit breaker is open", "ErrCircuitOpen"},
+	{"secret file has insecure permissions", "ErrInsecureSecretFile"},
+}
+
+// sentinelFor returns the sentinel whose messages contain text, if exactly
+// one does
+func sentinelFor(text string) (string, bool) {
+	var found string
+	for _, m := range errorMessages {
+		if !strings.Contains(m.fragment, text) {
+			continue
+		}
+		if found != "" && found != m.sentinel {
+			return "", false
+		}
+		found = m.sentinel
+	}
+	return found, found != ""
+}
+
+// rewriteErrorMatching turns strings.Contains(err.Error(), "<message>") into
+// errors.Is(err, lib.ErrX) when the message identifies one sentinel
+func rewriteErrorMatching(st *fileState) {
+	ast.Inspect(st.file, func(n ast.Node) bool {
+		call, ok := isStringsContains(n)
+		if !ok {
+			return true
+		}
+		errCall, ok := call.Args[0].(*ast.CallExpr)
+		if !ok || len(errCall.Args) != 0 {
+			return true
+		}
+		method, ok := errCall.Fun.(*ast.SelectorExpr)
+		if !ok || method.Sel.Name != "Error" {
+			return true
+		}
+		lit, ok := call.Args[1].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		text, err := strconv.Unquote(lit.Value)
+		if err != nil || len(text) < 4 {
+			return true
+		}
+		sentinel, ok := sentinelFor(text)
+		if !ok {
+			if strings.Contains(text, "LDAP") || strings.Contains(text, "ldap") {
+				st.finding(call.Pos(), "errors: no single error sentinel matches %q", text)
+			}
+			return true
+		}
+		call.Fun = &ast.SelectorExpr{X: &ast.Ident{Name: "errors", NamePos: call.Pos()}, Sel: ast.NewIdent("Is")}
+		call.Args = []ast.Expr{method.X, &ast.SelectorExpr{X: ast.NewIdent(st.lib), Sel: ast.NewIdent(sentinel)}}
+		st.needsImport("errors")
+		st.dropsStrings = true
+		st.change(call.Pos(), "errors: matching %q is errors.Is(err, %s.%s)", text, st.lib, sentinel)
+		return true
+	})
+}
+
+// reportAtSignHeuristics points out strings.Contains(x, "@") checks, which
+// usually pick IDTEmail over IDTUID and miss the other identifier types
+func reportAtSignHeuristics(st *fileState) {
+	ast.Inspect(st.file, func(n ast.Node) bool {
+		call, ok := isStringsContains(n)
+		if !ok {
+			return true
+		}
+		if lit, ok := call.Args[1].(*ast.BasicLit); ok && lit.Value == `"@"` {
+			st.finding(call.Pos(), "atsign: use %s.ParseIdentifier instead of checking for \"@\"", st.lib)
+		}
+		return true
+	})
+}
+
+// isLibCall returns n if it is a call of lib.name
+func isLibCall(n ast.Node, lib, name string) (*ast.CallExpr, bool) {
+	call, ok := n.(*ast.CallExpr)
+	if !ok || !isLibSelector(call.Fun, lib, name) {
+		return nil, false
+	}
+	return call, true
+}
+
+// rewritePasswords moves binds off the deprecated Config.Password and
+// GetPasswordFromEnv: Password fields of Config literals become Credentials,
+// with CredentialsFromEnv for GetPasswordFromEnv and a StaticPassword
+// otherwise, and WithBind(dn, GetPasswordFromEnv()) becomes WithCredentials.
+// Other GetPasswordFromEnv calls are reported.
+func rewritePasswords(st *fileState) {
+	fromEnv := func(expr ast.Expr) bool {
+		call, ok := isLibCall(expr, st.lib, "GetPasswordFromEnv")
+		return ok && len(call.Args) == 0
+	}
+	credentialsFromEnv := func(pos token.Pos) ast.Expr {
+		return &ast.CallExpr{Fun: &ast.SelectorExpr{X: &ast.Ident{Name: st.lib, NamePos: pos}, Sel: ast.NewIdent("CredentialsFromEnv")}}
+	}
+	fix := func(lit *ast.CompositeLit) {
+		var password, credentials *ast.KeyValueExpr
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return // positional Config literals are left to the compiler
+			}
+			switch {
+			case isIdent(kv.Key, "Password"):
+				password = kv
+			case isIdent(kv.Key, "Credentials"):
+				credentials = kv
+			}
+		}
+		if password == nil {
+			return
+		}
+		if credentials != nil {
+			st.finding(password.Pos(), "password: Config sets Credentials, so Password is ignored and can be removed")
+			return
+		}
+		key := password.Key.(*ast.Ident)
+		key.Name = "Credentials"
+		if fromEnv(password.Value) {
+			password.Value = credentialsFromEnv(password.Value.Pos())
+			st.change(key.Pos(), "password: Config.Password from GetPasswordFromEnv is Credentials: %s.CredentialsFromEnv()", st.lib)
+			return
+		}
+		password.Value = &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: &ast.Ident{Name: st.lib, NamePos: password.Value.Pos()}, Sel: ast.NewIdent("StaticPassword")},
+			Args: []ast.Expr{password.Value},
+		}
+		st.change(key.Pos(), "password: Config.Password is Credentials: %s.StaticPassword(...)", st.lib)
+	}
+	ast.Inspect(st.file, func(n ast.Node) bool {
+		if call, ok := isLibCall(n, st.lib, "WithBind"); ok && len(call.Args) == 2 && fromEnv(call.Args[1]) {
+			call.Fun.(*ast.SelectorExpr).Sel.Name = "WithCredentials"
+			call.Args[1] = credentialsFromEnv(call.Args[1].Pos())
+			st.change(call.Pos(), "password: WithBind with GetPasswordFromEnv is WithCredentials with %s.CredentialsFromEnv", st.lib)
+			return true
+		}
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		if isLibSelector(lit.Type, st.lib, "Config") {
+			fix(lit)
+			return true
+		}
+		for _, inner := range elidedLiterals(lit, st.lib, "Config") {
+			fix(inner)
+		}
+		return true
+	})
+	ast.Inspect(st.file, func(n ast.Node) bool {
+		if call, ok := isLibCall(n, st.lib, "GetPasswordFromEnv"); ok {
+			st.finding(call.Pos(), "password: keep a %s.CredentialsFromEnv() source instead of the password from GetPasswordFromEnv", st.lib)
+		}
+		return true
+	})
+}
+
+// rewriteConstructors replaces NewSearcherFromEnv() with
+// NewSearcherWithOptions(context.Background(), WithEnv())
+func rewriteConstructors(st *fileState) {
+	ast.Inspect(st.file, func(n ast.Node) bool {
+		call, ok := isLibCall(n, st.lib, "NewSearcherFromEnv")
+		if !ok || len(call.Args) != 0 {
+			return true
+		}
+		pos := call.Pos()
+		call.Fun.(*ast.SelectorExpr).Sel.Name = "NewSearcherWithOptions"
+		call.Args = []ast.Expr{
+			&ast.CallExpr{Fun: &ast.SelectorExpr{X: &ast.Ident{Name: "context", NamePos: pos}, Sel: ast.NewIdent("Background")}},
+			&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(st.lib), Sel: ast.NewIdent("WithEnv")}},
+		}
+		st.needsImport("context")
+		st.change(pos, "ctor: NewSearcherFromEnv() is %s.NewSearcherWithOptions(context.Background(), %s.WithEnv())", st.lib, st.lib)
+		return true
+	})
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// TestMigrate runs rules over testdata/<name>.input and compares the result
+// with testdata/<name>.golden, which holds the rewritten source followed by
+// the changes and findings reported
+func TestMigrate(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		rules string
+	}{
+		{"idtype", "idtype"},
+		{"errors", "errors"},
+		{"errors_keep_strings", "errors"},
+		{"errors_single_import", "errors"},
+		{"atsign", "atsign"},
+		{"password", "password"},
+		{"ctor", "ctor"},
+		{"ctor_context", "ctor"},
+		{"unrelated", strings.Join(ruleNames(), ",")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled, err := selectRules(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			input := filepath.Join("testdata", tt.name+".input")
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, changes, findings, err := migrate(input, src, enabled)
+			if err != nil {
+				t.Fatalf("migrate failed: %v", err)
+			}
+			got := bytes.NewBuffer(out)
+			for _, n := range changes {
+				fmt.Fprintf(got, "// change %d:%d: %s\n", n.pos.Line, n.pos.Column, n.msg)
+			}
+			for _, n := range findings {
+				fmt.Fprintf(got, "// finding %d:%d: %s\n", n.pos.Line, n.pos.Column, n.msg)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("Got:\n%s\nwant:\n%s", got, want)
+			}
+			if len(changes) > 0 {
+				if _, err := parser.ParseFile(token.NewFileSet(), golden, out, 0); err != nil {
+					t.Errorf("Rewritten source does not parse: %v", err)
+				}
+				if formatted, _ := format.Source(out); !bytes.Equal(formatted, out) {
+					t.Error("Rewritten source is not gofmt-ed")
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateIdempotent(t *testing.T) {
+	enabled, _ := selectRules(strings.Join(ruleNames(), ","))
+	inputs, _ := filepath.Glob(filepath.Join("testdata", "*.input"))
+	for _, input := range inputs {
+		src, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		once, _, _, err := migrate(input, src, enabled)
+		if err != nil {
+			t.Fatalf("%s: migrate failed: %v", input, err)
+		}
+		twice, changes, _, err := migrate(input, once, enabled)
+		if err != nil || len(changes) != 0 || !bytes.Equal(once, twice) {
+			t.Errorf("%s: second run made changes %v (%v)", input, changes, err)
+		}
+	}
+}
+
+func TestMigrateParseError(t *testing.T) {
+	if _, _, _, err := migrate("broken.go", []byte("package consumer\nfunc {"), nil); err == nil {
+		t.Error("Expected an error for source that does not parse")
+	}
+}
+
+func TestSelectRules(t *testing.T) {
+	enabled, err := selectRules(" errors, ,ctor")
+	if err != nil || len(enabled) != 2 || enabled[0].name != "errors" || enabled[1].name != "ctor" {
+		t.Errorf("selectRules returned %v (%v), want errors and ctor", enabled, err)
+	}
+	if _, err := selectRules("errors,nosuchrule"); err == nil || !strings.Contains(err.Error(), "nosuchrule") {
+		t.Errorf("Expected an error naming the unknown rule, got %v", err)
+	}
+}
+
+func TestAddImport(t *testing.T) {
+	for _, tt := range []struct {
+		name, src, path, want string
+	}{
+		{
+			name: "block",
+			src:  "package p\n\nimport (\n\t\"strings\"\n\n\tx \"example.com/x\"\n)\n",
+			path: "errors",
+			want: "package p\n\nimport (\n\t\"errors\"\n\t\"strings\"\n\n\tx \"example.com/x\"\n)\n",
+		},
+		{
+			name: "single standard import",
+			src:  "package p\n\nimport \"strings\"\n\nvar _ = strings.ToUpper\n",
+			path: "errors",
+			want: "package p\n\nimport (\n\t\"errors\"\n\t\"strings\"\n)\n\nvar _ = strings.ToUpper\n",
+		},
+		{
+			name: "single aliased module import",
+			src:  "package p\n\nimport x \"example.com/x\"\n\nvar _ = x.Y\n",
+			path: "context",
+			want: "package p\n\nimport (\n\t\"context\"\n\n\tx \"example.com/x\"\n)\n\nvar _ = x.Y\n",
+		},
+		{
+			name: "comment mentioning import",
+			src:  "package p\n\n// import comes first\nimport \"strings\"\n",
+			path: "errors",
+			want: "package p\n\n// import comes first\nimport (\n\t\"errors\"\n\t\"strings\"\n)\n",
+		},
+	} {
+		got, err := addImport("p.go", []byte(tt.src), tt.path)
+		if err != nil {
+			t.Errorf("%s: addImport failed: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := addImport("p.go", []byte("package p\n"), "errors"); err == nil {
+		t.Error("Expected an error for a file without imports")
+	}
+}
+
+func TestDeleteImport(t *testing.T) {
+	for _, tt := range []struct {
+		name, src, want string
+	}{
+		{
+			name: "from a block",
+			src:  "package p\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n",
+			want: "package p\n\nimport (\n\t\"fmt\"\n)\n",
+		},
+		{
+			name: "last of a declaration",
+			src:  "package p\n\nimport \"strings\"\n\nimport \"fmt\"\n",
+			want: "package p\n\nimport \"fmt\"\n",
+		},
+		{
+			name: "named imports are kept",
+			src:  "package p\n\nimport str \"strings\"\n",
+			want: "package p\n\nimport str \"strings\"\n",
+		},
+	} {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "p.go", tt.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deleteImport(file, "strings")
+		var buf bytes.Buffer
+		if err := format.Node(&buf, fset, file); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, buf.String(), tt.want)
+		}
+		if _, ok := importName(file, "strings", "strings"); ok != strings.Contains(tt.want, `"strings"`) {
+			t.Errorf("%s: file.Imports not updated: %v", tt.name, file.Imports)
+		}
+	}
+}
+
+func TestSentinelFor(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want string // "" when no single sentinel matches
+	}{
+		{"user not found", "ErrUserNotFound"},
+		{"user not found in offline snapshot", "ErrUserNotFound"},
+		{"Invalid Credentials", "ErrAuthFailed"},
+		{"circuit breaker", "ErrCircuitOpen"},
+		{"insecure permissions", "ErrInsecureSecretFile"},
+		{"LDAP", ""},          // several sentinels
+		{"Not Found", ""},     // case matters
+		{"no such entry", ""}, // none
+	} {
+		got, ok := sentinelFor(tt.text)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("sentinelFor(%q) = %q, %v, want %q", tt.text, got, ok, tt.want)
+		}
+	}
+}
+
+func TestUsesPackage(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", "package p\n\nimport \"strings\"\n\nvar s = strings.ToUpper\nvar strings2 = 1\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !usesPackage(file, "strings") || usesPackage(file, "errors") {
+		t.Error("usesPackage should report strings only")
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			sel.X = ast.NewIdent("other")
+		}
+		return true
+	})
+	if usesPackage(file, "strings") {
+		t.Error("usesPackage should not report strings once its selector is gone")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWebhookTimeout bounds each webhook delivery attempt
+const defaultWebhookTimeout = 10 * time.Second
+
+// watchNamePattern keeps watch names safe to use in file names and URLs
+var watchNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// daemonConfig is the ldapsyncd configuration file. LDAP connection settings
+// come from the library's usual config.yaml and environment variables.
+//
+//	schedule: "*/15 * * * *"
+//	listen: ":8080"
+//	data_dir: /var/lib/ldapsyncd      # or s3: {bucket: ..., region: ...}
+//	retention: {keep: 48, max_age: 720h}
+//	masking_policy: /etc/ldapsyncd/masking.yaml
+//	watches:                          # or watches_file: /etc/ldapsyncd/watches.yaml
+//	  - name: all
+//	    filter: "(uid=*)"
+//	webhooks:
+//	  - name: hr
+//	    url: https://hooks.example.com/ldap
+//	    secret_file: /etc/ldapsyncd/webhook-secret
+//	leader_election: {enabled: true}
+type daemonConfig struct {
+	Schedule      string          `yaml:"schedule"`
+	Listen        string          `yaml:"listen"`
+	DataDir       string          `yaml:"data_dir"`
+	S3            *s3Config       `yaml:"s3"`
+	Retention     retentionConfig `yaml:"retention"`
+	MaskingPolicy string          `yaml:"masking_policy"`
+	Watches       []watchConfig   `yaml:"watches"`
+	// WatchesFile declares the watches in a separate file, reloaded when
+	// it changes
+	WatchesFile string          `yaml:"watches_file"`
+	Webhooks    []webhookConfig `yaml:"webhooks"`
+
+	LeaderElection leaderElectionConfig `yaml:"leader_election"`
+
+	watchesData []byte // content of WatchesFile when loaded
+}
+
+// webhookConfig is an endpoint that receives each non-empty diff
+type webhookConfig struct {
+	Name       string        `yaml:"name"` // referenced by watch sinks (optional)
+	URL        string        `yaml:"url"`
+	SecretFile string        `yaml:"secret_file"` // HMAC key for the X-Ldapsyncd-Signature header (optional)
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// loadDaemonConfig reads and validates the configuration file at path
+func loadDaemonConfig(path string) (daemonConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return daemonConfig{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var c daemonConfig
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return daemonConfig{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if c.Schedule == "" {
+		c.Schedule = "@hourly"
+	}
+	if c.Listen == "" {
+		c.Listen = ":8080"
+	}
+	c.LeaderElection.setDefaults()
+	for i := range c.Webhooks {
+		if c.Webhooks[i].Timeout == 0 {
+			c.Webhooks[i].Timeout = defaultWebhookTimeout
+		}
+	}
+	if err := c.validate(); err != nil {
+		return daemonConfig{}, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if c.WatchesFile != "" {
+		if c.Watches, c.watchesData, err = loadWatchesFile(c.WatchesFile); err != nil {
+			return daemonConfig{}, err
+		}
+	}
+	if c.Watches, err = resolveWatches(c, c.Watches); err != nil {
+		return daemonConfig{}, fmt.Errorf("invalid %s: %w", cmp.Or(c.WatchesFile, path), err)
+	}
+	return c, nil
+}
+
+func (c daemonConfig) validate() error {
+	if _, err := parseSchedule(c.Schedule); err != nil {
+		return err
+	}
+	if c.DataDir != "" && c.S3 != nil {
+		return fmt.Errorf("data_dir and s3 are mutually exclusive")
+	}
+	if c.Retention.Keep < 0 || c.Retention.MaxAge < 0 {
+		return fmt.Errorf("retention limits must not be negative")
+	}
+	if err := c.LeaderElection.validate(); err != nil {
+		return err
+	}
+	if c.WatchesFile != "" && len(c.Watches) > 0 {
+		return fmt.Errorf("watches and watches_file are mutually exclusive")
+	}
+	names := map[string]bool{}
+	for _, h := range c.Webhooks {
+		if h.URL == "" {
+			return fmt.Errorf("webhook without url")
+		}
+		if h.Name != "" {
+			if names[h.Name] {
+				return fmt.Errorf("duplicate webhook name %q", h.Name)
+			}
+			names[h.Name] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("No time zone database: %v", err)
+	}
+	at := func(loc *time.Location, s string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatalf("Invalid time %q: %v", s, err)
+		}
+		return v
+	}
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want []time.Time // successive runs
+	}{
+		{"every minute", "* * * * *", at(time.UTC, "2026-01-01 10:00"),
+			[]time.Time{at(time.UTC, "2026-01-01 10:01"), at(time.UTC, "2026-01-01 10:02")}},
+		{"strictly after", "0 12 * * *", at(time.UTC, "2026-01-01 12:00"),
+			[]time.Time{at(time.UTC, "2026-01-02 12:00")}},
+		{"step over the whole range", "*/20 * * * *", at(time.UTC, "2026-01-01 10:41"),
+			[]time.Time{at(time.UTC, "2026-01-01 11:00"), at(time.UTC, "2026-01-01 11:20")}},
+		{"step from a value", "5/30 * * * *", at(time.UTC, "2026-01-01 10:00"),
+			[]time.Time{at(time.UTC, "2026-01-01 10:05"), at(time.UTC, "2026-01-01 10:35"), at(time.UTC, "2026-01-01 11:05")}},
+		{"step within a range", "0 9-17/4 * * *", at(time.UTC, "2026-01-01 10:00"),
+			[]time.Time{at(time.UTC, "2026-01-01 13:00"), at(time.UTC, "2026-01-01 17:00"), at(time.UTC, "2026-01-02 09:00")}},
+		{"list", "0 8,20 * * *", at(time.UTC, "2026-01-01 09:00"),
+			[]time.Time{at(time.UTC, "2026-01-01 20:00"), at(time.UTC, "2026-01-02 08:00")}},
+		// 2026-01-01 is a Thursday
+		{"day of week only", "0 0 * * 1", at(time.UTC, "2026-01-01 00:00"),
+			[]time.Time{at(time.UTC, "2026-01-05 00:00"), at(time.UTC, "2026-01-12 00:00")}},
+		{"7 is Sunday", "0 0 * * 7", at(time.UTC, "2026-01-01 00:00"),
+			[]time.Time{at(time.UTC, "2026-01-04 00:00")}},
+		{"day of month only", "0 0 15 * *", at(time.UTC, "2026-01-01 00:00"),
+			[]time.Time{at(time.UTC, "2026-01-15 00:00"), at(time.UTC, "2026-02-15 00:00")}},
+		{"day of month or day of week", "0 0 15 * 1", at(time.UTC, "2026-01-10 00:00"),
+			[]time.Time{at(time.UTC, "2026-01-12 00:00"), at(time.UTC, "2026-01-15 00:00"), at(time.UTC, "2026-01-19 00:00")}},
+		{"day of week with a starred day of month", "0 0 * 1 5", at(time.UTC, "2026-01-01 00:00"),
+			[]time.Time{at(time.UTC, "2026-01-02 00:00"), at(time.UTC, "2026-01-09 00:00")}},
+		{"month and leap day", "0 0 29 2 *", at(time.UTC, "2026-01-01 00:00"),
+			[]time.Time{at(time.UTC, "2028-02-29 00:00")}},
+		{"never", "0 0 31 2 *", at(time.UTC, "2026-01-01 00:00"), []time.Time{{}}},
+		{"alias", "@weekly", at(time.UTC, "2026-01-01 00:00"),
+			[]time.Time{at(time.UTC, "2026-01-04 00:00")}},
+		// Clocks go forward from 02:00 to 03:00 on 2026-03-08 and back from
+		// 02:00 to 01:00 on 2026-11-01
+		{"time skipped by DST", "30 2 * * *", at(ny, "2026-03-07 03:00"),
+			[]time.Time{at(ny, "2026-03-09 02:30")}},
+		{"hourly across a skipped hour", "15 * * * *", at(ny, "2026-03-08 01:30"),
+			[]time.Time{at(ny, "2026-03-08 03:15"), at(ny, "2026-03-08 04:15")}},
+		{"time repeated by DST", "30 1 * * *", at(ny, "2026-10-31 12:00"),
+			[]time.Time{at(ny, "2026-11-01 01:30"), at(ny, "2026-11-02 01:30")}},
+		{"hourly across a repeated hour", "30 * * * *", at(ny, "2026-11-01 00:45"),
+			[]time.Time{
+				at(ny, "2026-11-01 01:30"),
+				at(ny, "2026-11-01 01:30").Add(time.Hour), // 01:30 EST
+				at(ny, "2026-11-01 02:30"),
+			}},
+	}
+	for _, tc := range tests {
+		sched, err := parseSchedule(tc.spec)
+		if err != nil {
+			t.Errorf("%s: parseSchedule(%q) failed: %v", tc.name, tc.spec, err)
+			continue
+		}
+		next := tc.from
+		for i, want := range tc.want {
+			next = sched.Next(next)
+			if !next.Equal(want) {
+				t.Errorf("%s: run %d of %q after %s is %s, want %s", tc.name, i+1, tc.spec, tc.from, next, want)
+				break
+			}
+		}
+	}
+}
+
+func TestEveryNext(t *testing.T) {
+	sched, err := parseSchedule("@every 90s")
+	if err != nil {
+		t.Fatalf("parseSchedule failed: %v", err)
+	}
+	from := time.Date(2026, 1, 1, 10, 0, 30, 0, time.UTC)
+	if got := sched.Next(from); !got.Equal(from.Add(90 * time.Second)) {
+		t.Errorf("Got %s, want 90s after %s", got, from)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"@every",
+		"@every 500ms",
+		"@every soon",
+		"@yearly",
+	} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
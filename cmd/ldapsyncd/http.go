@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// newHandler serves the latest snapshots:
+//
+//	GET  /healthz                 ok once the daemon is up
+//	GET  /watches                 state of every watch
+//	GET  /watches/{name}/snapshot latest snapshot as JSON Lines (masked)
+//	GET  /watches/{name}/diff     differences found by the latest run
+//	GET  /leader                  whether this replica is the leader
+//	POST /run                     run every watch now (leader only)
+//
+// el is nil without leader election, in which case this replica always runs.
+func newHandler(ctx context.Context, sy *syncer, el *elector) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /watches", func(w http.ResponseWriter, r *http.Request) {
+		watches := sy.currentWatches()
+		states := make([]watchState, 0, len(watches))
+		for _, watch := range watches {
+			state, _, _ := sy.state(watch.Name)
+			states = append(states, state)
+		}
+		writeJSON(w, states)
+	})
+	mux.HandleFunc("GET /watches/{name}/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		_, sn, ok := sy.state(r.PathValue("name"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if sn == nil {
+			http.Error(w, "no snapshot taken yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/jsonl")
+		w.Header().Set("Last-Modified", sn.TakenAt.UTC().Format(http.TimeFormat))
+		if err := sn.WriteMasked(w, sy.policy); err != nil {
+			log.Printf("failed to serve snapshot: %v", err)
+		}
+	})
+	mux.HandleFunc("GET /watches/{name}/diff", func(w http.ResponseWriter, r *http.Request) {
+		state, _, ok := sy.state(r.PathValue("name"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if state.Diff == nil {
+			http.Error(w, "no diff yet: fewer than two snapshots taken", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, sy.diffEvents(state.Name, *state.Diff, state.TakenAt))
+	})
+	mux.HandleFunc("GET /leader", func(w http.ResponseWriter, r *http.Request) {
+		if el == nil {
+			writeJSON(w, map[string]any{"leader": true})
+			return
+		}
+		writeJSON(w, map[string]any{"leader": el.isLeader(), "identity": el.identity})
+	})
+	mux.HandleFunc("POST /run", func(w http.ResponseWriter, r *http.Request) {
+		if el != nil && !el.isLeader() {
+			http.Error(w, "not the leader", http.StatusServiceUnavailable)
+			return
+		}
+		// Run detached from the request so a client disconnect does not
+		// abort the snapshot
+		go sy.runAll(ctx)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
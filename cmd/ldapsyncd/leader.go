@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// In-cluster service account files
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	tokenFile         = serviceAccountDir + "/token"
+	caFile            = serviceAccountDir + "/ca.crt"
+	namespaceFile     = serviceAccountDir + "/namespace"
+)
+
+// leaderElectionConfig enables Kubernetes Lease based leader election so that
+// only one replica snapshots LDAP and publishes events.
+type leaderElectionConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	LeaseName     string        `yaml:"lease_name"`     // default "ldapsyncd"
+	Namespace     string        `yaml:"namespace"`      // default: the pod's namespace
+	Identity      string        `yaml:"identity"`       // default: $POD_NAME or the hostname
+	LeaseDuration time.Duration `yaml:"lease_duration"` // how long followers wait before taking over, default 15s
+	RenewDeadline time.Duration `yaml:"renew_deadline"` // how long the leader retries renewing before stepping down, default 10s
+	RetryPeriod   time.Duration `yaml:"retry_period"`   // interval between acquire and renew attempts, default 2s
+}
+
+// setDefaults fills unset fields
+func (c *leaderElectionConfig) setDefaults() {
+	if c.LeaseName == "" {
+		c.LeaseName = "ldapsyncd"
+	}
+	if c.LeaseDuration == 0 {
+		c.LeaseDuration = 15 * time.Second
+	}
+	if c.RenewDeadline == 0 {
+		c.RenewDeadline = 10 * time.Second
+	}
+	if c.RetryPeriod == 0 {
+		c.RetryPeriod = 2 * time.Second
+	}
+}
+
+func (c leaderElectionConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.LeaseDuration < time.Second {
+		return fmt.Errorf("leader_election: lease_duration must be at least 1s")
+	}
+	if c.LeaseDuration <= c.RenewDeadline || c.RenewDeadline <= c.RetryPeriod || c.RetryPeriod <= 0 {
+		return fmt.Errorf("leader_election: lease_duration must exceed renew_deadline, which must exceed retry_period")
+	}
+	return nil
+}
+
+// microTime is a Kubernetes MicroTime
+type microTime struct{ time.Time }
+
+func (t microTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
+}
+
+// lease is a coordination.k8s.io/v1 Lease with the fields leader election uses
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string     `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int        `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          *microTime `json:"acquireTime,omitempty"`
+	RenewTime            *microTime `json:"renewTime,omitempty"`
+	LeaseTransitions     int        `json:"leaseTransitions,omitempty"`
+}
+
+// errLeaseNotFound and errLeaseConflict are returned by the lease client for
+// 404 and 409 responses
+var (
+	errLeaseNotFound = errors.New("lease not found")
+	errLeaseConflict = errors.New("lease was modified concurrently")
+)
+
+// kubeClient reads and writes Leases using the pod's service account
+type kubeClient struct {
+	baseURL   string
+	tokenFile string
+	client    *http.Client
+}
+
+// newInClusterClient configures a client from the service account mounted
+// into every pod
+func newInClusterClient() (*kubeClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("leader_election: not running in a Kubernetes pod (KUBERNETES_SERVICE_HOST is not set)")
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("leader_election: failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("leader_election: no certificates in %s", caFile)
+	}
+	return &kubeClient{
+		baseURL:   "https://" + net.JoinHostPort(host, port),
+		tokenFile: tokenFile,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+	}, nil
+}
+
+func leasePath(namespace, name string) string {
+	path := "/apis/coordination.k8s.io/v1/namespaces/" + namespace + "/leases"
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+func (k *kubeClient) getLease(ctx context.Context, namespace, name string) (*lease, error) {
+	var l lease
+	if err := k.do(ctx, http.MethodGet, leasePath(namespace, name), nil, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (k *kubeClient) createLease(ctx context.Context, l *lease) error {
+	return k.do(ctx, http.MethodPost, leasePath(l.Metadata.Namespace, ""), l, nil)
+}
+
+// updateLease replaces l. The resourceVersion makes the update fail with
+// errLeaseConflict if another replica changed the lease since it was read.
+func (k *kubeClient) updateLease(ctx context.Context, l *lease) error {
+	return k.do(ctx, http.MethodPut, leasePath(l.Metadata.Namespace, l.Metadata.Name), l, nil)
+}
+
+func (k *kubeClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, k.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Bound service account tokens are rotated, so read the token every time
+	if token, err := os.ReadFile(k.tokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errLeaseNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return errLeaseConflict
+	case resp.StatusCode/100 != 2:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// elector holds a Lease while this replica is the leader
+type elector struct {
+	client   *kubeClient
+	config   leaderElectionConfig
+	identity string
+	leading  atomic.Bool
+
+	// observed is the last lease spec seen and observedAt when it last
+	// changed. Expiry is judged by the local clock from observedAt rather
+	// than the holder's renewTime, so clock skew between nodes is harmless.
+	observed   leaseSpec
+	observedAt time.Time
+}
+
+func newElector(c leaderElectionConfig) (*elector, error) {
+	client, err := newInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+	if c.Namespace == "" {
+		data, err := os.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("leader_election: namespace not set and %s unreadable: %w", namespaceFile, err)
+		}
+		c.Namespace = strings.TrimSpace(string(data))
+	}
+	identity := c.Identity
+	if identity == "" {
+		identity = os.Getenv("POD_NAME")
+	}
+	if identity == "" {
+		if identity, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("leader_election: no identity: %w", err)
+		}
+	}
+	return &elector{client: client, config: c, identity: identity}, nil
+}
+
+// isLeader reports whether this replica currently holds the lease
+func (e *elector) isLeader() bool {
+	return e.leading.Load()
+}
+
+// run calls lead each time this replica becomes the leader, with a context
+// that is canceled when leadership is lost. It returns when ctx is done,
+// releasing the lease if held.
+func (e *elector) run(ctx context.Context, lead func(ctx context.Context)) {
+	log.Printf("leader election: %s waiting for lease %s/%s", e.identity, e.config.Namespace, e.config.LeaseName)
+	for {
+		if !e.acquire(ctx) {
+			return
+		}
+		log.Printf("leader election: %s acquired lease %s/%s", e.identity, e.config.Namespace, e.config.LeaseName)
+		e.leading.Store(true)
+		leadCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			lead(leadCtx)
+		}()
+		e.renew(leadCtx)
+		cancel()
+		<-done
+		e.leading.Store(false)
+		if ctx.Err() != nil {
+			e.release()
+			return
+		}
+		log.Printf("leader election: %s lost lease %s/%s", e.identity, e.config.Namespace, e.config.LeaseName)
+	}
+}
+
+// acquire retries until this replica holds the lease, or ctx is done
+func (e *elector) acquire(ctx context.Context) bool {
+	ticker := time.NewTicker(e.config.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		ok, err := e.tryAcquireOrRenew(ctx)
+		if err != nil && !errors.Is(err, errLeaseConflict) && ctx.Err() == nil {
+			log.Printf("leader election: %v", err)
+		}
+		if ok {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// renew keeps the lease until a renewal fails for longer than RenewDeadline,
+// another replica takes it, or ctx is done
+func (e *elector) renew(ctx context.Context) {
+	ticker := time.NewTicker(e.config.RetryPeriod)
+	defer ticker.Stop()
+	lastRenew := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, e.config.RenewDeadline)
+		ok, err := e.tryAcquireOrRenew(attemptCtx)
+		cancel()
+		switch {
+		case ok:
+			lastRenew = time.Now()
+		case err == nil:
+			return // another replica holds the lease
+		default:
+			if ctx.Err() == nil {
+				log.Printf("leader election: renew failed: %v", err)
+			}
+			if time.Since(lastRenew) > e.config.RenewDeadline {
+				return
+			}
+		}
+	}
+}
+
+// tryAcquireOrRenew creates, renews or takes over an expired lease. It
+// returns false without an error when another replica holds a valid lease.
+func (e *elector) tryAcquireOrRenew(ctx context.Context) (bool, error) {
+	now := time.Now()
+	spec := leaseSpec{
+		HolderIdentity:       e.identity,
+		LeaseDurationSeconds: int(e.config.LeaseDuration / time.Second),
+		AcquireTime:          &microTime{now},
+		RenewTime:            &microTime{now},
+	}
+
+	current, err := e.client.getLease(ctx, e.config.Namespace, e.config.LeaseName)
+	if errors.Is(err, errLeaseNotFound) {
+		err = e.client.createLease(ctx, &lease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMetadata{Name: e.config.LeaseName, Namespace: e.config.Namespace},
+			Spec:       spec,
+		})
+		if err != nil {
+			return false, err
+		}
+		e.observe(spec, now)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	e.observe(current.Spec, now)
+	held := current.Spec.HolderIdentity
+	if held != "" && held != e.identity {
+		duration := time.Duration(current.Spec.LeaseDurationSeconds) * time.Second
+		if now.Before(e.observedAt.Add(duration)) {
+			return false, nil
+		}
+	}
+	if held == e.identity {
+		spec.AcquireTime = current.Spec.AcquireTime
+		spec.LeaseTransitions = current.Spec.LeaseTransitions
+	} else {
+		spec.LeaseTransitions = current.Spec.LeaseTransitions + 1
+	}
+	current.Spec = spec
+	if err := e.client.updateLease(ctx, current); err != nil {
+		return false, err
+	}
+	e.observe(spec, now)
+	return true, nil
+}
+
+// observe records spec, restarting the expiry clock when it changed
+func (e *elector) observe(spec leaseSpec, now time.Time) {
+	if spec.HolderIdentity != e.observed.HolderIdentity ||
+		!renewTime(spec).Equal(renewTime(e.observed)) ||
+		e.observedAt.IsZero() {
+		e.observed = spec
+		e.observedAt = now
+	}
+}
+
+func renewTime(spec leaseSpec) time.Time {
+	if spec.RenewTime == nil {
+		return time.Time{}
+	}
+	return spec.RenewTime.Time
+}
+
+// release gives up the lease on shutdown so another replica can take over
+// without waiting for it to expire
+func (e *elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.RenewDeadline)
+	defer cancel()
+	current, err := e.client.getLease(ctx, e.config.Namespace, e.config.LeaseName)
+	if err != nil || current.Spec.HolderIdentity != e.identity {
+		return
+	}
+	now := time.Now()
+	current.Spec.HolderIdentity = ""
+	current.Spec.LeaseDurationSeconds = 1
+	current.Spec.RenewTime = &microTime{now}
+	if err := e.client.updateLease(ctx, current); err != nil {
+		log.Printf("leader election: failed to release lease: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLeaseAPI serves a single Lease the way the Kubernetes API server does:
+// creating an existing lease or updating a stale resourceVersion conflicts
+type fakeLeaseAPI struct {
+	mu      sync.Mutex
+	lease   *lease
+	version int
+	// beforeUpdate, when set, runs before each update is applied, e.g. to
+	// simulate another replica writing first
+	beforeUpdate func()
+}
+
+func (f *fakeLeaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodPut && f.beforeUpdate != nil {
+		f.beforeUpdate()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		if f.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(f.lease)
+	case http.MethodPost, http.MethodPut:
+		var l lease
+		if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if (r.Method == http.MethodPost) != (f.lease == nil) ||
+			r.Method == http.MethodPut && l.Metadata.ResourceVersion != f.lease.Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.version++
+		l.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		f.lease = &l
+		json.NewEncoder(w).Encode(f.lease)
+	}
+}
+
+// set replaces the lease's spec as another replica would
+func (f *fakeLeaseAPI) set(spec leaseSpec) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version++
+	f.lease.Spec = spec
+	f.lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+}
+
+// spec returns the lease's current spec
+func (f *fakeLeaseAPI) spec() leaseSpec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lease == nil {
+		return leaseSpec{}
+	}
+	return f.lease.Spec
+}
+
+// newTestElectors starts a fake API server and returns it with an elector
+// for each identity
+func newTestElectors(t *testing.T, identities ...string) (*fakeLeaseAPI, []*elector) {
+	t.Helper()
+	api := &fakeLeaseAPI{}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("test-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := leaderElectionConfig{
+		Enabled:       true,
+		Namespace:     "sync",
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	}
+	config.setDefaults()
+	var electors []*elector
+	for _, id := range identities {
+		client := &kubeClient{baseURL: srv.URL, tokenFile: token, client: srv.Client()}
+		electors = append(electors, &elector{client: client, config: config, identity: id})
+	}
+	return api, electors
+}
+
+func TestLeaseAcquireAndTakeover(t *testing.T) {
+	api, electors := newTestElectors(t, "a", "b")
+	a, b := electors[0], electors[1]
+	ctx := context.Background()
+
+	if ok, err := a.tryAcquireOrRenew(ctx); !ok || err != nil {
+		t.Fatalf("Expected a to create the lease, got %v, %v", ok, err)
+	}
+	if spec := api.spec(); spec.HolderIdentity != "a" || spec.LeaseDurationSeconds != 1 || spec.LeaseTransitions != 0 {
+		t.Errorf("Unexpected lease after creation: %+v", spec)
+	}
+	if ok, err := b.tryAcquireOrRenew(ctx); ok || err != nil {
+		t.Fatalf("Expected b to wait for a's lease, got %v, %v", ok, err)
+	}
+
+	// a renewing restarts b's expiry clock, however long b has waited
+	b.observedAt = b.observedAt.Add(-time.Minute)
+	acquired := api.spec().AcquireTime.Time
+	if ok, err := a.tryAcquireOrRenew(ctx); !ok || err != nil {
+		t.Fatalf("Expected a to renew, got %v, %v", ok, err)
+	}
+	if spec := api.spec(); !spec.AcquireTime.Equal(acquired) || !spec.RenewTime.After(acquired) {
+		t.Errorf("Expected a renewal to keep the acquire time, got %+v", spec)
+	}
+	if ok, err := b.tryAcquireOrRenew(ctx); ok || err != nil {
+		t.Fatalf("Expected b to wait for a's renewed lease, got %v, %v", ok, err)
+	}
+
+	// Once a stops renewing for the lease duration, by b's clock, b takes over
+	b.observedAt = b.observedAt.Add(-2 * time.Second)
+	if ok, err := b.tryAcquireOrRenew(ctx); !ok || err != nil {
+		t.Fatalf("Expected b to take over the expired lease, got %v, %v", ok, err)
+	}
+	if spec := api.spec(); spec.HolderIdentity != "b" || spec.LeaseTransitions != 1 || !spec.AcquireTime.After(acquired) {
+		t.Errorf("Unexpected lease after a takeover: %+v", spec)
+	}
+	if ok, err := a.tryAcquireOrRenew(ctx); ok || err != nil {
+		t.Errorf("Expected a to find b holding the lease, got %v, %v", ok, err)
+	}
+}
+
+func TestLeaseConflict(t *testing.T) {
+	api, electors := newTestElectors(t, "a", "b")
+	a, b := electors[0], electors[1]
+	ctx := context.Background()
+	if ok, err := a.tryAcquireOrRenew(ctx); !ok || err != nil {
+		t.Fatalf("Expected a to create the lease, got %v, %v", ok, err)
+	}
+
+	// b reads the expired lease, but a renews it before b's update lands
+	b.observe(api.spec(), time.Now().Add(-time.Minute))
+	api.beforeUpdate = func() {
+		api.beforeUpdate = nil
+		spec := api.spec()
+		spec.RenewTime = &microTime{time.Now()}
+		api.set(spec)
+	}
+	if ok, err := b.tryAcquireOrRenew(ctx); ok || !errors.Is(err, errLeaseConflict) {
+		t.Fatalf("Expected a conflict, got %v, %v", ok, err)
+	}
+	if holder := api.spec().HolderIdentity; holder != "a" {
+		t.Errorf("Expected a to keep the lease, got %q", holder)
+	}
+
+	// A concurrent create conflicts as well
+	_, electors = newTestElectors(t, "c")
+	c := electors[0]
+	c.client.createLease(ctx, &lease{Metadata: leaseMetadata{Name: c.config.LeaseName, Namespace: c.config.Namespace}})
+	if err := c.client.createLease(ctx, &lease{Metadata: leaseMetadata{Name: c.config.LeaseName, Namespace: c.config.Namespace}}); !errors.Is(err, errLeaseConflict) {
+		t.Errorf("Expected creating an existing lease to conflict, got %v", err)
+	}
+}
+
+func TestLeaseReleaseOnShutdown(t *testing.T) {
+	api, electors := newTestElectors(t, "a", "b")
+	a, b := electors[0], electors[1]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leading := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		a.run(ctx, func(leadCtx context.Context) {
+			close(leading)
+			<-leadCtx.Done()
+		})
+	}()
+	select {
+	case <-leading:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a never became the leader")
+	}
+	if !a.isLeader() {
+		t.Error("Expected a to report leadership while leading")
+	}
+	cancel()
+	<-stopped
+	if a.isLeader() {
+		t.Error("Expected a to stop reporting leadership")
+	}
+	if spec := api.spec(); spec.HolderIdentity != "" || spec.LeaseDurationSeconds != 1 {
+		t.Errorf("Expected the lease to be released, got %+v", spec)
+	}
+
+	// b takes over at once, without waiting for the lease to expire
+	if ok, err := b.tryAcquireOrRenew(context.Background()); !ok || err != nil {
+		t.Errorf("Expected b to take over the released lease, got %v, %v", ok, err)
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	api, electors := newTestElectors(t, "a")
+	a := electors[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leads := make(chan context.Context, 2)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		a.run(ctx, func(leadCtx context.Context) {
+			leads <- leadCtx
+			<-leadCtx.Done()
+		})
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	leadCtx := <-leads
+
+	// Another replica takes the lease, e.g. after a network partition
+	api.set(leaseSpec{HolderIdentity: "b", LeaseDurationSeconds: 3600, RenewTime: &microTime{time.Now()}})
+	select {
+	case <-leadCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected leadership to end when another replica took the lease")
+	}
+	select {
+	case <-leads:
+		t.Error("Expected a not to lead again while b's lease is valid")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
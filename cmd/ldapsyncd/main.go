@@ -0,0 +1,132 @@
+// Command ldapsyncd periodically snapshots configured LDAP populations,
+// publishes the differences between consecutive snapshots as events and
+// webhooks, and serves the latest snapshots over HTTP.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	_ "github.com/openshift-eng/go-ldap-redhat/vault" // registers the vault: credentials provider
+)
+
+// shutdownTimeout bounds how long in-flight HTTP requests may take on exit
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	configPath := flag.String("config", "ldapsyncd.yaml", "daemon configuration file")
+	listen := flag.String("listen", "", "HTTP listen address (overrides listen in the config file)")
+	once := flag.Bool("once", false, "run every watch once and exit")
+	version := flag.Bool("version", false, "print version information")
+	flag.Parse()
+
+	if *version {
+		fmt.Printf("ldapsyncd %s\n", ldap_redhat.BuildInfo().Version)
+		return
+	}
+
+	config, err := loadDaemonConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *listen != "" {
+		config.Listen = *listen
+	}
+	sched, err := parseSchedule(config.Schedule)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var policy ldap_redhat.MaskingPolicy
+	if config.MaskingPolicy != "" {
+		if policy, err = ldap_redhat.LoadMaskingPolicy(config.MaskingPolicy); err != nil {
+			log.Fatal(err)
+		}
+	}
+	st, err := newStore(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s, err := ldap_redhat.NewSearcherWithDefaults()
+	if err != nil {
+		log.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer s.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sy, err := newSyncer(ctx, s, st, config, policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var el *elector
+	if config.LeaderElection.Enabled && !*once {
+		if el, err = newElector(config.LeaderElection); err != nil {
+			log.Fatal(err)
+		}
+		if st == nil {
+			log.Printf("leader election without data_dir or s3: a new leader starts from a fresh baseline")
+		}
+	}
+
+	if *once {
+		if err := sy.runAll(ctx); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	server := &http.Server{
+		Addr:              config.Listen,
+		Handler:           newHandler(ctx, sy, el),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf("serving on %s", config.Listen)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+	}()
+
+	if config.WatchesFile != "" {
+		go sy.pollWatchesFile(ctx, config.WatchesFile, config.watchesData)
+	}
+
+	// Run once at startup, or on becoming the leader, so the latest snapshot
+	// is current, then follow the schedules
+	lead := func(ctx context.Context) {
+		sy.runAll(ctx)
+		sy.scheduleLoop(ctx)
+	}
+	if el == nil {
+		lead(ctx)
+	} else {
+		// Followers refresh from the shared store so they serve the
+		// leader's latest snapshots
+		go sy.loop(ctx, sched, func(ctx context.Context) {
+			if !el.isLeader() {
+				sy.reload(ctx)
+			}
+		})
+		el.run(ctx, func(ctx context.Context) {
+			sy.reload(ctx)
+			lead(ctx)
+		})
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+}
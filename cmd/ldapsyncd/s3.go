@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// s3Config selects an S3-compatible bucket. Credentials are read from the
+// files when set, otherwise from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+type s3Config struct {
+	Endpoint            string `yaml:"endpoint"` // default https://s3.<region>.amazonaws.com
+	Region              string `yaml:"region"`
+	Bucket              string `yaml:"bucket"`
+	Prefix              string `yaml:"prefix"`     // prepended to every key, e.g. "ldapsyncd/"
+	PathStyle           bool   `yaml:"path_style"` // https://endpoint/bucket/key, as MinIO and Ceph RGW expect
+	AccessKeyIDFile     string `yaml:"access_key_id_file"`
+	SecretAccessKeyFile string `yaml:"secret_access_key_file"`
+}
+
+// s3Store is a store in an S3 bucket, signed with AWS Signature Version 4
+type s3Store struct {
+	base      *url.URL // bucket URL, ending in "/"
+	region    string
+	prefix    string
+	accessKey string
+	secretKey string
+	token     string
+	client    *http.Client
+}
+
+func newS3Store(c s3Config) (*s3Store, error) {
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("s3: bucket is required")
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = "https://s3." + c.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(strings.TrimSuffix(c.Endpoint, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", c.Endpoint)
+	}
+	if c.PathStyle {
+		base.Path += "/" + c.Bucket + "/"
+	} else {
+		base.Host = c.Bucket + "." + base.Host
+		base.Path += "/"
+	}
+
+	st := &s3Store{
+		base:      base,
+		region:    c.Region,
+		prefix:    c.Prefix,
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+	if c.AccessKeyIDFile != "" {
+		st.accessKey = ldap_redhat.ReadSecretFile(c.AccessKeyIDFile)
+	}
+	if c.SecretAccessKeyFile != "" {
+		st.secretKey = ldap_redhat.ReadSecretFile(c.SecretAccessKeyFile)
+	}
+	if st.accessKey == "" || st.secretKey == "" {
+		return nil, fmt.Errorf("s3: no credentials; set access_key_id_file/secret_access_key_file or AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY")
+	}
+	return st, nil
+}
+
+func (st *s3Store) String() string { return st.base.String() + st.prefix }
+
+func (st *s3Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := st.do(ctx, http.MethodPut, st.prefix+key, nil, data)
+	if err != nil {
+		return fmt.Errorf("s3: failed to put %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (st *s3Store) Get(ctx context.Context, key string) ([]byte, time.Time, error) {
+	resp, err := st.do(ctx, http.MethodGet, st.prefix+key, nil, nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("s3: failed to get %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("s3: failed to get %s: %w", key, err)
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return data, modTime, nil
+}
+
+func (st *s3Store) Delete(ctx context.Context, key string) error {
+	resp, err := st.do(ctx, http.MethodDelete, st.prefix+key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("s3: failed to delete %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listBucketResult is the ListObjectsV2 response
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (st *s3Store) List(ctx context.Context, prefix string) ([]storedObject, error) {
+	var objects []storedObject
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {st.prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := st.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("s3: failed to list %s: %w", prefix, err)
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: failed to list %s: %w", prefix, err)
+		}
+		for _, c := range result.Contents {
+			objects = append(objects, storedObject{Key: strings.TrimPrefix(c.Key, st.prefix), Modified: c.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// s3Error is the XML error body returned by S3
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do sends a signed request and returns the response for 2xx statuses. A
+// missing object is reported as errNotStored.
+func (st *s3Store) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	req, err := st.newRequest(ctx, method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	st.sign(req, body, time.Now())
+	req.Header.Set("User-Agent", "ldapsyncd/"+ldap_redhat.Version)
+
+	resp, err := st.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, errNotStored
+	}
+	var e s3Error
+	xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&e)
+	if e.Code != "" {
+		return nil, fmt.Errorf("%s: %s (%s)", resp.Status, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// newRequest returns an unsigned request for key, or for the bucket when key
+// is empty
+func (st *s3Store) newRequest(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Request, error) {
+	u := *st.base
+	u.RawPath = st.base.EscapedPath() + encodePath(key)
+	u.Path += key
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	}
+	return req, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req, signing the host and
+// every header already set on it.
+func (st *s3Store) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if st.token != "" {
+		req.Header.Set("X-Amz-Security-Token", st.token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + st.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+st.secretKey), date)
+	key = hmacSHA256(key, st.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		st.accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query sorted by key with RFC 3986 escaping, as
+// Signature Version 4 requires
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePath escapes each segment of an object key
+func encodePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = uriEncode(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode escapes everything but RFC 3986 unreserved characters
+func uriEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// errNotStored is returned by store.Get for a missing key
+var errNotStored = errors.New("not stored")
+
+// storedTimeFormat names history objects so they sort chronologically
+const storedTimeFormat = "20060102T150405Z"
+
+// store keeps the daemon's snapshots and deltas. Keys are slash-separated:
+//
+//	<watch>/latest.jsonl                 latest snapshot, used to resume after a restart
+//	<watch>/snapshots/<time>.jsonl       snapshot history, masked
+//	<watch>/deltas/<time>.json           each non-empty diff, as posted to webhooks
+type store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, time.Time, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]storedObject, error)
+	String() string
+}
+
+// storedObject is a key returned by store.List
+type storedObject struct {
+	Key      string
+	Modified time.Time
+}
+
+// retentionConfig limits the snapshot and delta history of each watch. Zero
+// values keep everything; latest.jsonl is never pruned.
+type retentionConfig struct {
+	Keep   int           `yaml:"keep"`    // newest objects kept per watch and kind
+	MaxAge time.Duration `yaml:"max_age"` // older objects are deleted
+}
+
+// dirStore is a store in a local directory
+type dirStore struct {
+	dir string
+}
+
+func (d dirStore) String() string { return d.dir }
+
+func (d dirStore) path(key string) string {
+	return filepath.Join(d.dir, filepath.FromSlash(key))
+}
+
+// Put writes through a temporary file and renames it into place. Files are
+// created with mode 0600 since snapshots include HR data.
+func (d dirStore) Put(ctx context.Context, key string, data []byte) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d dirStore) Get(ctx context.Context, key string) ([]byte, time.Time, error) {
+	path := d.path(key)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, time.Time{}, errNotStored
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	return data, info.ModTime(), err
+}
+
+func (d dirStore) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(d.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (d dirStore) List(ctx context.Context, prefix string) ([]storedObject, error) {
+	dir := d.path(prefix)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var objects []storedObject
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, storedObject{Key: strings.TrimSuffix(prefix, "/") + "/" + e.Name(), Modified: info.ModTime()})
+	}
+	return objects, nil
+}
+
+// newStore returns the configured store, or nil when snapshots are only kept
+// in memory
+func newStore(c daemonConfig) (store, error) {
+	switch {
+	case c.S3 != nil:
+		return newS3Store(*c.S3)
+	case c.DataDir != "":
+		if err := os.MkdirAll(c.DataDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		return dirStore{dir: c.DataDir}, nil
+	}
+	return nil, nil
+}
+
+// loadLatest reads the latest snapshot of a watch, or nil if none is stored
+func loadLatest(ctx context.Context, st store, watch string) (*ldap_redhat.Snapshot, error) {
+	data, modTime, err := st.Get(ctx, watch+"/latest.jsonl")
+	if errors.Is(err, errNotStored) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ldap_redhat.ReadSnapshot(bytes.NewReader(data), modTime)
+}
+
+// saveRun stores the latest snapshot of a watch, its masked copy in the
+// history, and the delta when payload has events, then prunes the history
+func saveRun(ctx context.Context, st store, watch string, sn *ldap_redhat.Snapshot, policy ldap_redhat.MaskingPolicy, payload *webhookPayload, retention retentionConfig) error {
+	stamp := sn.TakenAt.UTC().Format(storedTimeFormat)
+
+	var buf bytes.Buffer
+	if err := sn.Write(&buf); err != nil {
+		return err
+	}
+	if err := st.Put(ctx, watch+"/latest.jsonl", buf.Bytes()); err != nil {
+		return err
+	}
+	buf.Reset()
+	if err := sn.WriteMasked(&buf, policy); err != nil {
+		return err
+	}
+	if err := st.Put(ctx, watch+"/snapshots/"+stamp+".jsonl", buf.Bytes()); err != nil {
+		return err
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if err := st.Put(ctx, watch+"/deltas/"+stamp+".json", data); err != nil {
+			return err
+		}
+	}
+	for _, kind := range []string{"snapshots", "deltas"} {
+		if err := prune(ctx, st, watch+"/"+kind+"/", retention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune deletes history objects under prefix beyond the retention limits.
+// Keys embed their time, so sorting them orders objects by age.
+func prune(ctx context.Context, st store, prefix string, retention retentionConfig) error {
+	if retention.Keep <= 0 && retention.MaxAge <= 0 {
+		return nil
+	}
+	objects, err := st.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key > objects[j].Key })
+	cutoff := time.Now().Add(-retention.MaxAge)
+	for i, obj := range objects {
+		expired := retention.MaxAge > 0 && obj.Modified.Before(cutoff)
+		if (retention.Keep > 0 && i >= retention.Keep) || expired {
+			if err := st.Delete(ctx, obj.Key); err != nil {
+				log.Printf("retention: %v", err)
+			}
+		}
+	}
+	return nil
+}
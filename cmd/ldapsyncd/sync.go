@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// Event types emitted for each difference between consecutive snapshots
+const (
+	eventUserAdded   = "user.added"
+	eventUserRemoved = "user.removed"
+	eventUserChanged = "user.changed"
+)
+
+// event is one user-level change, written to stdout as a JSON line and
+// delivered to webhooks. User is the current record (the last known record
+// for removals) with the masking policy applied.
+type event struct {
+	Type   string         `json:"type"`
+	Watch  string         `json:"watch"`
+	UID    string         `json:"uid"`
+	Fields []string       `json:"fields,omitempty"`
+	Time   time.Time      `json:"time"`
+	User   map[string]any `json:"user"`
+}
+
+// watchState is the latest result of one watch
+type watchState struct {
+	Name     string                    `json:"name"`
+	Filter   string                    `json:"filter"`
+	LastRun  time.Time                 `json:"last_run,omitzero"`
+	LastErr  string                    `json:"last_error,omitempty"`
+	Users    int                       `json:"users"`
+	TakenAt  time.Time                 `json:"taken_at,omitzero"`
+	Diff     *ldap_redhat.SnapshotDiff `json:"-"`
+	snapshot *ldap_redhat.Snapshot
+}
+
+// syncer takes the configured snapshots and publishes their differences
+type syncer struct {
+	searcher *ldap_redhat.Searcher
+	store    store // nil keeps snapshots in memory only
+	config   daemonConfig
+	policy   ldap_redhat.MaskingPolicy
+	webhooks []*webhook
+	events   io.Writer
+
+	runMu   sync.Mutex // serializes scheduled and on-demand runs
+	mu      sync.RWMutex
+	watches []watchConfig
+	states  map[string]*watchState
+	changed chan struct{} // signalled when the watches are replaced
+}
+
+func newSyncer(ctx context.Context, s *ldap_redhat.Searcher, st store, config daemonConfig, policy ldap_redhat.MaskingPolicy) (*syncer, error) {
+	sy := &syncer{
+		searcher: s,
+		store:    st,
+		config:   config,
+		policy:   policy,
+		events:   os.Stdout,
+		watches:  config.Watches,
+		states:   make(map[string]*watchState, len(config.Watches)),
+		changed:  make(chan struct{}, 1),
+	}
+	for _, h := range config.Webhooks {
+		wh, err := newWebhook(h)
+		if err != nil {
+			return nil, err
+		}
+		sy.webhooks = append(sy.webhooks, wh)
+	}
+	for _, w := range config.Watches {
+		sy.states[w.Name] = &watchState{Name: w.Name, Filter: w.Filter}
+	}
+	if st != nil {
+		log.Printf("storing snapshots in %s", st)
+		sy.reload(ctx)
+	}
+	return sy, nil
+}
+
+// reload replaces the latest snapshot of every watch with the stored one.
+// It resumes after a restart without missing or replaying changes, and lets
+// a new leader continue from the previous leader's last run.
+func (sy *syncer) reload(ctx context.Context) {
+	if sy.store == nil {
+		return
+	}
+	for _, w := range sy.currentWatches() {
+		sn, err := loadLatest(ctx, sy.store, w.Name)
+		if err != nil {
+			log.Printf("watch %s: ignoring stored snapshot: %v", w.Name, err)
+			continue
+		}
+		if sn == nil {
+			continue
+		}
+		sy.mu.Lock()
+		state, ok := sy.states[w.Name]
+		if !ok {
+			sy.mu.Unlock()
+			continue
+		}
+		state.snapshot = sn
+		state.Users = len(sn.Users)
+		state.TakenAt = sn.TakenAt
+		sy.mu.Unlock()
+	}
+}
+
+// runAll runs every watch once, logging failures. It returns a
+// *ldap_redhat.BatchError listing the watches that failed, if any.
+func (sy *syncer) runAll(ctx context.Context) error {
+	sy.runMu.Lock()
+	defer sy.runMu.Unlock()
+	watches := sy.currentWatches()
+	var failed []ldap_redhat.ItemError
+	for i, w := range watches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := sy.run(ctx, w); err != nil {
+			log.Printf("watch %s: %v", w.Name, err)
+			failed = append(failed, ldap_redhat.ItemError{Index: i, Item: w.Name, Err: err})
+		}
+	}
+	if len(failed) > 0 {
+		return &ldap_redhat.BatchError{Total: len(watches), Errors: failed}
+	}
+	return nil
+}
+
+// run snapshots one watch, diffs it against the previous snapshot and
+// publishes the changes. The first snapshot of a watch only sets the baseline.
+func (sy *syncer) run(ctx context.Context, w watchConfig) error {
+	started := time.Now()
+	snapCtx := ctx
+	if w.Attributes != nil {
+		// Without the termination date every user would look active
+		attrs := append(slices.Clip(w.Attributes), ldap_redhat.AttrRhatTermDate)
+		snapCtx = ldap_redhat.WithRequestScope(ctx, ldap_redhat.RequestScope{Attributes: attrs})
+	}
+	sn, err := sy.searcher.TakeSnapshot(snapCtx, w.Filter)
+
+	sy.mu.Lock()
+	state, ok := sy.states[w.Name]
+	if !ok || !sy.watchUnchanged(w) {
+		// Removed or redefined while the snapshot was taken
+		sy.mu.Unlock()
+		return nil
+	}
+	state.LastRun = started
+	if err != nil {
+		state.LastErr = err.Error()
+		sy.mu.Unlock()
+		return fmt.Errorf("snapshot failed: %w", err)
+	}
+	previous := state.snapshot
+	var diff *ldap_redhat.SnapshotDiff
+	if previous != nil {
+		d := ldap_redhat.DiffSnapshots(previous, sn)
+		diff = &d
+	}
+	state.LastErr = ""
+	state.snapshot = sn
+	state.Users = len(sn.Users)
+	state.TakenAt = sn.TakenAt
+	state.Diff = diff
+	sy.mu.Unlock()
+
+	var payload *webhookPayload
+	if diff != nil {
+		log.Printf("watch %s: %d users, %d added, %d removed, %d changed",
+			w.Name, len(sn.Users), len(diff.Added), len(diff.Removed), len(diff.Changed))
+	} else {
+		log.Printf("watch %s: baseline snapshot of %d users", w.Name, len(sn.Users))
+	}
+	if diff != nil && !diff.Empty() {
+		payload = &webhookPayload{
+			Watch:      w.Name,
+			TakenAt:    sn.TakenAt,
+			PreviousAt: previous.TakenAt,
+			Added:      len(diff.Added),
+			Removed:    len(diff.Removed),
+			Changed:    len(diff.Changed),
+			Events:     sy.diffEvents(w.Name, *diff, sn.TakenAt),
+		}
+	}
+	if sy.store != nil {
+		if err := saveRun(ctx, sy.store, w.Name, sn, sy.policy, payload, sy.config.Retention); err != nil {
+			log.Printf("watch %s: failed to store snapshot: %v", w.Name, err)
+		}
+	}
+	if payload != nil {
+		sy.writeEvents(payload.Events)
+		sy.deliver(ctx, w.Sinks, *payload)
+	}
+	return nil
+}
+
+// diffEvents converts a diff into events with the masking policy applied
+func (sy *syncer) diffEvents(watch string, d ldap_redhat.SnapshotDiff, at time.Time) []event {
+	events := make([]event, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for _, u := range d.Added {
+		events = append(events, event{Type: eventUserAdded, Watch: watch, UID: u.UID, Time: at, User: sy.policy.Apply(u)})
+	}
+	for _, u := range d.Removed {
+		events = append(events, event{Type: eventUserRemoved, Watch: watch, UID: u.UID, Time: at, User: sy.policy.Apply(u)})
+	}
+	for _, c := range d.Changed {
+		events = append(events, event{Type: eventUserChanged, Watch: watch, UID: c.UID, Fields: c.Fields, Time: at, User: sy.policy.Apply(c.After)})
+	}
+	return events
+}
+
+// writeEvents writes events to the event stream as JSON lines
+func (sy *syncer) writeEvents(events []event) {
+	enc := json.NewEncoder(sy.events)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			log.Printf("failed to write event: %v", err)
+			return
+		}
+	}
+}
+
+// deliver posts payload to the webhooks named in sinks, or to every webhook
+// when sinks is empty, logging failures
+func (sy *syncer) deliver(ctx context.Context, sinks []string, payload webhookPayload) {
+	for _, wh := range sy.webhooks {
+		if len(sinks) > 0 && !slices.Contains(sinks, wh.name) {
+			continue
+		}
+		if err := wh.send(ctx, payload); err != nil {
+			log.Printf("watch %s: %v", payload.Watch, err)
+		}
+	}
+}
+
+// state returns a copy of a watch's state and its latest snapshot
+func (sy *syncer) state(name string) (watchState, *ldap_redhat.Snapshot, bool) {
+	sy.mu.RLock()
+	defer sy.mu.RUnlock()
+	state, ok := sy.states[name]
+	if !ok {
+		return watchState{}, nil, false
+	}
+	return *state, state.snapshot, true
+}
+
+// currentWatches returns the watches in effect
+func (sy *syncer) currentWatches() []watchConfig {
+	sy.mu.RLock()
+	defer sy.mu.RUnlock()
+	return sy.watches
+}
+
+// watchUnchanged reports whether w is still defined with the same filter and
+// attributes. sy.mu must be held.
+func (sy *syncer) watchUnchanged(w watchConfig) bool {
+	for _, current := range sy.watches {
+		if current.Name == w.Name {
+			return current.sameSnapshot(w)
+		}
+	}
+	return false
+}
+
+// setWatches replaces the watches in effect. Watches whose filter and
+// attributes are unchanged keep their latest snapshot; new and redefined
+// watches start from a fresh baseline, and removed watches are dropped.
+func (sy *syncer) setWatches(watches []watchConfig) {
+	sy.mu.Lock()
+	states := make(map[string]*watchState, len(watches))
+	for _, w := range watches {
+		if state, ok := sy.states[w.Name]; ok && sy.watchUnchanged(w) {
+			states[w.Name] = state
+			continue
+		}
+		states[w.Name] = &watchState{Name: w.Name, Filter: w.Filter}
+	}
+	sy.watches = watches
+	sy.states = states
+	sy.mu.Unlock()
+
+	log.Printf("watches reloaded: %d watches", len(watches))
+	select {
+	case sy.changed <- struct{}{}:
+	default:
+	}
+}
+
+// scheduled is a watch and the time of its next run
+type scheduled struct {
+	watch watchConfig
+	next  time.Time
+}
+
+// reschedule returns the next runs of watches, which replace those of pending
+// at now. pending is nil on startup, when every watch waits for its
+// schedule; afterwards new watches, and those whose snapshots are no longer
+// comparable, run at once, watches with the same interval keep their next
+// run, and the others follow their new schedule.
+func reschedule(pending map[string]scheduled, watches []watchConfig, now time.Time) map[string]scheduled {
+	next := make(map[string]scheduled, len(watches))
+	for _, w := range watches {
+		old, ok := pending[w.Name]
+		switch {
+		case pending != nil && (!ok || !old.watch.sameSnapshot(w)):
+			next[w.Name] = scheduled{w, now}
+		case ok && old.watch.Interval == w.Interval:
+			next[w.Name] = scheduled{w, old.next}
+		default:
+			next[w.Name] = scheduled{w, w.sched.Next(now)}
+		}
+	}
+	return next
+}
+
+// scheduleLoop runs each watch at the times its schedule yields until ctx is
+// done. When the watches are replaced, new and redefined watches run at once
+// to set their baseline and the others keep their next run.
+func (sy *syncer) scheduleLoop(ctx context.Context) {
+	pending := reschedule(nil, sy.currentWatches(), time.Now())
+
+	for {
+		var due time.Time
+		for _, p := range pending {
+			if !p.next.IsZero() && (due.IsZero() || p.next.Before(due)) {
+				due = p.next
+			}
+		}
+		var timer *time.Timer
+		var fire <-chan time.Time
+		if !due.IsZero() {
+			timer = time.NewTimer(time.Until(due))
+			fire = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return
+		case <-sy.changed:
+			stopTimer(timer)
+			pending = reschedule(pending, sy.currentWatches(), time.Now())
+		case <-fire:
+			now := time.Now()
+			sy.runMu.Lock()
+			for name, p := range pending {
+				if p.next.IsZero() || p.next.After(now) {
+					continue
+				}
+				if err := sy.run(ctx, p.watch); err != nil {
+					log.Printf("watch %s: %v", name, err)
+				}
+				p.next = p.watch.sched.Next(time.Now())
+				pending[name] = p
+			}
+			sy.runMu.Unlock()
+		}
+	}
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// loop calls fn at each time sched yields until ctx is done
+func (sy *syncer) loop(ctx context.Context, sched schedule, fn func(context.Context)) {
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("schedule %q never fires again", sy.config.Schedule)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			fn(ctx)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReschedule(t *testing.T) {
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	watch := func(name, filter string, interval time.Duration) watchConfig {
+		return watchConfig{Name: name, Filter: filter, Interval: interval, sched: everySchedule(max(interval, time.Hour))}
+	}
+
+	// On startup every watch waits for its schedule
+	pending := reschedule(nil, []watchConfig{watch("eng", "(ou=Engineering)", 0), watch("sales", "(ou=Sales)", 0)}, now)
+	for name, p := range pending {
+		if !p.next.Equal(now.Add(time.Hour)) {
+			t.Errorf("Watch %s is due at %s, want its first scheduled run", name, p.next)
+		}
+	}
+
+	later := now.Add(10 * time.Minute)
+	kept := pending["eng"].next
+	pending = reschedule(pending, []watchConfig{
+		watch("eng", "(ou=Engineering)", 0),          // unchanged
+		watch("sales", "(ou=Sales)", 30*time.Minute), // same population, new interval
+		watch("support", "(ou=Support)", 0),          // new
+		watch("legal", "(ou=Legal)", 0),              // new
+	}, later)
+	if len(pending) != 4 {
+		t.Fatalf("Got %d watches, want 4", len(pending))
+	}
+	for name, want := range map[string]time.Time{
+		"eng":     kept,
+		"sales":   later.Add(time.Hour),
+		"support": later,
+		"legal":   later,
+	} {
+		if got := pending[name].next; !got.Equal(want) {
+			t.Errorf("Watch %s is due at %s, want %s", name, got, want)
+		}
+	}
+
+	// A redefined population needs a new baseline at once; removed watches
+	// are dropped
+	pending = reschedule(pending, []watchConfig{watch("eng", "(ou=Eng*)", 0)}, later)
+	if len(pending) != 1 || !pending["eng"].next.Equal(later) {
+		t.Errorf("Expected the redefined watch to run at once, got %+v", pending)
+	}
+}
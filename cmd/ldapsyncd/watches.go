@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"gopkg.in/yaml.v3"
+)
+
+// watchesPollInterval is how often watches_file is checked for changes.
+// ConfigMap volumes are updated by swapping a symlink, which file watches
+// miss, so the file is polled.
+const watchesPollInterval = 10 * time.Second
+
+// watchConfig is one population snapshotted on its own schedule
+type watchConfig struct {
+	Name   string `yaml:"name"`
+	Filter string `yaml:"filter"` // LDAP filter, all users when empty
+	// Interval runs the watch every Interval instead of on the daemon's
+	// schedule.
+	Interval time.Duration `yaml:"interval"`
+	// Sinks names the webhooks that receive this watch's diffs; all
+	// webhooks when empty.
+	Sinks []string `yaml:"sinks"`
+	// Attributes limits the LDAP attributes snapshotted and compared, e.g.
+	// {"cn", "title", "manager"}; uid is always kept, and so is
+	// rhatTermDate, which the diff's hires and terminations are judged by.
+	// All user attributes when empty.
+	Attributes []string `yaml:"attributes"`
+
+	sched schedule // resolved from Interval or the daemon's schedule
+}
+
+// sameSnapshot reports whether w and other snapshot the same population, so
+// that an existing baseline remains comparable
+func (w watchConfig) sameSnapshot(other watchConfig) bool {
+	return w.Filter == other.Filter && slices.Equal(w.Attributes, other.Attributes)
+}
+
+// watchesFile is the format of watches_file
+type watchesFile struct {
+	Watches []watchConfig `yaml:"watches"`
+}
+
+// loadWatchesFile reads the watches declared in path
+func loadWatchesFile(path string) ([]watchConfig, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var f watchesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return f.Watches, data, nil
+}
+
+// resolveWatches validates watches against the daemon configuration and
+// sets their schedules
+func resolveWatches(c daemonConfig, watches []watchConfig) ([]watchConfig, error) {
+	if len(watches) == 0 {
+		return nil, fmt.Errorf("no watches configured")
+	}
+	defaultSched, err := parseSchedule(c.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	sinks := map[string]bool{}
+	for _, h := range c.Webhooks {
+		if h.Name != "" {
+			sinks[h.Name] = true
+		}
+	}
+	seen := map[string]bool{}
+	out := make([]watchConfig, len(watches))
+	for i, w := range watches {
+		if !watchNamePattern.MatchString(w.Name) {
+			return nil, fmt.Errorf("invalid watch name %q", w.Name)
+		}
+		if seen[w.Name] {
+			return nil, fmt.Errorf("duplicate watch name %q", w.Name)
+		}
+		seen[w.Name] = true
+		if err := ldap_redhat.CheckAttributes(w.Attributes); err != nil {
+			return nil, fmt.Errorf("watch %s: %w", w.Name, err)
+		}
+		for _, sink := range w.Sinks {
+			if !sinks[sink] {
+				return nil, fmt.Errorf("watch %s: no webhook named %q", w.Name, sink)
+			}
+		}
+		switch {
+		case w.Interval < 0 || (w.Interval > 0 && w.Interval < time.Second):
+			return nil, fmt.Errorf("watch %s: interval must be at least 1s", w.Name)
+		case w.Interval > 0:
+			w.sched = everySchedule(w.Interval)
+		default:
+			w.sched = defaultSched
+		}
+		out[i] = w
+	}
+	return out, nil
+}
+
+// pollWatchesFile reloads watches_file whenever its content changes until
+// ctx is done. Invalid files are logged and the current watches kept.
+func (sy *syncer) pollWatchesFile(ctx context.Context, path string, current []byte) {
+	ticker := time.NewTicker(watchesPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		watches, data, err := loadWatchesFile(path)
+		if err != nil {
+			log.Printf("watches: %v", err)
+			continue
+		}
+		if bytes.Equal(data, current) {
+			continue
+		}
+		current = data
+		resolved, err := resolveWatches(sy.config, watches)
+		if err != nil {
+			log.Printf("watches: keeping current watches, %s is invalid: %v", path, err)
+			continue
+		}
+		sy.setWatches(resolved)
+	}
+}
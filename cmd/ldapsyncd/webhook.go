@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// webhookAttempts is how many times a delivery is tried before giving up
+const webhookAttempts = 3
+
+// webhookBackoff is the wait before the second attempt; each later attempt
+// waits one more backoff
+var webhookBackoff = time.Second
+
+// signatureHeader carries "sha256=<hex HMAC of the body>" when a webhook has
+// a secret
+const signatureHeader = "X-Ldapsyncd-Signature"
+
+// webhookPayload is the JSON body posted for each non-empty diff
+type webhookPayload struct {
+	Watch      string    `json:"watch"`
+	TakenAt    time.Time `json:"taken_at"`
+	PreviousAt time.Time `json:"previous_taken_at"`
+	Added      int       `json:"added"`
+	Removed    int       `json:"removed"`
+	Changed    int       `json:"changed"`
+	Events     []event   `json:"events"`
+}
+
+// webhook delivers payloads to one endpoint
+type webhook struct {
+	name   string
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func newWebhook(c webhookConfig) (*webhook, error) {
+	wh := &webhook{name: c.Name, url: c.URL, client: &http.Client{Timeout: c.Timeout}}
+	if c.SecretFile != "" {
+		secret := ldap_redhat.ReadSecretFile(c.SecretFile)
+		if secret == "" {
+			return nil, fmt.Errorf("webhook %s: secret file %s is missing or empty", c.URL, c.SecretFile)
+		}
+		wh.secret = []byte(secret)
+	}
+	return wh, nil
+}
+
+// send posts payload, retrying with backoff on network errors and 5xx or
+// 429 responses
+func (wh *webhook) send(ctx context.Context, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	var lastErr error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		retry, err := wh.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt == webhookAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * webhookBackoff):
+		}
+	}
+	return fmt.Errorf("webhook %s failed: %w", wh.url, lastErr)
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying
+func (wh *webhook) post(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ldapsyncd/"+ldap_redhat.Version)
+	if wh.secret != nil {
+		mac := hmac.New(sha256.New, wh.secret)
+		mac.Write(body)
+		req.Header.Set(signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return false, nil
+}
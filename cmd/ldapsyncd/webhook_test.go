@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestWebhook returns a webhook posting to a server answering with
+// statuses in turn, then 200, and signing with secret when it is not empty.
+// The server checks every signature and counts the requests it received.
+func newTestWebhook(t *testing.T, secret string, statuses ...int) (*webhook, *atomic.Int32) {
+	t.Helper()
+	old := webhookBackoff
+	webhookBackoff = time.Millisecond
+	t.Cleanup(func() { webhookBackoff = old })
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		body, _ := io.ReadAll(r.Body)
+		if secret != "" && !validSignature(r.Header.Get(signatureHeader), []byte(secret), body) {
+			t.Errorf("Request %d has an invalid signature %q", n, r.Header.Get(signatureHeader))
+		}
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Watch != "engineering" {
+			t.Errorf("Request %d has an unexpected payload %s (%v)", n, body, err)
+		}
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	config := webhookConfig{URL: srv.URL, Timeout: 5 * time.Second}
+	if secret != "" {
+		config.SecretFile = filepath.Join(t.TempDir(), "secret")
+		if err := os.WriteFile(config.SecretFile, []byte(secret+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wh, err := newWebhook(config)
+	if err != nil {
+		t.Fatalf("newWebhook failed: %v", err)
+	}
+	return wh, &requests
+}
+
+// validSignature checks header as a receiver would
+func validSignature(header string, secret, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func TestWebhookRetries(t *testing.T) {
+	payload := webhookPayload{Watch: "engineering", Added: 1}
+	for _, tc := range []struct {
+		name     string
+		statuses []int
+		requests int32
+		fail     bool
+	}{
+		{"delivered", nil, 1, false},
+		{"retried after a server error", []int{http.StatusServiceUnavailable}, 2, false},
+		{"retried when throttled", []int{http.StatusTooManyRequests, http.StatusBadGateway}, 3, false},
+		{"given up after every attempt", []int{500, 500, 500}, webhookAttempts, true},
+		{"not retried on a client error", []int{http.StatusBadRequest}, 1, true},
+	} {
+		wh, requests := newTestWebhook(t, "s3cret", tc.statuses...)
+		err := wh.send(context.Background(), payload)
+		if (err != nil) != tc.fail {
+			t.Errorf("%s: send returned %v", tc.name, err)
+		}
+		if got := requests.Load(); got != tc.requests {
+			t.Errorf("%s: got %d requests, want %d", tc.name, got, tc.requests)
+		}
+	}
+}
+
+func TestWebhookUnsigned(t *testing.T) {
+	var header atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header.Store(r.Header.Get(signatureHeader))
+	}))
+	defer srv.Close()
+	wh, err := newWebhook(webhookConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("newWebhook failed: %v", err)
+	}
+	if err := wh.send(context.Background(), webhookPayload{Watch: "engineering"}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if got := header.Load(); got != "" {
+		t.Errorf("Expected no signature without a secret, got %q", got)
+	}
+}
+
+func TestWebhookMissingSecret(t *testing.T) {
+	if _, err := newWebhook(webhookConfig{URL: "http://localhost", SecretFile: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("Expected an error for a missing secret file")
+	}
+}
+
+func TestWebhookCancelledRetry(t *testing.T) {
+	wh, requests := newTestWebhook(t, "", http.StatusServiceUnavailable)
+	webhookBackoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for requests.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if err := wh.send(ctx, webhookPayload{Watch: "engineering"}); err != context.Canceled {
+		t.Errorf("Expected the backoff to stop when cancelled, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// TestDirectoryCompatibility exercises the features most sensitive to
+// server differences against the suite directory: the embedded server, or a
+// real server seeded with the same fixtures by test/matrix, which sets
+// LDAP_TEST_FIXTURES to the number of generated users.
+func TestDirectoryCompatibility(t *testing.T) {
+	users := embeddedDirectoryUsers
+	if embeddedDirectory == nil {
+		n, err := strconv.Atoi(os.Getenv("LDAP_TEST_FIXTURES"))
+		if err != nil {
+			t.Skip("Skipping: LDAP_URL is not seeded with test fixtures (LDAP_TEST_FIXTURES not set)")
+		}
+		users = n
+	}
+
+	ctx := context.Background()
+	searcher, err := ldap_redhat.NewSearcherWithOptions(ctx,
+		ldap_redhat.WithServers(os.Getenv("LDAP_URL")),
+		ldap_redhat.WithBind(os.Getenv("LDAP_BIND_DN"), ldap_redhat.GetPasswordFromEnv()),
+		ldap_redhat.WithBaseDN(testserver.UsersBaseDN),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+
+	t.Run("Identifiers", func(t *testing.T) {
+		const i = 3
+		uid := testserver.UserUID(i)
+		for _, id := range []ldap_redhat.Identifier{
+			{Type: ldap_redhat.IDTUID, Value: uid},
+			{Type: ldap_redhat.IDTEmail, Value: uid + "@redhat.com"},
+			{Type: ldap_redhat.IDTUUID, Value: fmt.Sprintf("%08x-0000-4000-8000-%012x", i, i)},
+			{Type: ldap_redhat.IDTEmployeeNumber, Value: strconv.Itoa(100000 + i)},
+			{Type: ldap_redhat.IDTKerberos, Value: uid + "@REDHAT.COM"},
+		} {
+			user, err := searcher.GetUser(ctx, id)
+			if err != nil {
+				t.Errorf("GetUser(%v) failed: %v", id, err)
+			} else if user.UID != uid {
+				t.Errorf("GetUser(%v) = %s, want %s", id, user.UID, uid)
+			}
+		}
+	})
+
+	// More than one page when seeded with test/matrix's default of 1200
+	t.Run("PagedSearch", func(t *testing.T) {
+		all, err := searcher.SearchUsers(ctx, "")
+		if err != nil {
+			t.Fatalf("SearchUsers failed: %v", err)
+		}
+		if len(all) != users+1 {
+			t.Errorf("SearchUsers returned %d users, want %d", len(all), users+1)
+		}
+	})
+
+	t.Run("GeneralizedTimeFilter", func(t *testing.T) {
+		// Hire dates start on 2010-01-04 and advance a day per user
+		want := 0
+		for i := 0; i <= users; i++ {
+			if i%5000 >= 6 {
+				want++
+			}
+		}
+		hired, err := searcher.SearchUsers(ctx, "(rhatHireDate>=20100110000000Z)")
+		if err != nil {
+			t.Fatalf("SearchUsers failed: %v", err)
+		}
+		if len(hired) != want {
+			t.Errorf("Got %d users hired since 2010-01-10, want %d", len(hired), want)
+		}
+	})
+
+	t.Run("Managers", func(t *testing.T) {
+		chain, err := searcher.ManagerChain(ctx, testserver.UserUID(9))
+		if err != nil {
+			t.Fatalf("ManagerChain failed: %v", err)
+		}
+		if len(chain) != 2 || chain[0].UID != testserver.UserUID(1) || chain[1].UID != testserver.UserUID(0) {
+			t.Errorf("Unexpected manager chain %v", chain)
+		}
+		reports, err := searcher.FindDirectReports(ctx, testserver.UserUID(0))
+		if err != nil {
+			t.Fatalf("FindDirectReports failed: %v", err)
+		}
+		if len(reports) != testserver.ReportsPerManager {
+			t.Errorf("Got %d direct reports, want %d", len(reports), testserver.ReportsPerManager)
+		}
+	})
+
+	t.Run("ConsistentFlow", func(t *testing.T) {
+		pinned, token, err := searcher.Pin(ctx)
+		if err != nil {
+			t.Fatalf("Pin failed: %v", err)
+		}
+		for _, i := range []int{2, 0, 2} {
+			if _, err := searcher.GetUser(pinned, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(i)}); err != nil {
+				t.Fatalf("GetUser failed: %v", err)
+			}
+		}
+		if err := token.Verify(pinned); err != nil {
+			t.Errorf("Verify failed on unchanged data: %v", err)
+		}
+	})
+
+	t.Run("Ping", func(t *testing.T) {
+		if err := searcher.Ping(ctx); err != nil {
+			t.Errorf("Ping failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// TestConcurrentUse hammers one Searcher from several goroutines while the
+// server drops connections and Ping swaps in new ones. Run it with -race.
+func TestConcurrentUse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping stress test in short mode")
+	}
+	searcher, srv := newEmbeddedSearcher(t, 20)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var ok atomic.Int64
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ctx.Err() == nil; i++ {
+				id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1 + (w+i)%20)}
+				var err error
+				switch i % 3 {
+				case 0:
+					_, err = searcher.GetUser(ctx, id)
+				case 1:
+					_, err = searcher.GetUsers(ctx, []ldap_redhat.Identifier{id})
+				default:
+					_, err = searcher.SearchUsers(ctx, "")
+				}
+				if err != nil {
+					// Searches in flight when the server drops the connection
+					// fail; recover the way a long-running service would
+					searcher.Ping(context.Background())
+					continue
+				}
+				ok.Add(1)
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			time.Sleep(100 * time.Millisecond)
+			srv.DropConnections()
+			searcher.Ping(context.Background())
+		}
+	}()
+	wg.Wait()
+
+	if ok.Load() == 0 {
+		t.Error("Expected some searches to succeed")
+	}
+	if err := searcher.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping after the stress run failed: %v", err)
+	}
+	if _, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}); err != nil {
+		t.Errorf("GetUser after the stress run failed: %v", err)
+	}
+}
@@ -127,18 +127,16 @@ func TestReadSecretFile(t *testing.T) {
 
 func TestNewSearcherWithDefaults(t *testing.T) {
 	// Save original config
-	originalConfig := ldap_redhat.DefaultConfig
-	defer func() {
-		ldap_redhat.DefaultConfig = originalConfig
-	}()
+	originalConfig := ldap_redhat.LoadDefaultConfig()
+	defer ldap_redhat.SetDefaultConfig(originalConfig)
 
 	// Test with missing password
-	ldap_redhat.DefaultConfig = ldap_redhat.Config{
+	ldap_redhat.SetDefaultConfig(ldap_redhat.Config{
 		LdapServers: []string{"ldap://test.example.com:389"},
 		Username:    "test",
 		BaseDN:      "dc=test,dc=com",
 		// Password missing
-	}
+	})
 
 	_, err := ldap_redhat.NewSearcherWithDefaults()
 	if err == nil {
@@ -151,12 +149,12 @@ func TestNewSearcherWithDefaults(t *testing.T) {
 	}
 
 	// Test with missing URL
-	ldap_redhat.DefaultConfig = ldap_redhat.Config{
+	ldap_redhat.SetDefaultConfig(ldap_redhat.Config{
 		Password: "test-password",
 		Username: "test",
 		BaseDN:   "dc=test,dc=com",
 		// LdapServers missing
-	}
+	})
 
 	_, err = ldap_redhat.NewSearcherWithDefaults()
 	if err == nil {
@@ -168,3 +166,49 @@ func TestNewSearcherWithDefaults(t *testing.T) {
 		t.Errorf("Expected error message '%s', got '%s'", expectedMsg, err.Error())
 	}
 }
+
+func TestConfigRedacted(t *testing.T) {
+	config := ldap_redhat.Config{
+		LdapServers:  []string{"ldaps://ldap.example.com"},
+		Username:     "uid=svc,ou=users,dc=redhat,dc=com",
+		Password:     "hunter2",
+		CAFile:       "/etc/pki/ca.pem",
+		SnapshotFile: "/var/lib/ldap/users.jsonl",
+	}
+
+	redacted := config.Redacted()
+	if redacted.Password != "REDACTED" {
+		t.Errorf("Password should be redacted, got %q", redacted.Password)
+	}
+	if redacted.Username != config.Username || redacted.CAFile != config.CAFile || redacted.SnapshotFile != config.SnapshotFile {
+		t.Error("Non-secret fields should be preserved")
+	}
+
+	redacted.LdapServers[0] = "changed"
+	if config.LdapServers[0] != "ldaps://ldap.example.com" {
+		t.Error("Redacted should not share LdapServers with the original")
+	}
+	if config.Password != "hunter2" {
+		t.Error("Redacted should not modify the original")
+	}
+
+	if got := (ldap_redhat.Config{}).Redacted().Password; got != "" {
+		t.Errorf("Empty password should stay empty, got %q", got)
+	}
+}
+
+func TestSetDefaultConfig(t *testing.T) {
+	originalConfig := ldap_redhat.LoadDefaultConfig()
+	defer ldap_redhat.SetDefaultConfig(originalConfig)
+
+	injected := ldap_redhat.Config{
+		LdapServers: []string{"ldap://injected.example.com:389"},
+		BaseDN:      "dc=injected,dc=com",
+	}
+	ldap_redhat.SetDefaultConfig(injected)
+
+	got := ldap_redhat.LoadDefaultConfig()
+	if len(got.LdapServers) != 1 || got.LdapServers[0] != injected.LdapServers[0] || got.BaseDN != injected.BaseDN {
+		t.Errorf("LoadDefaultConfig() = %+v, want the injected config", got)
+	}
+}
@@ -0,0 +1,75 @@
+package ldap_redhat
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ConfigField describes one configuration setting.
+type ConfigField struct {
+	Field       string   `json:"field" yaml:"field"`                   // Config field name, empty for YAML-only keys
+	YAML        string   `json:"yaml,omitempty" yaml:"yaml,omitempty"` // key under environments.<env> in config.yaml
+	Env         []string `json:"env,omitempty" yaml:"env,omitempty"`   // environment variables, in order of precedence
+	Type        string   `json:"type" yaml:"type"`                     // string, bool, int, duration, list or map
+	Default     string   `json:"default,omitempty" yaml:"default,omitempty"`
+	Description string   `json:"description" yaml:"description"`
+}
+
+// ConfigSchema lists every setting read by LoadConfigFromAll, generated from
+// the tags on Config and EnvConfig. YAML keys that have no Config field of
+// their own, such as password_file, are listed last.
+func ConfigSchema() []ConfigField {
+	var out []ConfigField
+	seen := map[string]bool{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		field := schemaField(f)
+		field.Field = f.Name
+		seen[field.YAML] = true
+		out = append(out, field)
+	}
+
+	t = reflect.TypeOf(EnvConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		field := schemaField(t.Field(i))
+		if field.YAML == "" || seen[field.YAML] {
+			continue
+		}
+		out = append(out, field)
+	}
+	return out
+}
+
+// schemaField reads the yaml, env, default and desc tags of f
+func schemaField(f reflect.StructField) ConfigField {
+	field := ConfigField{
+		Type:        schemaType(f.Type),
+		Default:     f.Tag.Get("default"),
+		Description: f.Tag.Get("desc"),
+	}
+	if name, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); name != "-" {
+		field.YAML = name
+	}
+	if env := f.Tag.Get("env"); env != "" {
+		field.Env = strings.Split(env, ",")
+	}
+	return field
+}
+
+func schemaType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return t.String()
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		return "map"
+	default:
+		return t.Kind().String()
+	}
+}
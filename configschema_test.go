@@ -0,0 +1,69 @@
+package ldap_redhat_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// TestConfigSchemaMatchesYAML keeps the Config tags in sync with the keys
+// tryLoadYAMLFile actually reads
+func TestConfigSchemaMatchesYAML(t *testing.T) {
+	yamlKeys := map[string]bool{}
+	envConfig := reflect.TypeOf(ldap_redhat.EnvConfig{})
+	for i := 0; i < envConfig.NumField(); i++ {
+		name, _, _ := strings.Cut(envConfig.Field(i).Tag.Get("yaml"), ",")
+		yamlKeys[name] = true
+	}
+
+	schemaKeys := map[string]bool{}
+	for _, f := range ldap_redhat.ConfigSchema() {
+		if f.Description == "" {
+			t.Errorf("Setting %s/%s has no description", f.Field, f.YAML)
+		}
+		if f.YAML == "" {
+			continue
+		}
+		if !yamlKeys[f.YAML] {
+			t.Errorf("Config.%s declares YAML key %q, which EnvConfig does not read", f.Field, f.YAML)
+		}
+		schemaKeys[f.YAML] = true
+	}
+	for key := range yamlKeys {
+		if !schemaKeys[key] {
+			t.Errorf("YAML key %q is missing from ConfigSchema", key)
+		}
+	}
+}
+
+func TestConfigSchemaFields(t *testing.T) {
+	byYAML := map[string]ldap_redhat.ConfigField{}
+	for _, f := range ldap_redhat.ConfigSchema() {
+		byYAML[f.YAML] = f
+	}
+
+	tests := []struct {
+		key, typ, env string
+	}{
+		{"ldap_servers", "list", "LDAP_URL"},
+		{"use_start_tls", "bool", "LDAP_START_TLS"},
+		{"breaker_open_timeout", "duration", "LDAP_BREAKER_OPEN_TIMEOUT"},
+		{"password_file", "string", "LDAP_PASSWORD_FILE"},
+		{"feature_gates", "map", ""},
+	}
+	for _, test := range tests {
+		f, ok := byYAML[test.key]
+		if !ok {
+			t.Errorf("Expected %s in schema", test.key)
+			continue
+		}
+		if f.Type != test.typ {
+			t.Errorf("%s: expected type %s, got %s", test.key, test.typ, f.Type)
+		}
+		if env := strings.Join(f.Env, ","); env != test.env {
+			t.Errorf("%s: expected env %q, got %q", test.key, test.env, env)
+		}
+	}
+}
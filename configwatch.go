@@ -0,0 +1,253 @@
+package ldap_redhat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigPollInterval is how often a ConfigWatcher checks config.yaml
+// when ConfigWatcher.Interval is zero
+const DefaultConfigPollInterval = 10 * time.Second
+
+// ConfigWatcher reloads config.yaml when its content changes, replacing
+// DefaultConfig and reconfiguring the searchers passed to Track, so that
+// Kubernetes ConfigMap updates take effect without a restart. ConfigMap
+// volumes are updated by swapping a symlink, which file watches miss, so the
+// config file locations are polled.
+//
+// A file that does not parse, or a configuration that fails Config.Validate,
+// is reported and the current configuration kept. Settings that only Go code can
+// set, such as Logger or Credentials, keep their current values unless the
+// reloaded configuration sets them.
+//
+// The zero value is ready to use:
+//
+//	w := &ldap_redhat.ConfigWatcher{}
+//	w.Track(searcher)
+//	go w.Run(ctx)
+type ConfigWatcher struct {
+	// Interval is how often the config files are checked;
+	// DefaultConfigPollInterval when zero.
+	Interval time.Duration
+	// OnChange, if set, is called after each reload with the previous and
+	// the new DefaultConfig, once tracked searchers have been reconfigured.
+	OnChange func(old, new Config)
+	// OnError, if set, receives reload failures. They are logged with the
+	// DefaultConfig logger otherwise.
+	OnError func(error)
+
+	mu        sync.Mutex
+	searchers []*Searcher
+}
+
+// Track adds s to the searchers reconfigured on each reload
+func (w *ConfigWatcher) Track(s *Searcher) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.searchers = append(w.searchers, s)
+}
+
+// Untrack stops reconfiguring s, e.g. before closing it
+func (w *ConfigWatcher) Untrack(s *Searcher) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, t := range w.searchers {
+		if t == s {
+			w.searchers = append(w.searchers[:i], w.searchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Run polls the config files until ctx is done, reloading whenever their
+// content changes, and returns ctx.Err().
+func (w *ConfigWatcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultConfigPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	current := readConfigFiles()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		data := readConfigFiles()
+		if bytes.Equal(data, current) {
+			continue
+		}
+		current = data
+		w.Reload(ctx)
+	}
+}
+
+// Reload re-reads the configuration now, replaces DefaultConfig and
+// reconfigures every tracked searcher. Searchers created by
+// NewSearcherForEnv get their own environment's configuration. Run calls it
+// when the files change.
+func (w *ConfigWatcher) Reload(ctx context.Context) {
+	if err := checkConfigFiles(); err != nil {
+		w.report(fmt.Errorf("keeping current configuration: %w", err))
+		return
+	}
+
+	LoadDefaultConfig() // so that old is the configuration in use
+	defaultConfigMu.Lock()
+	old := DefaultConfig
+	config := LoadConfigFromAll()
+	keepGoOnlySettings(&config, old)
+	if err := config.Validate(); err != nil {
+		defaultConfigMu.Unlock()
+		w.report(fmt.Errorf("keeping current configuration: %w", err))
+		return
+	}
+	DefaultConfig = config
+	defaultConfigMu.Unlock()
+
+	w.mu.Lock()
+	searchers := append([]*Searcher(nil), w.searchers...)
+	w.mu.Unlock()
+	for _, s := range searchers {
+		next := config
+		if env := s.Environment(); env != "" {
+			var err error
+			if next, err = LoadConfigForEnv(env); err == nil {
+				err = next.Validate()
+			}
+			if err != nil {
+				w.report(fmt.Errorf("searcher for environment %s kept its current configuration: %w", env, err))
+				continue
+			}
+		}
+		keepGoOnlySettings(&next, s.config())
+		if err := s.Reload(ctx, next); err != nil {
+			w.report(fmt.Errorf("searcher kept its current configuration: %w", err))
+		}
+	}
+
+	if w.OnChange != nil {
+		w.OnChange(old, config)
+	}
+}
+
+// report passes err to OnError, or logs it
+func (w *ConfigWatcher) report(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+		return
+	}
+	defaultConfigMu.RLock()
+	logger := DefaultConfig.logger()
+	defaultConfigMu.RUnlock()
+	logger.Warn("config reload failed", "error", err)
+}
+
+// readConfigFiles returns the paths and contents of every config file
+// present, so that any change to them is noticed
+func readConfigFiles() []byte {
+	var buf bytes.Buffer
+	for _, path := range configFilePaths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		buf.WriteString(path)
+		buf.WriteByte(0)
+		buf.Write(data)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// checkConfigFiles reports config files that exist but do not parse, which
+// loadYAMLConfig would silently skip
+func checkConfigFiles() error {
+	for _, path := range configFilePaths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var yamlConfig YAMLConfig
+		if err := yaml.Unmarshal(data, &yamlConfig); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// keepGoOnlySettings copies into config the fields of current that have
+// neither a YAML key nor an environment variable, where config leaves them
+// unset
+func keepGoOnlySettings(config *Config, current Config) {
+	dst, src := reflect.ValueOf(config).Elem(), reflect.ValueOf(current)
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); name != "-" || f.Tag.Get("env") != "" {
+			continue
+		}
+		if dst.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+// Reload switches the searcher to config. Unless config is LazyConnect or
+// names no servers, it first dials with the new settings, and keeps the
+// current configuration and connection if that fails. Calls in flight on
+// the replaced connection fail and may be retried, as with Ping. An Offline
+// config reloads its fixtures file into a new fixture server. The circuit
+// breaker keeps the settings the searcher was created with.
+func (s *Searcher) Reload(ctx context.Context, config Config) error {
+	if err := checkSearcherConfig(config); err != nil {
+		return err
+	}
+	var fixtures *ldapserver.Server
+	if config.Offline {
+		var err error
+		if fixtures, config, err = startFixtureServer(config); err != nil {
+			return err
+		}
+	}
+	var conn *ldap.Conn
+	var name string
+	if len(config.LdapServers) > 0 && !config.LazyConnect {
+		var err error
+		if conn, name, err = dial(ctx, config); err != nil {
+			if fixtures != nil {
+				fixtures.Close()
+			}
+			return wrapLDAPError(err, "LDAP reconnect failed")
+		}
+	}
+
+	s.pingMu.Lock()
+	defer s.pingMu.Unlock()
+	s.mu.Lock()
+	old, oldFixtures := s.Conn, s.fixtures
+	if config.SnapshotFile != s.Config.SnapshotFile {
+		s.snapshot = nil
+	}
+	s.Config, s.Conn, s.connName, s.fixtures = config, conn, name, fixtures
+	s.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	if oldFixtures != nil {
+		oldFixtures.Close()
+	}
+	return nil
+}
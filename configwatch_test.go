@@ -0,0 +1,154 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// useConfigFile runs the test in a directory whose config.yaml holds a
+// "watch" environment pointing at url with baseDN, and restores DefaultConfig
+// afterwards
+func useConfigFile(t *testing.T, url, baseDN string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("LDAP_ENV", "watch")
+	t.Setenv("LDAP_PASSWORD", embeddedPassword)
+	t.Setenv("LDAP_PASSWORD_FILE", "")
+	original := ldap_redhat.LoadDefaultConfig()
+	t.Cleanup(func() { ldap_redhat.SetDefaultConfig(original) })
+	writeConfigFile(t, url, baseDN)
+	ldap_redhat.SetDefaultConfig(ldap_redhat.LoadConfigFromAll())
+}
+
+func writeConfigFile(t *testing.T, url, baseDN string) {
+	t.Helper()
+	data := fmt.Sprintf("environments:\n  watch:\n    ldap_servers: [%q]\n    username: %q\n    base_dn: %q\n",
+		url, embeddedBindDN, baseDN)
+	if err := os.WriteFile("config.yaml", []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write config.yaml: %v", err)
+	}
+}
+
+func TestConfigWatcherReload(t *testing.T) {
+	srv := startEmbeddedServer(t, 3)
+	useConfigFile(t, srv.URL(), "ou=missing,dc=example,dc=com")
+
+	config := ldap_redhat.LoadDefaultConfig()
+	config.Logger = slog.New(slog.DiscardHandler)
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+
+	var changes int
+	w := &ldap_redhat.ConfigWatcher{
+		OnChange: func(old, new ldap_redhat.Config) {
+			changes++
+			if old.BaseDN == new.BaseDN {
+				t.Errorf("OnChange got the same base DN %q before and after", new.BaseDN)
+			}
+		},
+		OnError: func(err error) { t.Errorf("Unexpected reload error: %v", err) },
+	}
+	w.Track(searcher)
+
+	writeConfigFile(t, srv.URL(), testserver.UsersBaseDN)
+	w.Reload(context.Background())
+
+	if changes != 1 {
+		t.Errorf("OnChange called %d times, want 1", changes)
+	}
+	if got := ldap_redhat.LoadDefaultConfig().BaseDN; got != testserver.UsersBaseDN {
+		t.Errorf("DefaultConfig.BaseDN = %q, want %q", got, testserver.UsersBaseDN)
+	}
+	if searcher.Config.Logger != config.Logger {
+		t.Error("Reload dropped the searcher's Logger")
+	}
+	user, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)})
+	if err != nil {
+		t.Fatalf("GetUser after reload failed: %v", err)
+	}
+	if user.UID != testserver.UserUID(1) {
+		t.Errorf("GetUser returned %q, want %q", user.UID, testserver.UserUID(1))
+	}
+}
+
+func TestConfigWatcherKeepsConfigOnInvalidFile(t *testing.T) {
+	srv := startEmbeddedServer(t, 1)
+	useConfigFile(t, srv.URL(), testserver.UsersBaseDN)
+
+	var errs []error
+	w := &ldap_redhat.ConfigWatcher{
+		OnChange: func(old, new ldap_redhat.Config) { t.Error("OnChange called for an invalid file") },
+		OnError:  func(err error) { errs = append(errs, err) },
+	}
+	if err := os.WriteFile("config.yaml", []byte("environments: [unclosed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w.Reload(context.Background())
+
+	if len(errs) != 1 {
+		t.Errorf("Got %d errors, want 1: %v", len(errs), errs)
+	}
+	if got := ldap_redhat.LoadDefaultConfig().BaseDN; got != testserver.UsersBaseDN {
+		t.Errorf("DefaultConfig.BaseDN = %q after an invalid file, want it kept", got)
+	}
+}
+
+func TestConfigWatcherRun(t *testing.T) {
+	srv := startEmbeddedServer(t, 1)
+	useConfigFile(t, srv.URL(), "ou=old,dc=example,dc=com")
+
+	changed := make(chan ldap_redhat.Config, 1)
+	w := &ldap_redhat.ConfigWatcher{
+		Interval: 10 * time.Millisecond,
+		OnChange: func(old, new ldap_redhat.Config) { changed <- new },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	// Let Run read the current file before changing it
+	time.Sleep(50 * time.Millisecond)
+	writeConfigFile(t, srv.URL(), "ou=new,dc=example,dc=com")
+
+	select {
+	case config := <-changed:
+		if config.BaseDN != "ou=new,dc=example,dc=com" {
+			t.Errorf("Reloaded BaseDN = %q, want ou=new,dc=example,dc=com", config.BaseDN)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Run did not reload the changed file")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+}
+
+func TestSearcherReloadKeepsConnectionOnDialFailure(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 1)
+	conn := searcher.Connection()
+
+	config := searcher.Config
+	config.LdapServers = []string{"ldap://127.0.0.1:1"}
+	if err := searcher.Reload(context.Background(), config); err == nil {
+		t.Fatal("Reload to an unreachable server succeeded")
+	}
+	if searcher.Connection() != conn || searcher.Config.LdapServers[0] == "ldap://127.0.0.1:1" {
+		t.Error("Failed Reload replaced the searcher's connection or config")
+	}
+	if _, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(0)}); err != nil {
+		t.Errorf("GetUser after a failed Reload: %v", err)
+	}
+}
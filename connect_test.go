@@ -0,0 +1,87 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestLazyConnect(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+		LazyConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	if searcher.Connection() != nil {
+		t.Fatal("Expected a lazy searcher not to dial in NewSearcher")
+	}
+
+	user, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)})
+	if err != nil || user.UID != testserver.UserUID(1) {
+		t.Fatalf("Expected GetUser to connect on first use, got %q, %v", user.UID, err)
+	}
+	conn := searcher.Connection()
+	if conn == nil {
+		t.Fatal("Expected the searcher to keep the connection it dialed")
+	}
+	if _, err := searcher.SearchUsers(context.Background(), ""); err != nil {
+		t.Errorf("SearchUsers failed: %v", err)
+	}
+	if searcher.Connection() != conn {
+		t.Error("Expected later calls to reuse the connection")
+	}
+}
+
+func TestLazyConnectUnreachable(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	url := srv.URL()
+	srv.Close()
+
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{url},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		LazyConnect: true,
+	})
+	if err != nil {
+		t.Fatalf("Expected a lazy searcher to be created while the server is down, got %v", err)
+	}
+	if _, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "jdoe"}); err == nil {
+		t.Error("Expected GetUser to fail while the server is down")
+	}
+	if err := searcher.Connect(context.Background()); err == nil {
+		t.Error("Expected Connect to fail while the server is down")
+	}
+}
+
+func TestConnect(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	conn := searcher.Connection()
+	if err := searcher.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if searcher.Connection() != conn {
+		t.Error("Expected Connect to keep an open connection")
+	}
+
+	conn.Close()
+	if err := searcher.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if searcher.Connection() == conn {
+		t.Error("Expected Connect to replace a closed connection")
+	}
+
+	if err := (&ldap_redhat.Searcher{}).Connect(context.Background()); err == nil {
+		t.Error("Expected Connect to fail without configured servers")
+	}
+}
@@ -0,0 +1,200 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// modifyTimestampAttr is the operational attribute recording an entry's last
+// modification. Servers return it only when asked for it by name.
+const modifyTimestampAttr = AttrModifyTimestamp
+
+// ConsistencyToken ties together the queries of a multi-query flow, such as
+// user, then manager, then groups. Searches made with the context returned
+// by Searcher.Pin all use the connection that was current when the flow
+// began, so they are answered by the same server even if Ping or Reload
+// swaps the searcher's connection. If that connection is replaced, the
+// flow's searches fail with ErrNotConnected instead of moving to another
+// server.
+//
+// The token records the modifyTimestamp of every entry returned. Changed
+// reports entries read twice with different timestamps, and Verify re-reads
+// them all at the end of the flow, so callers can retry rather than act on
+// an inconsistent composite result. Referrals are still followed to other
+// servers, and lookups answered by a CachedSearcher or the offline snapshot
+// are neither pinned nor recorded.
+type ConsistencyToken struct {
+	searcher *Searcher
+	conn     *ldap.Conn
+	connName string
+
+	mu      sync.Mutex
+	seen    map[string]seenEntry // by normalized DN
+	changed map[string]bool      // DNs of entries that changed, by DN as returned
+}
+
+// seenEntry is the first modifyTimestamp read for an entry in a flow
+type seenEntry struct {
+	dn        string
+	timestamp string
+}
+
+type consistencyKey struct{}
+
+// Pin begins a consistent flow on the searcher's current connection,
+// connecting a LazyConnect searcher first. Pass the returned context to
+// every query of the flow and call Verify on the token once they are done.
+func (s *Searcher) Pin(ctx context.Context) (context.Context, *ConsistencyToken, error) {
+	conn, name, err := s.activeConn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &ConsistencyToken{
+		searcher: s,
+		conn:     conn,
+		connName: name,
+		seen:     map[string]seenEntry{},
+		changed:  map[string]bool{},
+	}
+	return context.WithValue(ctx, consistencyKey{}, t), t, nil
+}
+
+// pinnedFlow returns the token of the consistent flow on s that ctx belongs
+// to, or nil
+func (s *Searcher) pinnedFlow(ctx context.Context) *ConsistencyToken {
+	t, _ := ctx.Value(consistencyKey{}).(*ConsistencyToken)
+	if t == nil || t.searcher != s {
+		return nil
+	}
+	return t
+}
+
+// queryConn returns the connection for a search in ctx and its name: the
+// pinned one of a consistent flow, or the searcher's current one
+func (s *Searcher) queryConn(ctx context.Context) (*ldap.Conn, string, *ConsistencyToken, error) {
+	t := s.pinnedFlow(ctx)
+	if t == nil {
+		conn, name, err := s.activeConn(ctx)
+		return conn, name, nil, err
+	}
+	if t.conn.IsClosing() {
+		return nil, "", nil, newError(ErrNotConnected, "pinned LDAP connection was closed")
+	}
+	return t.conn, t.connName, t, nil
+}
+
+// request returns attrs with modifyTimestamp added, so that the entries a
+// search returns can be recorded
+func (t *ConsistencyToken) request(attrs []string) []string {
+	switch {
+	case len(attrs) == 0:
+		return []string{"*", modifyTimestampAttr}
+	case len(attrs) == 1 && attrs[0] == "1.1":
+		return []string{modifyTimestampAttr}
+	case slices.Contains(attrs, "+") || slices.ContainsFunc(attrs, isModifyTimestamp):
+		return attrs
+	}
+	return append(slices.Clip(attrs), modifyTimestampAttr)
+}
+
+func isModifyTimestamp(attr string) bool {
+	return strings.EqualFold(attr, modifyTimestampAttr)
+}
+
+// observe records the modifyTimestamp of entries, noting entries already
+// read with a different one, and warning of those read with an older one.
+// Entries without a timestamp are ignored.
+func (t *ConsistencyToken) observe(ctx context.Context, entries ...*ldap.Entry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range entries {
+		ts := e.GetEqualFoldAttributeValue(modifyTimestampAttr)
+		if ts == "" {
+			continue
+		}
+		key, err := NormalizeDN(e.DN)
+		if err != nil {
+			key = e.DN
+		}
+		prev, ok := t.seen[key]
+		if !ok {
+			t.seen[key] = seenEntry{dn: e.DN, timestamp: ts}
+		} else if prev.timestamp != ts {
+			t.changed[prev.dn] = true
+			if ts < prev.timestamp {
+				warn(ctx, Warning{
+					Kind:    WarnReplicaDivergence,
+					DN:      prev.dn,
+					Message: fmt.Sprintf("%s was read with modifyTimestamp %s after %s", prev.dn, ts, prev.timestamp),
+				})
+			}
+		}
+	}
+}
+
+// Changed returns the DNs of the entries found to have changed during the
+// flow so far, sorted: read twice with different timestamps, or found
+// modified or deleted by Verify.
+func (t *ConsistencyToken) Changed() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var dns []string
+	for dn := range t.changed {
+		dns = append(dns, dn)
+	}
+	slices.Sort(dns)
+	return dns
+}
+
+// Verify re-reads the modifyTimestamp of every entry the flow returned on
+// the pinned connection. It returns an ErrDataChanged error naming the
+// entries modified or deleted since they were first read, including those
+// Changed already reports, and nil if the flow saw consistent data.
+func (t *ConsistencyToken) Verify(ctx context.Context) error {
+	if t.conn.IsClosing() {
+		return newError(ErrNotConnected, "pinned LDAP connection was closed")
+	}
+	t.mu.Lock()
+	seen := make([]seenEntry, 0, len(t.seen))
+	for _, e := range t.seen {
+		seen = append(seen, e)
+	}
+	t.mu.Unlock()
+
+	config := t.searcher.config()
+	for _, e := range seen {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		req := ldap.NewSearchRequest(
+			e.dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+			0, 0, false, "(objectClass=*)", []string{modifyTimestampAttr}, withSessionTracking(nil, t.connName),
+		)
+		config.applyTimeLimit(req)
+		result, err := t.conn.Search(req)
+		switch {
+		case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+			t.markChanged(e.dn)
+		case err != nil:
+			return wrapLDAPError(err, "LDAP consistency check failed")
+		case len(result.Entries) != 1 || result.Entries[0].GetEqualFoldAttributeValue(modifyTimestampAttr) != e.timestamp:
+			t.markChanged(e.dn)
+		}
+	}
+
+	if changed := t.Changed(); len(changed) > 0 {
+		return newError(ErrDataChanged, "LDAP data changed during the flow: %d entries, first %s", len(changed), changed[0])
+	}
+	return nil
+}
+
+func (t *ConsistencyToken) markChanged(dn string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changed[dn] = true
+}
@@ -0,0 +1,94 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func uidIdentifier(i int) ldap_redhat.Identifier {
+	return ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(i)}
+}
+
+func TestPinnedFlowConsistent(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	ctx, token, err := searcher.Pin(context.Background())
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	user, err := searcher.GetUser(ctx, uidIdentifier(1))
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: user.ManagerUID}); err != nil {
+		t.Fatalf("Manager lookup failed: %v", err)
+	}
+	if err := token.Verify(ctx); err != nil {
+		t.Errorf("Verify of an unchanged flow failed: %v", err)
+	}
+}
+
+func TestPinnedFlowDetectsChangeBetweenReads(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 3)
+	ctx, token, err := searcher.Pin(context.Background())
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	if _, err := searcher.GetUser(ctx, uidIdentifier(1)); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	srv.SetAttribute(testserver.UserDN(1), "modifyTimestamp", "20300101000000Z")
+	if _, err := searcher.SearchUsers(ctx, ""); err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+
+	if got := token.Changed(); len(got) != 1 || got[0] != testserver.UserDN(1) {
+		t.Errorf("Changed() = %v, want [%s]", got, testserver.UserDN(1))
+	}
+	if err := token.Verify(ctx); !errors.Is(err, ldap_redhat.ErrDataChanged) {
+		t.Errorf("Verify returned %v, want ErrDataChanged", err)
+	}
+}
+
+func TestPinnedFlowVerifyDetectsLaterChange(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 3)
+	ctx, token, err := searcher.Pin(context.Background())
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, uidIdentifier(2)); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if len(token.Changed()) != 0 {
+		t.Errorf("Changed() = %v before any modification", token.Changed())
+	}
+
+	srv.SetAttribute(testserver.UserDN(2), "modifyTimestamp", "20300101000000Z")
+	if err := token.Verify(ctx); !errors.Is(err, ldap_redhat.ErrDataChanged) {
+		t.Errorf("Verify returned %v, want ErrDataChanged", err)
+	}
+}
+
+func TestPinnedFlowFailsWhenConnectionReplaced(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 3)
+	ctx, _, err := searcher.Pin(context.Background())
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	srv.DropConnections()
+	if err := searcher.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed to reconnect: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, uidIdentifier(1)); !errors.Is(err, ldap_redhat.ErrNotConnected) {
+		t.Errorf("Pinned GetUser after reconnect returned %v, want ErrNotConnected", err)
+	}
+	if _, err := searcher.GetUser(context.Background(), uidIdentifier(1)); err != nil {
+		t.Errorf("Unpinned GetUser after reconnect failed: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CredentialSource supplies the bind password. Searchers ask it on every
+// bind: when they connect, when Ping reconnects and when they follow a
+// referral, so sources can hand out short-lived credentials and the password
+// never has to be kept in Config. Implementations must be safe for
+// concurrent use.
+type CredentialSource interface {
+	Password(ctx context.Context) (string, error)
+}
+
+// StaticPassword is a fixed password. It prints as REDACTED.
+type StaticPassword string
+
+// Password returns p
+func (p StaticPassword) Password(context.Context) (string, error) {
+	return string(p), nil
+}
+
+// String hides the password from fmt and loggers
+func (p StaticPassword) String() string {
+	return redactedValue
+}
+
+// PasswordFile reads the password from a file at every bind, so a rotated
+// secret, such as a Kubernetes secret volume, is picked up on the next
+// reconnect without restarting. Policy applies to the file's permissions as
+// in ReadSecretFileWithPolicy; a leading ~/ in Path is expanded. Searchers
+// log the warning for an insecure file to Config.Logger.
+type PasswordFile struct {
+	Path   string
+	Policy SecretFilePolicy
+}
+
+// Password reads the file, failing if it is unreadable, refused by Policy or
+// empty
+func (f PasswordFile) Password(ctx context.Context) (string, error) {
+	return f.loggedPassword(ctx, discardLogger)
+}
+
+// loggedPassword is Password logging the warning for an insecure file to
+// logger
+func (f PasswordFile) loggedPassword(_ context.Context, logger *slog.Logger) (string, error) {
+	password, err := readSecretFileWithPolicy(expandHome(f.Path), f.Policy, logger)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", f.Path)
+	}
+	return password, nil
+}
+
+// CredentialFunc adapts a function, e.g. a call to a secrets manager, to a
+// CredentialSource.
+type CredentialFunc func(ctx context.Context) (string, error)
+
+// Password calls f
+func (f CredentialFunc) Password(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// credentials returns Credentials, falling back to the deprecated Password,
+// or nil when neither is set
+func (c Config) credentials() CredentialSource {
+	if c.Credentials != nil {
+		return c.Credentials
+	}
+	if c.Password != "" {
+		return StaticPassword(c.Password)
+	}
+	return nil
+}
+
+// HasCredentials reports whether c has a bind password, from Credentials or
+// Password. It does not ask the source.
+func (c Config) HasCredentials() bool {
+	return c.credentials() != nil
+}
+
+// loggedSource is implemented by sources logging their warnings, which
+// bindPassword has log to Config.Logger
+type loggedSource interface {
+	loggedPassword(ctx context.Context, logger *slog.Logger) (string, error)
+}
+
+// bindPassword asks the configured source for the password to bind with
+func (c Config) bindPassword(ctx context.Context) (string, error) {
+	var password string
+	var err error
+	if source, ok := c.credentials().(loggedSource); ok {
+		password, err = source.loggedPassword(ctx, c.logger())
+	} else {
+		password, err = c.credentials().Password(ctx)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get LDAP password: %w", err)
+	}
+	if password == "" {
+		return "", fmt.Errorf("credential source returned an empty password")
+	}
+	return password, nil
+}
+
+// simpleBind binds conn, named connName, as config.Username with password
+func simpleBind(ctx context.Context, conn *ldap.Conn, config Config, ldapURL, connName, password string) error {
+	start := time.Now()
+	_, span := startSpan(ctx, config, "ldap.bind",
+		attribute.String("ldap.server", ldapURL),
+		attribute.String("ldap.bind_dn", config.Username),
+	)
+	_, err := conn.SimpleBind(&ldap.SimpleBindRequest{
+		Username: config.Username,
+		Password: password,
+		Controls: withSessionTracking(nil, connName),
+	})
+	logOperation(config.logger(), "ldap bind", start, err, slog.String("bind_dn", config.Username))
+	endSpan(span, err)
+	return err
+}
+
+// rebindIfRotated handles a bind with password rejected with err. The
+// secret may have been rotated since it was read, e.g. a password file
+// rewritten while the bind was in flight, so the source is asked again and
+// the bind retried once if it now returns a different password.
+func rebindIfRotated(ctx context.Context, conn *ldap.Conn, config Config, ldapURL, connName, password string, err error) error {
+	fresh, freshErr := config.bindPassword(ctx)
+	if freshErr != nil || fresh == password {
+		return err
+	}
+	config.logger().Info("LDAP bind rejected, retrying with the reloaded password", "bind_dn", config.Username)
+	return simpleBind(ctx, conn, config, ldapURL, connName, fresh)
+}
+
+// passwordFileSource returns a PasswordFile for path if it can be read now,
+// so loaders fall through to the next source for a missing or refused file
+// as they did when the password was read at load time
+func passwordFileSource(path string, policy SecretFilePolicy) CredentialSource {
+	source := PasswordFile{Path: path, Policy: policy}
+	if readSecretFile(expandHome(path), policy) == "" {
+		return nil
+	}
+	return source
+}
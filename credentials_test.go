@@ -0,0 +1,175 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestCredentialSourceAskedAtBind(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	var calls atomic.Int32
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		BaseDN:      testserver.UsersBaseDN,
+		Credentials: ldap_redhat.CredentialFunc(func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			return embeddedPassword, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	if calls.Load() != 1 {
+		t.Errorf("Expected the source to be asked once at connect, got %d", calls.Load())
+	}
+
+	searcher.Conn.Close()
+	if err := searcher.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected the source to be asked again on reconnect, got %d", calls.Load())
+	}
+}
+
+func TestCredentialSourceError(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	boom := errors.New("vault sealed")
+	_, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Credentials: ldap_redhat.CredentialFunc(func(ctx context.Context) (string, error) {
+			return "", boom
+		}),
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected the source error, got %v", err)
+	}
+}
+
+func TestPasswordFileRotation(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte(embeddedPassword+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		BaseDN:      testserver.UsersBaseDN,
+		Credentials: ldap_redhat.PasswordFile{Path: path},
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+
+	// Rotate the secret on the server and in the file
+	srv.AddBind(embeddedBindDN, "rotated")
+	if err := os.WriteFile(path, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	searcher.Conn.Close()
+	if err := searcher.Ping(context.Background()); err != nil {
+		t.Errorf("Expected the reconnect to bind with the rotated password, got %v", err)
+	}
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	searcher.Conn.Close()
+	if err := searcher.Ping(context.Background()); err == nil {
+		t.Error("Expected an empty password file to fail the bind")
+	}
+}
+
+func TestLoadConfigPasswordFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("file-password\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LDAP_ENV", "credentials-test") // not in any config.yaml
+	t.Setenv("LDAP_PASSWORD_FILE", path)
+	t.Setenv("LDAP_PASSWORD", "env-password")
+
+	config := ldap_redhat.LoadConfigFromAll()
+	if config.Password != "" {
+		t.Errorf("Expected the password to stay out of Config, got %q", config.Password)
+	}
+	source, ok := config.Credentials.(ldap_redhat.PasswordFile)
+	if !ok || source.Path != path {
+		t.Fatalf("Expected a PasswordFile source for LDAP_PASSWORD_FILE, got %#v", config.Credentials)
+	}
+	if password, err := source.Password(context.Background()); err != nil || password != "file-password" {
+		t.Errorf("Expected the file's password, got %q, %v", password, err)
+	}
+
+	t.Setenv("LDAP_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	config = ldap_redhat.LoadConfigFromAll()
+	if config.Credentials != nil || config.Password != "env-password" {
+		t.Errorf("Expected a missing file to fall back to LDAP_PASSWORD, got %#v", config.Credentials)
+	}
+}
+
+func TestCredentialsRedacted(t *testing.T) {
+	config := ldap_redhat.Config{Credentials: ldap_redhat.StaticPassword("hunter2")}
+	if s := fmt.Sprintf("%v %+v", config, config); strings.Contains(s, "hunter2") {
+		t.Errorf("Expected StaticPassword to be hidden when printed, got %s", s)
+	}
+
+	config.Credentials = ldap_redhat.CredentialFunc(func(ctx context.Context) (string, error) { return "hunter2", nil })
+	if got := config.Redacted().Credentials; got != ldap_redhat.StaticPassword("REDACTED") {
+		t.Errorf("Expected a function source to be redacted, got %#v", got)
+	}
+	file := ldap_redhat.PasswordFile{Path: "/etc/ldap/password"}
+	config.Credentials = file
+	if got := config.Redacted().Credentials; got != file {
+		t.Errorf("Expected PasswordFile paths to be kept, got %#v", got)
+	}
+}
+
+func TestRotatedPasswordRetried(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	var calls atomic.Int32
+	// The first read returns the password from before the rotation
+	passwords := []string{"before-rotation", embeddedPassword}
+	config := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Credentials: ldap_redhat.CredentialFunc(func(ctx context.Context) (string, error) {
+			n := int(calls.Add(1)) - 1
+			return passwords[min(n, len(passwords)-1)], nil
+		}),
+	}
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("Expected the bind to be retried with the reloaded password, got %v", err)
+	}
+	searcher.Close()
+	if calls.Load() != 2 {
+		t.Errorf("Expected the source to be asked twice, got %d", calls.Load())
+	}
+
+	calls.Store(0)
+	config.Credentials = ldap_redhat.CredentialFunc(func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "wrong", nil
+	})
+	if _, err := ldap_redhat.NewSearcher(config); !errors.Is(err, ldap_redhat.ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed for an unchanged wrong password, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected one reload and no retry with the same password, got %d calls", calls.Load())
+	}
+}
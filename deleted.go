@@ -0,0 +1,83 @@
+package ldap_redhat
+
+import (
+	"context"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// UserStatus tells whether a user still works at Red Hat. Terminated users
+// keep their entry under the users OU until offboarding moves it to
+// Config.DeletedUsersBaseDN.
+type UserStatus string
+
+const (
+	// StatusActive users have no termination date, or one in the future.
+	StatusActive UserStatus = "active"
+	// StatusTerminated users have a past termination date but have not been
+	// moved out of the users OU yet.
+	StatusTerminated UserStatus = "terminated"
+	// StatusDeleted users live under Config.DeletedUsersBaseDN.
+	StatusDeleted UserStatus = "deleted"
+)
+
+// defaultDeletedUsersBaseDN holds the entries of users who have left when
+// Config.DeletedUsersBaseDN is empty
+const defaultDeletedUsersBaseDN = "ou=deletedusers,dc=redhat,dc=com"
+
+// deletedUsersBaseDN returns the configured deleted users OU or
+// defaultDeletedUsersBaseDN
+func (c Config) deletedUsersBaseDN() string {
+	if c.DeletedUsersBaseDN != "" {
+		return c.DeletedUsersBaseDN
+	}
+	return defaultDeletedUsersBaseDN
+}
+
+// GetUserIncludingDeleted looks up a user like GetUser, and when no current
+// entry matches, also searches Config.DeletedUsersBaseDN, so that offboarding
+// tools can find people after they leave. Check UserRecord.Status to tell
+// the two apart. It is GetUser with SearchOptions.IncludeDeleted set.
+func (s *Searcher) GetUserIncludingDeleted(ctx context.Context, id Identifier) (UserRecord, error) {
+	opts, _ := SearchOptionsFromContext(ctx)
+	opts.IncludeDeleted = true
+	return s.GetUser(WithSearchOptions(ctx, opts), id)
+}
+
+// includeDeleted reports whether lookups in ctx also search the deleted
+// users OU
+func includeDeleted(ctx context.Context) bool {
+	opts, _ := SearchOptionsFromContext(ctx)
+	return opts.IncludeDeleted
+}
+
+// searchDeleted runs filter, requesting attrs, against the deleted users OU
+// after a search of baseDN. It returns no entries when baseDN already covers
+// the OU, or when the context's request scope confines searches to a
+// subtree, since deleted users lie outside it.
+func (s *Searcher) searchDeleted(ctx context.Context, baseDN, filter string, attrs []string) (*ldap.SearchResult, error) {
+	deleted := s.config().deletedUsersBaseDN()
+	within, err := dnWithin(deleted, baseDN)
+	if err != nil {
+		return nil, err
+	}
+	if scope, ok := RequestScopeFromContext(ctx); within || ok && scope.BaseDN != "" {
+		return &ldap.SearchResult{}, nil
+	}
+	return s.search(ctx, ldap.NewSearchRequest(
+		deleted, s.searchScope(ctx), s.derefAliases(ctx),
+		0, 0, false, filter, attrs, nil,
+	))
+}
+
+// userRecord converts a search result entry to a UserRecord, marking
+// entries under the deleted users OU as StatusDeleted, and warns of
+// attributes withheld from the entry
+func (s *Searcher) userRecord(ctx context.Context, entry *ldap.Entry) UserRecord {
+	s.warnDeniedAttributes(ctx, entry)
+	rec := entryToUserRecord(entry)
+	if deleted, _ := dnWithin(entry.DN, s.config().deletedUsersBaseDN()); deleted {
+		rec.Status = StatusDeleted
+	}
+	return rec
+}
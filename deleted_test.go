@@ -0,0 +1,128 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+const deletedUsersBaseDN = "ou=deletedusers,dc=redhat,dc=com"
+
+// newDeletedUsersSearcher returns a searcher over ten current users, with
+// user 3 terminated, and a deleted users OU holding "leaver" and an old
+// entry of the rehired user 2
+func newDeletedUsersSearcher(t *testing.T, baseDN string) *ldap_redhat.Searcher {
+	t.Helper()
+	srv := startEmbeddedServer(t, 10)
+	srv.SetAttribute(testserver.UserDN(3), "rhatTermDate", "20200131000000Z")
+	leaver := testserver.NamedUser(20, "leaver")
+	leaver.DN = "uid=leaver," + deletedUsersBaseDN
+	rehired := testserver.NamedUser(2, testserver.UserUID(2))
+	rehired.DN = "uid=" + testserver.UserUID(2) + "," + deletedUsersBaseDN
+	rehired.Attrs["rhatTermDate"] = []string{"20150630000000Z"}
+	srv.AddEntries([]*ldapserver.Entry{leaver, rehired})
+
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      baseDN,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	t.Cleanup(func() { searcher.Close() })
+	return searcher
+}
+
+// byUID identifies a user by uid
+func byUID(uid string) ldap_redhat.Identifier {
+	return ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}
+}
+
+// TestUserStatus tests the status of current, terminated and deleted users
+func TestUserStatus(t *testing.T) {
+	searcher := newDeletedUsersSearcher(t, testserver.UsersBaseDN)
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		uid  string
+		want ldap_redhat.UserStatus
+	}{
+		{testserver.UserUID(1), ldap_redhat.StatusActive},
+		{testserver.UserUID(2), ldap_redhat.StatusActive},
+		{testserver.UserUID(3), ldap_redhat.StatusTerminated},
+		{"leaver", ldap_redhat.StatusDeleted},
+	} {
+		user, err := searcher.GetUserIncludingDeleted(ctx, byUID(test.uid))
+		if err != nil {
+			t.Errorf("GetUserIncludingDeleted(%s) failed: %v", test.uid, err)
+			continue
+		}
+		if user.Status != test.want {
+			t.Errorf("GetUserIncludingDeleted(%s).Status = %q, want %q", test.uid, user.Status, test.want)
+		}
+	}
+
+	if _, err := searcher.GetUser(ctx, byUID("leaver")); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("GetUser of a deleted user returned %v, want ErrUserNotFound", err)
+	}
+
+	// The scope confines lookups to the users OU
+	scoped := ldap_redhat.WithRequestScope(ctx, ldap_redhat.RequestScope{BaseDN: testserver.UsersBaseDN})
+	if _, err := searcher.GetUserIncludingDeleted(scoped, byUID("leaver")); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Scoped GetUserIncludingDeleted returned %v, want ErrUserNotFound", err)
+	}
+
+	// Without the termination date the status is unknown
+	redacted := ldap_redhat.WithRequestScope(ctx, ldap_redhat.RequestScope{Attributes: []string{"cn"}})
+	user, err := searcher.GetUser(redacted, byUID(testserver.UserUID(3)))
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.Status != "" {
+		t.Errorf("Status = %q without rhatTermDate, want it empty", user.Status)
+	}
+}
+
+// TestGetUsersIncludeDeleted tests batch lookups with SearchOptions.IncludeDeleted
+func TestGetUsersIncludeDeleted(t *testing.T) {
+	searcher := newDeletedUsersSearcher(t, testserver.UsersBaseDN)
+	ctx := ldap_redhat.WithSearchOptions(context.Background(), ldap_redhat.SearchOptions{IncludeDeleted: true})
+
+	users, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{
+		byUID(testserver.UserUID(2)),
+		byUID("leaver"),
+		byUID("nobody"),
+	})
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if users[0].Status != ldap_redhat.StatusActive || users[0].RhatTermDate != "" {
+		t.Errorf("Rehired user = %+v, want the current entry", users[0])
+	}
+	if users[1].UID != "leaver" || users[1].Status != ldap_redhat.StatusDeleted {
+		t.Errorf("Deleted user = %q with status %q, want leaver deleted", users[1].UID, users[1].Status)
+	}
+	if users[2].UID != "" {
+		t.Errorf("Unknown user = %q, want no match", users[2].UID)
+	}
+}
+
+// TestDeletedUsersUnderBaseDN tests a search base that covers the deleted
+// users OU, where plain lookups find deleted users too
+func TestDeletedUsersUnderBaseDN(t *testing.T) {
+	searcher := newDeletedUsersSearcher(t, "dc=redhat,dc=com")
+
+	user, err := searcher.GetUser(context.Background(), byUID("leaver"))
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.Status != ldap_redhat.StatusDeleted {
+		t.Errorf("Status = %q, want %q", user.Status, ldap_redhat.StatusDeleted)
+	}
+}
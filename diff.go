@@ -0,0 +1,135 @@
+package ldap_redhat
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// diffIgnoredFields are not compared by DiffSnapshots. The parsed dates follow
+// their rhat_* string fields, as status does rhat_term_date and the entry's
+// OU, and stale/snapshot_age describe how a record was served rather than the
+// user.
+var diffIgnoredFields = map[string]bool{
+	"hire_date":        true,
+	"term_date":        true,
+	"adj_service_date": true,
+	"status":           true,
+	"stale":            true,
+	"snapshot_age":     true,
+}
+
+// SnapshotDiff lists the users added, removed and changed between two
+// snapshots, each sorted by UID.
+//
+// Hires and Terminations list the users who became active or inactive
+// between the snapshots, judged by termination date at each snapshot's
+// TakenAt. Added users count as hires when active, and removed users as
+// terminations when they were active. A termination date that passes between
+// the snapshots counts even though the record did not change.
+type SnapshotDiff struct {
+	Added   []UserRecord `json:"added" yaml:"added"`
+	Removed []UserRecord `json:"removed" yaml:"removed"`
+	Changed []UserChange `json:"changed" yaml:"changed"`
+
+	Hires        []UserRecord `json:"hires,omitempty" yaml:"hires,omitempty"`               // newer records
+	Terminations []UserRecord `json:"terminations,omitempty" yaml:"terminations,omitempty"` // newer records, or the removed ones
+}
+
+// UserChange is a user present in both snapshots whose record differs.
+type UserChange struct {
+	UID    string     `json:"uid" yaml:"uid"`
+	Fields []string   `json:"fields" yaml:"fields"` // JSON names of the changed fields, sorted
+	Before UserRecord `json:"before" yaml:"before"`
+	After  UserRecord `json:"after" yaml:"after"`
+}
+
+// Empty reports whether the snapshots held the same users.
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSnapshots compares users by UID. Either snapshot may be nil, which is
+// treated as empty. Users without a UID are ignored.
+func DiffSnapshots(old, new *Snapshot) SnapshotDiff {
+	before := snapshotByUID(old)
+	after := snapshotByUID(new)
+	oldAt, newAt := snapshotTime(old), snapshotTime(new)
+
+	var d SnapshotDiff
+	for uid, a := range after {
+		b, ok := before[uid]
+		if !ok {
+			d.Added = append(d.Added, a)
+			if a.activeAt(newAt) {
+				d.Hires = append(d.Hires, a)
+			}
+			continue
+		}
+		if fields := ChangedFields(b, a); len(fields) > 0 {
+			d.Changed = append(d.Changed, UserChange{UID: uid, Fields: fields, Before: b, After: a})
+		}
+		switch wasActive, isActive := b.activeAt(oldAt), a.activeAt(newAt); {
+		case !wasActive && isActive:
+			d.Hires = append(d.Hires, a)
+		case wasActive && !isActive:
+			d.Terminations = append(d.Terminations, a)
+		}
+	}
+	for uid, b := range before {
+		if _, ok := after[uid]; !ok {
+			d.Removed = append(d.Removed, b)
+			if b.activeAt(oldAt) {
+				d.Terminations = append(d.Terminations, b)
+			}
+		}
+	}
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].UID < d.Added[j].UID })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].UID < d.Removed[j].UID })
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].UID < d.Changed[j].UID })
+	sort.Slice(d.Hires, func(i, j int) bool { return d.Hires[i].UID < d.Hires[j].UID })
+	sort.Slice(d.Terminations, func(i, j int) bool { return d.Terminations[i].UID < d.Terminations[j].UID })
+	return d
+}
+
+// snapshotTime is when sn was taken, or now if unknown
+func snapshotTime(sn *Snapshot) time.Time {
+	if sn == nil || sn.TakenAt.IsZero() {
+		return time.Now()
+	}
+	return sn.TakenAt
+}
+
+// ChangedFields returns the JSON names of the fields that differ between two
+// records of the same user, sorted.
+func ChangedFields(before, after UserRecord) []string {
+	bv := reflect.ValueOf(before)
+	av := reflect.ValueOf(after)
+	t := bv.Type()
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || diffIgnoredFields[name] {
+			continue
+		}
+		if !reflect.DeepEqual(bv.Field(i).Interface(), av.Field(i).Interface()) {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func snapshotByUID(sn *Snapshot) map[string]UserRecord {
+	if sn == nil {
+		return nil
+	}
+	out := make(map[string]UserRecord, len(sn.Users))
+	for _, u := range sn.Users {
+		if u.UID != "" {
+			out[u.UID] = u
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package ldap_redhat_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Now()
+	old := ldap_redhat.NewSnapshot([]ldap_redhat.UserRecord{
+		{UID: "alice", Email: "alice@redhat.com", Title: "Engineer", ManagerUID: "carol"},
+		{UID: "bob", Email: "bob@redhat.com"},
+		{UID: "dave", Email: "dave@redhat.com"},
+	}, now.Add(-time.Hour))
+	new := ldap_redhat.NewSnapshot([]ldap_redhat.UserRecord{
+		{UID: "alice", Email: "alice@redhat.com", Title: "Senior Engineer", ManagerUID: "erin"},
+		{UID: "dave", Email: "dave@redhat.com", Stale: true},
+		{UID: "frank", Email: "frank@redhat.com"},
+	}, now)
+
+	d := ldap_redhat.DiffSnapshots(old, new)
+	if len(d.Added) != 1 || d.Added[0].UID != "frank" {
+		t.Errorf("Expected frank added, got %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].UID != "bob" {
+		t.Errorf("Expected bob removed, got %+v", d.Removed)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].UID != "alice" {
+		t.Fatalf("Expected only alice changed, got %+v", d.Changed)
+	}
+	if want := []string{"manager_uid", "title"}; !reflect.DeepEqual(d.Changed[0].Fields, want) {
+		t.Errorf("Expected changed fields %v, got %v", want, d.Changed[0].Fields)
+	}
+
+	if d := ldap_redhat.DiffSnapshots(new, new); !d.Empty() {
+		t.Errorf("Expected no differences comparing a snapshot with itself, got %+v", d)
+	}
+	if d := ldap_redhat.DiffSnapshots(nil, new); len(d.Added) != 3 {
+		t.Errorf("Expected every user added against a nil snapshot, got %d", len(d.Added))
+	}
+}
+
+func TestDiffSnapshotsHiresAndTerminations(t *testing.T) {
+	oldAt := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	newAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	old := ldap_redhat.NewSnapshot([]ldap_redhat.UserRecord{
+		{UID: "alice"},
+		{UID: "bob"}, // leaves the directory
+		{UID: "carol", TermDate: newAt.AddDate(0, 0, -7)}, // term date passes between snapshots
+		{UID: "dave", TermDate: oldAt.AddDate(0, -1, 0)},  // rehired
+		{UID: "erin", TermDate: oldAt.AddDate(0, -1, 0)},  // already gone
+	}, oldAt)
+	new := ldap_redhat.NewSnapshot([]ldap_redhat.UserRecord{
+		{UID: "alice"},
+		{UID: "carol", TermDate: newAt.AddDate(0, 0, -7)},
+		{UID: "dave"},
+		{UID: "frank"},
+		{UID: "grace", TermDate: newAt.AddDate(0, 0, -1)}, // added already terminated
+	}, newAt)
+
+	d := ldap_redhat.DiffSnapshots(old, new)
+	if got, want := uids(d.Hires), []string{"dave", "frank"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hires = %v, want %v", got, want)
+	}
+	if got, want := uids(d.Terminations), []string{"bob", "carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Terminations = %v, want %v", got, want)
+	}
+}
+
+func uids(users []ldap_redhat.UserRecord) []string {
+	var out []string
+	for _, u := range users {
+		out = append(out, u.UID)
+	}
+	return out
+}
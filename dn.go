@@ -0,0 +1,49 @@
+package ldap_redhat
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// EqualDN reports whether a and b name the same entry. Attribute types and
+// values are compared case-insensitively, and whitespace around separators
+// and differences in escaping are ignored, as in RFC 4514. DNs that do not
+// parse are equal only if they are identical.
+func EqualDN(a, b string) bool {
+	if a == b {
+		return true
+	}
+	pa, errA := ldap.ParseDN(a)
+	pb, errB := ldap.ParseDN(b)
+	return errA == nil && errB == nil && pa.EqualFold(pb)
+}
+
+// DNWithin reports whether dn equals base or is one of its descendants,
+// comparing like EqualDN. DNs that do not parse are never within base.
+func DNWithin(dn, base string) bool {
+	within, err := dnWithin(dn, base)
+	return err == nil && within
+}
+
+// NormalizeDN returns a canonical form of dn for use as a map key: attribute
+// types and values are lowercased, the attributes of a multi-valued RDN are
+// sorted, and values are re-escaped, so DNs that are EqualDN normalize to the
+// same string.
+func NormalizeDN(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+	rdns := make([]string, len(parsed.RDNs))
+	for i, rdn := range parsed.RDNs {
+		attrs := make([]string, len(rdn.Attributes))
+		for j, attr := range rdn.Attributes {
+			attrs[j] = strings.ToLower(attr.Type) + "=" + ldap.EscapeDN(strings.ToLower(attr.Value))
+		}
+		sort.Strings(attrs)
+		rdns[i] = strings.Join(attrs, "+")
+	}
+	return strings.Join(rdns, ","), nil
+}
@@ -0,0 +1,51 @@
+package ldap_redhat_test
+
+import (
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestEqualDN(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"uid=jdoe,ou=users,dc=redhat,dc=com", "UID=JDoe,OU=Users,DC=RedHat,DC=com", true},
+		{"uid=jdoe,ou=users,dc=redhat,dc=com", "uid=jdoe, ou=users, dc=redhat, dc=com", true},
+		{"cn=Doe\\, John,ou=users,dc=redhat,dc=com", "cn=doe\\2c john,ou=users,dc=redhat,dc=com", true},
+		{"cn=a+uid=b,dc=com", "uid=B+cn=A,dc=com", true},
+		{"uid=jdoe,ou=users,dc=redhat,dc=com", "uid=jdoe2,ou=users,dc=redhat,dc=com", false},
+		{"uid=jdoe,ou=users,dc=redhat,dc=com", "uid=jdoe,dc=redhat,dc=com", false},
+		{"not a dn", "NOT A DN", false},
+	}
+	for _, tt := range tests {
+		if got := ldap_redhat.EqualDN(tt.a, tt.b); got != tt.equal {
+			t.Errorf("EqualDN(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+		na, errA := ldap_redhat.NormalizeDN(tt.a)
+		nb, errB := ldap_redhat.NormalizeDN(tt.b)
+		if errA == nil && errB == nil && (na == nb) != tt.equal {
+			t.Errorf("NormalizeDN(%q) = %q and NormalizeDN(%q) = %q disagree with EqualDN", tt.a, na, tt.b, nb)
+		}
+	}
+}
+
+func TestDNWithin(t *testing.T) {
+	tests := []struct {
+		dn, base string
+		within   bool
+	}{
+		{"uid=jdoe,ou=users,dc=redhat,dc=com", "OU=Users, DC=RedHat, DC=com", true},
+		{"ou=users,dc=redhat,dc=com", "ou=users,dc=redhat,dc=com", true},
+		{"uid=svc,ou=serviceaccounts,dc=redhat,dc=com", "ou=users,dc=redhat,dc=com", false},
+		{"ou=users,dc=redhat,dc=com", "uid=jdoe,ou=users,dc=redhat,dc=com", false},
+		{"uid=jdoe,ou=xusers,dc=redhat,dc=com", "ou=users,dc=redhat,dc=com", false},
+		{"garbage", "ou=users,dc=redhat,dc=com", false},
+	}
+	for _, tt := range tests {
+		if got := ldap_redhat.DNWithin(tt.dn, tt.base); got != tt.within {
+			t.Errorf("DNWithin(%q, %q) = %v, want %v", tt.dn, tt.base, got, tt.within)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package ldap_redhat
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfigForEnv loads the environment named env, e.g. "stage", from the
+// first config file that defines it: config.yaml, configs/config.yaml, then
+// ~/.config/ldap/config.yaml. Unlike LoadConfigFromAll it ignores LDAP_ENV and ENV and does
+// not read LDAP_* variables, except LDAP_SECRET_FILE_PERMISSIONS, so that one
+// process can load several environments side by side. A config file that
+// does not parse, or an environment no file defines, is an ErrInvalidConfig
+// error.
+func LoadConfigForEnv(env string) (Config, error) {
+	if err := checkConfigFiles(); err != nil {
+		return Config{}, newError(ErrInvalidConfig, "%v", err)
+	}
+	for _, path := range configFilePaths() {
+		if config := tryLoadYAMLFile(path, env); config != nil {
+			return *config, nil
+		}
+	}
+	defined := ConfigEnvironments()
+	if len(defined) == 0 {
+		return Config{}, newError(ErrInvalidConfig, "environment %q: no config file found (tried %s)", env, strings.Join(configFilePaths(), ", "))
+	}
+	return Config{}, newError(ErrInvalidConfig, "environment %q is not defined in any config file (defined: %s)", env, strings.Join(defined, ", "))
+}
+
+// ConfigEnvironments returns the names of the environments defined in the
+// config files, sorted
+func ConfigEnvironments() []string {
+	var envs []string
+	for _, path := range configFilePaths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var yamlConfig YAMLConfig
+		if err := yaml.Unmarshal(data, &yamlConfig); err != nil {
+			continue
+		}
+		for env := range yamlConfig.Environments {
+			if !slices.Contains(envs, env) {
+				envs = append(envs, env)
+			}
+		}
+	}
+	slices.Sort(envs)
+	return envs
+}
+
+// NewSearcherForEnv creates a searcher for the environment named env,
+// loaded with LoadConfigForEnv and checked with Config.Validate. Searchers
+// for different environments, such as prod and stage, can be used side by
+// side. A ConfigWatcher tracking the searcher reloads the same environment.
+func NewSearcherForEnv(env string) (*Searcher, error) {
+	config, err := LoadConfigForEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	searcher, err := NewSearcher(config)
+	if err != nil {
+		return nil, err
+	}
+	searcher.env = env
+	return searcher, nil
+}
+
+// Environment returns the config file environment the searcher was created
+// for by NewSearcherForEnv, or "" for other searchers
+func (s *Searcher) Environment() string {
+	return s.env
+}
@@ -0,0 +1,98 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// writeEnvironments writes a config.yaml with one environment per server,
+// binding with a password file, into a fresh working directory
+func writeEnvironments(t *testing.T, servers map[string]*ldapserver.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	password := filepath.Join(dir, "password")
+	if err := os.WriteFile(password, []byte(embeddedPassword), 0o600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	data := "environments:\n"
+	for env, srv := range servers {
+		data += fmt.Sprintf("  %s:\n    ldap_servers: [%q]\n    username: %q\n    password_file: %q\n    base_dn: %q\n    client_name: %q\n",
+			env, srv.URL(), embeddedBindDN, password, testserver.UsersBaseDN, "svc-"+env)
+	}
+	if err := os.WriteFile("config.yaml", []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write config.yaml: %v", err)
+	}
+}
+
+func TestNewSearcherForEnv(t *testing.T) {
+	prod, stage := startEmbeddedServer(t, 3), startEmbeddedServer(t, 3)
+	writeEnvironments(t, map[string]*ldapserver.Server{"prod": prod, "stage": stage})
+	t.Setenv("LDAP_ENV", "prod")
+	t.Setenv("LDAP_URL", "ldap://ignored.example.com")
+
+	if got := ldap_redhat.ConfigEnvironments(); !slices.Equal(got, []string{"prod", "stage"}) {
+		t.Errorf("ConfigEnvironments() = %v", got)
+	}
+
+	ctx := context.Background()
+	for env, srv := range map[string]*ldapserver.Server{"prod": prod, "stage": stage} {
+		searcher, err := ldap_redhat.NewSearcherForEnv(env)
+		if err != nil {
+			t.Fatalf("NewSearcherForEnv(%s) failed: %v", env, err)
+		}
+		defer searcher.Close()
+		if searcher.Environment() != env {
+			t.Errorf("Environment() = %q, want %q", searcher.Environment(), env)
+		}
+		if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}); err != nil {
+			t.Errorf("GetUser on %s failed: %v", env, err)
+		}
+		if ids := srv.SessionIDs(); len(ids) != 1 || !strings.HasPrefix(ids[0], "svc-"+env+"/") {
+			t.Errorf("Expected %s traffic from svc-%s only, got %v", env, env, ids)
+		}
+	}
+}
+
+func TestNewSearcherForEnvUnknown(t *testing.T) {
+	srv := startEmbeddedServer(t, 1)
+	writeEnvironments(t, map[string]*ldapserver.Server{"prod": srv})
+
+	_, err := ldap_redhat.NewSearcherForEnv("stage")
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for an undefined environment, got %v", err)
+	}
+}
+
+func TestConfigWatcherReloadsEnvironment(t *testing.T) {
+	prod, stage := startEmbeddedServer(t, 3), startEmbeddedServer(t, 3)
+	writeEnvironments(t, map[string]*ldapserver.Server{"prod": prod, "stage": stage})
+	t.Setenv("LDAP_ENV", "prod")
+	original := ldap_redhat.LoadDefaultConfig()
+	t.Cleanup(func() { ldap_redhat.SetDefaultConfig(original) })
+	ldap_redhat.SetDefaultConfig(ldap_redhat.LoadConfigFromAll())
+
+	searcher, err := ldap_redhat.NewSearcherForEnv("stage")
+	if err != nil {
+		t.Fatalf("NewSearcherForEnv failed: %v", err)
+	}
+	defer searcher.Close()
+	w := &ldap_redhat.ConfigWatcher{OnError: func(err error) { t.Errorf("Unexpected reload error: %v", err) }}
+	w.Track(searcher)
+	w.Reload(context.Background())
+
+	if got := searcher.Config.LdapServers; !slices.Equal(got, []string{stage.URL()}) {
+		t.Errorf("Expected the stage searcher to stay on %s, got %v", stage.URL(), got)
+	}
+}
@@ -0,0 +1,111 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Errors returned by the searcher. Match them with errors.Is; the underlying
+// go-ldap error, if any, remains available through errors.As.
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrNotConnected    = errors.New("LDAP connection not established")
+	ErrAuthFailed      = errors.New("LDAP authentication failed")
+	ErrMultipleMatches = errors.New("multiple LDAP entries match")
+	ErrTimeout         = errors.New("LDAP operation timed out")
+
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+	ErrInvalidConfig     = errors.New("invalid LDAP configuration")
+
+	// ErrDataChanged is returned by ConsistencyToken.Verify when entries
+	// read during a consistent flow were modified before it finished.
+	ErrDataChanged = errors.New("LDAP data changed during a consistent flow")
+
+	// ErrSyncUnsupported is returned by Searcher.Sync when the server
+	// supports neither content synchronization nor persistent search.
+	ErrSyncUnsupported = errors.New("LDAP server does not support change notifications")
+
+	// ErrVLVUnsupported is returned by Searcher.SearchUsersPage when the
+	// server does not support virtual list views.
+	ErrVLVUnsupported = errors.New("LDAP server does not support virtual list views")
+
+	// ErrFeatureDisabled is returned by experimental methods, such as
+	// Searcher.SearchUsersPage, when their feature gate is off.
+	ErrFeatureDisabled = errors.New("feature gate is disabled")
+
+	// ErrNoPhoto is returned by Searcher.GetUserPhoto for users without a
+	// jpegPhoto or thumbnailPhoto.
+	ErrNoPhoto = errors.New("user has no photo")
+
+	// ErrInvalidCredentials is returned by Searcher.Authenticate when the
+	// password is wrong, or the user cannot log in at all.
+	ErrInvalidCredentials = errors.New("invalid user credentials")
+)
+
+// libError carries a human-readable message while matching both a sentinel
+// kind and the underlying error. The message is kept independent of the
+// sentinel so existing error strings do not change.
+type libError struct {
+	msg  string
+	kind error
+	err  error
+}
+
+func (e *libError) Error() string { return e.msg }
+
+func (e *libError) Unwrap() []error {
+	var out []error
+	for _, err := range []error{e.kind, e.err} {
+		if err != nil {
+			out = append(out, err)
+		}
+	}
+	return out
+}
+
+// newError returns an error of the given kind with a formatted message
+func newError(kind error, format string, args ...any) error {
+	return &libError{msg: fmt.Sprintf(format, args...), kind: kind}
+}
+
+// errNotConnected is returned by operations that need a connection
+func errNotConnected() error {
+	return newError(ErrNotConnected, "LDAP connection not established")
+}
+
+// wrapLDAPError formats "message: err" like fmt.Errorf with %w, and also
+// classifies err as ErrTimeout or ErrAuthFailed where it applies.
+func wrapLDAPError(err error, format string, args ...any) error {
+	return &libError{
+		msg:  fmt.Sprintf(format, args...) + ": " + err.Error(),
+		kind: classifyLDAPError(err),
+		err:  err,
+	}
+}
+
+// isRequestTimeout reports whether err is go-ldap giving up on a request
+// after the connection's timeout, which it reports as a network error
+func isRequestTimeout(err error) bool {
+	var ldapErr *ldap.Error
+	return errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.ErrorNetwork &&
+		ldapErr.Err != nil && ldapErr.Err.Error() == errRequestTimedOut
+}
+
+// classifyLDAPError maps a go-ldap error to a sentinel, or nil
+func classifyLDAPError(err error) error {
+	var netErr net.Error
+	switch {
+	case ldap.IsErrorAnyOf(err, ldap.LDAPResultInvalidCredentials, ldap.LDAPResultInappropriateAuthentication):
+		return ErrAuthFailed
+	case ldap.IsErrorAnyOf(err, ldap.LDAPResultTimeLimitExceeded, ldap.LDAPResultTimeout),
+		errors.Is(err, context.DeadlineExceeded),
+		isRequestTimeout(err),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	srv := startEmbeddedServer(t, 3)
+	srv.AddEntry("uid=dup1,"+testserver.UsersBaseDN, map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"dup1"}, "mail": {"shared@redhat.com"},
+	})
+	srv.AddEntry("uid=dup2,"+testserver.UsersBaseDN, map[string][]string{
+		"objectClass": {"inetOrgPerson"}, "uid": {"dup2"}, "mail": {"shared@redhat.com"},
+	})
+	config := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+	}
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	_, err = searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nonexistent"})
+	if !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if err != nil && err.Error() != "user not found in LDAP directory: nonexistent" {
+		t.Errorf("Unexpected error message: %q", err.Error())
+	}
+
+	_, err = searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "shared@redhat.com"})
+	if !errors.Is(err, ldap_redhat.ErrMultipleMatches) {
+		t.Errorf("Expected ErrMultipleMatches, got %v", err)
+	}
+
+	_, err = (&ldap_redhat.Searcher{}).GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "x"})
+	if !errors.Is(err, ldap_redhat.ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected, got %v", err)
+	}
+
+	config.Password = "wrong"
+	_, err = ldap_redhat.NewSearcher(config)
+	if !errors.Is(err, ldap_redhat.ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed, got %v", err)
+	}
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultInvalidCredentials {
+		t.Errorf("Expected the underlying go-ldap error to be preserved, got %v", err)
+	}
+	if errors.Is(err, ldap_redhat.ErrTimeout) || errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Auth failure matched an unrelated sentinel: %v", err)
+	}
+}
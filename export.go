@@ -0,0 +1,134 @@
+package ldap_redhat
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCSVColumns are written by WriteCSV when no columns are given: who
+// someone is, where they sit and who they report to.
+var DefaultCSVColumns = []string{
+	"uid", "email", "display_name", "title", "manager_uid",
+	"cost_center", "cost_center_desc", "department", "rhat_location", "status",
+}
+
+// csvFields maps each CSV column, the JSON name of a UserRecord field, to the
+// field's index
+var csvFields = func() map[string]int {
+	t := reflect.TypeOf(UserRecord{})
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+// CSVColumns returns every column WriteCSV accepts, in UserRecord field
+// order. Columns are named as in the JSON encoding of UserRecord.
+func CSVColumns() []string {
+	t := reflect.TypeOf(UserRecord{})
+	columns := make([]string, 0, len(csvFields))
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if _, ok := csvFields[name]; ok {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// WriteCSV writes users to w as CSV with a header row, one row per user with
+// the given columns (DefaultCSVColumns when empty). Parsed dates are written
+// as YYYY-MM-DD and empty fields as empty cells. Unknown columns are rejected
+// before anything is written.
+//
+//	users, err := searcher.SearchUsers(ctx, "(rhatCostCenter=123)")
+//	err = ldap_redhat.WriteCSV(f, users, []string{"uid", "display_name", "title"})
+func WriteCSV(w io.Writer, users []UserRecord, columns []string) error {
+	return writeCSV(w, users, columns, MaskingPolicy{})
+}
+
+// WriteCSVMasked writes users as WriteCSV does with policy applied to every
+// row. Dropped fields are written as empty cells, keeping the header as
+// asked for; hashed and truncated fields are masked in the same string form
+// as Snapshot.WriteMasked uses, so hashes join across both exports.
+func WriteCSVMasked(w io.Writer, users []UserRecord, columns []string, policy MaskingPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid masking policy: %w", err)
+	}
+	return writeCSV(w, users, columns, policy)
+}
+
+// writeCSV implements WriteCSV and WriteCSVMasked
+func writeCSV(w io.Writer, users []UserRecord, columns []string, policy MaskingPolicy) error {
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+	idx := make([]int, len(columns))
+	for i, c := range columns {
+		field, ok := csvFields[c]
+		if !ok {
+			return fmt.Errorf("unknown CSV column %q (expected one of %s)", c, strings.Join(CSVColumns(), ", "))
+		}
+		idx[i] = field
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	for _, u := range users {
+		v := reflect.ValueOf(u)
+		var masked map[string]any
+		if len(policy.Fields) > 0 {
+			masked = policy.Apply(u)
+		}
+		for i, field := range idx {
+			if _, ok := policy.Fields[columns[i]]; ok {
+				row[i], _ = masked[columns[i]].(string)
+				continue
+			}
+			row[i] = csvValue(v.Field(field).Interface())
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue renders a UserRecord field as a cell
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case UserStatus:
+		return string(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	case time.Duration:
+		if v == 0 {
+			return ""
+		}
+		return v.String()
+	case bool:
+		if !v {
+			return ""
+		}
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,90 @@
+package ldap_redhat_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestWriteCSV(t *testing.T) {
+	users := []ldap_redhat.UserRecord{
+		{UID: "alice", DisplayName: "Doe, Alice", HireDate: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), IsPeopleManager: true},
+		{UID: "bob", DisplayName: `Bob "B" Smith`},
+	}
+	var buf bytes.Buffer
+	if err := ldap_redhat.WriteCSV(&buf, users, []string{"uid", "display_name", "hire_date", "is_people_manager"}); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	want := "uid,display_name,hire_date,is_people_manager\n" +
+		"alice,\"Doe, Alice\",2020-03-01,true\n" +
+		"bob,\"Bob \"\"B\"\" Smith\",,\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := ldap_redhat.WriteCSV(&buf, users, []string{"uid", "password"}); err == nil || buf.Len() != 0 {
+		t.Errorf("Expected an unknown column to fail before writing, got %v and %q", err, buf.String())
+	}
+}
+
+func TestWriteCSVDefaultColumns(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	users, err := searcher.SearchUsers(context.Background(), "")
+	if err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := ldap_redhat.WriteCSV(&buf, users, nil); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 || lines[0] != strings.Join(ldap_redhat.DefaultCSVColumns, ",") {
+		t.Errorf("Expected the default header and 5 rows, got:\n%s", buf.String())
+	}
+
+	for _, c := range ldap_redhat.DefaultCSVColumns {
+		if !slices.Contains(ldap_redhat.CSVColumns(), c) {
+			t.Errorf("Default column %q is not in CSVColumns", c)
+		}
+	}
+}
+
+func TestWriteCSVMasked(t *testing.T) {
+	policy := ldap_redhat.MaskingPolicy{
+		Salt: "pepper",
+		Fields: map[string]ldap_redhat.FieldMask{
+			"cost_center": {Action: ldap_redhat.MaskDrop},
+			"term_date":   {Action: ldap_redhat.MaskTruncate, Length: 7},
+			"rhat_uuid":   {Action: ldap_redhat.MaskHash},
+		},
+	}
+	user := ldap_redhat.UserRecord{
+		UID:        "jdoe",
+		CostCenter: "123",
+		RhatUUID:   "abc-123",
+		TermDate:   time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC),
+	}
+	var buf bytes.Buffer
+	columns := []string{"uid", "cost_center", "term_date", "rhat_uuid"}
+	if err := ldap_redhat.WriteCSVMasked(&buf, []ldap_redhat.UserRecord{user}, columns, policy); err != nil {
+		t.Fatalf("WriteCSVMasked failed: %v", err)
+	}
+	hash := policy.Apply(user)["rhat_uuid"].(string)
+	want := "uid,cost_center,term_date,rhat_uuid\n" +
+		"jdoe,,2024-03," + hash + "\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSVMasked =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	policy.Salt = ""
+	if err := ldap_redhat.WriteCSVMasked(&buf, []ldap_redhat.UserRecord{user}, columns, policy); err == nil || buf.Len() != 0 {
+		t.Errorf("Expected an invalid policy to fail before writing, got %v and %q", err, buf.String())
+	}
+}
@@ -0,0 +1,117 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestInjectedBindFaults(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	config := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+	}
+
+	srv.InjectFault(ldapserver.OpBind, ldapserver.Fault{Code: ldap.LDAPResultInvalidCredentials})
+	if _, err := ldap_redhat.NewSearcher(config); !errors.Is(err, ldap_redhat.ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed, got %v", err)
+	}
+
+	srv.InjectFault(ldapserver.OpBind, ldapserver.Fault{Code: ldap.LDAPResultBusy, Times: 1})
+	_, err := ldap_redhat.NewSearcher(config)
+	if err == nil || errors.Is(err, ldap_redhat.ErrAuthFailed) || !ldap.IsErrorWithCode(err, ldap.LDAPResultBusy) {
+		t.Errorf("Expected a busy error, got %v", err)
+	}
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("Expected the bind to succeed once the fault cleared, got %v", err)
+	}
+	searcher.Close()
+}
+
+func TestInjectedSearchFaults(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 10)
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultTimeLimitExceeded})
+	if _, err := searcher.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultSizeLimitExceeded, Entries: 3})
+	if _, err := searcher.SearchUsers(ctx, ""); !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		t.Errorf("Expected a size limit error from SearchUsers, got %v", err)
+	}
+
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{
+		Code:      ldap.LDAPResultReferral,
+		Referrals: []string{"ldap://replica.example.com/dc=redhat,dc=com"},
+	})
+	_, err := searcher.GetUser(ctx, id)
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultReferral {
+		t.Errorf("Expected a referral error, got %v", err)
+	}
+	if errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("A referral must not be reported as a missing user: %v", err)
+	}
+
+	srv.ClearFaults()
+	if _, err := searcher.GetUser(ctx, id); err != nil {
+		t.Errorf("GetUser after ClearFaults failed: %v", err)
+	}
+}
+
+func TestInjectedFaultsOpenBreaker(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:             []string{srv.URL()},
+		Username:                embeddedBindDN,
+		Password:                embeddedPassword,
+		BaseDN:                  testserver.UsersBaseDN,
+		BreakerFailureThreshold: 2,
+		BreakerOpenTimeout:      100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+
+	// A slow server times out the request; a dropped connection fails the next
+	searcher.Conn.SetTimeout(50 * time.Millisecond)
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Delay: time.Second, Times: 1})
+	if _, err := searcher.GetUser(ctx, id); err == nil {
+		t.Fatalf("Expected the delayed search to time out")
+	}
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Drop: true})
+	if _, err := searcher.GetUser(ctx, id); err == nil || errors.Is(err, ldap_redhat.ErrCircuitOpen) {
+		t.Fatalf("Expected the dropped search to reach the directory, got %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen once the threshold is reached, got %v", err)
+	}
+
+	srv.ClearFaults()
+	time.Sleep(100 * time.Millisecond)
+	if err := searcher.Ping(ctx); err != nil {
+		t.Fatalf("Expected trial reconnect to succeed, got %v", err)
+	}
+	if state := searcher.CircuitState(); state != ldap_redhat.CircuitClosed {
+		t.Errorf("Expected closed breaker after recovery, got %s", state)
+	}
+	if _, err := searcher.GetUser(ctx, id); err != nil {
+		t.Errorf("GetUser after recovery failed: %v", err)
+	}
+}
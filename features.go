@@ -0,0 +1,155 @@
+package ldap_redhat
+
+import (
+	"expvar"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature names an experimental capability that is off unless its gate is
+// enabled, so larger changes can be rolled out service by service.
+type Feature string
+
+// Experimental features
+const (
+	FeatureVLV Feature = "VLV" // virtual list view (windowed) searches: SearchUsersPage
+)
+
+// featureDefaults lists every known feature and whether it is on by default
+var featureDefaults = map[Feature]bool{
+	FeatureVLV: false,
+}
+
+// featureGatesVar is the expvar publishing the effective gates
+const featureGatesVar = "ldap_redhat_feature_gates"
+
+func init() {
+	expvar.Publish(featureGatesVar, expvar.Func(func() any { return FeatureGates() }))
+}
+
+// featureGatesEnv holds process-wide gates, e.g. "VLV=true"
+const featureGatesEnv = "LDAP_FEATURE_GATES"
+
+var (
+	gatesOnce sync.Once
+	gatesMu   sync.RWMutex
+	gates     map[Feature]bool
+)
+
+// KnownFeatures returns every feature that can be gated, sorted by name.
+func KnownFeatures() []Feature {
+	out := make([]Feature, 0, len(featureDefaults))
+	for f := range featureDefaults {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// ParseFeatureGates parses a comma-separated list of Feature=bool pairs.
+// Unknown features and invalid booleans are errors.
+func ParseFeatureGates(spec string) (map[Feature]bool, error) {
+	out := map[Feature]bool{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid feature gate %q: expected Feature=true|false", pair)
+		}
+		f := Feature(strings.TrimSpace(name))
+		if _, known := featureDefaults[f]; !known {
+			return nil, fmt.Errorf("unknown feature gate %q", f)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for feature gate %s: %q", f, value)
+		}
+		out[f] = enabled
+	}
+	return out, nil
+}
+
+// FeatureGatesFromEnv parses LDAP_FEATURE_GATES.
+func FeatureGatesFromEnv() (map[Feature]bool, error) {
+	return ParseFeatureGates(os.Getenv(featureGatesEnv))
+}
+
+// loadGates initializes the process-wide gates from the environment on first
+// use. An invalid LDAP_FEATURE_GATES leaves every feature at its default;
+// FeatureGatesFromEnv reports the error.
+func loadGates() {
+	gatesOnce.Do(func() {
+		g, err := FeatureGatesFromEnv()
+		if err != nil {
+			g = map[Feature]bool{}
+		}
+		gatesMu.Lock()
+		if gates == nil {
+			gates = g
+		}
+		gatesMu.Unlock()
+	})
+}
+
+// SetFeatureGates replaces the process-wide gates, overriding
+// LDAP_FEATURE_GATES. Features not listed revert to their defaults.
+func SetFeatureGates(g map[Feature]bool) error {
+	for f := range g {
+		if _, known := featureDefaults[f]; !known {
+			return fmt.Errorf("unknown feature gate %q", f)
+		}
+	}
+	loadGates()
+	copied := make(map[Feature]bool, len(g))
+	for f, v := range g {
+		copied[f] = v
+	}
+	gatesMu.Lock()
+	gates = copied
+	gatesMu.Unlock()
+	return nil
+}
+
+// FeatureEnabled reports whether f is enabled process-wide.
+func FeatureEnabled(f Feature) bool {
+	loadGates()
+	gatesMu.RLock()
+	defer gatesMu.RUnlock()
+	if v, ok := gates[f]; ok {
+		return v
+	}
+	return featureDefaults[f]
+}
+
+// FeatureGates returns the effective state of every known feature.
+func FeatureGates() map[Feature]bool {
+	out := make(map[Feature]bool, len(featureDefaults))
+	for f := range featureDefaults {
+		out[f] = FeatureEnabled(f)
+	}
+	return out
+}
+
+// FeatureEnabled reports whether f is enabled for this searcher:
+// Config.FeatureGates overrides the process-wide gates.
+func (s *Searcher) FeatureEnabled(f Feature) bool {
+	if v, ok := s.config().FeatureGates[f]; ok {
+		return v
+	}
+	return FeatureEnabled(f)
+}
+
+// featureDisabled returns an ErrFeatureDisabled error for f
+func featureDisabled(f Feature) error {
+	return &libError{
+		msg:  fmt.Sprintf("feature gate %s is disabled; enable it with %s=%s=true or Config.FeatureGates", f, featureGatesEnv, f),
+		kind: ErrFeatureDisabled,
+	}
+}
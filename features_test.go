@@ -0,0 +1,99 @@
+package ldap_redhat_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	gates, err := ldap_redhat.ParseFeatureGates(" VLV=true ,")
+	if err != nil {
+		t.Fatalf("ParseFeatureGates failed: %v", err)
+	}
+	if len(gates) != 1 || !gates[ldap_redhat.FeatureVLV] {
+		t.Errorf("Unexpected gates: %v", gates)
+	}
+
+	invalid := []string{"VLV", "VLV=maybe", "Teleport=true", "=true"}
+	for _, spec := range invalid {
+		if _, err := ldap_redhat.ParseFeatureGates(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestFeatureGates(t *testing.T) {
+	defer ldap_redhat.SetFeatureGates(nil)
+
+	for _, f := range ldap_redhat.KnownFeatures() {
+		if ldap_redhat.FeatureEnabled(f) {
+			t.Errorf("Feature %s should be disabled by default", f)
+		}
+	}
+
+	if err := ldap_redhat.SetFeatureGates(map[ldap_redhat.Feature]bool{ldap_redhat.FeatureVLV: true}); err != nil {
+		t.Fatalf("SetFeatureGates failed: %v", err)
+	}
+	if !ldap_redhat.FeatureEnabled(ldap_redhat.FeatureVLV) {
+		t.Error("VLV should be enabled process-wide")
+	}
+	if !ldap_redhat.BuildInfo().FeatureGates[ldap_redhat.FeatureVLV] {
+		t.Error("BuildInfo should report the VLV gate as enabled")
+	}
+
+	var published map[ldap_redhat.Feature]bool
+	if err := json.Unmarshal([]byte(expvar.Get("ldap_redhat_feature_gates").String()), &published); err != nil || !published[ldap_redhat.FeatureVLV] {
+		t.Errorf("Expected the published gates to enable VLV, got %v (%v)", published, err)
+	}
+
+	// Config overrides the process-wide gate in both directions
+	searcher := &ldap_redhat.Searcher{Config: ldap_redhat.Config{FeatureGates: map[ldap_redhat.Feature]bool{
+		ldap_redhat.FeatureVLV: false,
+	}}}
+	if searcher.FeatureEnabled(ldap_redhat.FeatureVLV) {
+		t.Error("Config should disable VLV for this searcher")
+	}
+	if !(&ldap_redhat.Searcher{}).FeatureEnabled(ldap_redhat.FeatureVLV) {
+		t.Error("A searcher without overrides should follow the process-wide gate")
+	}
+	ldap_redhat.SetFeatureGates(nil)
+	searcher.Config.FeatureGates[ldap_redhat.FeatureVLV] = true
+	if !searcher.FeatureEnabled(ldap_redhat.FeatureVLV) {
+		t.Error("Config should enable VLV for this searcher")
+	}
+
+	if err := ldap_redhat.SetFeatureGates(map[ldap_redhat.Feature]bool{"Teleport": true}); err == nil {
+		t.Error("Expected error for unknown feature")
+	}
+}
+
+func TestLoadConfigFeatureGatesFromYAML(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `environments:
+  local:
+    ldap_servers: ["ldap://localhost:389"]
+    feature_gates:
+      VLV: true
+      FromTheFuture: true
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(tmpDir)
+	t.Setenv("LDAP_ENV", "local")
+
+	config := ldap_redhat.LoadConfigFromAll()
+	if !config.FeatureGates[ldap_redhat.FeatureVLV] {
+		t.Errorf("Expected VLV gate from YAML, got %v", config.FeatureGates)
+	}
+	if _, ok := config.FeatureGates["FromTheFuture"]; ok {
+		t.Error("Unknown gates should be ignored")
+	}
+}
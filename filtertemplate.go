@@ -0,0 +1,89 @@
+package ldap_redhat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Keys of Config.FilterTemplates, naming the identifier type a template
+// looks up.
+const (
+	FilterTemplateUID            = "uid"
+	FilterTemplateEmail          = "email"
+	FilterTemplateUUID           = "uuid"
+	FilterTemplateEmployeeNumber = "employee_number"
+	FilterTemplateKerberos       = "kerberos"
+)
+
+// filterTemplateType returns the identifier type a Config.FilterTemplates
+// key names
+func filterTemplateType(key string) (int, bool) {
+	for idType, kind := range identifierKinds {
+		if kind.name == key {
+			return idType, true
+		}
+	}
+	return 0, false
+}
+
+// filterTemplateKeys returns the valid Config.FilterTemplates keys, sorted
+func filterTemplateKeys() []string {
+	keys := make([]string, 0, len(identifierKinds))
+	for _, kind := range identifierKinds {
+		keys = append(keys, kind.name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateFilterTemplates checks that every template names a known
+// identifier type, contains the %s placeholder and expands to a valid filter.
+func validateFilterTemplates(templates map[string]string) error {
+	for key, tmpl := range templates {
+		if _, ok := filterTemplateType(key); !ok {
+			return fmt.Errorf("unknown filter template %q: expected one of %s", key, strings.Join(filterTemplateKeys(), ", "))
+		}
+		if !strings.Contains(tmpl, "%s") {
+			return fmt.Errorf("filter template %s %q has no %%s placeholder", key, tmpl)
+		}
+		if _, err := ldap.CompileFilter(expandFilterTemplate(tmpl, "value")); err != nil {
+			return fmt.Errorf("invalid filter template %s %q: %w", key, tmpl, err)
+		}
+	}
+	return nil
+}
+
+// expandFilterTemplate replaces every %s in tmpl with the escaped value
+func expandFilterTemplate(tmpl, value string) string {
+	return strings.ReplaceAll(tmpl, "%s", ldap.EscapeFilter(value))
+}
+
+// customFilterTemplate returns the Config.FilterTemplates entry for idType
+func (s *Searcher) customFilterTemplate(idType int) (string, bool) {
+	kind, ok := identifierKinds[idType]
+	if !ok {
+		return "", false
+	}
+	tmpl, ok := s.config().FilterTemplates[kind.name]
+	return tmpl, ok
+}
+
+// identifierFilter returns the search filter that looks id up, from
+// Config.FilterTemplates or the identifier type's default filter.
+func (s *Searcher) identifierFilter(id Identifier) (string, error) {
+	kind, ok := identifierKinds[id.Type]
+	if !ok {
+		return "", newError(ErrInvalidIdentifier, "unknown identifier type: %d", id.Type)
+	}
+	if err := s.validateIdentifier(id); err != nil {
+		return "", err
+	}
+	tmpl := kind.filter
+	if custom, ok := s.customFilterTemplate(id.Type); ok {
+		tmpl = custom
+	}
+	return expandFilterTemplate(tmpl, id.Value), nil
+}
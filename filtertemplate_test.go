@@ -0,0 +1,69 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestFilterTemplates(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	srv.AddEntry("uid=alias1,"+testserver.UsersBaseDN, map[string][]string{
+		"uid":                {"alias1"},
+		"mail":               {"alias1@redhat.com"},
+		"rhatPreferredAlias": {"a.one@redhat.com"},
+	})
+	config := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+		FilterTemplates: map[string]string{
+			ldap_redhat.FilterTemplateEmail: "(|(mail=%s)(rhatPreferredAlias=%s))",
+		},
+	}
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	user, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "a.one@redhat.com"})
+	if err != nil || user.UID != "alias1" {
+		t.Errorf("Expected alias1 by alias address, got %q, %v", user.UID, err)
+	}
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "*"}); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected the value to be escaped in the template, got %v", err)
+	}
+
+	users, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)},
+		{Type: ldap_redhat.IDTEmail, Value: "a.one@redhat.com"},
+		{Type: ldap_redhat.IDTEmail, Value: "missing@redhat.com"},
+		{Type: ldap_redhat.IDTEmail, Value: testserver.UserUID(2) + "@redhat.com"},
+	})
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	want := []string{testserver.UserUID(1), "alias1", "", testserver.UserUID(2)}
+	for i, u := range users {
+		if u.UID != want[i] {
+			t.Errorf("GetUsers result %d: expected %q, got %q", i, want[i], u.UID)
+		}
+	}
+
+	for _, templates := range []map[string]string{
+		{"github": "(rhatGitHubUser=%s)"},
+		{ldap_redhat.FilterTemplateUID: "(uid=jdoe)"},
+		{ldap_redhat.FilterTemplateUID: "(uid=%s"},
+	} {
+		config.FilterTemplates = templates
+		if _, err := ldap_redhat.NewSearcher(config); err == nil {
+			t.Errorf("Expected NewSearcher to reject filter templates %v", templates)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package ldap_redhat
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"gopkg.in/yaml.v3"
+)
+
+// fixtureBindDN is the identity offline searchers bind as to their fixture
+// server
+const fixtureBindDN = "cn=fixtures,dc=redhat,dc=com"
+
+// Fixtures are the users and groups an Offline searcher serves, read from
+// Config.FixturesFile by LoadFixtures.
+//
+//	users:
+//	  - uid: jdoe
+//	    email: jdoe@redhat.com
+//	    display_name: Jane Doe
+//	    manager_uid: mgr
+//	    cost_center: "700"
+//	    rhat_hire_date: 20190304000000Z
+//	groups:
+//	  - name: openshift-eng
+//	    members: [jdoe, mgr]
+type Fixtures struct {
+	Users  []UserRecord   `json:"users" yaml:"users"`
+	Groups []FixtureGroup `json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// FixtureGroup is a group of Fixtures, whose members are user UIDs.
+type FixtureGroup struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Members     []string `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+// LoadFixtures reads a fixtures file: JSON if its name ends in .json, YAML
+// otherwise.
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures %s: %w", path, err)
+	}
+	var f Fixtures
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &f)
+	} else {
+		err = yaml.Unmarshal(data, &f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(f.Users))
+	for i, u := range f.Users {
+		if u.UID == "" {
+			return nil, fmt.Errorf("fixtures %s: user %d has no uid", path, i+1)
+		}
+		if seen[u.UID] {
+			return nil, fmt.Errorf("fixtures %s: duplicate user %s", path, u.UID)
+		}
+		seen[u.UID] = true
+	}
+	for i, g := range f.Groups {
+		if g.Name == "" {
+			return nil, fmt.Errorf("fixtures %s: group %d has no name", path, i+1)
+		}
+	}
+	return &f, nil
+}
+
+// WriteFile writes f to path in the format LoadFixtures reads for the name,
+// e.g. to save generated users. The file is created with mode 0600 since
+// records include HR data.
+func (f *Fixtures) WriteFile(path string) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(f, "", "  ")
+	} else {
+		data, err = yaml.Marshal(f)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode fixtures: %w", err)
+	}
+	if err := os.WriteFile(expandHome(path), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write fixtures %s: %w", path, err)
+	}
+	return nil
+}
+
+// entries returns the directory entries of f as config lays out the tree:
+// users under the base DN, or the deleted users base DN for StatusDeleted
+// records, and groups under ou=groups of the group search base. Every entry
+// is stamped modified at modified.
+func (f *Fixtures) entries(config Config, modified time.Time) []*ldapserver.Entry {
+	usersBase := config.BaseDN
+	if usersBase == "" {
+		usersBase = defaultBaseDN
+	}
+	deletedBase := config.DeletedUsersBaseDN
+	if deletedBase == "" {
+		deletedBase = defaultDeletedUsersBaseDN
+	}
+	groupsBase := config.BaseDN
+	if groupsBase == "" {
+		groupsBase = defaultGroupBaseDN
+	}
+	stamp := FormatLDAPTime(modified)
+
+	dns := make(map[string]string, len(f.Users)) // uid -> DN, for group members
+	entries := make([]*ldapserver.Entry, 0, len(f.Users)+len(f.Groups))
+	for _, u := range f.Users {
+		base := usersBase
+		if u.Status == StatusDeleted {
+			base = deletedBase
+		}
+		dn := fmt.Sprintf("uid=%s,%s", ldap.EscapeDN(u.UID), base)
+		dns[u.UID] = dn
+		attrs := fixtureUserAttributes(u)
+		attrs[AttrModifyTimestamp] = []string{stamp}
+		entries = append(entries, &ldapserver.Entry{DN: dn, Attrs: attrs})
+	}
+	for _, g := range f.Groups {
+		attrs := map[string][]string{
+			"objectClass":       {"top", "groupOfUniqueNames"},
+			AttrCN:              {g.Name},
+			AttrModifyTimestamp: {stamp},
+		}
+		if g.Description != "" {
+			attrs[AttrDescription] = []string{g.Description}
+		}
+		for _, uid := range g.Members {
+			dn, ok := dns[uid]
+			if !ok {
+				dn = managerDNForUID(uid)
+			}
+			attrs[AttrUniqueMember] = append(attrs[AttrUniqueMember], dn)
+		}
+		dn := fmt.Sprintf("cn=%s,ou=groups,%s", ldap.EscapeDN(g.Name), groupsBase)
+		entries = append(entries, &ldapserver.Entry{DN: dn, Attrs: attrs})
+	}
+	return entries
+}
+
+// fixtureUserAttributes maps u back to the LDAP attributes it would be read
+// from. The manager attribute is derived from ManagerUID when ManagerDN is
+// empty, and date attributes from the parsed dates when the raw strings are.
+func fixtureUserAttributes(u UserRecord) map[string][]string {
+	if u.ManagerDN == "" && u.ManagerUID != "" {
+		u.ManagerDN = managerDNForUID(u.ManagerUID)
+	}
+	attrs := map[string][]string{
+		"objectClass": {"top", "person", "organizationalPerson", "inetOrgPerson", "rhatPerson"},
+	}
+	for _, a := range attributeSchemas {
+		if a.value == nil {
+			continue
+		}
+		value := *a.value(&u)
+		if value == "" && a.parsed != nil {
+			if t := *a.parsed(&u); !t.IsZero() {
+				value = FormatLDAPTime(t)
+			}
+		}
+		if value != "" {
+			attrs[a.Name] = []string{value}
+		}
+	}
+	return attrs
+}
+
+// startFixtureServer loads config.FixturesFile into an LDAP server on the
+// loopback interface and returns it with the configuration that connects to
+// it: config with the server as its only server and a simple bind with a
+// generated password. Lookups thus take the same code paths, and fail with
+// the same errors, as against the real directory.
+func startFixtureServer(config Config) (*ldapserver.Server, Config, error) {
+	fixtures, err := LoadFixtures(config.FixturesFile)
+	if err != nil {
+		return nil, config, newError(ErrInvalidConfig, "%v", err)
+	}
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, config, fmt.Errorf("failed to generate fixture server password: %w", err)
+	}
+	password := hex.EncodeToString(secret)
+
+	srv := ldapserver.New()
+	srv.AddEntries(fixtures.entries(config, time.Now()))
+	srv.AddBind(fixtureBindDN, password)
+	if err := srv.Start(); err != nil {
+		return nil, config, fmt.Errorf("failed to start fixture server: %w", err)
+	}
+
+	config.LdapServers = []string{srv.URL()}
+	config.Port = 0
+	config.Username = fixtureBindDN
+	config.Password = password
+	config.Credentials = nil
+	config.AuthMode = AuthSimple
+	config.UseStartTLS = false
+	config.TLSServerName = ""
+	config.CAFile, config.CACertPEM = "", ""
+	config.ClientCertFile, config.ClientKeyFile = "", ""
+	config.MaxReferralHops = 0
+	config.OfflineFallback = false
+	return srv, config, nil
+}
@@ -0,0 +1,167 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+const testFixtures = `
+users:
+  - uid: mgr
+    email: mgr@redhat.com
+    display_name: Morgan Manager
+    cost_center: "700"
+  - uid: jdoe
+    email: jdoe@redhat.com
+    display_name: Jane Doe
+    manager_uid: mgr
+    cost_center: "700"
+    rhat_hire_date: 20190304000000Z
+  - uid: leaver
+    email: leaver@redhat.com
+    status: deleted
+groups:
+  - name: openshift-eng
+    description: OpenShift engineering
+    members: [jdoe, mgr]
+`
+
+// writeFixtures writes content to a fixtures file named name in a temp dir
+func writeFixtures(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newOfflineSearcher returns a searcher serving testFixtures
+func newOfflineSearcher(t *testing.T) *ldap_redhat.Searcher {
+	t.Helper()
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:  []string{"ldaps://ldap.corp.redhat.com"},
+		Offline:      true,
+		FixturesFile: writeFixtures(t, "fixtures.yaml", testFixtures),
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	t.Cleanup(func() { searcher.Close() })
+	return searcher
+}
+
+func TestOfflineGetUser(t *testing.T) {
+	searcher := newOfflineSearcher(t)
+	ctx := context.Background()
+
+	user, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "JDoe@RedHat.com"})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.UID != "jdoe" || user.DisplayName != "Jane Doe" || user.ManagerUID != "mgr" {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+	if want := time.Date(2019, time.March, 4, 0, 0, 0, 0, time.UTC); !user.HireDate.Equal(want) {
+		t.Errorf("Expected hire date %v, got %v", want, user.HireDate)
+	}
+	if user.Status != ldap_redhat.StatusActive {
+		t.Errorf("Expected active status, got %q", user.Status)
+	}
+
+	_, err = searcher.GetUser(ctx, byUID("nobody"))
+	if !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for an unknown user, got %v", err)
+	}
+	_, err = searcher.GetUser(ctx, ldap_redhat.Identifier{Type: 99, Value: "jdoe"})
+	if !errors.Is(err, ldap_redhat.ErrInvalidIdentifier) {
+		t.Errorf("Expected ErrInvalidIdentifier for an unknown identifier type, got %v", err)
+	}
+
+	if _, err := searcher.GetUser(ctx, byUID("leaver")); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected a deleted user to be hidden from GetUser, got %v", err)
+	}
+	leaver, err := searcher.GetUserIncludingDeleted(ctx, byUID("leaver"))
+	if err != nil || leaver.Status != ldap_redhat.StatusDeleted {
+		t.Errorf("Expected GetUserIncludingDeleted to find the deleted user, got %+v, %v", leaver, err)
+	}
+}
+
+func TestOfflineGroups(t *testing.T) {
+	searcher := newOfflineSearcher(t)
+	ctx := context.Background()
+
+	groups, err := searcher.GetUserGroups(ctx, "jdoe")
+	if err != nil {
+		t.Fatalf("GetUserGroups failed: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Name != "openshift-eng" || groups[0].Description != "OpenShift engineering" {
+		t.Errorf("Unexpected groups: %+v", groups)
+	}
+	members, err := searcher.GetGroupMembers(ctx, "openshift-eng")
+	if err != nil {
+		t.Fatalf("GetGroupMembers failed: %v", err)
+	}
+	if len(members) != 2 {
+		t.Errorf("Expected 2 members, got %v", members)
+	}
+}
+
+func TestOfflineJSONFixtures(t *testing.T) {
+	path := writeFixtures(t, "fixtures.json", `{"users": [{"uid": "jdoe", "email": "jdoe@redhat.com", "hire_date": "2019-03-04T00:00:00Z"}]}`)
+	searcher, err := ldap_redhat.NewSearcherWithOptions(context.Background(), ldap_redhat.WithConfig(ldap_redhat.Config{Offline: true, FixturesFile: path}))
+	if err != nil {
+		t.Fatalf("NewSearcherWithOptions failed: %v", err)
+	}
+	defer searcher.Close()
+
+	user, err := searcher.GetUser(context.Background(), byUID("jdoe"))
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.RhatHireDate != "20190304000000Z" {
+		t.Errorf("Expected the raw hire date to be derived from hire_date, got %q", user.RhatHireDate)
+	}
+}
+
+func TestOfflineConfigErrors(t *testing.T) {
+	_, err := ldap_redhat.NewSearcher(ldap_redhat.Config{Offline: true})
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig without a fixtures file, got %v", err)
+	}
+	_, err = ldap_redhat.NewSearcher(ldap_redhat.Config{Offline: true, FixturesFile: filepath.Join(t.TempDir(), "missing.yaml")})
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for a missing fixtures file, got %v", err)
+	}
+	path := writeFixtures(t, "dup.yaml", "users:\n  - uid: jdoe\n  - uid: jdoe\n")
+	if _, err := ldap_redhat.LoadFixtures(path); err == nil {
+		t.Error("Expected LoadFixtures to reject duplicate users")
+	}
+
+	config := ldap_redhat.Config{Offline: true, FixturesFile: writeFixtures(t, "fixtures.yaml", testFixtures)}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected an offline config without servers to be valid, got %v", err)
+	}
+}
+
+func TestOfflineReload(t *testing.T) {
+	searcher := newOfflineSearcher(t)
+	ctx := context.Background()
+
+	path := writeFixtures(t, "reloaded.yaml", "users:\n  - uid: newhire\n    email: newhire@redhat.com\n")
+	if err := searcher.Reload(ctx, ldap_redhat.Config{Offline: true, FixturesFile: path}); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, byUID("newhire")); err != nil {
+		t.Errorf("Expected the reloaded fixtures to be served, got %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, byUID("jdoe")); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected the previous fixtures to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// defaultGroupBaseDN is searched for groups when Config.BaseDN is empty. The
+// default user base (ou=users) does not contain the group containers.
+const defaultGroupBaseDN = "dc=redhat,dc=com"
+
+// errGroupNotFound is returned by GetGroupMembers for unknown groups
+var errGroupNotFound = errors.New("group not found")
+
+// groupAttributes is the list of LDAP attributes fetched for group lookups
+var groupAttributes = []string{AttrCN, AttrDescription, AttrUniqueMember, AttrMember, AttrMemberUID}
+
+// Group is a directory group such as an ad-hoc managed group or a POSIX group.
+type Group struct {
+	Name        string `json:"name" yaml:"name"` // cn
+	DN          string `json:"dn" yaml:"dn"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// groupBase returns the base DN for group searches made with ctx
+func (s *Searcher) groupBase(ctx context.Context) (string, error) {
+	if scope, ok := RequestScopeFromContext(ctx); ok && scope.BaseDN != "" {
+		return s.searchBase(ctx)
+	}
+	if s.config().BaseDN != "" {
+		return s.config().BaseDN, nil
+	}
+	return defaultGroupBaseDN, nil
+}
+
+// GetUserGroups returns the groups uid belongs to, matched by uniqueMember,
+// member or memberUid, sorted by name. When the search stops at a size
+// limit, the groups received before it are returned with a
+// *PartialResultsError.
+func (s *Searcher) GetUserGroups(ctx context.Context, uid string) ([]Group, error) {
+	groups, err := s.getUserGroups(ctx, uid)
+	s.auditResults(ctx, AuditGetUserGroups, auditIdentifierType(IDTUID), uid, len(groups), err)
+	return groups, err
+}
+
+// getUserGroups is GetUserGroups without auditing
+func (s *Searcher) getUserGroups(ctx context.Context, uid string) ([]Group, error) {
+	if s.disconnected() {
+		return nil, errNotConnected()
+	}
+	baseDN, err := s.groupBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userDN := managerDNForUID(uid)
+	filter := fmt.Sprintf("(|(uniqueMember=%s)(member=%s)(memberUid=%s))", userDN, userDN, ldap.EscapeFilter(uid))
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		s.sizeLimit(ctx), 0, false, filter, []string{AttrCN, AttrDescription}, nil,
+	))
+	if err != nil && asPartial(err) == nil {
+		return nil, wrapLDAPError(err, "LDAP group search failed for %s", uid)
+	}
+
+	groups := make([]Group, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		groups = append(groups, entryToGroup(entry))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, err
+}
+
+// GetGroupMembers returns the UIDs of the members of the group named name
+// (its cn), sorted. Members listed by DN outside the users container, such as
+// nested groups, are skipped. A member listed more than once, by DNs or uids
+// that differ only in case, is returned once.
+func (s *Searcher) GetGroupMembers(ctx context.Context, name string) ([]string, error) {
+	members, err := s.getGroupMembers(ctx, name)
+	s.auditResults(ctx, AuditGetGroupMembers, auditRedactionGroup, name, len(members), err)
+	return members, err
+}
+
+// getGroupMembers is GetGroupMembers without auditing
+func (s *Searcher) getGroupMembers(ctx context.Context, name string) ([]string, error) {
+	if s.disconnected() {
+		return nil, errNotConnected()
+	}
+	baseDN, err := s.groupBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := fmt.Sprintf("(&(cn=%s)(|(objectClass=groupOfUniqueNames)(objectClass=groupOfNames)(objectClass=posixGroup)))", ldap.EscapeFilter(name))
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		0, 0, false, filter, groupAttributes, nil,
+	))
+	if err != nil {
+		return nil, wrapLDAPError(err, "LDAP group search failed for %s", name)
+	}
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("%w in LDAP directory: %s", errGroupNotFound, name)
+	}
+
+	seen := map[string]string{} // lowercased uid -> uid as first listed
+	add := func(uid string) {
+		if key := strings.ToLower(uid); uid != "" && seen[key] == "" {
+			seen[key] = uid
+		}
+	}
+	entry := result.Entries[0]
+	var dns []string
+	dns = append(dns, entry.GetEqualFoldAttributeValues(AttrUniqueMember)...)
+	dns = append(dns, entry.GetEqualFoldAttributeValues(AttrMember)...)
+	for _, dn := range dns {
+		add(userUIDFromDN(dn))
+	}
+	for _, uid := range entry.GetEqualFoldAttributeValues(AttrMemberUID) {
+		add(strings.TrimSpace(uid))
+	}
+
+	members := make([]string, 0, len(seen))
+	for _, uid := range seen {
+		members = append(members, uid)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func entryToGroup(entry *ldap.Entry) Group {
+	return Group{
+		Name:        entry.GetEqualFoldAttributeValue(AttrCN),
+		DN:          entry.DN,
+		Description: entry.GetEqualFoldAttributeValue(AttrDescription),
+	}
+}
@@ -0,0 +1,103 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// newGroupSearcher returns an embedded searcher whose base DN also covers the
+// group containers, seeded with an ad-hoc group and a POSIX group
+func newGroupSearcher(t *testing.T) *ldap_redhat.Searcher {
+	t.Helper()
+	searcher, srv := newEmbeddedSearcher(t, 10)
+	searcher.Config.BaseDN = "dc=redhat,dc=com"
+	srv.AddEntry("cn=openshift-eng,ou=adhoc,ou=managedGroups,dc=redhat,dc=com", map[string][]string{
+		"objectClass":  {"top", "groupOfUniqueNames"},
+		"cn":           {"openshift-eng"},
+		"description":  {"OpenShift engineering"},
+		"uniqueMember": {testserver.UserDN(1), testserver.UserDN(2), "cn=nested,ou=adhoc,ou=managedGroups,dc=redhat,dc=com"},
+	})
+	srv.AddEntry("cn=devs,ou=Groups,dc=redhat,dc=com", map[string][]string{
+		"objectClass": {"top", "posixGroup"},
+		"cn":          {"devs"},
+		"memberUid":   {testserver.UserUID(2), testserver.UserUID(3)},
+	})
+	return searcher
+}
+
+func TestGetUserGroups(t *testing.T) {
+	searcher := newGroupSearcher(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		uid      string
+		expected []string
+	}{
+		{testserver.UserUID(1), []string{"openshift-eng"}},
+		{testserver.UserUID(2), []string{"devs", "openshift-eng"}},
+		{testserver.UserUID(5), []string{}},
+	}
+	for _, test := range tests {
+		groups, err := searcher.GetUserGroups(ctx, test.uid)
+		if err != nil {
+			t.Errorf("GetUserGroups(%s) failed: %v", test.uid, err)
+			continue
+		}
+		names := []string{}
+		for _, g := range groups {
+			names = append(names, g.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("GetUserGroups(%s) = %v, expected %v", test.uid, names, test.expected)
+		}
+	}
+}
+
+func TestGetGroupMembers(t *testing.T) {
+	searcher := newGroupSearcher(t)
+	ctx := context.Background()
+
+	members, err := searcher.GetGroupMembers(ctx, "openshift-eng")
+	if err != nil {
+		t.Fatalf("GetGroupMembers failed: %v", err)
+	}
+	if expected := []string{testserver.UserUID(1), testserver.UserUID(2)}; !reflect.DeepEqual(members, expected) {
+		t.Errorf("Expected members %v, got %v", expected, members)
+	}
+
+	if _, err := searcher.GetGroupMembers(ctx, "missing"); err == nil {
+		t.Error("Expected error for a missing group")
+	}
+}
+
+func TestGroupMemberDNNormalization(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	searcher.Config.BaseDN = "dc=redhat,dc=com"
+	srv.AddEntry("cn=mixed,ou=adhoc,ou=managedGroups,dc=redhat,dc=com", map[string][]string{
+		"objectClass": {"top", "groupOfNames"},
+		"cn":          {"mixed"},
+		"member": {
+			"UID=user000001,OU=Users,DC=RedHat,DC=com",
+			"uid=USER000001, ou=users, dc=redhat, dc=com",
+			"uid=user000002, ou=users, dc=redhat, dc=com",
+			"uid=svc,ou=serviceaccounts,dc=redhat,dc=com",
+		},
+	})
+	ctx := context.Background()
+
+	members, err := searcher.GetGroupMembers(ctx, "mixed")
+	if err != nil {
+		t.Fatalf("GetGroupMembers failed: %v", err)
+	}
+	if expected := []string{testserver.UserUID(1), testserver.UserUID(2)}; !reflect.DeepEqual(members, expected) {
+		t.Errorf("Expected members %v, got %v", expected, members)
+	}
+	groups, err := searcher.GetUserGroups(ctx, testserver.UserUID(2))
+	if err != nil || len(groups) != 1 || groups[0].Name != "mixed" {
+		t.Errorf("Expected user000002 to be found by a member DN with spaces, got %v, %v", groups, err)
+	}
+}
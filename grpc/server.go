@@ -0,0 +1,263 @@
+// Package grpc serves user lookups over gRPC with the UserDirectory service
+// of userdirectory.proto, so internal services can share one deployment
+// that holds the directory credentials and connection instead of each
+// binding to LDAP themselves.
+//
+//	s := grpc.NewServer()
+//	userdirectorypb.RegisterUserDirectoryServer(s, ldapgrpc.NewServer(searcher))
+//	s.Serve(lis)
+//
+// Import it under another name, such as ldapgrpc, next to google.golang.org/grpc.
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/grpc/userdirectorypb"
+	"github.com/openshift-eng/go-ldap-redhat/internal/buildfeature"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxBatchSize is the number of identifiers BatchGetUsers accepts at once,
+// keeping the OR filter it searches with within what directories allow.
+const MaxBatchSize = 1000
+
+// defaultFilter is searched by SearchUsers when the request has no filter
+const defaultFilter = "(uid=*)"
+
+// Directory is the part of *ldap_redhat.Searcher the server uses, also
+// implemented by ldaptest.FakeSearcher.
+type Directory interface {
+	GetUser(ctx context.Context, id ldap_redhat.Identifier) (ldap_redhat.UserRecord, error)
+	GetUsers(ctx context.Context, ids []ldap_redhat.Identifier) ([]ldap_redhat.UserRecord, error)
+	ForEachUser(ctx context.Context, filter string, fn func(ldap_redhat.UserRecord) error) error
+}
+
+// Server implements userdirectorypb.UserDirectoryServer on a Directory.
+type Server struct {
+	userdirectorypb.UnimplementedUserDirectoryServer
+	dir    Directory
+	policy ldap_redhat.ScopePolicy
+	claims func(ctx context.Context) ([]string, error)
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithScopePolicy restricts each call to the RequestScope that policy grants
+// the caller's claims, which claims returns from the call's context, e.g. the
+// OAuth scopes of its token. Calls whose claims cannot be read fail with
+// UNAUTHENTICATED, and those without a recognized claim with
+// PERMISSION_DENIED. Without it every caller may read every attribute.
+func WithScopePolicy(policy ldap_redhat.ScopePolicy, claims func(ctx context.Context) ([]string, error)) ServerOption {
+	return func(s *Server) {
+		s.policy = policy
+		s.claims = claims
+	}
+}
+
+var _ userdirectorypb.UserDirectoryServer = (*Server)(nil)
+
+func init() { buildfeature.Register("grpc") }
+
+// NewServer returns a Server answering from dir. A *ldap_redhat.Searcher is
+// safe to share across the concurrent calls a gRPC server makes.
+func NewServer(dir Directory, opts ...ServerOption) *Server {
+	s := &Server{dir: dir}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// scoped returns ctx restricted to the scope of its caller, when the server
+// has a scope policy
+func (s *Server) scoped(ctx context.Context) (context.Context, error) {
+	if s.claims == nil {
+		return ctx, nil
+	}
+	claims, err := s.claims(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "failed to read scope claims: %v", err)
+	}
+	scope, err := s.policy.Resolve(claims)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return ldap_redhat.WithRequestScope(ctx, scope), nil
+}
+
+// GetUser returns the user req.Id refers to.
+func (s *Server) GetUser(ctx context.Context, req *userdirectorypb.GetUserRequest) (*userdirectorypb.User, error) {
+	id, err := ldap_redhat.ParseIdentifier(req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	ctx, err = s.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.dir.GetUser(ctx, id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(user), nil
+}
+
+// BatchGetUsers returns a result for each of req.Ids, in order. Identifiers
+// that cannot be parsed, match no one or fail on their own are reported in
+// their result; errors affecting the whole batch fail the call.
+func (s *Server) BatchGetUsers(ctx context.Context, req *userdirectorypb.BatchGetUsersRequest) (*userdirectorypb.BatchGetUsersResponse, error) {
+	if len(req.GetIds()) > MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "%d identifiers requested, at most %d allowed", len(req.GetIds()), MaxBatchSize)
+	}
+	ctx, err := s.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*userdirectorypb.UserResult, len(req.GetIds()))
+	var ids []ldap_redhat.Identifier
+	var positions []int // result index of each of ids
+	for i, value := range req.GetIds() {
+		results[i] = &userdirectorypb.UserResult{Id: value}
+		id, err := ldap_redhat.ParseIdentifier(value)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		ids = append(ids, id)
+		positions = append(positions, i)
+	}
+
+	users, err := s.dir.GetUsers(ctx, ids)
+	var batchErr *ldap_redhat.BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return nil, statusError(err)
+	}
+	if batchErr != nil {
+		for _, item := range batchErr.Errors {
+			results[positions[item.Index]].Error = item.Err.Error()
+		}
+	}
+	for i, u := range users {
+		if u.UID != "" {
+			results[positions[i]].User = toProto(u)
+		}
+	}
+	return &userdirectorypb.BatchGetUsersResponse{Results: results}, nil
+}
+
+// SearchUsers streams the users matching req.Filter as ForEachUser returns
+// them. The filter is checked before the search so that a malformed one
+// fails with INVALID_ARGUMENT.
+func (s *Server) SearchUsers(req *userdirectorypb.SearchUsersRequest, stream userdirectorypb.UserDirectory_SearchUsersServer) error {
+	filter := req.GetFilter()
+	if filter == "" {
+		filter = defaultFilter
+	}
+	if _, err := ldap.CompileFilter(filter); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid filter %q: %v", filter, err)
+	}
+	ctx, err := s.scoped(stream.Context())
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	err = s.dir.ForEachUser(ctx, filter, func(u ldap_redhat.UserRecord) error {
+		sendErr = stream.Send(toProto(u))
+		return sendErr
+	})
+	if sendErr != nil {
+		// already a status, or the client has gone away
+		return sendErr
+	}
+	if err != nil {
+		return statusError(err)
+	}
+	return nil
+}
+
+// GetVersion reports the build of the serving binary.
+func (s *Server) GetVersion(ctx context.Context, req *userdirectorypb.GetVersionRequest) (*userdirectorypb.VersionInfo, error) {
+	info := ldap_redhat.BuildInfo()
+	gates := make(map[string]bool, len(info.FeatureGates))
+	for f, enabled := range info.FeatureGates {
+		gates[string(f)] = enabled
+	}
+	return &userdirectorypb.VersionInfo{
+		Version:       info.Version,
+		Commit:        info.Commit,
+		Modified:      info.Modified,
+		GoVersion:     info.GoVersion,
+		GoLdapVersion: info.LDAPVersion,
+		Features:      info.Features,
+		FeatureGates:  gates,
+	}, nil
+}
+
+// statusError returns err as a gRPC status with the code its error kind
+// maps to
+func statusError(err error) error {
+	return status.Error(statusCode(err), err.Error())
+}
+
+// statusCode returns the gRPC code for a lookup error
+func statusCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ldap_redhat.ErrInvalidIdentifier):
+		return codes.InvalidArgument
+	case errors.Is(err, ldap_redhat.ErrUserNotFound):
+		return codes.NotFound
+	case errors.Is(err, ldap_redhat.ErrMultipleMatches):
+		return codes.FailedPrecondition
+	case errors.Is(err, ldap_redhat.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, ldap_redhat.ErrNotConnected), errors.Is(err, ldap_redhat.ErrCircuitOpen):
+		return codes.Unavailable
+	case errors.Is(err, ldap_redhat.ErrAuthFailed):
+		return codes.Internal
+	case errors.As(err, new(*ldap_redhat.PartialResultsError)):
+		return codes.ResourceExhausted
+	}
+	return codes.Unknown
+}
+
+// toProto converts u to its wire form
+func toProto(u ldap_redhat.UserRecord) *userdirectorypb.User {
+	return &userdirectorypb.User{
+		Uid:               u.UID,
+		Email:             u.Email,
+		DisplayName:       u.DisplayName,
+		Surname:           u.Surname,
+		Title:             u.Title,
+		ManagerUid:        u.ManagerUID,
+		ManagerDn:         u.ManagerDN,
+		CostCenter:        u.CostCenter,
+		CostCenterDesc:    u.CostCenterDesc,
+		RhatLocation:      u.RhatLocation,
+		RhatJobCode:       u.RhatJobCode,
+		RhatUuid:          u.RhatUUID,
+		RhatHireDate:      u.RhatHireDate,
+		RhatTermDate:      u.RhatTermDate,
+		RhatAdjSvcDate:    u.RhatAdjSvcDate,
+		Country:           u.Country,
+		Department:        u.Department,
+		EmployeeNumber:    u.EmployeeNumber,
+		KerberosPrincipal: u.KerberosPrincipal,
+		Geo:               u.Geo,
+		OrgChartTitle:     u.OrgChartTitle,
+		PersonType:        u.PersonType,
+		Building:          u.Building,
+		Mobile:            u.Mobile,
+		TelephoneNumber:   u.TelephoneNumber,
+		IsPeopleManager:   u.IsPeopleManager,
+		Status:            string(u.Status),
+		Stale:             u.Stale,
+	}
+}
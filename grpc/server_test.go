@@ -0,0 +1,277 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	ldapgrpc "github.com/openshift-eng/go-ldap-redhat/grpc"
+	"github.com/openshift-eng/go-ldap-redhat/grpc/userdirectorypb"
+	"github.com/openshift-eng/go-ldap-redhat/ldaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient serves dir over an in-memory listener and returns a client for it
+func newClient(t *testing.T, dir ldapgrpc.Directory, opts ...ldapgrpc.ServerOption) userdirectorypb.UserDirectoryClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	userdirectorypb.RegisterUserDirectoryServer(srv, ldapgrpc.NewServer(dir, opts...))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return userdirectorypb.NewUserDirectoryClient(conn)
+}
+
+func newDirectory() *ldaptest.FakeSearcher {
+	return ldaptest.NewFakeSearcher(
+		ldap_redhat.UserRecord{UID: "alice", Email: "alice@redhat.com", DisplayName: "Alice Ng", CostCenter: "700", Status: ldap_redhat.StatusActive},
+		ldap_redhat.UserRecord{UID: "bob", Email: "bob@redhat.com", CostCenter: "700", Geo: "EMEA"},
+		ldap_redhat.UserRecord{UID: "carol", Email: "carol@redhat.com", CostCenter: "800"},
+	)
+}
+
+func TestGetUser(t *testing.T) {
+	client := newClient(t, newDirectory())
+	ctx := context.Background()
+
+	user, err := client.GetUser(ctx, &userdirectorypb.GetUserRequest{Id: "alice@redhat.com"})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.GetUid() != "alice" || user.GetDisplayName() != "Alice Ng" || user.GetStatus() != "active" {
+		t.Errorf("GetUser returned %v, want alice", user)
+	}
+
+	for _, tt := range []struct {
+		id   string
+		code codes.Code
+	}{
+		{"nobody", codes.NotFound},
+		{"not an identifier!", codes.InvalidArgument},
+	} {
+		_, err := client.GetUser(ctx, &userdirectorypb.GetUserRequest{Id: tt.id})
+		if status.Code(err) != tt.code {
+			t.Errorf("GetUser(%q) returned %v, want %s", tt.id, err, tt.code)
+		}
+	}
+}
+
+func TestGetUserErrors(t *testing.T) {
+	dir := newDirectory()
+	client := newClient(t, dir)
+	for _, tt := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{ldap_redhat.ErrTimeout, codes.DeadlineExceeded},
+		{ldap_redhat.ErrNotConnected, codes.Unavailable},
+		{ldap_redhat.ErrMultipleMatches, codes.FailedPrecondition},
+		{errors.New("boom"), codes.Unknown},
+	} {
+		dir.SetError("GetUser", tt.err)
+		_, err := client.GetUser(context.Background(), &userdirectorypb.GetUserRequest{Id: "alice"})
+		if status.Code(err) != tt.code {
+			t.Errorf("GetUser with %v returned %v, want %s", tt.err, err, tt.code)
+		}
+	}
+}
+
+func TestBatchGetUsers(t *testing.T) {
+	client := newClient(t, newDirectory())
+	resp, err := client.BatchGetUsers(context.Background(), &userdirectorypb.BatchGetUsersRequest{
+		Ids: []string{"carol", "bad id!", "nobody", "alice@redhat.com"},
+	})
+	if err != nil {
+		t.Fatalf("BatchGetUsers failed: %v", err)
+	}
+	var got []string
+	for _, r := range resp.GetResults() {
+		switch {
+		case r.GetError() != "":
+			got = append(got, r.GetId()+":error")
+		case r.GetUser() == nil:
+			got = append(got, r.GetId()+":missing")
+		default:
+			got = append(got, r.GetId()+":"+r.GetUser().GetUid())
+		}
+	}
+	want := "carol:carol bad id!:error nobody:missing alice@redhat.com:alice"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Results = %s, want %s", strings.Join(got, " "), want)
+	}
+
+	ids := make([]string, ldapgrpc.MaxBatchSize+1)
+	if _, err := client.BatchGetUsers(context.Background(), &userdirectorypb.BatchGetUsersRequest{Ids: ids}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Oversized batch returned %v, want InvalidArgument", err)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	client := newClient(t, newDirectory())
+	search := func(filter string) ([]string, error) {
+		stream, err := client.SearchUsers(context.Background(), &userdirectorypb.SearchUsersRequest{Filter: filter})
+		if err != nil {
+			return nil, err
+		}
+		var uids []string
+		for {
+			u, err := stream.Recv()
+			if err == io.EOF {
+				return uids, nil
+			}
+			if err != nil {
+				return uids, err
+			}
+			uids = append(uids, u.GetUid())
+		}
+	}
+
+	uids, err := search("(rhatCostCenter=700)")
+	if err != nil || strings.Join(uids, " ") != "alice bob" {
+		t.Errorf("Search returned %v (%v), want alice bob", uids, err)
+	}
+	if uids, err := search(""); err != nil || len(uids) != 3 {
+		t.Errorf("Search without a filter returned %v (%v), want everyone", uids, err)
+	}
+	if _, err := search("(rhatCostCenter=700"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Malformed filter returned %v, want InvalidArgument", err)
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	client := newClient(t, newDirectory())
+
+	info, err := client.GetVersion(context.Background(), &userdirectorypb.GetVersionRequest{})
+	if err != nil {
+		t.Fatalf("GetVersion failed: %v", err)
+	}
+	if info.GetVersion() != ldap_redhat.Version || !strings.HasPrefix(info.GetGoLdapVersion(), "v3.") {
+		t.Errorf("GetVersion returned %v, want version %s and a go-ldap v3 version", info, ldap_redhat.Version)
+	}
+	if !slices.Contains(info.GetFeatures(), "grpc") {
+		t.Errorf("Expected the grpc feature to be reported, got %v", info.GetFeatures())
+	}
+	if _, ok := info.GetFeatureGates()[string(ldap_redhat.FeatureVLV)]; !ok {
+		t.Errorf("Expected the VLV gate to be reported, got %v", info.GetFeatureGates())
+	}
+}
+
+// scopeRecorder records the RequestScope each lookup is made with
+type scopeRecorder struct {
+	*ldaptest.FakeSearcher
+	scopes []*ldap_redhat.RequestScope
+}
+
+func (r *scopeRecorder) record(ctx context.Context) {
+	var scope *ldap_redhat.RequestScope
+	if s, ok := ldap_redhat.RequestScopeFromContext(ctx); ok {
+		scope = &s
+	}
+	r.scopes = append(r.scopes, scope)
+}
+
+func (r *scopeRecorder) GetUser(ctx context.Context, id ldap_redhat.Identifier) (ldap_redhat.UserRecord, error) {
+	r.record(ctx)
+	return r.FakeSearcher.GetUser(ctx, id)
+}
+
+func (r *scopeRecorder) GetUsers(ctx context.Context, ids []ldap_redhat.Identifier) ([]ldap_redhat.UserRecord, error) {
+	r.record(ctx)
+	return r.FakeSearcher.GetUsers(ctx, ids)
+}
+
+func (r *scopeRecorder) ForEachUser(ctx context.Context, filter string, fn func(ldap_redhat.UserRecord) error) error {
+	r.record(ctx)
+	return r.FakeSearcher.ForEachUser(ctx, filter, fn)
+}
+
+func TestScopePolicy(t *testing.T) {
+	dir := &scopeRecorder{FakeSearcher: newDirectory()}
+	policy := ldap_redhat.ScopePolicy{
+		"directory.basic": {Attributes: []string{"cn", "mail"}},
+		"directory.full":  {},
+	}
+	// Claims come from the x-scopes metadata of each call
+	claims := func(ctx context.Context) ([]string, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if len(md.Get("x-scopes")) == 0 {
+			return nil, errors.New("no x-scopes metadata")
+		}
+		return md.Get("x-scopes"), nil
+	}
+	client := newClient(t, dir, ldapgrpc.WithScopePolicy(policy, claims))
+	as := func(scopes ...string) context.Context {
+		ctx := context.Background()
+		for _, scope := range scopes {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-scopes", scope)
+		}
+		return ctx
+	}
+
+	if _, err := client.GetUser(as("directory.basic"), &userdirectorypb.GetUserRequest{Id: "alice"}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if _, err := client.BatchGetUsers(as("directory.full"), &userdirectorypb.BatchGetUsersRequest{Ids: []string{"alice"}}); err != nil {
+		t.Fatalf("BatchGetUsers failed: %v", err)
+	}
+	stream, err := client.SearchUsers(as("directory.basic", "directory.full"), &userdirectorypb.SearchUsersRequest{})
+	if err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if err != io.EOF {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+
+	if len(dir.scopes) != 3 {
+		t.Fatalf("Got %d lookups, want 3", len(dir.scopes))
+	}
+	if s := dir.scopes[0]; s == nil || !slices.Equal(s.Attributes, []string{"cn", "mail"}) {
+		t.Errorf("GetUser scope is %v, want cn and mail", s)
+	}
+	for i, s := range dir.scopes[1:] {
+		if s == nil || s.Attributes != nil {
+			t.Errorf("Lookup %d scope is %v, want unrestricted", i+1, s)
+		}
+	}
+
+	// Callers without claims, or without recognized ones, never reach the
+	// directory
+	if _, err := client.GetUser(as(), &userdirectorypb.GetUserRequest{Id: "alice"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetUser without claims returned %v, want Unauthenticated", err)
+	}
+	if _, err := client.BatchGetUsers(as("directory.admin"), &userdirectorypb.BatchGetUsersRequest{Ids: []string{"alice"}}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("BatchGetUsers with an unknown claim returned %v, want PermissionDenied", err)
+	}
+	stream, err = client.SearchUsers(as("directory.admin"), &userdirectorypb.SearchUsersRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("SearchUsers with an unknown claim returned %v, want PermissionDenied", err)
+	}
+	if len(dir.scopes) != 3 {
+		t.Errorf("Got %d lookups, want none past the first 3", len(dir.scopes))
+	}
+}
@@ -0,0 +1,808 @@
+// UserDirectory serves Red Hat LDAP user lookups to internal services, so
+// that one deployment holds the directory credentials and connections.
+//
+// Regenerate the Go stubs in userdirectorypb with `make proto`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: userdirectory.proto
+
+package userdirectorypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetUserRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// A uid, email address, rhatUUID, employee number or Kerberos principal,
+	// detected as ldap_redhat.ParseIdentifier does.
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserRequest) Reset() {
+	*x = GetUserRequest{}
+	mi := &file_userdirectory_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserRequest) ProtoMessage() {}
+
+func (x *GetUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserRequest.ProtoReflect.Descriptor instead.
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetUserRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type BatchGetUsersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifiers in any of the forms GetUserRequest.id accepts.
+	Ids           []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetUsersRequest) Reset() {
+	*x = BatchGetUsersRequest{}
+	mi := &file_userdirectory_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetUsersRequest) ProtoMessage() {}
+
+func (x *BatchGetUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetUsersRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetUsersRequest) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BatchGetUsersRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type BatchGetUsersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One result per requested identifier, in request order.
+	Results       []*UserResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetUsersResponse) Reset() {
+	*x = BatchGetUsersResponse{}
+	mi := &file_userdirectory_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetUsersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetUsersResponse) ProtoMessage() {}
+
+func (x *BatchGetUsersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetUsersResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetUsersResponse) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchGetUsersResponse) GetResults() []*UserResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type UserResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The identifier as requested.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The user, unset when the identifier failed or matched no one.
+	User *User `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	// Why the identifier failed, empty when it did not.
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserResult) Reset() {
+	*x = UserResult{}
+	mi := &file_userdirectory_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserResult) ProtoMessage() {}
+
+func (x *UserResult) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserResult.ProtoReflect.Descriptor instead.
+func (*UserResult) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UserResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UserResult) GetUser() *User {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *UserResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type SearchUsersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// An LDAP filter such as "(rhatCostCenter=700)"; all users when empty.
+	Filter        string `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchUsersRequest) Reset() {
+	*x = SearchUsersRequest{}
+	mi := &file_userdirectory_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchUsersRequest) ProtoMessage() {}
+
+func (x *SearchUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchUsersRequest.ProtoReflect.Descriptor instead.
+func (*SearchUsersRequest) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchUsersRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+// User mirrors ldap_redhat.UserRecord. Dates are the raw directory values,
+// which ldap_redhat.ParseLDAPTime parses.
+type User struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Uid               string                 `protobuf:"bytes,1,opt,name=uid,proto3" json:"uid,omitempty"`
+	Email             string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	DisplayName       string                 `protobuf:"bytes,3,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Surname           string                 `protobuf:"bytes,4,opt,name=surname,proto3" json:"surname,omitempty"`
+	Title             string                 `protobuf:"bytes,5,opt,name=title,proto3" json:"title,omitempty"`
+	ManagerUid        string                 `protobuf:"bytes,6,opt,name=manager_uid,json=managerUid,proto3" json:"manager_uid,omitempty"`
+	ManagerDn         string                 `protobuf:"bytes,7,opt,name=manager_dn,json=managerDn,proto3" json:"manager_dn,omitempty"`
+	CostCenter        string                 `protobuf:"bytes,8,opt,name=cost_center,json=costCenter,proto3" json:"cost_center,omitempty"`
+	CostCenterDesc    string                 `protobuf:"bytes,9,opt,name=cost_center_desc,json=costCenterDesc,proto3" json:"cost_center_desc,omitempty"`
+	RhatLocation      string                 `protobuf:"bytes,10,opt,name=rhat_location,json=rhatLocation,proto3" json:"rhat_location,omitempty"`
+	RhatJobCode       string                 `protobuf:"bytes,11,opt,name=rhat_job_code,json=rhatJobCode,proto3" json:"rhat_job_code,omitempty"`
+	RhatUuid          string                 `protobuf:"bytes,12,opt,name=rhat_uuid,json=rhatUuid,proto3" json:"rhat_uuid,omitempty"`
+	RhatHireDate      string                 `protobuf:"bytes,13,opt,name=rhat_hire_date,json=rhatHireDate,proto3" json:"rhat_hire_date,omitempty"`
+	RhatTermDate      string                 `protobuf:"bytes,14,opt,name=rhat_term_date,json=rhatTermDate,proto3" json:"rhat_term_date,omitempty"`
+	RhatAdjSvcDate    string                 `protobuf:"bytes,15,opt,name=rhat_adj_svc_date,json=rhatAdjSvcDate,proto3" json:"rhat_adj_svc_date,omitempty"`
+	Country           string                 `protobuf:"bytes,16,opt,name=country,proto3" json:"country,omitempty"`
+	Department        string                 `protobuf:"bytes,17,opt,name=department,proto3" json:"department,omitempty"`
+	EmployeeNumber    string                 `protobuf:"bytes,18,opt,name=employee_number,json=employeeNumber,proto3" json:"employee_number,omitempty"`
+	KerberosPrincipal string                 `protobuf:"bytes,19,opt,name=kerberos_principal,json=kerberosPrincipal,proto3" json:"kerberos_principal,omitempty"`
+	Geo               string                 `protobuf:"bytes,20,opt,name=geo,proto3" json:"geo,omitempty"`
+	OrgChartTitle     string                 `protobuf:"bytes,21,opt,name=org_chart_title,json=orgChartTitle,proto3" json:"org_chart_title,omitempty"`
+	PersonType        string                 `protobuf:"bytes,22,opt,name=person_type,json=personType,proto3" json:"person_type,omitempty"`
+	Building          string                 `protobuf:"bytes,23,opt,name=building,proto3" json:"building,omitempty"`
+	Mobile            string                 `protobuf:"bytes,24,opt,name=mobile,proto3" json:"mobile,omitempty"`
+	TelephoneNumber   string                 `protobuf:"bytes,25,opt,name=telephone_number,json=telephoneNumber,proto3" json:"telephone_number,omitempty"`
+	IsPeopleManager   bool                   `protobuf:"varint,26,opt,name=is_people_manager,json=isPeopleManager,proto3" json:"is_people_manager,omitempty"`
+	// "active", "terminated" or "deleted".
+	Status string `protobuf:"bytes,27,opt,name=status,proto3" json:"status,omitempty"`
+	// Served from the offline snapshot because the directory was unreachable.
+	Stale         bool `protobuf:"varint,28,opt,name=stale,proto3" json:"stale,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_userdirectory_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *User) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+func (x *User) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *User) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *User) GetSurname() string {
+	if x != nil {
+		return x.Surname
+	}
+	return ""
+}
+
+func (x *User) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *User) GetManagerUid() string {
+	if x != nil {
+		return x.ManagerUid
+	}
+	return ""
+}
+
+func (x *User) GetManagerDn() string {
+	if x != nil {
+		return x.ManagerDn
+	}
+	return ""
+}
+
+func (x *User) GetCostCenter() string {
+	if x != nil {
+		return x.CostCenter
+	}
+	return ""
+}
+
+func (x *User) GetCostCenterDesc() string {
+	if x != nil {
+		return x.CostCenterDesc
+	}
+	return ""
+}
+
+func (x *User) GetRhatLocation() string {
+	if x != nil {
+		return x.RhatLocation
+	}
+	return ""
+}
+
+func (x *User) GetRhatJobCode() string {
+	if x != nil {
+		return x.RhatJobCode
+	}
+	return ""
+}
+
+func (x *User) GetRhatUuid() string {
+	if x != nil {
+		return x.RhatUuid
+	}
+	return ""
+}
+
+func (x *User) GetRhatHireDate() string {
+	if x != nil {
+		return x.RhatHireDate
+	}
+	return ""
+}
+
+func (x *User) GetRhatTermDate() string {
+	if x != nil {
+		return x.RhatTermDate
+	}
+	return ""
+}
+
+func (x *User) GetRhatAdjSvcDate() string {
+	if x != nil {
+		return x.RhatAdjSvcDate
+	}
+	return ""
+}
+
+func (x *User) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *User) GetDepartment() string {
+	if x != nil {
+		return x.Department
+	}
+	return ""
+}
+
+func (x *User) GetEmployeeNumber() string {
+	if x != nil {
+		return x.EmployeeNumber
+	}
+	return ""
+}
+
+func (x *User) GetKerberosPrincipal() string {
+	if x != nil {
+		return x.KerberosPrincipal
+	}
+	return ""
+}
+
+func (x *User) GetGeo() string {
+	if x != nil {
+		return x.Geo
+	}
+	return ""
+}
+
+func (x *User) GetOrgChartTitle() string {
+	if x != nil {
+		return x.OrgChartTitle
+	}
+	return ""
+}
+
+func (x *User) GetPersonType() string {
+	if x != nil {
+		return x.PersonType
+	}
+	return ""
+}
+
+func (x *User) GetBuilding() string {
+	if x != nil {
+		return x.Building
+	}
+	return ""
+}
+
+func (x *User) GetMobile() string {
+	if x != nil {
+		return x.Mobile
+	}
+	return ""
+}
+
+func (x *User) GetTelephoneNumber() string {
+	if x != nil {
+		return x.TelephoneNumber
+	}
+	return ""
+}
+
+func (x *User) GetIsPeopleManager() bool {
+	if x != nil {
+		return x.IsPeopleManager
+	}
+	return false
+}
+
+func (x *User) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *User) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+type GetVersionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVersionRequest) Reset() {
+	*x = GetVersionRequest{}
+	mi := &file_userdirectory_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVersionRequest) ProtoMessage() {}
+
+func (x *GetVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVersionRequest.ProtoReflect.Descriptor instead.
+func (*GetVersionRequest) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{6}
+}
+
+// VersionInfo mirrors ldap_redhat.VersionInfo.
+type VersionInfo struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Version string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Commit  string                 `protobuf:"bytes,2,opt,name=commit,proto3" json:"commit,omitempty"`
+	// Built from a modified working tree.
+	Modified      bool   `protobuf:"varint,3,opt,name=modified,proto3" json:"modified,omitempty"`
+	GoVersion     string `protobuf:"bytes,4,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	GoLdapVersion string `protobuf:"bytes,5,opt,name=go_ldap_version,json=goLdapVersion,proto3" json:"go_ldap_version,omitempty"`
+	// Optional packages linked into the binary, such as "vault" or "grpc".
+	Features []string `protobuf:"bytes,6,rep,name=features,proto3" json:"features,omitempty"`
+	// The effective experimental feature gates.
+	FeatureGates  map[string]bool `protobuf:"bytes,7,rep,name=feature_gates,json=featureGates,proto3" json:"feature_gates,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VersionInfo) Reset() {
+	*x = VersionInfo{}
+	mi := &file_userdirectory_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionInfo) ProtoMessage() {}
+
+func (x *VersionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_userdirectory_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionInfo.ProtoReflect.Descriptor instead.
+func (*VersionInfo) Descriptor() ([]byte, []int) {
+	return file_userdirectory_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *VersionInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *VersionInfo) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *VersionInfo) GetModified() bool {
+	if x != nil {
+		return x.Modified
+	}
+	return false
+}
+
+func (x *VersionInfo) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *VersionInfo) GetGoLdapVersion() string {
+	if x != nil {
+		return x.GoLdapVersion
+	}
+	return ""
+}
+
+func (x *VersionInfo) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *VersionInfo) GetFeatureGates() map[string]bool {
+	if x != nil {
+		return x.FeatureGates
+	}
+	return nil
+}
+
+var File_userdirectory_proto protoreflect.FileDescriptor
+
+const file_userdirectory_proto_rawDesc = "" +
+	"\n" +
+	"\x13userdirectory.proto\x12\x0eredhat.ldap.v1\" \n" +
+	"\x0eGetUserRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"(\n" +
+	"\x14BatchGetUsersRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"M\n" +
+	"\x15BatchGetUsersResponse\x124\n" +
+	"\aresults\x18\x01 \x03(\v2\x1a.redhat.ldap.v1.UserResultR\aresults\"\\\n" +
+	"\n" +
+	"UserResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x04user\x18\x02 \x01(\v2\x14.redhat.ldap.v1.UserR\x04user\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\",\n" +
+	"\x12SearchUsersRequest\x12\x16\n" +
+	"\x06filter\x18\x01 \x01(\tR\x06filter\"\x8f\a\n" +
+	"\x04User\x12\x10\n" +
+	"\x03uid\x18\x01 \x01(\tR\x03uid\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x12!\n" +
+	"\fdisplay_name\x18\x03 \x01(\tR\vdisplayName\x12\x18\n" +
+	"\asurname\x18\x04 \x01(\tR\asurname\x12\x14\n" +
+	"\x05title\x18\x05 \x01(\tR\x05title\x12\x1f\n" +
+	"\vmanager_uid\x18\x06 \x01(\tR\n" +
+	"managerUid\x12\x1d\n" +
+	"\n" +
+	"manager_dn\x18\a \x01(\tR\tmanagerDn\x12\x1f\n" +
+	"\vcost_center\x18\b \x01(\tR\n" +
+	"costCenter\x12(\n" +
+	"\x10cost_center_desc\x18\t \x01(\tR\x0ecostCenterDesc\x12#\n" +
+	"\rrhat_location\x18\n" +
+	" \x01(\tR\frhatLocation\x12\"\n" +
+	"\rrhat_job_code\x18\v \x01(\tR\vrhatJobCode\x12\x1b\n" +
+	"\trhat_uuid\x18\f \x01(\tR\brhatUuid\x12$\n" +
+	"\x0erhat_hire_date\x18\r \x01(\tR\frhatHireDate\x12$\n" +
+	"\x0erhat_term_date\x18\x0e \x01(\tR\frhatTermDate\x12)\n" +
+	"\x11rhat_adj_svc_date\x18\x0f \x01(\tR\x0erhatAdjSvcDate\x12\x18\n" +
+	"\acountry\x18\x10 \x01(\tR\acountry\x12\x1e\n" +
+	"\n" +
+	"department\x18\x11 \x01(\tR\n" +
+	"department\x12'\n" +
+	"\x0femployee_number\x18\x12 \x01(\tR\x0eemployeeNumber\x12-\n" +
+	"\x12kerberos_principal\x18\x13 \x01(\tR\x11kerberosPrincipal\x12\x10\n" +
+	"\x03geo\x18\x14 \x01(\tR\x03geo\x12&\n" +
+	"\x0forg_chart_title\x18\x15 \x01(\tR\rorgChartTitle\x12\x1f\n" +
+	"\vperson_type\x18\x16 \x01(\tR\n" +
+	"personType\x12\x1a\n" +
+	"\bbuilding\x18\x17 \x01(\tR\bbuilding\x12\x16\n" +
+	"\x06mobile\x18\x18 \x01(\tR\x06mobile\x12)\n" +
+	"\x10telephone_number\x18\x19 \x01(\tR\x0ftelephoneNumber\x12*\n" +
+	"\x11is_people_manager\x18\x1a \x01(\bR\x0fisPeopleManager\x12\x16\n" +
+	"\x06status\x18\x1b \x01(\tR\x06status\x12\x14\n" +
+	"\x05stale\x18\x1c \x01(\bR\x05stale\"\x13\n" +
+	"\x11GetVersionRequest\"\xd3\x02\n" +
+	"\vVersionInfo\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x16\n" +
+	"\x06commit\x18\x02 \x01(\tR\x06commit\x12\x1a\n" +
+	"\bmodified\x18\x03 \x01(\bR\bmodified\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x04 \x01(\tR\tgoVersion\x12&\n" +
+	"\x0fgo_ldap_version\x18\x05 \x01(\tR\rgoLdapVersion\x12\x1a\n" +
+	"\bfeatures\x18\x06 \x03(\tR\bfeatures\x12R\n" +
+	"\rfeature_gates\x18\a \x03(\v2-.redhat.ldap.v1.VersionInfo.FeatureGatesEntryR\ffeatureGates\x1a?\n" +
+	"\x11FeatureGatesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x012\xc7\x02\n" +
+	"\rUserDirectory\x12?\n" +
+	"\aGetUser\x12\x1e.redhat.ldap.v1.GetUserRequest\x1a\x14.redhat.ldap.v1.User\x12\\\n" +
+	"\rBatchGetUsers\x12$.redhat.ldap.v1.BatchGetUsersRequest\x1a%.redhat.ldap.v1.BatchGetUsersResponse\x12I\n" +
+	"\vSearchUsers\x12\".redhat.ldap.v1.SearchUsersRequest\x1a\x14.redhat.ldap.v1.User0\x01\x12L\n" +
+	"\n" +
+	"GetVersion\x12!.redhat.ldap.v1.GetVersionRequest\x1a\x1b.redhat.ldap.v1.VersionInfoB>Z<github.com/openshift-eng/go-ldap-redhat/grpc/userdirectorypbb\x06proto3"
+
+var (
+	file_userdirectory_proto_rawDescOnce sync.Once
+	file_userdirectory_proto_rawDescData []byte
+)
+
+func file_userdirectory_proto_rawDescGZIP() []byte {
+	file_userdirectory_proto_rawDescOnce.Do(func() {
+		file_userdirectory_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_userdirectory_proto_rawDesc), len(file_userdirectory_proto_rawDesc)))
+	})
+	return file_userdirectory_proto_rawDescData
+}
+
+var file_userdirectory_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_userdirectory_proto_goTypes = []any{
+	(*GetUserRequest)(nil),        // 0: redhat.ldap.v1.GetUserRequest
+	(*BatchGetUsersRequest)(nil),  // 1: redhat.ldap.v1.BatchGetUsersRequest
+	(*BatchGetUsersResponse)(nil), // 2: redhat.ldap.v1.BatchGetUsersResponse
+	(*UserResult)(nil),            // 3: redhat.ldap.v1.UserResult
+	(*SearchUsersRequest)(nil),    // 4: redhat.ldap.v1.SearchUsersRequest
+	(*User)(nil),                  // 5: redhat.ldap.v1.User
+	(*GetVersionRequest)(nil),     // 6: redhat.ldap.v1.GetVersionRequest
+	(*VersionInfo)(nil),           // 7: redhat.ldap.v1.VersionInfo
+	nil,                           // 8: redhat.ldap.v1.VersionInfo.FeatureGatesEntry
+}
+var file_userdirectory_proto_depIdxs = []int32{
+	3, // 0: redhat.ldap.v1.BatchGetUsersResponse.results:type_name -> redhat.ldap.v1.UserResult
+	5, // 1: redhat.ldap.v1.UserResult.user:type_name -> redhat.ldap.v1.User
+	8, // 2: redhat.ldap.v1.VersionInfo.feature_gates:type_name -> redhat.ldap.v1.VersionInfo.FeatureGatesEntry
+	0, // 3: redhat.ldap.v1.UserDirectory.GetUser:input_type -> redhat.ldap.v1.GetUserRequest
+	1, // 4: redhat.ldap.v1.UserDirectory.BatchGetUsers:input_type -> redhat.ldap.v1.BatchGetUsersRequest
+	4, // 5: redhat.ldap.v1.UserDirectory.SearchUsers:input_type -> redhat.ldap.v1.SearchUsersRequest
+	6, // 6: redhat.ldap.v1.UserDirectory.GetVersion:input_type -> redhat.ldap.v1.GetVersionRequest
+	5, // 7: redhat.ldap.v1.UserDirectory.GetUser:output_type -> redhat.ldap.v1.User
+	2, // 8: redhat.ldap.v1.UserDirectory.BatchGetUsers:output_type -> redhat.ldap.v1.BatchGetUsersResponse
+	5, // 9: redhat.ldap.v1.UserDirectory.SearchUsers:output_type -> redhat.ldap.v1.User
+	7, // 10: redhat.ldap.v1.UserDirectory.GetVersion:output_type -> redhat.ldap.v1.VersionInfo
+	7, // [7:11] is the sub-list for method output_type
+	3, // [3:7] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_userdirectory_proto_init() }
+func file_userdirectory_proto_init() {
+	if File_userdirectory_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_userdirectory_proto_rawDesc), len(file_userdirectory_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_userdirectory_proto_goTypes,
+		DependencyIndexes: file_userdirectory_proto_depIdxs,
+		MessageInfos:      file_userdirectory_proto_msgTypes,
+	}.Build()
+	File_userdirectory_proto = out.File
+	file_userdirectory_proto_goTypes = nil
+	file_userdirectory_proto_depIdxs = nil
+}
@@ -0,0 +1,266 @@
+// UserDirectory serves Red Hat LDAP user lookups to internal services, so
+// that one deployment holds the directory credentials and connections.
+//
+// Regenerate the Go stubs in userdirectorypb with `make proto`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: userdirectory.proto
+
+package userdirectorypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	UserDirectory_GetUser_FullMethodName       = "/redhat.ldap.v1.UserDirectory/GetUser"
+	UserDirectory_BatchGetUsers_FullMethodName = "/redhat.ldap.v1.UserDirectory/BatchGetUsers"
+	UserDirectory_SearchUsers_FullMethodName   = "/redhat.ldap.v1.UserDirectory/SearchUsers"
+	UserDirectory_GetVersion_FullMethodName    = "/redhat.ldap.v1.UserDirectory/GetVersion"
+)
+
+// UserDirectoryClient is the client API for UserDirectory service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserDirectoryClient interface {
+	// GetUser returns the user an identifier refers to. It fails with
+	// INVALID_ARGUMENT for an identifier that cannot be parsed, NOT_FOUND when
+	// no user matches and FAILED_PRECONDITION when several do.
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	// BatchGetUsers looks up several identifiers in one directory search. The
+	// results are in request order; an identifier that fails on its own is
+	// reported in its result rather than failing the call.
+	BatchGetUsers(ctx context.Context, in *BatchGetUsersRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error)
+	// SearchUsers streams the users matching an LDAP filter as the directory
+	// returns them, a page at a time.
+	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[User], error)
+	// GetVersion reports the build of the serving binary, as
+	// ldap_redhat.BuildInfo does, so operators can tell which version and
+	// optional packages a deployment runs.
+	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*VersionInfo, error)
+}
+
+type userDirectoryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserDirectoryClient(cc grpc.ClientConnInterface) UserDirectoryClient {
+	return &userDirectoryClient{cc}
+}
+
+func (c *userDirectoryClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, UserDirectory_GetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userDirectoryClient) BatchGetUsers(ctx context.Context, in *BatchGetUsersRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetUsersResponse)
+	err := c.cc.Invoke(ctx, UserDirectory_BatchGetUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userDirectoryClient) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[User], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &UserDirectory_ServiceDesc.Streams[0], UserDirectory_SearchUsers_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SearchUsersRequest, User]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserDirectory_SearchUsersClient = grpc.ServerStreamingClient[User]
+
+func (c *userDirectoryClient) GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*VersionInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VersionInfo)
+	err := c.cc.Invoke(ctx, UserDirectory_GetVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserDirectoryServer is the server API for UserDirectory service.
+// All implementations must embed UnimplementedUserDirectoryServer
+// for forward compatibility.
+type UserDirectoryServer interface {
+	// GetUser returns the user an identifier refers to. It fails with
+	// INVALID_ARGUMENT for an identifier that cannot be parsed, NOT_FOUND when
+	// no user matches and FAILED_PRECONDITION when several do.
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	// BatchGetUsers looks up several identifiers in one directory search. The
+	// results are in request order; an identifier that fails on its own is
+	// reported in its result rather than failing the call.
+	BatchGetUsers(context.Context, *BatchGetUsersRequest) (*BatchGetUsersResponse, error)
+	// SearchUsers streams the users matching an LDAP filter as the directory
+	// returns them, a page at a time.
+	SearchUsers(*SearchUsersRequest, grpc.ServerStreamingServer[User]) error
+	// GetVersion reports the build of the serving binary, as
+	// ldap_redhat.BuildInfo does, so operators can tell which version and
+	// optional packages a deployment runs.
+	GetVersion(context.Context, *GetVersionRequest) (*VersionInfo, error)
+	mustEmbedUnimplementedUserDirectoryServer()
+}
+
+// UnimplementedUserDirectoryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUserDirectoryServer struct{}
+
+func (UnimplementedUserDirectoryServer) GetUser(context.Context, *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedUserDirectoryServer) BatchGetUsers(context.Context, *BatchGetUsersRequest) (*BatchGetUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetUsers not implemented")
+}
+func (UnimplementedUserDirectoryServer) SearchUsers(*SearchUsersRequest, grpc.ServerStreamingServer[User]) error {
+	return status.Errorf(codes.Unimplemented, "method SearchUsers not implemented")
+}
+func (UnimplementedUserDirectoryServer) GetVersion(context.Context, *GetVersionRequest) (*VersionInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
+func (UnimplementedUserDirectoryServer) mustEmbedUnimplementedUserDirectoryServer() {}
+func (UnimplementedUserDirectoryServer) testEmbeddedByValue()                       {}
+
+// UnsafeUserDirectoryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserDirectoryServer will
+// result in compilation errors.
+type UnsafeUserDirectoryServer interface {
+	mustEmbedUnimplementedUserDirectoryServer()
+}
+
+func RegisterUserDirectoryServer(s grpc.ServiceRegistrar, srv UserDirectoryServer) {
+	// If the following call pancis, it indicates UnimplementedUserDirectoryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&UserDirectory_ServiceDesc, srv)
+}
+
+func _UserDirectory_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDirectoryServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserDirectory_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDirectoryServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserDirectory_BatchGetUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDirectoryServer).BatchGetUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserDirectory_BatchGetUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDirectoryServer).BatchGetUsers(ctx, req.(*BatchGetUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserDirectory_SearchUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchUsersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserDirectoryServer).SearchUsers(m, &grpc.GenericServerStream[SearchUsersRequest, User]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserDirectory_SearchUsersServer = grpc.ServerStreamingServer[User]
+
+func _UserDirectory_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDirectoryServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserDirectory_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDirectoryServer).GetVersion(ctx, req.(*GetVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserDirectory_ServiceDesc is the grpc.ServiceDesc for UserDirectory service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserDirectory_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "redhat.ldap.v1.UserDirectory",
+	HandlerType: (*UserDirectoryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetUser",
+			Handler:    _UserDirectory_GetUser_Handler,
+		},
+		{
+			MethodName: "BatchGetUsers",
+			Handler:    _UserDirectory_BatchGetUsers_Handler,
+		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _UserDirectory_GetVersion_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SearchUsers",
+			Handler:       _UserDirectory_SearchUsers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "userdirectory.proto",
+}
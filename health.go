@@ -0,0 +1,201 @@
+package ldap_redhat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DefaultHealthTimeout bounds Healthz when its context has no deadline,
+// below the 1s-10s probe timeouts Kubernetes deployments usually set
+const DefaultHealthTimeout = 5 * time.Second
+
+// Health check names, in the order Healthz runs them
+const (
+	HealthConnectivity = "connectivity" // the server answers a root DSE read, re-dialing if needed
+	HealthBind         = "bind"         // the connection is bound as the configured identity
+	HealthSearch       = "search"       // a canary search under the base DN returns a user
+)
+
+// HealthCheck is the outcome of one check of Healthz.
+type HealthCheck struct {
+	Name     string        `json:"name"`
+	OK       bool          `json:"ok"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// HealthReport is the outcome of Healthz, served as JSON by
+// ReadinessHandler.
+type HealthReport struct {
+	Status    string        `json:"status"` // "ok" or "unavailable"
+	Server    string        `json:"server"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+// Healthy reports whether every check passed
+func (r HealthReport) Healthy() bool {
+	return r.Status == "ok"
+}
+
+// Healthz checks that s can serve lookups: the server is reachable (Ping,
+// which re-dials a dropped connection), the connection is bound as the
+// configured identity rather than anonymously, e.g. after a password
+// rotation broke the re-bind, and a canary search under the base DN returns
+// a user, which catches lost read access. Checks run in order and stop at
+// the first failure, whose error is returned with the report. Without a
+// deadline on ctx, DefaultHealthTimeout applies.
+func Healthz(ctx context.Context, s *Searcher) (HealthReport, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultHealthTimeout)
+		defer cancel()
+	}
+	report := HealthReport{Status: "ok", CheckedAt: time.Now()}
+	if servers := s.config().LdapServers; len(servers) > 0 {
+		report.Server = servers[0]
+	}
+	checks := []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{HealthConnectivity, s.Ping},
+		{HealthBind, s.checkBind},
+		{HealthSearch, s.checkCanarySearch},
+	}
+	for _, c := range checks {
+		start := time.Now()
+		err := c.run(ctx)
+		check := HealthCheck{Name: c.name, OK: err == nil, Duration: time.Since(start)}
+		if err != nil {
+			check.Error = err.Error()
+			report.Status = "unavailable"
+		}
+		report.Checks = append(report.Checks, check)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// DefaultReadinessInterval is how long ReadinessHandler reuses a report when
+// called with a zero interval
+const DefaultReadinessInterval = 5 * time.Second
+
+// LivenessHandler returns an http.Handler for liveness probes. It answers
+// 200 without contacting the directory, so an LDAP outage makes the process
+// unready rather than restarted, and probes cost nothing.
+func (s *Searcher) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// ReadinessHandler returns an http.Handler for readiness probes. It writes
+// the HealthReport of Healthz as JSON, with status 200 when healthy and 503
+// otherwise. Healthz runs at most once per interval, DefaultReadinessInterval
+// when zero: requests in between get the last report, and concurrent
+// requests share one run, so frequent or unauthenticated probes cannot load
+// the directory. Each run has DefaultHealthTimeout and is not cancelled by
+// the request that started it.
+func (s *Searcher) ReadinessHandler(interval time.Duration) http.Handler {
+	if interval <= 0 {
+		interval = DefaultReadinessInterval
+	}
+	rc := &readinessCache{s: s, interval: interval}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := rc.report(r.Context())
+		status := http.StatusOK
+		if !report.Healthy() {
+			status = http.StatusServiceUnavailable
+		}
+		writeHealth(w, status, report)
+	})
+}
+
+// readinessCache holds the last HealthReport of ReadinessHandler
+type readinessCache struct {
+	s        *Searcher
+	interval time.Duration
+
+	mu      sync.Mutex // held while Healthz runs, so callers share the run
+	checked time.Time
+	last    HealthReport
+}
+
+// report returns the last report, running Healthz first if it is older
+// than the interval
+func (rc *readinessCache) report(ctx context.Context) HealthReport {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if !rc.checked.IsZero() && time.Since(rc.checked) < rc.interval {
+		return rc.last
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultHealthTimeout)
+	defer cancel()
+	rc.last, _ = Healthz(ctx, rc.s)
+	rc.checked = time.Now()
+	return rc.last
+}
+
+// writeHealth writes a probe response as uncached JSON
+func writeHealth(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// checkBind fails when the connection is anonymous although the searcher is
+// configured to bind. The "Who am I?" operation takes no context, so it is
+// abandoned, not cancelled, when ctx ends first.
+func (s *Searcher) checkBind(ctx context.Context) error {
+	type result struct {
+		id  BindIdentity
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		id, err := s.WhoAmI(ctx)
+		done <- result{id, err}
+	}()
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	config := s.config()
+	authMode, _ := config.AuthMode.normalize()
+	if r.id.Anonymous() && (config.HasCredentials() || authMode == AuthExternal) {
+		return newError(ErrAuthFailed, "LDAP connection is anonymous, want %s bind", authMode)
+	}
+	return nil
+}
+
+// checkCanarySearch searches the base DN for any one user, returning no
+// attributes. It bypasses Config.Interceptors, so that a caching or rate
+// limiting interceptor cannot hide the state of the directory.
+func (s *Searcher) checkCanarySearch(ctx context.Context) error {
+	result, err := s.searchLDAP(ctx, ldap.NewSearchRequest(
+		s.baseDN(), ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		1, 0, false, defaultSnapshotFilter, []string{"1.1"}, nil,
+	))
+	if err != nil && asPartial(err) == nil { // more than one user is fine
+		return wrapLDAPError(err, "LDAP canary search failed")
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return errors.New("LDAP canary search returned no users")
+	}
+	return nil
+}
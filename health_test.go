@@ -0,0 +1,88 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+)
+
+func TestHealthz(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx := context.Background()
+
+	report, err := ldap_redhat.Healthz(ctx, searcher)
+	if err != nil || !report.Healthy() || len(report.Checks) != 3 {
+		t.Fatalf("Healthz returned %+v, %v, want 3 passing checks", report, err)
+	}
+	for i, name := range []string{ldap_redhat.HealthConnectivity, ldap_redhat.HealthBind, ldap_redhat.HealthSearch} {
+		if c := report.Checks[i]; c.Name != name || !c.OK {
+			t.Errorf("Check %d is %+v, want %s passing", i, c, name)
+		}
+	}
+
+	// Searches failing, e.g. after losing read access, fail the canary
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultInsufficientAccessRights})
+	report, err = ldap_redhat.Healthz(ctx, searcher)
+	if err == nil || report.Healthy() {
+		t.Fatalf("Healthz with failing searches returned %+v, %v", report, err)
+	}
+	if last := report.Checks[len(report.Checks)-1]; last.OK || last.Error == "" {
+		t.Errorf("The failed check is reported as %+v", last)
+	}
+}
+
+func TestHealthzEmptyDirectory(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 0)
+	report, err := ldap_redhat.Healthz(context.Background(), searcher)
+	if err == nil || report.Checks[len(report.Checks)-1].Name != ldap_redhat.HealthSearch {
+		t.Errorf("Healthz of a directory without users returned %+v, %v, want a failed canary search", report, err)
+	}
+}
+
+func TestLivenessHandler(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	srv.Close()
+
+	rec := httptest.NewRecorder()
+	searcher.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Got %d with the server down, want 200: liveness must not depend on LDAP", rec.Code)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	handler := searcher.ReadinessHandler(100 * time.Millisecond)
+	probe := func() (int, ldap_redhat.HealthReport) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report ldap_redhat.HealthReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Invalid health report %q: %v", rec.Body, err)
+		}
+		return rec.Code, report
+	}
+
+	code, first := probe()
+	if code != http.StatusOK || first.Status != "ok" || first.Server != srv.URL() {
+		t.Errorf("Got %d %+v, want 200 with an ok report", code, first)
+	}
+
+	// Within the interval the last report is served without checking again
+	srv.Close()
+	if code, report := probe(); code != http.StatusOK || !report.CheckedAt.Equal(first.CheckedAt) {
+		t.Errorf("Got %d checked at %v within the interval, want the cached 200 of %v", code, report.CheckedAt, first.CheckedAt)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if code, _ := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("Got %d with the server down after the interval, want 503", code)
+	}
+}
@@ -0,0 +1,158 @@
+package ldap_redhat
+
+import (
+	"regexp"
+	"strings"
+)
+
+// identifierKind describes how users are looked up by an identifier type
+type identifierKind struct {
+	name   string                   // Config.FilterTemplates key, also used in cache keys
+	filter string                   // default search filter, %s replaced by the value
+	attr   string                   // attribute the filter matches and field is read from
+	field  func(*UserRecord) string // record field holding the identifier
+	fold   bool                     // values match case-insensitively
+	valid  func(string) bool        // rejects malformed values before searching, if set
+}
+
+// identifierKinds maps every identifier type to its lookup
+var identifierKinds = map[int]identifierKind{
+	IDTUID: {
+		name: FilterTemplateUID, filter: "(uid=%s)", attr: AttrUID,
+		field: func(u *UserRecord) string { return u.UID },
+		// The directory matches uids case-insensitively
+		valid: func(uid string) bool { return IsValidRhatUID(strings.ToLower(uid)) },
+	},
+	IDTEmail: {
+		name: FilterTemplateEmail, filter: "(mail=%s)", attr: AttrMail, fold: true,
+		field: func(u *UserRecord) string { return u.Email },
+	},
+	IDTUUID: {
+		name: FilterTemplateUUID, filter: "(rhatUUID=%s)", attr: AttrRhatUUID, fold: true,
+		field: func(u *UserRecord) string { return u.RhatUUID },
+		valid: IsValidRhatUUID,
+	},
+	IDTEmployeeNumber: {
+		name: FilterTemplateEmployeeNumber, filter: "(employeeNumber=%s)", attr: AttrEmployeeNumber,
+		field: func(u *UserRecord) string { return u.EmployeeNumber },
+	},
+	IDTKerberos: {
+		name: FilterTemplateKerberos, filter: "(krbPrincipalName=%s)", attr: AttrKrbPrincipalName, fold: true,
+		field: func(u *UserRecord) string { return u.KerberosPrincipal },
+	},
+}
+
+// identifierKey returns the form in which values of idType are compared, or
+// false for an unknown type
+func identifierKey(idType int, value string) (string, bool) {
+	kind, ok := identifierKinds[idType]
+	if !ok {
+		return "", false
+	}
+	if kind.fold {
+		value = strings.ToLower(value)
+	}
+	return value, true
+}
+
+// recordIdentifiers returns the identifier types u can be found by, with the
+// keys of its values
+func recordIdentifiers(u *UserRecord) map[int]string {
+	out := make(map[int]string, len(identifierKinds))
+	for idType, kind := range identifierKinds {
+		if value := kind.field(u); value != "" {
+			out[idType], _ = identifierKey(idType, value)
+		}
+	}
+	return out
+}
+
+// withIdentifierAttributes returns attrs plus the attributes batch lookups of
+// ids match results by, which a request scope or attribute profile may have
+// left out. Results are redacted once matched.
+func withIdentifierAttributes(attrs []string, ids []Identifier) []string {
+	for _, id := range ids {
+		if kind, ok := identifierKinds[id.Type]; ok {
+			attrs = withAttributes(attrs, kind.attr)
+		}
+	}
+	return attrs
+}
+
+var (
+	uuidPattern           = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	employeeNumberPattern = regexp.MustCompile(`^[0-9]+$`)
+	uidPattern            = regexp.MustCompile(`^[a-z](?:[a-z0-9._-]*[a-z0-9])?$`)
+	emailPattern          = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	kerberosRealmPattern  = regexp.MustCompile(`^[A-Z0-9][A-Z0-9.-]*$`)
+)
+
+// maxUIDLength is the longest uid the directory assigns, the POSIX login
+// name limit
+const maxUIDLength = 32
+
+// nilUUID is never assigned as a rhatUUID
+const nilUUID = "00000000-0000-0000-0000-000000000000"
+
+// IsValidRhatUID reports whether uid is a well-formed Red Hat login name:
+// 1 to 32 lowercase letters, digits, '.', '_' and '-', starting with a letter
+// and ending with a letter or digit, such as "jdoe" or "pco-deleted-users".
+// Directory uids are lowercase; fold user input with strings.ToLower first.
+func IsValidRhatUID(uid string) bool {
+	return len(uid) <= maxUIDLength && uidPattern.MatchString(uid)
+}
+
+// IsValidRhatUUID reports whether uuid is a well-formed rhatUUID: the
+// hyphenated 8-4-4-4-12 form of RFC 4122, with hex digits in either case.
+// The nil UUID is rejected, since it is never assigned.
+func IsValidRhatUUID(uuid string) bool {
+	return uuidPattern.MatchString(uuid) && uuid != nilUUID
+}
+
+// validateIdentifier rejects malformed uids and UUIDs before they are
+// searched for, unless Config.FilterTemplates replaces their lookup
+func (s *Searcher) validateIdentifier(id Identifier) error {
+	kind, ok := identifierKinds[id.Type]
+	if !ok || kind.valid == nil || kind.valid(id.Value) {
+		return nil
+	}
+	if _, custom := s.customFilterTemplate(id.Type); custom {
+		return nil
+	}
+	return newError(ErrInvalidIdentifier, "invalid %s: %q", kind.name, id.Value)
+}
+
+// ParseIdentifier classifies user input as an identifier:
+//
+//   - name@REALM with an all-uppercase realm is a Kerberos principal
+//   - any other local@domain.tld is an email address
+//   - a hyphenated 8-4-4-4-12 hex string is a rhatUUID
+//   - a string of digits is an employee number
+//   - anything IsValidRhatUID accepts once lowercased is a uid
+//
+// Surrounding whitespace is trimmed, and uids are lowercased. Anything else returns an error matching
+// ErrInvalidIdentifier.
+func ParseIdentifier(input string) (Identifier, error) {
+	value := strings.TrimSpace(input)
+	if value == "" {
+		return Identifier{}, newError(ErrInvalidIdentifier, "empty identifier")
+	}
+	if local, realm, ok := strings.Cut(value, "@"); ok {
+		if local != "" && !strings.ContainsAny(local, "@ \t") && kerberosRealmPattern.MatchString(realm) && strings.ContainsAny(realm, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			return Identifier{Type: IDTKerberos, Value: value}, nil
+		}
+		if emailPattern.MatchString(value) {
+			return Identifier{Type: IDTEmail, Value: value}, nil
+		}
+		return Identifier{}, newError(ErrInvalidIdentifier, "invalid email address: %q", value)
+	}
+	switch {
+	case uuidPattern.MatchString(value):
+		return Identifier{Type: IDTUUID, Value: value}, nil
+	case employeeNumberPattern.MatchString(value):
+		return Identifier{Type: IDTEmployeeNumber, Value: value}, nil
+	case IsValidRhatUID(strings.ToLower(value)):
+		return Identifier{Type: IDTUID, Value: strings.ToLower(value)}, nil
+	}
+	return Identifier{}, newError(ErrInvalidIdentifier, "not a uid, email, UUID or employee number: %q", value)
+}
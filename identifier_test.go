@@ -0,0 +1,208 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestIdentifierTypes(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 10)
+	ctx := context.Background()
+
+	user3, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(3)})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user3.EmployeeNumber != "100003" || user3.KerberosPrincipal != testserver.UserUID(3)+"@REDHAT.COM" {
+		t.Errorf("Expected employee number and Kerberos principal, got %q, %q", user3.EmployeeNumber, user3.KerberosPrincipal)
+	}
+
+	ids := []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUUID, Value: strings.ToUpper(user3.RhatUUID)},
+		{Type: ldap_redhat.IDTEmployeeNumber, Value: "100004"},
+		{Type: ldap_redhat.IDTKerberos, Value: testserver.UserUID(5) + "@redhat.com"},
+		{Type: ldap_redhat.IDTEmployeeNumber, Value: "999999"},
+	}
+	want := []string{testserver.UserUID(3), testserver.UserUID(4), testserver.UserUID(5), ""}
+	for i, id := range ids[:3] {
+		user, err := searcher.GetUser(ctx, id)
+		if err != nil || user.UID != want[i] {
+			t.Errorf("GetUser(%+v) = %q, %v; want %q", id, user.UID, err, want[i])
+		}
+	}
+	users, err := searcher.GetUsers(ctx, ids)
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	for i, u := range users {
+		if u.UID != want[i] {
+			t.Errorf("GetUsers result %d: expected %q, got %q", i, want[i], u.UID)
+		}
+	}
+
+	sn := ldap_redhat.NewSnapshot(users[:3], time.Now())
+	if u, ok := sn.Lookup(ids[0]); !ok || u.UID != testserver.UserUID(3) {
+		t.Errorf("Expected snapshot lookup by rhatUUID, got %q, %v", u.UID, ok)
+	}
+	if u, ok := sn.Lookup(ids[2]); !ok || u.UID != testserver.UserUID(5) {
+		t.Errorf("Expected snapshot lookup by Kerberos principal, got %q, %v", u.UID, ok)
+	}
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: 99, Value: "x"}); err == nil {
+		t.Error("Expected an error for an unknown identifier type")
+	}
+}
+
+func TestCachedSearcherIdentifierTypes(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), 0)
+	ctx := context.Background()
+
+	if _, err := cached.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	srv.Close()
+	// The record is cached under every identifier it has, and identifier
+	// types with the same value do not collide
+	byNumber := ldap_redhat.Identifier{Type: ldap_redhat.IDTEmployeeNumber, Value: "100002"}
+	if user, err := cached.GetUser(ctx, byNumber); err != nil || user.UID != testserver.UserUID(2) {
+		t.Errorf("Expected cached record by employee number, got %q, %v", user.UID, err)
+	}
+	if _, err := cached.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "100002"}); err == nil {
+		t.Error("Expected a uid lookup not to hit the employee number key")
+	}
+}
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		input    string
+		idType   int
+		value    string
+		wantFail bool
+	}{
+		{input: "jdoe", idType: ldap_redhat.IDTUID, value: "jdoe"},
+		{input: "  j.doe-2 ", idType: ldap_redhat.IDTUID, value: "j.doe-2"},
+		{input: "JDoe", idType: ldap_redhat.IDTUID, value: "jdoe"},
+		{input: "jdoe@redhat.com", idType: ldap_redhat.IDTEmail, value: "jdoe@redhat.com"},
+		{input: "John.Doe+tag@Example.org", idType: ldap_redhat.IDTEmail, value: "John.Doe+tag@Example.org"},
+		{input: "jdoe@REDHAT.COM", idType: ldap_redhat.IDTKerberos, value: "jdoe@REDHAT.COM"},
+		{input: "host/box.example.com@IPA.EXAMPLE.COM", idType: ldap_redhat.IDTKerberos, value: "host/box.example.com@IPA.EXAMPLE.COM"},
+		{input: "0000002a-0000-4000-8000-00000000002A", idType: ldap_redhat.IDTUUID, value: "0000002a-0000-4000-8000-00000000002A"},
+		{input: "100042", idType: ldap_redhat.IDTEmployeeNumber, value: "100042"},
+		{input: "", wantFail: true},
+		{input: "   ", wantFail: true},
+		{input: "jdoe@", wantFail: true},
+		{input: "@redhat.com", wantFail: true},
+		{input: "jdoe@localhost", wantFail: true},
+		{input: "a@b@redhat.com", wantFail: true},
+		{input: "j doe", wantFail: true},
+		{input: "(uid=*)", wantFail: true},
+		{input: "-jdoe", wantFail: true},
+		{input: "jdoe-", wantFail: true},
+		{input: "2jdoe", wantFail: true},
+	}
+	for _, test := range tests {
+		id, err := ldap_redhat.ParseIdentifier(test.input)
+		if test.wantFail {
+			if !errors.Is(err, ldap_redhat.ErrInvalidIdentifier) {
+				t.Errorf("ParseIdentifier(%q) = %+v, %v; expected ErrInvalidIdentifier", test.input, id, err)
+			}
+			continue
+		}
+		if err != nil || id.Type != test.idType || id.Value != test.value {
+			t.Errorf("ParseIdentifier(%q) = %+v, %v; expected type %d value %q", test.input, id, err, test.idType, test.value)
+		}
+	}
+}
+
+func TestIsValidRhatUID(t *testing.T) {
+	tests := []struct {
+		uid   string
+		valid bool
+	}{
+		{"jdoe", true},
+		{"j", true},
+		{"jemedina", true},
+		{"j.doe-2", true},
+		{"pco-deleted-users-query", true},
+		{"svc_backup", true},
+		{testserver.UserUID(42), true},
+		{strings.Repeat("a", 32), true},
+		{strings.Repeat("a", 33), false},
+		{"", false},
+		{"JDoe", false},
+		{"2jdoe", false},
+		{"100042", false},
+		{"-jdoe", false},
+		{"jdoe-", false},
+		{"jdoe.", false},
+		{"j doe", false},
+		{"jdoe@redhat.com", false},
+		{"(uid=*)", false},
+		{"jdöe", false},
+	}
+	for _, test := range tests {
+		if got := ldap_redhat.IsValidRhatUID(test.uid); got != test.valid {
+			t.Errorf("IsValidRhatUID(%q) = %v, expected %v", test.uid, got, test.valid)
+		}
+	}
+}
+
+func TestIsValidRhatUUID(t *testing.T) {
+	tests := []struct {
+		uuid  string
+		valid bool
+	}{
+		{"0000002a-0000-4000-8000-00000000002a", true},
+		{"0000002A-0000-4000-8000-00000000002A", true},
+		{"12345678-1234-1234-1234-123456789abc", true},
+		{"00000000-0000-0000-0000-000000000000", false},
+		{"", false},
+		{"0000002a00004000800000000000002a", false},
+		{"{0000002a-0000-4000-8000-00000000002a}", false},
+		{"0000002a-0000-4000-8000-00000000002", false},
+		{"0000002g-0000-4000-8000-00000000002a", false},
+		{" 0000002a-0000-4000-8000-00000000002a", false},
+	}
+	for _, test := range tests {
+		if got := ldap_redhat.IsValidRhatUUID(test.uuid); got != test.valid {
+			t.Errorf("IsValidRhatUUID(%q) = %v, expected %v", test.uuid, got, test.valid)
+		}
+	}
+}
+
+func TestGetUserRejectsMalformedIdentifiers(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	ctx := context.Background()
+
+	for _, id := range []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUID, Value: "j doe"},
+		{Type: ldap_redhat.IDTUUID, Value: "not-a-uuid"},
+	} {
+		if _, err := searcher.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrInvalidIdentifier) {
+			t.Errorf("GetUser(%+v) = %v, expected ErrInvalidIdentifier", id, err)
+		}
+	}
+	// uids match case-insensitively in the directory
+	upper := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: strings.ToUpper(testserver.UserUID(1))}
+	if _, err := searcher.GetUser(ctx, upper); err != nil {
+		t.Errorf("GetUser(%+v) failed: %v", upper, err)
+	}
+
+	users, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)},
+		{Type: ldap_redhat.IDTUID, Value: "j doe"},
+	})
+	var batchErr *ldap_redhat.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Index != 1 {
+		t.Errorf("Expected a batch error for the malformed uid, got %v", err)
+	}
+	if len(users) != 2 || users[0].UID != testserver.UserUID(1) {
+		t.Errorf("Expected the valid uid to be found, got %v", users)
+	}
+}
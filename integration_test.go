@@ -2,8 +2,8 @@ package ldap_redhat_test
 
 import (
 	"context"
+	"errors"
 	"os"
-	"strings"
 	"testing"
 	"time"
 
@@ -17,8 +17,8 @@ func TestLDAPIntegration(t *testing.T) {
 		t.Skip("Skipping integration test: LDAP_URL not set")
 	}
 
-	// Skip if running in CI without credentials
-	if os.Getenv("CI") == "true" && os.Getenv("INTEGRATION_TESTS") != "true" {
+	// Skip if running in CI against a real directory without opting in
+	if embeddedDirectory == nil && os.Getenv("CI") == "true" && os.Getenv("INTEGRATION_TESTS") != "true" {
 		t.Skip("Skipping integration test in CI")
 	}
 
@@ -38,7 +38,7 @@ func TestLDAPIntegration(t *testing.T) {
 			identifier := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testUID}
 			user, err := searcher.GetUser(ctx, identifier)
 			if err != nil {
-				if err.Error() == "user not found in LDAP directory: "+testUID {
+				if errors.Is(err, ldap_redhat.ErrUserNotFound) {
 					t.Skipf("Test user %s not found (expected for some environments)", testUID)
 				}
 				t.Fatalf("Failed to search by UID: %v", err)
@@ -61,7 +61,7 @@ func TestLDAPIntegration(t *testing.T) {
 			identifier := ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: testEmail}
 			user, err := searcher.GetUser(ctx, identifier)
 			if err != nil {
-				if err.Error() == "user not found in LDAP directory: "+testEmail {
+				if errors.Is(err, ldap_redhat.ErrUserNotFound) {
 					t.Skipf("Test email %s not found (expected for some environments)", testEmail)
 				}
 				t.Fatalf("Failed to search by email: %v", err)
@@ -84,9 +84,7 @@ func TestLDAPIntegration(t *testing.T) {
 		}
 
 		// Check for either authentication error (no credentials) or user not found
-		if !strings.Contains(err.Error(), "user not found in LDAP directory") &&
-			!strings.Contains(err.Error(), "Anonymous access is not allowed") &&
-			!strings.Contains(err.Error(), "Inappropriate Authentication") {
+		if !errors.Is(err, ldap_redhat.ErrUserNotFound) && !errors.Is(err, ldap_redhat.ErrAuthFailed) {
 			t.Errorf("Expected user not found or authentication error, got: %v", err)
 		}
 	})
@@ -0,0 +1,93 @@
+package ldap_redhat
+
+import (
+	"context"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// SearchFunc runs an LDAP search request and returns its raw result.
+type SearchFunc func(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error)
+
+// Interceptor wraps the searcher's searches with a cross-cutting concern,
+// such as logging, metrics, caching, rate limiting or auditing. It returns a
+// SearchFunc that does its work and calls next to continue the search, or
+// returns without calling next to answer or refuse it itself:
+//
+//	func rateLimit(limiter *rate.Limiter) ldap_redhat.Interceptor {
+//		return func(next ldap_redhat.SearchFunc) ldap_redhat.SearchFunc {
+//			return func(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+//				if err := limiter.Wait(ctx); err != nil {
+//					return nil, err
+//				}
+//				return next(ctx, req)
+//			}
+//		}
+//	}
+//
+// Paged searches, those of ForEachUser and the methods built on it, pass
+// through interceptors a page at a time: req carries the paging control, and
+// the page's cookie is in the result's controls, so interceptors caching
+// results must key them on the controls as well as the filter. Sync, whose
+// persistent search streams changes until it is cancelled rather than
+// returning a result, does not pass through them.
+//
+// Interceptors may modify req before calling next. They must not modify a
+// result returned by next that they also keep, e.g. in a cache, because the
+// searcher reads it after they return.
+type Interceptor func(next SearchFunc) SearchFunc
+
+// ChainInterceptors returns a single interceptor running interceptors in
+// order: the first is outermost and sees each search first and its result
+// last. It is how Config.Interceptors are applied.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	interceptors = append([]Interceptor(nil), interceptors...)
+	return func(next SearchFunc) SearchFunc {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			next = interceptors[i](next)
+		}
+		return next
+	}
+}
+
+// search runs req through Config.Interceptors, ending in searchLDAP
+func (s *Searcher) search(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	return s.interceptedSearch()(ctx, req)
+}
+
+// interceptedSearch returns searchLDAP wrapped in Config.Interceptors. The
+// chain is built once for the configured interceptors, and again only when
+// Reload replaces them.
+func (s *Searcher) interceptedSearch() SearchFunc {
+	s.mu.RLock()
+	interceptors, chain, built := s.Config.Interceptors, s.chain, s.chainFor
+	s.mu.RUnlock()
+	if len(interceptors) == 0 {
+		return s.searchLDAP
+	}
+	if chain != nil && sameInterceptors(built, interceptors) {
+		return chain
+	}
+	chain = ChainInterceptors(interceptors...)(s.searchLDAP)
+	s.mu.Lock()
+	s.chain, s.chainFor = chain, interceptors
+	s.mu.Unlock()
+	return chain
+}
+
+// sameInterceptors reports whether a and b are the same slice. Functions
+// cannot be compared, but a Config keeps its slice until it is replaced.
+func sameInterceptors(a, b []Interceptor) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// validateInterceptors rejects nil interceptors, which would only fail at
+// the first search
+func validateInterceptors(interceptors []Interceptor) error {
+	for i, interceptor := range interceptors {
+		if interceptor == nil {
+			return newError(ErrInvalidConfig, "interceptor %d is nil", i)
+		}
+	}
+	return nil
+}
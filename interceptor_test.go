@@ -0,0 +1,226 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// tracing returns an interceptor appending name's entry and exit to calls
+func tracing(name string, calls *[]string) ldap_redhat.Interceptor {
+	return func(next ldap_redhat.SearchFunc) ldap_redhat.SearchFunc {
+		return func(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			*calls = append(*calls, name+" "+req.Filter)
+			result, err := next(ctx, req)
+			*calls = append(*calls, name+" done")
+			return result, err
+		}
+	}
+}
+
+func TestInterceptorsOrder(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	var calls []string
+	searcher, err := ldap_redhat.NewSearcherWithOptions(context.Background(),
+		ldap_redhat.WithServers(srv.URL()),
+		ldap_redhat.WithBind(embeddedBindDN, embeddedPassword),
+		ldap_redhat.WithBaseDN(testserver.UsersBaseDN),
+		ldap_redhat.WithInterceptors(tracing("outer", &calls)),
+		ldap_redhat.WithInterceptors(tracing("inner", &calls)),
+	)
+	if err != nil {
+		t.Fatalf("NewSearcherWithOptions failed: %v", err)
+	}
+	defer searcher.Close()
+
+	uid := testserver.UserUID(2)
+	if _, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	filter := "(uid=" + uid + ")"
+	want := []string{"outer " + filter, "inner " + filter, "inner done", "outer done"}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected calls %q, got %q", want, calls)
+	}
+}
+
+func TestInterceptorShortCircuits(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	var (
+		mu      sync.Mutex
+		cache   = map[string]*ldap.SearchResult{}
+		misses  int
+		limited bool
+		refused = errors.New("rate limited")
+	)
+	caching := func(next ldap_redhat.SearchFunc) ldap_redhat.SearchFunc {
+		return func(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			mu.Lock()
+			cached, ok := cache[req.Filter]
+			mu.Unlock()
+			if ok {
+				return cached, nil
+			}
+			result, err := next(ctx, req)
+			if err == nil {
+				mu.Lock()
+				cache[req.Filter] = result
+				misses++
+				mu.Unlock()
+			}
+			return result, err
+		}
+	}
+	limiting := func(next ldap_redhat.SearchFunc) ldap_redhat.SearchFunc {
+		return func(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			mu.Lock()
+			refuse := limited
+			mu.Unlock()
+			if refuse {
+				return nil, refused
+			}
+			return next(ctx, req)
+		}
+	}
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:  []string{srv.URL()},
+		Username:     embeddedBindDN,
+		Password:     embeddedPassword,
+		BaseDN:       testserver.UsersBaseDN,
+		Interceptors: []ldap_redhat.Interceptor{limiting, caching},
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+	for i := 0; i < 3; i++ {
+		user, err := searcher.GetUser(ctx, id)
+		if err != nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+		if user.UID != id.Value {
+			t.Errorf("Expected %s, got %s", id.Value, user.UID)
+		}
+	}
+	if misses != 1 {
+		t.Errorf("Expected one search to reach the directory, got %d", misses)
+	}
+
+	mu.Lock()
+	limited = true
+	mu.Unlock()
+	if _, err := searcher.GetUser(ctx, id); !errors.Is(err, refused) {
+		t.Errorf("Expected the interceptor's error, got %v", err)
+	}
+	if report, err := ldap_redhat.Healthz(ctx, searcher); err != nil || !report.Healthy() {
+		t.Errorf("Expected the health check to bypass interceptors, got %+v, %v", report, err)
+	}
+}
+
+func TestInterceptorsNil(t *testing.T) {
+	_, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:  []string{"ldap://ldap.example.com"},
+		LazyConnect:  true,
+		Interceptors: []ldap_redhat.Interceptor{nil},
+	})
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for a nil interceptor, got %v", err)
+	}
+}
+
+func TestInterceptorsPagedSearches(t *testing.T) {
+	srv := startEmbeddedServer(t, 1200)
+	var (
+		mu    sync.Mutex
+		pages int
+	)
+	counting := func(next ldap_redhat.SearchFunc) ldap_redhat.SearchFunc {
+		return func(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			if ldap.FindControl(req.Controls, ldap.ControlTypePaging) != nil {
+				mu.Lock()
+				pages++
+				mu.Unlock()
+			}
+			return next(ctx, req)
+		}
+	}
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:  []string{srv.URL()},
+		Username:     embeddedBindDN,
+		Password:     embeddedPassword,
+		BaseDN:       testserver.UsersBaseDN,
+		Interceptors: []ldap_redhat.Interceptor{counting},
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+
+	users, err := searcher.SearchUsers(context.Background(), "")
+	if err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	if len(users) != 1200 || users[0].UID == "" {
+		t.Errorf("Expected 1200 users, got %d", len(users))
+	}
+	if pages != 3 {
+		t.Errorf("Expected each of 3 pages to pass through the interceptor, got %d", pages)
+	}
+
+	stop := errors.New("stop")
+	err = searcher.ForEachUser(context.Background(), "(uid=*)", func(ldap_redhat.UserRecord) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected fn's error, got %v", err)
+	}
+}
+
+func TestInterceptorsChainBuiltOnce(t *testing.T) {
+	srv := startEmbeddedServer(t, 3)
+	var builds int
+	building := func(next ldap_redhat.SearchFunc) ldap_redhat.SearchFunc {
+		builds++
+		return next
+	}
+	config := ldap_redhat.Config{
+		LdapServers:  []string{srv.URL()},
+		Username:     embeddedBindDN,
+		Password:     embeddedPassword,
+		BaseDN:       testserver.UsersBaseDN,
+		Interceptors: []ldap_redhat.Interceptor{building},
+	}
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}); err != nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+	}
+	if builds != 1 {
+		t.Errorf("Expected the chain to be built once, got %d builds", builds)
+	}
+
+	config.Interceptors = []ldap_redhat.Interceptor{building}
+	if err := searcher.Reload(ctx, config); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if builds != 2 {
+		t.Errorf("Expected Reload to rebuild the chain, got %d builds", builds)
+	}
+}
@@ -0,0 +1,33 @@
+// Package buildfeature records the optional packages linked into a binary,
+// which ldap_redhat.BuildInfo reports as its features. Each such package
+// registers itself from init, so only binaries that import it list it.
+package buildfeature
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	mu    sync.Mutex
+	names = map[string]bool{}
+)
+
+// Register records the feature name, such as "vault" or "grpc".
+func Register(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	names[name] = true
+}
+
+// Names returns the registered features, sorted.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]string, 0, len(names))
+	for name := range names {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
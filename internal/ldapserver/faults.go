@@ -0,0 +1,152 @@
+package ldapserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Operation names the LDAP operation a Fault applies to.
+type Operation string
+
+const (
+	OpBind   Operation = "bind"
+	OpSearch Operation = "search"
+)
+
+// Fault is a failure the server injects into an operation. Delay is applied
+// first; then the connection is dropped, or the operation answered with
+// Code, or, for a zero Code, served normally.
+type Fault struct {
+	Code      uint16        // LDAP result code to answer with, e.g. ldap.LDAPResultBusy
+	Message   string        // diagnostic message sent with Code
+	Referrals []string      // referral URLs sent with ldap.LDAPResultReferral
+	Entries   int           // search entries sent before Code, e.g. up to a size limit
+	Delay     time.Duration // wait before answering, e.g. to exceed a client timeout
+	Drop      bool          // close the connection instead of answering
+	Times     int           // operations to fail before the fault clears; 0 = until ClearFaults
+}
+
+// InjectFault makes the following op requests fail with f, replacing any
+// fault already set for op.
+func (s *Server) InjectFault(op Operation, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[op] = &f
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.faults)
+}
+
+// fault returns the fault to apply to the next op request, if any, and
+// counts it against Times
+func (s *Server) fault(op Operation) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.faults[op]
+	if !ok {
+		return Fault{}, false
+	}
+	if f.Times > 0 {
+		f.Times--
+		if f.Times == 0 {
+			delete(s.faults, op)
+		}
+	}
+	return *f, true
+}
+
+// sleep waits for d, returning false if the server is closed first
+func (s *Server) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.closing:
+		return false
+	}
+}
+
+// appendReferrals adds the optional referral field of an LDAPResult
+func appendReferrals(body *ber.Packet, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	referral := ber.Encode(ber.ClassContext, ber.TypeConstructed, 3, nil, "Referral")
+	for _, u := range urls {
+		referral.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, u, "URI"))
+	}
+	body.AppendChild(referral)
+}
+
+// ParseFaults reads faults from a comma-separated list of op:kind[=value]
+// items, as set in LDAP_TEST_FAULTS to run a test suite against a failing
+// server. Items for the same operation are combined. Kinds:
+//
+//	invalid-credentials, busy, unavailable, timelimit   answer with that result code
+//	sizelimit=N      send N entries, then sizeLimitExceeded
+//	referral=URL     answer with a referral to URL
+//	delay=DURATION   wait before answering
+//	drop             close the connection
+//
+// For example "bind:delay=200ms,search:sizelimit=5".
+func ParseFaults(spec string) (map[Operation]Fault, error) {
+	faults := map[Operation]Fault{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		op, kind, ok := strings.Cut(item, ":")
+		if !ok || (Operation(op) != OpBind && Operation(op) != OpSearch) {
+			return nil, fmt.Errorf("invalid fault %q: expected bind:<kind> or search:<kind>", item)
+		}
+		kind, value, _ := strings.Cut(kind, "=")
+		f := faults[Operation(op)]
+		switch kind {
+		case "invalid-credentials":
+			f.Code = ldap.LDAPResultInvalidCredentials
+		case "busy":
+			f.Code = ldap.LDAPResultBusy
+		case "unavailable":
+			f.Code = ldap.LDAPResultUnavailable
+		case "timelimit":
+			f.Code = ldap.LDAPResultTimeLimitExceeded
+		case "sizelimit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid fault %q: sizelimit needs an entry count", item)
+			}
+			f.Code, f.Entries = ldap.LDAPResultSizeLimitExceeded, n
+		case "referral":
+			if value == "" {
+				return nil, fmt.Errorf("invalid fault %q: referral needs a URL", item)
+			}
+			f.Code, f.Referrals = ldap.LDAPResultReferral, []string{value}
+		case "delay":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fault %q: %w", item, err)
+			}
+			f.Delay = d
+		case "drop":
+			f.Drop = true
+		default:
+			return nil, fmt.Errorf("invalid fault %q: unknown kind %q", item, kind)
+		}
+		faults[Operation(op)] = f
+	}
+	return faults, nil
+}
@@ -0,0 +1,77 @@
+package ldapserver_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ldapserver.ParseFaults("bind:delay=50ms, bind:busy,search:sizelimit=5,")
+	if err != nil {
+		t.Fatalf("ParseFaults failed: %v", err)
+	}
+	if f := faults[ldapserver.OpBind]; f.Delay != 50*time.Millisecond || f.Code != ldap.LDAPResultBusy {
+		t.Errorf("Unexpected bind fault %+v", f)
+	}
+	if f := faults[ldapserver.OpSearch]; f.Code != ldap.LDAPResultSizeLimitExceeded || f.Entries != 5 {
+		t.Errorf("Unexpected search fault %+v", f)
+	}
+	if faults, err := ldapserver.ParseFaults(""); err != nil || len(faults) != 0 {
+		t.Errorf("Expected no faults for an empty spec, got %v, %v", faults, err)
+	}
+	for _, spec := range []string{"modify:drop", "search", "search:sizelimit=x", "search:referral", "bind:delay=soon", "bind:explode"} {
+		if _, err := ldapserver.ParseFaults(spec); err == nil {
+			t.Errorf("ParseFaults(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestInjectFault(t *testing.T) {
+	srv := startServer(t, 10)
+
+	conn, err := ldap.DialURL(srv.URL())
+	if err != nil {
+		t.Fatalf("DialURL failed: %v", err)
+	}
+	defer conn.Close()
+
+	srv.InjectFault(ldapserver.OpBind, ldapserver.Fault{Code: ldap.LDAPResultUnavailable, Times: 1})
+	if err := conn.Bind("uid=svc,ou=users,dc=redhat,dc=com", "secret"); !ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailable) {
+		t.Errorf("Expected unavailable, got %v", err)
+	}
+	if err := conn.Bind("uid=svc,ou=users,dc=redhat,dc=com", "secret"); err != nil {
+		t.Fatalf("Expected the fault to clear after one bind, got %v", err)
+	}
+
+	search := ldap.NewSearchRequest(
+		testserver.UsersBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(uid=*)", []string{"uid"}, nil,
+	)
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultSizeLimitExceeded, Entries: 4})
+	res, err := conn.Search(search)
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		t.Errorf("Expected size limit exceeded, got %v", err)
+	}
+	if res == nil || len(res.Entries) != 4 {
+		t.Errorf("Expected 4 partial entries, got %v", res)
+	}
+
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultReferral, Referrals: []string{"ldap://replica.example.com"}})
+	if _, err := conn.Search(search); !ldap.IsErrorWithCode(err, ldap.LDAPResultReferral) {
+		t.Errorf("Expected a referral, got %v", err)
+	}
+
+	srv.ClearFaults()
+	if res, err := conn.Search(search); err != nil || len(res.Entries) != 10 {
+		t.Errorf("Expected a normal search after ClearFaults, got %v", err)
+	}
+
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Drop: true})
+	if _, err := conn.Search(search); err == nil {
+		t.Errorf("Expected an error when the connection is dropped")
+	}
+}
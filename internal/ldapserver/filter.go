@@ -0,0 +1,179 @@
+package ldapserver
+
+import (
+	"sort"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// MatchFilter reports whether an entry with attrs matches the RFC 4515
+// filter string, as a search of this server would.
+func MatchFilter(attrs map[string][]string, filter string) (bool, error) {
+	f, err := ldap.CompileFilter(filter)
+	if err != nil {
+		return false, err
+	}
+	return matchFilter(&Entry{Attrs: attrs}, f), nil
+}
+
+// matchFilter evaluates an encoded RFC 4511 filter against an entry.
+// Unsupported filter types (extensible match) never match.
+func matchFilter(e *Entry, f *ber.Packet) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, child := range f.Children {
+			if !matchFilter(e, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range f.Children {
+			if matchFilter(e, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(f.Children) == 1 && !matchFilter(e, f.Children[0])
+	case ldap.FilterPresent:
+		attr := f.Data.String()
+		if strings.EqualFold(attr, "objectClass") {
+			return true
+		}
+		return len(e.Get(attr)) > 0
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+		attr, value, ok := assertion(f)
+		if !ok {
+			return false
+		}
+		key := valueKey(attr, value)
+		for _, v := range e.Get(attr) {
+			if valueKey(attr, v) == key {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		attr, value, ok := assertion(f)
+		if !ok {
+			return false
+		}
+		value = strings.ToLower(value)
+		for _, v := range e.Get(attr) {
+			v = strings.ToLower(v)
+			if f.Tag == ldap.FilterGreaterOrEqual && v >= value {
+				return true
+			}
+			if f.Tag == ldap.FilterLessOrEqual && v <= value {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		if len(f.Children) != 2 {
+			return false
+		}
+		attr, _ := f.Children[0].Value.(string)
+		for _, v := range e.Get(attr) {
+			if matchSubstrings(strings.ToLower(v), f.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// dnAttributes are the attributes with DN syntax, whose values match
+// regardless of case and of whitespace around separators
+var dnAttributes = map[string]bool{
+	"manager": true, "member": true, "uniquemember": true, "owner": true, "seealso": true, "secretary": true,
+}
+
+// valueKey returns the form in which values of attr are compared for
+// equality and indexed
+func valueKey(attr, value string) string {
+	if dnAttributes[strings.ToLower(attr)] {
+		return normalizeDN(value)
+	}
+	return strings.ToLower(value)
+}
+
+func assertion(f *ber.Packet) (attr, value string, ok bool) {
+	if len(f.Children) != 2 {
+		return "", "", false
+	}
+	attr, ok1 := f.Children[0].Value.(string)
+	value, ok2 := f.Children[1].Value.(string)
+	return attr, value, ok1 && ok2
+}
+
+func matchSubstrings(v string, parts []*ber.Packet) bool {
+	for _, p := range parts {
+		sub := strings.ToLower(p.Data.String())
+		switch p.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(v, sub) {
+				return false
+			}
+			v = v[len(sub):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(v, sub)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(sub):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(v, sub) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// candidates narrows the entries a filter can match using the equality index.
+// It returns false when the filter cannot be answered from the index and a
+// full scan is required. Candidates are returned in insertion order.
+func (s *Server) candidates(f *ber.Packet) ([]*Entry, bool) {
+	switch f.Tag {
+	case ldap.FilterEqualityMatch:
+		attr, value, ok := assertion(f)
+		if !ok {
+			return nil, false
+		}
+		byValue, ok := s.index[strings.ToLower(attr)]
+		if !ok {
+			return nil, false
+		}
+		return byValue[valueKey(attr, value)], true
+	case ldap.FilterAnd:
+		for _, child := range f.Children {
+			if c, ok := s.candidates(child); ok {
+				return c, true
+			}
+		}
+		return nil, false
+	case ldap.FilterOr:
+		seen := map[*Entry]bool{}
+		var out []*Entry
+		for _, child := range f.Children {
+			c, ok := s.candidates(child)
+			if !ok {
+				return nil, false
+			}
+			for _, e := range c {
+				if !seen[e] {
+					seen[e] = true
+					out = append(out, e)
+				}
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].seq < out[j].seq })
+		return out, true
+	}
+	return nil, false
+}
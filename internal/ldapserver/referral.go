@@ -0,0 +1,106 @@
+package ldapserver
+
+import (
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// hasObjectClass reports whether e has the object class, case-insensitively
+func (e *Entry) hasObjectClass(class string) bool {
+	for _, c := range e.Get("objectClass") {
+		if strings.EqualFold(c, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// referralAbove returns the referral object at or above the normalized dn.
+// Callers hold s.mu.
+func (s *Server) referralAbove(dn string) *Entry {
+	for _, r := range s.referrals {
+		if inScope(dn, r.norm, ldap.ScopeWholeSubtree) {
+			return r
+		}
+	}
+	return nil
+}
+
+// derefBase returns the normalized DN an alias base object points to, or
+// base itself. Callers hold s.mu.
+func (s *Server) derefBase(base string) string {
+	if e := s.byDN[base]; e != nil && e.hasObjectClass("alias") {
+		if target := e.Get("aliasedObjectName"); len(target) > 0 {
+			return normalizeDN(target[0])
+		}
+	}
+	return base
+}
+
+// resolveScope applies referral objects and aliases to the entries matching
+// a search: entries held by another server are replaced by references to it,
+// and with deref set, aliases in scope by the entries they point to when
+// those match filter. Callers hold s.mu.
+func (s *Server) resolveScope(matches []*Entry, base string, scope int, filter *ber.Packet, deref int64, manageDsaIT bool) ([]*Entry, []string) {
+	var refs []string
+	if !manageDsaIT {
+		kept := matches[:0:0]
+		for _, e := range matches {
+			if r := s.referralAbove(e.norm); r == nil || r.norm == base {
+				kept = append(kept, e)
+			}
+		}
+		matches = kept
+		for _, r := range s.referrals {
+			if r.norm != base && inScope(r.norm, base, scope) && s.referralAbove(parentDN(r.norm)) == nil {
+				refs = append(refs, r.Get("ref")...)
+			}
+		}
+	}
+	if deref != ldap.DerefInSearching && deref != ldap.DerefAlways {
+		return matches, refs
+	}
+	seen := map[*Entry]bool{}
+	var out []*Entry
+	add := func(e *Entry) {
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	for _, e := range matches {
+		if !e.hasObjectClass("alias") {
+			add(e)
+		}
+	}
+	for _, a := range s.aliases {
+		if !inScope(a.norm, base, scope) {
+			continue
+		}
+		for _, target := range a.Get("aliasedObjectName") {
+			if e := s.byDN[normalizeDN(target)]; e != nil && matchFilter(e, filter) {
+				add(e)
+			}
+		}
+	}
+	return out, refs
+}
+
+// parentDN returns the normalized DN above dn
+func parentDN(dn string) string {
+	if i := strings.Index(dn, ","); i >= 0 {
+		return dn[i+1:]
+	}
+	return ""
+}
+
+// encodeReference encodes a search result reference to urls
+func encodeReference(msgID int64, urls ...string) *ber.Packet {
+	op := newOp(ldap.ApplicationSearchResultReference, "Search Result Reference")
+	for _, u := range urls {
+		op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, u, "URI"))
+	}
+	return envelope(msgID, op)
+}
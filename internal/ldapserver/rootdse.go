@@ -0,0 +1,56 @@
+package ldapserver
+
+import (
+	"slices"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// supportedControls are the controls the root DSE lists, less those
+// disabled with DisableControl
+var supportedControls = []string{
+	ldap.ControlTypePaging,
+	ldap.ControlTypeServerSideSorting,
+	VLVRequestOID,
+	ldap.ControlTypeManageDsaIT,
+	ldap.ControlTypeSyncRequest,
+	PersistentSearchOID,
+	SessionTrackingOID,
+}
+
+// operationalEntry returns the root DSE or the subschema subentry, which only
+// base searches of their normalized DN read, or nil for other bases. The
+// caller holds s.mu.
+func (s *Server) operationalEntry(base string) *Entry {
+	var e *Entry
+	switch base {
+	case "":
+		var controls []string
+		for _, oid := range supportedControls {
+			if !s.disabledControls[oid] {
+				controls = append(controls, oid)
+			}
+		}
+		e = &Entry{DN: "", Attrs: map[string][]string{
+			"objectClass":             {"top"},
+			"namingContexts":          {"dc=redhat,dc=com"},
+			"subschemaSubentry":       {SubschemaDN},
+			"supportedLDAPVersion":    {"3"},
+			"supportedControl":        controls,
+			"supportedExtension":      {ldap.ControlTypeWhoAmI},
+			"supportedSASLMechanisms": {"EXTERNAL"},
+			"vendorName":              {"go-ldap-redhat ldapserver"},
+		}}
+	case normalizeDN(SubschemaDN):
+		e = &Entry{DN: SubschemaDN, Attrs: map[string][]string{
+			"objectClass":    {"top", "subentry", "subschema"},
+			"cn":             {"Subschema"},
+			"attributeTypes": s.attributeTypes,
+			"objectClasses":  append(slices.Clone(standardObjectClasses), SchemaObjectClasses...),
+		}}
+	default:
+		return nil
+	}
+	e.prepare(0)
+	return e
+}
@@ -0,0 +1,715 @@
+// Package ldapserver implements a minimal in-process LDAP server, which
+// serves the fixtures of offline searchers and backs the benchmarks and
+// integration tests. It understands just enough of RFC 4511
+// (simple bind, SASL EXTERNAL over a Unix socket, search with the common
+// filter types, the paged results control, aliases and referral objects,
+// content synchronization and persistent search, the root DSE and subschema
+// subentry, unbind) to exercise the go-ldap client the library is built on.
+package ldapserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// indexedAttributes are the attributes with an equality index, chosen to
+// keep identifier and manager lookups fast on large fixture directories.
+var indexedAttributes = []string{"uid", "mail", "manager", "rhatuuid", "employeenumber", "krbprincipalname"}
+
+// Entry is a directory entry served by the server.
+type Entry struct {
+	DN    string
+	Attrs map[string][]string
+
+	seq   int
+	norm  string
+	lower map[string][]string
+}
+
+// Get returns the values of attr, matched case-insensitively.
+func (e *Entry) Get(attr string) []string {
+	if e.lower != nil {
+		return e.lower[strings.ToLower(attr)]
+	}
+	for name, v := range e.Attrs {
+		if strings.EqualFold(name, attr) {
+			return v
+		}
+	}
+	return nil
+}
+
+// prepare caches the normalized DN and lowercased attribute names.
+func (e *Entry) prepare(seq int) {
+	e.seq = seq
+	e.norm = normalizeDN(e.DN)
+	e.lower = make(map[string][]string, len(e.Attrs))
+	for name, v := range e.Attrs {
+		e.lower[strings.ToLower(name)] = v
+	}
+}
+
+// Server is an in-memory LDAP server listening on a loopback port.
+type Server struct {
+	mu        sync.RWMutex
+	entries   []*Entry
+	index     map[string]map[string][]*Entry
+	byDN      map[string]*Entry // by normalized DN
+	aliases   []*Entry          // objectClass alias
+	referrals []*Entry          // objectClass referral, with ref URLs
+	binds     map[string]string
+	faults    map[Operation]*Fault
+	sessions  map[string]bool // session tracking identifiers seen
+
+	attributeTypes   []string        // served by the subschema subentry
+	disabledControls map[string]bool // critical controls rejected, see DisableControl
+	subscribers      []*subscriber   // searches notified of changes
+	csn              int             // changes made, the sync cookie
+
+	ln        net.Listener
+	scheme    string
+	wg        sync.WaitGroup
+	conns     map[net.Conn]struct{}
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// New returns an empty server. Call Start to begin accepting connections.
+func New() *Server {
+	index := map[string]map[string][]*Entry{}
+	for _, attr := range indexedAttributes {
+		index[attr] = map[string][]*Entry{}
+	}
+	return &Server{
+		index:    index,
+		byDN:     map[string]*Entry{},
+		binds:    map[string]string{},
+		faults:   map[Operation]*Fault{},
+		sessions: map[string]bool{},
+		conns:    map[net.Conn]struct{}{},
+		closing:  make(chan struct{}),
+
+		attributeTypes:   append(slices.Clone(standardAttributeTypes), SchemaAttributeTypes...),
+		disabledControls: map[string]bool{},
+	}
+}
+
+// AddEntry adds an entry to the directory.
+func (s *Server) AddEntry(dn string, attrs map[string][]string) {
+	s.AddEntries([]*Entry{{DN: dn, Attrs: attrs}})
+}
+
+// AddEntries adds several entries to the directory.
+func (s *Server) AddEntries(entries []*Entry) {
+	s.addEntries(entries)
+	for _, e := range entries {
+		s.notify(nil, e)
+	}
+}
+
+func (s *Server) addEntries(entries []*Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		e.prepare(len(s.entries))
+		s.entries = append(s.entries, e)
+		s.byDN[e.norm] = e
+		if e.hasObjectClass("alias") {
+			s.aliases = append(s.aliases, e)
+		}
+		if e.hasObjectClass("referral") {
+			s.referrals = append(s.referrals, e)
+		}
+		for attr, byValue := range s.index {
+			for _, v := range e.lower[attr] {
+				key := valueKey(attr, v)
+				byValue[key] = append(byValue[key], e)
+			}
+		}
+	}
+}
+
+// SetAttribute replaces the values of attr on the entry dn, removing the
+// attribute when no values are given, and reports whether the entry exists.
+// The entry is replaced rather than modified, so searches in flight keep
+// serving the old values.
+func (s *Server) SetAttribute(dn, attr string, values ...string) bool {
+	old, e := s.setAttribute(dn, attr, values)
+	if e == nil {
+		return false
+	}
+	s.notify(old, e)
+	return true
+}
+
+// setAttribute replaces the entry dn and returns it before and after, or
+// nils if it does not exist
+func (s *Server) setAttribute(dn, attr string, values []string) (*Entry, *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.byDN[normalizeDN(dn)]
+	if !ok {
+		return nil, nil
+	}
+	attrs := make(map[string][]string, len(old.Attrs)+1)
+	for name, v := range old.Attrs {
+		if !strings.EqualFold(name, attr) {
+			attrs[name] = v
+		}
+	}
+	if len(values) > 0 {
+		attrs[attr] = values
+	}
+	e := &Entry{DN: old.DN, Attrs: attrs}
+	e.prepare(old.seq)
+
+	s.entries[e.seq] = e
+	s.byDN[e.norm] = e
+	replace := func(list []*Entry) []*Entry {
+		list = slices.Clone(list)
+		for i, x := range list {
+			if x == old {
+				list[i] = e
+			}
+		}
+		return list
+	}
+	s.aliases = replace(s.aliases)
+	s.referrals = replace(s.referrals)
+	for attr, byValue := range s.index {
+		for _, v := range old.lower[attr] {
+			key := valueKey(attr, v)
+			byValue[key] = slices.DeleteFunc(slices.Clone(byValue[key]), func(x *Entry) bool { return x == old })
+		}
+		for _, v := range e.lower[attr] {
+			key := valueKey(attr, v)
+			byValue[key] = append(slices.Clip(byValue[key]), e)
+		}
+	}
+	return old, e
+}
+
+// AddBind registers a DN/password pair accepted by simple bind.
+func (s *Server) AddBind(dn, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binds[normalizeDN(dn)] = password
+}
+
+// Start listens on a random loopback port and serves connections in the background.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.serveListener(ln, "ldap")
+	return nil
+}
+
+// StartLDAPS is like Start but serves LDAP over TLS (ldaps://) using config.
+func (s *Server) StartLDAPS(config *tls.Config) error {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.serveListener(ln, "ldaps")
+	return nil
+}
+
+// StartUnix is like Start but listens on a Unix domain socket at path and
+// serves ldapi:// URLs. Clients on the socket may bind with SASL EXTERNAL.
+func (s *Server) StartUnix(path string) error {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.serveListener(ln, "ldapi")
+	return nil
+}
+
+func (s *Server) serveListener(ln net.Listener, scheme string) {
+	s.ln = ln
+	s.scheme = scheme
+	s.wg.Add(1)
+	go s.acceptLoop()
+}
+
+// URL returns the ldap://, ldaps:// or ldapi:// URL of the running server.
+// The socket path of an ldapi:// URL is percent-encoded in the host part.
+func (s *Server) URL() string {
+	if s.scheme == "ldapi" {
+		return "ldapi://" + strings.ReplaceAll(url.PathEscape(s.ln.Addr().String()), "/", "%2F")
+	}
+	return s.scheme + "://" + s.ln.Addr().String()
+}
+
+// DropConnections closes every open client connection while keeping the
+// listener running, simulating a server-side idle timeout.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Close stops the listener and closes every open connection.
+func (s *Server) Close() error {
+	if s.ln == nil {
+		return nil
+	}
+	s.closeOnce.Do(func() { close(s.closing) })
+	err := s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		s.unsubscribe(c)
+		c.Close()
+	}()
+
+	var wmu sync.Mutex
+	authzID := "" // identity bound on c, answered to WhoAmI
+	write := func(p *ber.Packet) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_, err := c.Write(p.Bytes())
+		return err
+	}
+
+	for {
+		packet, err := ber.ReadPacket(c)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		msgID, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		op := packet.Children[1]
+		var controls []ldap.Control
+		if len(packet.Children) > 2 {
+			for _, child := range packet.Children[2].Children {
+				if ctrl, err := decodeControl(child); err == nil {
+					controls = append(controls, ctrl)
+				}
+			}
+			s.recordSession(controls)
+		}
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			f, faulty := s.fault(OpBind)
+			if faulty && (!s.sleep(f.Delay) || f.Drop) {
+				return
+			}
+			if faulty && f.Code != 0 {
+				resp := newOp(ldap.ApplicationBindResponse, "Bind Response")
+				appendResult(resp, f.Code, "", f.Message)
+				appendReferrals(resp, f.Referrals)
+				authzID = ""
+				err = write(envelope(msgID, resp))
+			} else {
+				_, local := c.(*net.UnixConn)
+				var resp *ber.Packet
+				resp, authzID = s.handleBind(msgID, op, local)
+				err = write(resp)
+			}
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationSearchRequest:
+			f, faulty := s.fault(OpSearch)
+			if faulty && (!s.sleep(f.Delay) || f.Drop) {
+				return
+			}
+			if !faulty || f.Code == 0 {
+				f = Fault{}
+			}
+			switch ctrl := notificationControl(controls); {
+			case s.rejectsControl(controls):
+				err = write(envelope(msgID, searchDone(ldap.LDAPResultUnavailableCriticalExtension, "unsupported critical control")))
+			case ctrl != nil && f.Code == 0:
+				err = s.subscribe(c, msgID, op, ctrl, write)
+			default:
+				err = s.handleSearch(msgID, op, controls, f, write)
+			}
+		case ldap.ApplicationAbandonRequest:
+			continue
+		case ldap.ApplicationExtendedRequest:
+			resp := newOp(ldap.ApplicationExtendedResponse, "Extended Response")
+			if len(op.Children) > 0 && op.Children[0].Data.String() == ldap.ControlTypeWhoAmI {
+				appendResult(resp, ldap.LDAPResultSuccess, "", "")
+				resp.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 11, authzID, "Response Value"))
+			} else {
+				appendResult(resp, ldap.LDAPResultProtocolError, "", "unsupported extended operation")
+			}
+			err = write(envelope(msgID, resp))
+		default:
+			op := newOp(ldap.ApplicationExtendedResponse, "Extended Response")
+			appendResult(op, ldap.LDAPResultUnwillingToPerform, "", "unsupported operation")
+			err = write(envelope(msgID, op))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// PeerCredAuthzID is the identity WhoAmI reports after a SASL EXTERNAL bind
+// on a Unix socket, as OpenLDAP reports the peer's credentials.
+const PeerCredAuthzID = "dn:cn=peercred,cn=external,cn=auth"
+
+// SessionTrackingOID is the session tracking control (draft-wahl-ldap-session)
+// whose identifiers SessionIDs reports.
+const SessionTrackingOID = "1.3.6.1.4.1.21008.108.63.1"
+
+// SessionIDs returns the distinct session tracking identifiers sent with
+// binds and searches so far, sorted.
+func (s *Server) SessionIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// recordSession records the identifier of a session tracking control in
+// controls. Its value is SEQUENCE { sourceIp, sourceName, formatOID,
+// identifier }.
+func (s *Server) recordSession(controls []ldap.Control) {
+	ctrl, ok := ldap.FindControl(controls, SessionTrackingOID).(*ldap.ControlString)
+	if !ok {
+		return
+	}
+	value, err := ber.DecodePacketErr([]byte(ctrl.ControlValue))
+	if err != nil || len(value.Children) != 4 {
+		return
+	}
+	id := value.Children[3].Data.String()
+	s.mu.RLock()
+	seen := s.sessions[id]
+	s.mu.RUnlock()
+	if !seen {
+		s.mu.Lock()
+		s.sessions[id] = true
+		s.mu.Unlock()
+	}
+}
+
+// handleBind answers a simple or SASL bind. SASL EXTERNAL succeeds on local
+// (Unix socket) connections, where the peer is identified by the socket. It
+// also returns the authorization identity the connection is then bound as,
+// "dn:<bind DN>", or "" if the bind failed or was anonymous.
+func (s *Server) handleBind(msgID int64, op *ber.Packet, local bool) (*ber.Packet, string) {
+	resp := newOp(ldap.ApplicationBindResponse, "Bind Response")
+	if len(op.Children) < 3 {
+		appendResult(resp, ldap.LDAPResultProtocolError, "", "malformed bind request")
+		return envelope(msgID, resp), ""
+	}
+	if auth := op.Children[2]; auth.ClassType == ber.ClassContext && auth.Tag == 3 {
+		mechanism := ""
+		if len(auth.Children) > 0 {
+			mechanism = auth.Children[0].Data.String()
+		}
+		switch {
+		case mechanism != "EXTERNAL":
+			appendResult(resp, ldap.LDAPResultAuthMethodNotSupported, "", "unsupported SASL mechanism "+mechanism)
+		case !local:
+			appendResult(resp, ldap.LDAPResultInappropriateAuthentication, "", "EXTERNAL requires a local connection")
+		default:
+			appendResult(resp, ldap.LDAPResultSuccess, "", "")
+			return envelope(msgID, resp), PeerCredAuthzID
+		}
+		return envelope(msgID, resp), ""
+	}
+	name, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+	if name == "" && password == "" {
+		appendResult(resp, ldap.LDAPResultSuccess, "", "")
+		return envelope(msgID, resp), ""
+	}
+
+	s.mu.RLock()
+	want, ok := s.binds[normalizeDN(name)]
+	s.mu.RUnlock()
+	if !ok || want != password {
+		appendResult(resp, ldap.LDAPResultInvalidCredentials, "", "invalid credentials")
+		return envelope(msgID, resp), ""
+	}
+	appendResult(resp, ldap.LDAPResultSuccess, "", "")
+	return envelope(msgID, resp), "dn:" + name
+}
+
+// handleSearch answers a search request. A fault with a non-zero Code
+// replaces the result after at most fault.Entries entries.
+func (s *Server) handleSearch(msgID int64, op *ber.Packet, controls []ldap.Control, fault Fault, write func(*ber.Packet) error) error {
+	req, ok := parseSearch(op)
+	if !ok {
+		return write(envelope(msgID, searchDone(ldap.LDAPResultProtocolError, "malformed search request")))
+	}
+	baseDN, scope, deref, sizeLimit, filter, attrs := req.baseDN, req.scope, req.deref, req.sizeLimit, req.filter, req.attrs
+
+	s.mu.RLock()
+	candidates, indexed := s.candidates(filter)
+	if !indexed {
+		candidates = s.entries
+	}
+	normBase := normalizeDN(baseDN)
+	if deref == ldap.DerefFindingBaseObj || deref == ldap.DerefAlways {
+		normBase = s.derefBase(normBase)
+	}
+	manageDsaIT := ldap.FindControl(controls, ldap.ControlTypeManageDsaIT) != nil
+	if r := s.referralAbove(normBase); r != nil && !manageDsaIT {
+		s.mu.RUnlock()
+		done := newOp(ldap.ApplicationSearchResultDone, "Search Result Done")
+		appendResult(done, ldap.LDAPResultReferral, "", "")
+		appendReferrals(done, r.Get("ref"))
+		return write(envelope(msgID, done))
+	}
+	var matches []*Entry
+	for _, e := range candidates {
+		if inScope(e.norm, normBase, int(scope)) && matchFilter(e, filter) {
+			matches = append(matches, e)
+		}
+	}
+	if e := s.operationalEntry(normBase); e != nil && scope == ldap.ScopeBaseObject && matchFilter(e, filter) {
+		matches = append(matches, e)
+	}
+	matches, refs := s.resolveScope(matches, normBase, int(scope), filter, deref, manageDsaIT)
+	s.mu.RUnlock()
+
+	code := uint16(ldap.LDAPResultSuccess)
+	var respControls []ldap.Control
+	sorting, sorted := ldap.FindControl(controls, ldap.ControlTypeServerSideSorting).(*ldap.ControlString)
+	if sorted {
+		sortEntries(matches, parseSortKeys(sorting.ControlValue))
+		respControls = append(respControls, sortResponse())
+	}
+	vlv, _ := ldap.FindControl(controls, VLVRequestOID).(*ldap.ControlString)
+	if fault.Code != 0 {
+		matches = matches[:min(fault.Entries, len(matches))]
+		code = fault.Code
+	} else if ctrl, ok := ldap.FindControl(controls, ldap.ControlTypePaging).(*ldap.ControlPaging); ok {
+		offset, _ := strconv.Atoi(string(ctrl.Cookie))
+		if offset > len(matches) {
+			offset = len(matches)
+		}
+		end := len(matches)
+		if ctrl.PagingSize > 0 && offset+int(ctrl.PagingSize) < end {
+			end = offset + int(ctrl.PagingSize)
+		}
+		if ctrl.PagingSize == 0 {
+			// A zero page size abandons the paged search.
+			end = offset
+		}
+		if sizeLimit > 0 && int64(end) > sizeLimit {
+			// The size limit counts the entries of every page
+			end = max(offset, int(sizeLimit))
+			matches = matches[:end]
+			code = ldap.LDAPResultSizeLimitExceeded
+		}
+		page := ldap.NewControlPaging(ctrl.PagingSize)
+		if end < len(matches) && ctrl.PagingSize > 0 {
+			page.SetCookie([]byte(strconv.Itoa(end)))
+			refs = nil // sent with the last page
+		}
+		respControls = append(respControls, page)
+		matches = matches[offset:end]
+	} else if vlv != nil && !sorted {
+		matches = nil
+		code = vlvSortControlMissing
+		respControls = append(respControls, vlvResponse(0, 0, vlvSortControlMissing))
+	} else if vlv != nil {
+		var ctrl ldap.Control
+		matches, ctrl, code = vlvWindow(matches, vlv.ControlValue)
+		respControls = append(respControls, ctrl)
+	} else if sizeLimit > 0 && int64(len(matches)) > sizeLimit {
+		matches = matches[:sizeLimit]
+		code = ldap.LDAPResultSizeLimitExceeded
+	}
+
+	for _, e := range matches {
+		if err := write(encodeEntry(msgID, e, attrs)); err != nil {
+			return err
+		}
+	}
+	for _, ref := range refs {
+		if err := write(encodeReference(msgID, ref)); err != nil {
+			return err
+		}
+	}
+
+	done := newOp(ldap.ApplicationSearchResultDone, "Search Result Done")
+	appendResult(done, code, "", fault.Message)
+	appendReferrals(done, fault.Referrals)
+	return write(envelope(msgID, done, respControls...))
+}
+
+// searchRequest is the part of a search request the server uses
+type searchRequest struct {
+	baseDN    string
+	base      string // baseDN normalized
+	scope     int
+	deref     int64
+	sizeLimit int64
+	filter    *ber.Packet
+	attrs     []string
+}
+
+// parseSearch returns the search request op, or false if it is malformed
+func parseSearch(op *ber.Packet) (searchRequest, bool) {
+	if len(op.Children) < 8 {
+		return searchRequest{}, false
+	}
+	var req searchRequest
+	req.baseDN, _ = op.Children[0].Value.(string)
+	req.base = normalizeDN(req.baseDN)
+	scope, _ := op.Children[1].Value.(int64)
+	req.scope = int(scope)
+	req.deref, _ = op.Children[2].Value.(int64)
+	req.sizeLimit, _ = op.Children[3].Value.(int64)
+	req.filter = op.Children[6]
+	for _, a := range op.Children[7].Children {
+		if name, ok := a.Value.(string); ok {
+			req.attrs = append(req.attrs, name)
+		}
+	}
+	return req, true
+}
+
+// searchDone returns a search result done op with code
+func searchDone(code uint16, message string) *ber.Packet {
+	done := newOp(ldap.ApplicationSearchResultDone, "Search Result Done")
+	appendResult(done, code, "", message)
+	return done
+}
+
+func encodeEntry(msgID int64, e *Entry, attrs []string, controls ...ldap.Control) *ber.Packet {
+	op := newOp(ldap.ApplicationSearchResultEntry, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "Object Name"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+
+	for _, name := range selectAttributes(e, attrs) {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range e.Attrs[name] {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	op.AppendChild(list)
+	return envelope(msgID, op, controls...)
+}
+
+// selectAttributes returns the attribute names of e to return for the
+// requested list, honoring "*" and "1.1".
+func selectAttributes(e *Entry, requested []string) []string {
+	all := len(requested) == 0
+	want := map[string]bool{}
+	for _, r := range requested {
+		if r == "*" {
+			all = true
+		}
+		want[strings.ToLower(r)] = true
+	}
+	var names []string
+	for name := range e.Attrs {
+		if all || want[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newOp(tag ber.Tag, description string) *ber.Packet {
+	return ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, description)
+}
+
+// envelope wraps a fully built protocol op into an LDAPMessage. The op must
+// be complete: ber packets copy child bytes when appended.
+func envelope(msgID int64, op *ber.Packet, controls ...ldap.Control) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, "MessageID"))
+	packet.AppendChild(op)
+	if len(controls) > 0 {
+		wrapper := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		for _, ctrl := range controls {
+			wrapper.AppendChild(ctrl.Encode())
+		}
+		packet.AppendChild(wrapper)
+	}
+	return packet
+}
+
+func appendResult(body *ber.Packet, code uint16, matchedDN, message string) {
+	body.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	body.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, matchedDN, "Matched DN"))
+	body.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+}
+
+// normalizeDN lowercases a DN and removes whitespace around separators.
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(p))
+	}
+	return strings.Join(parts, ",")
+}
+
+// inScope reports whether the normalized dn falls under the normalized baseDN.
+func inScope(dn, baseDN string, scope int) bool {
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == baseDN
+	case ldap.ScopeSingleLevel:
+		i := strings.Index(dn, ",")
+		return i >= 0 && dn[i+1:] == baseDN
+	default:
+		return baseDN == "" || dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
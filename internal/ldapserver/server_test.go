@@ -0,0 +1,143 @@
+package ldapserver_test
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func startServer(t *testing.T, n int) *ldapserver.Server {
+	t.Helper()
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(n))
+	srv.AddBind("uid=svc,ou=users,dc=redhat,dc=com", "secret")
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func TestBindAndSearch(t *testing.T) {
+	srv := startServer(t, 20)
+
+	conn, err := ldap.DialURL(srv.URL())
+	if err != nil {
+		t.Fatalf("DialURL failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind("uid=svc,ou=users,dc=redhat,dc=com", "wrong"); !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Errorf("Expected invalid credentials, got %v", err)
+	}
+	if err := conn.Bind("uid=svc,ou=users,dc=redhat,dc=com", "secret"); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		{"(uid=user000003)", 1},
+		{"(UID=USER000003)", 1},
+		{"(|(uid=user000001)(mail=user000002@redhat.com))", 2},
+		{"(&(objectClass=*)(co=US))", 3},
+		{"(!(co=US))", 17},
+		{"(uid=user00001*)", 10},
+		{"(cn=*000 01*)", 0},
+		{"(manager=" + testserver.UserDN(0) + ")", testserver.ReportsPerManager},
+		{"(rhatHireDate>=20100110000000Z)", 14},
+		{"(uid=nobody)", 0},
+	}
+	for _, tt := range tests {
+		res, err := conn.Search(ldap.NewSearchRequest(
+			testserver.UsersBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+			0, 0, false, tt.filter, []string{"uid"}, nil,
+		))
+		if err != nil {
+			t.Errorf("Search(%s) failed: %v", tt.filter, err)
+			continue
+		}
+		if len(res.Entries) != tt.want {
+			t.Errorf("Search(%s) returned %d entries, want %d", tt.filter, len(res.Entries), tt.want)
+		}
+	}
+}
+
+func TestPagedSearch(t *testing.T) {
+	srv := startServer(t, 25)
+
+	conn, err := ldap.DialURL(srv.URL())
+	if err != nil {
+		t.Fatalf("DialURL failed: %v", err)
+	}
+	defer conn.Close()
+
+	res, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		testserver.UsersBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(uid=*)", []string{"uid"}, nil,
+	), 10)
+	if err != nil {
+		t.Fatalf("SearchWithPaging failed: %v", err)
+	}
+	if len(res.Entries) != 25 {
+		t.Errorf("Expected 25 entries across pages, got %d", len(res.Entries))
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	srv := startServer(t, 10)
+
+	conn, err := ldap.DialURL(srv.URL())
+	if err != nil {
+		t.Fatalf("DialURL failed: %v", err)
+	}
+	defer conn.Close()
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		testserver.UsersBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		3, 0, false, "(uid=*)", nil, nil,
+	))
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		t.Errorf("Expected size limit exceeded, got %v", err)
+	}
+	if res == nil || len(res.Entries) != 3 {
+		t.Errorf("Expected 3 partial entries, got %v", res)
+	}
+}
+
+func TestSetAttribute(t *testing.T) {
+	srv := startServer(t, 3)
+
+	conn, err := ldap.DialURL(srv.URL())
+	if err != nil {
+		t.Fatalf("DialURL failed: %v", err)
+	}
+	defer conn.Close()
+
+	if !srv.SetAttribute(testserver.UserDN(1), "mail", "renamed@redhat.com") {
+		t.Fatal("SetAttribute did not find the entry")
+	}
+	if srv.SetAttribute("uid=nobody,"+testserver.UsersBaseDN, "mail", "x@redhat.com") {
+		t.Error("SetAttribute reported a missing entry as found")
+	}
+
+	for filter, want := range map[string]int{
+		"(mail=renamed@redhat.com)":                       1,
+		"(mail=" + testserver.UserUID(1) + "@redhat.com)": 0,
+		"(uid=" + testserver.UserUID(1) + ")":             1,
+	} {
+		res, err := conn.Search(ldap.NewSearchRequest(
+			testserver.UsersBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+			0, 0, false, filter, []string{"mail"}, nil,
+		))
+		if err != nil {
+			t.Fatalf("Search(%s) failed: %v", filter, err)
+		}
+		if len(res.Entries) != want {
+			t.Errorf("Search(%s) returned %d entries, want %d", filter, len(res.Entries), want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package ldapserver
+
+import (
+	"encoding/binary"
+	"net"
+	"slices"
+	"strconv"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// PersistentSearchOID is the persistent search control
+// (draft-ietf-ldapext-psearch).
+const PersistentSearchOID = "2.16.840.1.113730.3.4.3"
+
+// entryChangeNotificationOID is the control sent with the entries of a
+// persistent search that changed
+const entryChangeNotificationOID = "2.16.840.1.113730.3.4.7"
+
+// subscriber is a search in the persist phase of a content synchronization
+// (RFC 4533) or a persistent search, notified of changes by AddEntries and
+// SetAttribute
+type subscriber struct {
+	conn       net.Conn
+	msgID      int64
+	req        searchRequest
+	persistent bool // a persistent search rather than a content synchronization
+	write      func(*ber.Packet) error
+}
+
+// DisableControl makes the server reject searches carrying the critical
+// control oid with unavailableCriticalExtension, like a server that does not
+// implement it, and drops it from the root DSE's supportedControl. Content
+// synchronization and persistent search are supported until disabled.
+func (s *Server) DisableControl(oid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disabledControls[oid] = true
+}
+
+// rejectsControl reports whether controls hold a critical control disabled
+// with DisableControl
+func (s *Server) rejectsControl(controls []ldap.Control) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range controls {
+		if c, ok := c.(*ldap.ControlString); ok && c.Criticality && s.disabledControls[c.ControlType] {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationControl returns the sync request or persistent search control
+// in controls, or nil
+func notificationControl(controls []ldap.Control) *ldap.ControlString {
+	for _, c := range controls {
+		if c, ok := c.(*ldap.ControlString); ok && (c.ControlType == ldap.ControlTypeSyncRequest || c.ControlType == PersistentSearchOID) {
+			return c
+		}
+	}
+	return nil
+}
+
+// subscribe answers a content synchronization in refreshAndPersist mode or a
+// persistent search: it sends the matching entries, unless a sync cookie
+// resumes an earlier synchronization, then registers the search to be
+// notified of changes until the connection closes. A content
+// synchronization in refreshOnly mode ends after the entries.
+func (s *Server) subscribe(c net.Conn, msgID int64, op *ber.Packet, ctrl *ldap.ControlString, write func(*ber.Packet) error) error {
+	req, ok := parseSearch(op)
+	if !ok {
+		return write(envelope(msgID, searchDone(ldap.LDAPResultProtocolError, "malformed search request")))
+	}
+	sub := &subscriber{conn: c, msgID: msgID, req: req, persistent: ctrl.ControlType == PersistentSearchOID, write: write}
+	persist, resumed := true, false
+	if !sub.persistent {
+		value, err := ber.DecodePacketErr([]byte(ctrl.ControlValue))
+		if err != nil || len(value.Children) == 0 {
+			return write(envelope(msgID, searchDone(ldap.LDAPResultProtocolError, "malformed sync request control")))
+		}
+		mode, _ := value.Children[0].Value.(int64)
+		persist = mode == int64(ldap.SyncRequestModeRefreshAndPersist)
+		resumed = len(value.Children) == 3 && len(value.Children[1].ByteValue) > 0
+	}
+
+	s.mu.Lock()
+	var matches []*Entry
+	if !resumed {
+		for _, e := range s.entries {
+			if sub.matches(e) {
+				matches = append(matches, e)
+			}
+		}
+	}
+	cookie := strconv.Itoa(s.csn)
+	if persist {
+		s.subscribers = append(s.subscribers, sub)
+	}
+	s.mu.Unlock()
+
+	for _, e := range matches {
+		if err := sub.send(e, ldap.SyncStateAdd, cookie, false); err != nil {
+			return err
+		}
+	}
+	if sub.persistent {
+		return nil
+	}
+	if persist {
+		return write(envelope(msgID, refreshPresent(cookie)))
+	}
+	return write(envelope(msgID, searchDone(ldap.LDAPResultSuccess, ""), syncDone(cookie)))
+}
+
+// unsubscribe forgets the searches made on c
+func (s *Server) unsubscribe(c net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = slices.DeleteFunc(s.subscribers, func(sub *subscriber) bool { return sub.conn == c })
+}
+
+// notify tells the subscribers about an entry changing from old, nil when
+// added, to e. It is called without s.mu held.
+func (s *Server) notify(old, e *Entry) {
+	s.mu.Lock()
+	s.csn++
+	cookie := strconv.Itoa(s.csn)
+	subs := slices.Clone(s.subscribers)
+	s.mu.Unlock()
+	for _, sub := range subs {
+		was, is := old != nil && sub.matches(old), sub.matches(e)
+		switch {
+		case is && !was:
+			sub.send(e, ldap.SyncStateAdd, cookie, true)
+		case is:
+			sub.send(e, ldap.SyncStateModify, cookie, true)
+		case was && !sub.persistent:
+			// The entry left the content: a persistent search only reports
+			// changes of entries that still match
+			sub.send(old, ldap.SyncStateDelete, cookie, true)
+		}
+	}
+}
+
+func (sub *subscriber) matches(e *Entry) bool {
+	return inScope(e.norm, sub.req.base, sub.req.scope) && matchFilter(e, sub.req.filter)
+}
+
+// send writes e with its sync state control, or with an entry change
+// notification for the changes of a persistent search. Deleted entries are
+// sent without attributes.
+func (sub *subscriber) send(e *Entry, state ldap.ControlSyncStateState, cookie string, change bool) error {
+	attrs := sub.req.attrs
+	if state == ldap.SyncStateDelete {
+		attrs = []string{"1.1"}
+	}
+	switch {
+	case !sub.persistent:
+		return sub.write(encodeEntry(sub.msgID, e, attrs, syncState(state, e, cookie)))
+	case change:
+		return sub.write(encodeEntry(sub.msgID, e, attrs, entryChangeNotification(state)))
+	}
+	return sub.write(encodeEntry(sub.msgID, e, attrs))
+}
+
+// entryUUID returns a UUID for e derived from its position in the directory
+func entryUUID(e *Entry) []byte {
+	id := []byte{0x7e, 0x57, 0, 0, 0, 0, 0x40, 0, 0x80, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(id[12:], uint32(e.seq))
+	return id
+}
+
+// syncState returns the sync state control
+//
+//	SEQUENCE { state ENUMERATED, entryUUID OCTET STRING, cookie OCTET STRING }
+func syncState(state ldap.ControlSyncStateState, e *Entry, cookie string) ldap.Control {
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Sync State Value")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(state), "State"))
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, string(entryUUID(e)), "Entry UUID"))
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, cookie, "Cookie"))
+	return ldap.NewControlString(ldap.ControlTypeSyncState, false, string(value.Bytes()))
+}
+
+// entryChangeNotification returns the entry change notification control for
+// a sync state
+//
+//	SEQUENCE { changeType ENUMERATED }
+func entryChangeNotification(state ldap.ControlSyncStateState) ldap.Control {
+	changeType := int64(1) // add
+	if state == ldap.SyncStateModify {
+		changeType = 4
+	}
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Entry Change Notification Value")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, changeType, "Change Type"))
+	return ldap.NewControlString(entryChangeNotificationOID, false, string(value.Bytes()))
+}
+
+// refreshPresent returns the sync info intermediate response ending the
+// refresh phase of a content synchronization,
+//
+//	refreshPresent [2] SEQUENCE { cookie OCTET STRING, refreshDone BOOLEAN }
+func refreshPresent(cookie string) *ber.Packet {
+	info := ber.Encode(ber.ClassContext, ber.TypeConstructed, 2, nil, "Refresh Present")
+	info.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, cookie, "Cookie"))
+	info.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "Refresh Done"))
+
+	op := newOp(ldap.ApplicationIntermediateResponse, "Intermediate Response")
+	op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, ldap.ControlTypeSyncInfo, "Response Name"))
+	value := ber.Encode(ber.ClassContext, ber.TypePrimitive, 1, nil, "Response Value")
+	value.Data.Write(info.Bytes())
+	op.AppendChild(value)
+	return op
+}
+
+// syncDone returns the sync done control
+//
+//	SEQUENCE { cookie OCTET STRING }
+func syncDone(cookie string) ldap.Control {
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Sync Done Value")
+	value.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, cookie, "Cookie"))
+	return ldap.NewControlString(ldap.ControlTypeSyncDone, false, string(value.Bytes()))
+}
@@ -0,0 +1,147 @@
+package ldapserver
+
+import (
+	"sort"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Virtual list view controls (draft-ietf-ldapext-ldapv3-vlv)
+const (
+	VLVRequestOID  = "2.16.840.1.113730.3.4.9"
+	VLVResponseOID = "2.16.840.1.113730.3.4.10"
+)
+
+// vlvSortControlMissing is the virtualListViewResult, and search result, of
+// a virtual list view request without a server-side sort control
+const vlvSortControlMissing = 60
+
+// decodeControl decodes a request control. go-ldap cannot decode the
+// server-side sort request control, so it is returned as an
+// *ldap.ControlString holding its raw value, as are controls go-ldap does
+// not know.
+func decodeControl(packet *ber.Packet) (ldap.Control, error) {
+	if len(packet.Children) == 0 {
+		return ldap.DecodeControl(packet)
+	}
+	if oid, _ := packet.Children[0].Value.(string); oid != ldap.ControlTypeServerSideSorting {
+		return ldap.DecodeControl(packet)
+	}
+	c := &ldap.ControlString{ControlType: ldap.ControlTypeServerSideSorting}
+	for _, child := range packet.Children[1:] {
+		if critical, ok := child.Value.(bool); ok {
+			c.Criticality = critical
+		} else {
+			c.ControlValue = child.Data.String()
+		}
+	}
+	return c, nil
+}
+
+// sortKey is a key of a server-side sort request (RFC 2891)
+type sortKey struct {
+	attr    string
+	reverse bool
+}
+
+// parseSortKeys parses a server-side sort request control value,
+//
+//	SEQUENCE OF SEQUENCE { attributeType, orderingRule [0] OPTIONAL, reverseOrder [1] BOOLEAN DEFAULT FALSE }
+func parseSortKeys(value string) []sortKey {
+	packet, err := ber.DecodePacketErr([]byte(value))
+	if err != nil {
+		return nil
+	}
+	var keys []sortKey
+	for _, seq := range packet.Children {
+		if len(seq.Children) == 0 {
+			continue
+		}
+		key := sortKey{attr: seq.Children[0].Data.String()}
+		for _, field := range seq.Children[1:] {
+			if field.ClassType == ber.ClassContext && field.Tag == 1 {
+				key.reverse = field.Data.Len() > 0 && field.Data.Bytes()[0] != 0
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// sortEntries sorts entries by keys, comparing the first value of each
+// attribute case-insensitively. Entries without the attribute sort last.
+func sortEntries(entries []*Entry, keys []sortKey) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := firstValue(entries[i], k.attr), firstValue(entries[j], k.attr)
+			if a == b {
+				continue
+			}
+			if a == "" || b == "" {
+				return b == ""
+			}
+			return (a < b) != k.reverse
+		}
+		return false
+	})
+}
+
+func firstValue(e *Entry, attr string) string {
+	if v := e.Get(attr); len(v) > 0 {
+		return strings.ToLower(v[0])
+	}
+	return ""
+}
+
+// vlvWindow returns the entries of the sorted list entries a virtual list
+// view request value selects, and the response control,
+//
+//	SEQUENCE { beforeCount INTEGER, afterCount INTEGER,
+//	           byOffset [0] SEQUENCE { offset INTEGER, contentCount INTEGER } }
+//
+// A target greater than the list is its last entry; a contentCount other
+// than the list's scales offset, as the draft describes. Targets by value
+// are not supported.
+func vlvWindow(entries []*Entry, value string) ([]*Entry, ldap.Control, uint16) {
+	packet, err := ber.DecodePacketErr([]byte(value))
+	if err != nil || len(packet.Children) < 3 || packet.Children[2].Tag != 0 || len(packet.Children[2].Children) < 2 {
+		return nil, vlvResponse(0, len(entries), ldap.LDAPResultProtocolError), ldap.LDAPResultProtocolError
+	}
+	before, _ := packet.Children[0].Value.(int64)
+	after, _ := packet.Children[1].Value.(int64)
+	offset, _ := ber.ParseInt64(packet.Children[2].Children[0].Data.Bytes())
+	contentCount, _ := ber.ParseInt64(packet.Children[2].Children[1].Data.Bytes())
+	target := int(offset)
+	if contentCount > 0 && int(contentCount) != len(entries) {
+		target = int(offset * int64(len(entries)) / contentCount)
+	}
+	target = max(1, min(target, len(entries)))
+	start := max(0, target-1-int(before))
+	end := min(len(entries), target+int(after))
+	if len(entries) == 0 {
+		start, end, target = 0, 0, 0
+	}
+	return entries[start:end], vlvResponse(target, len(entries), ldap.LDAPResultSuccess), ldap.LDAPResultSuccess
+}
+
+// vlvResponse returns a virtual list view response control,
+//
+//	SEQUENCE { targetPosition INTEGER, contentCount INTEGER, virtualListViewResult ENUMERATED }
+func vlvResponse(target, count int, result uint16) ldap.Control {
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "VLV Response")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(target), "Target Position"))
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(count), "Content Count"))
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(result), "Result"))
+	return &ldap.ControlString{ControlType: VLVResponseOID, ControlValue: string(value.Bytes())}
+}
+
+// sortResponse returns a successful server-side sort response control,
+//
+//	SEQUENCE { sortResult ENUMERATED }
+func sortResponse() ldap.Control {
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Sort Result")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(0), "Result"))
+	return &ldap.ControlString{ControlType: ldap.ControlTypeServerSideSortingResult, ControlValue: string(value.Bytes())}
+}
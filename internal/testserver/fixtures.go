@@ -0,0 +1,91 @@
+// Package testserver generates the directories the benchmarks and
+// integration tests run against an internal/ldapserver server, and the
+// certificates they serve LDAPS with.
+package testserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+)
+
+// UsersBaseDN is the container the generated users live in, matching the
+// Red Hat directory layout the library assumes.
+const UsersBaseDN = "ou=users,dc=redhat,dc=com"
+
+// ReportsPerManager is the fan-out of the generated management tree.
+const ReportsPerManager = 8
+
+var (
+	fixtureCountries   = []string{"US", "CZ", "IN", "DE", "IE", "BR", "JP", "ES"}
+	fixtureLocations   = []string{"Remote US NC", "Brno", "Bangalore", "Munich", "Cork", "Sao Paulo", "Tokyo", "Madrid"}
+	fixtureGeos        = []string{"NA", "EMEA", "APAC", "EMEA", "EMEA", "LATAM", "APAC", "EMEA"} // of fixtureLocations
+	fixtureTitles      = []string{"Software Engineer", "Senior Software Engineer", "Principal Software Engineer", "Engineering Manager", "Product Manager"}
+	fixtureJobCodes    = []string{"E1234", "E1235", "E1236", "M2001", "P3001"}
+	fixtureDepartments = []string{"Engineering", "OpenShift", "RHEL", "AI Platform", "Sales"}
+)
+
+// UserUID returns the uid of the i-th generated user.
+func UserUID(i int) string {
+	return fmt.Sprintf("user%06d", i)
+}
+
+// UserDN returns the DN of the i-th generated user.
+func UserDN(i int) string {
+	return fmt.Sprintf("uid=%s,%s", UserUID(i), UsersBaseDN)
+}
+
+// GenerateUsers returns n deterministic users following the Red Hat schema.
+// User 0 is the root of the management tree; every other user i reports to
+// user (i-1)/ReportsPerManager.
+func GenerateUsers(n int) []*ldapserver.Entry {
+	entries := make([]*ldapserver.Entry, 0, n)
+	for i := 0; i < n; i++ {
+		entries = append(entries, NamedUser(i, UserUID(i)))
+	}
+	return entries
+}
+
+// NamedUser returns the i-th generated user with uid in place of UserUID(i),
+// for tests that look up a well-known account. It reports to the same manager
+// as the generated user, but generated reports still name UserDN(i).
+func NamedUser(i int, uid string) *ldapserver.Entry {
+	base := time.Date(2010, time.January, 4, 8, 0, 0, 0, time.UTC)
+	attrs := map[string][]string{
+		"objectClass":        {"top", "person", "organizationalPerson", "inetOrgPerson", "rhatPerson"},
+		"uid":                {uid},
+		"mail":               {uid + "@redhat.com"},
+		"cn":                 {fmt.Sprintf("User %06d", i)},
+		"sn":                 {fmt.Sprintf("%06d", i)},
+		"title":              {fixtureTitles[i%len(fixtureTitles)]},
+		"rhatCostCenter":     {fmt.Sprintf("%03d", 100+i%50)},
+		"rhatCostCenterDesc": {fmt.Sprintf("Cost Center %03d", 100+i%50)},
+		"rhatLocation":       {fixtureLocations[i%len(fixtureLocations)]},
+		"rhatJobCode":        {fixtureJobCodes[i%len(fixtureJobCodes)]},
+		"rhatGeo":            {fixtureGeos[i%len(fixtureGeos)]},
+		"rhatOrgCharTitle":   {fixtureTitles[i%len(fixtureTitles)]},
+		"rhatPersonType":     {fixturePersonType(i)},
+		"telephoneNumber":    {fmt.Sprintf("+1 919 555 %04d", i%10000)},
+		"rhatUUID":           {fmt.Sprintf("%08x-0000-4000-8000-%012x", i, i)},
+		"rhatHireDate":       {base.AddDate(0, 0, i%5000).Format("20060102150405Z")},
+		"rhatAdjSvcDate":     {base.AddDate(0, 0, i%5000).Format("20060102150405Z")},
+		"co":                 {fixtureCountries[i%len(fixtureCountries)]},
+		"ou":                 {fixtureDepartments[i%len(fixtureDepartments)]},
+		"employeeNumber":     {fmt.Sprintf("%d", 100000+i)},
+		"krbPrincipalName":   {uid + "@REDHAT.COM"},
+		"modifyTimestamp":    {base.AddDate(0, 0, i%5000).Format("20060102150405Z")},
+	}
+	if i > 0 {
+		attrs["manager"] = []string{UserDN((i - 1) / ReportsPerManager)}
+	}
+	return &ldapserver.Entry{DN: fmt.Sprintf("uid=%s,%s", uid, UsersBaseDN), Attrs: attrs}
+}
+
+// fixturePersonType makes every tenth generated user a contractor
+func fixturePersonType(i int) string {
+	if i%10 == 9 {
+		return "Contractor"
+	}
+	return "Employee"
+}
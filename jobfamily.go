@@ -0,0 +1,163 @@
+package ldap_redhat
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JobProfile is the normalized job family and seniority band derived from a
+// user's rhatJobCode and title.
+type JobProfile struct {
+	Family    string `json:"family" yaml:"family"`
+	Seniority string `json:"seniority" yaml:"seniority"`
+}
+
+// JobFamilyRule assigns Family to users whose job code starts with one of
+// CodePrefixes or whose title contains one of TitleKeywords (whole words,
+// case-insensitive).
+type JobFamilyRule struct {
+	Family        string   `yaml:"family"`
+	CodePrefixes  []string `yaml:"code_prefixes"`
+	TitleKeywords []string `yaml:"title_keywords"`
+}
+
+// SeniorityRule assigns Band to users whose title contains one of
+// TitleKeywords (whole words, case-insensitive).
+type SeniorityRule struct {
+	Band          string   `yaml:"band"`
+	TitleKeywords []string `yaml:"title_keywords"`
+}
+
+// JobTable is an ordered mapping from job codes and titles to job families
+// and seniority bands. Rules are evaluated top to bottom and the first match
+// wins, so more specific rules must come first. Job code rules are tried
+// before any title rule.
+type JobTable struct {
+	Families         []JobFamilyRule `yaml:"families"`
+	Seniority        []SeniorityRule `yaml:"seniority"`
+	DefaultFamily    string          `yaml:"default_family"`
+	DefaultSeniority string          `yaml:"default_seniority"`
+}
+
+// DefaultJobTable is the mapping used by NormalizeJob.
+var DefaultJobTable = JobTable{
+	Families: []JobFamilyRule{
+		{Family: "Quality Engineering", TitleKeywords: []string{"quality engineer", "qe", "test engineer", "quality assurance"}},
+		{Family: "Product Management", TitleKeywords: []string{"product manager", "product owner", "product management"}},
+		{Family: "Program Management", TitleKeywords: []string{"program manager", "project manager", "scrum master"}},
+		{Family: "Design", TitleKeywords: []string{"designer", "ux", "user experience"}},
+		{Family: "Support", TitleKeywords: []string{"support", "technical account manager", "escalation"}},
+		{Family: "Consulting", TitleKeywords: []string{"consultant", "consulting"}},
+		{Family: "Sales", TitleKeywords: []string{"sales", "account executive", "account manager", "solution architect", "solutions architect"}},
+		{Family: "Marketing", TitleKeywords: []string{"marketing", "marketer", "communications"}},
+		{Family: "Finance", TitleKeywords: []string{"finance", "financial", "accountant", "controller"}},
+		{Family: "Legal", TitleKeywords: []string{"legal", "counsel", "attorney", "paralegal"}},
+		{Family: "People", TitleKeywords: []string{"hr", "people", "talent", "recruiter", "human resources"}},
+		{Family: "Engineering", TitleKeywords: []string{"engineer", "engineering", "developer", "architect", "sre", "technologist"}},
+	},
+	Seniority: []SeniorityRule{
+		{Band: "Executive", TitleKeywords: []string{"vice president", "vp", "svp", "evp", "chief"}},
+		{Band: "Director", TitleKeywords: []string{"director"}},
+		{Band: "Manager", TitleKeywords: []string{"manager", "supervisor", "team lead"}},
+		{Band: "Distinguished", TitleKeywords: []string{"distinguished", "fellow"}},
+		{Band: "Senior Principal", TitleKeywords: []string{"senior principal", "sr principal"}},
+		{Band: "Principal", TitleKeywords: []string{"principal"}},
+		{Band: "Senior", TitleKeywords: []string{"senior", "sr"}},
+		{Band: "Associate", TitleKeywords: []string{"associate", "junior", "jr", "intern"}},
+	},
+	DefaultFamily:    "Other",
+	DefaultSeniority: "Mid",
+}
+
+// NormalizeJob maps a user's job code and title to a JobProfile using
+// DefaultJobTable.
+func NormalizeJob(user UserRecord) JobProfile {
+	return DefaultJobTable.Normalize(user)
+}
+
+// Normalize maps a user's job code and title to a JobProfile.
+func (t JobTable) Normalize(user UserRecord) JobProfile {
+	words := titleWords(user.Title)
+	profile := JobProfile{Family: t.DefaultFamily, Seniority: t.DefaultSeniority}
+
+	code := strings.ToUpper(strings.TrimSpace(user.RhatJobCode))
+	matched := false
+	if code != "" {
+		for _, rule := range t.Families {
+			for _, prefix := range rule.CodePrefixes {
+				if prefix != "" && strings.HasPrefix(code, strings.ToUpper(prefix)) {
+					profile.Family, matched = rule.Family, true
+					break
+				}
+			}
+			if matched {
+				break
+			}
+		}
+	}
+	if !matched {
+		for _, rule := range t.Families {
+			if containsAnyPhrase(words, rule.TitleKeywords) {
+				profile.Family = rule.Family
+				break
+			}
+		}
+	}
+
+	for _, rule := range t.Seniority {
+		if containsAnyPhrase(words, rule.TitleKeywords) {
+			profile.Seniority = rule.Band
+			break
+		}
+	}
+	return profile
+}
+
+// LoadJobTable reads a JobTable from a YAML file, for deployments that
+// maintain their own mapping.
+func LoadJobTable(path string) (JobTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return JobTable{}, fmt.Errorf("failed to read job table %s: %w", path, err)
+	}
+	var table JobTable
+	if err := yaml.Unmarshal(data, &table); err != nil {
+		return JobTable{}, fmt.Errorf("failed to parse job table %s: %w", path, err)
+	}
+	return table, nil
+}
+
+// titleWords lowercases a title and splits it into words, treating
+// punctuation such as "Sr." or "Engineer, OpenShift" as separators.
+func titleWords(title string) []string {
+	return strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+}
+
+// containsAnyPhrase reports whether any phrase appears in words as a
+// contiguous sequence of whole words.
+func containsAnyPhrase(words []string, phrases []string) bool {
+	for _, phrase := range phrases {
+		want := titleWords(phrase)
+		if len(want) == 0 || len(want) > len(words) {
+			continue
+		}
+		for i := 0; i+len(want) <= len(words); i++ {
+			match := true
+			for j := range want {
+				if words[i+j] != want[j] {
+					match = false
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+	}
+	return false
+}
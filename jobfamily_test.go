@@ -0,0 +1,102 @@
+package ldap_redhat_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestNormalizeJob(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected ldap_redhat.JobProfile
+	}{
+		{"Software Engineer", ldap_redhat.JobProfile{Family: "Engineering", Seniority: "Mid"}},
+		{"Senior Software Engineer", ldap_redhat.JobProfile{Family: "Engineering", Seniority: "Senior"}},
+		{"Sr. Principal Software Engineer", ldap_redhat.JobProfile{Family: "Engineering", Seniority: "Senior Principal"}},
+		{"Principal Quality Engineer", ldap_redhat.JobProfile{Family: "Quality Engineering", Seniority: "Principal"}},
+		{"Associate Software Engineer", ldap_redhat.JobProfile{Family: "Engineering", Seniority: "Associate"}},
+		{"Senior Manager, Software Engineering", ldap_redhat.JobProfile{Family: "Engineering", Seniority: "Manager"}},
+		{"Senior Product Manager", ldap_redhat.JobProfile{Family: "Product Management", Seniority: "Manager"}},
+		{"Director, Engineering", ldap_redhat.JobProfile{Family: "Engineering", Seniority: "Director"}},
+		{"VP, Global Support", ldap_redhat.JobProfile{Family: "Support", Seniority: "Executive"}},
+		{"Distinguished Engineer", ldap_redhat.JobProfile{Family: "Engineering", Seniority: "Distinguished"}},
+		{"Technical Account Manager", ldap_redhat.JobProfile{Family: "Support", Seniority: "Manager"}},
+		{"Senior Recruiter", ldap_redhat.JobProfile{Family: "People", Seniority: "Senior"}},
+		{"Chief Executive Officer", ldap_redhat.JobProfile{Family: "Other", Seniority: "Executive"}},
+		// "Supporting" is not the word "support"
+		{"Supporting Staff", ldap_redhat.JobProfile{Family: "Other", Seniority: "Mid"}},
+		{"", ldap_redhat.JobProfile{Family: "Other", Seniority: "Mid"}},
+	}
+
+	for _, test := range tests {
+		got := ldap_redhat.NormalizeJob(ldap_redhat.UserRecord{Title: test.title})
+		if got != test.expected {
+			t.Errorf("NormalizeJob(%q) = %+v, expected %+v", test.title, got, test.expected)
+		}
+	}
+}
+
+func TestJobTableCodePrefixes(t *testing.T) {
+	table := ldap_redhat.JobTable{
+		Families: []ldap_redhat.JobFamilyRule{
+			{Family: "Engineering", TitleKeywords: []string{"engineer"}},
+			{Family: "Sales", CodePrefixes: []string{"SAL"}},
+		},
+		DefaultFamily:    "Other",
+		DefaultSeniority: "Mid",
+	}
+
+	// A job code match takes precedence over an earlier title rule
+	got := table.Normalize(ldap_redhat.UserRecord{RhatJobCode: "sal1234", Title: "Sales Engineer"})
+	if got.Family != "Sales" {
+		t.Errorf("Expected job code to select Sales, got %q", got.Family)
+	}
+
+	got = table.Normalize(ldap_redhat.UserRecord{RhatJobCode: "ENG0001", Title: "Sales Engineer"})
+	if got.Family != "Engineering" {
+		t.Errorf("Expected title fallback to select Engineering, got %q", got.Family)
+	}
+}
+
+func TestLoadJobTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.yaml")
+	content := `families:
+  - family: Field
+    code_prefixes: [FLD]
+    title_keywords: [field engineer]
+seniority:
+  - band: Lead
+    title_keywords: [lead]
+default_family: Unknown
+default_seniority: Staff
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write job table: %v", err)
+	}
+
+	table, err := ldap_redhat.LoadJobTable(path)
+	if err != nil {
+		t.Fatalf("LoadJobTable failed: %v", err)
+	}
+
+	tests := []struct {
+		user     ldap_redhat.UserRecord
+		expected ldap_redhat.JobProfile
+	}{
+		{ldap_redhat.UserRecord{Title: "Lead Field Engineer"}, ldap_redhat.JobProfile{Family: "Field", Seniority: "Lead"}},
+		{ldap_redhat.UserRecord{RhatJobCode: "FLD9"}, ldap_redhat.JobProfile{Family: "Field", Seniority: "Staff"}},
+		{ldap_redhat.UserRecord{Title: "Accountant"}, ldap_redhat.JobProfile{Family: "Unknown", Seniority: "Staff"}},
+	}
+	for _, test := range tests {
+		if got := table.Normalize(test.user); got != test.expected {
+			t.Errorf("Normalize(%+v) = %+v, expected %+v", test.user, got, test.expected)
+		}
+	}
+
+	if _, err := ldap_redhat.LoadJobTable(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing job table")
+	}
+}
@@ -0,0 +1,82 @@
+package ldap_redhat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultKubernetesSecretDir is where LoadConfigFromAll looks for the bind
+// Secret when running in a pod. Mount a Secret with the keys username (bind
+// DN), password and, optionally, ca.crt there.
+const DefaultKubernetesSecretDir = "/var/run/secrets/ldap"
+
+// DefaultKubernetesConfigDir is where LoadConfigFromAll looks for a
+// ConfigMap holding config.yaml when running in a pod, after the usual
+// config file locations.
+const DefaultKubernetesConfigDir = "/etc/ldap"
+
+// Keys of the bind Secret, the standard names of kubernetes.io/basic-auth
+// and kubernetes.io/tls Secrets
+const (
+	kubernetesUsernameKey = "username"
+	kubernetesPasswordKey = "password"
+	kubernetesCAKey       = "ca.crt"
+)
+
+// InCluster reports whether the process runs in a Kubernetes pod, detected
+// as client-go does from KUBERNETES_SERVICE_HOST. LDAP_KUBERNETES=true or
+// false overrides the detection, e.g. to test a deployment's mounts locally.
+func InCluster() bool {
+	switch strings.ToLower(os.Getenv("LDAP_KUBERNETES")) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// kubernetesSecretDir returns LDAP_KUBERNETES_SECRET_DIR or the default
+func kubernetesSecretDir() string {
+	if dir := os.Getenv("LDAP_KUBERNETES_SECRET_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultKubernetesSecretDir
+}
+
+// kubernetesConfigDir returns LDAP_KUBERNETES_CONFIG_DIR or the default
+func kubernetesConfigDir() string {
+	if dir := os.Getenv("LDAP_KUBERNETES_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultKubernetesConfigDir
+}
+
+// applyKubernetesSecret fills the bind DN, password and CA settings config
+// leaves empty from the keys of the Secret mounted at dir. Missing keys are
+// skipped. The password is loaded as a PasswordFile, so a Secret updated in
+// place is picked up on the next reconnect.
+func applyKubernetesSecret(config *Config, dir string) {
+	if config.Username == "" {
+		if data, err := os.ReadFile(filepath.Join(dir, kubernetesUsernameKey)); err == nil {
+			config.Username = strings.TrimSpace(string(data))
+		}
+	}
+	if !config.HasCredentials() {
+		if path := filepath.Join(dir, kubernetesPasswordKey); fileExists(path) {
+			config.Credentials = passwordFileSource(path, config.SecretFilePermissions)
+		}
+	}
+	if config.CAFile == "" && config.CACertPEM == "" {
+		if path := filepath.Join(dir, kubernetesCAKey); fileExists(path) {
+			config.CAFile = path
+		}
+	}
+}
+
+// fileExists reports whether path names something that can be stat'ed
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,98 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// mountSecret writes a bind Secret's keys into a directory, as a secret
+// volume would, and points LDAP_KUBERNETES_SECRET_DIR at it
+func mountSecret(t *testing.T, keys map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for key, value := range keys {
+		if err := os.WriteFile(filepath.Join(dir, key), []byte(value+"\n"), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", key, err)
+		}
+	}
+	t.Setenv("LDAP_KUBERNETES_SECRET_DIR", dir)
+	return dir
+}
+
+// clearLDAPEnv runs the test outside any config file and LDAP_* settings
+func clearLDAPEnv(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	for _, name := range []string{"LDAP_URL", "LDAP_BIND_DN", "LDAP_PASSWORD", "LDAP_PASSWORD_FILE", "LDAP_CREDENTIALS", "LDAP_CA_FILE", "LDAP_CA_CERT_PEM", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadConfigFromKubernetesSecret(t *testing.T) {
+	clearLDAPEnv(t)
+	dir := mountSecret(t, map[string]string{
+		"username": "uid=svc,ou=serviceaccounts,dc=redhat,dc=com",
+		"password": "s3cret",
+		"ca.crt":   "-----BEGIN CERTIFICATE-----",
+	})
+
+	config := ldap_redhat.LoadConfigFromAll()
+	if config.Username != "" || config.HasCredentials() {
+		t.Errorf("Secret was read outside a cluster: %+v", config.Redacted())
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	if !ldap_redhat.InCluster() {
+		t.Fatal("InCluster() = false with KUBERNETES_SERVICE_HOST set")
+	}
+	config = ldap_redhat.LoadConfigFromAll()
+	if config.Username != "uid=svc,ou=serviceaccounts,dc=redhat,dc=com" {
+		t.Errorf("Username = %q, want the Secret's username", config.Username)
+	}
+	if password, err := config.Credentials.Password(context.Background()); err != nil || password != "s3cret" {
+		t.Errorf("Password() = %q, %v, want the Secret's password", password, err)
+	}
+	if want := filepath.Join(dir, "ca.crt"); config.CAFile != want {
+		t.Errorf("CAFile = %q, want %q", config.CAFile, want)
+	}
+
+	// Settings from the environment win over the Secret
+	t.Setenv("LDAP_BIND_DN", "uid=other,dc=redhat,dc=com")
+	t.Setenv("LDAP_PASSWORD", "from-env")
+	config = ldap_redhat.LoadConfigFromAll()
+	if config.Username != "uid=other,dc=redhat,dc=com" || config.Password != "from-env" || config.Credentials != nil {
+		t.Errorf("Environment did not take precedence: %+v", config)
+	}
+
+	t.Setenv("LDAP_KUBERNETES", "false")
+	if ldap_redhat.InCluster() {
+		t.Error("LDAP_KUBERNETES=false did not override detection")
+	}
+}
+
+func TestLoadConfigFromKubernetesConfigMap(t *testing.T) {
+	clearLDAPEnv(t)
+	mountSecret(t, map[string]string{"password": "s3cret"})
+	configDir := t.TempDir()
+	data := "environments:\n  local:\n    ldap_servers: [\"ldaps://ldap.corp.redhat.com\"]\n    username: \"uid=svc,dc=redhat,dc=com\"\n"
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write config.yaml: %v", err)
+	}
+	t.Setenv("LDAP_KUBERNETES_CONFIG_DIR", configDir)
+	t.Setenv("LDAP_ENV", "local")
+	t.Setenv("LDAP_KUBERNETES", "true")
+
+	config := ldap_redhat.LoadConfigFromAll()
+	if len(config.LdapServers) != 1 || config.LdapServers[0] != "ldaps://ldap.corp.redhat.com" {
+		t.Errorf("LdapServers = %v, want the ConfigMap's", config.LdapServers)
+	}
+	if config.Username != "uid=svc,dc=redhat,dc=com" || !config.HasCredentials() {
+		t.Errorf("Got username %q and credentials %v, want the ConfigMap's username and the Secret's password", config.Username, config.HasCredentials())
+	}
+}
@@ -3,28 +3,178 @@ package ldap_redhat
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 )
 
 // Version of the go-ldap-redhat library
-const Version = "v1.3.0"
-
-// Config holds LDAP connection configuration
+const Version = "v1.4.0"
+
+// Config holds LDAP connection configuration.
+//
+// The struct tags are the authoritative description of every setting: the
+// YAML key, the environment variables that fill it when YAML leaves it empty,
+// the default, and a description. ConfigSchema and `ldapcheck config schema`
+// are generated from them, so keep them in sync with LoadConfigFromAll.
 type Config struct {
-	LdapServers   []string
-	Port          int
-	Username      string
-	Password      string
-	BaseDN        string
-	UseStartTLS   bool
-	VerifySSL     bool
-	TLSServerName string // Optional: Override ServerName for TLS verification (useful when connecting via IP)
+	LdapServers []string `yaml:"ldap_servers" env:"LDAP_URL" desc:"LDAP server URLs (ldap://, ldaps:// or ldapi://)"`
+	Port        int      `yaml:"-" desc:"Port, usually included in the URL"`
+	Username    string   `yaml:"username" env:"LDAP_BIND_DN" desc:"Bind DN of the service account"`
+	// Deprecated: Password keeps the secret in Config for the searcher's
+	// lifetime. Set Credentials instead; Password is only used when
+	// Credentials is nil.
+	Password      string   `yaml:"-" env:"LDAP_PASSWORD" desc:"Bind password; prefer password_file or LDAP_PASSWORD_FILE"`
+	BaseDN        string   `yaml:"base_dn" env:"LDAP_BASE_DN" default:"ou=users,dc=redhat,dc=com" desc:"Search base DN"`
+	UseStartTLS   bool     `yaml:"use_start_tls" env:"LDAP_START_TLS" default:"false" desc:"Upgrade ldap:// connections with StartTLS"`
+	VerifySSL     bool     `yaml:"verify_ssl" env:"LDAP_VERIFY_SSL" default:"false" desc:"Verify server certificates"`
+	TLSServerName string   `yaml:"tls_server_name" env:"LDAP_TLS_SERVER_NAME" desc:"Override ServerName for TLS verification (useful when connecting via IP)"`
+	AuthMode      AuthMode `yaml:"auth_mode" env:"LDAP_AUTH_MODE" default:"simple" desc:"Bind method: simple (username and password) or external (SASL EXTERNAL, e.g. over ldapi://)"`
+
+	// DialTimeout bounds connecting, including the TLS handshake for
+	// ldaps://. BindTimeout bounds StartTLS and the bind. SearchTimeout
+	// bounds each search request, or each page of a paged search, on the
+	// client and, rounded up to seconds, as the server-side time limit. Zero
+	// uses DefaultDialTimeout, DefaultBindTimeout and DefaultSearchTimeout;
+	// negative values disable the limit.
+	DialTimeout   time.Duration `yaml:"dial_timeout" env:"LDAP_DIAL_TIMEOUT" default:"10s" desc:"How long connecting to a server may take (negative: no limit)"`
+	BindTimeout   time.Duration `yaml:"bind_timeout" env:"LDAP_BIND_TIMEOUT" default:"10s" desc:"How long StartTLS and the bind may take (negative: no limit)"`
+	SearchTimeout time.Duration `yaml:"search_timeout" env:"LDAP_SEARCH_TIMEOUT" default:"60s" desc:"How long a search, or a page of a paged search, may take (negative: no limit)"`
+
+	// DeletedUsersBaseDN is where offboarding moves the entries of users who
+	// have left. Their records have StatusDeleted, and GetUserIncludingDeleted
+	// and SearchOptions.IncludeDeleted search it when BaseDN does not cover it.
+	DeletedUsersBaseDN string `yaml:"deleted_users_base_dn" env:"LDAP_DELETED_USERS_BASE_DN" default:"ou=deletedusers,dc=redhat,dc=com" desc:"Base DN of the entries of users who have left"`
+
+	// LazyConnect makes NewSearcher return without dialing. The searcher
+	// connects on first use, or when Connect is called.
+	LazyConnect bool `yaml:"lazy_connect" env:"LDAP_LAZY_CONNECT" default:"false" desc:"Connect on first use instead of in NewSearcher"`
+
+	// Credentials supplies the bind password each time the searcher binds.
+	// The loaders set it from the credentials reference (see
+	// CredentialProvider), or to a PasswordFile for password_file and
+	// LDAP_PASSWORD_FILE, so the file is re-read on every reconnect.
+	Credentials CredentialSource `yaml:"-" desc:"Source of the bind password, asked at every bind (Go API only; credentials, LDAP_CREDENTIALS, password_file and LDAP_PASSWORD_FILE set one)"`
+
+	CAFile         string `yaml:"ca_file" env:"LDAP_CA_FILE" desc:"PEM bundle (or directory of PEM files) of CAs to trust instead of the system pool"`
+	CACertPEM      string `yaml:"ca_cert_pem" env:"LDAP_CA_CERT_PEM" desc:"Inline PEM CA certificates, added to the ca_file pool"`
+	ClientCertFile string `yaml:"client_cert_file" env:"LDAP_CLIENT_CERT_FILE" desc:"PEM client certificate for mutual TLS"`
+	ClientKeyFile  string `yaml:"client_key_file" env:"LDAP_CLIENT_KEY_FILE" desc:"PEM private key matching client_cert_file"`
+
+	// FilterTemplates replaces the search filter GetUser and GetUsers use for
+	// an identifier type, keyed by one of the FilterTemplate constants ("uid",
+	// "email", "uuid", "employee_number", "kerberos"). Every %s is replaced by the
+	// escaped identifier, e.g. "(|(mail=%s)(rhatPreferredAlias=%s))" to also
+	// find users by alias address.
+	FilterTemplates map[string]string `yaml:"filter_templates" desc:"Search filters per identifier type (uid, email, uuid, employee_number, kerberos) with %s for the value"`
+
+	SearchScope     SearchScope   `yaml:"search_scope" env:"LDAP_SEARCH_SCOPE" default:"sub" desc:"How much of the tree below the base DN searches cover: base, one or sub"`
+	DerefAliases    AliasDeref    `yaml:"deref_aliases" env:"LDAP_DEREF_ALIASES" default:"never" desc:"When searches dereference aliases: never, searching, finding or always"`
+	MaxReferralHops int           `yaml:"max_referral_hops" env:"LDAP_MAX_REFERRAL_HOPS" default:"0" desc:"Referrals a search follows, re-binding with the same credentials (0 returns referrals as errors)"`
+	SizeLimit       int           `yaml:"size_limit" env:"LDAP_SIZE_LIMIT" default:"0" desc:"Most results a user or group listing returns before it stops with partial results (0 for the server's limit)"`
+	TimeLimit       time.Duration `yaml:"time_limit" env:"LDAP_TIME_LIMIT" default:"0" desc:"Time limit sent to the server with each search, rounded up to seconds (0 for search_timeout, negative for none)"`
+
+	// AttributeProfile limits the attributes user lookups fetch, so that
+	// services without a need for HR data never request it (see
+	// AttributeProfile). SearchOptions.AttributeProfile overrides it for a
+	// request.
+	AttributeProfile AttributeProfile `yaml:"attribute_profile" env:"LDAP_ATTRIBUTE_PROFILE" default:"full" desc:"Attributes user lookups fetch: minimal (uid, email, display name), standard (no HR data) or full"`
+
+	DetectPeopleManagers   bool   `yaml:"-" default:"false" desc:"Populate UserRecord.IsPeopleManager in GetUser/GetUsers"`
+	PeopleManagerAttribute string `yaml:"-" desc:"Boolean directory attribute flagging managers, used instead of probing when present"`
+
+	SnapshotFile    string `yaml:"snapshot_file" env:"LDAP_SNAPSHOT_FILE" desc:"JSON Lines snapshot (see Snapshot) used by offline_fallback"`
+	OfflineFallback bool   `yaml:"offline_fallback" env:"LDAP_OFFLINE_FALLBACK" default:"false" desc:"Serve stale snapshot_file results when no LDAP server is reachable"`
+
+	// Offline serves the users and groups of FixturesFile (see Fixtures)
+	// instead of connecting to LdapServers, for developing and demoing
+	// without access to the directory. The searcher starts an LDAP server
+	// on the loopback interface loaded with the fixtures and connects to it,
+	// so lookups take the same code paths and fail with the same errors as
+	// against the real directory.
+	Offline      bool   `yaml:"offline" env:"LDAP_OFFLINE" default:"false" desc:"Serve fixtures_file instead of connecting to an LDAP server"`
+	FixturesFile string `yaml:"fixtures_file" env:"LDAP_FIXTURES_FILE" desc:"YAML or JSON users and groups (see Fixtures) served in offline mode"`
+
+	FeatureGates map[Feature]bool `yaml:"feature_gates" desc:"Per-searcher overrides of the process-wide feature gates (LDAP_FEATURE_GATES)"`
+
+	BreakerFailureThreshold int           `yaml:"breaker_failure_threshold" env:"LDAP_BREAKER_THRESHOLD" default:"0" desc:"Consecutive unreachable-directory failures that open the circuit breaker (0 disables it)"`
+	BreakerOpenTimeout      time.Duration `yaml:"breaker_open_timeout" env:"LDAP_BREAKER_OPEN_TIMEOUT" default:"30s" desc:"How long the breaker fails fast before a trial request"`
+
+	// OnDegradationChange, if set, is called when the circuit breaker opens
+	// and the searcher becomes degraded, and again when it recovers. It is
+	// called synchronously by the request that caused the change, so it
+	// must not block.
+	OnDegradationChange func(Degradation) `yaml:"-" desc:"Called when the searcher becomes degraded or recovers, to shed load (Go API only)"`
+
+	// ClientName identifies the consuming service to the directory team. It
+	// names the searcher's connections ("billing/3") in logs and in the
+	// session tracking control sent with binds and searches, which servers
+	// that support it record with each operation. The program name is used
+	// when it is empty.
+	ClientName string `yaml:"client_name" env:"LDAP_CLIENT_NAME" desc:"Name of the consuming service, sent to the server and used to name connections in logs (default: program name)"`
+
+	// Interceptors wrap the searcher's lookups, in order, the first being
+	// outermost (see Interceptor). They see lookups, group, report and
+	// schema queries, and the listings of ForEachUser, SearchUsers and
+	// Users a page at a time. Sync, ConsistencyToken.Verify, Ping and the
+	// health check's canary search bypass them.
+	Interceptors []Interceptor `yaml:"-" desc:"Search middleware for logging, metrics, caching, rate limiting or auditing (Go API only)"`
+
+	// AuditSink, if set, receives an AuditEvent for every user lookup,
+	// recording the principal attached with WithPrincipal and the
+	// identifier or filter looked up, masked by AuditRedaction.
+	AuditSink      AuditSink      `yaml:"-" desc:"Receives who-searched-what events for compliance audits (Go API only)"`
+	AuditRedaction AuditRedaction `yaml:"-" desc:"Masks of the identifiers and filters recorded in audit events (Go API only)"`
+
+	// Logger receives dials, binds and searches at debug level. Passwords are
+	// never logged.
+	Logger *slog.Logger `yaml:"-" desc:"Structured logger for dials, binds and searches (Go API only)"`
+
+	// EnableTracing wraps dials, binds and searches in OpenTelemetry spans,
+	// children of the span in the caller's context. Spans go to
+	// TracerProvider, or the global provider when it is nil.
+	EnableTracing  bool                 `yaml:"enable_tracing" env:"LDAP_ENABLE_TRACING" default:"false" desc:"Create OpenTelemetry spans for dials, binds and searches"`
+	TracerProvider trace.TracerProvider `yaml:"-" desc:"OpenTelemetry tracer provider for enable_tracing; the global provider when unset (Go API only)"`
+
+	// SecretFilePermissions decides whether password files that other users
+	// can read, or that another user owns, are read with a warning or
+	// refused. It applies while the configuration is loaded.
+	SecretFilePermissions SecretFilePolicy `yaml:"secret_file_permissions" env:"LDAP_SECRET_FILE_PERMISSIONS" default:"warn" desc:"Insecure password file handling: warn, strict (refuse) or off"`
+}
+
+// redactedValue replaces secrets in Redacted configs
+const redactedValue = "REDACTED"
+
+// Redacted returns a copy of the config that is safe to log or share. The
+// password and credential sources other than PasswordFile, EnvPassword and
+// PasswordCommand are replaced; file paths, variable names and commands are
+// kept so that misconfigurations remain diagnosable.
+func (c Config) Redacted() Config {
+	if c.Password != "" {
+		c.Password = redactedValue
+	}
+	switch c.Credentials.(type) {
+	case nil, PasswordFile, EnvPassword, PasswordCommand:
+	default:
+		c.Credentials = StaticPassword(redactedValue)
+	}
+	c.LdapServers = append([]string(nil), c.LdapServers...)
+	return c
 }
 
 // YAMLConfig represents the YAML configuration structure
@@ -38,68 +188,250 @@ type EnvConfig struct {
 	BaseDN       string   `yaml:"base_dn"`
 	UseStartTLS  bool     `yaml:"use_start_tls"`
 	VerifySSL    bool     `yaml:"verify_ssl"`
-	PasswordFile string   `yaml:"password_file"`
+	PasswordFile string   `yaml:"password_file" env:"LDAP_PASSWORD_FILE" desc:"File containing the bind password"`
+	Credentials  string   `yaml:"credentials" env:"LDAP_CREDENTIALS" desc:"Credential provider reference, used instead of password_file: env:VAR, file:PATH, exec:COMMAND, vault:PATH or static:PASSWORD"`
+	AuthMode     AuthMode `yaml:"auth_mode"`
+	LazyConnect  bool     `yaml:"lazy_connect"`
+
+	DeletedUsersBaseDN string `yaml:"deleted_users_base_dn"`
+
+	DialTimeout   time.Duration `yaml:"dial_timeout"`
+	BindTimeout   time.Duration `yaml:"bind_timeout"`
+	SearchTimeout time.Duration `yaml:"search_timeout"`
+
+	TLSServerName  string `yaml:"tls_server_name"`
+	CAFile         string `yaml:"ca_file"`
+	CACertPEM      string `yaml:"ca_cert_pem"`
+	ClientCertFile string `yaml:"client_cert_file"`
+	ClientKeyFile  string `yaml:"client_key_file"`
+
+	SnapshotFile    string `yaml:"snapshot_file"`
+	OfflineFallback bool   `yaml:"offline_fallback"`
+
+	Offline      bool   `yaml:"offline"`
+	FixturesFile string `yaml:"fixtures_file"`
+
+	FeatureGates map[string]bool `yaml:"feature_gates"`
+
+	FilterTemplates map[string]string `yaml:"filter_templates"`
+
+	SearchScope     SearchScope   `yaml:"search_scope"`
+	DerefAliases    AliasDeref    `yaml:"deref_aliases"`
+	MaxReferralHops int           `yaml:"max_referral_hops"`
+	SizeLimit       int           `yaml:"size_limit"`
+	TimeLimit       time.Duration `yaml:"time_limit"`
+
+	AttributeProfile AttributeProfile `yaml:"attribute_profile"`
+
+	BreakerFailureThreshold int           `yaml:"breaker_failure_threshold"`
+	BreakerOpenTimeout      time.Duration `yaml:"breaker_open_timeout"`
+
+	ClientName    string `yaml:"client_name"`
+	EnableTracing bool   `yaml:"enable_tracing"`
+
+	SecretFilePermissions SecretFilePolicy `yaml:"secret_file_permissions"`
 }
 
-// DefaultConfig holds the auto-loaded configuration
+// DefaultConfig holds the configuration used by NewSearcherWithDefaults. It
+// is empty until LoadDefaultConfig or SetDefaultConfig is called; importing
+// the package reads no files or environment variables. A running
+// ConfigWatcher replaces it when config.yaml changes, so read it with
+// LoadDefaultConfig and change it with SetDefaultConfig.
 var DefaultConfig Config
 
-func init() {
-	DefaultConfig = LoadConfigFromAll()
+var (
+	defaultConfigMu   sync.RWMutex // guards DefaultConfig against a running ConfigWatcher
+	defaultConfigOnce sync.Once    // loads DefaultConfig on first use
+)
+
+// LoadDefaultConfig returns DefaultConfig, loading it with LoadConfigFromAll
+// on the first call. Later calls return the same configuration until
+// SetDefaultConfig or a ConfigWatcher replaces it. A DefaultConfig assigned
+// directly before the first call is kept rather than loaded over.
+func LoadDefaultConfig() Config {
+	defaultConfigOnce.Do(func() {
+		defaultConfigMu.Lock()
+		defer defaultConfigMu.Unlock()
+		if reflect.ValueOf(DefaultConfig).IsZero() {
+			DefaultConfig = LoadConfigFromAll()
+		}
+	})
+	defaultConfigMu.RLock()
+	defer defaultConfigMu.RUnlock()
+	return DefaultConfig
 }
 
+// SetDefaultConfig replaces DefaultConfig, and keeps LoadDefaultConfig from
+// loading one, e.g. to inject a configuration in tests.
+func SetDefaultConfig(config Config) {
+	defaultConfigOnce.Do(func() {})
+	defaultConfigMu.Lock()
+	defer defaultConfigMu.Unlock()
+	DefaultConfig = config
+}
+
+// UserSearcher is the user lookup API of *Searcher. Accept it instead of
+// *Searcher in code that only looks users up, so tests can substitute a fake
+// such as ldaptest.FakeSearcher. The constructors return *Searcher, which
+// satisfies it, as does *CachedSearcher, so a cache can be added without
+// changing callers.
+type UserSearcher interface {
+	GetUser(ctx context.Context, id Identifier) (UserRecord, error)
+	GetUsers(ctx context.Context, ids []Identifier) ([]UserRecord, error)
+	SearchUsers(ctx context.Context, filter string) ([]UserRecord, error)
+	Close() error
+}
+
+var (
+	_ UserSearcher = (*Searcher)(nil)
+	_ UserSearcher = (*CachedSearcher)(nil)
+)
+
+// Searcher looks users up in the directory. It is safe for concurrent use by
+// multiple goroutines: searches share one connection, which go-ldap
+// multiplexes, and Ping swaps in a new connection without disturbing
+// concurrent callers. Config must not be modified while the searcher is in
+// use; call Reload to switch a live searcher to a new configuration.
 type Searcher struct {
+	// Config is the searcher's configuration. Reload replaces it, so do not
+	// read it directly while other goroutines use the searcher.
 	Config Config
-	Conn   *ldap.Conn
+	// Conn is the current connection. Ping replaces it, so read it with
+	// Connection while other goroutines use the searcher.
+	Conn *ldap.Conn
+
+	mu       sync.RWMutex       // guards Config, Conn, connName, snapshot, fixtures and chain
+	pingMu   sync.Mutex         // serializes Connect and Ping, so only one dial runs at a time
+	connName string             // name of Conn in logs and the session tracking control
+	snapshot *Snapshot          // loaded from Config.SnapshotFile on first offline use
+	breaker  *breaker           // nil unless Config.BreakerFailureThreshold is set
+	env      string             // config file environment, set by NewSearcherForEnv
+	fixtures *ldapserver.Server // serves Config.FixturesFile in offline mode
+	chain    SearchFunc         // Config.Interceptors around searchLDAP, built on first use
+	chainFor []Interceptor      // the interceptors chain was built from
+}
+
+// Connection returns the current connection, or nil if the searcher is not
+// connected.
+func (s *Searcher) Connection() *ldap.Conn {
+	return s.conn()
+}
+
+// conn returns the current connection under the read lock
+func (s *Searcher) conn() *ldap.Conn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Conn
+}
+
+// config returns the current configuration under the read lock
+func (s *Searcher) config() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Config
+}
+
+// namedConn returns the current connection and its name, which is empty if
+// the searcher did not dial it
+func (s *Searcher) namedConn() (*ldap.Conn, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Conn, s.connName
+}
+
+// setConn swaps in conn, named name, and closes the connection it replaces
+func (s *Searcher) setConn(conn *ldap.Conn, name string) {
+	s.mu.Lock()
+	old := s.Conn
+	s.Conn, s.connName = conn, name
+	s.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
 }
 
 type UserRecord struct {
-	UID            string
-	Email          string
-	DisplayName    string
-	Surname        string
-	Title          string
-	ManagerUID     string
-	CostCenter     string
-	CostCenterDesc string
-	RhatLocation   string
-	RhatJobCode    string
-	RhatUUID       string
-	RhatHireDate   string
-	RhatTermDate   string
-	RhatAdjSvcDate string
-	Country        string // co — ISO 3166 country code (e.g. "US", "DEU")
-	Department     string // ou — organizational unit / department
-}
-
-// userAttributes is the canonical list of LDAP attributes fetched for user lookups.
-var userAttributes = []string{
-	"uid", "mail", "cn", "sn", "title", "manager",
-	"rhatCostCenter", "rhatCostCenterDesc", "rhatLocation",
-	"rhatJobCode", "rhatUUID", "rhatHireDate", "rhatTermDate", "rhatAdjSvcDate",
-	"co", "ou",
-}
-
-// entryToUserRecord converts an LDAP entry to a UserRecord.
+	UID            string `json:"uid" yaml:"uid"`
+	Email          string `json:"email" yaml:"email"`
+	DisplayName    string `json:"display_name" yaml:"display_name"`
+	Surname        string `json:"surname" yaml:"surname"`
+	Title          string `json:"title" yaml:"title"`
+	ManagerUID     string `json:"manager_uid" yaml:"manager_uid"`                   // uid of the manager, parsed from ManagerDN
+	ManagerDN      string `json:"manager_dn,omitempty" yaml:"manager_dn,omitempty"` // raw manager attribute
+	CostCenter     string `json:"cost_center,omitempty" yaml:"cost_center,omitempty"`
+	CostCenterDesc string `json:"cost_center_desc,omitempty" yaml:"cost_center_desc,omitempty"`
+	RhatLocation   string `json:"rhat_location,omitempty" yaml:"rhat_location,omitempty"`
+	RhatJobCode    string `json:"rhat_job_code,omitempty" yaml:"rhat_job_code,omitempty"`
+	RhatUUID       string `json:"rhat_uuid,omitempty" yaml:"rhat_uuid,omitempty"`
+	RhatHireDate   string `json:"rhat_hire_date,omitempty" yaml:"rhat_hire_date,omitempty"`
+	RhatTermDate   string `json:"rhat_term_date,omitempty" yaml:"rhat_term_date,omitempty"`
+	RhatAdjSvcDate string `json:"rhat_adj_svc_date,omitempty" yaml:"rhat_adj_svc_date,omitempty"`
+	Country        string `json:"country,omitempty" yaml:"country,omitempty"`       // co — ISO 3166 country code (e.g. "US", "DEU")
+	Department     string `json:"department,omitempty" yaml:"department,omitempty"` // ou — organizational unit / department
+
+	EmployeeNumber    string `json:"employee_number,omitempty" yaml:"employee_number,omitempty"`       // employeeNumber (worker ID)
+	KerberosPrincipal string `json:"kerberos_principal,omitempty" yaml:"kerberos_principal,omitempty"` // krbPrincipalName
+
+	Geo             string `json:"geo,omitempty" yaml:"geo,omitempty"`                           // rhatGeo — region, e.g. "NA", "EMEA", "APAC", "LATAM"
+	OrgChartTitle   string `json:"org_chart_title,omitempty" yaml:"org_chart_title,omitempty"`   // rhatOrgCharTitle — title shown on the org chart
+	PersonType      string `json:"person_type,omitempty" yaml:"person_type,omitempty"`           // rhatPersonType, e.g. "Employee", "Contractor", "Intern"
+	Building        string `json:"building,omitempty" yaml:"building,omitempty"`                 // rhatBuilding
+	Mobile          string `json:"mobile,omitempty" yaml:"mobile,omitempty"`                     // mobile
+	TelephoneNumber string `json:"telephone_number,omitempty" yaml:"telephone_number,omitempty"` // telephoneNumber
+
+	HireDate       time.Time `json:"hire_date,omitzero" yaml:"hire_date,omitempty"`               // parsed RhatHireDate (zero if absent or invalid)
+	TermDate       time.Time `json:"term_date,omitzero" yaml:"term_date,omitempty"`               // parsed RhatTermDate (zero if absent or invalid)
+	AdjServiceDate time.Time `json:"adj_service_date,omitzero" yaml:"adj_service_date,omitempty"` // parsed RhatAdjSvcDate (zero if absent or invalid)
+
+	IsPeopleManager bool `json:"is_people_manager,omitempty" yaml:"is_people_manager,omitempty"` // has at least one direct report (only set when Config.DetectPeopleManagers is enabled)
+
+	Status UserStatus `json:"status,omitempty" yaml:"status,omitempty"` // active, terminated, or deleted for entries under Config.DeletedUsersBaseDN
+
+	Stale       bool          `json:"stale,omitempty" yaml:"stale,omitempty"`               // served from the offline snapshot because the directory was unreachable
+	SnapshotAge time.Duration `json:"snapshot_age,omitempty" yaml:"snapshot_age,omitempty"` // age of the snapshot a Stale record came from
+
+	meta RecordMeta // returned by Meta
+}
+
+// IsActive reports whether the user has no termination date, or one that is
+// still in the future. An unparseable RhatTermDate counts as terminated.
+func (u UserRecord) IsActive() bool {
+	return u.activeAt(time.Now())
+}
+
+// activeAt reports whether the user was active at t, as IsActive does for now
+func (u UserRecord) activeAt(t time.Time) bool {
+	term := u.TermDate
+	if term.IsZero() && u.RhatTermDate != "" {
+		var err error
+		if term, err = ParseLDAPTime(u.RhatTermDate); err != nil {
+			return false
+		}
+	}
+	return term.IsZero() || term.After(t)
+}
+
+// entryToUserRecord converts an LDAP entry to a UserRecord, parsing each
+// attribute as its schema describes.
 func entryToUserRecord(entry *ldap.Entry) UserRecord {
-	return UserRecord{
-		UID:            entry.GetAttributeValue("uid"),
-		Email:          entry.GetAttributeValue("mail"),
-		DisplayName:    entry.GetAttributeValue("cn"),
-		Surname:        entry.GetAttributeValue("sn"),
-		Title:          entry.GetAttributeValue("title"),
-		ManagerUID:     entry.GetAttributeValue("manager"),
-		CostCenter:     entry.GetAttributeValue("rhatCostCenter"),
-		CostCenterDesc: entry.GetAttributeValue("rhatCostCenterDesc"),
-		RhatLocation:   entry.GetAttributeValue("rhatLocation"),
-		RhatJobCode:    entry.GetAttributeValue("rhatJobCode"),
-		RhatUUID:       entry.GetAttributeValue("rhatUUID"),
-		RhatHireDate:   entry.GetAttributeValue("rhatHireDate"),
-		RhatTermDate:   entry.GetAttributeValue("rhatTermDate"),
-		RhatAdjSvcDate: entry.GetAttributeValue("rhatAdjSvcDate"),
-		Country:        entry.GetAttributeValue("co"),
-		Department:     entry.GetAttributeValue("ou"),
+	var rec UserRecord
+	for _, a := range attributeSchemas {
+		if a.value == nil {
+			continue
+		}
+		value := entry.GetAttributeValue(a.Name)
+		*a.value(&rec) = value
+		if a.parsed != nil {
+			// Invalid dates are left zero; the raw strings remain available
+			*a.parsed(&rec), _ = ParseLDAPTime(value)
+		}
+	}
+	rec.ManagerUID = managerUIDFromDN(rec.ManagerDN)
+	rec.Status = StatusActive
+	if !rec.IsActive() {
+		rec.Status = StatusTerminated
 	}
+	return rec
 }
 
 // ReportSearchOptions configures FindDirectReports behavior.
@@ -116,173 +448,447 @@ type Identifier struct {
 
 // Constants for identifier types
 const (
-	IDTUID = iota
-	IDTEmail
+	IDTUID            = iota
+	IDTEmail          // mail
+	IDTUUID           // rhatUUID
+	IDTEmployeeNumber // employeeNumber (worker ID)
+	IDTKerberos       // krbPrincipalName, e.g. jdoe@REDHAT.COM
 )
 
 // NewSearcherFromEnv creates a searcher using environment variables
+//
+// Deprecated: Use NewSearcherWithOptions(ctx, WithEnv()), which takes a
+// context for the initial connect and further options.
 func NewSearcherFromEnv() (*Searcher, error) {
+	return NewSearcherWithOptions(context.Background(), WithEnv())
+}
+
+// configFromEnv returns the configuration in LDAP_* environment variables
+// alone, without config.yaml or defaults
+func configFromEnv() Config {
 	config := Config{
 		LdapServers: []string{os.Getenv("LDAP_URL")},
 		Username:    os.Getenv("LDAP_BIND_DN"),
-		Password:    GetPasswordFromEnv(),
+		Credentials: CredentialsFromEnv(),
 		BaseDN:      os.Getenv("LDAP_BASE_DN"),
 		UseStartTLS: os.Getenv("LDAP_START_TLS") == "true",
 		VerifySSL:   os.Getenv("LDAP_VERIFY_SSL") != "false",
+		AuthMode:    AuthMode(os.Getenv("LDAP_AUTH_MODE")),
+		LazyConnect: os.Getenv("LDAP_LAZY_CONNECT") == "true",
+
+		TLSServerName:  os.Getenv("LDAP_TLS_SERVER_NAME"),
+		CAFile:         os.Getenv("LDAP_CA_FILE"),
+		CACertPEM:      os.Getenv("LDAP_CA_CERT_PEM"),
+		ClientCertFile: os.Getenv("LDAP_CLIENT_CERT_FILE"),
+		ClientKeyFile:  os.Getenv("LDAP_CLIENT_KEY_FILE"),
+
+		SnapshotFile:    os.Getenv("LDAP_SNAPSHOT_FILE"),
+		OfflineFallback: os.Getenv("LDAP_OFFLINE_FALLBACK") == "true",
+
+		Offline:      os.Getenv("LDAP_OFFLINE") == "true",
+		FixturesFile: os.Getenv("LDAP_FIXTURES_FILE"),
+
+		SearchScope:  SearchScope(os.Getenv("LDAP_SEARCH_SCOPE")),
+		DerefAliases: AliasDeref(os.Getenv("LDAP_DEREF_ALIASES")),
+
+		AttributeProfile: AttributeProfile(os.Getenv("LDAP_ATTRIBUTE_PROFILE")),
 	}
-	return NewSearcher(config)
+	config.MaxReferralHops, _ = strconv.Atoi(os.Getenv("LDAP_MAX_REFERRAL_HOPS"))
+	config.SizeLimit, _ = strconv.Atoi(os.Getenv("LDAP_SIZE_LIMIT"))
+	config.TimeLimit, _ = time.ParseDuration(os.Getenv("LDAP_TIME_LIMIT"))
+	config.DialTimeout, _ = time.ParseDuration(os.Getenv("LDAP_DIAL_TIMEOUT"))
+	config.BindTimeout, _ = time.ParseDuration(os.Getenv("LDAP_BIND_TIMEOUT"))
+	config.SearchTimeout, _ = time.ParseDuration(os.Getenv("LDAP_SEARCH_TIMEOUT"))
+	return config
 }
 
-// NewSearcher creates a searcher with the given config
+// NewSearcher creates a searcher with the given config.
+//
+// If the directory is unreachable and Config.OfflineFallback is enabled with a
+// loadable Config.SnapshotFile, the searcher is returned without a connection
+// and serves stale snapshot results until Ping reconnects it.
+//
+// With Config.Offline, the searcher serves Config.FixturesFile instead of
+// connecting to Config.LdapServers; its Config names the fixture server.
+//
+// With Config.LazyConnect, NewSearcher only validates the config; the
+// searcher dials on first use. NewSearcherWithOptions builds the config
+// from options instead.
 func NewSearcher(config Config) (*Searcher, error) {
-	searcher := &Searcher{Config: config}
-	if len(config.LdapServers) == 0 {
-		return searcher, nil
+	return NewSearcherWithOptions(context.Background(), WithConfig(config))
+}
+
+// checkSearcherConfig rejects settings NewSearcher and Reload cannot use
+func checkSearcherConfig(config Config) error {
+	if err := validateFilterTemplates(config.FilterTemplates); err != nil {
+		return err
+	}
+	if _, err := config.SearchScope.ldapValue(); err != nil {
+		return err
+	}
+	if _, err := config.DerefAliases.ldapValue(); err != nil {
+		return err
+	}
+	if _, err := config.AttributeProfile.normalize(); err != nil {
+		return newError(ErrInvalidConfig, "%v", err)
+	}
+	if err := validateInterceptors(config.Interceptors); err != nil {
+		return err
+	}
+	if err := config.AuditRedaction.Validate(); err != nil {
+		return newError(ErrInvalidConfig, "invalid audit redaction: %v", err)
+	}
+	if config.Offline && config.FixturesFile == "" {
+		return newError(ErrInvalidConfig, "offline mode needs a fixtures file: set fixtures_file or LDAP_FIXTURES_FILE")
+	}
+	return nil
+}
+
+// Connect dials and binds if the searcher has no open connection, and is a
+// no-op otherwise. Call it to connect a LazyConnect searcher up front, e.g.
+// from a readiness check, rather than on its first lookup.
+func (s *Searcher) Connect(ctx context.Context) error {
+	if len(s.config().LdapServers) == 0 {
+		return fmt.Errorf("no LDAP servers configured")
+	}
+	s.pingMu.Lock()
+	defer s.pingMu.Unlock()
+	if conn := s.conn(); conn != nil && !conn.IsClosing() {
+		return nil
 	}
+	conn, name, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	s.setConn(conn, name)
+	return nil
+}
+
+// activeConn returns the current connection and its name, first connecting
+// a LazyConnect searcher that has none
+func (s *Searcher) activeConn(ctx context.Context) (*ldap.Conn, string, error) {
+	if conn, name := s.namedConn(); conn != nil {
+		return conn, name, nil
+	}
+	if !s.config().LazyConnect {
+		return nil, "", errNotConnected()
+	}
+	if err := s.Connect(ctx); err != nil {
+		return nil, "", err
+	}
+	conn, name := s.namedConn()
+	return conn, name, nil
+}
+
+// disconnected reports whether operations must fail with ErrNotConnected or
+// fall back to the snapshot, because the searcher has no connection and will
+// not dial one on demand
+func (s *Searcher) disconnected() bool {
+	return s.conn() == nil && !s.config().LazyConnect
+}
+
+// dial connects through the circuit breaker
+func (s *Searcher) dial(ctx context.Context) (*ldap.Conn, string, error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, "", err
+	}
+	conn, name, err := dial(ctx, s.config())
+	s.breaker.record(err != nil && s.unreachable(err))
+	return conn, name, err
+}
+
+// dial connects to the first configured server, negotiates TLS and binds. It
+// returns the connection's name, which its log records carry as "conn".
+func dial(ctx context.Context, config Config) (*ldap.Conn, string, error) {
 	ldapURL := config.LdapServers[0]
-	
-	// For ldaps:// URLs, use DialURL with custom TLS config if TLSServerName is set
-	var conn *ldap.Conn
-	var err error
-	if strings.HasPrefix(ldapURL, "ldaps://") && config.TLSServerName != "" {
-		serverName := config.TLSServerName
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: !config.VerifySSL,
-			ServerName:         serverName,
-		}
-		conn, err = ldap.DialURL(ldapURL, ldap.DialWithTLSConfig(tlsConfig))
-	} else {
-		conn, err = ldap.DialURL(ldapURL)
-	}
-	
+	isLDAPS := strings.HasPrefix(strings.ToLower(ldapURL), "ldaps://")
+	if isLDAPS && config.UseStartTLS {
+		return nil, "", fmt.Errorf("StartTLS cannot be used with ldaps:// URL %s", ldapURL)
+	}
+	if isLDAPI(ldapURL) && config.UseStartTLS {
+		return nil, "", fmt.Errorf("StartTLS cannot be used with ldapi:// URL %s", ldapURL)
+	}
+	authMode, err := config.AuthMode.normalize()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to LDAP server %s: %w", ldapURL, err)
+		return nil, "", err
 	}
-	if config.UseStartTLS {
-		serverName := config.TLSServerName
-		if serverName == "" {
-			serverName = ExtractHostname(ldapURL)
+	dialURL := ldapURL
+	if isLDAPI(ldapURL) {
+		if dialURL, err = ldapiDialURL(ldapURL); err != nil {
+			return nil, "", err
 		}
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: !config.VerifySSL,
-			ServerName:         serverName,
+	}
+
+	var tlsConfig *tls.Config
+	if isLDAPS || config.UseStartTLS {
+		tlsConfig, err = newTLSConfig(config, ldapURL)
+		if err != nil {
+			return nil, "", err
 		}
+	}
+
+	// ldaps:// negotiates TLS before any LDAP traffic; ldap:// may upgrade via StartTLS below
+	name := config.newConnName()
+	logger := config.logger().With(slog.String("conn", name))
+	config.Logger = logger
+	start := time.Now()
+	_, span := startSpan(ctx, config, "ldap.dial",
+		attribute.String("ldap.server", ldapURL),
+		attribute.Bool("ldap.start_tls", config.UseStartTLS),
+	)
+	var conn *ldap.Conn
+	dialOpts := []ldap.DialOpt{ldap.DialWithDialer(&net.Dialer{Timeout: config.dialTimeout()})}
+	if isLDAPS {
+		dialOpts = append(dialOpts, ldap.DialWithTLSConfig(tlsConfig))
+	}
+	conn, err = ldap.DialURL(dialURL, dialOpts...)
+	if err != nil {
+		logOperation(logger, "ldap dial", start, err, slog.String("server", ldapURL))
+		endSpan(span, err)
+		return nil, "", wrapLDAPError(err, "failed to connect to LDAP server %s", ldapURL)
+	}
+	conn.SetTimeout(config.bindTimeout())
+	if config.UseStartTLS {
 		err = conn.StartTLS(tlsConfig)
 		if err != nil {
+			logOperation(logger, "ldap dial", start, err, slog.String("server", ldapURL), slog.Bool("start_tls", true))
+			endSpan(span, err)
 			conn.Close()
-			return nil, fmt.Errorf("failed to start TLS: %w", err)
+			return nil, "", wrapLDAPError(err, "failed to start TLS")
 		}
 	}
-	if config.Username != "" && config.Password != "" {
-		err = conn.Bind(config.Username, config.Password)
-		if err != nil {
+	logOperation(logger, "ldap dial", start, nil, slog.String("server", ldapURL), slog.Bool("start_tls", config.UseStartTLS))
+	endSpan(span, nil)
+	switch {
+	case authMode == AuthExternal:
+		start = time.Now()
+		_, span = startSpan(ctx, config, "ldap.bind",
+			attribute.String("ldap.server", ldapURL),
+			attribute.String("ldap.auth_mode", string(authMode)),
+		)
+		err = conn.ExternalBind()
+		logOperation(logger, "ldap bind", start, err, slog.String("auth_mode", string(authMode)))
+		endSpan(span, err)
+	case config.Username != "" && config.HasCredentials():
+		var password string
+		if password, err = config.bindPassword(ctx); err != nil {
 			conn.Close()
-			return nil, fmt.Errorf("failed to bind to LDAP: %w", err)
+			return nil, "", err
 		}
+		err = simpleBind(ctx, conn, config, ldapURL, name, password)
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			err = rebindIfRotated(ctx, conn, config, ldapURL, name, password, err)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, "", wrapLDAPError(err, "failed to bind to LDAP")
+	}
+	conn.SetTimeout(config.searchTimeout())
+	return conn, name, nil
+}
+
+// defaultBaseDN is searched when Config.BaseDN is empty
+const defaultBaseDN = "ou=users,dc=redhat,dc=com"
+
+// baseDN returns the configured search base or defaultBaseDN
+func (s *Searcher) baseDN() string {
+	if s.config().BaseDN != "" {
+		return s.config().BaseDN
 	}
-	searcher.Conn = conn
-	return searcher, nil
+	return defaultBaseDN
 }
 
+// Close closes the connection, and in offline mode stops the fixture server.
+// Calls in flight on other goroutines fail.
 func (s *Searcher) Close() error {
-	if s.Conn != nil {
-		s.Conn.Close()
+	if conn := s.conn(); conn != nil {
+		conn.Close()
+	}
+	s.mu.RLock()
+	fixtures := s.fixtures
+	s.mu.RUnlock()
+	if fixtures != nil {
+		fixtures.Close()
 	}
 	return nil
 }
 
 func (s *Searcher) GetUser(ctx context.Context, id Identifier) (UserRecord, error) {
-	if s.Conn == nil {
-		return UserRecord{}, fmt.Errorf("LDAP connection not established")
-	}
-	var filter string
-	switch id.Type {
-	case IDTUID:
-		filter = fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(id.Value))
-	case IDTEmail:
-		filter = fmt.Sprintf("(mail=%s)", ldap.EscapeFilter(id.Value))
-	default:
-		return UserRecord{}, fmt.Errorf("unknown identifier type: %d", id.Type)
+	rec, err := s.getUser(ctx, id)
+	s.auditLookup(ctx, AuditGetUser, id, err)
+	return rec, err
+}
+
+// getUser is GetUser without auditing
+func (s *Searcher) getUser(ctx context.Context, id Identifier) (UserRecord, error) {
+	if s.disconnected() {
+		if s.offlineEnabled() {
+			return s.offlineUser(ctx, id)
+		}
+		return UserRecord{}, errNotConnected()
 	}
-	baseDN := s.Config.BaseDN
-	if baseDN == "" {
-		baseDN = "ou=users,dc=redhat,dc=com"
+	filter, err := s.identifierFilter(id)
+	if err != nil {
+		return UserRecord{}, err
 	}
-	result, err := s.Conn.Search(ldap.NewSearchRequest(
-		baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
-		0, 0, false, filter, userAttributes, nil,
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return UserRecord{}, err
+	}
+	start := time.Now()
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		0, 0, false, filter, s.attributes(ctx), nil,
 	))
 	if err != nil {
-		return UserRecord{}, fmt.Errorf("LDAP search failed: %w", err)
+		if s.offlineEnabled() && s.unreachable(err) {
+			return s.offlineUser(ctx, id)
+		}
+		return UserRecord{}, wrapLDAPError(err, "LDAP search failed")
+	}
+	if len(result.Entries) == 0 && includeDeleted(ctx) {
+		if result, err = s.searchDeleted(ctx, baseDN, filter, s.attributes(ctx)); err != nil {
+			return UserRecord{}, wrapLDAPError(err, "LDAP search of deleted users failed")
+		}
 	}
 	if len(result.Entries) == 0 {
-		return UserRecord{}, fmt.Errorf("user not found in LDAP directory: %s", id.Value)
+		return UserRecord{}, newError(ErrUserNotFound, "user not found in LDAP directory: %s", id.Value)
 	}
-	return entryToUserRecord(result.Entries[0]), nil
+	if len(result.Entries) > 1 {
+		return UserRecord{}, newError(ErrMultipleMatches, "%d LDAP entries match %s", len(result.Entries), id.Value)
+	}
+	rec := s.userRecord(ctx, result.Entries[0])
+	rec.meta = s.recordMeta(start)
+	if err := s.resolvePeopleManager(ctx, result.Entries[0], &rec); err != nil {
+		return UserRecord{}, err
+	}
+	s.redact(ctx, &rec)
+	return rec, nil
 }
 
 // GetUsers performs a batch lookup of multiple identifiers in a single call.
 // Returns results in the same order as the input; missing users have empty UID.
+// Identifiers with a Config.FilterTemplates entry are looked up one by one,
+// since results cannot be matched back to them by uid or mail.
+//
+// If only some identifiers fail, e.g. one has an unknown type or its
+// templated lookup times out, the other results are still returned together
+// with a *BatchError listing the failures. Errors that affect the whole
+// batch, such as the search failing, are returned with nil results.
 func (s *Searcher) GetUsers(ctx context.Context, ids []Identifier) ([]UserRecord, error) {
+	out, err := s.getUsers(ctx, ids)
+	s.auditBatch(ctx, ids, out, err)
+	return out, err
+}
+
+// getUsers is GetUsers without auditing
+func (s *Searcher) getUsers(ctx context.Context, ids []Identifier) ([]UserRecord, error) {
 	if len(ids) == 0 {
 		return nil, nil
 	}
-	if s.Conn == nil {
-		return nil, fmt.Errorf("LDAP connection not established")
+	if s.disconnected() {
+		if s.offlineEnabled() {
+			return s.offlineUsers(ctx, ids)
+		}
+		return nil, errNotConnected()
 	}
 
 	var parts []string
-	for _, id := range ids {
-		switch id.Type {
-		case IDTUID:
-			parts = append(parts, fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(id.Value)))
-		case IDTEmail:
-			parts = append(parts, fmt.Sprintf("(mail=%s)", ldap.EscapeFilter(id.Value)))
-		default:
-			return nil, fmt.Errorf("unknown identifier type: %d", id.Type)
+	var templated []int // indexes of ids looked up one by one
+	var failed []ItemError
+	for i, id := range ids {
+		if _, ok := s.customFilterTemplate(id.Type); ok {
+			templated = append(templated, i)
+			continue
 		}
+		filter, err := s.identifierFilter(id)
+		if err != nil {
+			failed = append(failed, ItemError{Index: i, Item: id.Value, Err: err})
+			continue
+		}
+		parts = append(parts, filter)
 	}
 
-	filter := fmt.Sprintf("(|%s)", strings.Join(parts, ""))
-	baseDN := s.Config.BaseDN
-	if baseDN == "" {
-		baseDN = "ou=users,dc=redhat,dc=com"
-	}
-	result, err := s.Conn.Search(ldap.NewSearchRequest(
-		baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
-		0, 0, false, filter, userAttributes, nil,
-	))
-	if err != nil {
-		return nil, fmt.Errorf("LDAP batch search failed: %w", err)
-	}
-
-	byUID := map[string]UserRecord{}
-	byEmail := map[string]UserRecord{}
-	for _, entry := range result.Entries {
-		rec := entryToUserRecord(entry)
-		byUID[rec.UID] = rec
-		if rec.Email != "" {
-			byEmail[strings.ToLower(rec.Email)] = rec
+	found := map[int]map[string]UserRecord{} // identifier type -> key -> record
+	if len(parts) > 0 {
+		filter := fmt.Sprintf("(|%s)", strings.Join(parts, ""))
+		baseDN, err := s.searchBase(ctx)
+		if err != nil {
+			return nil, err
+		}
+		attrs := withIdentifierAttributes(s.attributes(ctx), ids)
+		start := time.Now()
+		result, err := s.search(ctx, ldap.NewSearchRequest(
+			baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+			0, 0, false, filter, attrs, nil,
+		))
+		if err != nil {
+			if s.offlineEnabled() && s.unreachable(err) {
+				return s.offlineUsers(ctx, ids)
+			}
+			return nil, wrapLDAPError(err, "LDAP batch search failed")
+		}
+		entries := result.Entries
+		if includeDeleted(ctx) {
+			deleted, err := s.searchDeleted(ctx, baseDN, filter, attrs)
+			if err != nil {
+				return nil, wrapLDAPError(err, "LDAP batch search of deleted users failed")
+			}
+			entries = append(entries, deleted.Entries...)
+		}
+		meta := s.recordMeta(start)
+		for _, entry := range entries {
+			rec := s.userRecord(ctx, entry)
+			rec.meta = meta
+			if err := s.resolvePeopleManager(ctx, entry, &rec); err != nil {
+				return nil, err
+			}
+			for idType, key := range recordIdentifiers(&rec) {
+				if found[idType] == nil {
+					found[idType] = map[string]UserRecord{}
+				}
+				// A rehired user's current entry wins over the deleted one
+				if _, ok := found[idType][key]; ok && rec.Status == StatusDeleted {
+					continue
+				}
+				found[idType][key] = rec
+			}
 		}
 	}
 
 	out := make([]UserRecord, len(ids))
 	for i, id := range ids {
-		switch id.Type {
-		case IDTUID:
-			out[i] = byUID[id.Value]
-		case IDTEmail:
-			out[i] = byEmail[strings.ToLower(id.Value)]
+		key, _ := identifierKey(id.Type, id.Value)
+		out[i] = found[id.Type][key]
+		s.redact(ctx, &out[i])
+	}
+	for _, i := range templated {
+		rec, err := s.getUser(ctx, ids[i])
+		if err != nil && !errors.Is(err, ErrUserNotFound) {
+			failed = append(failed, ItemError{Index: i, Item: ids[i].Value, Err: err})
+			continue
 		}
+		out[i] = rec
 	}
-	return out, nil
+	slices.SortFunc(failed, func(a, b ItemError) int { return a.Index - b.Index })
+	return out, newBatchError(len(ids), failed)
 }
 
 // FindDirectReports returns all users whose LDAP manager attribute points to managerUID.
 // Use opts to exclude Works Council countries or enable recursive subtree traversal.
+// When the search stops at a size limit, the reports received before it are
+// returned with a *PartialResultsError.
 func (s *Searcher) FindDirectReports(ctx context.Context, managerUID string, opts ...ReportSearchOptions) ([]UserRecord, error) {
-	if s.Conn == nil {
-		return nil, fmt.Errorf("LDAP connection not established")
+	reports, err := s.findDirectReports(ctx, managerUID, opts...)
+	s.auditReports(ctx, AuditFindDirectReports, managerUID, len(reports), err)
+	return reports, err
+}
+
+// findDirectReports is FindDirectReports without auditing
+func (s *Searcher) findDirectReports(ctx context.Context, managerUID string, opts ...ReportSearchOptions) ([]UserRecord, error) {
+	if s.disconnected() {
+		return nil, errNotConnected()
 	}
 
 	var opt ReportSearchOptions
@@ -290,25 +896,29 @@ func (s *Searcher) FindDirectReports(ctx context.Context, managerUID string, opt
 		opt = opts[0]
 	}
 
-	baseDN := s.Config.BaseDN
-	if baseDN == "" {
-		baseDN = "ou=users,dc=redhat,dc=com"
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	reports, err := s.findReportsForUID(ctx, managerUID, baseDN, opt.ExcludeCountries)
-	if err != nil {
+	if err != nil && asPartial(err) == nil {
 		return nil, err
 	}
 
 	if !opt.Recursive {
-		return reports, nil
+		return reports, err
 	}
 
-	return s.walkReports(ctx, reports, baseDN, opt, 1)
+	all, walkErr := s.walkReports(ctx, reports, baseDN, opt, 1)
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	return all, err
 }
 
 func (s *Searcher) findReportsForUID(ctx context.Context, managerUID, baseDN string, excludeCountries []string) ([]UserRecord, error) {
-	managerDN := fmt.Sprintf("uid=%s,ou=users,dc=redhat,dc=com", ldap.EscapeFilter(managerUID))
+	managerDN := managerDNForUID(managerUID)
 
 	var wcFilter string
 	for _, cc := range excludeCountries {
@@ -317,19 +927,24 @@ func (s *Searcher) findReportsForUID(ctx context.Context, managerUID, baseDN str
 
 	filter := fmt.Sprintf("(&(manager=%s)%s)", managerDN, wcFilter)
 
-	result, err := s.Conn.Search(ldap.NewSearchRequest(
-		baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
-		0, 0, false, filter, userAttributes, nil,
+	start := time.Now()
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		s.sizeLimit(ctx), 0, false, filter, s.attributes(ctx), nil,
 	))
-	if err != nil {
-		return nil, fmt.Errorf("LDAP direct reports search failed for %s: %w", managerUID, err)
+	if err != nil && asPartial(err) == nil {
+		return nil, wrapLDAPError(err, "LDAP direct reports search failed for %s", managerUID)
 	}
 
+	meta := s.recordMeta(start)
 	var records []UserRecord
 	for _, entry := range result.Entries {
-		records = append(records, entryToUserRecord(entry))
+		rec := s.userRecord(ctx, entry)
+		rec.meta = meta
+		s.redact(ctx, &rec)
+		records = append(records, rec)
 	}
-	return records, nil
+	return records, err
 }
 
 func (s *Searcher) walkReports(ctx context.Context, current []UserRecord, baseDN string, opt ReportSearchOptions, depth int) ([]UserRecord, error) {
@@ -345,7 +960,7 @@ func (s *Searcher) walkReports(ctx context.Context, current []UserRecord, baseDN
 			continue
 		}
 		children, err := s.findReportsForUID(ctx, u.UID, baseDN, opt.ExcludeCountries)
-		if err != nil {
+		if err != nil && asPartial(err) == nil {
 			continue
 		}
 		if len(children) > 0 {
@@ -387,20 +1002,35 @@ func LoadConfigFromAll() Config {
 		}
 	}
 
-	// Password: YAML password_file → LDAP_PASSWORD_FILE → LDAP_PASSWORD → error
-	if config.Password == "" {
+	if config.DeletedUsersBaseDN == "" {
+		config.DeletedUsersBaseDN = os.Getenv("LDAP_DELETED_USERS_BASE_DN")
+	}
+
+	// Password: YAML credentials → YAML password_file → LDAP_CREDENTIALS →
+	// LDAP_PASSWORD_FILE → LDAP_PASSWORD → Kubernetes Secret → error
+	if config.SecretFilePermissions == "" {
+		config.SecretFilePermissions = secretFilePolicyFromEnv()
+	}
+	if !config.HasCredentials() {
+		if reference := os.Getenv("LDAP_CREDENTIALS"); reference != "" {
+			config.Credentials = loadCredentials(reference, config.SecretFilePermissions)
+		}
+	}
+	if !config.HasCredentials() {
 		if passwordFile := os.Getenv("LDAP_PASSWORD_FILE"); passwordFile != "" {
-			if password := ReadSecretFile(passwordFile); password != "" {
-				config.Password = password
-			}
+			config.Credentials = passwordFileSource(passwordFile, config.SecretFilePermissions)
 		}
-		if config.Password == "" {
+		if !config.HasCredentials() {
 			if password := os.Getenv("LDAP_PASSWORD"); password != "" {
 				config.Password = password
 			}
 		}
 	}
 
+	if config.AuthMode == "" {
+		config.AuthMode = AuthMode(os.Getenv("LDAP_AUTH_MODE"))
+	}
+
 	// 3. Set defaults for boolean flags if not set in YAML
 	if os.Getenv("LDAP_START_TLS") != "" {
 		config.UseStartTLS = os.Getenv("LDAP_START_TLS") == "true"
@@ -410,6 +1040,95 @@ func LoadConfigFromAll() Config {
 		config.VerifySSL = os.Getenv("LDAP_VERIFY_SSL") == "true"
 	}
 
+	if os.Getenv("LDAP_LAZY_CONNECT") != "" {
+		config.LazyConnect = os.Getenv("LDAP_LAZY_CONNECT") == "true"
+	}
+
+	// 4. TLS material
+	if config.TLSServerName == "" {
+		config.TLSServerName = os.Getenv("LDAP_TLS_SERVER_NAME")
+	}
+	if config.CAFile == "" {
+		config.CAFile = os.Getenv("LDAP_CA_FILE")
+	}
+	if config.CACertPEM == "" {
+		config.CACertPEM = os.Getenv("LDAP_CA_CERT_PEM")
+	}
+	if config.ClientCertFile == "" {
+		config.ClientCertFile = os.Getenv("LDAP_CLIENT_CERT_FILE")
+	}
+	if config.ClientKeyFile == "" {
+		config.ClientKeyFile = os.Getenv("LDAP_CLIENT_KEY_FILE")
+	}
+
+	// 5. Offline snapshot fallback
+	if config.SnapshotFile == "" {
+		config.SnapshotFile = os.Getenv("LDAP_SNAPSHOT_FILE")
+	}
+	if os.Getenv("LDAP_OFFLINE_FALLBACK") != "" {
+		config.OfflineFallback = os.Getenv("LDAP_OFFLINE_FALLBACK") == "true"
+	}
+	if config.FixturesFile == "" {
+		config.FixturesFile = os.Getenv("LDAP_FIXTURES_FILE")
+	}
+	if os.Getenv("LDAP_OFFLINE") != "" {
+		config.Offline = os.Getenv("LDAP_OFFLINE") == "true"
+	}
+
+	// 6. Circuit breaker
+	if config.BreakerFailureThreshold == 0 {
+		config.BreakerFailureThreshold, _ = strconv.Atoi(os.Getenv("LDAP_BREAKER_THRESHOLD"))
+	}
+	if config.BreakerOpenTimeout == 0 {
+		config.BreakerOpenTimeout, _ = time.ParseDuration(os.Getenv("LDAP_BREAKER_OPEN_TIMEOUT"))
+	}
+
+	// 7. Search scope, aliases, referrals and limits
+	if config.SearchScope == "" {
+		config.SearchScope = SearchScope(os.Getenv("LDAP_SEARCH_SCOPE"))
+	}
+	if config.DerefAliases == "" {
+		config.DerefAliases = AliasDeref(os.Getenv("LDAP_DEREF_ALIASES"))
+	}
+	if config.MaxReferralHops == 0 {
+		config.MaxReferralHops, _ = strconv.Atoi(os.Getenv("LDAP_MAX_REFERRAL_HOPS"))
+	}
+	if config.SizeLimit == 0 {
+		config.SizeLimit, _ = strconv.Atoi(os.Getenv("LDAP_SIZE_LIMIT"))
+	}
+	if config.TimeLimit == 0 {
+		config.TimeLimit, _ = time.ParseDuration(os.Getenv("LDAP_TIME_LIMIT"))
+	}
+	if config.AttributeProfile == "" {
+		config.AttributeProfile = AttributeProfile(os.Getenv("LDAP_ATTRIBUTE_PROFILE"))
+	}
+
+	// 8. Timeouts
+	if config.DialTimeout == 0 {
+		config.DialTimeout, _ = time.ParseDuration(os.Getenv("LDAP_DIAL_TIMEOUT"))
+	}
+	if config.BindTimeout == 0 {
+		config.BindTimeout, _ = time.ParseDuration(os.Getenv("LDAP_BIND_TIMEOUT"))
+	}
+	if config.SearchTimeout == 0 {
+		config.SearchTimeout, _ = time.ParseDuration(os.Getenv("LDAP_SEARCH_TIMEOUT"))
+	}
+
+	// 9. Tracing
+	if os.Getenv("LDAP_ENABLE_TRACING") != "" {
+		config.EnableTracing = os.Getenv("LDAP_ENABLE_TRACING") == "true"
+	}
+
+	// 10. Client identification
+	if config.ClientName == "" {
+		config.ClientName = os.Getenv("LDAP_CLIENT_NAME")
+	}
+
+	// 11. Bind Secret mounted in the pod, for what is still unset
+	if InCluster() {
+		applyKubernetesSecret(&config, kubernetesSecretDir())
+	}
+
 	return config
 }
 
@@ -417,14 +1136,7 @@ func LoadConfigFromAll() Config {
 func loadYAMLConfig() *Config {
 	env := GetEnvironment()
 
-	// Try multiple config file locations
-	configPaths := []string{
-		"config.yaml",
-		"configs/config.yaml",
-		filepath.Join(os.Getenv("HOME"), ".config", "ldap", "config.yaml"),
-	}
-
-	for _, configPath := range configPaths {
+	for _, configPath := range configFilePaths() {
 		if config := tryLoadYAMLFile(configPath, env); config != nil {
 			return config
 		}
@@ -433,6 +1145,20 @@ func loadYAMLConfig() *Config {
 	return nil
 }
 
+// configFilePaths lists the config file locations tried, in order, ending
+// with the ConfigMap mount when running in a pod
+func configFilePaths() []string {
+	paths := []string{
+		"config.yaml",
+		"configs/config.yaml",
+		filepath.Join(os.Getenv("HOME"), ".config", "ldap", "config.yaml"),
+	}
+	if InCluster() {
+		paths = append(paths, filepath.Join(kubernetesConfigDir(), "config.yaml"))
+	}
+	return paths
+}
+
 // tryLoadYAMLFile attempts to load and parse a YAML config file
 func tryLoadYAMLFile(configPath, env string) *Config {
 	data, err := os.ReadFile(configPath)
@@ -451,29 +1177,82 @@ func tryLoadYAMLFile(configPath, env string) *Config {
 	}
 
 	config := &Config{
-		LdapServers: envConfig.LdapServers,
-		Username:    envConfig.Username,
-		BaseDN:      envConfig.BaseDN,
-		UseStartTLS: envConfig.UseStartTLS,
-		VerifySSL:   envConfig.VerifySSL,
+		LdapServers:    envConfig.LdapServers,
+		Username:       envConfig.Username,
+		BaseDN:         envConfig.BaseDN,
+		UseStartTLS:    envConfig.UseStartTLS,
+		VerifySSL:      envConfig.VerifySSL,
+		AuthMode:       envConfig.AuthMode,
+		LazyConnect:    envConfig.LazyConnect,
+		DialTimeout:    envConfig.DialTimeout,
+		BindTimeout:    envConfig.BindTimeout,
+		SearchTimeout:  envConfig.SearchTimeout,
+		TLSServerName:  envConfig.TLSServerName,
+		CAFile:         expandHome(envConfig.CAFile),
+		CACertPEM:      envConfig.CACertPEM,
+		ClientCertFile: expandHome(envConfig.ClientCertFile),
+		ClientKeyFile:  expandHome(envConfig.ClientKeyFile),
+
+		DeletedUsersBaseDN: envConfig.DeletedUsersBaseDN,
+
+		SnapshotFile:    expandHome(envConfig.SnapshotFile),
+		OfflineFallback: envConfig.OfflineFallback,
+
+		Offline:      envConfig.Offline,
+		FixturesFile: expandHome(envConfig.FixturesFile),
+
+		BreakerFailureThreshold: envConfig.BreakerFailureThreshold,
+		BreakerOpenTimeout:      envConfig.BreakerOpenTimeout,
+
+		ClientName:    envConfig.ClientName,
+		EnableTracing: envConfig.EnableTracing,
+
+		FilterTemplates: envConfig.FilterTemplates,
+
+		SearchScope:     envConfig.SearchScope,
+		DerefAliases:    envConfig.DerefAliases,
+		MaxReferralHops: envConfig.MaxReferralHops,
+		SizeLimit:       envConfig.SizeLimit,
+		TimeLimit:       envConfig.TimeLimit,
+
+		AttributeProfile: envConfig.AttributeProfile,
+
+		SecretFilePermissions: envConfig.SecretFilePermissions,
+	}
+	if config.SecretFilePermissions == "" {
+		config.SecretFilePermissions = secretFilePolicyFromEnv()
 	}
 
-	// Load password from YAML-specified file if configured
-	if envConfig.PasswordFile != "" {
-		// Expand ~ to home directory
-		passwordPath := envConfig.PasswordFile
-		if strings.HasPrefix(passwordPath, "~/") {
-			homeDir, _ := os.UserHomeDir()
-			passwordPath = filepath.Join(homeDir, passwordPath[2:])
-		}
-		if password := ReadSecretFile(passwordPath); password != "" {
-			config.Password = password
+	// Unknown gates are ignored so older binaries accept newer config files
+	for name, enabled := range envConfig.FeatureGates {
+		if _, known := featureDefaults[Feature(name)]; known {
+			if config.FeatureGates == nil {
+				config.FeatureGates = map[Feature]bool{}
+			}
+			config.FeatureGates[Feature(name)] = enabled
 		}
 	}
 
+	// Load password from the YAML credentials reference or password file
+	if envConfig.Credentials != "" {
+		config.Credentials = loadCredentials(envConfig.Credentials, config.SecretFilePermissions)
+	}
+	if !config.HasCredentials() && envConfig.PasswordFile != "" {
+		config.Credentials = passwordFileSource(envConfig.PasswordFile, config.SecretFilePermissions)
+	}
+
 	return config
 }
 
+// expandHome expands a leading ~/ to the user's home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[2:])
+	}
+	return path
+}
+
 // getEnvironment returns the current environment (local, dev, prod)
 func GetEnvironment() string {
 	if env := os.Getenv("LDAP_ENV"); env != "" {
@@ -485,27 +1264,26 @@ func GetEnvironment() string {
 	return "local" // default
 }
 
-// ReadSecretFile safely reads a secret file and returns its contents
-func ReadSecretFile(path string) string {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return ""
-	}
-	return strings.TrimSpace(string(data))
-}
-
-// NewSearcherWithDefaults creates a searcher using the auto-loaded default config
+// NewSearcherWithDefaults creates a searcher using the default config, loading
+// it first if needed (see LoadDefaultConfig)
 func NewSearcherWithDefaults() (*Searcher, error) {
-	if DefaultConfig.Password == "" {
+	config := LoadDefaultConfig()
+	if mode, _ := config.AuthMode.normalize(); !config.HasCredentials() && mode != AuthExternal {
 		return nil, fmt.Errorf("no LDAP password found in secrets or environment variables")
 	}
-	if len(DefaultConfig.LdapServers) == 0 {
+	if len(config.LdapServers) == 0 {
 		return nil, fmt.Errorf("no LDAP_URL found in environment variables")
 	}
-	return NewSearcher(DefaultConfig)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewSearcher(config)
 }
 
 // GetPasswordFromEnv loads password from LDAP_PASSWORD_FILE or LDAP_PASSWORD
+//
+// Deprecated: Use CredentialsFromEnv, whose source rereads a rotated
+// LDAP_PASSWORD_FILE at every bind instead of keeping the password.
 func GetPasswordFromEnv() string {
 	// Try LDAP_PASSWORD_FILE first
 	if passwordFile := os.Getenv("LDAP_PASSWORD_FILE"); passwordFile != "" {
@@ -517,6 +1295,20 @@ func GetPasswordFromEnv() string {
 	return os.Getenv("LDAP_PASSWORD")
 }
 
+// CredentialsFromEnv returns a PasswordFile for LDAP_PASSWORD_FILE, falling
+// back to LDAP_PASSWORD, or nil when neither is usable
+func CredentialsFromEnv() CredentialSource {
+	if passwordFile := os.Getenv("LDAP_PASSWORD_FILE"); passwordFile != "" {
+		if source := passwordFileSource(passwordFile, secretFilePolicyFromEnv()); source != nil {
+			return source
+		}
+	}
+	if password := os.Getenv("LDAP_PASSWORD"); password != "" {
+		return StaticPassword(password)
+	}
+	return nil
+}
+
 // extractHostname extracts hostname from LDAP URL for TLS ServerName
 func ExtractHostname(ldapURL string) string {
 	// Remove protocol prefix
@@ -12,8 +12,8 @@ func TestVersion(t *testing.T) {
 	if ldap_redhat.Version == "" {
 		t.Error("ldap_redhat.Version should not be empty")
 	}
-	if ldap_redhat.Version != "v1.3.0" {
-		t.Errorf("Expected version v1.3.0, got %s", ldap_redhat.Version)
+	if ldap_redhat.Version != "v1.4.0" {
+		t.Errorf("Expected version v1.4.0, got %s", ldap_redhat.Version)
 	}
 }
 
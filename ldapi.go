@@ -0,0 +1,78 @@
+package ldap_redhat
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// AuthMode selects how a searcher authenticates after connecting.
+type AuthMode string
+
+const (
+	// AuthSimple binds with Config.Username and Config.Password, or stays
+	// anonymous when either is empty. It is the default.
+	AuthSimple AuthMode = "simple"
+	// AuthExternal binds with SASL EXTERNAL: the server identifies the
+	// client from the connection, i.e. the peer credentials of an ldapi://
+	// socket or a TLS client certificate. Username and Password are unused.
+	AuthExternal AuthMode = "external"
+)
+
+// normalize maps the empty mode to AuthSimple and rejects unknown modes
+func (m AuthMode) normalize() (AuthMode, error) {
+	switch AuthMode(strings.ToLower(string(m))) {
+	case "", AuthSimple:
+		return AuthSimple, nil
+	case AuthExternal:
+		return AuthExternal, nil
+	}
+	return "", fmt.Errorf("unknown auth_mode %q: expected %q or %q", m, AuthSimple, AuthExternal)
+}
+
+// defaultLDAPISocket is dialed for an ldapi:// URL without a path, as in
+// OpenLDAP and go-ldap
+const defaultLDAPISocket = "/var/run/slapd/ldapi"
+
+// isLDAPI reports whether ldapURL is an LDAP over Unix domain socket URL
+func isLDAPI(ldapURL string) bool {
+	return strings.HasPrefix(strings.ToLower(ldapURL), "ldapi://")
+}
+
+// LDAPISocketPath returns the Unix socket path of an ldapi:// URL. Both the
+// OpenLDAP form, with the path percent-encoded as the host
+// (ldapi://%2Fvar%2Frun%2Fslapd%2Fldapi), and the path form
+// (ldapi:///var/run/slapd/ldapi) are accepted; an empty path means the
+// default /var/run/slapd/ldapi.
+func LDAPISocketPath(ldapURL string) (string, error) {
+	if !isLDAPI(ldapURL) {
+		return "", fmt.Errorf("not an ldapi:// URL: %s", ldapURL)
+	}
+	rest := ldapURL[len("ldapi://"):]
+	// Drop the DN, attributes and so on of a full LDAP URL
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		rest = rest[:i]
+	}
+	if !strings.HasPrefix(rest, "/") {
+		host, _, _ := strings.Cut(rest, "/")
+		path, err := url.PathUnescape(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid ldapi:// URL %s: %w", ldapURL, err)
+		}
+		rest = path
+	}
+	if rest == "" || rest == "/" {
+		return defaultLDAPISocket, nil
+	}
+	return rest, nil
+}
+
+// ldapiDialURL rewrites an ldapi:// URL into the path form go-ldap dials,
+// which cannot parse the percent-encoded OpenLDAP form
+func ldapiDialURL(ldapURL string) (string, error) {
+	path, err := LDAPISocketPath(ldapURL)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "ldapi", Path: path}).String(), nil
+}
@@ -0,0 +1,80 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestLDAPISocketPath(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"ldapi://%2Fvar%2Frun%2Fproxy.sock", "/var/run/proxy.sock"},
+		{"LDAPI://%2ftmp%2fldapi", "/tmp/ldapi"},
+		{"ldapi:///run/ldap/ldapi", "/run/ldap/ldapi"},
+		{"ldapi://%2Frun%2Fldapi/dc=redhat,dc=com?uid", "/run/ldapi"},
+		{"ldapi://", "/var/run/slapd/ldapi"},
+		{"ldapi:///", "/var/run/slapd/ldapi"},
+	}
+	for _, tt := range tests {
+		got, err := ldap_redhat.LDAPISocketPath(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("LDAPISocketPath(%q) = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"ldap://localhost", "ldapi://%zz"} {
+		if _, err := ldap_redhat.LDAPISocketPath(bad); err == nil {
+			t.Errorf("LDAPISocketPath(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestLDAPIExternalBind(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ldapi:// needs Unix domain sockets")
+	}
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(3))
+	if err := srv.StartUnix(filepath.Join(t.TempDir(), "ldapi")); err != nil {
+		t.Fatalf("Failed to start embedded LDAP server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		BaseDN:      testserver.UsersBaseDN,
+		AuthMode:    ldap_redhat.AuthExternal,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect over ldapi://: %v", err)
+	}
+	defer searcher.Close()
+	user, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)})
+	if err != nil || user.UID != testserver.UserUID(2) {
+		t.Errorf("GetUser over ldapi:// returned %q, %v", user.UID, err)
+	}
+
+	_, err = ldap_redhat.NewSearcher(ldap_redhat.Config{LdapServers: []string{srv.URL()}, UseStartTLS: true})
+	if err == nil {
+		t.Errorf("Expected StartTLS over ldapi:// to be rejected")
+	}
+	_, err = ldap_redhat.NewSearcher(ldap_redhat.Config{LdapServers: []string{srv.URL()}, AuthMode: "kerberos"})
+	if err == nil {
+		t.Errorf("Expected an unknown auth mode to be rejected")
+	}
+
+	// Over TCP the server cannot identify the client
+	tcp := startEmbeddedServer(t, 3)
+	_, err = ldap_redhat.NewSearcher(ldap_redhat.Config{LdapServers: []string{tcp.URL()}, AuthMode: ldap_redhat.AuthExternal})
+	if !errors.Is(err, ldap_redhat.ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed for EXTERNAL over TCP, got %v", err)
+	}
+}
@@ -0,0 +1,703 @@
+// Package ldaptest provides an in-memory stand-in for *ldap_redhat.Searcher,
+// so code that looks up users and groups can be unit tested without a
+// directory.
+//
+// Code under test accepts ldap_redhat.UserSearcher, the Searcher interface
+// or a narrower interface of its own instead of *ldap_redhat.Searcher; tests pass a FakeSearcher
+// seeded with the users and groups they need:
+//
+//	fake := ldaptest.NewFakeSearcher(
+//		ldap_redhat.UserRecord{UID: "jdoe", Email: "jdoe@redhat.com", ManagerUID: "boss"},
+//		ldap_redhat.UserRecord{UID: "boss", Email: "boss@redhat.com"},
+//	)
+//	fake.AddGroup(ldap_redhat.Group{Name: "openshift-eng"}, "jdoe")
+//	fake.SetError("GetUsers", ldap_redhat.ErrTimeout)
+//
+// GenerateUsers produces consistent fake users for larger tests, and
+// OfflineConfig serves them to a real searcher. RecordOrReplay runs tests
+// against recorded directory traffic.
+package ldaptest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+)
+
+// Searcher is the part of *ldap_redhat.Searcher that FakeSearcher
+// implements.
+type Searcher interface {
+	ldap_redhat.UserSearcher
+	ForEachUser(ctx context.Context, filter string, fn func(ldap_redhat.UserRecord) error) error
+	Users(ctx context.Context, filter string) iter.Seq2[ldap_redhat.UserRecord, error]
+	TakeSnapshot(ctx context.Context, filter string) (*ldap_redhat.Snapshot, error)
+	FindDirectReports(ctx context.Context, managerUID string, opts ...ldap_redhat.ReportSearchOptions) ([]ldap_redhat.UserRecord, error)
+	GetUsersByCostCenter(ctx context.Context, costCenter string, opts ...ldap_redhat.CostCenterOptions) ([]ldap_redhat.UserRecord, error)
+	IsPeopleManager(ctx context.Context, managerUID string) (bool, error)
+	ManagerChain(ctx context.Context, uid string) ([]ldap_redhat.UserRecord, error)
+	GetUserIncludingDeleted(ctx context.Context, id ldap_redhat.Identifier) (ldap_redhat.UserRecord, error)
+	GetUserGroups(ctx context.Context, uid string) ([]ldap_redhat.Group, error)
+	GetGroupMembers(ctx context.Context, name string) ([]string, error)
+	MapEmailsToUIDs(ctx context.Context, emails []string) (map[string]string, []string, error)
+	MapUIDsToEmails(ctx context.Context, uids []string) (map[string]ldap_redhat.EmailAddresses, []string, error)
+	Authenticate(ctx context.Context, id ldap_redhat.Identifier, password string) (ldap_redhat.UserRecord, error)
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ Searcher = (*ldap_redhat.Searcher)(nil)
+	_ Searcher = (*FakeSearcher)(nil)
+)
+
+// defaultFilter is used by ForEachUser and TakeSnapshot for an empty filter
+const defaultFilter = "(uid=*)"
+
+// FakeSearcher is an in-memory Searcher. Lookups follow the same rules as
+// the real searcher: identifiers match exactly for UIDs and
+// case-insensitively for emails, missing users are reported with
+// ldap_redhat.ErrUserNotFound, and filters are evaluated against the LDAP
+// attributes each UserRecord field comes from. Users with
+// ldap_redhat.StatusDeleted are only found by GetUserIncludingDeleted and
+// lookups with SearchOptions.IncludeDeleted, as in the default search base.
+// Request scopes and attribute profiles are not applied. A FakeSearcher is
+// safe for concurrent use.
+type FakeSearcher struct {
+	mu        sync.Mutex
+	users     []ldap_redhat.UserRecord
+	groups    []fakeGroup
+	passwords map[string]string // uid -> password accepted by Authenticate
+	errs      map[string]error
+	calls     map[string]int
+	closed    bool
+}
+
+type fakeGroup struct {
+	group   ldap_redhat.Group
+	members []string
+}
+
+// NewFakeSearcher returns a FakeSearcher holding users.
+func NewFakeSearcher(users ...ldap_redhat.UserRecord) *FakeSearcher {
+	f := &FakeSearcher{passwords: map[string]string{}, errs: map[string]error{}, calls: map[string]int{}}
+	for _, u := range users {
+		f.AddUser(u)
+	}
+	return f
+}
+
+// AddUser adds u, replacing any user with the same UID. ManagerUID and
+// ManagerDN are derived from each other when only one is set, the parsed
+// dates from the raw rhat* date strings, and an empty Status from the
+// termination date.
+func (f *FakeSearcher) AddUser(u ldap_redhat.UserRecord) {
+	if u.ManagerDN == "" && u.ManagerUID != "" {
+		u.ManagerDN = userDN(u.ManagerUID)
+	}
+	if u.ManagerUID == "" && u.ManagerDN != "" {
+		u.ManagerUID = uidFromDN(u.ManagerDN)
+	}
+	for _, d := range []struct {
+		raw    string
+		parsed *time.Time
+	}{{u.RhatHireDate, &u.HireDate}, {u.RhatTermDate, &u.TermDate}, {u.RhatAdjSvcDate, &u.AdjServiceDate}} {
+		if d.parsed.IsZero() && d.raw != "" {
+			*d.parsed, _ = ldap_redhat.ParseLDAPTime(d.raw)
+		}
+	}
+	if u.Status == "" {
+		u.Status = ldap_redhat.StatusActive
+		if !u.IsActive() {
+			u.Status = ldap_redhat.StatusTerminated
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.users {
+		if f.users[i].UID == u.UID {
+			f.users[i] = u
+			return
+		}
+	}
+	f.users = append(f.users, u)
+}
+
+// SetPassword sets the password Authenticate accepts for the user with the
+// given UID. Users without one cannot authenticate.
+func (f *FakeSearcher) SetPassword(uid, password string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.passwords[uid] = password
+}
+
+// RemoveUser removes the user with the given UID, if any.
+func (f *FakeSearcher) RemoveUser(uid string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users = slices.DeleteFunc(f.users, func(u ldap_redhat.UserRecord) bool { return u.UID == uid })
+}
+
+// AddGroup adds g with the given member UIDs, replacing any group with the
+// same name. An empty DN is filled in under ou=adhoc.
+func (f *FakeSearcher) AddGroup(g ldap_redhat.Group, memberUIDs ...string) {
+	if g.DN == "" {
+		g.DN = fmt.Sprintf("cn=%s,ou=adhoc,ou=managedGroups,dc=redhat,dc=com", ldap.EscapeDN(g.Name))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fg := fakeGroup{group: g, members: slices.Clone(memberUIDs)}
+	for i := range f.groups {
+		if f.groups[i].group.Name == g.Name {
+			f.groups[i] = fg
+			return
+		}
+	}
+	f.groups = append(f.groups, fg)
+}
+
+// SetError makes every later call to the named method, e.g. "GetUser",
+// return err until it is cleared with a nil err. Methods that only wrap
+// others, like ManagerChain, also fail when the methods they wrap do.
+func (f *FakeSearcher) SetError(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errs, method)
+	} else {
+		f.errs[method] = err
+	}
+}
+
+// Calls returns how many times the named method has been called.
+func (f *FakeSearcher) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// begin records a call to method and returns the error it must fail with,
+// if any. f.mu must be held.
+func (f *FakeSearcher) begin(ctx context.Context, method string) error {
+	f.calls[method]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f.closed {
+		return fmt.Errorf("%w: searcher closed", ldap_redhat.ErrNotConnected)
+	}
+	return f.errs[method]
+}
+
+// lookup returns the user id refers to, skipping deleted users unless
+// includeDeleted is set. f.mu must be held.
+func (f *FakeSearcher) lookup(id ldap_redhat.Identifier, includeDeleted bool) (ldap_redhat.UserRecord, bool, error) {
+	field, fold := identifierField(id.Type)
+	if field == nil {
+		return ldap_redhat.UserRecord{}, false, fmt.Errorf("unknown identifier type: %d", id.Type)
+	}
+	if id.Type == ldap_redhat.IDTUID && !ldap_redhat.IsValidRhatUID(strings.ToLower(id.Value)) ||
+		id.Type == ldap_redhat.IDTUUID && !ldap_redhat.IsValidRhatUUID(id.Value) {
+		return ldap_redhat.UserRecord{}, false, fmt.Errorf("%w: %q", ldap_redhat.ErrInvalidIdentifier, id.Value)
+	}
+	for _, u := range f.users {
+		if u.Status == ldap_redhat.StatusDeleted && !includeDeleted {
+			continue
+		}
+		value := field(u)
+		if value != "" && (value == id.Value || fold && strings.EqualFold(value, id.Value)) {
+			return u, true, nil
+		}
+	}
+	return ldap_redhat.UserRecord{}, false, nil
+}
+
+// includeDeleted reports whether lookups in ctx also find deleted users
+func includeDeleted(ctx context.Context) bool {
+	opts, _ := ldap_redhat.SearchOptionsFromContext(ctx)
+	return opts.IncludeDeleted
+}
+
+// identifierField returns the field an identifier type matches and whether
+// it matches case-insensitively, or nil for an unknown type
+func identifierField(idType int) (func(ldap_redhat.UserRecord) string, bool) {
+	switch idType {
+	case ldap_redhat.IDTUID:
+		return func(u ldap_redhat.UserRecord) string { return u.UID }, false
+	case ldap_redhat.IDTEmail:
+		return func(u ldap_redhat.UserRecord) string { return u.Email }, true
+	case ldap_redhat.IDTUUID:
+		return func(u ldap_redhat.UserRecord) string { return u.RhatUUID }, true
+	case ldap_redhat.IDTEmployeeNumber:
+		return func(u ldap_redhat.UserRecord) string { return u.EmployeeNumber }, false
+	case ldap_redhat.IDTKerberos:
+		return func(u ldap_redhat.UserRecord) string { return u.KerberosPrincipal }, true
+	}
+	return nil, false
+}
+
+// GetUser returns the user id refers to.
+func (f *FakeSearcher) GetUser(ctx context.Context, id ldap_redhat.Identifier) (ldap_redhat.UserRecord, error) {
+	return f.getUser(ctx, "GetUser", id, includeDeleted(ctx))
+}
+
+// GetUserIncludingDeleted returns the user id refers to, deleted or not.
+func (f *FakeSearcher) GetUserIncludingDeleted(ctx context.Context, id ldap_redhat.Identifier) (ldap_redhat.UserRecord, error) {
+	return f.getUser(ctx, "GetUserIncludingDeleted", id, true)
+}
+
+// getUser looks id up, recording a call to method
+func (f *FakeSearcher) getUser(ctx context.Context, method string, id ldap_redhat.Identifier, includeDeleted bool) (ldap_redhat.UserRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, method); err != nil {
+		return ldap_redhat.UserRecord{}, err
+	}
+	u, ok, err := f.lookup(id, includeDeleted)
+	if err != nil {
+		return ldap_redhat.UserRecord{}, err
+	}
+	if !ok {
+		return ldap_redhat.UserRecord{}, &notFoundError{fmt.Sprintf("user not found in LDAP directory: %s", id.Value)}
+	}
+	return u, nil
+}
+
+// GetUsers returns the users ids refer to, in order; missing users have an
+// empty UID.
+func (f *FakeSearcher) GetUsers(ctx context.Context, ids []ldap_redhat.Identifier) ([]ldap_redhat.UserRecord, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "GetUsers"); err != nil {
+		return nil, err
+	}
+	out := make([]ldap_redhat.UserRecord, len(ids))
+	var failed []ldap_redhat.ItemError
+	for i, id := range ids {
+		u, _, err := f.lookup(id, includeDeleted(ctx))
+		if err != nil {
+			failed = append(failed, ldap_redhat.ItemError{Index: i, Item: id.Value, Err: err})
+			continue
+		}
+		out[i] = u
+	}
+	if len(failed) > 0 {
+		return out, &ldap_redhat.BatchError{Total: len(ids), Errors: failed}
+	}
+	return out, nil
+}
+
+// Authenticate returns the user id refers to if password is the one set
+// with SetPassword. Wrong and empty passwords, unknown identifiers and
+// terminated users fail with ldap_redhat.ErrInvalidCredentials.
+func (f *FakeSearcher) Authenticate(ctx context.Context, id ldap_redhat.Identifier, password string) (ldap_redhat.UserRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "Authenticate"); err != nil {
+		return ldap_redhat.UserRecord{}, err
+	}
+	u, ok, err := f.lookup(id, false)
+	if err != nil {
+		return ldap_redhat.UserRecord{}, err
+	}
+	if !ok || password == "" || f.passwords[u.UID] != password || !u.IsActive() {
+		return ldap_redhat.UserRecord{}, fmt.Errorf("%w for %s", ldap_redhat.ErrInvalidCredentials, id.Value)
+	}
+	return u, nil
+}
+
+// ForEachUser calls fn for every user matching filter (all users when
+// empty), stopping at the first error fn returns.
+func (f *FakeSearcher) ForEachUser(ctx context.Context, filter string, fn func(ldap_redhat.UserRecord) error) error {
+	users, err := f.matching(ctx, "ForEachUser", filter)
+	if err != nil {
+		return err
+	}
+	users, partial := sizeLimited(ctx, filter, users)
+	for _, u := range users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return partial
+}
+
+// Users returns an iterator over the users matching filter (all users when
+// empty), yielding an error last, as *ldap_redhat.Searcher.Users does.
+func (f *FakeSearcher) Users(ctx context.Context, filter string) iter.Seq2[ldap_redhat.UserRecord, error] {
+	return func(yield func(ldap_redhat.UserRecord, error) bool) {
+		users, err := f.matching(ctx, "Users", filter)
+		if err != nil {
+			yield(ldap_redhat.UserRecord{}, err)
+			return
+		}
+		users, partial := sizeLimited(ctx, filter, users)
+		for _, u := range users {
+			if !yield(u, nil) {
+				return
+			}
+		}
+		if partial != nil {
+			yield(ldap_redhat.UserRecord{}, partial)
+		}
+	}
+}
+
+// SearchUsers returns the users matching filter (all users when empty).
+func (f *FakeSearcher) SearchUsers(ctx context.Context, filter string) ([]ldap_redhat.UserRecord, error) {
+	users, err := f.matching(ctx, "SearchUsers", filter)
+	if err != nil {
+		return nil, err
+	}
+	return sizeLimited(ctx, filter, users)
+}
+
+// sizeLimited cuts users to the SizeLimit of the SearchOptions of ctx and
+// returns a *PartialResultsError, as the real searcher does, if any were cut
+func sizeLimited(ctx context.Context, filter string, users []ldap_redhat.UserRecord) ([]ldap_redhat.UserRecord, error) {
+	opts, _ := ldap_redhat.SearchOptionsFromContext(ctx)
+	if opts.SizeLimit <= 0 || len(users) <= opts.SizeLimit {
+		return users, nil
+	}
+	return users[:opts.SizeLimit], &ldap_redhat.PartialResultsError{
+		Filter:   filter,
+		Limit:    opts.SizeLimit,
+		Returned: opts.SizeLimit,
+		Err:      ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded")),
+	}
+}
+
+// TakeSnapshot returns the users matching filter (all users when empty) as a
+// Snapshot taken now.
+func (f *FakeSearcher) TakeSnapshot(ctx context.Context, filter string) (*ldap_redhat.Snapshot, error) {
+	users, err := f.matching(ctx, "TakeSnapshot", filter)
+	if err != nil {
+		return nil, err
+	}
+	return ldap_redhat.NewSnapshot(users, time.Now()), nil
+}
+
+// matching returns the users that match filter, recording a call to method
+func (f *FakeSearcher) matching(ctx context.Context, method, filter string) ([]ldap_redhat.UserRecord, error) {
+	if filter == "" {
+		filter = defaultFilter
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, method); err != nil {
+		return nil, err
+	}
+	var out []ldap_redhat.UserRecord
+	for _, u := range f.users {
+		if u.Status == ldap_redhat.StatusDeleted {
+			continue
+		}
+		ok, err := ldapserver.MatchFilter(userAttributes(u), filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", filter, err)
+		}
+		if ok {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+// FindDirectReports returns the users whose manager is managerUID, honoring
+// the country exclusions and recursion of opts.
+func (f *FakeSearcher) FindDirectReports(ctx context.Context, managerUID string, opts ...ldap_redhat.ReportSearchOptions) ([]ldap_redhat.UserRecord, error) {
+	var opt ldap_redhat.ReportSearchOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "FindDirectReports"); err != nil {
+		return nil, err
+	}
+	reports := f.reportsOf(managerUID, opt.ExcludeCountries)
+	if !opt.Recursive {
+		return reports, nil
+	}
+	return f.walkReports(reports, opt, 1, map[string]bool{managerUID: true}), nil
+}
+
+// walkReports returns current followed by the reports below each of them, in
+// the order the real searcher returns them. Users already seen are not
+// descended into again, so management cycles terminate. f.mu must be held.
+func (f *FakeSearcher) walkReports(current []ldap_redhat.UserRecord, opt ldap_redhat.ReportSearchOptions, depth int, seen map[string]bool) []ldap_redhat.UserRecord {
+	if opt.MaxDepth > 0 && depth >= opt.MaxDepth {
+		return current
+	}
+	all := slices.Clone(current)
+	for _, u := range current {
+		if seen[u.UID] {
+			continue
+		}
+		seen[u.UID] = true
+		if children := f.reportsOf(u.UID, opt.ExcludeCountries); len(children) > 0 {
+			all = append(all, f.walkReports(children, opt, depth+1, seen)...)
+		}
+	}
+	return all
+}
+
+// reportsOf returns the direct reports of uid. f.mu must be held.
+func (f *FakeSearcher) reportsOf(uid string, excludeCountries []string) []ldap_redhat.UserRecord {
+	var out []ldap_redhat.UserRecord
+	for _, u := range f.users {
+		if !strings.EqualFold(u.ManagerUID, uid) || u.UID == "" || u.Status == ldap_redhat.StatusDeleted {
+			continue
+		}
+		if slices.ContainsFunc(excludeCountries, func(cc string) bool {
+			return strings.EqualFold(strings.TrimSpace(cc), u.Country)
+		}) {
+			continue
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+// GetUsersByCostCenter returns the users in costCenter sorted by UID, only
+// the active ones unless opts include terminated users.
+func (f *FakeSearcher) GetUsersByCostCenter(ctx context.Context, costCenter string, opts ...ldap_redhat.CostCenterOptions) ([]ldap_redhat.UserRecord, error) {
+	var opt ldap_redhat.CostCenterOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	costCenter = strings.TrimSpace(costCenter)
+	if costCenter == "" {
+		return nil, fmt.Errorf("cost center must not be empty")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "GetUsersByCostCenter"); err != nil {
+		return nil, err
+	}
+	var out []ldap_redhat.UserRecord
+	for _, u := range f.users {
+		if u.UID != "" && u.Status != ldap_redhat.StatusDeleted && strings.EqualFold(u.CostCenter, costCenter) &&
+			(opt.IncludeTerminated || u.IsActive()) {
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UID < out[j].UID })
+	return out, nil
+}
+
+// IsPeopleManager reports whether any user has managerUID as their manager.
+func (f *FakeSearcher) IsPeopleManager(ctx context.Context, managerUID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "IsPeopleManager"); err != nil {
+		return false, err
+	}
+	return len(f.reportsOf(managerUID, nil)) > 0, nil
+}
+
+// ManagerChain returns the management chain above uid, looking each manager
+// up with GetUser.
+func (f *FakeSearcher) ManagerChain(ctx context.Context, uid string) ([]ldap_redhat.UserRecord, error) {
+	f.mu.Lock()
+	err := f.begin(ctx, "ManagerChain")
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	user, err := f.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{user.UID: true}
+	var chain []ldap_redhat.UserRecord
+	for next := user.ManagerUID; next != "" && !seen[next]; next = user.ManagerUID {
+		user, err = f.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: next})
+		if err != nil {
+			return chain, fmt.Errorf("failed to resolve manager %s: %w", next, err)
+		}
+		seen[next] = true
+		chain = append(chain, user)
+	}
+	return chain, nil
+}
+
+// GetUserGroups returns the groups uid belongs to, sorted by name.
+func (f *FakeSearcher) GetUserGroups(ctx context.Context, uid string) ([]ldap_redhat.Group, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "GetUserGroups"); err != nil {
+		return nil, err
+	}
+	groups := []ldap_redhat.Group{}
+	for _, g := range f.groups {
+		if slices.ContainsFunc(g.members, func(m string) bool { return strings.EqualFold(m, uid) }) {
+			groups = append(groups, g.group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// GetGroupMembers returns the sorted member UIDs of the group named name.
+func (f *FakeSearcher) GetGroupMembers(ctx context.Context, name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "GetGroupMembers"); err != nil {
+		return nil, err
+	}
+	for _, g := range f.groups {
+		if g.group.Name == name {
+			members := slices.Clone(g.members)
+			sort.Strings(members)
+			return slices.Compact(members), nil
+		}
+	}
+	return nil, fmt.Errorf("group not found in LDAP directory: %s", name)
+}
+
+// MapEmailsToUIDs resolves emails to UIDs like Searcher.MapEmailsToUIDs.
+func (f *FakeSearcher) MapEmailsToUIDs(ctx context.Context, emails []string) (map[string]string, []string, error) {
+	found := make(map[string]string, len(emails))
+	if len(emails) == 0 {
+		return found, nil, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "MapEmailsToUIDs"); err != nil {
+		return nil, nil, err
+	}
+	var notFound []string
+	for _, email := range emails {
+		key := strings.TrimSpace(email)
+		if key == "" {
+			continue
+		}
+		if u, ok, _ := f.lookup(ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: key}, false); ok {
+			found[email] = u.UID
+		} else {
+			notFound = append(notFound, email)
+		}
+	}
+	return found, notFound, nil
+}
+
+// MapUIDsToEmails resolves UIDs to email addresses like
+// Searcher.MapUIDsToEmails. A user's Email is its primary address; fake
+// users have no aliases.
+func (f *FakeSearcher) MapUIDsToEmails(ctx context.Context, uids []string) (map[string]ldap_redhat.EmailAddresses, []string, error) {
+	found := make(map[string]ldap_redhat.EmailAddresses, len(uids))
+	if len(uids) == 0 {
+		return found, nil, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin(ctx, "MapUIDsToEmails"); err != nil {
+		return nil, nil, err
+	}
+	var notFound []string
+	for _, uid := range uids {
+		key := strings.TrimSpace(uid)
+		if key == "" {
+			continue
+		}
+		if u, ok, _ := f.lookup(ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: key}, false); ok && u.Email != "" {
+			found[uid] = ldap_redhat.EmailAddresses{Primary: u.Email}
+		} else {
+			notFound = append(notFound, uid)
+		}
+	}
+	return found, notFound, nil
+}
+
+// Ping succeeds unless an error was set for it. It does not reopen a closed
+// FakeSearcher.
+func (f *FakeSearcher) Ping(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.begin(ctx, "Ping")
+}
+
+// Close makes later calls fail with ldap_redhat.ErrNotConnected.
+func (f *FakeSearcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["Close"]++
+	f.closed = true
+	return nil
+}
+
+// notFoundError matches ldap_redhat.ErrUserNotFound with the real
+// searcher's message
+type notFoundError struct{ msg string }
+
+func (e *notFoundError) Error() string { return e.msg }
+func (e *notFoundError) Unwrap() error { return ldap_redhat.ErrUserNotFound }
+
+// userDN returns the DN of the user entry for uid
+func userDN(uid string) string {
+	return fmt.Sprintf("uid=%s,ou=users,dc=redhat,dc=com", ldap.EscapeDN(uid))
+}
+
+// uidFromDN returns the uid in the leading RDN of dn, or ""
+func uidFromDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+	for _, attr := range parsed.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, ldap_redhat.AttrUID) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// userAttributes returns the LDAP attributes u would have been read from, so
+// that filters match as they would against the directory
+func userAttributes(u ldap_redhat.UserRecord) map[string][]string {
+	attrs := map[string][]string{"objectClass": {"top", "person", "inetOrgPerson"}}
+	for name, value := range map[string]string{
+		ldap_redhat.AttrUID:                u.UID,
+		ldap_redhat.AttrMail:               u.Email,
+		ldap_redhat.AttrCN:                 u.DisplayName,
+		ldap_redhat.AttrSN:                 u.Surname,
+		ldap_redhat.AttrTitle:              u.Title,
+		ldap_redhat.AttrManager:            u.ManagerDN,
+		ldap_redhat.AttrRhatCostCenter:     u.CostCenter,
+		ldap_redhat.AttrRhatCostCenterDesc: u.CostCenterDesc,
+		ldap_redhat.AttrRhatLocation:       u.RhatLocation,
+		ldap_redhat.AttrRhatJobCode:        u.RhatJobCode,
+		ldap_redhat.AttrRhatUUID:           u.RhatUUID,
+		ldap_redhat.AttrRhatHireDate:       u.RhatHireDate,
+		ldap_redhat.AttrRhatTermDate:       u.RhatTermDate,
+		ldap_redhat.AttrRhatAdjSvcDate:     u.RhatAdjSvcDate,
+		ldap_redhat.AttrCo:                 u.Country,
+		ldap_redhat.AttrOU:                 u.Department,
+		ldap_redhat.AttrEmployeeNumber:     u.EmployeeNumber,
+		ldap_redhat.AttrKrbPrincipalName:   u.KerberosPrincipal,
+		ldap_redhat.AttrRhatGeo:            u.Geo,
+		ldap_redhat.AttrRhatOrgCharTitle:   u.OrgChartTitle,
+		ldap_redhat.AttrRhatPersonType:     u.PersonType,
+		ldap_redhat.AttrRhatBuilding:       u.Building,
+		ldap_redhat.AttrMobile:             u.Mobile,
+		ldap_redhat.AttrTelephoneNumber:    u.TelephoneNumber,
+	} {
+		if value != "" {
+			attrs[name] = []string{value}
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,253 @@
+package ldaptest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/ldaptest"
+)
+
+func newOrg() *ldaptest.FakeSearcher {
+	return ldaptest.NewFakeSearcher(
+		ldap_redhat.UserRecord{UID: "ceo", Email: "ceo@redhat.com", Title: "Chief Executive Officer", Country: "US"},
+		ldap_redhat.UserRecord{UID: "vp", Email: "vp@redhat.com", Title: "Vice President", ManagerUID: "ceo", Country: "US"},
+		ldap_redhat.UserRecord{UID: "dev1", Email: "Dev1@redhat.com", Title: "Software Engineer", ManagerUID: "vp", Country: "DEU"},
+		ldap_redhat.UserRecord{UID: "dev2", Email: "dev2@redhat.com", Title: "Senior Software Engineer", ManagerUID: "vp", Country: "US",
+			RhatTermDate: "20200101000000Z"},
+	)
+}
+
+func uids(users []ldap_redhat.UserRecord) []string {
+	out := make([]string, len(users))
+	for i, u := range users {
+		out[i] = u.UID
+	}
+	return out
+}
+
+func TestFakeSearcherLookups(t *testing.T) {
+	fake := newOrg()
+	ctx := context.Background()
+
+	u, err := fake.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "dev1@REDHAT.com"})
+	if err != nil || u.UID != "dev1" {
+		t.Errorf("expected dev1 by email, got %q, %v", u.UID, err)
+	}
+	if u.ManagerDN != "uid=vp,ou=users,dc=redhat,dc=com" {
+		t.Errorf("expected ManagerDN derived from ManagerUID, got %q", u.ManagerDN)
+	}
+	u, _ = fake.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "dev2"})
+	if u.TermDate.IsZero() || u.IsActive() {
+		t.Errorf("expected TermDate parsed from RhatTermDate, got %v", u.TermDate)
+	}
+
+	_, err = fake.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nobody"})
+	if !errors.Is(err, ldap_redhat.ErrUserNotFound) || err.Error() != "user not found in LDAP directory: nobody" {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+
+	users, err := fake.GetUsers(ctx, []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUID, Value: "vp"},
+		{Type: ldap_redhat.IDTUID, Value: "nobody"},
+		{Type: ldap_redhat.IDTEmail, Value: "ceo@redhat.com"},
+	})
+	if err != nil || !reflect.DeepEqual(uids(users), []string{"vp", "", "ceo"}) {
+		t.Errorf("unexpected GetUsers result %v, %v", uids(users), err)
+	}
+
+	chain, err := fake.ManagerChain(ctx, "dev1")
+	if err != nil || !reflect.DeepEqual(uids(chain), []string{"vp", "ceo"}) {
+		t.Errorf("unexpected ManagerChain %v, %v", uids(chain), err)
+	}
+
+	found, notFound, err := fake.MapEmailsToUIDs(ctx, []string{"DEV1@redhat.com", "missing@redhat.com", " "})
+	if err != nil || found["DEV1@redhat.com"] != "dev1" || !reflect.DeepEqual(notFound, []string{"missing@redhat.com"}) {
+		t.Errorf("unexpected MapEmailsToUIDs result %v, %v, %v", found, notFound, err)
+	}
+	emails, notFound, err := fake.MapUIDsToEmails(ctx, []string{"vp", "nobody"})
+	if err != nil || emails["vp"].Primary != "vp@redhat.com" || !reflect.DeepEqual(notFound, []string{"nobody"}) {
+		t.Errorf("unexpected MapUIDsToEmails result %v, %v, %v", emails, notFound, err)
+	}
+}
+
+func TestFakeSearcherDeletedUsers(t *testing.T) {
+	fake := newOrg()
+	fake.AddUser(ldap_redhat.UserRecord{UID: "gone", ManagerUID: "vp", Status: ldap_redhat.StatusDeleted, RhatTermDate: "20150101000000Z"})
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "gone"}
+
+	if _, err := fake.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound for a deleted user, got %v", err)
+	}
+	u, err := fake.GetUserIncludingDeleted(ctx, id)
+	if err != nil || u.Status != ldap_redhat.StatusDeleted {
+		t.Errorf("expected the deleted user, got %q (%s), %v", u.UID, u.Status, err)
+	}
+	users, err := fake.GetUsers(ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{IncludeDeleted: true}), []ldap_redhat.Identifier{id})
+	if err != nil || users[0].UID != "gone" {
+		t.Errorf("unexpected GetUsers result %v, %v", uids(users), err)
+	}
+	reports, err := fake.FindDirectReports(ctx, "vp")
+	if err != nil || !reflect.DeepEqual(uids(reports), []string{"dev1", "dev2"}) {
+		t.Errorf("unexpected reports %v, %v", uids(reports), err)
+	}
+
+	dev2, _ := fake.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "dev2"})
+	if dev2.Status != ldap_redhat.StatusTerminated {
+		t.Errorf("expected dev2 terminated, got %q", dev2.Status)
+	}
+}
+
+func TestFakeSearcherFilters(t *testing.T) {
+	fake := newOrg()
+	ctx := context.Background()
+
+	sn, err := fake.TakeSnapshot(ctx, "")
+	if err != nil || len(sn.Users) != 4 {
+		t.Fatalf("expected a snapshot of every user, got %v, %v", sn, err)
+	}
+	var searcher ldap_redhat.UserSearcher = fake
+	users, err := searcher.SearchUsers(ctx, "(|(uid=ceo)(mail=vp@*))")
+	if err != nil || !reflect.DeepEqual(uids(users), []string{"ceo", "vp"}) {
+		t.Errorf("unexpected SearchUsers result %v, %v", uids(users), err)
+	}
+	var matched []string
+	err = fake.ForEachUser(ctx, "(&(title=*Engineer)(!(co=DEU)))", func(u ldap_redhat.UserRecord) error {
+		matched = append(matched, u.UID)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(matched, []string{"dev2"}) {
+		t.Errorf("unexpected filter matches %v, %v", matched, err)
+	}
+	if err := fake.ForEachUser(ctx, "(uid=", func(ldap_redhat.UserRecord) error { return nil }); err == nil {
+		t.Errorf("expected an error for an invalid filter")
+	}
+
+	reports, _ := fake.FindDirectReports(ctx, "vp", ldap_redhat.ReportSearchOptions{ExcludeCountries: []string{"deu"}})
+	if !reflect.DeepEqual(uids(reports), []string{"dev2"}) {
+		t.Errorf("unexpected direct reports %v", uids(reports))
+	}
+	reports, _ = fake.FindDirectReports(ctx, "ceo", ldap_redhat.ReportSearchOptions{Recursive: true})
+	if !reflect.DeepEqual(uids(reports), []string{"vp", "dev1", "dev2"}) {
+		t.Errorf("unexpected recursive reports %v", uids(reports))
+	}
+	reports, _ = fake.FindDirectReports(ctx, "ceo", ldap_redhat.ReportSearchOptions{Recursive: true, MaxDepth: 1})
+	if !reflect.DeepEqual(uids(reports), []string{"vp"}) {
+		t.Errorf("unexpected depth-limited reports %v", uids(reports))
+	}
+	if ok, _ := fake.IsPeopleManager(ctx, "vp"); !ok {
+		t.Errorf("expected vp to be a people manager")
+	}
+
+	fake.AddUser(ldap_redhat.UserRecord{UID: "fin1", CostCenter: "700"})
+	fake.AddUser(ldap_redhat.UserRecord{UID: "fin2", CostCenter: "700", RhatTermDate: "20200101000000Z"})
+	roster, _ := fake.GetUsersByCostCenter(ctx, "700")
+	if !reflect.DeepEqual(uids(roster), []string{"fin1"}) {
+		t.Errorf("unexpected cost center roster %v", uids(roster))
+	}
+	roster, _ = fake.GetUsersByCostCenter(ctx, "700", ldap_redhat.CostCenterOptions{IncludeTerminated: true})
+	if !reflect.DeepEqual(uids(roster), []string{"fin1", "fin2"}) {
+		t.Errorf("unexpected cost center roster with terminated users %v", uids(roster))
+	}
+}
+
+func TestFakeSearcherSizeLimit(t *testing.T) {
+	fake := newOrg()
+	ctx := ldap_redhat.WithSearchOptions(context.Background(), ldap_redhat.SearchOptions{SizeLimit: 2})
+
+	users, err := fake.SearchUsers(ctx, "")
+	var partial *ldap_redhat.PartialResultsError
+	if !errors.As(err, &partial) || partial.Returned != 2 || !reflect.DeepEqual(uids(users), []string{"ceo", "vp"}) {
+		t.Errorf("unexpected size-limited SearchUsers result %v, %v", uids(users), err)
+	}
+	var streamed int
+	err = fake.ForEachUser(ctx, "", func(ldap_redhat.UserRecord) error {
+		streamed++
+		return nil
+	})
+	if !errors.As(err, &partial) || streamed != 2 {
+		t.Errorf("expected 2 users and a *PartialResultsError from ForEachUser, got %d, %v", streamed, err)
+	}
+	var iterated []string
+	err = nil
+	for u, uerr := range fake.Users(ctx, "") {
+		if uerr != nil {
+			err = uerr
+			continue
+		}
+		iterated = append(iterated, u.UID)
+	}
+	if !errors.As(err, &partial) || !reflect.DeepEqual(iterated, []string{"ceo", "vp"}) {
+		t.Errorf("expected ceo, vp and a *PartialResultsError from Users, got %v, %v", iterated, err)
+	}
+}
+
+func TestFakeSearcherGroups(t *testing.T) {
+	fake := newOrg()
+	fake.AddGroup(ldap_redhat.Group{Name: "zeta"}, "dev1")
+	fake.AddGroup(ldap_redhat.Group{Name: "alpha", Description: "Alpha team"}, "dev2", "dev1")
+	ctx := context.Background()
+
+	groups, err := fake.GetUserGroups(ctx, "dev1")
+	if err != nil || len(groups) != 2 || groups[0].Name != "alpha" || groups[0].DN == "" {
+		t.Errorf("unexpected groups %v, %v", groups, err)
+	}
+	members, err := fake.GetGroupMembers(ctx, "alpha")
+	if err != nil || !reflect.DeepEqual(members, []string{"dev1", "dev2"}) {
+		t.Errorf("unexpected members %v, %v", members, err)
+	}
+	if _, err := fake.GetGroupMembers(ctx, "missing"); err == nil {
+		t.Errorf("expected an error for a missing group")
+	}
+}
+
+func TestFakeSearcherAuthenticate(t *testing.T) {
+	fake := newOrg()
+	fake.SetPassword("dev1", "hunter2")
+	fake.SetPassword("dev2", "hunter2")
+	ctx := context.Background()
+
+	u, err := fake.Authenticate(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "dev1@redhat.com"}, "hunter2")
+	if err != nil || u.UID != "dev1" {
+		t.Errorf("expected dev1 to authenticate, got %q, %v", u.UID, err)
+	}
+	for _, tc := range []struct{ uid, password string }{{"dev1", "wrong"}, {"dev1", ""}, {"dev2", "hunter2"}, {"vp", ""}, {"nobody", "hunter2"}} {
+		if _, err := fake.Authenticate(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: tc.uid}, tc.password); !errors.Is(err, ldap_redhat.ErrInvalidCredentials) {
+			t.Errorf("expected ErrInvalidCredentials for %s/%q, got %v", tc.uid, tc.password, err)
+		}
+	}
+}
+
+func TestFakeSearcherErrors(t *testing.T) {
+	fake := newOrg()
+	ctx := context.Background()
+
+	fake.SetError("GetUser", ldap_redhat.ErrTimeout)
+	if _, err := fake.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "ceo"}); !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+	if _, err := fake.ManagerChain(ctx, "dev1"); !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Errorf("expected ManagerChain to fail through GetUser, got %v", err)
+	}
+	fake.SetError("GetUser", nil)
+	if _, err := fake.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "ceo"}); err != nil {
+		t.Errorf("expected the error to be cleared, got %v", err)
+	}
+	if calls := fake.Calls("GetUser"); calls != 3 {
+		t.Errorf("expected 3 GetUser calls, got %d", calls)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := fake.Ping(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	fake.Close()
+	if _, err := fake.GetUsers(ctx, []ldap_redhat.Identifier{{Type: ldap_redhat.IDTUID, Value: "ceo"}}); !errors.Is(err, ldap_redhat.ErrNotConnected) {
+		t.Errorf("expected ErrNotConnected after Close, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package ldaptest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// DefaultReportsPerManager is the fan-out of GenerateUsers' management tree
+// when GenerateOptions.ReportsPerManager is zero.
+const DefaultReportsPerManager = 6
+
+// GenerateOptions configures GenerateUsers.
+type GenerateOptions struct {
+	// Seed selects the users generated: the same seed and options always
+	// produce the same users.
+	Seed uint64
+	// ReportsPerManager is the number of direct reports of each manager
+	// (default DefaultReportsPerManager).
+	ReportsPerManager int
+	// TerminatedPercent is the share of individual contributors given a
+	// past termination date. Managers are never terminated, so every manager
+	// chain ends at the first user.
+	TerminatedPercent int
+	// Now is the date hire and termination dates are generated before
+	// (default 2025-01-01, so the users do not change over time).
+	Now time.Time
+}
+
+// generatorNow is the default GenerateOptions.Now
+var generatorNow = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	firstNames = []string{
+		"Aisha", "Alejandro", "Ana", "Arjun", "Chen", "Daniel", "Elena", "Emma", "Fatima", "Hiroshi",
+		"Ines", "Jakub", "James", "Jana", "Kavya", "Liam", "Lucia", "Mateo", "Mei", "Michael",
+		"Niamh", "Olga", "Pavel", "Priya", "Rahul", "Sara", "Sean", "Sofia", "Tomas", "Yuki",
+	}
+	lastNames = []string{
+		"Brennan", "Chowdhury", "Costa", "Dvorak", "Fischer", "Garcia", "Gupta", "Horvath", "Ito", "Kelly",
+		"Kowalski", "Li", "Martin", "Meyer", "Murphy", "Nakamura", "Novak", "Oliveira", "Patel", "Perez",
+		"Rao", "Rossi", "Santos", "Schmidt", "Silva", "Smith", "Svoboda", "Tanaka", "Wang", "Weber",
+	}
+
+	// generatorSites are consistent location, country, region, building and
+	// phone prefix combinations
+	generatorSites = []struct{ location, country, geo, building, phone string }{
+		{"Raleigh", "US", "NA", "RAL-Tower", "+1 919 754"},
+		{"Boston", "US", "NA", "BOS-Fort Point", "+1 978 392"},
+		{"Remote US NC", "US", "NA", "", "+1 919 555"},
+		{"Brno", "CZ", "EMEA", "BRQ-TPB-C", "+420 532 294"},
+		{"Cork", "IE", "EMEA", "ORK-Penrose", "+353 21 230"},
+		{"Munich", "DE", "EMEA", "MUC-Werner", "+49 89 205071"},
+		{"Bangalore", "IN", "APAC", "BLR-Bagmane", "+91 80 6795"},
+		{"Tokyo", "JP", "APAC", "TYO-Ebisu", "+81 3 5798"},
+		{"Sao Paulo", "BR", "LATAM", "GRU-Berrini", "+55 11 3529"},
+	}
+
+	// generatorDepartments are assigned to the subtrees below the first
+	// user, with the cost center descriptions of their teams
+	generatorDepartments = []string{"OpenShift", "RHEL", "AI Platform", "Ansible", "Middleware", "Customer Experience"}
+
+	generatorICTitles = []struct{ title, jobCode string }{
+		{"Associate Software Engineer", "E1233"},
+		{"Software Engineer", "E1234"},
+		{"Senior Software Engineer", "E1235"},
+		{"Principal Software Engineer", "E1236"},
+		{"Senior Quality Engineer", "E1245"},
+		{"Technical Writer", "E1260"},
+	}
+	generatorManagerTitles = []struct{ title, jobCode string }{
+		{"Vice President, Engineering", "M4001"}, // the first user
+		{"Senior Director, Engineering", "M3001"},
+		{"Director, Engineering", "M2501"},
+		{"Senior Manager, Software Engineering", "M2101"},
+		{"Manager, Software Engineering", "M2001"},
+	}
+)
+
+// GenerateUsers returns n users following the Red Hat schema, for
+// seeding a FakeSearcher or, with OfflineConfig, a real searcher. The users
+// form one management tree rooted at the first user, in which every user
+// after the first reports to an earlier one through ManagerUID and
+// ManagerDN. Fields are consistent with each other as in the directory:
+// each team shares its manager's cost center and department, job codes match
+// titles, locations match countries and regions, rhatUUIDs are valid version
+// 4 UUIDs, and the raw date attributes match the parsed dates.
+func GenerateUsers(n int, opts GenerateOptions) []ldap_redhat.UserRecord {
+	fanout := opts.ReportsPerManager
+	if fanout <= 0 {
+		fanout = DefaultReportsPerManager
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = generatorNow
+	}
+	rng := rand.New(rand.NewPCG(opts.Seed, 0x72686174)) // "rhat"
+	earliest := time.Date(2005, time.January, 3, 0, 0, 0, 0, time.UTC)
+	hireDays := int(now.Sub(earliest).Hours()/24) - 30
+
+	users := make([]ldap_redhat.UserRecord, n)
+	depth := make([]int, n)
+	teamCostCenter := make([]string, n) // of the team each manager leads
+	taken := map[string]bool{}
+	for i := range users {
+		u := &users[i]
+		first, last := firstNames[rng.IntN(len(firstNames))], lastNames[rng.IntN(len(lastNames))]
+		u.UID = uniqueUID(strings.ToLower(first[:1]+last), taken)
+		u.Email = u.UID + "@redhat.com"
+		u.DisplayName = first + " " + last
+		u.Surname = last
+		u.KerberosPrincipal = u.UID + "@REDHAT.COM"
+		u.EmployeeNumber = fmt.Sprint(100001 + i)
+		u.RhatUUID = uuid4(rng)
+		u.Status = ldap_redhat.StatusActive
+
+		manages := i*fanout+1 < n
+		if i > 0 {
+			manager := (i - 1) / fanout
+			depth[i] = depth[manager] + 1
+			u.ManagerUID = users[manager].UID
+			u.ManagerDN = userDN(u.ManagerUID)
+			u.CostCenter = teamCostCenter[manager]
+			u.Department = users[manager].Department
+			if depth[i] == 1 {
+				u.Department = generatorDepartments[(i-1)%len(generatorDepartments)]
+			}
+		} else {
+			u.Department = "Engineering"
+		}
+		if manages {
+			teamCostCenter[i] = fmt.Sprintf("%03d", 600+i%400)
+			if i == 0 {
+				u.CostCenter = teamCostCenter[i]
+			}
+			t := generatorManagerTitles[min(depth[i], len(generatorManagerTitles)-1)]
+			u.Title, u.RhatJobCode = t.title, t.jobCode
+		} else {
+			t := generatorICTitles[rng.IntN(len(generatorICTitles))]
+			u.Title, u.RhatJobCode = t.title, t.jobCode
+		}
+		u.CostCenterDesc = u.Department + " " + u.CostCenter
+		u.OrgChartTitle = u.Title
+		u.PersonType = "Employee"
+		if !manages && rng.IntN(10) == 0 {
+			u.PersonType = "Contractor"
+		}
+
+		site := generatorSites[rng.IntN(len(generatorSites))]
+		u.RhatLocation, u.Country, u.Geo, u.Building = site.location, site.country, site.geo, site.building
+		u.TelephoneNumber = fmt.Sprintf("%s %04d", site.phone, rng.IntN(10000))
+
+		u.HireDate = earliest.AddDate(0, 0, rng.IntN(hireDays))
+		u.AdjServiceDate = u.HireDate
+		if rng.IntN(20) == 0 { // a rehire, credited with earlier service
+			u.AdjServiceDate = u.HireDate.AddDate(-1-rng.IntN(3), 0, 0)
+		}
+		if !manages && rng.IntN(100) < opts.TerminatedPercent {
+			days := int(now.Sub(u.HireDate).Hours() / 24)
+			u.TermDate = u.HireDate.AddDate(0, 0, 1+rng.IntN(max(days-1, 1)))
+			u.Status = ldap_redhat.StatusTerminated
+		}
+		u.RhatHireDate = ldap_redhat.FormatLDAPTime(u.HireDate)
+		u.RhatAdjSvcDate = ldap_redhat.FormatLDAPTime(u.AdjServiceDate)
+		if !u.TermDate.IsZero() {
+			u.RhatTermDate = ldap_redhat.FormatLDAPTime(u.TermDate)
+		}
+	}
+	return users
+}
+
+// uniqueUID returns uid, or uid with the lowest numeric suffix not yet
+// taken, and marks it taken
+func uniqueUID(uid string, taken map[string]bool) string {
+	candidate := uid
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", uid, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// uuid4 returns a random version 4 UUID from rng
+func uuid4(rng *rand.Rand) string {
+	var b [16]byte
+	for i := 0; i < len(b); i += 8 {
+		v := rng.Uint64()
+		for j := 0; j < 8; j++ {
+			b[i+j] = byte(v >> (8 * j))
+		}
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// OfflineConfig returns a configuration whose searcher serves users from an
+// embedded LDAP server (see ldap_redhat.Config.Offline), so tests exercise
+// the real searcher's filters, paging and errors. The fixtures file is
+// written to t.TempDir().
+//
+//	config := ldaptest.OfflineConfig(t, ldaptest.GenerateUsers(500, ldaptest.GenerateOptions{Seed: 1}))
+//	searcher, err := ldap_redhat.NewSearcher(config)
+func OfflineConfig(t testing.TB, users []ldap_redhat.UserRecord) ldap_redhat.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := (&ldap_redhat.Fixtures{Users: users}).WriteFile(path); err != nil {
+		t.Fatalf("ldaptest: %v", err)
+	}
+	return ldap_redhat.Config{Offline: true, FixturesFile: path}
+}
@@ -0,0 +1,100 @@
+package ldaptest_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/ldaptest"
+)
+
+func TestGenerateUsersDeterministic(t *testing.T) {
+	a := ldaptest.GenerateUsers(50, ldaptest.GenerateOptions{Seed: 7})
+	b := ldaptest.GenerateUsers(50, ldaptest.GenerateOptions{Seed: 7})
+	if !reflect.DeepEqual(a, b) {
+		t.Error("Expected the same seed to generate the same users")
+	}
+	c := ldaptest.GenerateUsers(50, ldaptest.GenerateOptions{Seed: 8})
+	if reflect.DeepEqual(a, c) {
+		t.Error("Expected another seed to generate other users")
+	}
+}
+
+func TestGenerateUsersConsistent(t *testing.T) {
+	users := ldaptest.GenerateUsers(300, ldaptest.GenerateOptions{Seed: 1, TerminatedPercent: 10})
+	byUID := map[string]ldap_redhat.UserRecord{}
+	uuids := map[string]bool{}
+	terminated := 0
+	for _, u := range users {
+		if _, dup := byUID[u.UID]; dup {
+			t.Errorf("Duplicate uid %s", u.UID)
+		}
+		byUID[u.UID] = u
+		if !ldap_redhat.IsValidRhatUUID(u.RhatUUID) || uuids[u.RhatUUID] {
+			t.Errorf("Invalid or duplicate rhatUUID %q for %s", u.RhatUUID, u.UID)
+		}
+		uuids[u.RhatUUID] = true
+		if hire, err := ldap_redhat.ParseLDAPTime(u.RhatHireDate); err != nil || !hire.Equal(u.HireDate) {
+			t.Errorf("rhatHireDate %q of %s does not match HireDate %v", u.RhatHireDate, u.UID, u.HireDate)
+		}
+		if u.Status == ldap_redhat.StatusTerminated {
+			terminated++
+			if u.IsActive() || !u.TermDate.After(u.HireDate) {
+				t.Errorf("Terminated user %s has termination date %v, hired %v", u.UID, u.TermDate, u.HireDate)
+			}
+		}
+	}
+	if terminated == 0 {
+		t.Error("Expected some terminated users")
+	}
+
+	for _, u := range users[1:] {
+		manager, ok := byUID[u.ManagerUID]
+		if !ok {
+			t.Fatalf("Manager %q of %s was not generated", u.ManagerUID, u.UID)
+		}
+		if manager.Status != ldap_redhat.StatusActive {
+			t.Errorf("Manager %s of %s is %s", manager.UID, u.UID, manager.Status)
+		}
+		if u.Department != manager.Department && manager.ManagerUID != "" {
+			t.Errorf("%s is in %s but their manager %s is in %s", u.UID, u.Department, manager.UID, manager.Department)
+		}
+	}
+	if users[0].ManagerUID != "" {
+		t.Errorf("Expected the first user to have no manager, got %q", users[0].ManagerUID)
+	}
+}
+
+func TestGeneratedUsersWithFakeAndOfflineSearchers(t *testing.T) {
+	users := ldaptest.GenerateUsers(40, ldaptest.GenerateOptions{Seed: 3})
+	leaf := users[len(users)-1]
+	ctx := context.Background()
+
+	fake := ldaptest.NewFakeSearcher(users...)
+	fakeChain, err := fake.ManagerChain(ctx, leaf.UID)
+	if err != nil {
+		t.Fatalf("FakeSearcher.ManagerChain failed: %v", err)
+	}
+
+	searcher, err := ldap_redhat.NewSearcher(ldaptest.OfflineConfig(t, users))
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	chain, err := searcher.ManagerChain(ctx, leaf.UID)
+	if err != nil {
+		t.Fatalf("ManagerChain failed: %v", err)
+	}
+	if len(chain) != len(fakeChain) || len(chain) == 0 || chain[len(chain)-1].UID != users[0].UID {
+		t.Errorf("Expected chains ending at %s, got %d from the searcher and %d from the fake", users[0].UID, len(chain), len(fakeChain))
+	}
+
+	got, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUUID, Value: leaf.RhatUUID})
+	if err != nil {
+		t.Fatalf("GetUser by rhatUUID failed: %v", err)
+	}
+	if got.UID != leaf.UID || got.CostCenter != leaf.CostCenter || !got.HireDate.Equal(leaf.HireDate) {
+		t.Errorf("Expected %+v, got %+v", leaf, got)
+	}
+}
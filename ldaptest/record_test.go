@@ -0,0 +1,152 @@
+package ldaptest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+	"github.com/openshift-eng/go-ldap-redhat/ldaptest"
+)
+
+const (
+	recordBindDN   = "uid=svc,ou=users,dc=redhat,dc=com"
+	recordPassword = "s3cret-bind-password"
+)
+
+// lookups runs the searches the record and replay tests compare
+func lookups(t *testing.T, config ldap_redhat.Config) (ldap_redhat.UserRecord, []ldap_redhat.UserRecord, error) {
+	t.Helper()
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	user, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(3)})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	var all []ldap_redhat.UserRecord
+	err = searcher.ForEachUser(ctx, "(uid=*)", func(u ldap_redhat.UserRecord) error {
+		all = append(all, u)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachUser failed: %v", err)
+	}
+	_, missing := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nobody"})
+	return user, all, missing
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(25))
+	srv.AddBind(recordBindDN, recordPassword)
+	srv.SetAttribute(testserver.UserDN(3), "userPassword", "{SSHA}hash")
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	rec, err := ldaptest.NewRecorder(srv.URL(), ldaptest.RecorderOptions{})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	config := ldap_redhat.Config{
+		LdapServers: []string{rec.URL()},
+		Username:    recordBindDN,
+		Password:    recordPassword,
+		BaseDN:      testserver.UsersBaseDN,
+	}
+	wantUser, wantAll, _ := lookups(t, config)
+	rec.Close()
+
+	path := filepath.Join(t.TempDir(), "lookups.json")
+	if err := rec.Recording().WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), recordPassword) || strings.Contains(string(data), "SSHA") {
+		t.Error("Expected the recording to leave out the bind password and redact userPassword")
+	}
+	srv.Close() // replay must not need the server
+
+	recording, err := ldaptest.LoadRecording(path)
+	if err != nil {
+		t.Fatalf("LoadRecording failed: %v", err)
+	}
+	replayer, err := ldaptest.NewReplayer(recording)
+	if err != nil {
+		t.Fatalf("NewReplayer failed: %v", err)
+	}
+	defer replayer.Close()
+	config.LdapServers = []string{replayer.URL()}
+	config.Password = "not-the-password"
+
+	user, all, missing := lookups(t, config)
+	if user.UID != wantUser.UID || user.Email != wantUser.Email || user.ManagerUID != wantUser.ManagerUID {
+		t.Errorf("Expected replayed user %+v, got %+v", wantUser, user)
+	}
+	if len(all) != len(wantAll) || len(all) != 25 {
+		t.Errorf("Expected 25 replayed users, got %d (recorded %d)", len(all), len(wantAll))
+	}
+	if !errors.Is(missing, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected the recorded miss to replay as ErrUserNotFound, got %v", missing)
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 0 {
+		t.Errorf("Expected every request to be recorded, got unmatched %v", unmatched)
+	}
+
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	if _, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "unrecorded"}); err == nil {
+		t.Error("Expected an unrecorded search to fail")
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 1 || !strings.Contains(unmatched[0], "unrecorded") {
+		t.Errorf("Expected the unrecorded search to be reported, got %v", unmatched)
+	}
+}
+
+func TestRecordOrReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lookups.json")
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(5))
+	srv.AddBind(recordBindDN, recordPassword)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	live := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    recordBindDN,
+		Password:    recordPassword,
+		BaseDN:      testserver.UsersBaseDN,
+	}
+
+	t.Run("record", func(t *testing.T) {
+		t.Setenv(ldaptest.RecordEnv, "1")
+		lookups(t, ldaptest.RecordOrReplay(t, path, live))
+	})
+	srv.Close()
+
+	t.Run("replay", func(t *testing.T) {
+		live.Password = ""
+		user, all, _ := lookups(t, ldaptest.RecordOrReplay(t, path, live))
+		if user.UID != testserver.UserUID(3) || len(all) != 5 {
+			t.Errorf("Unexpected replayed results: %s, %d users", user.UID, len(all))
+		}
+	})
+}
@@ -0,0 +1,308 @@
+package ldaptest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// RecorderOptions configures a Recorder.
+type RecorderOptions struct {
+	// TLSConfig secures connections to an ldaps:// upstream, or an ldap://
+	// one with StartTLS. Nil verifies the server against the system roots.
+	TLSConfig *tls.Config
+	// StartTLS upgrades connections to an ldap:// upstream with StartTLS.
+	StartTLS bool
+	// RedactAttributes are recorded as "REDACTED", on top of
+	// DefaultRedactedAttributes.
+	RedactAttributes []string
+}
+
+// Recorder is an LDAP proxy on the loopback interface that forwards each
+// client connection to an upstream server and records the requests and
+// responses, for a Replayer to serve in tests that cannot reach the
+// directory, such as in CI:
+//
+//	rec, err := ldaptest.NewRecorder("ldaps://ldap.corp.redhat.com", ldaptest.RecorderOptions{})
+//	...
+//	config.LdapServers = []string{rec.URL()} // plain LDAP: the Recorder does the TLS
+//	searcher, err := ldap_redhat.NewSearcher(config)
+//	...
+//	rec.Close()
+//	err = rec.Recording().WriteFile("testdata/lookups.json")
+//
+// Clients connect in the clear and must not use StartTLS, which the Recorder
+// refuses. Bind passwords are never recorded. RecordOrReplay wires a
+// Recorder or a Replayer into a test's configuration.
+type Recorder struct {
+	upstream  string
+	opts      RecorderOptions
+	redact    map[string]bool
+	ln        net.Listener
+	wg        sync.WaitGroup
+	mu        sync.Mutex // guards exchanges and conns
+	exchanges []*Exchange
+	conns     map[net.Conn]struct{}
+}
+
+// NewRecorder starts a Recorder forwarding to upstream, an ldap://, ldaps://
+// or ldapi:// URL.
+func NewRecorder(upstream string, opts RecorderOptions) (*Recorder, error) {
+	if _, err := url.Parse(upstream); err != nil {
+		return nil, fmt.Errorf("invalid upstream URL %s: %w", upstream, err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	r := &Recorder{
+		upstream: upstream,
+		opts:     opts,
+		redact:   redactSet(opts.RedactAttributes),
+		ln:       ln,
+		conns:    map[net.Conn]struct{}{},
+	}
+	r.wg.Add(1)
+	go r.acceptLoop()
+	return r, nil
+}
+
+// URL returns the ldap:// URL clients connect to.
+func (r *Recorder) URL() string {
+	return "ldap://" + r.ln.Addr().String()
+}
+
+// Recording returns the exchanges recorded so far. Close the clients before
+// the Recorder, so that no request is left without its responses.
+func (r *Recorder) Recording() *Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := &Recording{Exchanges: make([]Exchange, 0, len(r.exchanges))}
+	for _, ex := range r.exchanges {
+		rec.Exchanges = append(rec.Exchanges, Exchange{Request: ex.Request, Responses: append([]RecordedResponse(nil), ex.Responses...)})
+	}
+	return rec
+}
+
+// Close stops accepting connections and closes the open ones.
+func (r *Recorder) Close() error {
+	err := r.ln.Close()
+	r.mu.Lock()
+	for c := range r.conns {
+		c.Close()
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+func (r *Recorder) acceptLoop() {
+	defer r.wg.Done()
+	for {
+		c, err := r.ln.Accept()
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		r.conns[c] = struct{}{}
+		r.mu.Unlock()
+		r.wg.Add(1)
+		go r.serve(c)
+	}
+}
+
+// serve forwards the messages of client to its own upstream connection and
+// back, recording requests and the responses with their message IDs
+func (r *Recorder) serve(client net.Conn) {
+	defer r.wg.Done()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, client)
+		r.mu.Unlock()
+		client.Close()
+	}()
+	upstream, err := r.dial()
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	r.conns[upstream] = struct{}{}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, upstream)
+		r.mu.Unlock()
+		upstream.Close()
+	}()
+
+	var (
+		wmu     sync.Mutex // serializes writes to client
+		pmu     sync.Mutex // guards pending
+		pending = map[int64]*Exchange{}
+	)
+	write := func(p *ber.Packet) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_, err := client.Write(p.Bytes())
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer client.Close()
+		for {
+			packet, err := ber.ReadPacket(upstream)
+			if err != nil {
+				return
+			}
+			if msgID, ok := messageID(packet); ok {
+				pmu.Lock()
+				ex := pending[msgID]
+				pmu.Unlock()
+				if resp, final, ok := decodeResponse(packet, r.redact); ok && ex != nil {
+					r.mu.Lock()
+					ex.Responses = append(ex.Responses, resp)
+					r.mu.Unlock()
+					if final {
+						pmu.Lock()
+						delete(pending, msgID)
+						pmu.Unlock()
+					}
+				}
+			}
+			if err := write(packet); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		packet, err := ber.ReadPacket(client)
+		if err != nil {
+			break
+		}
+		msgID, _ := messageID(packet)
+		req, ok := decodeRequest(packet)
+		if ok && req.Op == "extended" && req.Name == startTLSOID {
+			if write(refuseStartTLS(req).encode(msgID)) != nil {
+				break
+			}
+			continue
+		}
+		if ok {
+			ex := &Exchange{Request: req}
+			r.mu.Lock()
+			r.exchanges = append(r.exchanges, ex)
+			r.mu.Unlock()
+			pmu.Lock()
+			pending[msgID] = ex
+			pmu.Unlock()
+		}
+		if _, err := upstream.Write(packet.Bytes()); err != nil {
+			break
+		}
+	}
+	upstream.Close()
+	<-done
+}
+
+// dial connects to the upstream server, upgrading ldap:// connections with
+// StartTLS if configured
+func (r *Recorder) dial() (net.Conn, error) {
+	u, err := url.Parse(r.upstream)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: ldap_redhat.DefaultDialTimeout}
+	switch strings.ToLower(u.Scheme) {
+	case "ldapi":
+		path, err := ldap_redhat.LDAPISocketPath(r.upstream)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.Dial("unix", path)
+	case "ldaps":
+		return tls.DialWithDialer(dialer, "tcp", hostPort(u, "636"), r.tlsConfig(u))
+	}
+	conn, err := dialer.Dial("tcp", hostPort(u, "389"))
+	if err != nil || !r.opts.StartTLS {
+		return conn, err
+	}
+	if err := startTLS(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, r.tlsConfig(u))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("StartTLS handshake with %s failed: %w", u.Host, err)
+	}
+	return tlsConn, nil
+}
+
+func (r *Recorder) tlsConfig(u *url.URL) *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r.opts.TLSConfig != nil {
+		config = r.opts.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = u.Hostname()
+	}
+	return config
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// startTLS sends the StartTLS extended operation on conn and waits for the
+// server to accept it. It uses the highest message ID, which the client's
+// own requests will not reach.
+func startTLS(conn net.Conn) error {
+	op := newOp(ldap.ApplicationExtendedRequest, "Extended Request")
+	op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, startTLSOID, "Request Name"))
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Request")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(math.MaxInt32), "MessageID"))
+	packet.AppendChild(op)
+	if _, err := conn.Write(packet.Bytes()); err != nil {
+		return fmt.Errorf("StartTLS failed: %w", err)
+	}
+	resp, err := ber.ReadPacket(conn)
+	if err != nil {
+		return fmt.Errorf("StartTLS failed: %w", err)
+	}
+	result, _, ok := decodeResponse(resp, nil)
+	if !ok || result.Op != "extended" {
+		return fmt.Errorf("StartTLS failed: unexpected response")
+	}
+	if result.Code != ldap.LDAPResultSuccess {
+		return fmt.Errorf("StartTLS failed: %s", ldap.LDAPResultCodeMap[uint16(result.Code)])
+	}
+	return nil
+}
+
+// refuseStartTLS answers a client's StartTLS request
+func refuseStartTLS(req RecordedRequest) RecordedResponse {
+	return finalResponse(req, ldap.LDAPResultUnwillingToPerform, "ldaptest: connect without StartTLS")
+}
+
+// messageID returns the message ID of an LDAPMessage
+func messageID(packet *ber.Packet) (int64, bool) {
+	if len(packet.Children) == 0 {
+		return 0, false
+	}
+	id, ok := packet.Children[0].Value.(int64)
+	return id, ok
+}
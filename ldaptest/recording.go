@@ -0,0 +1,431 @@
+package ldaptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// redacted replaces secret attribute values in recordings
+const redacted = "REDACTED"
+
+// sessionTrackingOID is the session tracking control the searcher sends with
+// every request. It names the host and connection, so it is left out of
+// recorded requests.
+const sessionTrackingOID = "1.3.6.1.4.1.21008.108.63.1"
+
+// startTLSOID is the StartTLS extended operation, which the Recorder and
+// Replayer refuse: clients talk to them in the clear on the loopback
+// interface.
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// DefaultRedactedAttributes are the attributes whose values a Recorder
+// replaces with "REDACTED", on top of RecorderOptions.RedactAttributes.
+var DefaultRedactedAttributes = []string{
+	"userPassword", "authPassword", "krbPrincipalKey", "sambaNTPassword", "sambaLMPassword", "unicodePwd",
+}
+
+// Recording is the LDAP traffic captured by a Recorder and served back by a
+// Replayer: each request with the responses the server answered it with, in
+// the order the requests were made. Bind passwords and SASL credentials are
+// never recorded, nor are the values of the Recorder's redacted attributes.
+type Recording struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Exchange is a request and its responses.
+type Exchange struct {
+	Request   RecordedRequest    `json:"request"`
+	Responses []RecordedResponse `json:"responses"`
+}
+
+// RecordedRequest is the part of a request a Replayer matches on. Message
+// IDs, time limits and session tracking controls vary between runs and are
+// left out.
+type RecordedRequest struct {
+	Op         string            `json:"op"`             // "bind", "search" or "extended"
+	DN         string            `json:"dn,omitempty"`   // bind DN or search base
+	Name       string            `json:"name,omitempty"` // SASL mechanism or extended operation OID
+	Scope      int64             `json:"scope,omitempty"`
+	Deref      int64             `json:"deref,omitempty"`
+	SizeLimit  int64             `json:"size_limit,omitempty"`
+	TypesOnly  bool              `json:"types_only,omitempty"`
+	Filter     string            `json:"filter,omitempty"`
+	Attributes []string          `json:"attributes,omitempty"`
+	Controls   []RecordedControl `json:"controls,omitempty"`
+}
+
+// String returns the request as a Replayer reports unmatched requests.
+func (r RecordedRequest) String() string {
+	switch r.Op {
+	case "search":
+		return fmt.Sprintf("search %q %s attributes=%v", r.DN, r.Filter, r.Attributes)
+	case "bind":
+		return fmt.Sprintf("bind %q %s", r.DN, r.Name)
+	default:
+		return fmt.Sprintf("%s %s", r.Op, r.Name)
+	}
+}
+
+// key identifies requests a Replayer answers alike
+func (r RecordedRequest) key() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+// RecordedResponse is one response message.
+type RecordedResponse struct {
+	Op         string              `json:"op"` // "bind", "entry", "reference", "done", "extended" or "intermediate"
+	DN         string              `json:"dn,omitempty"`
+	Attributes []RecordedAttribute `json:"attributes,omitempty"`
+	Code       int64               `json:"code,omitempty"`
+	MatchedDN  string              `json:"matched_dn,omitempty"`
+	Message    string              `json:"message,omitempty"`
+	Referrals  []string            `json:"referrals,omitempty"` // referral URLs of a result, or the URLs of a reference
+	Name       string              `json:"name,omitempty"`      // extended or intermediate response OID
+	Value      []byte              `json:"value,omitempty"`     // extended or intermediate response value
+	Controls   []RecordedControl   `json:"controls,omitempty"`
+}
+
+// RecordedAttribute is an attribute of an entry. Values that are not valid
+// UTF-8, such as photos, are kept in Binary instead of Values.
+type RecordedAttribute struct {
+	Type   string   `json:"type"`
+	Values []string `json:"values,omitempty"`
+	Binary [][]byte `json:"binary,omitempty"`
+}
+
+// RecordedControl is a request or response control.
+type RecordedControl struct {
+	Type     string `json:"type"`
+	Critical bool   `json:"critical,omitempty"`
+	Value    []byte `json:"value,omitempty"`
+}
+
+// LoadRecording reads a recording written by Recording.WriteFile.
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording %s: %w", path, err)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+// WriteFile writes the recording to path as indented JSON, so that changes
+// to checked-in recordings review well.
+func (r *Recording) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write recording %s: %w", path, err)
+	}
+	return nil
+}
+
+// decodeRequest returns the recorded form of a request message, and false for
+// operations that are not recorded (unbind, abandon and writes).
+func decodeRequest(packet *ber.Packet) (RecordedRequest, bool) {
+	if len(packet.Children) < 2 {
+		return RecordedRequest{}, false
+	}
+	op := packet.Children[1]
+	var req RecordedRequest
+	switch op.Tag {
+	case ldap.ApplicationBindRequest:
+		if len(op.Children) < 3 {
+			return RecordedRequest{}, false
+		}
+		req.Op = "bind"
+		req.DN, _ = op.Children[1].Value.(string)
+		if auth := op.Children[2]; auth.Tag == 3 && len(auth.Children) > 0 {
+			req.Name = auth.Children[0].Data.String() // the SASL mechanism; credentials are dropped
+		}
+		// the simple bind password is dropped
+	case ldap.ApplicationSearchRequest:
+		if len(op.Children) < 8 {
+			return RecordedRequest{}, false
+		}
+		req.Op = "search"
+		req.DN, _ = op.Children[0].Value.(string)
+		req.Scope, _ = op.Children[1].Value.(int64)
+		req.Deref, _ = op.Children[2].Value.(int64)
+		req.SizeLimit, _ = op.Children[3].Value.(int64)
+		req.TypesOnly, _ = op.Children[5].Value.(bool)
+		req.Filter, _ = ldap.DecompileFilter(op.Children[6])
+		for _, a := range op.Children[7].Children {
+			req.Attributes = append(req.Attributes, a.Data.String())
+		}
+	case ldap.ApplicationExtendedRequest:
+		req.Op = "extended"
+		if len(op.Children) > 0 {
+			req.Name = op.Children[0].Data.String() // request values are dropped
+		}
+	default:
+		return RecordedRequest{}, false
+	}
+	if len(packet.Children) > 2 {
+		for _, c := range decodeControls(packet.Children[2]) {
+			if c.Type != sessionTrackingOID {
+				req.Controls = append(req.Controls, c)
+			}
+		}
+	}
+	return req, true
+}
+
+// decodeResponse returns the recorded form of a response message, with the
+// values of the attributes in redact replaced, and whether it ends its
+// request. It returns false for messages it does not record.
+func decodeResponse(packet *ber.Packet, redact map[string]bool) (resp RecordedResponse, final, ok bool) {
+	if len(packet.Children) < 2 {
+		return RecordedResponse{}, false, false
+	}
+	op := packet.Children[1]
+	switch op.Tag {
+	case ldap.ApplicationBindResponse:
+		resp.Op, final = "bind", true
+		decodeResult(&resp, op)
+	case ldap.ApplicationSearchResultDone:
+		resp.Op, final = "done", true
+		decodeResult(&resp, op)
+	case ldap.ApplicationExtendedResponse:
+		resp.Op, final = "extended", true
+		decodeResult(&resp, op)
+	case ldap.ApplicationSearchResultEntry:
+		resp.Op = "entry"
+		if len(op.Children) < 2 {
+			return RecordedResponse{}, false, false
+		}
+		resp.DN = op.Children[0].Data.String()
+		for _, a := range op.Children[1].Children {
+			if len(a.Children) < 2 {
+				continue
+			}
+			attr := RecordedAttribute{Type: a.Children[0].Data.String()}
+			for _, v := range a.Children[1].Children {
+				attr.Binary = append(attr.Binary, bytes.Clone(v.Data.Bytes()))
+			}
+			if redact[strings.ToLower(attr.Type)] {
+				attr.Binary = [][]byte{[]byte(redacted)}
+			}
+			if allUTF8(attr.Binary) {
+				for _, v := range attr.Binary {
+					attr.Values = append(attr.Values, string(v))
+				}
+				attr.Binary = nil
+			}
+			resp.Attributes = append(resp.Attributes, attr)
+		}
+	case ldap.ApplicationSearchResultReference:
+		resp.Op = "reference"
+		for _, url := range op.Children {
+			resp.Referrals = append(resp.Referrals, url.Data.String())
+		}
+	case ldap.ApplicationIntermediateResponse:
+		resp.Op = "intermediate"
+		for _, c := range op.Children {
+			switch c.Tag {
+			case 0:
+				resp.Name = c.Data.String()
+			case 1:
+				resp.Value = bytes.Clone(c.Data.Bytes())
+			}
+		}
+	default:
+		return RecordedResponse{}, false, false
+	}
+	if len(packet.Children) > 2 {
+		resp.Controls = decodeControls(packet.Children[2])
+	}
+	return resp, final, true
+}
+
+// decodeResult fills resp from an LDAPResult and the fields bind and extended
+// responses append to it
+func decodeResult(resp *RecordedResponse, op *ber.Packet) {
+	if len(op.Children) < 3 {
+		return
+	}
+	resp.Code, _ = op.Children[0].Value.(int64)
+	resp.MatchedDN = op.Children[1].Data.String()
+	resp.Message = op.Children[2].Data.String()
+	for _, c := range op.Children[3:] {
+		switch {
+		case c.Tag == 3: // referral
+			for _, url := range c.Children {
+				resp.Referrals = append(resp.Referrals, url.Data.String())
+			}
+		case c.Tag == 10 && op.Tag == ldap.ApplicationExtendedResponse:
+			resp.Name = c.Data.String()
+		case c.Tag == 11 && op.Tag == ldap.ApplicationExtendedResponse:
+			resp.Value = bytes.Clone(c.Data.Bytes())
+		}
+	}
+}
+
+func decodeControls(p *ber.Packet) []RecordedControl {
+	var controls []RecordedControl
+	for _, c := range p.Children {
+		if len(c.Children) == 0 {
+			continue
+		}
+		ctrl := RecordedControl{Type: c.Children[0].Data.String()}
+		for _, field := range c.Children[1:] {
+			switch field.Tag {
+			case ber.TagBoolean:
+				ctrl.Critical, _ = field.Value.(bool)
+			case ber.TagOctetString:
+				ctrl.Value = bytes.Clone(field.Data.Bytes())
+			}
+		}
+		controls = append(controls, ctrl)
+	}
+	return controls
+}
+
+func allUTF8(values [][]byte) bool {
+	for _, v := range values {
+		if !utf8.Valid(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// encode returns the response as an LDAPMessage with msgID
+func (r RecordedResponse) encode(msgID int64) *ber.Packet {
+	var op *ber.Packet
+	switch r.Op {
+	case "entry":
+		op = newOp(ldap.ApplicationSearchResultEntry, "Search Result Entry")
+		op.AppendChild(octetString(r.DN, "Object Name"))
+		list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for _, a := range r.Attributes {
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attr.AppendChild(octetString(a.Type, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range a.Values {
+				set.AppendChild(octetString(v, "Value"))
+			}
+			for _, v := range a.Binary {
+				set.AppendChild(octetString(string(v), "Value"))
+			}
+			attr.AppendChild(set)
+			list.AppendChild(attr)
+		}
+		op.AppendChild(list)
+	case "reference":
+		op = newOp(ldap.ApplicationSearchResultReference, "Search Result Reference")
+		for _, url := range r.Referrals {
+			op.AppendChild(octetString(url, "URI"))
+		}
+	case "intermediate":
+		op = newOp(ldap.ApplicationIntermediateResponse, "Intermediate Response")
+		if r.Name != "" {
+			op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, r.Name, "Response Name"))
+		}
+		if r.Value != nil {
+			op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 1, string(r.Value), "Response Value"))
+		}
+	default:
+		var tag ber.Tag = ldap.ApplicationSearchResultDone
+		description := "Search Result Done"
+		switch r.Op {
+		case "bind":
+			tag, description = ldap.ApplicationBindResponse, "Bind Response"
+		case "extended":
+			tag, description = ldap.ApplicationExtendedResponse, "Extended Response"
+		}
+		op = resultOp(tag, description, r.Code, r.MatchedDN, r.Message)
+		if len(r.Referrals) > 0 {
+			refs := ber.Encode(ber.ClassContext, ber.TypeConstructed, 3, nil, "Referral")
+			for _, url := range r.Referrals {
+				refs.AppendChild(octetString(url, "URI"))
+			}
+			op.AppendChild(refs)
+		}
+		if r.Op == "extended" && r.Name != "" {
+			op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 10, r.Name, "Response Name"))
+		}
+		if r.Op == "extended" && r.Value != nil {
+			op.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 11, string(r.Value), "Response Value"))
+		}
+	}
+	return envelope(msgID, op, r.Controls)
+}
+
+func newOp(tag ber.Tag, description string) *ber.Packet {
+	return ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, description)
+}
+
+func octetString(s, description string) *ber.Packet {
+	return ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, s, description)
+}
+
+// resultOp returns a response op holding an LDAPResult
+func resultOp(tag ber.Tag, description string, code int64, matchedDN, message string) *ber.Packet {
+	op := newOp(tag, description)
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	op.AppendChild(octetString(matchedDN, "Matched DN"))
+	op.AppendChild(octetString(message, "Diagnostic Message"))
+	return op
+}
+
+// envelope wraps a complete op into an LDAPMessage: ber packets copy child
+// bytes when appended
+func envelope(msgID int64, op *ber.Packet, controls []RecordedControl) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, msgID, "MessageID"))
+	packet.AppendChild(op)
+	if len(controls) > 0 {
+		wrapper := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		for _, c := range controls {
+			ctrl := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+			ctrl.AppendChild(octetString(c.Type, "Control Type"))
+			if c.Critical {
+				ctrl.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "Criticality"))
+			}
+			if c.Value != nil {
+				ctrl.AppendChild(octetString(string(c.Value), "Control Value"))
+			}
+			wrapper.AppendChild(ctrl)
+		}
+		packet.AppendChild(wrapper)
+	}
+	return packet
+}
+
+// finalResponse returns the response that ends a request answered with code
+// and message instead of a recorded response
+func finalResponse(req RecordedRequest, code int64, message string) RecordedResponse {
+	op := "done"
+	switch req.Op {
+	case "bind", "extended":
+		op = req.Op
+	}
+	return RecordedResponse{Op: op, Code: code, Message: message}
+}
+
+// redactSet returns the lowercased attribute names of DefaultRedactedAttributes
+// and extra
+func redactSet(extra []string) map[string]bool {
+	set := map[string]bool{}
+	for _, names := range [][]string{DefaultRedactedAttributes, extra} {
+		for _, name := range names {
+			set[strings.ToLower(name)] = true
+		}
+	}
+	return set
+}
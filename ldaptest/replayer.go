@@ -0,0 +1,208 @@
+package ldaptest
+
+import (
+	"net"
+	"os"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// RecordEnv is the environment variable that makes RecordOrReplay record
+// against the live directory instead of replaying, when set to any value.
+const RecordEnv = "LDAPTEST_RECORD"
+
+// Replayer is an LDAP server on the loopback interface that answers requests
+// with the responses of a Recording. A request is matched to the recorded
+// requests equal to it, ignoring message IDs, time limits and session
+// tracking controls; when a request was recorded several times, the
+// recorded responses are served in order and the last is repeated. Binds
+// succeed or fail as recorded whatever the password, so tests replay without
+// credentials. Requests that were not recorded fail with result code Other
+// and are listed by Unmatched.
+type Replayer struct {
+	ln    net.Listener
+	wg    sync.WaitGroup
+	mu    sync.Mutex // guards queues, unmatched and conns
+	conns map[net.Conn]struct{}
+	// queues holds the recorded exchanges not yet replayed, by request key
+	queues    map[string][]Exchange
+	unmatched []string
+}
+
+// NewReplayer starts a Replayer serving rec.
+func NewReplayer(rec *Recording) (*Replayer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &Replayer{ln: ln, conns: map[net.Conn]struct{}{}, queues: map[string][]Exchange{}}
+	for _, ex := range rec.Exchanges {
+		key := ex.Request.key()
+		p.queues[key] = append(p.queues[key], ex)
+	}
+	p.wg.Add(1)
+	go p.acceptLoop()
+	return p, nil
+}
+
+// URL returns the ldap:// URL clients connect to.
+func (p *Replayer) URL() string {
+	return "ldap://" + p.ln.Addr().String()
+}
+
+// Unmatched returns the requests received that had no recorded responses.
+func (p *Replayer) Unmatched() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.unmatched...)
+}
+
+// Close stops accepting connections and closes the open ones.
+func (p *Replayer) Close() error {
+	err := p.ln.Close()
+	p.mu.Lock()
+	for c := range p.conns {
+		c.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return err
+}
+
+func (p *Replayer) acceptLoop() {
+	defer p.wg.Done()
+	for {
+		c, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		p.conns[c] = struct{}{}
+		p.mu.Unlock()
+		p.wg.Add(1)
+		go p.serve(c)
+	}
+}
+
+func (p *Replayer) serve(c net.Conn) {
+	defer p.wg.Done()
+	defer func() {
+		p.mu.Lock()
+		delete(p.conns, c)
+		p.mu.Unlock()
+		c.Close()
+	}()
+	for {
+		packet, err := ber.ReadPacket(c)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		msgID, ok := messageID(packet)
+		if !ok {
+			return
+		}
+		switch packet.Children[1].Tag {
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationAbandonRequest:
+			continue
+		}
+		for _, resp := range p.answer(packet) {
+			if _, err := c.Write(resp.encode(msgID).Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// answer returns the responses to the request message packet
+func (p *Replayer) answer(packet *ber.Packet) []RecordedResponse {
+	req, ok := decodeRequest(packet)
+	if !ok {
+		// not a recorded operation: answer as servers answer unknown ones
+		return []RecordedResponse{{Op: "extended", Code: ldap.LDAPResultUnwillingToPerform, Message: "ldaptest: unsupported operation"}}
+	}
+	if req.Op == "extended" && req.Name == startTLSOID {
+		return []RecordedResponse{refuseStartTLS(req)}
+	}
+	key := req.key()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	queue := p.queues[key]
+	if len(queue) == 0 {
+		p.unmatched = append(p.unmatched, req.String())
+		return []RecordedResponse{finalResponse(req, ldap.LDAPResultOther, "ldaptest: no recorded response for "+req.String())}
+	}
+	if len(queue) > 1 {
+		p.queues[key] = queue[1:]
+	}
+	return queue[0].Responses
+}
+
+// RecordOrReplay returns live with its server replaced for a test: by a
+// Replayer serving the recording at path, or, when the RecordEnv variable is
+// set, by a Recorder proxying to the first of live.LdapServers that writes
+// the recording to path when the test ends. Record once against the
+// directory, check the recording in, and the test runs in CI without
+// network access or credentials:
+//
+//	LDAPTEST_RECORD=1 LDAP_PASSWORD=... go test -run TestManagerChain ./...
+//
+// When replaying, the test fails on requests missing from the recording,
+// which means it must be recorded again. Close searchers using the returned
+// config before the test ends, e.g. with t.Cleanup registered after calling
+// RecordOrReplay.
+func RecordOrReplay(t testing.TB, path string, live ldap_redhat.Config) ldap_redhat.Config {
+	t.Helper()
+	config := live
+	config.UseStartTLS = false
+	config.TLSServerName, config.CAFile, config.CACertPEM = "", "", ""
+	config.ClientCertFile, config.ClientKeyFile = "", ""
+
+	if os.Getenv(RecordEnv) != "" {
+		if len(live.LdapServers) == 0 {
+			t.Fatalf("ldaptest: %s is set but the config names no LDAP server to record", RecordEnv)
+		}
+		upstream := live.LdapServers[0]
+		tlsConfig, err := live.TLSConfig(upstream)
+		if err != nil {
+			t.Fatalf("ldaptest: %v", err)
+		}
+		rec, err := NewRecorder(upstream, RecorderOptions{TLSConfig: tlsConfig, StartTLS: live.UseStartTLS})
+		if err != nil {
+			t.Fatalf("ldaptest: failed to start recorder: %v", err)
+		}
+		t.Cleanup(func() {
+			rec.Close()
+			if err := rec.Recording().WriteFile(path); err != nil {
+				t.Errorf("ldaptest: %v", err)
+			}
+		})
+		config.LdapServers = []string{rec.URL()}
+		return config
+	}
+
+	rec, err := LoadRecording(path)
+	if err != nil {
+		t.Fatalf("ldaptest: %v (record it with %s=1)", err, RecordEnv)
+	}
+	p, err := NewReplayer(rec)
+	if err != nil {
+		t.Fatalf("ldaptest: failed to start replayer: %v", err)
+	}
+	t.Cleanup(func() {
+		p.Close()
+		for _, req := range p.Unmatched() {
+			t.Errorf("ldaptest: %s was not recorded in %s; record again with %s=1", req, path, RecordEnv)
+		}
+	})
+	config.LdapServers = []string{p.URL()}
+	if config.Username != "" && !config.HasCredentials() {
+		config.Password = "replayed" // any password binds as recorded
+	}
+	return config
+}
@@ -0,0 +1,222 @@
+// Package ldif writes user records in the LDAP Data Interchange Format (RFC
+// 2849), for downstream identity tools that ingest LDIF rather than JSON.
+// Each record becomes a content record with the attributes it was read from;
+// fields derived by the library, such as Status and the parsed dates, are
+// not written.
+package ldif
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// DefaultBaseDN is the container of the entries written when
+// Encoder.BaseDN is empty
+const DefaultBaseDN = "ou=users,dc=redhat,dc=com"
+
+// lineWidth is the length past which lines are folded
+const lineWidth = 76
+
+// objectClasses are written for every entry
+var objectClasses = []string{"top", "person", "organizationalPerson", "inetOrgPerson"}
+
+// attribute is an LDAP attribute and the record field read from it, by
+// value and by JSON name for masking
+type attribute struct {
+	name  string
+	field string
+	value func(u *ldap_redhat.UserRecord) string
+}
+
+// attributes are written in the order the library requests them
+var attributes = []attribute{
+	{"uid", "uid", func(u *ldap_redhat.UserRecord) string { return u.UID }},
+	{"mail", "email", func(u *ldap_redhat.UserRecord) string { return u.Email }},
+	{"cn", "display_name", func(u *ldap_redhat.UserRecord) string { return u.DisplayName }},
+	{"sn", "surname", func(u *ldap_redhat.UserRecord) string { return u.Surname }},
+	{"title", "title", func(u *ldap_redhat.UserRecord) string { return u.Title }},
+	{"manager", "manager_dn", func(u *ldap_redhat.UserRecord) string { return u.ManagerDN }},
+	{"rhatCostCenter", "cost_center", func(u *ldap_redhat.UserRecord) string { return u.CostCenter }},
+	{"rhatCostCenterDesc", "cost_center_desc", func(u *ldap_redhat.UserRecord) string { return u.CostCenterDesc }},
+	{"rhatLocation", "rhat_location", func(u *ldap_redhat.UserRecord) string { return u.RhatLocation }},
+	{"rhatJobCode", "rhat_job_code", func(u *ldap_redhat.UserRecord) string { return u.RhatJobCode }},
+	{"rhatUUID", "rhat_uuid", func(u *ldap_redhat.UserRecord) string { return u.RhatUUID }},
+	{"rhatHireDate", "rhat_hire_date", func(u *ldap_redhat.UserRecord) string { return u.RhatHireDate }},
+	{"rhatTermDate", "rhat_term_date", func(u *ldap_redhat.UserRecord) string { return u.RhatTermDate }},
+	{"rhatAdjSvcDate", "rhat_adj_svc_date", func(u *ldap_redhat.UserRecord) string { return u.RhatAdjSvcDate }},
+	{"co", "country", func(u *ldap_redhat.UserRecord) string { return u.Country }},
+	{"ou", "department", func(u *ldap_redhat.UserRecord) string { return u.Department }},
+	{"employeeNumber", "employee_number", func(u *ldap_redhat.UserRecord) string { return u.EmployeeNumber }},
+	{"krbPrincipalName", "kerberos_principal", func(u *ldap_redhat.UserRecord) string { return u.KerberosPrincipal }},
+	{"rhatGeo", "geo", func(u *ldap_redhat.UserRecord) string { return u.Geo }},
+	{"rhatOrgCharTitle", "org_chart_title", func(u *ldap_redhat.UserRecord) string { return u.OrgChartTitle }},
+	{"rhatPersonType", "person_type", func(u *ldap_redhat.UserRecord) string { return u.PersonType }},
+	{"rhatBuilding", "building", func(u *ldap_redhat.UserRecord) string { return u.Building }},
+	{"mobile", "mobile", func(u *ldap_redhat.UserRecord) string { return u.Mobile }},
+	{"telephoneNumber", "telephone_number", func(u *ldap_redhat.UserRecord) string { return u.TelephoneNumber }},
+}
+
+// Encoder writes records to an LDIF stream, preceded by the version line.
+type Encoder struct {
+	// BaseDN is the container each entry's DN is built under, as
+	// uid=<UID>,<BaseDN>. Records do not carry the DN they were read from.
+	BaseDN string
+	// Masking, when it has fields, is applied to every entry as
+	// MaskingPolicy.Apply does: dropped fields are left out and hashed or
+	// truncated ones written masked. Fields are named by their JSON names,
+	// so "rhat_term_date" masks rhatTermDate. The DN is built from the
+	// masked UID, and a policy dropping "uid" is rejected.
+	Masking ldap_redhat.MaskingPolicy
+
+	w       io.Writer
+	started bool
+}
+
+// NewEncoder returns an Encoder writing to w with DefaultBaseDN.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes u as the next entry of the stream.
+func (e *Encoder) Encode(u ldap_redhat.UserRecord) error {
+	var buf bytes.Buffer
+	if e.started {
+		buf.WriteByte('\n')
+	} else {
+		if err := e.validate(); err != nil {
+			return err
+		}
+		buf.WriteString("version: 1\n")
+	}
+	e.appendEntry(&buf, &u)
+	if _, err := e.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	e.started = true
+	return nil
+}
+
+// MarshalLDIF returns the LDIF content record of u under DefaultBaseDN,
+// without a version line.
+func MarshalLDIF(u ldap_redhat.UserRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	(&Encoder{}).appendEntry(&buf, &u)
+	return buf.Bytes(), nil
+}
+
+// WriteLDIF writes users to w as an LDIF file under DefaultBaseDN.
+func WriteLDIF(w io.Writer, users []ldap_redhat.UserRecord) error {
+	enc := NewEncoder(w)
+	for _, u := range users {
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLDIFMasked writes users to w as WriteLDIF does, with policy applied
+// to every entry as described for Encoder.Masking.
+func WriteLDIFMasked(w io.Writer, users []ldap_redhat.UserRecord, policy ldap_redhat.MaskingPolicy) error {
+	enc := NewEncoder(w)
+	enc.Masking = policy
+	if err := enc.validate(); err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate rejects an invalid masking policy and one dropping the UID that
+// entries are named by
+func (e *Encoder) validate() error {
+	if err := e.Masking.Validate(); err != nil {
+		return fmt.Errorf("invalid masking policy: %w", err)
+	}
+	if mask, ok := e.Masking.Fields["uid"]; ok && mask.Action == ldap_redhat.MaskDrop {
+		return errors.New("invalid masking policy: LDIF entries are named by uid, which cannot be dropped")
+	}
+	return nil
+}
+
+// dn returns the DN of the entry for uid
+func (e *Encoder) dn(uid string) string {
+	base := e.BaseDN
+	if base == "" {
+		base = DefaultBaseDN
+	}
+	return "uid=" + ldap.EscapeDN(uid) + "," + base
+}
+
+// appendEntry writes the content record of u to buf, masked by e.Masking
+func (e *Encoder) appendEntry(buf *bytes.Buffer, u *ldap_redhat.UserRecord) {
+	var masked map[string]any
+	uid := u.UID
+	if len(e.Masking.Fields) > 0 {
+		masked = e.Masking.Apply(*u)
+		uid, _ = masked["uid"].(string)
+	}
+	appendLine(buf, "dn", e.dn(uid))
+	for _, oc := range objectClasses {
+		appendLine(buf, "objectClass", oc)
+	}
+	for _, attr := range attributes {
+		v := attr.value(u)
+		if masked != nil {
+			v, _ = masked[attr.field].(string)
+		}
+		if v != "" {
+			appendLine(buf, attr.name, v)
+		}
+	}
+}
+
+// appendLine writes an attribute line, base64-encoding values that are not
+// safe strings and folding it at lineWidth
+func appendLine(buf *bytes.Buffer, name, value string) {
+	line := name + ": " + value
+	if !safe(value) {
+		line = name + ":: " + base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	width := lineWidth
+	for len(line) > width {
+		buf.WriteString(line[:width])
+		buf.WriteString("\n ")
+		line = line[width:]
+		width = lineWidth - 1 // continuations start with a space
+	}
+	buf.WriteString(line)
+	buf.WriteByte('\n')
+}
+
+// safe reports whether value is a SAFE-STRING of RFC 2849 that can be
+// written as is. Values ending in a space are encoded too, so that trailing
+// whitespace survives editors and tools that trim it.
+func safe(value string) bool {
+	if value == "" {
+		return true
+	}
+	switch value[0] {
+	case ' ', ':', '<':
+		return false
+	}
+	if value[len(value)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c == 0 || c == '\n' || c == '\r' || c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
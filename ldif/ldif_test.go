@@ -0,0 +1,127 @@
+package ldif_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/ldif"
+)
+
+func TestMarshalLDIF(t *testing.T) {
+	u := ldap_redhat.UserRecord{
+		UID:          "jdoe",
+		Email:        "jdoe@redhat.com",
+		DisplayName:  "José Doe",
+		Surname:      "Doe",
+		Title:        " Engineer",
+		ManagerDN:    "uid=boss,ou=users,dc=redhat,dc=com",
+		CostCenter:   "123",
+		RhatHireDate: "20200101000000Z",
+		Status:       ldap_redhat.StatusActive,
+	}
+	data, err := ldif.MarshalLDIF(u)
+	if err != nil {
+		t.Fatalf("MarshalLDIF failed: %v", err)
+	}
+	want := `dn: uid=jdoe,ou=users,dc=redhat,dc=com
+objectClass: top
+objectClass: person
+objectClass: organizationalPerson
+objectClass: inetOrgPerson
+uid: jdoe
+mail: jdoe@redhat.com
+cn:: Sm9zw6kgRG9l
+sn: Doe
+title:: IEVuZ2luZWVy
+manager: uid=boss,ou=users,dc=redhat,dc=com
+rhatCostCenter: 123
+rhatHireDate: 20200101000000Z
+`
+	if string(data) != want {
+		t.Errorf("MarshalLDIF =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestMarshalLDIFFolding(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	data, _ := ldif.MarshalLDIF(ldap_redhat.UserRecord{UID: "jdoe", CostCenterDesc: long})
+	var unfolded strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if len(line) > 76 {
+			t.Errorf("Line of %d characters, want at most 76: %q", len(line), line)
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	if !strings.HasSuffix(unfolded.String(), "\nrhatCostCenterDesc: "+long) {
+		t.Errorf("Folded value does not unfold to the original:\n%s", data)
+	}
+}
+
+func TestWriteLDIF(t *testing.T) {
+	var buf bytes.Buffer
+	users := []ldap_redhat.UserRecord{{UID: "a"}, {UID: "b,c"}}
+	if err := ldif.WriteLDIF(&buf, users); err != nil {
+		t.Fatalf("WriteLDIF failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "version: 1\ndn: uid=a,ou=users,dc=redhat,dc=com\n") {
+		t.Errorf("Expected the version line then the first entry, got:\n%s", out)
+	}
+	if !strings.Contains(out, "uid: a\n\ndn: uid=b\\,c,ou=users,dc=redhat,dc=com\n") {
+		t.Errorf("Expected entries separated by a blank line with escaped DNs, got:\n%s", out)
+	}
+
+	buf.Reset()
+	enc := ldif.NewEncoder(&buf)
+	enc.BaseDN = "ou=contractors,dc=redhat,dc=com"
+	if err := enc.Encode(users[0]); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "dn: uid=a,ou=contractors,dc=redhat,dc=com\n") {
+		t.Errorf("Expected the entry under BaseDN, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteLDIFMasked(t *testing.T) {
+	policy := ldap_redhat.MaskingPolicy{
+		Salt: "pepper",
+		Fields: map[string]ldap_redhat.FieldMask{
+			"uid":            {Action: ldap_redhat.MaskHash},
+			"rhat_term_date": {Action: ldap_redhat.MaskDrop},
+			"title":          {Action: ldap_redhat.MaskTruncate, Length: 3},
+		},
+	}
+	user := ldap_redhat.UserRecord{UID: "jdoe", Title: "Engineer", RhatTermDate: "20240315000000Z"}
+	var buf bytes.Buffer
+	if err := ldif.WriteLDIFMasked(&buf, []ldap_redhat.UserRecord{user}, policy); err != nil {
+		t.Fatalf("WriteLDIFMasked failed: %v", err)
+	}
+	out := buf.String()
+	hash := policy.Apply(user)["uid"].(string)
+	unfolded := strings.ReplaceAll(out, "\n ", "")
+	if !strings.Contains(unfolded, "dn: uid="+hash+",ou=users,dc=redhat,dc=com\n") || !strings.Contains(unfolded, "\nuid: "+hash+"\n") {
+		t.Errorf("Expected the entry named by the hashed uid, got:\n%s", out)
+	}
+	if strings.Contains(out, "jdoe") || strings.Contains(out, "rhatTermDate") {
+		t.Errorf("Expected the uid hashed and rhatTermDate dropped, got:\n%s", out)
+	}
+	if !strings.Contains(out, "\ntitle: Eng\n") {
+		t.Errorf("Expected the title truncated, got:\n%s", out)
+	}
+
+	for _, bad := range []map[string]ldap_redhat.FieldMask{
+		{"uid": {Action: ldap_redhat.MaskDrop}},
+		{"salary": {Action: ldap_redhat.MaskDrop}},
+	} {
+		buf.Reset()
+		if err := ldif.WriteLDIFMasked(&buf, []ldap_redhat.UserRecord{user}, ldap_redhat.MaskingPolicy{Fields: bad}); err == nil || buf.Len() != 0 {
+			t.Errorf("Expected policy %v to be rejected before writing, got %v and %q", bad, err, buf.String())
+		}
+	}
+}
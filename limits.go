@@ -0,0 +1,84 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// PartialResultsError reports a search that stopped at a size limit before
+// returning every match: the SizeLimit of the config or request, or a size
+// or administrative limit of the server. SearchUsers, ForEachUser,
+// FindDirectReports and GetUserGroups return the results received before
+// the limit alongside it; lookups of single users fail with it. errors.As
+// also finds the go-ldap error carrying the server's result code.
+//
+// Searches made with a context from WithWarnings and no limit of their own
+// record a WarnTruncated warning instead, and succeed.
+type PartialResultsError struct {
+	BaseDN   string
+	Filter   string
+	Limit    int   // the request's size limit, 0 for a limit of the server
+	Returned int   // results received before the limit
+	Err      error // the sizeLimitExceeded or adminLimitExceeded error
+}
+
+func (e *PartialResultsError) Error() string {
+	limit := "a server limit"
+	if e.Limit > 0 {
+		limit = "the size limit of " + strconv.Itoa(e.Limit)
+	}
+	return fmt.Sprintf("search %s under %s stopped after %d results at %s: %v", e.Filter, e.BaseDN, e.Returned, limit, e.Err)
+}
+
+func (e *PartialResultsError) Unwrap() error { return e.Err }
+
+// limitExceeded reports whether err ended a search at a size or
+// administrative limit, after which the entries received are still valid
+func limitExceeded(err error) bool {
+	return ldap.IsErrorAnyOf(err, ldap.LDAPResultSizeLimitExceeded, ldap.LDAPResultAdminLimitExceeded)
+}
+
+// partialResults handles the error of a search of req that received
+// entries results. A search ended by a size or administrative limit is
+// reported as truncated when ctx collects warnings, and nil is returned so
+// the entries stand as the result; otherwise it returns a
+// *PartialResultsError. Other errors are returned as they are.
+func partialResults(ctx context.Context, req *ldap.SearchRequest, entries int, err error) error {
+	if !limitExceeded(err) {
+		return err
+	}
+	if truncated(ctx, req, entries, err) {
+		return nil
+	}
+	return &PartialResultsError{
+		BaseDN:   req.BaseDN,
+		Filter:   req.Filter,
+		Limit:    req.SizeLimit,
+		Returned: entries,
+		Err:      err,
+	}
+}
+
+// asPartial returns the *PartialResultsError in err, or nil
+func asPartial(err error) *PartialResultsError {
+	var partial *PartialResultsError
+	if errors.As(err, &partial) {
+		return partial
+	}
+	return nil
+}
+
+// sizeLimit returns the size limit for list searches in ctx, 0 for none.
+// Lookups of single users are not limited, so that they still detect
+// identifiers matching several entries.
+func (s *Searcher) sizeLimit(ctx context.Context) int {
+	opts, err := s.searchOptions(ctx)
+	if err != nil || opts.SizeLimit < 0 {
+		return 0
+	}
+	return opts.SizeLimit
+}
@@ -0,0 +1,66 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestSizeLimitReturnsPartialResults(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 10)
+	ctx := ldap_redhat.WithSearchOptions(context.Background(), ldap_redhat.SearchOptions{SizeLimit: 4})
+
+	users, err := searcher.SearchUsers(ctx, "")
+	var partial *ldap_redhat.PartialResultsError
+	if !errors.As(err, &partial) {
+		t.Fatalf("SearchUsers returned %v, want a *PartialResultsError", err)
+	}
+	if len(users) != 4 || partial.Returned != 4 || partial.Limit != 4 || partial.BaseDN != testserver.UsersBaseDN {
+		t.Errorf("Got %d users and %+v, want the 4 users before the limit", len(users), partial)
+	}
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		t.Errorf("PartialResultsError does not unwrap to the server's result: %v", err)
+	}
+
+	// Lookups of single users are not limited
+	one := ldap_redhat.WithSearchOptions(context.Background(), ldap_redhat.SearchOptions{SizeLimit: 1})
+	if _, err := searcher.GetUsers(one, []ldap_redhat.Identifier{uidIdentifier(1), uidIdentifier(2)}); err != nil {
+		t.Errorf("GetUsers with a size limit failed: %v", err)
+	}
+}
+
+func TestConfigSizeLimit(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 10)
+	searcher.Config.SizeLimit = 2
+	ctx := context.Background()
+
+	reports, err := searcher.FindDirectReports(ctx, testserver.UserUID(0))
+	var partial *ldap_redhat.PartialResultsError
+	if !errors.As(err, &partial) || len(reports) != 2 {
+		t.Errorf("FindDirectReports returned %d reports and %v, want 2 with a *PartialResultsError", len(reports), err)
+	}
+
+	unlimited := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{SizeLimit: -1})
+	if users, err := searcher.SearchUsers(unlimited, ""); err != nil || len(users) != 10 {
+		t.Errorf("A negative SizeLimit returned %d users and %v, want all 10", len(users), err)
+	}
+}
+
+func TestServerSizeLimitReturnsPartialResults(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 10)
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultSizeLimitExceeded, Entries: 3})
+
+	users, err := searcher.SearchUsers(context.Background(), "")
+	var partial *ldap_redhat.PartialResultsError
+	if !errors.As(err, &partial) || partial.Limit != 0 || len(users) != 3 {
+		t.Errorf("Got %d users and %v, want the 3 sent before the server's limit", len(users), err)
+	}
+	if _, err := searcher.GetUser(context.Background(), uidIdentifier(1)); !errors.As(err, &partial) {
+		t.Errorf("GetUser cut short by a server limit returned %v, want a *PartialResultsError", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package boltstore implements localstore.Store in a bbolt database file,
+// for tools that keep a local copy of the directory between runs.
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/buildfeature"
+	"github.com/openshift-eng/go-ldap-redhat/localstore"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ localstore.Store = (*Store)(nil)
+
+func init() { buildfeature.Register("localstore/boltstore") }
+
+var (
+	usersBucket       = []byte("users")       // uid -> record
+	checkpointsBucket = []byte("checkpoints") // base DN -> RFC 3339 time
+)
+
+// record is the stored form of a user
+type record struct {
+	Base string                 `json:"base"`
+	User ldap_redhat.UserRecord `json:"user"`
+}
+
+// Store is a localstore.Store in a bbolt database.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the database at path, creating it with mode 0600 if needed,
+// since it holds HR data. bbolt locks the file, so a second Open of the same
+// path waits for the first Store to close.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{usersBucket, checkpointsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Put(ctx context.Context, base string, users []ldap_redhat.UserRecord) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		for _, u := range users {
+			data, err := json.Marshal(record{Base: base, User: u})
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(u.UID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) Delete(ctx context.Context, uids []string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		for _, uid := range uids {
+			if err := b.Delete([]byte(uid)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) Get(ctx context.Context, uid string) (ldap_redhat.UserRecord, error) {
+	var rec record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(usersBucket).Get([]byte(uid))
+		if data == nil {
+			return localstore.NotFound(uid)
+		}
+		return json.Unmarshal(data, &rec)
+	})
+	return rec.User, err
+}
+
+// ForEach calls fn within a read transaction, so fn must not write to the
+// store.
+func (s *Store) ForEach(ctx context.Context, fn func(ldap_redhat.UserRecord) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(_, data []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var rec record
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return err
+			}
+			return fn(rec.User)
+		})
+	})
+}
+
+func (s *Store) UIDs(ctx context.Context, base string) ([]string, error) {
+	var uids []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(uid, data []byte) error {
+			var rec struct {
+				Base string `json:"base"`
+			}
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return err
+			}
+			if rec.Base == base {
+				uids = append(uids, string(uid))
+			}
+			return nil
+		})
+	})
+	return uids, err
+}
+
+func (s *Store) Checkpoint(ctx context.Context, base string) (time.Time, error) {
+	var t time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(checkpointsBucket).Get([]byte(base))
+		if data == nil {
+			return nil
+		}
+		return t.UnmarshalText(data)
+	})
+	return t, err
+}
+
+func (s *Store) SetCheckpoint(ctx context.Context, base string, t time.Time) error {
+	data, err := t.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointsBucket).Put([]byte(base), data)
+	})
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,52 @@
+// Package localstore keeps a local copy of the users of selected subtrees of
+// the directory, for offline and batch tools that would otherwise search LDAP
+// for every user they touch. A Syncer fills a Store with a full paged export
+// and keeps it current with incremental refreshes that fetch only entries
+// whose modifyTimestamp moved. MemoryStore is the reference Store; the
+// boltstore and sqlitestore packages persist to disk.
+//
+//	store, err := sqlitestore.Open("users.db")
+//	...
+//	syncer := &localstore.Syncer{Directory: searcher, Store: store, BaseDNs: []string{"ou=users,dc=redhat,dc=com"}}
+//	if _, err := syncer.Refresh(ctx); err != nil { // a full export on the first run
+//		...
+//	}
+//	user, err := store.Get(ctx, "jdoe")
+package localstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// Store holds synced users by UID, with the subtree each was synced from and
+// a checkpoint per subtree. Implementations must be safe for concurrent use.
+type Store interface {
+	// Put stores users synced from the subtree base, replacing stored users
+	// with the same UID.
+	Put(ctx context.Context, base string, users []ldap_redhat.UserRecord) error
+	// Delete removes the users with the given UIDs; unknown UIDs are ignored.
+	Delete(ctx context.Context, uids []string) error
+	// Get returns the user with the UID, or an error matching
+	// ldap_redhat.ErrUserNotFound.
+	Get(ctx context.Context, uid string) (ldap_redhat.UserRecord, error)
+	// ForEach calls fn for every stored user in UID order, stopping at the
+	// first error fn returns.
+	ForEach(ctx context.Context, fn func(ldap_redhat.UserRecord) error) error
+	// UIDs returns the UIDs of the users synced from the subtree base.
+	UIDs(ctx context.Context, base string) ([]string, error)
+	// Checkpoint returns the time the last sync of the subtree base started,
+	// or the zero time if it was never synced.
+	Checkpoint(ctx context.Context, base string) (time.Time, error)
+	SetCheckpoint(ctx context.Context, base string, t time.Time) error
+	Close() error
+}
+
+// NotFound returns the error Store implementations return from Get for a
+// missing uid, matching ldap_redhat.ErrUserNotFound.
+func NotFound(uid string) error {
+	return fmt.Errorf("%w in local store: %s", ldap_redhat.ErrUserNotFound, uid)
+}
@@ -0,0 +1,107 @@
+package localstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore is a Store in memory, for tests and for tools that sync once
+// per run.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	users       map[string]storedUser
+	checkpoints map[string]time.Time
+}
+
+type storedUser struct {
+	base string
+	user ldap_redhat.UserRecord
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{users: map[string]storedUser{}, checkpoints: map[string]time.Time{}}
+}
+
+func (m *MemoryStore) Put(ctx context.Context, base string, users []ldap_redhat.UserRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range users {
+		m.users[u.UID] = storedUser{base: base, user: u}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, uids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, uid := range uids {
+		delete(m.users, uid)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, uid string) (ldap_redhat.UserRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[uid]
+	if !ok {
+		return ldap_redhat.UserRecord{}, NotFound(uid)
+	}
+	return u.user, nil
+}
+
+// ForEach calls fn on a copy of the users taken when it is called, so fn may
+// modify the store.
+func (m *MemoryStore) ForEach(ctx context.Context, fn func(ldap_redhat.UserRecord) error) error {
+	m.mu.RLock()
+	users := make([]ldap_redhat.UserRecord, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u.user)
+	}
+	m.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].UID < users[j].UID })
+	for _, u := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) UIDs(ctx context.Context, base string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var uids []string
+	for uid, u := range m.users {
+		if u.base == base {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Strings(uids)
+	return uids, nil
+}
+
+func (m *MemoryStore) Checkpoint(ctx context.Context, base string) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.checkpoints[base], nil
+}
+
+func (m *MemoryStore) SetCheckpoint(ctx context.Context, base string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[base] = t
+	return nil
+}
+
+func (m *MemoryStore) Close() error { return nil }
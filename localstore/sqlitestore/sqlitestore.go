@@ -0,0 +1,178 @@
+// Package sqlitestore implements localstore.Store in an SQLite database,
+// whose users table other tools can also query with SQL, e.g.
+//
+//	SELECT uid, email FROM users WHERE cost_center = '700';
+//
+// It uses the pure Go modernc.org/sqlite driver, so it needs no cgo.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/buildfeature"
+	"github.com/openshift-eng/go-ldap-redhat/localstore"
+	_ "modernc.org/sqlite"
+)
+
+var _ localstore.Store = (*Store)(nil)
+
+func init() { buildfeature.Register("localstore/sqlitestore") }
+
+// schema creates the tables. record holds the user as JSON; the other user
+// columns are copies of its fields for queries.
+const schema = `
+CREATE TABLE IF NOT EXISTS users (
+	uid         TEXT PRIMARY KEY,
+	base        TEXT NOT NULL,
+	email       TEXT NOT NULL,
+	cost_center TEXT NOT NULL,
+	manager_uid TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	record      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS users_base ON users (base);
+CREATE INDEX IF NOT EXISTS users_email ON users (email);
+CREATE TABLE IF NOT EXISTS checkpoints (
+	base TEXT PRIMARY KEY,
+	time TEXT NOT NULL
+);`
+
+// Store is a localstore.Store in an SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the database at path, creating it if needed. Use ":memory:"
+// for a database that lives as long as the Store.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes writers, which SQLite would otherwise
+	// reject with SQLITE_BUSY, and keeps a :memory: database alive
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Put(ctx context.Context, base string, users []ldap_redhat.UserRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO users (uid, base, email, cost_center, manager_uid, status, record) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, u := range users {
+		data, err := json.Marshal(u)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, u.UID, base, u.Email, u.CostCenter, u.ManagerUID, string(u.Status), string(data)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Delete(ctx context.Context, uids []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, uid := range uids {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE uid = ?`, uid); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Get(ctx context.Context, uid string) (ldap_redhat.UserRecord, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT record FROM users WHERE uid = ?`, uid).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ldap_redhat.UserRecord{}, localstore.NotFound(uid)
+	}
+	if err != nil {
+		return ldap_redhat.UserRecord{}, err
+	}
+	var u ldap_redhat.UserRecord
+	err = json.Unmarshal([]byte(data), &u)
+	return u, err
+}
+
+// ForEach holds the store's only connection while it runs, so fn must not
+// use the store.
+func (s *Store) ForEach(ctx context.Context, fn func(ldap_redhat.UserRecord) error) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT record FROM users ORDER BY uid`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		var u ldap_redhat.UserRecord
+		if err := json.Unmarshal([]byte(data), &u); err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *Store) UIDs(ctx context.Context, base string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT uid FROM users WHERE base = ? ORDER BY uid`, base)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		uids = append(uids, uid)
+	}
+	return uids, rows.Err()
+}
+
+func (s *Store) Checkpoint(ctx context.Context, base string) (time.Time, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT time FROM checkpoints WHERE base = ?`, base).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func (s *Store) SetCheckpoint(ctx context.Context, base string, t time.Time) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO checkpoints (base, time) VALUES (?, ?)`, base, t.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
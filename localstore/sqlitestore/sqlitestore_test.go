@@ -0,0 +1,63 @@
+package sqlitestore_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/localstore/sqlitestore"
+)
+
+func TestStore(t *testing.T) {
+	store, err := sqlitestore.Open(filepath.Join(t.TempDir(), "users.sqlite"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	hired := time.Date(2019, time.March, 4, 0, 0, 0, 0, time.UTC)
+	err = store.Put(ctx, "ou=users,dc=redhat,dc=com", []ldap_redhat.UserRecord{
+		{UID: "jdoe", Email: "jdoe@redhat.com", ManagerUID: "boss", HireDate: hired, Status: ldap_redhat.StatusActive},
+		{UID: "boss", Email: "boss@redhat.com"},
+	})
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	store.Put(ctx, "ou=contractors,dc=redhat,dc=com", []ldap_redhat.UserRecord{{UID: "temp"}})
+
+	u, err := store.Get(ctx, "jdoe")
+	if err != nil || u.Email != "jdoe@redhat.com" || u.ManagerUID != "boss" || !u.HireDate.Equal(hired) || u.Status != ldap_redhat.StatusActive {
+		t.Errorf("Get returned %+v, %v", u, err)
+	}
+	if _, err := store.Get(ctx, "nobody"); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Get of an unknown user returned %v, want ErrUserNotFound", err)
+	}
+	if uids, err := store.UIDs(ctx, "ou=users,dc=redhat,dc=com"); err != nil || len(uids) != 2 || uids[0] != "boss" || uids[1] != "jdoe" {
+		t.Errorf("UIDs returned %v, %v, want boss and jdoe", uids, err)
+	}
+
+	if err := store.Delete(ctx, []string{"boss", "unknown"}); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	var all []string
+	store.ForEach(ctx, func(u ldap_redhat.UserRecord) error {
+		all = append(all, u.UID)
+		return nil
+	})
+	if len(all) != 2 || all[0] != "jdoe" || all[1] != "temp" {
+		t.Errorf("ForEach visited %v, want jdoe and temp", all)
+	}
+
+	if c, err := store.Checkpoint(ctx, "ou=users,dc=redhat,dc=com"); err != nil || !c.IsZero() {
+		t.Errorf("Checkpoint before any sync returned %v, %v", c, err)
+	}
+	now := time.Now()
+	store.SetCheckpoint(ctx, "ou=users,dc=redhat,dc=com", now)
+	if c, err := store.Checkpoint(ctx, "ou=users,dc=redhat,dc=com"); err != nil || !c.Equal(now) {
+		t.Errorf("Checkpoint returned %v, %v, want %v", c, err, now)
+	}
+}
@@ -0,0 +1,176 @@
+package localstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// Defaults of Syncer
+const (
+	DefaultFilter    = "(uid=*)"
+	DefaultOverlap   = 5 * time.Minute
+	DefaultBatchSize = 500
+)
+
+// Directory is the part of *ldap_redhat.Searcher a Syncer reads from.
+type Directory interface {
+	ForEachUser(ctx context.Context, filter string, fn func(ldap_redhat.UserRecord) error) error
+}
+
+// Syncer copies the users of BaseDNs into Store.
+type Syncer struct {
+	Directory Directory
+	Store     Store
+	// BaseDNs are the subtrees synced, each of which must be within the
+	// searcher's base DN. Empty syncs the searcher's base DN, stored under
+	// the base "".
+	BaseDNs []string
+	// Filter selects the users synced (default DefaultFilter).
+	Filter string
+	// Overlap is subtracted from a subtree's checkpoint for incremental
+	// refreshes, to cover clock skew between this host and the directory
+	// and changes still replicating when the checkpoint was taken
+	// (default DefaultOverlap). Users are written idempotently, so
+	// re-reading a few is harmless.
+	Overlap time.Duration
+	// BatchSize is the number of users written to Store at once (default
+	// DefaultBatchSize).
+	BatchSize int
+}
+
+// Stats counts what a sync changed in the store.
+type Stats struct {
+	Full    int // subtrees exported in full
+	Put     int // users written
+	Deleted int // users removed because they left a subtree
+}
+
+// Full exports every subtree in full, writing its users and removing stored
+// users that are no longer in it, e.g. because they were deleted or moved.
+func (s *Syncer) Full(ctx context.Context) (Stats, error) {
+	var stats Stats
+	for _, base := range s.bases() {
+		if err := s.syncBase(ctx, base, time.Time{}, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Refresh fetches the users of each subtree modified since its last sync
+// started, less Overlap, and writes them. Subtrees never synced are exported
+// in full. An incremental refresh cannot see users that left a subtree, since
+// they are no longer returned; run Full periodically, e.g. nightly, to remove
+// them.
+func (s *Syncer) Refresh(ctx context.Context) (Stats, error) {
+	var stats Stats
+	for _, base := range s.bases() {
+		since, err := s.Store.Checkpoint(ctx, base)
+		if err != nil {
+			return stats, fmt.Errorf("failed to read the checkpoint of %q: %w", base, err)
+		}
+		if !since.IsZero() {
+			since = since.Add(-s.overlap())
+		}
+		if err := s.syncBase(ctx, base, since, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func (s *Syncer) bases() []string {
+	if len(s.BaseDNs) == 0 {
+		return []string{""}
+	}
+	return s.BaseDNs
+}
+
+func (s *Syncer) overlap() time.Duration {
+	if s.Overlap > 0 {
+		return s.Overlap
+	}
+	return DefaultOverlap
+}
+
+// syncBase writes the users of base modified since since, or all of them
+// when since is zero, removing those no longer in base, and checkpoints
+// base at the time the search started
+func (s *Syncer) syncBase(ctx context.Context, base string, since time.Time, stats *Stats) error {
+	start := time.Now()
+	filter := s.Filter
+	if filter == "" {
+		filter = DefaultFilter
+	}
+	if !since.IsZero() {
+		filter = "(&" + filter + ldap_redhat.ModifiedSinceFilter(since) + ")"
+	}
+	searchCtx := ctx
+	if base != "" {
+		scope, _ := ldap_redhat.RequestScopeFromContext(ctx)
+		scope.BaseDN = base
+		searchCtx = ldap_redhat.WithRequestScope(ctx, scope)
+	}
+
+	size := s.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	seen := map[string]bool{}
+	batch := make([]ldap_redhat.UserRecord, 0, size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.Store.Put(ctx, base, batch); err != nil {
+			return fmt.Errorf("failed to store users of %q: %w", base, err)
+		}
+		stats.Put += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	err := s.Directory.ForEachUser(searchCtx, filter, func(u ldap_redhat.UserRecord) error {
+		if u.UID == "" {
+			return nil
+		}
+		seen[u.UID] = true
+		batch = append(batch, u)
+		if len(batch) == size {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	if since.IsZero() {
+		stored, err := s.Store.UIDs(ctx, base)
+		if err != nil {
+			return fmt.Errorf("failed to list stored users of %q: %w", base, err)
+		}
+		var gone []string
+		for _, uid := range stored {
+			if !seen[uid] {
+				gone = append(gone, uid)
+			}
+		}
+		if len(gone) > 0 {
+			if err := s.Store.Delete(ctx, gone); err != nil {
+				return fmt.Errorf("failed to remove users that left %q: %w", base, err)
+			}
+			stats.Deleted += len(gone)
+		}
+		stats.Full++
+	}
+	if err := s.Store.SetCheckpoint(ctx, base, start); err != nil {
+		return fmt.Errorf("failed to checkpoint %q: %w", base, err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package localstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+	"github.com/openshift-eng/go-ldap-redhat/localstore"
+)
+
+// newSearcher starts an embedded LDAP server seeded with n generated users
+// and returns a searcher connected to it
+func newSearcher(t *testing.T, n int) (*ldap_redhat.Searcher, *ldapserver.Server) {
+	t.Helper()
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(n))
+	srv.AddBind("uid=svc,ou=users,dc=redhat,dc=com", "secret")
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start embedded LDAP server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    "uid=svc,ou=users,dc=redhat,dc=com",
+		Password:    "secret",
+		BaseDN:      "dc=redhat,dc=com",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	t.Cleanup(func() { searcher.Close() })
+	return searcher, srv
+}
+
+func TestSyncer(t *testing.T) {
+	searcher, srv := newSearcher(t, 30)
+	store := localstore.NewMemoryStore()
+	ctx := context.Background()
+
+	// A user synced earlier who has since left the subtree
+	store.Put(ctx, testserver.UsersBaseDN, []ldap_redhat.UserRecord{{UID: "departed"}})
+
+	syncer := &localstore.Syncer{Directory: searcher, Store: store, BaseDNs: []string{testserver.UsersBaseDN}, BatchSize: 7}
+	stats, err := syncer.Refresh(ctx)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if stats != (localstore.Stats{Full: 1, Put: 30, Deleted: 1}) {
+		t.Errorf("The first Refresh returned %+v, want a full export of 30 users removing 1", stats)
+	}
+	if _, err := store.Get(ctx, "departed"); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Get of a user that left returned %v, want ErrUserNotFound", err)
+	}
+	if u, err := store.Get(ctx, testserver.UserUID(12)); err != nil || u.Email == "" {
+		t.Errorf("Get returned %+v, %v, want the synced user", u, err)
+	}
+	checkpoint, _ := store.Checkpoint(ctx, testserver.UsersBaseDN)
+	if checkpoint.IsZero() {
+		t.Error("Refresh did not checkpoint the subtree")
+	}
+
+	// Only entries whose modifyTimestamp moved are fetched again
+	srv.SetAttribute(testserver.UserDN(5), ldap_redhat.AttrTitle, "Distinguished Engineer")
+	srv.SetAttribute(testserver.UserDN(5), ldap_redhat.AttrModifyTimestamp, ldap_redhat.FormatLDAPTime(time.Now()))
+	stats, err = syncer.Refresh(ctx)
+	if err != nil || stats != (localstore.Stats{Put: 1}) {
+		t.Errorf("The incremental Refresh returned %+v, %v, want 1 user written", stats, err)
+	}
+	if u, _ := store.Get(ctx, testserver.UserUID(5)); u.Title != "Distinguished Engineer" {
+		t.Errorf("The refreshed user has title %q", u.Title)
+	}
+
+	stats, err = syncer.Full(ctx)
+	if err != nil || stats != (localstore.Stats{Full: 1, Put: 30}) {
+		t.Errorf("Full returned %+v, %v, want all 30 users written", stats, err)
+	}
+	n := 0
+	store.ForEach(ctx, func(ldap_redhat.UserRecord) error { n++; return nil })
+	if n != 30 {
+		t.Errorf("The store holds %d users, want 30", n)
+	}
+}
+
+func TestSyncerOutsideBaseDN(t *testing.T) {
+	searcher, _ := newSearcher(t, 3)
+	syncer := &localstore.Syncer{Directory: searcher, Store: localstore.NewMemoryStore(), BaseDNs: []string{"ou=users,dc=example,dc=com"}}
+	if _, err := syncer.Full(context.Background()); err == nil {
+		t.Error("Full accepted a subtree outside the searcher's base DN")
+	}
+}
@@ -0,0 +1,152 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DefaultLocationPageSize is the page size of GetUsersByLocation when
+// LocationOptions.PageSize is zero.
+const DefaultLocationPageSize = 500
+
+// LocationOptions configures GetUsersByLocation.
+type LocationOptions struct {
+	Geo               bool   // match rhatGeo (e.g. "EMEA") instead of rhatLocation (e.g. "Brno")
+	Prefix            bool   // match values starting with the location, e.g. "Remote US" for every US remote worker
+	IncludeTerminated bool   // also return users whose termination date has passed
+	PageSize          int    // users per page (0 = DefaultLocationPageSize)
+	PageToken         string // NextPageToken of the previous page; empty for the first page
+}
+
+// UserPage is a page of users ordered by UID.
+type UserPage struct {
+	Users         []UserRecord `json:"users" yaml:"users"`
+	NextPageToken string       `json:"next_page_token,omitempty" yaml:"next_page_token,omitempty"` // empty on the last page
+}
+
+// GetUsersByLocation returns a page of the users at an office or remote
+// location, or in a geo with opts.Geo, ordered by UID, compared without
+// regard to case as the directory does. Matching is case-insensitive and
+// exact unless opts.Prefix is set. Only active users are returned unless
+// opts.IncludeTerminated is set. Pass the page's NextPageToken in
+// opts.PageToken for the next one.
+//
+// Each page searches again for the users after the previous page's last UID,
+// so it reflects the directory when it is fetched, and users who move between
+// pages do not make others repeat or go missing. The search asks the server
+// to sort by UID and stops once the page and the user after it have arrived;
+// servers without server-side sorting are searched unsorted, reading every
+// later match but keeping only the page.
+func (s *Searcher) GetUsersByLocation(ctx context.Context, location string, opts LocationOptions) (UserPage, error) {
+	location = strings.TrimSpace(location)
+	if location == "" {
+		return UserPage{}, errors.New("location must not be empty")
+	}
+	after, err := decodePageToken(opts.PageToken, "location")
+	if err != nil {
+		return UserPage{}, err
+	}
+	size := opts.PageSize
+	if size <= 0 {
+		size = DefaultLocationPageSize
+	}
+
+	attr := AttrRhatLocation
+	if opts.Geo {
+		attr = AttrRhatGeo
+	}
+	filter := fmt.Sprintf("(%s=%s)", attr, ldap.EscapeFilter(location))
+	if opts.Prefix {
+		filter = fmt.Sprintf("(%s=%s*)", attr, ldap.EscapeFilter(location))
+	}
+	if after != "" {
+		filter = fmt.Sprintf("(&%s(%s>=%s))", filter, AttrUID, ldap.EscapeFilter(after))
+	}
+
+	var users []UserRecord
+	err = s.locationPage(ctx, filter, after, size, opts.IncludeTerminated, true, &users)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailableCriticalExtension) {
+		users = nil
+		err = s.locationPage(ctx, filter, after, size, opts.IncludeTerminated, false, &users)
+	}
+	if err != nil {
+		s.auditListing(ctx, AuditForEachUser, filter, 0, err)
+		return UserPage{}, err
+	}
+
+	var page UserPage
+	if len(users) > size {
+		users = users[:size]
+		page.NextPageToken = encodePageToken(users[size-1].UID)
+	}
+	page.Users = users
+	s.auditListing(ctx, AuditForEachUser, filter, len(page.Users), nil)
+	return page, nil
+}
+
+// locationPage collects into users the first size+1 users matching filter
+// whose UIDs sort after after, in order. With sorted, the server is asked
+// to sort by UID and the search stops once they have arrived; servers that
+// cannot sort fail it with unavailableCriticalExtension.
+func (s *Searcher) locationPage(ctx context.Context, filter, after string, size int, includeTerminated, sorted bool, users *[]UserRecord) error {
+	var controls []ldap.Control
+	if sorted {
+		controls = append(controls, uidSortControl{})
+	}
+	after = strings.ToLower(after)
+	err := s.forEachActive(ctx, filter, includeTerminated, func(u UserRecord) error {
+		uid := strings.ToLower(u.UID)
+		if uid <= after {
+			return nil // (uid>=after) also matches the last user of the previous page
+		}
+		i := sort.Search(len(*users), func(i int) bool { return strings.ToLower((*users)[i].UID) > uid })
+		if i > size {
+			return nil
+		}
+		*users = slices.Insert(*users, i, u)
+		if len(*users) > size+1 {
+			*users = (*users)[:size+1]
+		}
+		if sorted && len(*users) > size {
+			return errStopIteration
+		}
+		return nil
+	}, controls...)
+	if errors.Is(err, errStopIteration) {
+		return nil
+	}
+	return err
+}
+
+// uidSortControl implements ldap.Control for a critical server-side sort
+// request (RFC 2891) by UID. go-ldap's own is never critical, and a server
+// ignoring it would return entries unsorted.
+type uidSortControl struct{}
+
+// GetControlType returns the OID of server-side sort requests
+func (uidSortControl) GetControlType() string {
+	return ldap.ControlTypeServerSideSorting
+}
+
+// Encode returns go-ldap's encoding of the request with the criticality
+// flag added
+func (c uidSortControl) Encode() *ber.Packet {
+	sorting := ldap.NewControlServerSideSortingWithSortKeys([]*ldap.SortKey{{AttributeType: AttrUID}}).Encode()
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	packet.AppendChild(sorting.Children[0])
+	packet.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "Criticality"))
+	packet.AppendChild(sorting.Children[1])
+	return packet
+}
+
+// String returns a human-readable description of the control
+func (uidSortControl) String() string {
+	return fmt.Sprintf("Control Type: %s (%q)  Criticality: true  Sort: %s", ldap.ControlTypeMap[ldap.ControlTypeServerSideSorting], ldap.ControlTypeServerSideSorting, AttrUID)
+}
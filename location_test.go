@@ -0,0 +1,77 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestGetUsersByLocation(t *testing.T) {
+	// Generated users cycle through 8 locations: Brno holds users 1, 9, 17,
+	// 25 and 33, and EMEA the users at Brno, Munich, Cork and Madrid
+	searcher, srv := newEmbeddedSearcher(t, 40)
+	ctx := context.Background()
+	srv.SetAttribute(testserver.UserDN(17), "rhatTermDate", "20200131000000Z")
+
+	want := []string{testserver.UserUID(1), testserver.UserUID(9), testserver.UserUID(25), testserver.UserUID(33)}
+	if got := locationPages(t, searcher, "brno", 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want the active Brno users %v", got, want)
+	}
+	for _, tc := range []struct {
+		location string
+		opts     ldap_redhat.LocationOptions
+		want     int
+	}{
+		{"Brno", ldap_redhat.LocationOptions{IncludeTerminated: true}, 5},
+		{"Remote", ldap_redhat.LocationOptions{}, 0},
+		{"Remote", ldap_redhat.LocationOptions{Prefix: true}, 5},
+		{"emea", ldap_redhat.LocationOptions{Geo: true}, 19},
+		{"E*", ldap_redhat.LocationOptions{Geo: true, Prefix: true}, 0},
+	} {
+		page, err := searcher.GetUsersByLocation(ctx, tc.location, tc.opts)
+		if err != nil || len(page.Users) != tc.want {
+			t.Errorf("GetUsersByLocation(%q, %+v) found %d users (%v), want %d", tc.location, tc.opts, len(page.Users), err, tc.want)
+		}
+	}
+
+	minimal := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{AttributeProfile: ldap_redhat.ProfileMinimal})
+	if page, err := searcher.GetUsersByLocation(minimal, "Brno", ldap_redhat.LocationOptions{}); err != nil || !reflect.DeepEqual(uids(page.Users), want) {
+		t.Errorf("Got %v (%v) under the minimal profile, want the active Brno users %v", uids(page.Users), err, want)
+	}
+
+	if _, err := searcher.GetUsersByLocation(ctx, "Brno", ldap_redhat.LocationOptions{PageToken: "!"}); err == nil {
+		t.Error("Expected an error for an invalid page token")
+	}
+
+	// Servers without server-side sorting are read in full, with the same pages
+	srv.DisableControl(ldap.ControlTypeServerSideSorting)
+	if got := locationPages(t, searcher, "brno", 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v without server-side sorting, want the active Brno users %v", got, want)
+	}
+}
+
+// locationPages returns the UIDs of every page of the users at location,
+// fetched size at a time
+func locationPages(t *testing.T, searcher *ldap_redhat.Searcher, location string, size int) []string {
+	t.Helper()
+	var got []string
+	opts := ldap_redhat.LocationOptions{PageSize: size}
+	for pages := 1; ; pages++ {
+		page, err := searcher.GetUsersByLocation(context.Background(), location, opts)
+		if err != nil {
+			t.Fatalf("GetUsersByLocation failed: %v", err)
+		}
+		if len(page.Users) > size || pages > 10 {
+			t.Fatalf("Got %d users on page %d, want at most %d", len(page.Users), pages, size)
+		}
+		got = append(got, uids(page.Users)...)
+		if page.NextPageToken == "" {
+			return got
+		}
+		opts.PageToken = page.NextPageToken
+	}
+}
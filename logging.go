@@ -0,0 +1,53 @@
+package ldap_redhat
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// discardLogger is used when Config.Logger is nil
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// logger returns the configured logger, or one that discards everything
+func (c Config) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return discardLogger
+}
+
+// logOperation logs a completed directory operation at debug level. Callers
+// pass only non-secret attributes: bind DNs are logged, passwords never are.
+func logOperation(logger *slog.Logger, msg string, start time.Time, err error, attrs ...slog.Attr) {
+	ctx := context.Background()
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// logSearch logs a completed search with its base, filter, scope and number
+// of entries returned
+func (s *Searcher) logSearch(msg string, req *ldap.SearchRequest, start time.Time, entries int, err error) {
+	logger := s.config().logger()
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{}
+	if name := trackedConnName(req.Controls); name != "" {
+		attrs = append(attrs, slog.String("conn", name))
+	}
+	logOperation(logger, msg, start, err, append(attrs,
+		slog.String("base", req.BaseDN),
+		slog.String("filter", req.Filter),
+		slog.String("scope", ldap.ScopeMap[req.Scope]),
+		slog.Int("entries", entries),
+	)...)
+}
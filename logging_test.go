@@ -0,0 +1,53 @@
+package ldap_redhat_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestLogger(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	var buf bytes.Buffer
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+		Logger:      slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+
+	uid := testserver.UserUID(2)
+	if _, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+
+	logs := buf.String()
+	conn := regexp.MustCompile(`msg="ldap dial" conn=(\S+)`).FindStringSubmatch(logs)
+	if conn == nil {
+		t.Fatalf("Expected the dial log to name the connection, got:\n%s", logs)
+	}
+	for _, want := range []string{
+		`msg="ldap dial" conn=` + conn[1] + ` server=` + srv.URL(),
+		`msg="ldap bind" conn=` + conn[1] + ` bind_dn="` + embeddedBindDN + `"`,
+		`msg="ldap search" conn=` + conn[1] + ` base="` + testserver.UsersBaseDN + `" filter="(uid=` + uid + `)" scope="Whole Subtree" entries=1`,
+		"duration=",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected logs to contain %q, got:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, embeddedPassword) {
+		t.Errorf("Logs contain the bind password:\n%s", logs)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
 )
 
 // TestMain is the main test runner that sets up and tears down test environment
@@ -34,14 +36,29 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-// setupTestEnvironment prepares the test environment
+// embeddedDirectory is the in-process server the integration tests run
+// against when LDAP_URL is not set; nil when they use a real directory.
+var embeddedDirectory *ldapserver.Server
+
+// embeddedDirectoryUsers is the number of generated users in the embedded
+// directory. The well-known test account follows them.
+const embeddedDirectoryUsers = 50
+
+// setupTestEnvironment prepares the test environment. Without LDAP_URL the
+// integration tests run hermetically against an embedded server seeded with
+// Red Hat-schema fixtures; set LDAP_URL and credentials to run them against
+// a real directory instead.
 func setupTestEnvironment() {
 	fmt.Println("Setting up test environment...")
 
-	// Set test environment variables if not already set
 	if os.Getenv("LDAP_URL") == "" {
-		os.Setenv("LDAP_URL", "ldap://apps-ldap.corp.redhat.com:389")
-		fmt.Println("   Set LDAP_URL for tests")
+		if err := startEmbeddedDirectory(); err != nil {
+			fmt.Printf("   Failed to start embedded LDAP server: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("   Using embedded LDAP server at %s\n", embeddedDirectory.URL())
+		fmt.Println("")
+		return
 	}
 
 	if os.Getenv("LDAP_BASE_DN") == "" {
@@ -70,11 +87,50 @@ func setupTestEnvironment() {
 	fmt.Println("")
 }
 
+// startEmbeddedDirectory starts embeddedDirectory and points the LDAP_* and
+// TEST_LDAP_* variables at it and its fixtures.
+func startEmbeddedDirectory() error {
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(embeddedDirectoryUsers))
+	srv.AddEntries([]*ldapserver.Entry{testserver.NamedUser(embeddedDirectoryUsers, "jemedina")})
+	srv.AddBind(embeddedBindDN, embeddedPassword)
+	if err := srv.Start(); err != nil {
+		return err
+	}
+	embeddedDirectory = srv
+
+	for name, value := range map[string]string{
+		"LDAP_URL":              srv.URL(),
+		"LDAP_BASE_DN":          "dc=redhat,dc=com",
+		"LDAP_BIND_DN":          embeddedBindDN,
+		"LDAP_PASSWORD":         embeddedPassword,
+		"LDAP_START_TLS":        "false",
+		"TEST_LDAP_UID":         testserver.UserUID(1),
+		"TEST_LDAP_EMAIL":       testserver.UserUID(1) + "@redhat.com",
+		"TEST_LDAP_MANAGER_UID": testserver.UserUID(0),
+	} {
+		if os.Getenv(name) == "" {
+			os.Setenv(name, value)
+		}
+	}
+	// LoadDefaultConfig would prefer the checked-in config.yaml over the
+	// variables
+	ldap_redhat.SetDefaultConfig(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      "dc=redhat,dc=com",
+	})
+	return nil
+}
+
 // cleanupTestEnvironment cleans up after tests
 func cleanupTestEnvironment() {
 	fmt.Println("")
 	fmt.Println("Cleaning up test environment...")
-	// Any cleanup needed can go here
+	if embeddedDirectory != nil {
+		embeddedDirectory.Close()
+	}
 }
 
 // TestSuiteOverview provides a comprehensive test overview
@@ -91,7 +147,7 @@ func TestSuiteOverview(t *testing.T) {
 		{"Core Library", "ldap_redhat.Version, constants, basic functionality", "ldap_redhat_test.go"},
 		{"Configuration", "YAML, env vars, secrets loading", "config_test.go"},
 		{"User Validation", "UserRecord, identifiers, Red Hat fields", "user_test.go"},
-		{"Integration", "Real LDAP connections and searches", "integration_test.go"},
+		{"Integration", "LDAP connections and searches (embedded or real)", "integration_test.go"},
 	}
 
 	for _, cat := range categories {
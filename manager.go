@@ -0,0 +1,155 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// usersDN is the container holding every user entry
+const usersDN = "ou=users,dc=redhat,dc=com"
+
+// managerDNForUID returns the filter-escaped DN that report entries carry in
+// their manager attribute for the given manager UID.
+func managerDNForUID(uid string) string {
+	return fmt.Sprintf("uid=%s,%s", ldap.EscapeFilter(uid), usersDN)
+}
+
+// managerUIDFromDN extracts the uid from a manager DN such as
+// "uid=jdoe,ou=users,dc=redhat,dc=com". It returns "" if the DN is invalid or
+// its leading RDN is not a uid.
+func managerUIDFromDN(dn string) string {
+	if dn == "" {
+		return ""
+	}
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+	for _, attr := range parsed.RDNs[0].Attributes {
+		if strings.EqualFold(attr.Type, AttrUID) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// userUIDFromDN is like managerUIDFromDN, but also returns "" for DNs outside
+// the users container, such as service accounts or nested groups.
+func userUIDFromDN(dn string) string {
+	if !DNWithin(dn, usersDN) {
+		return ""
+	}
+	return managerUIDFromDN(dn)
+}
+
+// attributes returns the attributes requested for user lookups, narrowed by
+// the attribute profile, including the configured people-manager attribute
+// if any, and narrowed by the request scope.
+func (s *Searcher) attributes(ctx context.Context) []string {
+	profiled := s.profileAttributesFor(ctx, userAttributes)
+	if s.config().PeopleManagerAttribute == "" {
+		return scopedAttributes(ctx, profiled)
+	}
+	attrs := make([]string, 0, len(profiled)+1)
+	attrs = append(attrs, profiled...)
+	return scopedAttributes(ctx, append(attrs, s.config().PeopleManagerAttribute))
+}
+
+// IsPeopleManager reports whether anyone in the directory lists managerUID as
+// their manager. It issues a size-limited existence probe returning no
+// attributes, so it is cheap even for managers with large organizations.
+func (s *Searcher) IsPeopleManager(ctx context.Context, managerUID string) (bool, error) {
+	isManager, err := s.isPeopleManager(ctx, managerUID)
+	results := 0
+	if isManager {
+		results = 1
+	}
+	s.auditReports(ctx, AuditIsPeopleManager, managerUID, results, err)
+	return isManager, err
+}
+
+// isPeopleManager is IsPeopleManager without auditing, for the probes made
+// by lookups that are audited themselves
+func (s *Searcher) isPeopleManager(ctx context.Context, managerUID string) (bool, error) {
+	if s.disconnected() {
+		return false, errNotConnected()
+	}
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return false, err
+	}
+	filter := fmt.Sprintf("(manager=%s)", managerDNForUID(managerUID))
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		1, 0, false, filter, []string{"1.1"}, nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return true, nil
+	}
+	if err != nil {
+		return false, wrapLDAPError(err, "LDAP people manager probe failed for %s", managerUID)
+	}
+	return len(result.Entries) > 0, nil
+}
+
+// resolvePeopleManager fills rec.IsPeopleManager when detection is enabled,
+// preferring the configured directory attribute and falling back to a probe.
+func (s *Searcher) resolvePeopleManager(ctx context.Context, entry *ldap.Entry, rec *UserRecord) error {
+	if !s.config().DetectPeopleManagers || rec.UID == "" {
+		return nil
+	}
+	if attr := s.config().PeopleManagerAttribute; attr != "" {
+		if value := entry.GetEqualFoldAttributeValue(attr); value != "" {
+			rec.IsPeopleManager = parseLDAPBool(value)
+			return nil
+		}
+	}
+	isManager, err := s.isPeopleManager(ctx, rec.UID)
+	if err != nil {
+		return err
+	}
+	rec.IsPeopleManager = isManager
+	return nil
+}
+
+// parseLDAPBool interprets LDAP Boolean syntax ("TRUE"/"FALSE") along with
+// the "1"/"yes" spellings some directories use.
+func parseLDAPBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes", "y":
+		return true
+	}
+	return false
+}
+
+// maxManagerChainDepth bounds ManagerChain in case the directory contains a
+// management cycle
+const maxManagerChainDepth = 50
+
+// ManagerChain returns the management chain above uid, starting with the
+// direct manager and ending at the top of the hierarchy.
+func (s *Searcher) ManagerChain(ctx context.Context, uid string) ([]UserRecord, error) {
+	user, err := s.GetUser(ctx, Identifier{Type: IDTUID, Value: uid})
+	if err != nil {
+		return nil, err
+	}
+	// uids are case-insensitive; manager DNs do not always match the case
+	// of the uid attribute
+	seen := map[string]bool{strings.ToLower(user.UID): true}
+	var chain []UserRecord
+	for next := user.ManagerUID; next != "" && !seen[strings.ToLower(next)]; next = user.ManagerUID {
+		if len(chain) >= maxManagerChainDepth {
+			return chain, fmt.Errorf("management chain for %s exceeds %d levels", uid, maxManagerChainDepth)
+		}
+		user, err = s.GetUser(ctx, Identifier{Type: IDTUID, Value: next})
+		if err != nil {
+			return chain, fmt.Errorf("failed to resolve manager %s: %w", next, err)
+		}
+		seen[strings.ToLower(next)] = true
+		chain = append(chain, user)
+	}
+	return chain, nil
+}
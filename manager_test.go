@@ -0,0 +1,107 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestIsPeopleManager(t *testing.T) {
+	// With 20 users, user000000-user000002 have reports and the rest are leaves
+	searcher, _ := newEmbeddedSearcher(t, 20)
+	ctx := context.Background()
+
+	tests := []struct {
+		uid      string
+		expected bool
+	}{
+		{testserver.UserUID(0), true},
+		{testserver.UserUID(2), true},
+		{testserver.UserUID(3), false},
+		{testserver.UserUID(19), false},
+		{"nonexistent", false},
+	}
+	for _, test := range tests {
+		got, err := searcher.IsPeopleManager(ctx, test.uid)
+		if err != nil {
+			t.Errorf("IsPeopleManager(%s) failed: %v", test.uid, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("IsPeopleManager(%s) = %v, expected %v", test.uid, got, test.expected)
+		}
+	}
+}
+
+func TestGetUserDetectPeopleManagers(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 20)
+	ctx := context.Background()
+
+	manager := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+	user, err := searcher.GetUser(ctx, manager)
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.IsPeopleManager {
+		t.Error("IsPeopleManager should not be populated unless DetectPeopleManagers is set")
+	}
+
+	searcher.Config.DetectPeopleManagers = true
+	user, err = searcher.GetUser(ctx, manager)
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if !user.IsPeopleManager {
+		t.Error("Expected user000001 to be detected as a people manager")
+	}
+
+	users, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{
+		manager,
+		{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(10)},
+	})
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if !users[0].IsPeopleManager || users[1].IsPeopleManager {
+		t.Errorf("Unexpected IsPeopleManager values: %v, %v", users[0].IsPeopleManager, users[1].IsPeopleManager)
+	}
+
+	// A directory attribute, when present, takes precedence over the probe
+	srv.AddEntry("uid=flagged,ou=users,dc=redhat,dc=com", map[string][]string{
+		"uid":               {"flagged"},
+		"rhatPeopleManager": {"TRUE"},
+	})
+	searcher.Config.PeopleManagerAttribute = "rhatPeopleManager"
+	user, err = searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "flagged"})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if !user.IsPeopleManager {
+		t.Error("Expected directory attribute to mark user as people manager")
+	}
+}
+
+func TestManagerChain(t *testing.T) {
+	// user000050 reports to user000006, who reports to user000000
+	searcher, _ := newEmbeddedSearcher(t, 60)
+
+	chain, err := searcher.ManagerChain(context.Background(), testserver.UserUID(50))
+	if err != nil {
+		t.Fatalf("ManagerChain failed: %v", err)
+	}
+	var uids []string
+	for _, m := range chain {
+		uids = append(uids, m.UID)
+	}
+	expected := []string{testserver.UserUID(6), testserver.UserUID(0)}
+	if len(uids) != len(expected) || uids[0] != expected[0] || uids[1] != expected[1] {
+		t.Errorf("Expected chain %v, got %v", expected, uids)
+	}
+
+	chain, err = searcher.ManagerChain(context.Background(), testserver.UserUID(0))
+	if err != nil || len(chain) != 0 {
+		t.Errorf("Expected empty chain for the top of the hierarchy, got %v (err %v)", chain, err)
+	}
+}
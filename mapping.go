@@ -0,0 +1,273 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// mappingChunkSize is the number of values OR-ed into one mapping search,
+// keeping filters well under typical server size limits
+const mappingChunkSize = 100
+
+// MapEmailsToUIDs resolves email addresses to UIDs, e.g. for a spreadsheet
+// import. Matching is case-insensitive and surrounding whitespace is ignored.
+// The map is keyed by the emails as given; blank entries are skipped and
+// emails with no user are returned in notFound in input order.
+func (s *Searcher) MapEmailsToUIDs(ctx context.Context, emails []string) (map[string]string, []string, error) {
+	found, notFound, err := s.mapEmailsToUIDs(ctx, emails)
+	s.auditMapping(ctx, AuditMapEmailsToUIDs, IDTEmail, emails, func(email string) bool {
+		_, ok := found[email]
+		return ok
+	}, err)
+	return found, notFound, err
+}
+
+// mapEmailsToUIDs is MapEmailsToUIDs without auditing
+func (s *Searcher) mapEmailsToUIDs(ctx context.Context, emails []string) (map[string]string, []string, error) {
+	found := make(map[string]string, len(emails))
+	if len(emails) == 0 {
+		return found, nil, nil
+	}
+	if s.disconnected() && !s.offlineEnabled() {
+		return nil, nil, errNotConnected()
+	}
+
+	// Look up each address once, however often and however cased it appears
+	var unique []string
+	seen := map[string]bool{}
+	for _, email := range emails {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+
+	byEmail := make(map[string]string, len(unique))
+	for start := 0; start < len(unique); start += mappingChunkSize {
+		chunk := unique[start:min(start+mappingChunkSize, len(unique))]
+		if err := s.mapEmailChunk(ctx, chunk, byEmail); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var notFound []string
+	for _, email := range emails {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if key == "" {
+			continue
+		}
+		if uid, ok := byEmail[key]; ok {
+			found[email] = uid
+		} else {
+			notFound = append(notFound, email)
+		}
+	}
+	return found, notFound, nil
+}
+
+// mapEmailChunk resolves lowercased emails with a single OR filter requesting
+// only uid and mail, adding matches to byEmail
+func (s *Searcher) mapEmailChunk(ctx context.Context, emails []string, byEmail map[string]string) error {
+	if s.disconnected() {
+		return s.mapEmailChunkOffline(ctx, emails, byEmail)
+	}
+	entries, err := s.mapSearch(ctx, AttrMail, emails, []string{AttrUID, AttrMail})
+	if err != nil {
+		if s.offlineEnabled() && s.unreachable(err) {
+			return s.mapEmailChunkOffline(ctx, emails, byEmail)
+		}
+		return wrapLDAPError(err, "LDAP email mapping search failed")
+	}
+	for _, entry := range entries {
+		uid := entry.GetEqualFoldAttributeValue(AttrUID)
+		if uid == "" {
+			continue
+		}
+		for _, mail := range entry.GetEqualFoldAttributeValues(AttrMail) {
+			byEmail[strings.ToLower(mail)] = uid
+		}
+	}
+	return nil
+}
+
+// mapSearch finds the entries whose attr equals any of values with one OR
+// filter, requesting only attrs
+func (s *Searcher) mapSearch(ctx context.Context, attr string, values, attrs []string) ([]*ldap.Entry, error) {
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var filter strings.Builder
+	filter.WriteString("(|")
+	for _, v := range values {
+		fmt.Fprintf(&filter, "(%s=%s)", attr, ldap.EscapeFilter(v))
+	}
+	filter.WriteString(")")
+
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		0, 0, false, filter.String(), attrs, nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// mapEmailChunkOffline resolves emails from the offline snapshot
+func (s *Searcher) mapEmailChunkOffline(ctx context.Context, emails []string, byEmail map[string]string) error {
+	ids := make([]Identifier, len(emails))
+	for i, email := range emails {
+		ids[i] = Identifier{Type: IDTEmail, Value: email}
+	}
+	recs, err := s.offlineUsers(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, rec := range recs {
+		if rec.UID != "" {
+			byEmail[emails[i]] = rec.UID
+		}
+	}
+	return nil
+}
+
+// EmailAddresses holds a user's primary address and any aliases that also
+// deliver to them.
+type EmailAddresses struct {
+	Primary string   `json:"primary" yaml:"primary"`
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+// emailAttributes are fetched by MapUIDsToEmails. rhatPrimaryMail, when set,
+// names the primary among the mail values; mailAlternateAddress holds
+// additional aliases.
+var emailAttributes = []string{AttrUID, AttrMail, AttrRhatPrimaryMail, AttrMailAlternateAddress}
+
+// MapUIDsToEmails resolves UIDs to email addresses, e.g. for notification
+// systems that only store UIDs. The primary address is rhatPrimaryMail if
+// present, otherwise the first mail value; every other address is an alias.
+// The map is keyed by the UIDs as given; blank entries are skipped and UIDs
+// with no user or no address are returned in notFound in input order.
+func (s *Searcher) MapUIDsToEmails(ctx context.Context, uids []string) (map[string]EmailAddresses, []string, error) {
+	found, notFound, err := s.mapUIDsToEmails(ctx, uids)
+	s.auditMapping(ctx, AuditMapUIDsToEmails, IDTUID, uids, func(uid string) bool {
+		_, ok := found[uid]
+		return ok
+	}, err)
+	return found, notFound, err
+}
+
+// mapUIDsToEmails is MapUIDsToEmails without auditing
+func (s *Searcher) mapUIDsToEmails(ctx context.Context, uids []string) (map[string]EmailAddresses, []string, error) {
+	found := make(map[string]EmailAddresses, len(uids))
+	if len(uids) == 0 {
+		return found, nil, nil
+	}
+	if s.disconnected() && !s.offlineEnabled() {
+		return nil, nil, errNotConnected()
+	}
+
+	var unique []string
+	seen := map[string]bool{}
+	for _, uid := range uids {
+		uid = strings.TrimSpace(uid)
+		if uid != "" && !seen[uid] {
+			seen[uid] = true
+			unique = append(unique, uid)
+		}
+	}
+
+	byUID := make(map[string]EmailAddresses, len(unique))
+	for start := 0; start < len(unique); start += mappingChunkSize {
+		chunk := unique[start:min(start+mappingChunkSize, len(unique))]
+		if err := s.mapUIDChunk(ctx, chunk, byUID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var notFound []string
+	for _, uid := range uids {
+		key := strings.TrimSpace(uid)
+		if key == "" {
+			continue
+		}
+		if addrs, ok := byUID[key]; ok {
+			found[uid] = addrs
+		} else {
+			notFound = append(notFound, uid)
+		}
+	}
+	return found, notFound, nil
+}
+
+// mapUIDChunk resolves uids with a single OR filter, adding users that have
+// at least one address to byUID
+func (s *Searcher) mapUIDChunk(ctx context.Context, uids []string, byUID map[string]EmailAddresses) error {
+	if s.disconnected() {
+		return s.mapUIDChunkOffline(ctx, uids, byUID)
+	}
+	entries, err := s.mapSearch(ctx, AttrUID, uids, scopedAttributes(ctx, emailAttributes))
+	if err != nil {
+		if s.offlineEnabled() && s.unreachable(err) {
+			return s.mapUIDChunkOffline(ctx, uids, byUID)
+		}
+		return wrapLDAPError(err, "LDAP UID mapping search failed")
+	}
+	scope, scoped := RequestScopeFromContext(ctx)
+	for _, entry := range entries {
+		uid := entry.GetEqualFoldAttributeValue(AttrUID)
+		if uid == "" || (scoped && !scope.allows(AttrMail)) {
+			continue
+		}
+		if addrs, ok := entryEmailAddresses(entry); ok {
+			byUID[uid] = addrs
+		}
+	}
+	return nil
+}
+
+// entryEmailAddresses splits an entry's addresses into primary and aliases,
+// dropping case-insensitive duplicates
+func entryEmailAddresses(entry *ldap.Entry) (EmailAddresses, bool) {
+	mails := entry.GetEqualFoldAttributeValues(AttrMail)
+	primary := entry.GetEqualFoldAttributeValue(AttrRhatPrimaryMail)
+	if primary == "" && len(mails) > 0 {
+		primary = mails[0]
+	}
+	if primary == "" {
+		return EmailAddresses{}, false
+	}
+	addrs := EmailAddresses{Primary: primary}
+	seen := map[string]bool{strings.ToLower(primary): true}
+	for _, mail := range append(mails, entry.GetEqualFoldAttributeValues("mailAlternateAddress")...) {
+		if key := strings.ToLower(mail); mail != "" && !seen[key] {
+			seen[key] = true
+			addrs.Aliases = append(addrs.Aliases, mail)
+		}
+	}
+	return addrs, true
+}
+
+// mapUIDChunkOffline resolves uids from the offline snapshot, which records
+// only the primary address
+func (s *Searcher) mapUIDChunkOffline(ctx context.Context, uids []string, byUID map[string]EmailAddresses) error {
+	ids := make([]Identifier, len(uids))
+	for i, uid := range uids {
+		ids[i] = Identifier{Type: IDTUID, Value: uid}
+	}
+	recs, err := s.offlineUsers(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, rec := range recs {
+		if rec.Email != "" {
+			byUID[uids[i]] = EmailAddresses{Primary: rec.Email}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestMapEmailsToUIDs(t *testing.T) {
+	// More emails than fit in one mapping search
+	searcher, _ := newEmbeddedSearcher(t, 150)
+
+	var emails []string
+	for i := 0; i < 150; i++ {
+		emails = append(emails, testserver.UserUID(i)+"@redhat.com")
+	}
+	emails = append(emails,
+		" "+strings.ToUpper(testserver.UserUID(7))+"@REDHAT.COM ",
+		"nobody@redhat.com",
+		"",
+		"nobody@redhat.com",
+	)
+
+	found, notFound, err := searcher.MapEmailsToUIDs(context.Background(), emails)
+	if err != nil {
+		t.Fatalf("MapEmailsToUIDs failed: %v", err)
+	}
+	if len(found) != 151 {
+		t.Errorf("Expected 151 mapped emails, got %d", len(found))
+	}
+	if uid := found[testserver.UserUID(149)+"@redhat.com"]; uid != testserver.UserUID(149) {
+		t.Errorf("Expected %s, got %q", testserver.UserUID(149), uid)
+	}
+	if uid := found[" "+strings.ToUpper(testserver.UserUID(7))+"@REDHAT.COM "]; uid != testserver.UserUID(7) {
+		t.Errorf("Expected case-insensitive match for %s, got %q", testserver.UserUID(7), uid)
+	}
+	if expected := []string{"nobody@redhat.com", "nobody@redhat.com"}; !reflect.DeepEqual(notFound, expected) {
+		t.Errorf("Expected notFound %v, got %v", expected, notFound)
+	}
+}
+
+func TestMapUIDsToEmails(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	srv.AddEntry("uid=jdoe,"+testserver.UsersBaseDN, map[string][]string{
+		"uid":                  {"jdoe"},
+		"mail":                 {"john.doe@redhat.com", "jdoe@redhat.com"},
+		"rhatPrimaryMail":      {"jdoe@redhat.com"},
+		"mailAlternateAddress": {"JDOE@redhat.com", "johnd@redhat.com"},
+	})
+	srv.AddEntry("uid=nomail,"+testserver.UsersBaseDN, map[string][]string{
+		"uid": {"nomail"},
+	})
+
+	found, notFound, err := searcher.MapUIDsToEmails(context.Background(),
+		[]string{testserver.UserUID(1), "jdoe", "nomail", "missing", " ", "jdoe"})
+	if err != nil {
+		t.Fatalf("MapUIDsToEmails failed: %v", err)
+	}
+
+	expected := map[string]ldap_redhat.EmailAddresses{
+		testserver.UserUID(1): {Primary: testserver.UserUID(1) + "@redhat.com"},
+		"jdoe":                {Primary: "jdoe@redhat.com", Aliases: []string{"john.doe@redhat.com", "johnd@redhat.com"}},
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, found)
+	}
+	if expected := []string{"nomail", "missing"}; !reflect.DeepEqual(notFound, expected) {
+		t.Errorf("Expected notFound %v, got %v", expected, notFound)
+	}
+}
@@ -0,0 +1,179 @@
+package ldap_redhat
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaskAction says how an exported field is masked
+type MaskAction string
+
+// Mask actions
+const (
+	MaskDrop     MaskAction = "drop"     // omit the field
+	MaskHash     MaskAction = "hash"     // replace the value with a keyed SHA-256 hash
+	MaskTruncate MaskAction = "truncate" // keep only the first Length characters
+)
+
+// FieldMask masks one exported field.
+type FieldMask struct {
+	Action MaskAction `yaml:"action" json:"action"`
+	// Length is the number of characters kept by MaskTruncate. Values are
+	// truncated in their exported string form, RFC 3339 for times, so 4 keeps
+	// the year of a date and 7 the year and month.
+	Length int `yaml:"length,omitempty" json:"length,omitempty"`
+}
+
+// MaskingPolicy masks HR-sensitive fields in exports shared with other
+// environments, such as analytics copies of a snapshot. Fields are keyed by
+// their JSON names (e.g. "term_date", "rhat_term_date", "cost_center").
+type MaskingPolicy struct {
+	Fields map[string]FieldMask `yaml:"fields" json:"fields"`
+	// Salt keys MaskHash, which requires one. Hashes are stable for a given
+	// salt, so hashed values can still be joined across exports; without a
+	// salt, low-entropy values such as UIDs could be recovered by hashing
+	// guesses.
+	Salt string `yaml:"salt" json:"-"`
+}
+
+// LoadMaskingPolicy reads a YAML masking policy:
+//
+//	salt: "..."
+//	fields:
+//	  term_date: {action: truncate, length: 7}
+//	  rhat_term_date: {action: drop}
+//	  rhat_uuid: {action: hash}
+func LoadMaskingPolicy(path string) (MaskingPolicy, error) {
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return MaskingPolicy{}, fmt.Errorf("failed to read masking policy %s: %w", path, err)
+	}
+	var p MaskingPolicy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return MaskingPolicy{}, fmt.Errorf("failed to parse masking policy %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return MaskingPolicy{}, fmt.Errorf("invalid masking policy %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate rejects unknown fields and actions, non-positive truncation
+// lengths, and hashing without a salt.
+func (p MaskingPolicy) Validate() error {
+	known := userRecordFieldNames()
+	for field, mask := range p.Fields {
+		if !known[field] {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if err := mask.validate(field, p.Salt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate rejects unknown actions, non-positive truncation lengths, and
+// hashing without a salt
+func (m FieldMask) validate(field, salt string) error {
+	switch m.Action {
+	case MaskDrop:
+	case MaskHash:
+		if salt == "" {
+			return fmt.Errorf("field %s: hash requires a salt", field)
+		}
+	case MaskTruncate:
+		if m.Length <= 0 {
+			return fmt.Errorf("field %s: truncate requires a positive length", field)
+		}
+	default:
+		return fmt.Errorf("field %s: unknown mask action %q", field, m.Action)
+	}
+	return nil
+}
+
+// Apply returns u keyed by JSON field names, as ToMap does, with the policy's
+// masks applied. Masked values become strings.
+func (p MaskingPolicy) Apply(u UserRecord) map[string]any {
+	out := u.ToMap()
+	for field, mask := range p.Fields {
+		value, ok := out[field]
+		if !ok {
+			continue
+		}
+		switch mask.Action {
+		case MaskDrop:
+			delete(out, field)
+		case MaskHash, MaskTruncate:
+			out[field] = mask.apply(exportString(value), p.Salt)
+		}
+	}
+	return out
+}
+
+// apply returns value hashed with salt or truncated as m says, and value
+// itself for MaskDrop, which callers handle by omitting the field
+func (m FieldMask) apply(value, salt string) string {
+	switch m.Action {
+	case MaskHash:
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))
+	case MaskTruncate:
+		return truncateRunes(value, m.Length)
+	}
+	return value
+}
+
+// exportString renders a ToMap value as it appears in exports
+func exportString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// truncateRunes keeps the first n characters of s
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// WriteMasked encodes the snapshot as JSON Lines with policy applied to every
+// record. The output can no longer be read back as a snapshot if identity
+// fields are masked.
+func (sn *Snapshot) WriteMasked(w io.Writer, policy MaskingPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid masking policy: %w", err)
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, u := range sn.Users {
+		if err := enc.Encode(policy.Apply(u)); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFileMasked atomically replaces path with the masked snapshot, like
+// WriteFile.
+func (sn *Snapshot) WriteFileMasked(path string, policy MaskingPolicy) error {
+	return writeFileAtomic(path, func(w io.Writer) error { return sn.WriteMasked(w, policy) })
+}
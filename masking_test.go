@@ -0,0 +1,97 @@
+package ldap_redhat_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestMaskingPolicyApply(t *testing.T) {
+	policy := ldap_redhat.MaskingPolicy{
+		Salt: "pepper",
+		Fields: map[string]ldap_redhat.FieldMask{
+			"rhat_term_date": {Action: ldap_redhat.MaskDrop},
+			"term_date":      {Action: ldap_redhat.MaskTruncate, Length: 7},
+			"rhat_uuid":      {Action: ldap_redhat.MaskHash},
+			"cost_center":    {Action: ldap_redhat.MaskDrop},
+		},
+	}
+	user := ldap_redhat.UserRecord{
+		UID:          "jdoe",
+		Email:        "jdoe@redhat.com",
+		RhatUUID:     "abc-123",
+		RhatTermDate: "20240315000000Z",
+		TermDate:     time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	m := policy.Apply(user)
+	if _, ok := m["rhat_term_date"]; ok {
+		t.Error("Expected rhat_term_date to be dropped")
+	}
+	if m["term_date"] != "2024-03" {
+		t.Errorf("Expected term_date truncated to 2024-03, got %v", m["term_date"])
+	}
+	hash, _ := m["rhat_uuid"].(string)
+	if len(hash) != 64 || hash == user.RhatUUID {
+		t.Errorf("Expected rhat_uuid to be a SHA-256 hex hash, got %q", hash)
+	}
+	if again := policy.Apply(user)["rhat_uuid"]; again != hash {
+		t.Error("Expected hashing to be stable for the same salt")
+	}
+	policy.Salt = "other"
+	if other := policy.Apply(user)["rhat_uuid"]; other == hash {
+		t.Error("Expected a different salt to change the hash")
+	}
+	if m["uid"] != "jdoe" || m["email"] != "jdoe@redhat.com" {
+		t.Errorf("Expected unmasked fields to be kept, got %v", m)
+	}
+}
+
+func TestMaskingPolicyValidate(t *testing.T) {
+	tests := []struct {
+		mask    map[string]ldap_redhat.FieldMask
+		wantErr bool
+	}{
+		{map[string]ldap_redhat.FieldMask{"term_date": {Action: ldap_redhat.MaskDrop}}, false},
+		{map[string]ldap_redhat.FieldMask{"salary": {Action: ldap_redhat.MaskDrop}}, true},
+		{map[string]ldap_redhat.FieldMask{"title": {Action: "encrypt"}}, true},
+		{map[string]ldap_redhat.FieldMask{"title": {Action: ldap_redhat.MaskTruncate}}, true},
+		{map[string]ldap_redhat.FieldMask{"rhat_uuid": {Action: ldap_redhat.MaskHash}}, true},
+	}
+	for _, test := range tests {
+		err := ldap_redhat.MaskingPolicy{Fields: test.mask}.Validate()
+		if (err != nil) != test.wantErr {
+			t.Errorf("Validate(%v) error = %v, wantErr %v", test.mask, err, test.wantErr)
+		}
+	}
+}
+
+func TestSnapshotWriteMasked(t *testing.T) {
+	dir := t.TempDir()
+	policyPath := filepath.Join(dir, "masking.yaml")
+	os.WriteFile(policyPath, []byte("fields:\n  rhat_term_date: {action: drop}\n  title: {action: truncate, length: 3}\n"), 0600)
+	policy, err := ldap_redhat.LoadMaskingPolicy(policyPath)
+	if err != nil {
+		t.Fatalf("LoadMaskingPolicy failed: %v", err)
+	}
+
+	sn := ldap_redhat.NewSnapshot([]ldap_redhat.UserRecord{
+		{UID: "a", Title: "Engineer", RhatTermDate: "20240315000000Z"},
+	}, time.Now())
+	var buf bytes.Buffer
+	if err := sn.WriteMasked(&buf, policy); err != nil {
+		t.Fatalf("WriteMasked failed: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid masked output %q: %v", buf.String(), err)
+	}
+	if got["title"] != "Eng" || got["rhat_term_date"] != nil || got["uid"] != "a" {
+		t.Errorf("Unexpected masked record %v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package ldap_redhat
+
+import "time"
+
+// RecordMeta describes where and when a UserRecord was retrieved, for tracing
+// stale or divergent data back to the server that returned it.
+type RecordMeta struct {
+	Server      string        `json:"server" yaml:"server"`             // URL of the LDAP server, or the snapshot file of a Stale record
+	RetrievedAt time.Time     `json:"retrieved_at" yaml:"retrieved_at"` // when the search returned the record, or when the snapshot was taken
+	Duration    time.Duration `json:"duration" yaml:"duration"`         // how long the search that returned the record took
+}
+
+// Meta returns where and when the record was retrieved. It is kept across
+// CachedSearcher hits, so the time is that of the original search. Stale
+// records carry the snapshot file and the time it was taken; records built by
+// hand or read with ReadSnapshot have zero metadata.
+func (u UserRecord) Meta() RecordMeta {
+	return u.meta
+}
+
+// serverURL returns the server the searcher connects to
+func (s *Searcher) serverURL() string {
+	if len(s.config().LdapServers) == 0 {
+		return ""
+	}
+	return s.config().LdapServers[0]
+}
+
+// recordMeta returns the metadata of records returned by a search started at
+// start, which has just completed
+func (s *Searcher) recordMeta(start time.Time) RecordMeta {
+	now := time.Now()
+	return RecordMeta{Server: s.serverURL(), RetrievedAt: now, Duration: now.Sub(start)}
+}
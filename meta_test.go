@@ -0,0 +1,76 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestRecordMeta(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx := context.Background()
+	before := time.Now()
+
+	user, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	meta := user.Meta()
+	if meta.Server != srv.URL() {
+		t.Errorf("Expected server %s, got %q", srv.URL(), meta.Server)
+	}
+	if meta.RetrievedAt.Before(before) || meta.RetrievedAt.After(time.Now()) {
+		t.Errorf("Unexpected retrieval time %v", meta.RetrievedAt)
+	}
+	if meta.Duration < 0 || meta.Duration > time.Since(before) {
+		t.Errorf("Unexpected duration %v", meta.Duration)
+	}
+
+	users, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)},
+		{Type: ldap_redhat.IDTUID, Value: "missing"},
+	})
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if users[0].Meta().Server != srv.URL() {
+		t.Errorf("Expected GetUsers record metadata, got %+v", users[0].Meta())
+	}
+	if !users[1].Meta().RetrievedAt.IsZero() {
+		t.Errorf("Expected no metadata for a missing user, got %+v", users[1].Meta())
+	}
+
+	all, err := searcher.SearchUsers(ctx, "")
+	if err != nil || len(all) == 0 {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	if all[0].Meta().Server != srv.URL() {
+		t.Errorf("Expected SearchUsers record metadata, got %+v", all[0].Meta())
+	}
+	if _, ok := all[0].ToMap()["meta"]; ok {
+		t.Error("Expected metadata to be left out of ToMap")
+	}
+}
+
+func TestRecordMetaCached(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), 0)
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+
+	first, err := cached.GetUser(ctx, id)
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	srv.Close()
+	second, err := cached.GetUser(ctx, id)
+	if err != nil {
+		t.Fatalf("Cached GetUser failed: %v", err)
+	}
+	if !second.Meta().RetrievedAt.Equal(first.Meta().RetrievedAt) || second.Meta().Server != first.Meta().Server {
+		t.Errorf("Expected cached metadata %+v, got %+v", first.Meta(), second.Meta())
+	}
+}
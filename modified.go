@@ -0,0 +1,28 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ModifiedSinceFilter returns an LDAP filter matching entries whose
+// modifyTimestamp is t or later, to combine with other clauses, e.g.
+// "(&(ou=Engineering)" + ModifiedSinceFilter(t) + ")". GeneralizedTime
+// filters have one-second precision, so t is truncated to the second.
+func ModifiedSinceFilter(t time.Time) string {
+	return fmt.Sprintf("(%s>=%s)", AttrModifyTimestamp, FormatLDAPTime(t.Truncate(time.Second)))
+}
+
+// GetUsersModifiedSince returns the users whose entries were modified at or
+// after t, for incremental syncs of downstream systems of record: keep the
+// time each sync started and pass the previous one, less a margin for clock
+// skew between the hosts and replication lag. The bound is inclusive, so
+// users modified in the second of t are returned again; apply them
+// idempotently. Users who left are only returned when the search base covers
+// Config.DeletedUsersBaseDN, with StatusDeleted; otherwise reconcile with a
+// full SearchUsers now and then. When the search stops at a size limit, the
+// users received before it are returned with a *PartialResultsError.
+func (s *Searcher) GetUsersModifiedSince(ctx context.Context, t time.Time) ([]UserRecord, error) {
+	return s.SearchUsers(ctx, "(&"+defaultSnapshotFilter+ModifiedSinceFilter(t)+")")
+}
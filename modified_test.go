@@ -0,0 +1,51 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestGetUsersModifiedSince(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 20)
+	ctx := context.Background()
+
+	// Generated user i was last modified i days after 2010-01-04 08:00 UTC
+	since := time.Date(2010, time.January, 14, 8, 0, 0, 0, time.UTC)
+	users, err := searcher.GetUsersModifiedSince(ctx, since)
+	if err != nil {
+		t.Fatalf("GetUsersModifiedSince failed: %v", err)
+	}
+	if len(users) != 10 {
+		t.Errorf("Got %d users modified since %v, want users 10 to 19", len(users), since)
+	}
+	for _, u := range users {
+		if u.UID < testserver.UserUID(10) {
+			t.Errorf("%s was not modified since %v", u.UID, since)
+		}
+	}
+
+	now := time.Now()
+	srv.SetAttribute(testserver.UserDN(3), ldap_redhat.AttrModifyTimestamp, ldap_redhat.FormatLDAPTime(now))
+	srv.SetAttribute(testserver.UserDN(17), ldap_redhat.AttrModifyTimestamp, ldap_redhat.FormatLDAPTime(now))
+	users, err = searcher.GetUsersModifiedSince(ctx, now.Add(-time.Minute))
+	var uids []string
+	for _, u := range users {
+		uids = append(uids, u.UID)
+	}
+	sort.Strings(uids)
+	if err != nil || len(uids) != 2 || uids[0] != testserver.UserUID(3) || uids[1] != testserver.UserUID(17) {
+		t.Errorf("Got %v, %v, want the 2 users just modified", uids, err)
+	}
+}
+
+func TestModifiedSinceFilter(t *testing.T) {
+	got := ldap_redhat.ModifiedSinceFilter(time.Date(2024, time.May, 1, 12, 30, 15, 999, time.FixedZone("CEST", 2*3600)))
+	if want := "(modifyTimestamp>=20240501103015Z)"; got != want {
+		t.Errorf("ModifiedSinceFilter returned %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+package ldap_redhat
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+)
+
+// Option configures the searcher built by NewSearcherWithOptions. Options
+// are applied in order, so later ones override earlier ones.
+type Option func(*Config)
+
+// WithConfig starts from config, replacing every setting made by earlier
+// options. Use it first to adjust a loaded configuration, e.g.
+// WithConfig(LoadDefaultConfig()).
+func WithConfig(config Config) Option {
+	return func(c *Config) { *c = config }
+}
+
+// WithEnv starts from the configuration in LDAP_* environment variables
+// alone, as NewSearcherFromEnv reads them, replacing every setting made by
+// earlier options
+func WithEnv() Option {
+	return func(c *Config) { *c = configFromEnv() }
+}
+
+// WithServers sets the LDAP server URLs, e.g. "ldaps://ldap.corp.redhat.com"
+func WithServers(urls ...string) Option {
+	return func(c *Config) { c.LdapServers = append([]string(nil), urls...) }
+}
+
+// WithBind binds as dn with a fixed password
+func WithBind(dn, password string) Option {
+	return WithCredentials(dn, StaticPassword(password))
+}
+
+// WithCredentials binds as dn with the password source asks for at each
+// bind, such as a PasswordFile
+func WithCredentials(dn string, source CredentialSource) Option {
+	return func(c *Config) { c.Username, c.Credentials = dn, source }
+}
+
+// WithBaseDN sets the search base
+func WithBaseDN(dn string) Option {
+	return func(c *Config) { c.BaseDN = dn }
+}
+
+// WithStartTLS upgrades ldap:// connections with StartTLS
+func WithStartTLS() Option {
+	return func(c *Config) { c.UseStartTLS = true }
+}
+
+// WithVerifySSL verifies server certificates
+func WithVerifySSL() Option {
+	return func(c *Config) { c.VerifySSL = true }
+}
+
+// WithTimeout bounds connecting, binding and each search by d; see
+// Config.DialTimeout for zero and negative values
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) { c.DialTimeout, c.BindTimeout, c.SearchTimeout = d, d, d }
+}
+
+// WithLogger logs dials, binds and searches to logger at debug level
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) { c.Logger = logger }
+}
+
+// WithInterceptors adds interceptors around the searcher's lookups, after
+// those already added
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Config) {
+		c.Interceptors = append(append([]Interceptor(nil), c.Interceptors...), interceptors...)
+	}
+}
+
+// WithAudit sends an AuditEvent for every user lookup to sink, masked by
+// redaction
+func WithAudit(sink AuditSink, redaction AuditRedaction) Option {
+	return func(c *Config) { c.AuditSink, c.AuditRedaction = sink, redaction }
+}
+
+// WithLazyConnect makes the searcher dial on first use instead of in the
+// constructor
+func WithLazyConnect() Option {
+	return func(c *Config) { c.LazyConnect = true }
+}
+
+// WithClientName identifies the consuming service to the server and in logs
+func WithClientName(name string) Option {
+	return func(c *Config) { c.ClientName = name }
+}
+
+// NewSearcherWithOptions creates a searcher configured by opts, starting from
+// an empty Config, and connects it as NewSearcher does. ctx is passed to the
+// initial Connect, for tracing and the credential source.
+//
+//	searcher, err := ldap_redhat.NewSearcherWithOptions(ctx,
+//		ldap_redhat.WithServers("ldap://ldap.corp.redhat.com"),
+//		ldap_redhat.WithBind(bindDN, password),
+//		ldap_redhat.WithStartTLS(),
+//		ldap_redhat.WithTimeout(5*time.Second),
+//	)
+func NewSearcherWithOptions(ctx context.Context, opts ...Option) (*Searcher, error) {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := checkSearcherConfig(config); err != nil {
+		return nil, err
+	}
+	var fixtures *ldapserver.Server
+	if config.Offline {
+		var err error
+		if fixtures, config, err = startFixtureServer(config); err != nil {
+			return nil, err
+		}
+	}
+	searcher := &Searcher{Config: config, breaker: newBreaker(config), fixtures: fixtures}
+	if len(config.LdapServers) == 0 || config.LazyConnect {
+		return searcher, nil
+	}
+	if err := searcher.Connect(ctx); err != nil {
+		if fixtures != nil {
+			fixtures.Close()
+		}
+		if searcher.offlineEnabled() && searcher.unreachable(err) {
+			if _, snapErr := searcher.loadSnapshot(); snapErr == nil {
+				return searcher, nil
+			}
+		}
+		return nil, err
+	}
+	return searcher, nil
+}
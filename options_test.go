@@ -0,0 +1,107 @@
+package ldap_redhat_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestNewSearcherWithOptions(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	var buf bytes.Buffer
+	ctx := context.Background()
+	searcher, err := ldap_redhat.NewSearcherWithOptions(ctx,
+		ldap_redhat.WithServers(srv.URL()),
+		ldap_redhat.WithBind(embeddedBindDN, embeddedPassword),
+		ldap_redhat.WithBaseDN(testserver.UsersBaseDN),
+		ldap_redhat.WithTimeout(5*time.Second),
+		ldap_redhat.WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		ldap_redhat.WithClientName("options-test"),
+	)
+	if err != nil {
+		t.Fatalf("NewSearcherWithOptions failed: %v", err)
+	}
+	defer searcher.Close()
+
+	if searcher.Connection() == nil {
+		t.Fatal("Expected the searcher to be connected")
+	}
+	uid := testserver.UserUID(3)
+	user, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.UID != uid {
+		t.Errorf("Expected %s, got %s", uid, user.UID)
+	}
+	if config := searcher.Config; config.SearchTimeout != 5*time.Second || config.Password != "" {
+		t.Errorf("Unexpected config: SearchTimeout %v, Password %q", config.SearchTimeout, config.Password)
+	}
+	if !strings.Contains(buf.String(), "conn=options-test/") {
+		t.Errorf("Expected logs from the options-test connection, got:\n%s", buf.String())
+	}
+}
+
+func TestNewSearcherWithOptionsOrder(t *testing.T) {
+	ctx := context.Background()
+	searcher, err := ldap_redhat.NewSearcherWithOptions(ctx,
+		ldap_redhat.WithServers("ldap://ignored.example.com"),
+		ldap_redhat.WithConfig(ldap_redhat.Config{BaseDN: "dc=example,dc=com"}),
+		ldap_redhat.WithServers("ldap://ldap.example.com"),
+		ldap_redhat.WithStartTLS(),
+		ldap_redhat.WithLazyConnect(),
+	)
+	if err != nil {
+		t.Fatalf("NewSearcherWithOptions failed: %v", err)
+	}
+	config := searcher.Config
+	if got := strings.Join(config.LdapServers, ","); got != "ldap://ldap.example.com" {
+		t.Errorf("Expected only the server set after WithConfig, got %s", got)
+	}
+	if config.BaseDN != "dc=example,dc=com" || !config.UseStartTLS || !config.LazyConnect {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if searcher.Connection() != nil {
+		t.Error("Expected a lazy searcher not to connect")
+	}
+}
+
+func TestWithEnv(t *testing.T) {
+	t.Setenv("LDAP_URL", "ldap://ldap.example.com")
+	t.Setenv("LDAP_BIND_DN", "uid=svc,dc=example,dc=com")
+	t.Setenv("LDAP_PASSWORD_FILE", "")
+	t.Setenv("LDAP_PASSWORD", "s3cret")
+	t.Setenv("LDAP_BASE_DN", "dc=example,dc=com")
+	t.Setenv("LDAP_LAZY_CONNECT", "true")
+
+	searcher, err := ldap_redhat.NewSearcherWithOptions(context.Background(),
+		ldap_redhat.WithServers("ldap://ignored.example.com"),
+		ldap_redhat.WithEnv(),
+		ldap_redhat.WithClientName("env-test"),
+	)
+	if err != nil {
+		t.Fatalf("NewSearcherWithOptions failed: %v", err)
+	}
+	config := searcher.Config
+	if got := strings.Join(config.LdapServers, ","); got != "ldap://ldap.example.com" {
+		t.Errorf("Expected the server from LDAP_URL, got %s", got)
+	}
+	if config.BaseDN != "dc=example,dc=com" || !config.LazyConnect || config.ClientName != "env-test" || config.Password != "" {
+		t.Errorf("Unexpected config: %+v", config.Redacted())
+	}
+	password, err := config.Credentials.Password(context.Background())
+	if err != nil || password != "s3cret" {
+		t.Errorf("Expected credentials from LDAP_PASSWORD, got %q (%v)", password, err)
+	}
+
+	t.Setenv("LDAP_PASSWORD", "")
+	if source := ldap_redhat.CredentialsFromEnv(); source != nil {
+		t.Errorf("Expected no credentials without LDAP_PASSWORD, got %v", source)
+	}
+}
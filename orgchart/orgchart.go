@@ -0,0 +1,214 @@
+// Package orgchart builds the reporting tree below a manager from the
+// directory's manager attributes, for org chart pages, headcount rollups and
+// approval routing. Levels are fetched one at a time with the direct report
+// searches of a level run in parallel, and users reachable twice through a
+// management cycle are placed once.
+//
+//	chart, err := orgchart.Build(ctx, searcher, "vp-eng", orgchart.Options{MaxDepth: 3})
+//	err = chart.WriteDOT(os.Stdout) // dot -Tsvg
+package orgchart
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+// DefaultConcurrency is the number of direct report searches run at once
+// when Options.Concurrency is zero.
+const DefaultConcurrency = 8
+
+// Directory is the part of *ldap_redhat.Searcher that Build uses, also
+// implemented by ldaptest.FakeSearcher.
+type Directory interface {
+	GetUser(ctx context.Context, id ldap_redhat.Identifier) (ldap_redhat.UserRecord, error)
+	FindDirectReports(ctx context.Context, managerUID string, opts ...ldap_redhat.ReportSearchOptions) ([]ldap_redhat.UserRecord, error)
+}
+
+// Options configures Build.
+type Options struct {
+	MaxDepth         int      // levels below the root to include (0 = unlimited, 1 = direct reports only)
+	Concurrency      int      // direct report searches run at once (0 = DefaultConcurrency)
+	ExcludeCountries []string // ISO country codes whose users, and their reports, are left out
+}
+
+// Node is a user and the people reporting directly to them.
+type Node struct {
+	User    ldap_redhat.UserRecord `json:"user" yaml:"user"`
+	Reports []*Node                `json:"reports,omitempty" yaml:"reports,omitempty"` // sorted by UID
+}
+
+// Cycle is a manager attribute Build did not follow because the report was
+// already in the chart, higher up or under another manager.
+type Cycle struct {
+	ManagerUID string `json:"manager_uid" yaml:"manager_uid"`
+	ReportUID  string `json:"report_uid" yaml:"report_uid"`
+}
+
+// Chart is the reporting tree below a root user.
+type Chart struct {
+	Root   *Node   `json:"root" yaml:"root"`
+	Size   int     `json:"size" yaml:"size"`                         // people in the chart, the root included
+	Cycles []Cycle `json:"cycles,omitempty" yaml:"cycles,omitempty"` // edges left out to break management cycles
+}
+
+// Build returns the chart below rootUID. Each level is fetched before the
+// next, so a user reachable from two managers is placed under the one
+// closest to the root, and between managers at the same level under the one
+// listed first in the chart. The first search error cancels the searches
+// still running and is returned.
+func Build(ctx context.Context, dir Directory, rootUID string, opts Options) (*Chart, error) {
+	root, err := dir.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: rootUID})
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chart := &Chart{Root: &Node{User: root}, Size: 1}
+	placed := map[string]bool{root.UID: true}
+	level := []*Node{chart.Root}
+	for depth := 1; len(level) > 0 && (opts.MaxDepth <= 0 || depth <= opts.MaxDepth); depth++ {
+		reports, err := fetchLevel(ctx, cancel, dir, level, concurrency, opts.ExcludeCountries)
+		if err != nil {
+			return nil, err
+		}
+		var next []*Node
+		for i, n := range level {
+			for _, u := range reports[i] {
+				if placed[u.UID] {
+					chart.Cycles = append(chart.Cycles, Cycle{ManagerUID: n.User.UID, ReportUID: u.UID})
+					continue
+				}
+				placed[u.UID] = true
+				child := &Node{User: u}
+				n.Reports = append(n.Reports, child)
+				next = append(next, child)
+			}
+		}
+		chart.Size += len(next)
+		level = next
+	}
+	return chart, nil
+}
+
+// fetchLevel returns the direct reports of each node of level, sorted by UID,
+// searching for up to concurrency nodes at once. A failed search cancels the
+// others.
+func fetchLevel(ctx context.Context, cancel context.CancelFunc, dir Directory, level []*Node, concurrency int, exclude []string) ([][]ldap_redhat.UserRecord, error) {
+	reports := make([][]ldap_redhat.UserRecord, len(level))
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, n := range level {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			users, err := dir.FindDirectReports(ctx, n.User.UID, ldap_redhat.ReportSearchOptions{ExcludeCountries: exclude})
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to fetch the reports of %s: %w", n.User.UID, err)
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+			users = liveReports(users)
+			sort.Slice(users, func(a, b int) bool { return users[a].UID < users[b].UID })
+			reports[i] = users
+		}()
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return reports, firstErr
+}
+
+// liveReports drops users without a UID and those under the deleted users
+// base, which keep their manager attribute
+func liveReports(users []ldap_redhat.UserRecord) []ldap_redhat.UserRecord {
+	out := users[:0]
+	for _, u := range users {
+		if u.UID != "" && u.Status != ldap_redhat.StatusDeleted {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// Walk calls fn for every node of the chart, depth first from the root with
+// reports in UID order, with the node's depth below the root. It stops at
+// the first error fn returns.
+func (c *Chart) Walk(fn func(n *Node, depth int) error) error {
+	var walk func(n *Node, depth int) error
+	walk = func(n *Node, depth int) error {
+		if err := fn(n, depth); err != nil {
+			return err
+		}
+		for _, r := range n.Reports {
+			if err := walk(r, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(c.Root, 0)
+}
+
+// WriteJSON writes the chart as indented JSON.
+func (c *Chart) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// WriteDOT writes the chart as a Graphviz digraph, one box per person
+// labelled with their name and title and an edge from each manager to each
+// report.
+func (c *Chart) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph orgchart {\n\trankdir=TB;\n\tnode [shape=box];\n")
+	c.Walk(func(n *Node, _ int) error {
+		label := n.User.UID
+		if n.User.DisplayName != "" {
+			label = n.User.DisplayName
+		}
+		if n.User.Title != "" {
+			label += "\n" + n.User.Title
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", dotQuote(n.User.UID), dotQuote(label))
+		for _, r := range n.Reports {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", dotQuote(n.User.UID), dotQuote(r.User.UID))
+		}
+		return nil
+	})
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote returns s as a DOT quoted string
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,117 @@
+package orgchart_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/ldaptest"
+	"github.com/openshift-eng/go-ldap-redhat/orgchart"
+)
+
+// newOrg returns a fake directory with vp at the top, two managers below and
+// three engineers below them
+func newOrg() *ldaptest.FakeSearcher {
+	return ldaptest.NewFakeSearcher(
+		ldap_redhat.UserRecord{UID: "vp", DisplayName: "Vera Pike", Title: "VP Engineering"},
+		ldap_redhat.UserRecord{UID: "mgr-b", ManagerUID: "vp"},
+		ldap_redhat.UserRecord{UID: "mgr-a", ManagerUID: "vp"},
+		ldap_redhat.UserRecord{UID: "eng-1", ManagerUID: "mgr-a"},
+		ldap_redhat.UserRecord{UID: "eng-2", ManagerUID: "mgr-a", Country: "DEU"},
+		ldap_redhat.UserRecord{UID: "eng-3", ManagerUID: "mgr-b"},
+	)
+}
+
+// layout returns the chart as "uid@depth" in walk order
+func layout(c *orgchart.Chart) string {
+	var out []string
+	c.Walk(func(n *orgchart.Node, depth int) error {
+		out = append(out, n.User.UID+"@"+strconv.Itoa(depth))
+		return nil
+	})
+	return strings.Join(out, " ")
+}
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	chart, err := orgchart.Build(ctx, newOrg(), "vp", orgchart.Options{Concurrency: 1})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if got, want := layout(chart), "vp@0 mgr-a@1 eng-1@2 eng-2@2 mgr-b@1 eng-3@2"; got != want {
+		t.Errorf("Chart = %s, want %s", got, want)
+	}
+	if chart.Size != 6 || len(chart.Cycles) != 0 {
+		t.Errorf("Got size %d with cycles %v, want 6 without cycles", chart.Size, chart.Cycles)
+	}
+
+	chart, err = orgchart.Build(ctx, newOrg(), "vp", orgchart.Options{MaxDepth: 1})
+	if err != nil || layout(chart) != "vp@0 mgr-a@1 mgr-b@1" {
+		t.Errorf("Chart to depth 1 = %s (%v), want the managers only", layout(chart), err)
+	}
+	chart, err = orgchart.Build(ctx, newOrg(), "vp", orgchart.Options{ExcludeCountries: []string{"DEU"}})
+	if err != nil || chart.Size != 5 {
+		t.Errorf("Chart without DEU has %d people (%v), want 5", chart.Size, err)
+	}
+}
+
+func TestBuildCycle(t *testing.T) {
+	// vp reports to eng-3, two levels below them
+	dir := newOrg()
+	dir.AddUser(ldap_redhat.UserRecord{UID: "vp", ManagerUID: "eng-3"})
+	chart, err := orgchart.Build(context.Background(), dir, "vp", orgchart.Options{})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if chart.Size != 6 || len(chart.Cycles) != 1 || chart.Cycles[0] != (orgchart.Cycle{ManagerUID: "eng-3", ReportUID: "vp"}) {
+		t.Errorf("Got size %d with cycles %v, want 6 with the eng-3 -> vp edge left out", chart.Size, chart.Cycles)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	dir := newOrg()
+	if _, err := orgchart.Build(context.Background(), dir, "nobody", orgchart.Options{}); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for an unknown root, got %v", err)
+	}
+	dir.SetError("FindDirectReports", ldap_redhat.ErrTimeout)
+	if _, err := orgchart.Build(context.Background(), dir, "vp", orgchart.Options{}); !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Errorf("Expected the search error, got %v", err)
+	}
+}
+
+func TestChartSerialization(t *testing.T) {
+	chart, err := orgchart.Build(context.Background(), newOrg(), "vp", orgchart.Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := chart.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded orgchart.Chart
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || layout(&decoded) != layout(chart) {
+		t.Errorf("JSON does not round-trip (%v):\n%s", err, buf.String())
+	}
+
+	buf.Reset()
+	if err := chart.WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	dot := buf.String()
+	for _, want := range []string{
+		"digraph orgchart {",
+		`"vp" [label="Vera Pike\nVP Engineering"];`,
+		`"vp" -> "mgr-a";`,
+		`"vp" -> "mgr-b";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output is missing %s:\n%s", want, dot)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ldap_redhat
+
+import (
+	"context"
+	"net/http"
+)
+
+// photoAttributes hold a user's picture, in order of preference: the
+// inetOrgPerson jpegPhoto, then the smaller thumbnailPhoto Active Directory
+// and some directory sync tools populate
+var photoAttributes = []string{AttrJpegPhoto, AttrThumbnailPhoto}
+
+// Photo is a user's picture as stored in the directory.
+type Photo struct {
+	Data        []byte
+	ContentType string // sniffed from Data, e.g. "image/jpeg"; jpegPhoto values are not always JPEGs
+	Attribute   string // the attribute it was read from, jpegPhoto or thumbnailPhoto
+}
+
+// GetUserPhoto returns the picture of the user id identifies, from jpegPhoto
+// or, failing that, thumbnailPhoto. Photos are fetched separately from
+// GetUser because they are large and few callers need them. It returns
+// ErrNoPhoto if the user has neither attribute, or the request scope does
+// not allow them, and is not served from the offline snapshot.
+func (s *Searcher) GetUserPhoto(ctx context.Context, id Identifier) (Photo, error) {
+	photo, err := s.getUserPhoto(ctx, id)
+	s.auditLookup(ctx, AuditGetUserPhoto, id, err)
+	return photo, err
+}
+
+// getUserPhoto is GetUserPhoto without auditing
+func (s *Searcher) getUserPhoto(ctx context.Context, id Identifier) (Photo, error) {
+	attrs := append([]string{AttrUID}, scopedAttributes(ctx, photoAttributes)...)
+	entry, err := s.lookupEntry(ctx, id, attrs)
+	if err != nil {
+		return Photo{}, err
+	}
+	for _, attr := range attrs[1:] {
+		if values := entry.GetEqualFoldRawAttributeValues(attr); len(values) > 0 && len(values[0]) > 0 {
+			return Photo{Data: values[0], ContentType: http.DetectContentType(values[0]), Attribute: attr}, nil
+		}
+	}
+	return Photo{}, newError(ErrNoPhoto, "no photo in LDAP directory for %s", id.Value)
+}
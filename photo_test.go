@@ -0,0 +1,47 @@
+package ldap_redhat_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// jpegHeader is enough of a JPEG for content type sniffing
+var jpegHeader = []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01}
+
+func TestGetUserPhoto(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx := context.Background()
+	srv.SetAttribute(testserver.UserDN(1), "jpegPhoto", string(jpegHeader))
+	srv.SetAttribute(testserver.UserDN(2), "thumbnailPhoto", "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+	photo, err := searcher.GetUserPhoto(ctx, uidIdentifier(1))
+	if err != nil {
+		t.Fatalf("GetUserPhoto failed: %v", err)
+	}
+	if !bytes.Equal(photo.Data, jpegHeader) || photo.ContentType != "image/jpeg" || photo.Attribute != "jpegPhoto" {
+		t.Errorf("Got %d bytes of %s from %s, want the jpegPhoto as image/jpeg", len(photo.Data), photo.ContentType, photo.Attribute)
+	}
+
+	photo, err = searcher.GetUserPhoto(ctx, uidIdentifier(2))
+	if err != nil || photo.ContentType != "image/png" || photo.Attribute != "thumbnailPhoto" {
+		t.Errorf("Got %s from %s (%v), want the thumbnailPhoto as image/png", photo.ContentType, photo.Attribute, err)
+	}
+
+	if _, err := searcher.GetUserPhoto(ctx, uidIdentifier(3)); !errors.Is(err, ldap_redhat.ErrNoPhoto) {
+		t.Errorf("Expected ErrNoPhoto for a user without a photo, got %v", err)
+	}
+	if _, err := searcher.GetUserPhoto(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nobody"}); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	// A scope without the photo attributes hides them
+	scoped := ldap_redhat.WithRequestScope(ctx, ldap_redhat.RequestScope{Attributes: []string{"cn"}})
+	if _, err := searcher.GetUserPhoto(scoped, uidIdentifier(1)); !errors.Is(err, ldap_redhat.ErrNoPhoto) {
+		t.Errorf("Expected ErrNoPhoto outside the request scope, got %v", err)
+	}
+}
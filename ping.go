@@ -0,0 +1,69 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Ping checks the connection with a base-scope read of the root DSE, which
+// every server answers cheaply. If the check fails, or the connection was
+// already closed, Ping re-dials and re-binds with the searcher's Config and
+// swaps in the new connection. Long-running services should call it
+// periodically, or before use after an idle period, to recover from
+// connections the server dropped.
+//
+// Ping may run concurrently with other calls. Calls in flight on a
+// connection it replaces fail and may be retried.
+func (s *Searcher) Ping(ctx context.Context) error {
+	if len(s.config().LdapServers) == 0 {
+		return fmt.Errorf("no LDAP servers configured")
+	}
+	s.pingMu.Lock()
+	defer s.pingMu.Unlock()
+	if conn := s.conn(); conn != nil && !conn.IsClosing() {
+		if err := s.probeRootDSE(ctx, conn); err == nil {
+			return nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.reconnect(ctx)
+}
+
+// probeRootDSE performs a base-scope search of the root DSE on conn that
+// returns no attributes, bounded by ctx.
+func (s *Searcher) probeRootDSE(ctx context.Context, conn *ldap.Conn) error {
+	req := ldap.NewSearchRequest(
+		"", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", []string{"1.1"}, nil,
+	)
+	probeCtx, cancel := context.WithCancel(ctx)
+	resp := conn.SearchAsync(probeCtx, req, 1)
+	defer func() {
+		cancel()
+		for resp.Next() {
+		}
+	}()
+	for resp.Next() {
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := resp.Err(); err != nil {
+		return wrapLDAPError(err, "LDAP ping failed")
+	}
+	return nil
+}
+
+// reconnect dials a fresh connection, swaps it in and closes the old one
+func (s *Searcher) reconnect(ctx context.Context) error {
+	conn, name, err := s.dial(ctx)
+	if err != nil {
+		return wrapLDAPError(err, "LDAP reconnect failed")
+	}
+	s.setConn(conn, name)
+	return nil
+}
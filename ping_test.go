@@ -0,0 +1,60 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestPingHealthyConnection(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	conn := searcher.Conn
+
+	if err := searcher.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if searcher.Conn != conn {
+		t.Error("Ping should keep a healthy connection")
+	}
+}
+
+func TestPingReconnectsDroppedConnection(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "user000001"}
+
+	srv.DropConnections()
+	// Wait for the client to notice the closed socket
+	deadline := time.Now().Add(2 * time.Second)
+	for !searcher.Conn.IsClosing() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := searcher.GetUser(ctx, id); err == nil {
+		t.Fatal("Expected GetUser to fail on a dropped connection")
+	}
+
+	if err := searcher.Ping(ctx); err != nil {
+		t.Fatalf("Ping should reconnect, got: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, id); err != nil {
+		t.Errorf("GetUser after reconnect failed: %v", err)
+	}
+}
+
+func TestPingServerDown(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	srv.Close()
+
+	if err := searcher.Ping(context.Background()); err == nil {
+		t.Error("Expected Ping to fail when the server is down")
+	}
+}
+
+func TestPingWithoutServers(t *testing.T) {
+	searcher := &ldap_redhat.Searcher{Config: ldap_redhat.Config{}}
+	if err := searcher.Ping(context.Background()); err == nil {
+		t.Error("Expected Ping to fail without configured servers")
+	}
+}
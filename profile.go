@@ -0,0 +1,99 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// AttributeProfile selects how much personal data user lookups fetch, so
+// that services which do not need HR data never request it from the
+// directory. Set it for every lookup with Config.AttributeProfile, or for
+// one request with SearchOptions.AttributeProfile. Authenticate,
+// GetUsersByCostCenter and GetUsersByLocation are the exceptions: they fetch
+// the termination date under every profile to leave out terminated users,
+// without returning it.
+type AttributeProfile string
+
+const (
+	// ProfileFull fetches every attribute mapped to a UserRecord field. It is
+	// the default.
+	ProfileFull AttributeProfile = "full"
+	// ProfileStandard fetches what a company directory shows: names,
+	// contact details, title, manager, department and location, but no HR
+	// data such as hire and termination dates, cost centers, job codes,
+	// employee numbers or person types.
+	ProfileStandard AttributeProfile = "standard"
+	// ProfileMinimal fetches only the uid, email and display name.
+	ProfileMinimal AttributeProfile = "minimal"
+)
+
+// profileAttributes lists the attributes each restricted profile fetches
+var profileAttributes = map[AttributeProfile][]string{
+	ProfileMinimal: {AttrUID, AttrMail, AttrCN},
+	ProfileStandard: {
+		AttrUID, AttrMail, AttrCN, AttrSN, AttrTitle, AttrRhatOrgCharTitle, AttrManager,
+		AttrOU, AttrRhatLocation, AttrRhatBuilding, AttrCo, AttrRhatGeo,
+		AttrTelephoneNumber, AttrMobile, AttrKrbPrincipalName, AttrRhatUUID,
+	},
+}
+
+// normalize returns the profile in its canonical form; the empty profile
+// is ProfileFull.
+func (p AttributeProfile) normalize() (AttributeProfile, error) {
+	if p == "" {
+		return ProfileFull, nil
+	}
+	switch n := AttributeProfile(strings.ToLower(string(p))); n {
+	case ProfileFull, ProfileStandard, ProfileMinimal:
+		return n, nil
+	}
+	return "", fmt.Errorf("unknown attribute_profile %q: expected %q, %q or %q", p, ProfileMinimal, ProfileStandard, ProfileFull)
+}
+
+// Attributes returns the LDAP attributes lookups with the profile fetch, or
+// nil for ProfileFull, which fetches all of them.
+func (p AttributeProfile) Attributes() []string {
+	p, _ = p.normalize()
+	return append([]string(nil), profileAttributes[p]...)
+}
+
+// scope returns the request scope allowing the profile's attributes
+func (p AttributeProfile) scope() RequestScope {
+	return RequestScope{Attributes: p.Attributes()}
+}
+
+// attributeProfile returns the profile of lookups in ctx. An invalid
+// profile, which searchOptions reports before searching, is treated as
+// ProfileMinimal so that records served without a search are not
+// returned in full.
+func (s *Searcher) attributeProfile(ctx context.Context) AttributeProfile {
+	opts, err := s.searchOptions(ctx)
+	if err != nil {
+		return ProfileMinimal
+	}
+	return opts.AttributeProfile
+}
+
+// profileAttributesFor narrows attrs to those the profile of ctx fetches
+func (s *Searcher) profileAttributesFor(ctx context.Context, attrs []string) []string {
+	scope := s.attributeProfile(ctx).scope()
+	if scope.Attributes == nil {
+		return attrs
+	}
+	out := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		if scope.allows(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// redact clears the fields of u that the request scope and attribute
+// profile of ctx do not allow, for records served from the offline
+// snapshot, and for those searched with extra attributes to match them
+func (s *Searcher) redact(ctx context.Context, u *UserRecord) {
+	redactForContext(ctx, u)
+	s.attributeProfile(ctx).scope().redact(u)
+}
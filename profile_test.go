@@ -0,0 +1,146 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// requestedAttributes returns an interceptor recording the attributes of
+// every search
+func requestedAttributes(mu *sync.Mutex, requested *[][]string) ldap_redhat.Interceptor {
+	return func(next ldap_redhat.SearchFunc) ldap_redhat.SearchFunc {
+		return func(ctx context.Context, req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			mu.Lock()
+			*requested = append(*requested, slices.Clone(req.Attributes))
+			mu.Unlock()
+			return next(ctx, req)
+		}
+	}
+}
+
+func TestAttributeProfileMinimal(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	var (
+		mu        sync.Mutex
+		requested [][]string
+	)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:      []string{srv.URL()},
+		Username:         embeddedBindDN,
+		Password:         embeddedPassword,
+		BaseDN:           testserver.UsersBaseDN,
+		AttributeProfile: ldap_redhat.ProfileMinimal,
+		Interceptors:     []ldap_redhat.Interceptor{requestedAttributes(&mu, &requested)},
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	uid := testserver.UserUID(2)
+	user, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.UID != uid || user.Email == "" || user.DisplayName == "" {
+		t.Errorf("Expected uid, email and display name, got %+v", user)
+	}
+	if user.Title != "" || user.CostCenter != "" || !user.HireDate.IsZero() || user.EmployeeNumber != "" || user.Status != "" {
+		t.Errorf("Expected no other fields, got %+v", user)
+	}
+	if len(requested) != 1 || strings.Join(requested[0], ",") != "uid,mail,cn" {
+		t.Errorf("Expected only uid, mail and cn to be requested, got %v", requested)
+	}
+
+	// Batch lookups still match by the identifiers' attributes
+	requested = nil
+	other := ldap_redhat.Identifier{Type: ldap_redhat.IDTUUID, Value: "00000003-0000-4000-8000-000000000003"}
+	users, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{other})
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if users[0].UID != testserver.UserUID(3) || users[0].RhatUUID != "" {
+		t.Errorf("Expected %s matched by rhatUUID without returning it, got %+v", testserver.UserUID(3), users[0])
+	}
+	for _, attrs := range requested {
+		for _, hr := range []string{ldap_redhat.AttrRhatHireDate, ldap_redhat.AttrRhatCostCenter, ldap_redhat.AttrEmployeeNumber} {
+			if slices.Contains(attrs, hr) {
+				t.Errorf("Expected %s not to be requested, got %v", hr, attrs)
+			}
+		}
+	}
+
+	standard := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{AttributeProfile: ldap_redhat.ProfileStandard})
+	user, err = searcher.GetUser(standard, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.Title == "" || user.ManagerUID == "" || user.RhatLocation == "" {
+		t.Errorf("Expected the standard profile to return directory fields, got %+v", user)
+	}
+	if user.CostCenter != "" || user.RhatJobCode != "" || !user.HireDate.IsZero() || user.PersonType != "" {
+		t.Errorf("Expected the standard profile to leave out HR data, got %+v", user)
+	}
+
+	invalid := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{AttributeProfile: "everything"})
+	if _, err := searcher.GetUser(invalid, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}); err == nil {
+		t.Error("Expected an unknown per-call profile to fail")
+	}
+}
+
+func TestAttributeProfileAuthenticateTerminated(t *testing.T) {
+	searcher, srv := newLDAPSSearcher(t, ldap_redhat.Config{AttributeProfile: ldap_redhat.ProfileMinimal})
+	uid := testserver.UserUID(4)
+	srv.SetAttribute(testserver.UserDN(4), ldap_redhat.AttrRhatTermDate, "20200101000000Z")
+	srv.AddBind(testserver.UserDN(4), "user-password")
+
+	_, err := searcher.Authenticate(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}, "user-password")
+	if !errors.Is(err, ldap_redhat.ErrInvalidCredentials) {
+		t.Errorf("Expected a terminated user to be refused under the minimal profile, got %v", err)
+	}
+}
+
+func TestAttributeProfileInvalid(t *testing.T) {
+	_, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:      []string{"ldap://ldap.example.com"},
+		LazyConnect:      true,
+		AttributeProfile: "everything",
+	})
+	if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+	if got := ldap_redhat.ProfileFull.Attributes(); got != nil {
+		t.Errorf("Expected the full profile to fetch every attribute, got %v", got)
+	}
+	if got := ldap_redhat.AttributeProfile("Minimal").Attributes(); len(got) != 3 {
+		t.Errorf("Expected profiles to be case-insensitive, got %v", got)
+	}
+}
+
+func TestAttributeProfileCache(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	cached := ldap_redhat.NewCachedSearcher(searcher, ldap_redhat.NewMemoryCache(), 0)
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+
+	if _, err := cached.GetUser(ctx, id); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	minimal := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{AttributeProfile: ldap_redhat.ProfileMinimal})
+	user, err := cached.GetUser(minimal, id)
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.CostCenter != "" || user.Title != "" {
+		t.Errorf("Expected the minimal profile to bypass the cached full record, got %+v", user)
+	}
+}
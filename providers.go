@@ -0,0 +1,176 @@
+package ldap_redhat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider creates the CredentialSource a credentials reference
+// names. References have the form scheme:ref, e.g. env:LDAP_BIND_PASSWORD,
+// file:/run/secrets/ldap, exec:/usr/local/bin/ldap-password or
+// vault:secret/data/ldap/bind, and are set with the credentials YAML key or
+// LDAP_CREDENTIALS. Register a provider for a new scheme with
+// RegisterCredentialProvider.
+type CredentialProvider interface {
+	Credentials(ref string) (CredentialSource, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider.
+type CredentialProviderFunc func(ref string) (CredentialSource, error)
+
+// Credentials calls f
+func (f CredentialProviderFunc) Credentials(ref string) (CredentialSource, error) {
+	return f(ref)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]CredentialProvider{}
+)
+
+func init() {
+	RegisterCredentialProvider("static", CredentialProviderFunc(func(ref string) (CredentialSource, error) {
+		return StaticPassword(ref), nil
+	}))
+	RegisterCredentialProvider("env", CredentialProviderFunc(func(ref string) (CredentialSource, error) {
+		return EnvPassword(ref), nil
+	}))
+	RegisterCredentialProvider("file", CredentialProviderFunc(func(ref string) (CredentialSource, error) {
+		return PasswordFile{Path: ref}, nil
+	}))
+	RegisterCredentialProvider("exec", CredentialProviderFunc(func(ref string) (CredentialSource, error) {
+		return PasswordCommand{Command: strings.Fields(ref)}, nil
+	}))
+}
+
+// RegisterCredentialProvider makes p handle references with scheme. It is
+// meant to be called from init functions, as the vault package does, and
+// panics if scheme is empty or already registered.
+func RegisterCredentialProvider(scheme string, p CredentialProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if scheme == "" || p == nil {
+		panic("ldap_redhat: RegisterCredentialProvider needs a scheme and a provider")
+	}
+	if _, dup := providers[scheme]; dup {
+		panic("ldap_redhat: RegisterCredentialProvider called twice for " + scheme)
+	}
+	providers[scheme] = p
+}
+
+// CredentialProviders returns the registered schemes, sorted.
+func CredentialProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	out := make([]string, 0, len(providers))
+	for scheme := range providers {
+		out = append(out, scheme)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ParseCredentials returns the CredentialSource for a scheme:ref reference.
+// Errors match ErrInvalidConfig.
+func ParseCredentials(reference string) (CredentialSource, error) {
+	scheme, ref, ok := strings.Cut(reference, ":")
+	if !ok || ref == "" {
+		return nil, newError(ErrInvalidConfig, "credentials %q: want scheme:reference, e.g. env:LDAP_BIND_PASSWORD", redactReference(reference))
+	}
+	providersMu.RLock()
+	p, ok := providers[scheme]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, newError(ErrInvalidConfig, "credentials: unknown provider %q (have %s)", scheme, strings.Join(CredentialProviders(), ", "))
+	}
+	source, err := p.Credentials(ref)
+	if err != nil {
+		return nil, newError(ErrInvalidConfig, "credentials %s: %v", scheme, err)
+	}
+	return source, nil
+}
+
+// redactReference hides the password of static references in errors
+func redactReference(reference string) string {
+	if strings.HasPrefix(reference, "static:") {
+		return "static:" + redactedValue
+	}
+	return reference
+}
+
+// EnvPassword reads the password from the named environment variable at
+// every bind.
+type EnvPassword string
+
+// Password returns the variable's value, failing if it is unset or empty
+func (e EnvPassword) Password(context.Context) (string, error) {
+	password := os.Getenv(string(e))
+	if password == "" {
+		return "", fmt.Errorf("environment variable %s is empty", string(e))
+	}
+	return password, nil
+}
+
+// DefaultPasswordCommandTimeout bounds a PasswordCommand without a Timeout
+const DefaultPasswordCommandTimeout = 10 * time.Second
+
+// PasswordCommand runs a command at every bind and uses its output, with
+// surrounding whitespace removed, as the password, for secret managers with
+// a CLI. The command is run directly, not through a shell.
+type PasswordCommand struct {
+	Command []string
+	Timeout time.Duration // DefaultPasswordCommandTimeout when zero
+}
+
+// Password runs the command, failing if it exits non-zero, times out or
+// prints nothing
+func (c PasswordCommand) Password(ctx context.Context) (string, error) {
+	if len(c.Command) == 0 {
+		return "", fmt.Errorf("password command is empty")
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPasswordCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("password command %s failed: %w: %s", c.Command[0], err, msg)
+		}
+		return "", fmt.Errorf("password command %s failed: %w", c.Command[0], err)
+	}
+	password := strings.TrimSpace(stdout.String())
+	if password == "" {
+		return "", fmt.Errorf("password command %s printed nothing", c.Command[0])
+	}
+	return password, nil
+}
+
+// loadCredentials returns the source for a credentials setting, applying
+// policy to file references, or nil after logging why it is unusable, so
+// loaders fall through to the next source as they do for a missing file
+func loadCredentials(reference string, policy SecretFilePolicy) CredentialSource {
+	source, err := ParseCredentials(reference)
+	if err != nil {
+		slog.Error("ignoring credentials setting", "error", err.Error())
+		return nil
+	}
+	if file, ok := source.(PasswordFile); ok {
+		file.Policy = policy
+		return file
+	}
+	return source
+}
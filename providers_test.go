@@ -0,0 +1,111 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestParseCredentials(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	t.Setenv("TEST_BIND_PASSWORD", "from-env")
+
+	tests := map[string]string{
+		"static:literal":           "literal",
+		"env:TEST_BIND_PASSWORD":   "from-env",
+		"file:" + file:             "from-file",
+		"exec:echo from-command  ": "from-command",
+	}
+	for reference, want := range tests {
+		if runtime.GOOS == "windows" && strings.HasPrefix(reference, "exec:") {
+			continue
+		}
+		source, err := ldap_redhat.ParseCredentials(reference)
+		if err != nil {
+			t.Errorf("ParseCredentials(%q) failed: %v", reference, err)
+			continue
+		}
+		if got, err := source.Password(ctx); err != nil || got != want {
+			t.Errorf("%s: Password() = %q, %v, want %q", reference, got, err, want)
+		}
+	}
+
+	for _, reference := range []string{"", "env", "env:", "static", "nosuch:thing"} {
+		if _, err := ldap_redhat.ParseCredentials(reference); !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+			t.Errorf("ParseCredentials(%q) returned %v, want ErrInvalidConfig", reference, err)
+		}
+	}
+}
+
+func TestCredentialSourceErrors(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TEST_EMPTY_PASSWORD", "")
+	if _, err := ldap_redhat.EnvPassword("TEST_EMPTY_PASSWORD").Password(ctx); err == nil {
+		t.Error("Expected an error for an empty variable")
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if _, err := (ldap_redhat.PasswordCommand{Command: []string{"false"}}).Password(ctx); err == nil {
+		t.Error("Expected an error for a failing command")
+	}
+	if _, err := (ldap_redhat.PasswordCommand{Command: []string{"true"}}).Password(ctx); err == nil {
+		t.Error("Expected an error for a command printing nothing")
+	}
+}
+
+func TestRegisterCredentialProvider(t *testing.T) {
+	ldap_redhat.RegisterCredentialProvider("test-keyring", ldap_redhat.CredentialProviderFunc(func(ref string) (ldap_redhat.CredentialSource, error) {
+		if ref != "ldap" {
+			return nil, errors.New("no such key")
+		}
+		return ldap_redhat.StaticPassword("from-keyring"), nil
+	}))
+
+	clearLDAPEnv(t)
+	t.Setenv("LDAP_CREDENTIALS", "test-keyring:ldap")
+	config := ldap_redhat.LoadConfigFromAll()
+	if !config.HasCredentials() {
+		t.Fatal("LDAP_CREDENTIALS was not loaded")
+	}
+	if password, _ := config.Credentials.Password(context.Background()); password != "from-keyring" {
+		t.Errorf("Password() = %q, want from-keyring", password)
+	}
+	if _, err := ldap_redhat.ParseCredentials("test-keyring:other"); !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+		t.Errorf("Provider error returned %v, want ErrInvalidConfig", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Registering a scheme twice did not panic")
+		}
+	}()
+	ldap_redhat.RegisterCredentialProvider("test-keyring", ldap_redhat.CredentialProviderFunc(nil))
+}
+
+func TestLoadConfigCredentialsFromYAML(t *testing.T) {
+	clearLDAPEnv(t)
+	t.Setenv("LDAP_ENV", "local")
+	t.Setenv("TEST_BIND_PASSWORD", "from-env")
+	data := "environments:\n  local:\n    username: uid=svc,dc=redhat,dc=com\n    credentials: env:TEST_BIND_PASSWORD\n    password_file: /nonexistent\n"
+	if err := os.WriteFile("config.yaml", []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write config.yaml: %v", err)
+	}
+	config := ldap_redhat.LoadConfigFromAll()
+	if config.Credentials != ldap_redhat.EnvPassword("TEST_BIND_PASSWORD") {
+		t.Errorf("Credentials = %#v, want EnvPassword from the credentials key", config.Credentials)
+	}
+	if redacted := config.Redacted(); redacted.Credentials != config.Credentials {
+		t.Errorf("Redacted() replaced an environment variable name: %#v", redacted.Credentials)
+	}
+}
@@ -0,0 +1,70 @@
+package ldap_redhat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+)
+
+// pseudonymEncoding renders pseudonyms in lowercase without padding
+var pseudonymEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// PseudonymID returns a stable anonymized identifier for u, e.g.
+// "p-k5v3xq7d2mfa4hbn". It is an HMAC of the user's rhatUUID under key, so the
+// same user always maps to the same pseudonym for a given key and exports can
+// still be joined, while the real identity cannot be recovered without the
+// key. Users without a rhatUUID are keyed by UID instead, which yields a
+// different pseudonym than their UUID would.
+//
+// Keep key secret and stable for as long as pseudonyms must match; rotating
+// it unlinks all earlier datasets.
+func PseudonymID(u UserRecord, key []byte) string {
+	input := "rhatuuid:" + strings.ToLower(u.RhatUUID)
+	if u.RhatUUID == "" {
+		input = "uid:" + u.UID
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(input))
+	return "p-" + strings.ToLower(pseudonymEncoding.EncodeToString(mac.Sum(nil)[:10]))
+}
+
+// Pseudonymize returns u with its identity replaced by PseudonymID: UID holds
+// the pseudonym and the email, names, rhatUUID, employee number, Kerberos
+// principal, phone numbers and manager are cleared.
+// Organizational fields (title, cost center, location, job code, country,
+// department, geo, building, person type) and dates are kept; mask those separately with a MaskingPolicy
+// if needed. Use PseudonymizeAll to keep manager relationships.
+func Pseudonymize(u UserRecord, key []byte) UserRecord {
+	u.UID = PseudonymID(u, key)
+	u.Email = ""
+	u.DisplayName = ""
+	u.Surname = ""
+	u.RhatUUID = ""
+	u.EmployeeNumber = ""
+	u.KerberosPrincipal = ""
+	u.Mobile = ""
+	u.TelephoneNumber = ""
+	u.ManagerUID = ""
+	u.ManagerDN = ""
+	return u
+}
+
+// PseudonymizeAll pseudonymizes users, replacing each ManagerUID with the
+// manager's pseudonym when the manager is also in users so reporting lines
+// survive. Managers outside the set are cleared. UIDs are matched
+// case-insensitively, as the directory compares them.
+func PseudonymizeAll(users []UserRecord, key []byte) []UserRecord {
+	byUID := make(map[string]string, len(users))
+	for _, u := range users {
+		if u.UID != "" {
+			byUID[strings.ToLower(u.UID)] = PseudonymID(u, key)
+		}
+	}
+	out := make([]UserRecord, len(users))
+	for i, u := range users {
+		out[i] = Pseudonymize(u, key)
+		out[i].ManagerUID = byUID[strings.ToLower(u.ManagerUID)]
+	}
+	return out
+}
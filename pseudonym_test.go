@@ -0,0 +1,80 @@
+package ldap_redhat_test
+
+import (
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestPseudonymize(t *testing.T) {
+	key := []byte("analytics-2026")
+	user := ldap_redhat.UserRecord{
+		UID:         "jdoe",
+		Email:       "jdoe@redhat.com",
+		DisplayName: "John Doe",
+		Surname:     "Doe",
+		Title:       "Senior Software Engineer",
+		RhatUUID:    "0000abcd-0000-4000-8000-000000000001",
+		ManagerUID:  "boss",
+		ManagerDN:   "uid=boss,ou=users,dc=redhat,dc=com",
+		CostCenter:  "123",
+	}
+
+	p := ldap_redhat.Pseudonymize(user, key)
+	if !strings.HasPrefix(p.UID, "p-") || strings.Contains(p.UID, "jdoe") {
+		t.Errorf("Expected a pseudonym, got %q", p.UID)
+	}
+	if p.Email != "" || p.DisplayName != "" || p.Surname != "" || p.RhatUUID != "" || p.ManagerUID != "" || p.ManagerDN != "" {
+		t.Errorf("Expected identifying fields to be cleared, got %+v", p)
+	}
+	if p.Title != user.Title || p.CostCenter != user.CostCenter {
+		t.Errorf("Expected organizational fields to be kept, got %+v", p)
+	}
+
+	// Stable per UUID and key, even if the UID is renamed
+	renamed := user
+	renamed.UID = "john.doe"
+	if got := ldap_redhat.PseudonymID(renamed, key); got != p.UID {
+		t.Errorf("Expected the same pseudonym after a UID change, got %q and %q", got, p.UID)
+	}
+	if got := ldap_redhat.PseudonymID(user, []byte("other")); got == p.UID {
+		t.Error("Expected a different key to give a different pseudonym")
+	}
+}
+
+func TestPseudonymizeAll(t *testing.T) {
+	key := []byte("analytics-2026")
+	users := []ldap_redhat.UserRecord{
+		{UID: "boss", RhatUUID: "uuid-boss"},
+		{UID: "jdoe", RhatUUID: "uuid-jdoe", ManagerUID: "boss"},
+		{UID: "asmith", ManagerUID: "outside"},
+	}
+
+	out := ldap_redhat.PseudonymizeAll(users, key)
+	if out[1].ManagerUID != out[0].UID {
+		t.Errorf("Expected jdoe's manager to be boss's pseudonym %q, got %q", out[0].UID, out[1].ManagerUID)
+	}
+	if out[2].ManagerUID != "" {
+		t.Errorf("Expected manager outside the set to be cleared, got %q", out[2].ManagerUID)
+	}
+	if out[2].UID == "" || out[2].UID == out[1].UID {
+		t.Errorf("Expected distinct pseudonym for a user without UUID, got %q", out[2].UID)
+	}
+}
+
+func TestPseudonymizeAllMixedCase(t *testing.T) {
+	key := []byte("analytics-2026")
+	users := []ldap_redhat.UserRecord{
+		{UID: "BSmith", RhatUUID: "uuid-bsmith"},
+		{UID: "jdoe", RhatUUID: "uuid-jdoe", ManagerUID: "bsmith"},
+		{UID: "alee", ManagerUID: "BSMITH"},
+	}
+
+	out := ldap_redhat.PseudonymizeAll(users, key)
+	for _, i := range []int{1, 2} {
+		if out[i].ManagerUID != out[0].UID {
+			t.Errorf("Expected %s's manager %q to be BSmith's pseudonym %q, got %q", users[i].UID, users[i].ManagerUID, out[0].UID, out[i].ManagerUID)
+		}
+	}
+}
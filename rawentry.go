@@ -0,0 +1,89 @@
+package ldap_redhat
+
+import (
+	"context"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// GetRawEntry returns every attribute of the user id identifies, keyed by
+// attribute name as the server returned it, for attributes UserRecord does
+// not map. Values are the raw bytes of each value, so binary attributes
+// survive intact. Only the attributes both the request scope and the
+// attribute profile allow are requested and returned. It is not served from
+// the offline snapshot.
+func (s *Searcher) GetRawEntry(ctx context.Context, id Identifier) (map[string][]string, error) {
+	out, err := s.getRawEntry(ctx, id)
+	s.auditLookup(ctx, AuditGetRawEntry, id, err)
+	return out, err
+}
+
+// getRawEntry is GetRawEntry without auditing
+func (s *Searcher) getRawEntry(ctx context.Context, id Identifier) (map[string][]string, error) {
+	scope, _ := RequestScopeFromContext(ctx)
+	profile := s.attributeProfile(ctx).scope()
+	entry, err := s.lookupEntry(ctx, id, rawEntryAttributes(scope, profile))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]string, len(entry.Attributes))
+	for _, attr := range entry.Attributes {
+		if !scope.allows(attr.Name) || !profile.allows(attr.Name) {
+			continue
+		}
+		values := make([]string, len(attr.ByteValues))
+		for i, v := range attr.ByteValues {
+			values[i] = string(v)
+		}
+		out[attr.Name] = values
+	}
+	return out, nil
+}
+
+// rawEntryAttributes returns the attributes GetRawEntry requests: all user
+// attributes ("*") narrowed to those both scope and profile allow. uid is
+// always allowed, so the list is never empty, which LDAP would read as "*".
+func rawEntryAttributes(scope, profile RequestScope) []string {
+	switch {
+	case scope.Attributes == nil && profile.Attributes == nil:
+		return []string{"*"}
+	case scope.Attributes == nil:
+		return withAttributes(profile.Attributes, AttrUID)
+	}
+	var attrs []string
+	for _, a := range scope.Attributes {
+		if profile.allows(a) {
+			attrs = append(attrs, a)
+		}
+	}
+	return withAttributes(attrs, AttrUID)
+}
+
+// lookupEntry returns the single entry id identifies, with attrs
+func (s *Searcher) lookupEntry(ctx context.Context, id Identifier, attrs []string) (*ldap.Entry, error) {
+	if s.disconnected() {
+		return nil, errNotConnected()
+	}
+	filter, err := s.identifierFilter(id)
+	if err != nil {
+		return nil, err
+	}
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		0, 0, false, filter, attrs, nil,
+	))
+	if err != nil {
+		return nil, wrapLDAPError(err, "LDAP search failed")
+	}
+	if len(result.Entries) == 0 {
+		return nil, newError(ErrUserNotFound, "user not found in LDAP directory: %s", id.Value)
+	}
+	if len(result.Entries) > 1 {
+		return nil, newError(ErrMultipleMatches, "%d LDAP entries match %s", len(result.Entries), id.Value)
+	}
+	return result.Entries[0], nil
+}
@@ -0,0 +1,96 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestGetRawEntry(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 3)
+	ctx := context.Background()
+	binary := "\x00\xff\xfe binary"
+	srv.SetAttribute(testserver.UserDN(1), "rhatNickName", "jd", "johnny")
+	srv.SetAttribute(testserver.UserDN(1), "userCertificate", binary)
+
+	entry, err := searcher.GetRawEntry(ctx, uidIdentifier(1))
+	if err != nil {
+		t.Fatalf("GetRawEntry failed: %v", err)
+	}
+	if got := entry["uid"]; len(got) != 1 || got[0] != testserver.UserUID(1) {
+		t.Errorf("uid = %v, want [%s]", got, testserver.UserUID(1))
+	}
+	if got := entry["rhatNickName"]; len(got) != 2 || got[0] != "jd" || got[1] != "johnny" {
+		t.Errorf("rhatNickName = %v, want both values", got)
+	}
+	if got := entry["userCertificate"]; len(got) != 1 || got[0] != binary {
+		t.Errorf("userCertificate = %q, want the binary value intact", got)
+	}
+
+	scoped := ldap_redhat.WithRequestScope(ctx, ldap_redhat.RequestScope{Attributes: []string{"cn"}})
+	entry, err = searcher.GetRawEntry(scoped, uidIdentifier(1))
+	if err != nil {
+		t.Fatalf("GetRawEntry failed: %v", err)
+	}
+	for attr := range entry {
+		if attr != "uid" && attr != "cn" {
+			t.Errorf("Got %s outside the request scope", attr)
+		}
+	}
+
+	if _, err := searcher.GetRawEntry(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nobody"}); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestGetRawEntryAttributeProfile(t *testing.T) {
+	srv := startEmbeddedServer(t, 3)
+	srv.SetAttribute(testserver.UserDN(1), "rhatNickName", "jd")
+	var (
+		mu        sync.Mutex
+		requested [][]string
+	)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:      []string{srv.URL()},
+		Username:         embeddedBindDN,
+		Password:         embeddedPassword,
+		BaseDN:           testserver.UsersBaseDN,
+		AttributeProfile: ldap_redhat.ProfileMinimal,
+		Interceptors:     []ldap_redhat.Interceptor{requestedAttributes(&mu, &requested)},
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+
+	entry, err := searcher.GetRawEntry(ctx, uidIdentifier(1))
+	if err != nil {
+		t.Fatalf("GetRawEntry failed: %v", err)
+	}
+	if len(entry) != 3 || entry["mail"] == nil || entry["cn"] == nil {
+		t.Errorf("Expected only uid, mail and cn, got %v", entry)
+	}
+	if len(requested) != 1 || strings.Join(requested[0], ",") != "uid,mail,cn" {
+		t.Errorf("Expected the profile's attributes to be requested, got %v", requested)
+	}
+
+	// The request scope narrows the profile further
+	requested = nil
+	scoped := ldap_redhat.WithRequestScope(ctx, ldap_redhat.RequestScope{Attributes: []string{"cn", "rhatNickName"}})
+	entry, err = searcher.GetRawEntry(scoped, uidIdentifier(1))
+	if err != nil {
+		t.Fatalf("GetRawEntry failed: %v", err)
+	}
+	if len(entry) != 2 || entry["cn"] == nil || entry["uid"] == nil {
+		t.Errorf("Expected uid and cn, got %v", entry)
+	}
+	if len(requested) != 1 || strings.Join(requested[0], ",") != "cn,uid" {
+		t.Errorf("Expected cn and uid to be requested, got %v", requested)
+	}
+}
@@ -0,0 +1,51 @@
+package ldap_redhat
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// userRecordJSON has UserRecord's fields and tags but none of its methods, so
+// it can be marshaled without recursing into MarshalJSON.
+type userRecordJSON UserRecord
+
+// MarshalJSON encodes the record with stable lowercase snake_case keys. The
+// identity fields (uid, email, display_name, surname, title, manager_uid) are
+// always present; Red Hat and other optional fields are omitted when empty.
+func (u UserRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(userRecordJSON(u))
+}
+
+// ToMap returns the record keyed by its JSON field names, omitting the same
+// empty fields as MarshalJSON. Values keep their Go types, e.g. HireDate is a
+// time.Time.
+func (u UserRecord) ToMap() map[string]any {
+	v := reflect.ValueOf(u)
+	t := v.Type()
+	out := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		field := v.Field(i)
+		if (strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero")) && field.IsZero() {
+			continue
+		}
+		out[name] = field.Interface()
+	}
+	return out
+}
+
+// userRecordFieldNames returns the JSON names of UserRecord's fields
+func userRecordFieldNames() map[string]bool {
+	t := reflect.TypeOf(UserRecord{})
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+// Package rediscache implements ldap_redhat.Cache on Redis, so replicas of a
+// service can share one user cache. It is built on go-redis and is a module
+// of its own, so that the library does not depend on it.
+package rediscache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/buildfeature"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ ldap_redhat.Cache = (*Cache)(nil)
+
+func init() { buildfeature.Register("rediscache") }
+
+// Options configures a Cache
+type Options struct {
+	Addr        string        // host:port of the Redis server
+	Username    string        // Optional: ACL user (Redis 6+)
+	Password    string        // Optional: AUTH password
+	DB          int           // Optional: database selected with SELECT
+	PoolSize    int           // connections kept open, 4 by default
+	DialTimeout time.Duration // 5s by default
+	// Timeout bounds each command, and the handshake of a new connection,
+	// when the context has no earlier deadline. 5s by default.
+	Timeout   time.Duration
+	TLSConfig *tls.Config // Optional: connect with TLS
+}
+
+// Cache is a Redis-backed ldap_redhat.Cache
+type Cache struct {
+	client redis.UniversalClient
+}
+
+// New returns a Cache for opts. Connections are dialed on demand.
+func New(opts Options) *Cache {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 4
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	return NewFromClient(redis.NewClient(&redis.Options{
+		Addr:                  opts.Addr,
+		Username:              opts.Username,
+		Password:              opts.Password,
+		DB:                    opts.DB,
+		PoolSize:              opts.PoolSize,
+		MaxIdleConns:          opts.PoolSize,
+		DialTimeout:           opts.DialTimeout,
+		ReadTimeout:           opts.Timeout,
+		WriteTimeout:          opts.Timeout,
+		ContextTimeoutEnabled: true,
+		TLSConfig:             opts.TLSConfig,
+	}))
+}
+
+// NewFromClient returns a Cache storing entries with client, such as a
+// Sentinel or Cluster client. Close closes client.
+func NewFromClient(client redis.UniversalClient) *Cache {
+	return &Cache{client: client}
+}
+
+// Get returns the value stored under key and whether it was found.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for ttl, with millisecond precision.
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// go-redis stores a zero TTL without expiry
+	return c.client.Set(ctx, key, value, max(ttl, time.Millisecond)).Err()
+}
+
+// Delete removes key.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, key).Err()
+}
+
+// Close closes the client and its connections.
+func (c *Cache) Close() error {
+	return c.client.Close()
+}
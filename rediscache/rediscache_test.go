@@ -0,0 +1,310 @@
+package rediscache_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift-eng/go-ldap-redhat/rediscache"
+)
+
+// fakeRedis serves GET, SET, DEL, AUTH and SELECT from memory, and answers
+// anything else, such as HELLO, with an error as Redis 5 does
+type fakeRedis struct {
+	mu       sync.Mutex
+	data     map[string]string
+	password string
+	db       string // last database selected
+	ln       net.Listener
+}
+
+func startFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	return serveFakeRedis(t, ln, password)
+}
+
+// startFakeRedisTLS is startFakeRedis serving TLS with a self-signed
+// certificate, returned as the pool to trust
+func startFakeRedisTLS(t *testing.T, password string) (*fakeRedis, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake redis"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	return serveFakeRedis(t, ln, password), pool
+}
+
+// serveFakeRedis serves connections accepted on ln until the test ends
+func serveFakeRedis(t *testing.T, ln net.Listener, password string) *fakeRedis {
+	f := &fakeRedis{data: map[string]string{}, password: password, ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			fmt.Fprint(c, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		f.mu.Lock()
+		switch cmd {
+		case "AUTH":
+			if args[len(args)-1] == f.password {
+				authed = true
+				fmt.Fprint(c, "+OK\r\n")
+			} else {
+				fmt.Fprint(c, "-WRONGPASS invalid password\r\n")
+			}
+		case "GET":
+			if v, ok := f.data[args[1]]; ok {
+				fmt.Fprintf(c, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(c, "$-1\r\n")
+			}
+		case "SELECT":
+			f.db = args[1]
+			fmt.Fprint(c, "+OK\r\n")
+		case "SET":
+			f.data[args[1]] = args[2]
+			fmt.Fprint(c, "+OK\r\n")
+		case "DEL":
+			_, ok := f.data[args[1]]
+			delete(f.data, args[1])
+			if ok {
+				fmt.Fprint(c, ":1\r\n")
+			} else {
+				fmt.Fprint(c, ":0\r\n")
+			}
+		default:
+			fmt.Fprintf(c, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestCache(t *testing.T) {
+	f := startFakeRedis(t, "secret")
+	cache := rediscache.New(rediscache.Options{Addr: f.ln.Addr().String(), Password: "secret"})
+	defer cache.Close()
+	ctx := context.Background()
+
+	if _, ok, err := cache.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("Expected miss without error, got found=%v err=%v", ok, err)
+	}
+	value := []byte("{\"uid\":\"jdoe\"}\r\n")
+	if err := cache.Set(ctx, "user", value, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	got, ok, err := cache.Get(ctx, "user")
+	if err != nil || !ok || string(got) != string(value) {
+		t.Errorf("Expected %q, got %q (found %v, err %v)", value, got, ok, err)
+	}
+	if err := cache.Delete(ctx, "user"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := cache.Get(ctx, "user"); ok {
+		t.Error("Expected deleted key to be missing")
+	}
+}
+
+func TestCacheTLS(t *testing.T) {
+	f, pool := startFakeRedisTLS(t, "secret")
+	cache := rediscache.New(rediscache.Options{
+		Addr:      f.ln.Addr().String(),
+		Password:  "secret",
+		DB:        3,
+		TLSConfig: &tls.Config{RootCAs: pool},
+	})
+	defer cache.Close()
+	ctx := context.Background()
+
+	if err := cache.Set(ctx, "user", []byte("jdoe"), time.Minute); err != nil {
+		t.Fatalf("Set over TLS failed: %v", err)
+	}
+	if got, ok, err := cache.Get(ctx, "user"); err != nil || !ok || string(got) != "jdoe" {
+		t.Errorf("Got %q (found %v, err %v) over TLS, want jdoe", got, ok, err)
+	}
+	f.mu.Lock()
+	db := f.db
+	f.mu.Unlock()
+	if db != "3" {
+		t.Errorf("Selected database %q, want 3", db)
+	}
+
+	untrusted := rediscache.New(rediscache.Options{Addr: f.ln.Addr().String(), Password: "secret", TLSConfig: &tls.Config{}})
+	defer untrusted.Close()
+	if _, _, err := untrusted.Get(ctx, "user"); err == nil {
+		t.Error("Expected an error for an untrusted server certificate")
+	}
+}
+
+func TestCacheAuthFailure(t *testing.T) {
+	f := startFakeRedis(t, "secret")
+	cache := rediscache.New(rediscache.Options{Addr: f.ln.Addr().String(), Password: "wrong"})
+	defer cache.Close()
+
+	if _, _, err := cache.Get(context.Background(), "user"); err == nil {
+		t.Error("Expected authentication error")
+	}
+}
+
+func TestCacheTimeout(t *testing.T) {
+	// A server that accepts connections but never replies
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			go io.Copy(io.Discard, c)
+		}
+	}()
+
+	for _, password := range []string{"", "secret"} {
+		cache := rediscache.New(rediscache.Options{Addr: ln.Addr().String(), Password: password, Timeout: 50 * time.Millisecond})
+		start := time.Now()
+		if _, _, err := cache.Get(context.Background(), "user"); err == nil {
+			t.Errorf("password %q: expected a timeout error", password)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("password %q: expected the timeout to apply without a context deadline, took %s", password, elapsed)
+		}
+		cache.Close()
+	}
+}
+
+// TestRedisServer runs against a real server when REDIS_ADDR is set, as
+// test/matrix/run.sh does for each Redis version, authenticating with
+// REDIS_USERNAME and REDIS_PASSWORD
+func TestRedisServer(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set")
+	}
+	opts := rediscache.Options{Addr: addr, Username: os.Getenv("REDIS_USERNAME"), Password: os.Getenv("REDIS_PASSWORD"), DB: 1}
+	cache := rediscache.New(opts)
+	defer cache.Close()
+	ctx := context.Background()
+	key := fmt.Sprintf("ldap-redhat-test:%d", time.Now().UnixNano())
+
+	value := []byte("{\"uid\":\"jdoe\"}\r\n\x00")
+	if err := cache.Set(ctx, key, value, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if got, ok, err := cache.Get(ctx, key); err != nil || !ok || string(got) != string(value) {
+		t.Errorf("Got %q (found %v, err %v), want %q", got, ok, err, value)
+	}
+	if err := cache.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, err := cache.Get(ctx, key); ok || err != nil {
+		t.Errorf("Expected a deleted key to miss, got found=%v err=%v", ok, err)
+	}
+
+	if err := cache.Set(ctx, key, value, 50*time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if _, ok, err := cache.Get(ctx, key); ok || err != nil {
+		t.Errorf("Expected the key to expire after its TTL, got found=%v err=%v", ok, err)
+	}
+
+	if opts.Password != "" {
+		opts.Password += "-wrong"
+		wrong := rediscache.New(opts)
+		defer wrong.Close()
+		if _, _, err := wrong.Get(ctx, key); err == nil {
+			t.Error("Expected a wrong password to fail")
+		}
+	}
+}
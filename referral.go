@@ -0,0 +1,121 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// referralURLs returns the URLs carried by a referral result, or nil if err
+// is not one
+func referralURLs(err error) []string {
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultReferral || ldapErr.Packet == nil {
+		return nil
+	}
+	if len(ldapErr.Packet.Children) < 2 {
+		return nil
+	}
+	var urls []string
+	for _, child := range ldapErr.Packet.Children[1].Children {
+		// The referral field is [3] SEQUENCE OF URI
+		if child.ClassType != ber.ClassContext || child.TagType != ber.TypeConstructed || child.Tag != 3 {
+			continue
+		}
+		for _, u := range child.Children {
+			if s, ok := u.Value.(string); ok && s != "" {
+				urls = append(urls, s)
+			} else if s := u.Data.String(); s != "" {
+				urls = append(urls, s)
+			}
+		}
+	}
+	return urls
+}
+
+// parseReferralURL splits an LDAP URL into the server to dial and the base
+// DN to search there, which is empty when the URL has none
+func parseReferralURL(ref string) (server, baseDN string, err error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid referral URL %s: %w", ref, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "ldap", "ldaps":
+	default:
+		return "", "", fmt.Errorf("unsupported referral URL %s", ref)
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("referral URL %s has no host", ref)
+	}
+	return u.Scheme + "://" + u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// chaseReferrals follows the referrals of a search answered with result and
+// err, up to hops deep. Entries from subtrees held by other servers are
+// appended to the result; continuation references beyond the limit are left
+// in result.Referrals.
+func (s *Searcher) chaseReferrals(ctx context.Context, req *ldap.SearchRequest, result *ldap.SearchResult, err error, hops int) (*ldap.SearchResult, error) {
+	if urls := referralURLs(err); urls != nil {
+		if hops <= 0 {
+			return result, ldap.NewError(ldap.LDAPResultReferralLimitExceeded, fmt.Errorf("referral to %s exceeds the hop limit", strings.Join(urls, " ")))
+		}
+		return s.followReferral(ctx, req, urls, hops)
+	}
+	if err != nil || result == nil || len(result.Referrals) == 0 || hops <= 0 {
+		return result, err
+	}
+	refs := result.Referrals
+	result.Referrals = nil
+	for _, ref := range refs {
+		sub, err := s.followReferral(ctx, req, []string{ref}, hops)
+		if err != nil {
+			return result, err
+		}
+		result.Entries = append(result.Entries, sub.Entries...)
+		result.Referrals = append(result.Referrals, sub.Referrals...)
+	}
+	return result, nil
+}
+
+// followReferral runs req against the first of urls that answers, then
+// chases that server's own referrals with one hop fewer
+func (s *Searcher) followReferral(ctx context.Context, req *ldap.SearchRequest, urls []string, hops int) (*ldap.SearchResult, error) {
+	var lastErr error
+	for _, ref := range urls {
+		server, baseDN, err := parseReferralURL(ref)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		config := s.config()
+		config.LdapServers = []string{server}
+		config.TLSServerName = ""
+		conn, name, err := dial(ctx, config)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		sub := *req
+		sub.Controls = withSessionTracking(req.Controls, name)
+		if baseDN != "" {
+			sub.BaseDN = baseDN
+		}
+		start := time.Now()
+		result, err := conn.Search(&sub)
+		conn.Close()
+		entries := 0
+		if result != nil {
+			entries = len(result.Entries)
+		}
+		s.logSearch("ldap referral search", &sub, start, entries, err)
+		return s.chaseReferrals(ctx, &sub, result, err, hops-1)
+	}
+	return nil, wrapLDAPError(lastErr, "failed to follow referral to %s", strings.Join(urls, " "))
+}
@@ -0,0 +1,175 @@
+package ldap_redhat
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// DefaultReportPageSize is the page size of ListReportsFlat when
+// ReportListOptions.PageSize is zero.
+const DefaultReportPageSize = 500
+
+// reportManagersPerSearch bounds the managers OR-ed into one level search, to
+// keep filters well below server limits
+const reportManagersPerSearch = 100
+
+// ReportListOptions configures ListReportsFlat.
+type ReportListOptions struct {
+	ExcludeCountries []string // ISO country codes whose users, and their reports, are left out
+	MaxDepth         int      // levels below the manager to include (0 = unlimited, 1 = direct reports only)
+	PageSize         int      // reports per page (0 = DefaultReportPageSize)
+	PageToken        string   // NextPageToken of the previous page; empty for the first page
+}
+
+// Report is one of the transitive reports listed by ListReportsFlat.
+type Report struct {
+	User  UserRecord `json:"user" yaml:"user"`
+	Depth int        `json:"depth" yaml:"depth"` // levels below the manager: 1 for direct reports
+}
+
+// ReportPage is a page of a manager's reporting subtree.
+type ReportPage struct {
+	Reports       []Report `json:"reports" yaml:"reports"`
+	Total         int      `json:"total" yaml:"total"`                                         // reports in the whole subtree
+	NextPageToken string   `json:"next_page_token,omitempty" yaml:"next_page_token,omitempty"` // empty on the last page
+}
+
+// ListReportsFlat returns a page of everyone reporting to managerUID,
+// directly or transitively, as a flat list ordered by UID. Pass the page's
+// NextPageToken in opts.PageToken for the next one.
+//
+// Each page walks the subtree again, with one search per level, so it
+// reflects the directory when it is fetched. The walk fetches only UIDs;
+// the records of the page's reports are then fetched in batches of 100, so a
+// page costs a search per level of the subtree plus one per 100 reports on
+// it, whatever its position. Paging by UID means that users who join or
+// leave the subtree between pages do not make others repeat or go missing;
+// a report leaving between the walk and the fetch of its page is left out
+// of it. Deleted users are left out. A manager without reports has an empty
+// first page.
+func (s *Searcher) ListReportsFlat(ctx context.Context, managerUID string, opts ReportListOptions) (ReportPage, error) {
+	page, err := s.listReportsFlat(ctx, managerUID, opts)
+	s.auditReports(ctx, AuditListReportsFlat, managerUID, len(page.Reports), err)
+	return page, err
+}
+
+// listReportsFlat is ListReportsFlat without auditing
+func (s *Searcher) listReportsFlat(ctx context.Context, managerUID string, opts ReportListOptions) (ReportPage, error) {
+	if s.disconnected() {
+		return ReportPage{}, errNotConnected()
+	}
+	after, err := decodePageToken(opts.PageToken, "report")
+	if err != nil {
+		return ReportPage{}, err
+	}
+	size := opts.PageSize
+	if size <= 0 {
+		size = DefaultReportPageSize
+	}
+
+	refs, err := s.reportSubtree(ctx, managerUID, opts)
+	if err != nil {
+		return ReportPage{}, err
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].uid < refs[j].uid })
+
+	page := ReportPage{Total: len(refs)}
+	start := sort.Search(len(refs), func(i int) bool { return refs[i].uid > after })
+	end := min(start+size, len(refs))
+	if page.Reports, err = s.reportRecords(ctx, refs[start:end]); err != nil {
+		return ReportPage{}, err
+	}
+	if end < len(refs) {
+		page.NextPageToken = encodePageToken(refs[end-1].uid)
+	}
+	return page, nil
+}
+
+// reportRef is a report found by reportSubtree, before its record is fetched
+type reportRef struct {
+	uid   string
+	depth int
+}
+
+// reportRecords fetches the records of refs, reportManagersPerSearch at a
+// time, leaving out those no longer found
+func (s *Searcher) reportRecords(ctx context.Context, refs []reportRef) ([]Report, error) {
+	reports := make([]Report, 0, len(refs))
+	for chunk := range slices.Chunk(refs, reportManagersPerSearch) {
+		ids := make([]Identifier, len(chunk))
+		for i, ref := range chunk {
+			ids[i] = Identifier{Type: IDTUID, Value: ref.uid}
+		}
+		users, err := s.getUsers(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, u := range users {
+			if u.UID != "" {
+				reports = append(reports, Report{User: u, Depth: chunk[i].depth})
+			}
+		}
+	}
+	return reports, nil
+}
+
+// encodePageToken returns the token of the page after the one ending with
+// lastUID. Pages ordered by UID resume after it.
+func encodePageToken(lastUID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastUID))
+}
+
+// decodePageToken returns the last UID of the page before token, or "" for
+// the first page. what names the listing in the error.
+func decodePageToken(token, what string) (string, error) {
+	after, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s page token %q", what, token)
+	}
+	return string(after), nil
+}
+
+// reportSubtree returns the UID of every report of managerUID within
+// opts.MaxDepth, searching a level at a time. Each user is listed once, at
+// the shallowest depth it is found, which also stops management cycles.
+func (s *Searcher) reportSubtree(ctx context.Context, managerUID string, opts ReportListOptions) ([]reportRef, error) {
+	var exclude string
+	for _, cc := range opts.ExcludeCountries {
+		exclude += fmt.Sprintf("(!(co=%s))", ldap.EscapeFilter(strings.TrimSpace(cc)))
+	}
+
+	seen := map[string]bool{managerUID: true}
+	var refs []reportRef
+	managers := []string{managerUID}
+	for depth := 1; len(managers) > 0 && (opts.MaxDepth <= 0 || depth <= opts.MaxDepth); depth++ {
+		var next []string
+		for chunk := range slices.Chunk(managers, reportManagersPerSearch) {
+			var filter strings.Builder
+			filter.WriteString("(&(|")
+			for _, uid := range chunk {
+				fmt.Fprintf(&filter, "(manager=%s)", managerDNForUID(uid))
+			}
+			filter.WriteString(")" + exclude + ")")
+			err := s.forEachUserWith(ctx, filter.String(), []string{AttrUID}, nil, func(u UserRecord) error {
+				if u.UID == "" || seen[u.UID] || u.Status == StatusDeleted {
+					return nil
+				}
+				seen[u.UID] = true
+				refs = append(refs, reportRef{uid: u.UID, depth: depth})
+				next = append(next, u.UID)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		managers = next
+	}
+	return refs, nil
+}
@@ -0,0 +1,89 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// listAllReports pages through the reports of managerUID
+func listAllReports(t *testing.T, searcher *ldap_redhat.Searcher, managerUID string, opts ldap_redhat.ReportListOptions) []ldap_redhat.Report {
+	t.Helper()
+	var all []ldap_redhat.Report
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatalf("ListReportsFlat did not finish paging")
+		}
+		page, err := searcher.ListReportsFlat(context.Background(), managerUID, opts)
+		if err != nil {
+			t.Fatalf("ListReportsFlat failed: %v", err)
+		}
+		if opts.PageSize > 0 && len(page.Reports) > opts.PageSize {
+			t.Errorf("Got a page of %d reports, want at most %d", len(page.Reports), opts.PageSize)
+		}
+		all = append(all, page.Reports...)
+		if page.NextPageToken == "" {
+			if page.Total != len(all) {
+				t.Errorf("Total = %d, but %d reports were listed", page.Total, len(all))
+			}
+			return all
+		}
+		opts.PageToken = page.NextPageToken
+	}
+}
+
+func TestListReportsFlat(t *testing.T) {
+	// With 100 users, user000001-user000008 report to user000000, users 9-72
+	// to them and users 73-99 to users 9-12
+	searcher, _ := newEmbeddedSearcher(t, 100)
+
+	all := listAllReports(t, searcher, testserver.UserUID(0), ldap_redhat.ReportListOptions{PageSize: 30})
+	if len(all) != 99 {
+		t.Fatalf("Got %d reports, want 99", len(all))
+	}
+	for i, r := range all {
+		if want := testserver.UserUID(i + 1); r.User.UID != want {
+			t.Fatalf("Report %d is %s, want %s in UID order", i, r.User.UID, want)
+		}
+		want := 3
+		switch {
+		case i+1 <= 8:
+			want = 1
+		case i+1 <= 72:
+			want = 2
+		}
+		if r.Depth != want {
+			t.Errorf("%s has depth %d, want %d", r.User.UID, r.Depth, want)
+		}
+	}
+
+	if got := listAllReports(t, searcher, testserver.UserUID(1), ldap_redhat.ReportListOptions{}); len(got) != 8+27 {
+		t.Errorf("Got %d reports of %s, want 35", len(got), testserver.UserUID(1))
+	}
+	if got := listAllReports(t, searcher, testserver.UserUID(0), ldap_redhat.ReportListOptions{MaxDepth: 2}); len(got) != 72 {
+		t.Errorf("Got %d reports two levels deep, want 72", len(got))
+	}
+	if got := listAllReports(t, searcher, testserver.UserUID(99), ldap_redhat.ReportListOptions{}); len(got) != 0 {
+		t.Errorf("Got %d reports of a leaf, want none", len(got))
+	}
+
+	// Excluding a country also drops the subtrees below its users
+	excluded := listAllReports(t, searcher, testserver.UserUID(0), ldap_redhat.ReportListOptions{ExcludeCountries: []string{"CZ"}})
+	if slices.ContainsFunc(excluded, func(r ldap_redhat.Report) bool {
+		return r.User.Country == "CZ" || r.User.ManagerUID == testserver.UserUID(1)
+	}) {
+		t.Errorf("Excluded country or its subtree listed: %d reports", len(excluded))
+	}
+}
+
+func TestListReportsFlatInvalidToken(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 10)
+	_, err := searcher.ListReportsFlat(context.Background(), testserver.UserUID(0), ldap_redhat.ReportListOptions{PageToken: "not a token!"})
+	if err == nil || !strings.Contains(err.Error(), "invalid report page token") {
+		t.Errorf("Expected an invalid token error, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package ldap_redhat
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// StartTLSOID is the StartTLS extended operation (RFC 4511 section 4.14)
+const StartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// rootDSEAttributes are the root DSE attributes RootDSE reads. They are
+// operational, so servers return them only when named.
+var rootDSEAttributes = []string{
+	"namingContexts", "subschemaSubentry", "supportedLDAPVersion",
+	"supportedControl", "supportedExtension", "supportedFeatures",
+	"supportedSASLMechanisms", "vendorName", "vendorVersion",
+}
+
+// RootDSE describes a server's capabilities as published in its root DSE
+// (RFC 4512 section 5.1), for tooling that adapts to the server, e.g. by
+// checking for the paged results control before paging.
+type RootDSE struct {
+	Server                  string   `json:"server"` // the server that answered
+	NamingContexts          []string `json:"naming_contexts,omitempty"`
+	SubschemaSubentry       string   `json:"subschema_subentry,omitempty"`
+	SupportedLDAPVersions   []string `json:"supported_ldap_versions,omitempty"`
+	SupportedControls       []string `json:"supported_controls,omitempty"`   // OIDs
+	SupportedExtensions     []string `json:"supported_extensions,omitempty"` // OIDs
+	SupportedFeatures       []string `json:"supported_features,omitempty"`   // OIDs
+	SupportedSASLMechanisms []string `json:"supported_sasl_mechanisms,omitempty"`
+	VendorName              string   `json:"vendor_name,omitempty"`
+	VendorVersion           string   `json:"vendor_version,omitempty"`
+}
+
+// SupportsControl reports whether the server lists the control oid
+func (r RootDSE) SupportsControl(oid string) bool {
+	return slices.Contains(r.SupportedControls, oid)
+}
+
+// SupportsExtension reports whether the server lists the extended operation
+// oid
+func (r RootDSE) SupportsExtension(oid string) bool {
+	return slices.Contains(r.SupportedExtensions, oid)
+}
+
+// SupportsSASLMechanism reports whether the server offers the SASL mechanism,
+// e.g. "EXTERNAL", matched case-insensitively
+func (r RootDSE) SupportsSASLMechanism(mechanism string) bool {
+	return slices.ContainsFunc(r.SupportedSASLMechanisms, func(m string) bool { return strings.EqualFold(m, mechanism) })
+}
+
+// SupportsPaging reports whether the server supports the simple paged
+// results control (RFC 2696)
+func (r RootDSE) SupportsPaging() bool {
+	return r.SupportsControl(ldap.ControlTypePaging)
+}
+
+// SupportsStartTLS reports whether the server supports the StartTLS
+// extended operation
+func (r RootDSE) SupportsStartTLS() bool {
+	return r.SupportsExtension(StartTLSOID)
+}
+
+// RootDSE reads the server's root DSE: its naming contexts, supported
+// controls, extended operations, features and SASL mechanisms. A
+// LazyConnect searcher connects first. It fails with ErrNotConnected
+// without a connection, e.g. when serving from the offline snapshot.
+func (s *Searcher) RootDSE(ctx context.Context) (RootDSE, error) {
+	if s.disconnected() {
+		return RootDSE{}, errNotConnected()
+	}
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		"", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", rootDSEAttributes, nil,
+	))
+	if err != nil {
+		return RootDSE{}, wrapLDAPError(err, "LDAP root DSE read failed")
+	}
+	dse := RootDSE{Server: s.config().LdapServers[0]}
+	if len(result.Entries) == 0 {
+		return dse, nil
+	}
+	e := result.Entries[0]
+	dse.NamingContexts = e.GetEqualFoldAttributeValues("namingContexts")
+	dse.SubschemaSubentry = e.GetEqualFoldAttributeValue("subschemaSubentry")
+	dse.SupportedLDAPVersions = e.GetEqualFoldAttributeValues("supportedLDAPVersion")
+	dse.SupportedControls = e.GetEqualFoldAttributeValues("supportedControl")
+	dse.SupportedExtensions = e.GetEqualFoldAttributeValues("supportedExtension")
+	dse.SupportedFeatures = e.GetEqualFoldAttributeValues("supportedFeatures")
+	dse.SupportedSASLMechanisms = e.GetEqualFoldAttributeValues("supportedSASLMechanisms")
+	dse.VendorName = e.GetEqualFoldAttributeValue("vendorName")
+	dse.VendorVersion = e.GetEqualFoldAttributeValue("vendorVersion")
+	return dse, nil
+}
@@ -0,0 +1,43 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+)
+
+func TestRootDSE(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 1)
+	dse, err := searcher.RootDSE(context.Background())
+	if err != nil {
+		t.Fatalf("RootDSE failed: %v", err)
+	}
+	if dse.Server != srv.URL() || len(dse.NamingContexts) != 1 || dse.NamingContexts[0] != "dc=redhat,dc=com" {
+		t.Errorf("RootDSE returned %+v, want the test server's naming context", dse)
+	}
+	if dse.SubschemaSubentry != ldapserver.SubschemaDN || len(dse.SupportedLDAPVersions) != 1 {
+		t.Errorf("RootDSE returned subschema %q and versions %v", dse.SubschemaSubentry, dse.SupportedLDAPVersions)
+	}
+	if !dse.SupportsPaging() || !dse.SupportsControl(ldap.ControlTypeSyncRequest) || !dse.SupportsExtension(ldap.ControlTypeWhoAmI) {
+		t.Errorf("RootDSE controls %v and extensions %v miss what the server supports", dse.SupportedControls, dse.SupportedExtensions)
+	}
+	if dse.SupportsStartTLS() || !dse.SupportsSASLMechanism("external") || dse.SupportsSASLMechanism("GSSAPI") {
+		t.Errorf("RootDSE extensions %v and SASL mechanisms %v", dse.SupportedExtensions, dse.SupportedSASLMechanisms)
+	}
+
+	srv.DisableControl(ldap.ControlTypeSyncRequest)
+	if dse, err := searcher.RootDSE(context.Background()); err != nil || dse.SupportsControl(ldap.ControlTypeSyncRequest) {
+		t.Errorf("RootDSE lists a disabled control: %v, %v", dse.SupportedControls, err)
+	}
+}
+
+func TestRootDSEDisconnected(t *testing.T) {
+	searcher := &ldap_redhat.Searcher{}
+	if _, err := searcher.RootDSE(context.Background()); !errors.Is(err, ldap_redhat.ErrNotConnected) {
+		t.Errorf("RootDSE without a connection returned %v, want ErrNotConnected", err)
+	}
+}
@@ -0,0 +1,46 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// CostCenterOptions configures GetUsersByCostCenter.
+type CostCenterOptions struct {
+	IncludeTerminated bool // also return users whose termination date has passed
+}
+
+// GetUsersByCostCenter returns the users in a cost center, sorted by UID,
+// for reconciling headcount against finance records. Users are active by
+// default, as IsActive judges them: a termination date in the future still
+// counts. Results are fetched with a paged search, so large cost centers are
+// not cut off by server size limits. When the search stops at a size limit
+// set with SearchOptions.SizeLimit or Config.SizeLimit, the users received
+// before it are returned, sorted, with a *PartialResultsError.
+func (s *Searcher) GetUsersByCostCenter(ctx context.Context, costCenter string, opts ...CostCenterOptions) ([]UserRecord, error) {
+	var opt CostCenterOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	costCenter = strings.TrimSpace(costCenter)
+	if costCenter == "" {
+		return nil, errors.New("cost center must not be empty")
+	}
+
+	var users []UserRecord
+	filter := fmt.Sprintf("(rhatCostCenter=%s)", ldap.EscapeFilter(costCenter))
+	err := s.forEachActiveUser(ctx, filter, opt.IncludeTerminated, func(u UserRecord) error {
+		users = append(users, u)
+		return nil
+	})
+	if err != nil && asPartial(err) == nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UID < users[j].UID })
+	return users, err
+}
@@ -0,0 +1,85 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestGetUsersByCostCenter(t *testing.T) {
+	// Generated users cycle through 50 cost centers: 105 holds users 5, 55
+	// and 105
+	searcher, srv := newEmbeddedSearcher(t, 120)
+	ctx := context.Background()
+	srv.SetAttribute(testserver.UserDN(55), "rhatTermDate", "20200131000000Z")
+	srv.SetAttribute(testserver.UserDN(105), "rhatTermDate", "29990131000000Z") // leaving, but still active
+
+	users, err := searcher.GetUsersByCostCenter(ctx, "105")
+	if err != nil {
+		t.Fatalf("GetUsersByCostCenter failed: %v", err)
+	}
+	want := []string{testserver.UserUID(5), testserver.UserUID(105)}
+	if got := uids(users); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want the active users %v", got, want)
+	}
+
+	users, err = searcher.GetUsersByCostCenter(ctx, " 105 ", ldap_redhat.CostCenterOptions{IncludeTerminated: true})
+	if err != nil || len(users) != 3 {
+		t.Errorf("Got %v (%v), want all three users with terminated ones included", uids(users), err)
+	}
+
+	if users, err := searcher.GetUsersByCostCenter(ctx, "*"); err != nil || len(users) != 0 {
+		t.Errorf("Expected a wildcard to match no cost center, got %v, %v", uids(users), err)
+	}
+	if _, err := searcher.GetUsersByCostCenter(ctx, ""); err == nil {
+		t.Error("Expected an error for an empty cost center")
+	}
+}
+
+func TestGetUsersByCostCenterPartial(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 120)
+	// The server stops after two of the three users in cost center 105
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultSizeLimitExceeded, Entries: 2})
+
+	users, err := searcher.GetUsersByCostCenter(context.Background(), "105")
+	var partial *ldap_redhat.PartialResultsError
+	if !errors.As(err, &partial) {
+		t.Fatalf("GetUsersByCostCenter returned %v, want a *PartialResultsError", err)
+	}
+	want := []string{testserver.UserUID(5), testserver.UserUID(55)}
+	if got := uids(users); !reflect.DeepEqual(got, want) || partial.Returned != 2 {
+		t.Errorf("Got %v and %+v, want the users before the limit %v", got, partial, want)
+	}
+}
+
+func TestGetUsersByCostCenterRestricted(t *testing.T) {
+	// Profiles and scopes that leave out rhatTermDate must still exclude
+	// terminated users
+	searcher, srv := newEmbeddedSearcher(t, 120)
+	srv.SetAttribute(testserver.UserDN(55), "rhatTermDate", "20200131000000Z")
+
+	for name, ctx := range map[string]context.Context{
+		"minimal profile": ldap_redhat.WithSearchOptions(context.Background(), ldap_redhat.SearchOptions{AttributeProfile: ldap_redhat.ProfileMinimal}),
+		"request scope":   ldap_redhat.WithRequestScope(context.Background(), ldap_redhat.RequestScope{Attributes: []string{"cn"}}),
+	} {
+		users, err := searcher.GetUsersByCostCenter(ctx, "105")
+		if err != nil {
+			t.Fatalf("%s: GetUsersByCostCenter failed: %v", name, err)
+		}
+		want := []string{testserver.UserUID(5), testserver.UserUID(105)}
+		if got := uids(users); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want the active users %v", name, got, want)
+		}
+		for _, u := range users {
+			if u.RhatTermDate != "" || u.CostCenter != "" {
+				t.Errorf("%s: expected restricted fields to be cleared, got %+v", name, u)
+			}
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// defaultSubschemaDN is read when the root DSE does not name a subschema
+// subentry, as OpenLDAP and 389 Directory Server name theirs
+const defaultSubschemaDN = "cn=Subschema"
+
+// AttributeTypeDefinition is an attribute type of a server's schema, parsed
+// from its RFC 4512 description.
+type AttributeTypeDefinition struct {
+	OID         string   `json:"oid"`
+	Names       []string `json:"names"`
+	Description string   `json:"description,omitempty"`
+	Sup         string   `json:"sup,omitempty"`    // the supertype, whose syntax and matching rules it inherits
+	Syntax      string   `json:"syntax,omitempty"` // syntax OID, without a length bound
+	Equality    string   `json:"equality,omitempty"`
+	SingleValue bool     `json:"single_value,omitempty"`
+	Obsolete    bool     `json:"obsolete,omitempty"`
+	// Operational is set for types whose USAGE is not userApplications,
+	// such as modifyTimestamp, which searches return only when named
+	Operational bool `json:"operational,omitempty"`
+}
+
+// ObjectClassDefinition is an object class of a server's schema, parsed
+// from its RFC 4512 description.
+type ObjectClassDefinition struct {
+	OID         string   `json:"oid"`
+	Names       []string `json:"names"`
+	Description string   `json:"description,omitempty"`
+	Sup         []string `json:"sup,omitempty"`
+	Kind        string   `json:"kind"` // STRUCTURAL, AUXILIARY or ABSTRACT
+	Must        []string `json:"must,omitempty"`
+	May         []string `json:"may,omitempty"`
+	Obsolete    bool     `json:"obsolete,omitempty"`
+}
+
+// Schema is the subschema a server publishes: the attribute types and object
+// classes it defines. Definitions the library cannot parse are skipped.
+type Schema struct {
+	DN             string                    `json:"dn"` // the subschema subentry read
+	AttributeTypes []AttributeTypeDefinition `json:"attribute_types"`
+	ObjectClasses  []ObjectClassDefinition   `json:"object_classes"`
+
+	attributeTypes map[string]int // index in AttributeTypes by lowercased name and OID
+	objectClasses  map[string]int // index in ObjectClasses by lowercased name and OID
+}
+
+// AttributeType returns the definition of the attribute type named name, or
+// with OID name, matched case-insensitively.
+func (sc *Schema) AttributeType(name string) (AttributeTypeDefinition, bool) {
+	i, ok := sc.attributeTypes[strings.ToLower(name)]
+	if !ok {
+		return AttributeTypeDefinition{}, false
+	}
+	return sc.AttributeTypes[i], true
+}
+
+// ObjectClass returns the definition of the object class named name, or
+// with OID name, matched case-insensitively.
+func (sc *Schema) ObjectClass(name string) (ObjectClassDefinition, bool) {
+	i, ok := sc.objectClasses[strings.ToLower(name)]
+	if !ok {
+		return ObjectClassDefinition{}, false
+	}
+	return sc.ObjectClasses[i], true
+}
+
+// ValidateAttributes returns a WarnUnknownAttribute warning for each of
+// names the schema does not define. The special selectors "*", "+" and
+// "1.1" are always valid. Attribute options such as ";binary" are ignored.
+func (sc *Schema) ValidateAttributes(names []string) []Warning {
+	var warnings []Warning
+	for _, name := range names {
+		if name == "*" || name == "+" || name == "1.1" {
+			continue
+		}
+		attr, _, _ := strings.Cut(name, ";")
+		if _, ok := sc.AttributeType(attr); ok {
+			continue
+		}
+		warnings = append(warnings, Warning{
+			Kind:       WarnUnknownAttribute,
+			DN:         sc.DN,
+			Attributes: []string{name},
+			Message:    fmt.Sprintf("attribute %s is not defined by the server schema; searches return no values for it", name),
+		})
+	}
+	return warnings
+}
+
+// FetchSchema reads the server's subschema subentry, located through the
+// root DSE's subschemaSubentry attribute, and parses its attribute types and
+// object classes. A LazyConnect searcher connects first. It fails with
+// ErrNotConnected without a connection, e.g. when serving from the offline
+// snapshot.
+func (s *Searcher) FetchSchema(ctx context.Context) (*Schema, error) {
+	root, err := s.RootDSE(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dn := root.SubschemaSubentry
+	if dn == "" {
+		dn = defaultSubschemaDN
+	}
+	result, err := s.search(ctx, ldap.NewSearchRequest(
+		dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=subschema)", []string{"attributeTypes", "objectClasses"}, nil,
+	))
+	if err != nil {
+		return nil, wrapLDAPError(err, "LDAP schema read of %s failed", dn)
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("LDAP schema read of %s returned %d entries", dn, len(result.Entries))
+	}
+	entry := result.Entries[0]
+	return newSchema(entry.DN, entry.GetEqualFoldAttributeValues("attributeTypes"), entry.GetEqualFoldAttributeValues("objectClasses")), nil
+}
+
+// ValidateAttributes fetches the server's schema and returns a
+// WarnUnknownAttribute warning for each of names it does not define, also
+// recording them in the context's warnings collector. With no names it
+// checks the attributes the searcher requests for users, including
+// PeopleManagerAttribute, to catch configuration that drifted from the
+// directory.
+func (s *Searcher) ValidateAttributes(ctx context.Context, names []string) ([]Warning, error) {
+	schema, err := s.FetchSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		names = s.attributes(ctx)
+	}
+	warnings := schema.ValidateAttributes(names)
+	for _, w := range warnings {
+		warn(ctx, w)
+	}
+	return warnings, nil
+}
+
+// newSchema parses the attribute type and object class descriptions of the
+// subschema subentry dn
+func newSchema(dn string, attributeTypes, objectClasses []string) *Schema {
+	sc := &Schema{DN: dn, attributeTypes: map[string]int{}, objectClasses: map[string]int{}}
+	for _, desc := range attributeTypes {
+		d, ok := parseSchemaDescription(desc)
+		if !ok {
+			continue
+		}
+		def := AttributeTypeDefinition{
+			OID:         d.oid,
+			Names:       d.list("NAME"),
+			Description: d.value("DESC"),
+			Sup:         d.value("SUP"),
+			Syntax:      d.value("SYNTAX"),
+			Equality:    d.value("EQUALITY"),
+			SingleValue: d.has("SINGLE-VALUE"),
+			Obsolete:    d.has("OBSOLETE"),
+			Operational: d.has("USAGE") && d.value("USAGE") != "userApplications",
+		}
+		// A syntax may carry a length bound, e.g. 1.3.6.1.4.1.1466.115.121.1.15{256}
+		def.Syntax, _, _ = strings.Cut(def.Syntax, "{")
+		sc.attributeTypes[strings.ToLower(def.OID)] = len(sc.AttributeTypes)
+		for _, name := range def.Names {
+			sc.attributeTypes[strings.ToLower(name)] = len(sc.AttributeTypes)
+		}
+		sc.AttributeTypes = append(sc.AttributeTypes, def)
+	}
+	for _, desc := range objectClasses {
+		d, ok := parseSchemaDescription(desc)
+		if !ok {
+			continue
+		}
+		def := ObjectClassDefinition{
+			OID:         d.oid,
+			Names:       d.list("NAME"),
+			Description: d.value("DESC"),
+			Sup:         d.list("SUP"),
+			Kind:        "STRUCTURAL", // the RFC 4512 default
+			Must:        d.list("MUST"),
+			May:         d.list("MAY"),
+			Obsolete:    d.has("OBSOLETE"),
+		}
+		for _, kind := range []string{"ABSTRACT", "AUXILIARY"} {
+			if d.has(kind) {
+				def.Kind = kind
+			}
+		}
+		sc.objectClasses[strings.ToLower(def.OID)] = len(sc.ObjectClasses)
+		for _, name := range def.Names {
+			sc.objectClasses[strings.ToLower(name)] = len(sc.ObjectClasses)
+		}
+		sc.ObjectClasses = append(sc.ObjectClasses, def)
+	}
+	return sc
+}
+
+// schemaDescription is an RFC 4512 definition split into its OID and the
+// values of each keyword
+type schemaDescription struct {
+	oid    string
+	fields map[string][]string
+}
+
+func (d schemaDescription) has(keyword string) bool {
+	_, ok := d.fields[keyword]
+	return ok
+}
+
+// value returns the first value of keyword, or ""
+func (d schemaDescription) value(keyword string) string {
+	if v := d.fields[keyword]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (d schemaDescription) list(keyword string) []string {
+	return d.fields[keyword]
+}
+
+// schemaFlags are the keywords that take no value
+var schemaFlags = map[string]bool{
+	"OBSOLETE": true, "SINGLE-VALUE": true, "COLLECTIVE": true, "NO-USER-MODIFICATION": true,
+	"ABSTRACT": true, "STRUCTURAL": true, "AUXILIARY": true,
+}
+
+// parseSchemaDescription parses an attribute type or object class
+// description such as "( 2.5.4.3 NAME ( 'cn' 'commonName' ) SUP name )".
+// Values are a quoted string, a bare OID or name, or a parenthesized list
+// of either, separated by spaces or "$".
+func parseSchemaDescription(desc string) (schemaDescription, bool) {
+	tokens := schemaTokens(desc)
+	if len(tokens) < 3 || tokens[0] != "(" || tokens[len(tokens)-1] != ")" {
+		return schemaDescription{}, false
+	}
+	d := schemaDescription{oid: tokens[1], fields: map[string][]string{}}
+	tokens = tokens[2 : len(tokens)-1]
+	for len(tokens) > 0 {
+		keyword := tokens[0]
+		tokens = tokens[1:]
+		if schemaFlags[keyword] {
+			d.fields[keyword] = nil
+			continue
+		}
+		if len(tokens) == 0 {
+			return schemaDescription{}, false
+		}
+		if tokens[0] != "(" {
+			d.fields[keyword] = []string{unquoteSchema(tokens[0])}
+			tokens = tokens[1:]
+			continue
+		}
+		end := 1
+		for end < len(tokens) && tokens[end] != ")" {
+			end++
+		}
+		if end == len(tokens) {
+			return schemaDescription{}, false
+		}
+		values := []string{}
+		for _, t := range tokens[1:end] {
+			if t != "$" {
+				values = append(values, unquoteSchema(t))
+			}
+		}
+		d.fields[keyword] = values
+		tokens = tokens[end+1:]
+	}
+	return d, true
+}
+
+// schemaTokens splits a schema description into parentheses, "$", quoted
+// strings (kept with their quotes) and bare words
+func schemaTokens(desc string) []string {
+	var tokens []string
+	for i := 0; i < len(desc); {
+		switch c := desc[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == '$':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '\'':
+			end := strings.IndexByte(desc[i+1:], '\'')
+			if end < 0 {
+				return append(tokens, desc[i:]) // unterminated
+			}
+			tokens = append(tokens, desc[i:i+end+2])
+			i += end + 2
+		default:
+			start := i
+			for i < len(desc) && !strings.ContainsRune(" \t\n()$'", rune(desc[i])) {
+				i++
+			}
+			tokens = append(tokens, desc[start:i])
+		}
+	}
+	return tokens
+}
+
+// unquoteSchema strips the quotes of a quoted schema string
+func unquoteSchema(s string) string {
+	return strings.Trim(s, "'")
+}
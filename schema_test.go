@@ -0,0 +1,77 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestFetchSchema(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 1)
+	schema, err := searcher.FetchSchema(context.Background())
+	if err != nil {
+		t.Fatalf("FetchSchema failed: %v", err)
+	}
+	if schema.DN != "cn=Subschema" || len(schema.AttributeTypes) == 0 {
+		t.Errorf("FetchSchema returned %s with %d attribute types", schema.DN, len(schema.AttributeTypes))
+	}
+
+	cn, ok := schema.AttributeType("commonName")
+	if !ok || cn.OID != "2.5.4.3" || len(cn.Names) != 2 || cn.Sup != "name" {
+		t.Errorf("AttributeType(commonName) = %+v, %v, want cn by its alias", cn, ok)
+	}
+	if hire, ok := schema.AttributeType("RHATHIREDATE"); !ok || hire.Syntax != "1.3.6.1.4.1.1466.115.121.1.24" || !hire.SingleValue {
+		t.Errorf("AttributeType(rhatHireDate) = %+v, %v", hire, ok)
+	}
+	if ts, ok := schema.AttributeType("2.5.18.2"); !ok || !ts.Operational {
+		t.Errorf("AttributeType by OID returned %+v, %v, want operational modifyTimestamp", ts, ok)
+	}
+
+	person, ok := schema.ObjectClass("person")
+	if !ok || person.Kind != "STRUCTURAL" || strings.Join(person.Must, ",") != "sn,cn" || strings.Join(person.Sup, ",") != "top" {
+		t.Errorf("ObjectClass(person) = %+v, %v", person, ok)
+	}
+	if rhat, ok := schema.ObjectClass("rhatPerson"); !ok || rhat.Kind != "AUXILIARY" || len(rhat.May) == 0 {
+		t.Errorf("ObjectClass(rhatPerson) = %+v, %v", rhat, ok)
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 1)
+	ctx, collected := ldap_redhat.WithWarnings(context.Background())
+
+	warnings, err := searcher.ValidateAttributes(ctx, nil)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("ValidateAttributes of the user attributes returned %v, %v, want no warnings", warnings, err)
+	}
+	warnings, err = searcher.ValidateAttributes(ctx, []string{"*", "1.1", "CN", "jpegPhoto;binary", "rhatFavouriteColour"})
+	if err != nil || len(warnings) != 1 || warnings[0].Kind != ldap_redhat.WarnUnknownAttribute || warnings[0].Attributes[0] != "rhatFavouriteColour" {
+		t.Errorf("ValidateAttributes returned %v, %v, want a warning for rhatFavouriteColour", warnings, err)
+	}
+	if !collected.Has(ldap_redhat.WarnUnknownAttribute) {
+		t.Errorf("ValidateAttributes did not record its warning in the context")
+	}
+
+	// A directory without rhatGeo drifted from the one the library expects
+	var defs []string
+	for _, def := range srv.AttributeTypes() {
+		if !strings.Contains(def, "'rhatGeo'") {
+			defs = append(defs, def)
+		}
+	}
+	srv.SetAttributeTypes(defs)
+	warnings, err = searcher.ValidateAttributes(context.Background(), nil)
+	if err != nil || len(warnings) != 1 || warnings[0].Attributes[0] != ldap_redhat.AttrRhatGeo {
+		t.Errorf("ValidateAttributes returned %v, %v, want a warning for rhatGeo", warnings, err)
+	}
+}
+
+func TestFetchSchemaDisconnected(t *testing.T) {
+	searcher := &ldap_redhat.Searcher{}
+	if _, err := searcher.FetchSchema(context.Background()); !errors.Is(err, ldap_redhat.ErrNotConnected) {
+		t.Errorf("FetchSchema without a connection returned %v, want ErrNotConnected", err)
+	}
+}
@@ -0,0 +1,180 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// RequestScope restricts what a single request may read. Bridges serving
+// differently-privileged clients attach one to the request context with
+// WithRequestScope, usually resolved from the client's claims by a
+// ScopePolicy.
+type RequestScope struct {
+	// Attributes lists the LDAP attributes the request may return, e.g.
+	// {"cn", "title"}. uid is always returned. Nil means no restriction; an
+	// empty non-nil slice allows uid only.
+	Attributes []string `yaml:"attributes"`
+
+	// BaseDN confines searches to this subtree, which must be the configured
+	// base DN or lie beneath it. Empty means the configured base DN.
+	BaseDN string `yaml:"base_dn"`
+}
+
+type requestScopeKey struct{}
+
+// WithRequestScope returns a context whose searches are restricted to scope.
+func WithRequestScope(ctx context.Context, scope RequestScope) context.Context {
+	return context.WithValue(ctx, requestScopeKey{}, scope)
+}
+
+// RequestScopeFromContext returns the scope attached with WithRequestScope.
+func RequestScopeFromContext(ctx context.Context) (RequestScope, bool) {
+	scope, ok := ctx.Value(requestScopeKey{}).(RequestScope)
+	return scope, ok
+}
+
+// allows reports whether attr may be returned
+func (r RequestScope) allows(attr string) bool {
+	if r.Attributes == nil || strings.EqualFold(attr, AttrUID) {
+		return true
+	}
+	for _, a := range r.Attributes {
+		if strings.EqualFold(a, attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clearTermDate clears the termination date and the status derived from it.
+// StatusDeleted follows the entry's DN and is kept.
+func clearTermDate(u *UserRecord) {
+	u.RhatTermDate, u.TermDate = "", time.Time{}
+	if u.Status != StatusDeleted {
+		u.Status = ""
+	}
+}
+
+// redact clears the fields of u whose source attribute the scope does not
+// allow.
+func (r RequestScope) redact(u *UserRecord) {
+	if r.Attributes == nil {
+		return
+	}
+	for _, a := range attributeSchemas {
+		if a.value != nil && !r.allows(a.Name) {
+			a.clearFrom(u)
+		}
+	}
+}
+
+// scopedAttributes narrows attrs to those the context's scope allows. mail is
+// kept when present because batch lookups need it to match results; redact
+// removes it afterwards.
+func scopedAttributes(ctx context.Context, attrs []string) []string {
+	scope, ok := RequestScopeFromContext(ctx)
+	if !ok || scope.Attributes == nil {
+		return attrs
+	}
+	out := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		if scope.allows(a) || strings.EqualFold(a, AttrMail) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// redactForContext applies the context's scope, if any, to u
+func redactForContext(ctx context.Context, u *UserRecord) {
+	if scope, ok := RequestScopeFromContext(ctx); ok {
+		scope.redact(u)
+	}
+}
+
+// searchBase returns the base DN for a search made with ctx: the scope's base
+// DN if it lies within the configured base, otherwise an error.
+func (s *Searcher) searchBase(ctx context.Context) (string, error) {
+	base := s.baseDN()
+	scope, ok := RequestScopeFromContext(ctx)
+	if !ok || scope.BaseDN == "" {
+		return base, nil
+	}
+	within, err := dnWithin(scope.BaseDN, base)
+	if err != nil {
+		return "", err
+	}
+	if !within {
+		return "", fmt.Errorf("request scope base DN %s is outside the search base %s", scope.BaseDN, base)
+	}
+	return scope.BaseDN, nil
+}
+
+// dnWithin reports whether dn equals base or is one of its descendants
+func dnWithin(dn, base string) (bool, error) {
+	child, err := ldap.ParseDN(dn)
+	if err != nil {
+		return false, fmt.Errorf("invalid base DN %q: %w", dn, err)
+	}
+	parent, err := ldap.ParseDN(base)
+	if err != nil {
+		return false, fmt.Errorf("invalid base DN %q: %w", base, err)
+	}
+	return parent.EqualFold(child) || parent.AncestorOfFold(child), nil
+}
+
+// ScopePolicy maps client scope claims (e.g. OAuth scopes) to the
+// RequestScope each one grants.
+type ScopePolicy map[string]RequestScope
+
+// Resolve combines the scopes granted by claims into the union of their
+// privileges: attribute sets are merged, and a claim without an attribute list
+// or base DN lifts that restriction. Two different base DNs cannot be merged
+// and are an error. Claims not in the policy are ignored, and an error is
+// returned if none match.
+func (p ScopePolicy) Resolve(claims []string) (RequestScope, error) {
+	var out RequestScope
+	matched := false
+	unrestricted, anyBase := false, false
+	attrs := map[string]string{}
+	for _, claim := range claims {
+		scope, ok := p[claim]
+		if !ok {
+			continue
+		}
+		matched = true
+		if scope.Attributes == nil {
+			unrestricted = true
+		}
+		for _, a := range scope.Attributes {
+			attrs[strings.ToLower(a)] = a
+		}
+		if scope.BaseDN == "" {
+			anyBase = true
+		} else {
+			if out.BaseDN != "" && !EqualDN(out.BaseDN, scope.BaseDN) {
+				return RequestScope{}, fmt.Errorf("scope claims grant conflicting base DNs %s and %s", out.BaseDN, scope.BaseDN)
+			}
+			out.BaseDN = scope.BaseDN
+		}
+	}
+	if !matched {
+		return RequestScope{}, fmt.Errorf("no recognized scope claims in %v", claims)
+	}
+	if anyBase {
+		out.BaseDN = ""
+	}
+	if !unrestricted {
+		out.Attributes = make([]string, 0, len(attrs))
+		for _, a := range attrs {
+			out.Attributes = append(out.Attributes, a)
+		}
+		sort.Strings(out.Attributes)
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestRequestScopeAttributes(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	ctx := ldap_redhat.WithRequestScope(context.Background(), ldap_redhat.RequestScope{
+		Attributes: []string{"cn", "title"},
+	})
+
+	user, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.UID != testserver.UserUID(1) || user.DisplayName == "" || user.Title == "" {
+		t.Errorf("Expected uid, cn and title, got %+v", user)
+	}
+	if user.Email != "" || user.CostCenter != "" || user.ManagerUID != "" || !user.HireDate.IsZero() {
+		t.Errorf("Expected fields outside the scope to be empty, got %+v", user)
+	}
+
+	// Batch lookups by email still match even though mail is redacted
+	users, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{
+		{Type: ldap_redhat.IDTEmail, Value: testserver.UserUID(2) + "@redhat.com"},
+	})
+	if err != nil {
+		t.Fatalf("GetUsers failed: %v", err)
+	}
+	if users[0].UID != testserver.UserUID(2) || users[0].Email != "" {
+		t.Errorf("Expected %s without email, got %+v", testserver.UserUID(2), users[0])
+	}
+
+	err = searcher.ForEachUser(ctx, "(uid=*)", func(u ldap_redhat.UserRecord) error {
+		if u.Email != "" || u.RhatUUID != "" {
+			t.Errorf("ForEachUser returned fields outside the scope: %+v", u)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ForEachUser failed: %v", err)
+	}
+}
+
+func TestRequestScopeBaseDN(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	srv.AddEntry("uid=contractor,ou=contractors,"+testserver.UsersBaseDN, map[string][]string{
+		"uid":  {"contractor"},
+		"mail": {"contractor@redhat.com"},
+	})
+
+	ctx := ldap_redhat.WithRequestScope(context.Background(), ldap_redhat.RequestScope{
+		BaseDN: "ou=contractors," + testserver.UsersBaseDN,
+	})
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "contractor"}); err != nil {
+		t.Errorf("Expected user inside the scoped base to be found: %v", err)
+	}
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}); err == nil {
+		t.Error("Expected user outside the scoped base not to be found")
+	}
+
+	ctx = ldap_redhat.WithRequestScope(context.Background(), ldap_redhat.RequestScope{
+		BaseDN: "ou=groups,dc=redhat,dc=com",
+	})
+	_, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "contractor"})
+	if err == nil || !strings.Contains(err.Error(), "outside the search base") {
+		t.Errorf("Expected base DN outside the search base to be rejected, got %v", err)
+	}
+}
+
+func TestScopePolicyResolve(t *testing.T) {
+	policy := ldap_redhat.ScopePolicy{
+		"directory.read": {},
+		"profile.basic":  {Attributes: []string{"cn", "mail"}},
+		"profile.job":    {Attributes: []string{"title", "rhatJobCode"}},
+		"contractors":    {Attributes: []string{"cn"}, BaseDN: "ou=contractors,ou=users,dc=redhat,dc=com"},
+		"partners":       {Attributes: []string{"cn"}, BaseDN: "ou=partners,ou=users,dc=redhat,dc=com"},
+	}
+
+	tests := []struct {
+		claims   []string
+		expected ldap_redhat.RequestScope
+		wantErr  bool
+	}{
+		{[]string{"profile.basic", "profile.job", "unknown"}, ldap_redhat.RequestScope{Attributes: []string{"cn", "mail", "rhatJobCode", "title"}}, false},
+		{[]string{"profile.basic", "directory.read"}, ldap_redhat.RequestScope{}, false},
+		{[]string{"contractors"}, ldap_redhat.RequestScope{Attributes: []string{"cn"}, BaseDN: "ou=contractors,ou=users,dc=redhat,dc=com"}, false},
+		{[]string{"contractors", "profile.basic"}, ldap_redhat.RequestScope{Attributes: []string{"cn", "mail"}}, false},
+		{[]string{"contractors", "partners"}, ldap_redhat.RequestScope{}, true},
+		{[]string{"unknown"}, ldap_redhat.RequestScope{}, true},
+	}
+	for _, test := range tests {
+		got, err := policy.Resolve(test.claims)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Resolve(%v) error = %v, wantErr %v", test.claims, err, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Resolve(%v) = %+v, expected %+v", test.claims, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// AliasDeref selects when searches dereference alias entries (RFC 4511
+// derefAliases).
+type AliasDeref string
+
+const (
+	// DerefNever returns alias entries as they are. It is the default.
+	DerefNever AliasDeref = "never"
+	// DerefSearching dereferences aliases found below the search base.
+	DerefSearching AliasDeref = "searching"
+	// DerefFinding dereferences the search base only.
+	DerefFinding AliasDeref = "finding"
+	// DerefAlways dereferences both the search base and aliases below it.
+	DerefAlways AliasDeref = "always"
+)
+
+// derefValues maps policies to the go-ldap derefAliases values
+var derefValues = map[AliasDeref]int{
+	DerefNever:     ldap.NeverDerefAliases,
+	DerefSearching: ldap.DerefInSearching,
+	DerefFinding:   ldap.DerefFindingBaseObj,
+	DerefAlways:    ldap.DerefAlways,
+}
+
+// ldapValue returns the derefAliases value of d; the empty policy is
+// DerefNever.
+func (d AliasDeref) ldapValue() (int, error) {
+	if d == "" {
+		return ldap.NeverDerefAliases, nil
+	}
+	if v, ok := derefValues[AliasDeref(strings.ToLower(string(d)))]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("unknown deref_aliases %q: expected %q, %q, %q or %q", d, DerefNever, DerefSearching, DerefFinding, DerefAlways)
+}
+
+// SearchScope selects how much of the tree below the base DN searches
+// cover (RFC 4511 scope).
+type SearchScope string
+
+const (
+	// ScopeSubtree searches the base entry and everything below it. It is
+	// the default.
+	ScopeSubtree SearchScope = "sub"
+	// ScopeOneLevel searches the immediate children of the base entry only,
+	// e.g. the users directly in an OU and not those of nested OUs.
+	ScopeOneLevel SearchScope = "one"
+	// ScopeBase searches the base entry itself.
+	ScopeBase SearchScope = "base"
+)
+
+// scopeValues maps scopes to the go-ldap scope values
+var scopeValues = map[SearchScope]int{
+	ScopeSubtree:  ldap.ScopeWholeSubtree,
+	ScopeOneLevel: ldap.ScopeSingleLevel,
+	ScopeBase:     ldap.ScopeBaseObject,
+}
+
+// ldapValue returns the scope value of sc; the empty scope is ScopeSubtree.
+func (sc SearchScope) ldapValue() (int, error) {
+	if sc == "" {
+		return ldap.ScopeWholeSubtree, nil
+	}
+	if v, ok := scopeValues[SearchScope(strings.ToLower(string(sc)))]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("unknown search_scope %q: expected %q, %q or %q", sc, ScopeBase, ScopeOneLevel, ScopeSubtree)
+}
+
+// SearchOptions tunes how a single request's searches cover the tree,
+// follow aliases and referrals, and how much they may return. Attach them
+// with WithSearchOptions; zero fields fall back to the Config fields of the
+// same name.
+type SearchOptions struct {
+	Scope        SearchScope
+	DerefAliases AliasDeref
+
+	// SizeLimit is the most results SearchUsers, ForEachUser,
+	// FindDirectReports and GetUserGroups return, after which they return
+	// those with a *PartialResultsError. Lookups of single users are not
+	// limited. A negative value removes Config.SizeLimit for the request.
+	SizeLimit int
+
+	// TimeLimit asks the server to give up on each search after this long,
+	// rounded up to whole seconds. A negative value sends no limit. It does
+	// not shorten the client-side Config.SearchTimeout.
+	TimeLimit time.Duration
+
+	// MaxReferralHops is how many referrals a search follows: the referral
+	// answering the search itself, and continuation references to subtrees
+	// held by other servers. Referred servers are dialed with the searcher's
+	// TLS settings and credentials, so only raise it for directories whose
+	// referrals stay within trusted servers. A negative value disables
+	// following for the request even when Config.MaxReferralHops is set.
+	MaxReferralHops int
+
+	// IncludeDeleted makes GetUser and GetUsers also search
+	// Config.DeletedUsersBaseDN for identifiers no current entry matches.
+	IncludeDeleted bool
+
+	// AttributeProfile limits the attributes the request's user lookups
+	// fetch, overriding Config.AttributeProfile.
+	AttributeProfile AttributeProfile
+
+	// SyncCookie resumes Searcher.Sync from the SyncEvent.Cookie of an
+	// earlier synchronization.
+	SyncCookie []byte
+}
+
+type searchOptionsKey struct{}
+
+// WithSearchOptions returns a context whose searches use opts.
+func WithSearchOptions(ctx context.Context, opts SearchOptions) context.Context {
+	return context.WithValue(ctx, searchOptionsKey{}, opts)
+}
+
+// SearchOptionsFromContext returns the options attached with WithSearchOptions.
+func SearchOptionsFromContext(ctx context.Context) (SearchOptions, bool) {
+	opts, ok := ctx.Value(searchOptionsKey{}).(SearchOptions)
+	return opts, ok
+}
+
+// searchOptions returns the options for searches in ctx: those attached to
+// the context, completed from the config
+func (s *Searcher) searchOptions(ctx context.Context) (SearchOptions, error) {
+	config := s.config()
+	opts := SearchOptions{
+		Scope:            config.SearchScope,
+		DerefAliases:     config.DerefAliases,
+		SizeLimit:        config.SizeLimit,
+		TimeLimit:        config.timeLimit(),
+		MaxReferralHops:  config.MaxReferralHops,
+		AttributeProfile: config.AttributeProfile,
+	}
+	if o, ok := SearchOptionsFromContext(ctx); ok {
+		if o.Scope != "" {
+			opts.Scope = o.Scope
+		}
+		if o.SizeLimit != 0 {
+			opts.SizeLimit = o.SizeLimit
+		}
+		if o.TimeLimit != 0 {
+			opts.TimeLimit = o.TimeLimit
+		}
+		if o.DerefAliases != "" {
+			opts.DerefAliases = o.DerefAliases
+		}
+		if o.MaxReferralHops != 0 {
+			opts.MaxReferralHops = o.MaxReferralHops
+		}
+		if o.AttributeProfile != "" {
+			opts.AttributeProfile = o.AttributeProfile
+		}
+	}
+	if _, err := opts.Scope.ldapValue(); err != nil {
+		return SearchOptions{}, err
+	}
+	if _, err := opts.DerefAliases.ldapValue(); err != nil {
+		return SearchOptions{}, err
+	}
+	profile, err := opts.AttributeProfile.normalize()
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	opts.AttributeProfile = profile
+	return opts, nil
+}
+
+// searchScope returns the scope value for searches in ctx. Invalid scopes
+// are reported by searchOptions before the request is sent.
+func (s *Searcher) searchScope(ctx context.Context) int {
+	opts, err := s.searchOptions(ctx)
+	if err != nil {
+		return ldap.ScopeWholeSubtree
+	}
+	v, _ := opts.Scope.ldapValue()
+	return v
+}
+
+// derefAliases returns the derefAliases value for searches in ctx. Invalid
+// policies are reported by searchOptions before the request is sent.
+func (s *Searcher) derefAliases(ctx context.Context) int {
+	opts, err := s.searchOptions(ctx)
+	if err != nil {
+		return ldap.NeverDerefAliases
+	}
+	v, _ := opts.DerefAliases.ldapValue()
+	return v
+}
@@ -0,0 +1,196 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+const contractorsDN = "ou=contractors,ou=users,dc=redhat,dc=com"
+
+func TestAliasDeref(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	// A user outside the search base, found through an alias inside it
+	srv.AddEntry("uid=ext1,ou=external,dc=redhat,dc=com", map[string][]string{
+		"objectClass": {"top", "person"}, "uid": {"ext1"}, "cn": {"External One"},
+	})
+	srv.AddEntry("uid=ext1,ou=users,dc=redhat,dc=com", map[string][]string{
+		"objectClass": {"top", "alias", "extensibleObject"}, "uid": {"ext1"},
+		"aliasedObjectName": {"uid=ext1,ou=external,dc=redhat,dc=com"},
+	})
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "ext1"}
+
+	user, err := searcher.GetUser(ctx, id)
+	if err != nil || user.DisplayName != "" {
+		t.Errorf("Expected the alias entry itself without dereferencing, got %+v, %v", user, err)
+	}
+	searching := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{DerefAliases: ldap_redhat.DerefSearching})
+	if user, err := searcher.GetUser(searching, id); err != nil || user.DisplayName != "External One" {
+		t.Errorf("Expected the aliased entry with DerefSearching, got %+v, %v", user, err)
+	}
+
+	searcher.Config.DerefAliases = ldap_redhat.DerefAlways
+	if user, err := searcher.GetUser(ctx, id); err != nil || user.DisplayName != "External One" {
+		t.Errorf("Expected the aliased entry with Config.DerefAliases, got %+v, %v", user, err)
+	}
+	never := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{DerefAliases: ldap_redhat.DerefNever})
+	if user, err := searcher.GetUser(never, id); err != nil || user.DisplayName != "" {
+		t.Errorf("Expected per-request options to override the config, got %+v, %v", user, err)
+	}
+
+	bad := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{DerefAliases: "sometimes"})
+	if _, err := searcher.GetUser(bad, id); err == nil {
+		t.Error("Expected an error for an unknown deref policy")
+	}
+	if _, err := ldap_redhat.NewSearcher(ldap_redhat.Config{DerefAliases: "sometimes"}); err == nil {
+		t.Error("Expected NewSearcher to reject an unknown deref policy")
+	}
+}
+
+func TestSearchScope(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	// A user in a nested OU, below the users directly in the base
+	srv.AddEntry(contractorsDN, map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"contractors"}})
+	srv.AddEntry("uid=contractor1,"+contractorsDN, map[string][]string{
+		"objectClass": {"top", "person"}, "uid": {"contractor1"}, "cn": {"Contractor One"},
+	})
+	ctx := context.Background()
+	nested := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "contractor1"}
+	direct := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+
+	if _, err := searcher.GetUser(ctx, nested); err != nil {
+		t.Errorf("Expected the default subtree scope to find nested users, got %v", err)
+	}
+	oneLevel := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{Scope: ldap_redhat.ScopeOneLevel})
+	if _, err := searcher.GetUser(oneLevel, nested); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected ScopeOneLevel to skip nested OUs, got %v", err)
+	}
+	if _, err := searcher.GetUser(oneLevel, direct); err != nil {
+		t.Errorf("Expected ScopeOneLevel to find users directly in the base, got %v", err)
+	}
+
+	searcher.Config.SearchScope = ldap_redhat.ScopeBase
+	searcher.Config.BaseDN = testserver.UserDN(1)
+	if user, err := searcher.GetUser(ctx, direct); err != nil || user.UID != testserver.UserUID(1) {
+		t.Errorf("Expected Config.SearchScope base to read the base entry, got %q, %v", user.UID, err)
+	}
+	if _, err := searcher.GetUser(ctx, nested); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected ScopeBase to find nothing but the base entry, got %v", err)
+	}
+
+	bad := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{Scope: "children"})
+	if _, err := searcher.GetUser(bad, direct); err == nil {
+		t.Error("Expected an error for an unknown scope")
+	}
+	if _, err := ldap_redhat.NewSearcher(ldap_redhat.Config{SearchScope: "children"}); err == nil {
+		t.Error("Expected NewSearcher to reject an unknown scope")
+	}
+}
+
+func TestAliasDerefBase(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	srv.AddEntry("ou=staff,dc=redhat,dc=com", map[string][]string{
+		"objectClass": {"top", "alias", "extensibleObject"}, "ou": {"staff"},
+		"aliasedObjectName": {testserver.UsersBaseDN},
+	})
+	searcher.Config.BaseDN = "ou=staff,dc=redhat,dc=com"
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(2)}
+
+	if _, err := searcher.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected nothing below an alias base without dereferencing, got %v", err)
+	}
+	finding := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{DerefAliases: ldap_redhat.DerefFinding})
+	if user, err := searcher.GetUser(finding, id); err != nil || user.UID != testserver.UserUID(2) {
+		t.Errorf("Expected DerefFinding to search the aliased base, got %q, %v", user.UID, err)
+	}
+}
+
+// startContractorServer starts a second server holding the contractors
+// subtree, which the embedded server of searcher refers to
+func startContractorServer(t *testing.T, srv *ldapserver.Server) *ldapserver.Server {
+	t.Helper()
+	other := ldapserver.New()
+	other.AddBind(embeddedBindDN, embeddedPassword)
+	other.AddEntry(contractorsDN, map[string][]string{"objectClass": {"top", "organizationalUnit"}, "ou": {"contractors"}})
+	contractor := testserver.NamedUser(100, "contractor1")
+	contractor.DN = "uid=contractor1," + contractorsDN
+	other.AddEntries([]*ldapserver.Entry{contractor})
+	if err := other.Start(); err != nil {
+		t.Fatalf("Failed to start second LDAP server: %v", err)
+	}
+	t.Cleanup(func() { other.Close() })
+	srv.AddEntry(contractorsDN, map[string][]string{
+		"objectClass": {"top", "referral", "extensibleObject"}, "ou": {"contractors"},
+		"ref": {other.URL() + "/" + contractorsDN},
+	})
+	return other
+}
+
+func TestReferralFollowing(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	startContractorServer(t, srv)
+	ctx := context.Background()
+	follow := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{MaxReferralHops: 1})
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "contractor1"}
+
+	if _, err := searcher.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrUserNotFound) {
+		t.Errorf("Expected continuation references to be ignored by default, got %v", err)
+	}
+	if user, err := searcher.GetUser(follow, id); err != nil || user.UID != "contractor1" {
+		t.Errorf("Expected the referred server to be searched, got %q, %v", user.UID, err)
+	}
+
+	all, err := searcher.SearchUsers(follow, "")
+	if err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	if len(all) != 6 || all[5].UID != "contractor1" {
+		t.Errorf("Expected 5 local users and the referred contractor, got %d", len(all))
+	}
+
+	searcher.Config.BaseDN = contractorsDN
+	var ldapErr *ldap.Error
+	if _, err := searcher.GetUser(ctx, id); !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultReferral {
+		t.Errorf("Expected a referral error for a base held elsewhere, got %v", err)
+	}
+	searcher.Config.MaxReferralHops = 1
+	if user, err := searcher.GetUser(ctx, id); err != nil || user.UID != "contractor1" {
+		t.Errorf("Expected Config.MaxReferralHops to follow the referral, got %q, %v", user.UID, err)
+	}
+	disabled := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{MaxReferralHops: -1})
+	if _, err := searcher.GetUser(disabled, id); !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultReferral {
+		t.Errorf("Expected a negative hop limit to disable following, got %v", err)
+	}
+}
+
+func TestReferralHopLimit(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	// Two servers referring the contractors subtree to each other
+	other := ldapserver.New()
+	other.AddBind(embeddedBindDN, embeddedPassword)
+	if err := other.Start(); err != nil {
+		t.Fatalf("Failed to start second LDAP server: %v", err)
+	}
+	t.Cleanup(func() { other.Close() })
+	other.AddEntry(contractorsDN, map[string][]string{
+		"objectClass": {"top", "referral"}, "ref": {srv.URL() + "/" + contractorsDN},
+	})
+	srv.AddEntry(contractorsDN, map[string][]string{
+		"objectClass": {"top", "referral"}, "ref": {other.URL() + "/" + contractorsDN},
+	})
+	searcher.Config.BaseDN = contractorsDN
+	ctx := ldap_redhat.WithSearchOptions(context.Background(), ldap_redhat.SearchOptions{MaxReferralHops: 3})
+
+	var ldapErr *ldap.Error
+	_, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "contractor1"})
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultReferralLimitExceeded {
+		t.Errorf("Expected the referral loop to hit the hop limit, got %v", err)
+	}
+}
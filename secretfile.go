@@ -0,0 +1,103 @@
+package ldap_redhat
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ErrInsecureSecretFile is returned for secret files other users can access
+// or that are owned by someone else.
+var ErrInsecureSecretFile = errors.New("secret file has insecure permissions")
+
+// SecretFilePolicy selects what happens when a secret file, such as the
+// password file, fails CheckSecretFile.
+type SecretFilePolicy string
+
+const (
+	// SecretFileWarn logs a warning and reads the file anyway (the default)
+	SecretFileWarn SecretFilePolicy = "warn"
+	// SecretFileStrict refuses to read the file, like ssh does for private keys
+	SecretFileStrict SecretFilePolicy = "strict"
+	// SecretFileIgnore skips the check
+	SecretFileIgnore SecretFilePolicy = "off"
+)
+
+// secretFilePolicyFromEnv returns the policy set in LDAP_SECRET_FILE_PERMISSIONS
+func secretFilePolicyFromEnv() SecretFilePolicy {
+	return SecretFilePolicy(os.Getenv("LDAP_SECRET_FILE_PERMISSIONS"))
+}
+
+// normalize maps the empty and unknown policies to SecretFileWarn
+func (p SecretFilePolicy) normalize() SecretFilePolicy {
+	switch p := SecretFilePolicy(strings.ToLower(string(p))); p {
+	case SecretFileStrict, SecretFileIgnore:
+		return p
+	default:
+		return SecretFileWarn
+	}
+}
+
+// CheckSecretFile reports whether the file at path is safe to hold a secret:
+// it must not be accessible to other users, and must be owned by the current
+// user or root. Group access is allowed so that Kubernetes secret volumes
+// mounted for an fsGroup pass, but only with defaultMode 0440 or 0640: the
+// default mode of secret volumes, 0644, is readable by other users and is
+// rejected. Ownership and mode bits are only checked on Unix systems;
+// elsewhere only the file's existence is.
+func CheckSecretFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if problem := secretFileProblem(info); problem != "" {
+		return fmt.Errorf("%w: %s is %s", ErrInsecureSecretFile, path, problem)
+	}
+	return nil
+}
+
+// ReadSecretFileWithPolicy reads a secret file, applying policy to the result
+// of CheckSecretFile, and returns its contents with surrounding whitespace
+// removed. It logs nothing: under SecretFileWarn an insecure file is read
+// as if it passed, and callers wanting to report it call CheckSecretFile.
+// Searchers reading a PasswordFile log the warning to Config.Logger.
+func ReadSecretFileWithPolicy(path string, policy SecretFilePolicy) (string, error) {
+	return readSecretFileWithPolicy(path, policy, discardLogger)
+}
+
+// readSecretFileWithPolicy is ReadSecretFileWithPolicy logging the warning
+// of SecretFileWarn to logger
+func readSecretFileWithPolicy(path string, policy SecretFilePolicy, logger *slog.Logger) (string, error) {
+	if policy := policy.normalize(); policy != SecretFileIgnore {
+		if err := CheckSecretFile(path); errors.Is(err, ErrInsecureSecretFile) {
+			if policy == SecretFileStrict {
+				return "", err
+			}
+			logger.Warn("reading secret file with insecure permissions", "error", err.Error())
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ReadSecretFile safely reads a secret file and returns its contents. The
+// file is checked with the policy in LDAP_SECRET_FILE_PERMISSIONS (warn by
+// default); an unreadable or refused file yields "". Like
+// ReadSecretFileWithPolicy it logs nothing.
+func ReadSecretFile(path string) string {
+	return readSecretFile(path, secretFilePolicyFromEnv())
+}
+
+// readSecretFile reads a secret file with policy, returning "" on failure
+func readSecretFile(path string, policy SecretFilePolicy) string {
+	secret, err := ReadSecretFileWithPolicy(path, policy)
+	if err != nil {
+		return ""
+	}
+	return secret
+}
@@ -0,0 +1,115 @@
+package ldap_redhat_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestCheckSecretFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits are not checked on Windows")
+	}
+	tmpDir := t.TempDir()
+	tests := []struct {
+		mode   os.FileMode
+		secure bool
+	}{
+		{0o600, true},
+		{0o400, true},
+		{0o640, true},  // group access, as with a Kubernetes fsGroup
+		{0o440, true},  // a secret volume with defaultMode 0440
+		{0o644, false}, // the default mode of secret volumes
+		{0o602, false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(tmpDir, tt.mode.String())
+		if err := os.WriteFile(path, []byte("secret\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, tt.mode); err != nil {
+			t.Fatal(err)
+		}
+		err := ldap_redhat.CheckSecretFile(path)
+		if tt.secure && err != nil {
+			t.Errorf("mode %04o: unexpected error: %v", tt.mode, err)
+		}
+		if !tt.secure && !errors.Is(err, ldap_redhat.ErrInsecureSecretFile) {
+			t.Errorf("mode %04o: expected ErrInsecureSecretFile, got %v", tt.mode, err)
+		}
+	}
+
+	if err := ldap_redhat.CheckSecretFile(filepath.Join(tmpDir, "missing")); err == nil || errors.Is(err, ldap_redhat.ErrInsecureSecretFile) {
+		t.Errorf("expected a not-exist error for a missing file, got %v", err)
+	}
+}
+
+func TestReadSecretFileWithPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits are not checked on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("file-password\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, policy := range []ldap_redhat.SecretFilePolicy{"", ldap_redhat.SecretFileWarn, ldap_redhat.SecretFileIgnore} {
+		password, err := ldap_redhat.ReadSecretFileWithPolicy(path, policy)
+		if err != nil || password != "file-password" {
+			t.Errorf("policy %q: expected the password, got %q, %v", policy, password, err)
+		}
+	}
+	if _, err := ldap_redhat.ReadSecretFileWithPolicy(path, ldap_redhat.SecretFileStrict); !errors.Is(err, ldap_redhat.ErrInsecureSecretFile) {
+		t.Errorf("strict policy: expected ErrInsecureSecretFile, got %v", err)
+	}
+
+	t.Setenv("LDAP_SECRET_FILE_PERMISSIONS", "strict")
+	if password := ldap_redhat.ReadSecretFile(path); password != "" {
+		t.Errorf("expected ReadSecretFile to refuse the file in strict mode, got %q", password)
+	}
+	t.Setenv("LDAP_PASSWORD_FILE", path)
+	t.Setenv("LDAP_PASSWORD", "")
+	if config := ldap_redhat.LoadConfigFromAll(); config.HasCredentials() {
+		t.Errorf("expected LoadConfigFromAll to refuse the password file in strict mode")
+	}
+}
+
+func TestPasswordFileWarningLogged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits are not checked on Windows")
+	}
+	srv := startEmbeddedServer(t, 3)
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte(embeddedPassword), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Credentials: ldap_redhat.PasswordFile{Path: path},
+		BaseDN:      testserver.UsersBaseDN,
+		Logger:      slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	if !strings.Contains(logs.String(), "insecure permissions") {
+		t.Errorf("Expected the warning in Config.Logger, got %q", logs.String())
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package ldap_redhat
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// secretFileProblem describes why info is unsafe for a secret, or returns ""
+func secretFileProblem(info fs.FileInfo) string {
+	if perm := info.Mode().Perm(); perm&0o007 != 0 {
+		return fmt.Sprintf("accessible by other users (mode %04o)", perm)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if owner := int(st.Uid); owner != 0 && owner != os.Geteuid() {
+			return fmt.Sprintf("owned by uid %d, not the current user", owner)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,229 @@
+package ldap_redhat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// defaultSnapshotFilter selects every user when TakeSnapshot is given no filter
+const defaultSnapshotFilter = "(uid=*)"
+
+// Snapshot is a point-in-time copy of directory users. On disk it is stored
+// as JSON Lines, one UserRecord per line, and TakenAt is the file's
+// modification time.
+type Snapshot struct {
+	TakenAt time.Time
+	Users   []UserRecord
+
+	index map[int]map[string]int // identifier type -> key -> index in Users
+}
+
+// NewSnapshot indexes users taken at takenAt.
+func NewSnapshot(users []UserRecord, takenAt time.Time) *Snapshot {
+	sn := &Snapshot{
+		TakenAt: takenAt,
+		Users:   users,
+		index:   make(map[int]map[string]int, len(identifierKinds)),
+	}
+	for i := range users {
+		for idType, key := range recordIdentifiers(&users[i]) {
+			if sn.index[idType] == nil {
+				sn.index[idType] = make(map[string]int, len(users))
+			}
+			sn.index[idType][key] = i
+		}
+	}
+	return sn
+}
+
+// ReadSnapshot parses a JSON Lines snapshot taken at takenAt.
+func ReadSnapshot(r io.Reader, takenAt time.Time) (*Snapshot, error) {
+	var users []UserRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var u UserRecord
+		if err := json.Unmarshal(scanner.Bytes(), &u); err != nil {
+			return nil, fmt.Errorf("invalid snapshot record on line %d: %w", line, err)
+		}
+		users = append(users, u)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return NewSnapshot(users, takenAt), nil
+}
+
+// LoadSnapshotFile reads a snapshot written by WriteFile.
+func LoadSnapshotFile(path string) (*Snapshot, error) {
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat snapshot %s: %w", path, err)
+	}
+	return ReadSnapshot(f, info.ModTime())
+}
+
+// Write encodes the snapshot as JSON Lines.
+func (sn *Snapshot) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, u := range sn.Users {
+		if err := enc.Encode(u); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFile atomically replaces path with the snapshot. The file is created
+// with mode 0600 since records include HR data.
+func (sn *Snapshot) WriteFile(path string) error {
+	return writeFileAtomic(path, sn.Write)
+}
+
+// writeFileAtomic writes path through a temporary file in the same directory
+// and renames it into place. CreateTemp makes the file with mode 0600.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	path = expandHome(path)
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// Lookup finds a user by identifier. Email, rhatUUID and Kerberos principal
+// matching is case-insensitive.
+func (sn *Snapshot) Lookup(id Identifier) (UserRecord, bool) {
+	key, ok := identifierKey(id.Type, id.Value)
+	if !ok {
+		return UserRecord{}, false
+	}
+	i, ok := sn.index[id.Type][key]
+	if !ok {
+		return UserRecord{}, false
+	}
+	return sn.Users[i], true
+}
+
+// TakeSnapshot pages through every user matching filter (all users when
+// empty) and returns them as a Snapshot taken now.
+func (s *Searcher) TakeSnapshot(ctx context.Context, filter string) (*Snapshot, error) {
+	takenAt := time.Now()
+	users, err := s.SearchUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return NewSnapshot(users, takenAt), nil
+}
+
+// offlineEnabled reports whether stale snapshot results may be served when
+// the directory is unreachable.
+func (s *Searcher) offlineEnabled() bool {
+	return s.config().OfflineFallback && s.config().SnapshotFile != ""
+}
+
+// unreachable reports whether err means the directory could not be reached,
+// as opposed to the server answering with an error. A dropped connection
+// surfaces as a plain read error, so the connection state is checked too. An
+// open circuit breaker counts as unreachable.
+func (s *Searcher) unreachable(err error) bool {
+	return ldap.IsErrorWithCode(err, ldap.ErrorNetwork) || errors.Is(err, ErrCircuitOpen) ||
+		(s.conn() != nil && s.conn().IsClosing())
+}
+
+// loadSnapshot reads Config.SnapshotFile on first use.
+func (s *Searcher) loadSnapshot() (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot == nil {
+		sn, err := LoadSnapshotFile(s.Config.SnapshotFile)
+		if err != nil {
+			return nil, err
+		}
+		s.snapshot = sn
+	}
+	return s.snapshot, nil
+}
+
+// offlineUsers serves ids from the snapshot, marking every found record
+// Stale. Missing users have an empty UID, as in GetUsers. Requests scoped to a
+// base DN are refused because snapshot records carry no DN to check.
+func (s *Searcher) offlineUsers(ctx context.Context, ids []Identifier) ([]UserRecord, error) {
+	if scope, ok := RequestScopeFromContext(ctx); ok && scope.BaseDN != "" {
+		return nil, fmt.Errorf("LDAP directory unavailable and offline snapshot cannot serve requests scoped to %s", scope.BaseDN)
+	}
+	sn, err := s.loadSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("LDAP directory unavailable and offline snapshot could not be loaded: %w", err)
+	}
+	age := time.Since(sn.TakenAt)
+	warn(ctx, Warning{
+		Kind:    WarnStale,
+		Message: fmt.Sprintf("LDAP directory unavailable, served from snapshot %s taken %s ago", s.config().SnapshotFile, age.Round(time.Second)),
+	})
+	out := make([]UserRecord, len(ids))
+	var failed []ItemError
+	for i, id := range ids {
+		if _, ok := identifierKinds[id.Type]; !ok {
+			failed = append(failed, ItemError{Index: i, Item: id.Value, Err: newError(ErrInvalidIdentifier, "unknown identifier type: %d", id.Type)})
+			continue
+		}
+		if rec, ok := sn.Lookup(id); ok {
+			rec.Stale = true
+			rec.SnapshotAge = age
+			rec.meta = RecordMeta{Server: s.config().SnapshotFile, RetrievedAt: sn.TakenAt}
+			s.redact(ctx, &rec)
+			out[i] = rec
+		}
+	}
+	return out, newBatchError(len(ids), failed)
+}
+
+// offlineUser serves a single identifier from the snapshot.
+func (s *Searcher) offlineUser(ctx context.Context, id Identifier) (UserRecord, error) {
+	recs, err := s.offlineUsers(ctx, []Identifier{id})
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		return UserRecord{}, batchErr.Errors[0].Err
+	}
+	if err != nil {
+		return UserRecord{}, err
+	}
+	if recs[0].UID == "" {
+		return UserRecord{}, newError(ErrUserNotFound, "user not found in offline snapshot: %s", id.Value)
+	}
+	return recs[0], nil
+}
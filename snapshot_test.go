@@ -0,0 +1,141 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// writeEmbeddedSnapshot snapshots every user on searcher into a temp file
+func writeEmbeddedSnapshot(t *testing.T, searcher *ldap_redhat.Searcher) string {
+	t.Helper()
+	sn, err := searcher.TakeSnapshot(context.Background(), "")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	if err := sn.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 30)
+	path := writeEmbeddedSnapshot(t, searcher)
+
+	sn, err := ldap_redhat.LoadSnapshotFile(path)
+	if err != nil {
+		t.Fatalf("LoadSnapshotFile failed: %v", err)
+	}
+	if len(sn.Users) != 30 {
+		t.Errorf("Expected 30 users in snapshot, got %d", len(sn.Users))
+	}
+	if sn.TakenAt.IsZero() {
+		t.Error("Expected TakenAt to be set from the file")
+	}
+
+	uid := testserver.UserUID(7)
+	rec, ok := sn.Lookup(ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: strings.ToUpper(uid) + "@REDHAT.COM"})
+	if !ok || rec.UID != uid {
+		t.Errorf("Expected email lookup to find %s, got %q (found=%v)", uid, rec.UID, ok)
+	}
+	if _, ok := sn.Lookup(ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nonexistent"}); ok {
+		t.Error("Expected lookup of unknown UID to fail")
+	}
+}
+
+func TestReadSnapshotInvalid(t *testing.T) {
+	_, err := ldap_redhat.ReadSnapshot(strings.NewReader("{\"UID\":\"a\"}\nnot json\n"), time.Time{})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error on line 2, got %v", err)
+	}
+}
+
+func TestOfflineFallback(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 30)
+	path := writeEmbeddedSnapshot(t, searcher)
+	ctx := context.Background()
+
+	searcher.Config.SnapshotFile = path
+	searcher.Config.OfflineFallback = true
+
+	uid := testserver.UserUID(3)
+	live, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if live.Stale {
+		t.Error("Live result should not be marked stale")
+	}
+
+	srv.Close()
+
+	t.Run("EstablishedConnection", func(t *testing.T) {
+		warnCtx, warnings := ldap_redhat.WithWarnings(ctx)
+		rec, err := searcher.GetUser(warnCtx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid})
+		if err != nil {
+			t.Fatalf("GetUser should fall back to the snapshot: %v", err)
+		}
+		if !rec.Stale || rec.SnapshotAge <= 0 {
+			t.Errorf("Expected stale record with snapshot age, got Stale=%v SnapshotAge=%v", rec.Stale, rec.SnapshotAge)
+		}
+		if !warnings.Has(ldap_redhat.WarnStale) {
+			t.Errorf("Expected a stale warning, got %v", warnings.List())
+		}
+		if rec.Email != live.Email {
+			t.Errorf("Expected snapshot email %q, got %q", live.Email, rec.Email)
+		}
+
+		recs, err := searcher.GetUsers(ctx, []ldap_redhat.Identifier{
+			{Type: ldap_redhat.IDTEmail, Value: live.Email},
+			{Type: ldap_redhat.IDTUID, Value: "nonexistent"},
+		})
+		if err != nil {
+			t.Fatalf("GetUsers should fall back to the snapshot: %v", err)
+		}
+		if recs[0].UID != uid || !recs[0].Stale {
+			t.Errorf("Expected stale %s, got %+v", uid, recs[0])
+		}
+		if recs[1].UID != "" {
+			t.Errorf("Expected empty record for missing user, got %q", recs[1].UID)
+		}
+
+		if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "nonexistent"}); err == nil {
+			t.Error("Expected error for user missing from snapshot")
+		}
+	})
+
+	t.Run("NewSearcher", func(t *testing.T) {
+		config := ldap_redhat.Config{
+			LdapServers:     []string{srv.URL()},
+			BaseDN:          testserver.UsersBaseDN,
+			SnapshotFile:    path,
+			OfflineFallback: true,
+		}
+		offline, err := ldap_redhat.NewSearcher(config)
+		if err != nil {
+			t.Fatalf("NewSearcher should succeed in offline mode: %v", err)
+		}
+		rec, err := offline.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid})
+		if err != nil || !rec.Stale {
+			t.Errorf("Expected stale result, got %+v, %v", rec, err)
+		}
+
+		config.OfflineFallback = false
+		if _, err := ldap_redhat.NewSearcher(config); err == nil {
+			t.Error("Expected NewSearcher to fail without offline fallback")
+		}
+
+		config.OfflineFallback = true
+		config.SnapshotFile = filepath.Join(t.TempDir(), "missing.jsonl")
+		if _, err := ldap_redhat.NewSearcher(config); err == nil {
+			t.Error("Expected NewSearcher to fail when the snapshot cannot be loaded")
+		}
+	})
+}
@@ -0,0 +1,296 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// defaultPageSize is the number of entries requested per page in paged searches
+const defaultPageSize = 500
+
+// ForEachUser runs an LDAP filter under the configured base DN and calls fn
+// for every matching user as entries arrive, fetching results in pages so
+// large subtrees never have to be held in memory. With Config.Interceptors,
+// each page is a search through them, and its users are passed to fn once
+// the whole page has arrived. It stops and returns the
+// first error returned by fn, or ctx.Err() if the context is cancelled.
+// When the search stops at a size limit, fn has been called for the users
+// received before it and a *PartialResultsError is returned.
+func (s *Searcher) ForEachUser(ctx context.Context, filter string, fn func(UserRecord) error) error {
+	returned := 0
+	err := s.forEachUser(ctx, filter, func(u UserRecord) error {
+		returned++
+		return fn(u)
+	})
+	s.auditListing(ctx, AuditForEachUser, filter, returned, err)
+	return err
+}
+
+// forEachUser is ForEachUser without auditing
+func (s *Searcher) forEachUser(ctx context.Context, filter string, fn func(UserRecord) error) error {
+	return s.forEachUserWith(ctx, filter, s.attributes(ctx), nil, fn)
+}
+
+// forEachActiveUser is ForEachUser calling fn only for users with a UID who
+// are active, as IsActive judges them, unless includeTerminated is set. The
+// termination date is fetched under every attribute profile and request
+// scope so that it can be judged, and cleared like the other attributes they
+// do not allow once it has been.
+func (s *Searcher) forEachActiveUser(ctx context.Context, filter string, includeTerminated bool, fn func(UserRecord) error) error {
+	returned := 0
+	err := s.forEachActive(ctx, filter, includeTerminated, func(u UserRecord) error {
+		returned++
+		return fn(u)
+	})
+	s.auditListing(ctx, AuditForEachUser, filter, returned, err)
+	return err
+}
+
+// forEachActive is forEachActiveUser without auditing, sending controls
+// with every page
+func (s *Searcher) forEachActive(ctx context.Context, filter string, includeTerminated bool, fn func(UserRecord) error, controls ...ldap.Control) error {
+	keep := func(u UserRecord) bool {
+		return u.UID != "" && (includeTerminated || u.IsActive())
+	}
+	return s.forEachUserWith(ctx, filter, withAttributes(s.attributes(ctx), AttrRhatTermDate), keep, fn, controls...)
+}
+
+// forEachUserWith is forEachUser fetching attrs only, sending controls with
+// every page, and skipping the users keep, when not nil, rejects before they
+// are redacted
+func (s *Searcher) forEachUserWith(ctx context.Context, filter string, attrs []string, keep func(UserRecord) bool, fn func(UserRecord) error, controls ...ldap.Control) error {
+	if s.disconnected() {
+		return errNotConnected()
+	}
+
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return err
+	}
+	paging := ldap.NewControlPaging(defaultPageSize)
+	req := ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		s.sizeLimit(ctx), 0, false, filter, attrs, append([]ldap.Control{paging}, controls...),
+	)
+
+	returned := 0
+	count := func(u UserRecord) error {
+		if keep != nil && !keep(u) {
+			return nil
+		}
+		s.redact(ctx, &u)
+		returned++
+		return fn(u)
+	}
+	for {
+		cookie, err := s.forEachInPage(ctx, req, count)
+		if partial := asPartial(err); partial != nil {
+			partial.Returned = returned
+		}
+		if err != nil {
+			return err
+		}
+		if len(cookie) == 0 {
+			return nil
+		}
+		paging.SetCookie(cookie)
+	}
+}
+
+// SearchUsers returns every user matching an LDAP filter (all users when
+// empty). Results are collected in memory; use ForEachUser for populations
+// too large to hold at once. When the search stops at a size limit, the
+// users received before it are returned with a *PartialResultsError.
+func (s *Searcher) SearchUsers(ctx context.Context, filter string) ([]UserRecord, error) {
+	if filter == "" {
+		filter = defaultSnapshotFilter
+	}
+	var users []UserRecord
+	err := s.ForEachUser(ctx, filter, func(u UserRecord) error {
+		users = append(users, u)
+		return nil
+	})
+	if asPartial(err) != nil {
+		return users, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// errStopIteration stops the ForEachUser behind Users when the loop body
+// breaks out
+var errStopIteration = errors.New("iteration stopped")
+
+// Users returns an iterator over the users matching an LDAP filter (all
+// users when empty), which processes entries as they arrive like
+// ForEachUser, for range-over-func loops:
+//
+//	for u, err := range searcher.Users(ctx, "(ou=Engineering)") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// An error is yielded once, with a zero UserRecord, after the users
+// received before it; a *PartialResultsError follows the users returned
+// before a size limit. Breaking out of the loop abandons the search.
+func (s *Searcher) Users(ctx context.Context, filter string) iter.Seq2[UserRecord, error] {
+	if filter == "" {
+		filter = defaultSnapshotFilter
+	}
+	return func(yield func(UserRecord, error) bool) {
+		err := s.ForEachUser(ctx, filter, func(u UserRecord) error {
+			if !yield(u, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			yield(UserRecord{}, err)
+		}
+	}
+}
+
+// forEachInPage streams a single page of req to fn, unredacted, and returns
+// the cookie for the next page, which is empty once the server has no more
+// results.
+func (s *Searcher) forEachInPage(ctx context.Context, req *ldap.SearchRequest, fn func(UserRecord) error) ([]byte, error) {
+	if len(s.config().Interceptors) > 0 {
+		return s.forEachInInterceptedPage(ctx, req, fn)
+	}
+	opts, err := s.searchOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, connName, flow, err := s.queryConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	// Recorded on every return, so that a half-open trial stopped by fn or
+	// by ctx does not stay in flight and keep the breaker from closing
+	unreachable := false
+	defer func() { s.breaker.record(unreachable) }()
+	if flow != nil {
+		req.Attributes = flow.request(req.Attributes)
+	}
+	req.Controls = withSessionTracking(req.Controls, connName)
+	setTimeLimit(req, opts.TimeLimit)
+	start := time.Now()
+	entries := 0
+	_, span := startSpan(ctx, s.config(), "ldap.search", searchSpanAttributes(req)...)
+	defer func() {
+		span.SetAttributes(attribute.Int("ldap.result.count", entries))
+		span.End()
+	}()
+	pageCtx, cancel := context.WithCancel(ctx)
+	resp := conn.SearchAsync(pageCtx, req, 64)
+	defer func() {
+		// Stop the reader goroutine and drain it so it cannot block on a full channel
+		cancel()
+		for resp.Next() {
+		}
+	}()
+
+	var cookie []byte
+	var refs []string // continuation references to other servers
+	for resp.Next() {
+		if entry := resp.Entry(); entry != nil {
+			entries++
+			if flow != nil {
+				flow.observe(ctx, entry)
+			}
+			rec := s.userRecord(ctx, entry)
+			rec.meta = s.recordMeta(start)
+			if err := fn(rec); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if ref := resp.Referral(); ref != "" {
+			refs = append(refs, ref)
+			continue
+		}
+		if ctrl, ok := ldap.FindControl(resp.Controls(), ldap.ControlTypePaging).(*ldap.ControlPaging); ok {
+			cookie = ctrl.Cookie
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = resp.Err()
+	s.logSearch("ldap search page", req, start, entries, err)
+	if limitExceeded(err) {
+		return nil, partialResults(ctx, req, entries, err)
+	}
+	if opts.MaxReferralHops > 0 && (referralURLs(err) != nil || err == nil && len(refs) > 0) {
+		return cookie, s.forEachReferred(ctx, req, refs, err, opts.MaxReferralHops, fn)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		unreachable = s.unreachable(err)
+		return nil, wrapLDAPError(err, "LDAP search failed")
+	}
+	return cookie, nil
+}
+
+// forEachInInterceptedPage runs a single page of req through
+// Config.Interceptors like any other search, then passes its entries to fn,
+// and returns the cookie for the next page
+func (s *Searcher) forEachInInterceptedPage(ctx context.Context, req *ldap.SearchRequest, fn func(UserRecord) error) ([]byte, error) {
+	start := time.Now()
+	result, err := s.search(ctx, req)
+	if err != nil && asPartial(err) == nil {
+		return nil, wrapLDAPError(err, "LDAP search failed")
+	}
+	if result == nil {
+		return nil, err
+	}
+	meta := s.recordMeta(start)
+	for _, entry := range result.Entries {
+		rec := s.userRecord(ctx, entry)
+		rec.meta = meta
+		if err := fn(rec); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ctrl, ok := ldap.FindControl(result.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging); ok {
+		return ctrl.Cookie, nil
+	}
+	return nil, nil
+}
+
+// forEachReferred follows the referral err or the continuation references
+// refs of a page of req, and streams the entries found to fn
+func (s *Searcher) forEachReferred(ctx context.Context, req *ldap.SearchRequest, refs []string, err error, hops int, fn func(UserRecord) error) error {
+	start := time.Now()
+	plain := *req
+	plain.Controls = nil // paging cookies are only valid on the original server
+	result, err := s.chaseReferrals(ctx, &plain, &ldap.SearchResult{Referrals: refs}, err, hops)
+	if err != nil {
+		return wrapLDAPError(err, "LDAP search failed")
+	}
+	for _, entry := range result.Entries {
+		rec := s.userRecord(ctx, entry)
+		rec.meta = s.recordMeta(start)
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestForEachUserPaged(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 1200)
+	ctx := context.Background()
+
+	seen := map[string]bool{}
+	err := searcher.ForEachUser(ctx, "(uid=*)", func(u ldap_redhat.UserRecord) error {
+		if seen[u.UID] {
+			t.Errorf("User %s delivered twice", u.UID)
+		}
+		seen[u.UID] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachUser failed: %v", err)
+	}
+	if len(seen) != 1200 {
+		t.Errorf("Expected 1200 users across pages, got %d", len(seen))
+	}
+
+	// The connection must still be usable after a multi-page search
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "user000042"}); err != nil {
+		t.Errorf("GetUser after ForEachUser failed: %v", err)
+	}
+}
+
+func TestForEachUserStopsOnError(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 1200)
+	ctx := context.Background()
+
+	stop := errors.New("stop")
+	count := 0
+	err := searcher.ForEachUser(ctx, "(uid=*)", func(u ldap_redhat.UserRecord) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected callback error to be returned, got %v", err)
+	}
+	if count != 10 {
+		t.Errorf("Expected callback to stop after 10 users, got %d", count)
+	}
+
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "user000001"}); err != nil {
+		t.Errorf("GetUser after aborted ForEachUser failed: %v", err)
+	}
+}
+
+func TestForEachUserContextCancelled(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 50)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := searcher.ForEachUser(ctx, "(uid=*)", func(ldap_redhat.UserRecord) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestForEachUserWithoutConnection(t *testing.T) {
+	searcher := &ldap_redhat.Searcher{Config: ldap_redhat.Config{}}
+	err := searcher.ForEachUser(context.Background(), "(uid=*)", func(ldap_redhat.UserRecord) error { return nil })
+	if err == nil {
+		t.Error("Expected error when no LDAP connection established")
+	}
+}
+
+func TestUsersIterator(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 700)
+	ctx := context.Background()
+
+	n := 0
+	for u, err := range searcher.Users(ctx, "") {
+		if err != nil {
+			t.Fatalf("Users yielded an error: %v", err)
+		}
+		if u.UID == "" {
+			t.Errorf("Users yielded an empty record")
+		}
+		n++
+	}
+	if n != 700 {
+		t.Errorf("Expected 700 users across pages, got %d", n)
+	}
+
+	// Breaking out abandons the search and leaves the connection usable
+	n = 0
+	for range searcher.Users(ctx, "(uid=*)") {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "user000042"}); err != nil {
+		t.Errorf("GetUser after breaking out of Users failed: %v", err)
+	}
+
+	var last error
+	for _, err := range searcher.Users(ctx, "(uid=") {
+		last = err
+	}
+	if last == nil {
+		t.Error("Expected an error for an invalid filter")
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 600)
+	ctx := context.Background()
+
+	var s ldap_redhat.UserSearcher = searcher
+	users, err := s.SearchUsers(ctx, "")
+	if err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	if len(users) != 600 {
+		t.Errorf("Expected 600 users across pages, got %d", len(users))
+	}
+
+	users, err = s.SearchUsers(ctx, "(uid=user000007)")
+	if err != nil || len(users) != 1 || users[0].UID != "user000007" {
+		t.Errorf("Expected only user000007, got %v, %v", users, err)
+	}
+}
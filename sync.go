@@ -0,0 +1,372 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PersistentSearchControlOID is the persistent search control
+// (draft-ietf-ldapext-psearch), which 389 Directory Server implements.
+const PersistentSearchControlOID = "2.16.840.1.113730.3.4.3"
+
+// EntryChangeNotificationOID is the control a persistent search attaches to
+// the entries it returns for changes.
+const EntryChangeNotificationOID = "2.16.840.1.113730.3.4.7"
+
+// Persistent search change types: a bit mask in the request control, a
+// single value in entry change notifications
+const (
+	psearchAdd    = 1
+	psearchDelete = 2
+	psearchModify = 4
+	psearchModDN  = 8
+)
+
+// syncBufferSize is the number of results buffered between the connection
+// and the handler of Sync
+const syncBufferSize = 64
+
+// SyncEventKind is the kind of change Sync reports for an entry.
+type SyncEventKind string
+
+const (
+	// SyncPresent is an entry of the initial content, sent before changes.
+	SyncPresent SyncEventKind = "present"
+	// SyncAdd is an entry added to the results.
+	SyncAdd SyncEventKind = "add"
+	// SyncModify is an entry modified or renamed.
+	SyncModify SyncEventKind = "modify"
+	// SyncDelete is an entry deleted, or no longer matching the filter.
+	SyncDelete SyncEventKind = "delete"
+)
+
+// SyncEvent is a change notification streamed by Sync.
+type SyncEvent struct {
+	Kind SyncEventKind `json:"kind" yaml:"kind"`
+	DN   string        `json:"dn,omitempty" yaml:"dn,omitempty"` // empty for deletions known only by EntryUUID
+	User UserRecord    `json:"user" yaml:"user"`                 // the entry after the change; empty for SyncDelete
+
+	// EntryUUID identifies the entry across renames, with content
+	// synchronization only.
+	EntryUUID string `json:"entry_uuid,omitempty" yaml:"entry_uuid,omitempty"`
+	// Cookie is the synchronization state after this event, with content
+	// synchronization only. Pass it in SearchOptions.SyncCookie to resume
+	// from here.
+	Cookie []byte `json:"cookie,omitempty" yaml:"cookie,omitempty"`
+}
+
+// syncMechanism is the search control Sync is notified through
+type syncMechanism string
+
+const (
+	syncContent    syncMechanism = "syncrepl" // RFC 4533 content synchronization
+	syncPersistent syncMechanism = "psearch"  // persistent search
+)
+
+// Sync streams the users matching filter (all users when empty) under the
+// configured base DN to handler, then their changes as the server reports
+// them, so consumers do not have to poll. It uses content synchronization
+// (RFC 4533) in refreshAndPersist mode, and persistent search where the
+// server only supports that. When it supports neither, Sync returns an error
+// matching ErrSyncUnsupported.
+//
+// The initial content is reported as SyncPresent events. Resuming with
+// SearchOptions.SyncCookie makes the server send only what changed since
+// instead; deletions it can only identify by EntryUUID come without a DN.
+// Persistent searches cannot resume and always start with the whole content.
+//
+// The search runs on a connection of its own, without the search timeout
+// and without passing through Config.Interceptors, until ctx is done,
+// handler returns an error, or the server ends it. Sync returns handler's
+// error as is, ctx.Err(), or the LDAP error, and nil if the server ends the
+// search successfully. Restart it with the Cookie of the last event handled
+// to carry on.
+func (s *Searcher) Sync(ctx context.Context, filter string, handler func(SyncEvent) error) error {
+	var (
+		handled    int
+		handlerErr error
+	)
+	err := s.sync(ctx, filter, func(event SyncEvent) error {
+		handled++
+		handlerErr = handler(event)
+		return handlerErr
+	})
+	// Stopping through handler or ctx is how Sync ends, not a failure
+	outcome := err
+	if err != nil && (err == handlerErr || err == ctx.Err()) {
+		outcome = nil
+	}
+	s.auditListing(ctx, AuditSync, filter, handled, outcome)
+	return err
+}
+
+// sync is Sync without auditing
+func (s *Searcher) sync(ctx context.Context, filter string, handler func(SyncEvent) error) error {
+	if s.disconnected() {
+		return errNotConnected()
+	}
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return err
+	}
+	if filter == "" {
+		filter = defaultSnapshotFilter
+	}
+	if _, err := s.searchOptions(ctx); err != nil {
+		return err
+	}
+	opts, _ := SearchOptionsFromContext(ctx)
+
+	conn, connName, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetTimeout(0) // the search lasts as long as ctx
+
+	newRequest := func() *ldap.SearchRequest {
+		return ldap.NewSearchRequest(
+			baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+			0, 0, false, filter, s.attributes(ctx), withSessionTracking(nil, connName),
+		)
+	}
+	notified, err := s.syncStream(ctx, conn, newRequest(), syncContent, opts.SyncCookie, handler)
+	if notified || !ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailableCriticalExtension) {
+		return err
+	}
+	notified, err = s.syncStream(ctx, conn, newRequest(), syncPersistent, nil, handler)
+	if !notified && ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailableCriticalExtension) {
+		return &libError{
+			msg:  "LDAP server supports neither content synchronization nor persistent search: " + err.Error(),
+			kind: ErrSyncUnsupported,
+			err:  err,
+		}
+	}
+	return err
+}
+
+// syncStream runs req with mechanism's control on conn and passes the
+// events to handler. It reports whether handler was called.
+func (s *Searcher) syncStream(ctx context.Context, conn *ldap.Conn, req *ldap.SearchRequest, mechanism syncMechanism, cookie []byte, handler func(SyncEvent) error) (bool, error) {
+	start := time.Now()
+	events := 0
+	_, span := startSpan(ctx, s.config(), "ldap.sync",
+		append(searchSpanAttributes(req), attribute.String("ldap.sync.mechanism", string(mechanism)))...)
+	streamCtx, cancel := context.WithCancel(ctx)
+	var resp ldap.Response
+	if mechanism == syncPersistent {
+		req.Controls = append(req.Controls, persistentSearchControl{})
+		resp = conn.SearchAsync(streamCtx, req, syncBufferSize)
+	} else {
+		resp = conn.Syncrepl(streamCtx, req, syncBufferSize, ldap.SyncRequestModeRefreshAndPersist, cookie, false)
+	}
+	defer func() {
+		// Stop the reader goroutine and drain it so it cannot block on a full channel
+		cancel()
+		for resp.Next() {
+		}
+	}()
+
+	state := syncState{cookie: cookie, refreshing: len(cookie) == 0}
+	var handlerErr error
+	for handlerErr == nil && resp.Next() {
+		var evs []SyncEvent
+		if mechanism == syncPersistent {
+			evs = s.persistentEvents(ctx, resp)
+		} else {
+			evs = s.contentSyncEvents(ctx, resp, &state)
+		}
+		for _, ev := range evs {
+			if ev.Kind != SyncDelete {
+				ev.User.meta = s.recordMeta(start)
+				s.redact(ctx, &ev.User)
+			}
+			events++
+			if handlerErr = handler(ev); handlerErr != nil {
+				break
+			}
+		}
+	}
+
+	var err error
+	switch {
+	case handlerErr != nil:
+		err = handlerErr
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	case resp.Err() != nil:
+		err = wrapLDAPError(resp.Err(), "LDAP sync failed")
+	}
+	s.logSync(mechanism, req, start, events, err)
+	span.SetAttributes(attribute.Int("ldap.result.count", events))
+	endSpan(span, err)
+	return events > 0, err
+}
+
+// syncState is the progress of a content synchronization
+type syncState struct {
+	cookie     []byte
+	refreshing bool // in the initial refresh, whose entries are SyncPresent
+}
+
+// contentSyncEvents returns the events of a content synchronization result:
+// an entry with its sync state control, or the deletions of a sync info
+// message listing entry UUIDs. Other sync info messages update st.
+func (s *Searcher) contentSyncEvents(ctx context.Context, resp ldap.Response, st *syncState) []SyncEvent {
+	if entry := resp.Entry(); entry != nil {
+		ctrl, ok := ldap.FindControl(resp.Controls(), ldap.ControlTypeSyncState).(*ldap.ControlSyncState)
+		if !ok {
+			return nil
+		}
+		st.setCookie(ctrl.Cookie)
+		ev := SyncEvent{DN: entry.DN, EntryUUID: ctrl.EntryUUID.String(), Cookie: st.cookie}
+		switch ctrl.State {
+		case ldap.SyncStateDelete:
+			ev.Kind = SyncDelete
+			return []SyncEvent{ev}
+		case ldap.SyncStateModify:
+			ev.Kind = SyncModify
+		case ldap.SyncStateAdd:
+			ev.Kind = SyncAdd
+			if st.refreshing {
+				ev.Kind = SyncPresent
+			}
+		default:
+			// Present without attributes: the entry is unchanged since
+			// the cookie, so there is nothing to report
+			return nil
+		}
+		ev.User = s.userRecord(ctx, entry)
+		return []SyncEvent{ev}
+	}
+
+	var events []SyncEvent
+	for _, c := range resp.Controls() {
+		switch c := c.(type) {
+		case *ldap.ControlSyncInfo:
+			switch {
+			case c.NewCookie != nil:
+				st.setCookie(c.NewCookie.Cookie)
+			case c.RefreshDelete != nil:
+				st.setCookie(c.RefreshDelete.Cookie)
+				st.refreshing = st.refreshing && !c.RefreshDelete.RefreshDone
+			case c.RefreshPresent != nil:
+				st.setCookie(c.RefreshPresent.Cookie)
+				st.refreshing = st.refreshing && !c.RefreshPresent.RefreshDone
+			case c.SyncIdSet != nil:
+				st.setCookie(c.SyncIdSet.Cookie)
+				if c.SyncIdSet.RefreshDeletes {
+					for _, id := range c.SyncIdSet.SyncUUIDs {
+						events = append(events, SyncEvent{Kind: SyncDelete, EntryUUID: id.String(), Cookie: st.cookie})
+					}
+				}
+			}
+		case *ldap.ControlSyncDone:
+			st.setCookie(c.Cookie)
+		}
+	}
+	return events
+}
+
+// setCookie records cookie, which sync info messages may leave out
+func (st *syncState) setCookie(cookie []byte) {
+	if len(cookie) > 0 {
+		st.cookie = cookie
+	}
+}
+
+// persistentEvents returns the event of a persistent search result. Entries
+// without an entry change notification are the initial content.
+func (s *Searcher) persistentEvents(ctx context.Context, resp ldap.Response) []SyncEvent {
+	entry := resp.Entry()
+	if entry == nil {
+		return nil
+	}
+	ev := SyncEvent{Kind: SyncPresent, DN: entry.DN}
+	if ctrl, ok := ldap.FindControl(resp.Controls(), EntryChangeNotificationOID).(*ldap.ControlString); ok {
+		changeType, err := parseEntryChangeNotification(ctrl.ControlValue)
+		if err != nil {
+			s.config().logger().Warn("ignoring malformed entry change notification", slog.String("dn", entry.DN), slog.Any("error", err))
+			return nil
+		}
+		switch changeType {
+		case psearchAdd:
+			ev.Kind = SyncAdd
+		case psearchDelete:
+			ev.Kind = SyncDelete
+			return []SyncEvent{ev}
+		default:
+			ev.Kind = SyncModify
+		}
+	}
+	ev.User = s.userRecord(ctx, entry)
+	return []SyncEvent{ev}
+}
+
+// parseEntryChangeNotification returns the change type of an entry change
+// notification control value,
+//
+//	SEQUENCE { changeType ENUMERATED, previousDN LDAPDN OPTIONAL, changeNumber INTEGER OPTIONAL }
+func parseEntryChangeNotification(value string) (int64, error) {
+	packet, err := ber.DecodePacketErr([]byte(value))
+	if err != nil {
+		return 0, err
+	}
+	if len(packet.Children) == 0 {
+		return 0, errors.New("missing change type")
+	}
+	changeType, ok := packet.Children[0].Value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("invalid change type %v", packet.Children[0].Value)
+	}
+	return changeType, nil
+}
+
+// persistentSearchControl implements ldap.Control for a critical persistent
+// search asking for every change type, the initial content and entry change
+// notifications.
+type persistentSearchControl struct{}
+
+func (persistentSearchControl) GetControlType() string {
+	return PersistentSearchControlOID
+}
+
+// Encode returns the control with its value,
+//
+//	SEQUENCE { changeTypes INTEGER, changesOnly BOOLEAN, returnECs BOOLEAN }
+func (persistentSearchControl) Encode() *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, PersistentSearchControlOID, "Control Type (Persistent Search)"))
+	packet.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "Criticality"))
+
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Persistent Search")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(psearchAdd|psearchDelete|psearchModify|psearchModDN), "Change Types"))
+	value.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, false, "Changes Only"))
+	value.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "Return ECs"))
+	wrapper := ber.Encode(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, nil, "Control Value (Persistent Search)")
+	wrapper.AppendChild(value)
+	packet.AppendChild(wrapper)
+	return packet
+}
+
+func (persistentSearchControl) String() string {
+	return fmt.Sprintf("Control Type: Persistent Search (%q)  Criticality: true", PersistentSearchControlOID)
+}
+
+// logSync logs the end of a Sync search at debug level
+func (s *Searcher) logSync(mechanism syncMechanism, req *ldap.SearchRequest, start time.Time, events int, err error) {
+	logOperation(s.config().logger(), "ldap sync", start, err,
+		slog.String("conn", trackedConnName(req.Controls)),
+		slog.String("mechanism", string(mechanism)),
+		slog.String("base", req.BaseDN),
+		slog.String("filter", req.Filter),
+		slog.Int("events", events),
+	)
+}
@@ -0,0 +1,137 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// startSync runs Sync of the active users in the background and returns its
+// events and its result
+func startSync(t *testing.T, ctx context.Context, searcher *ldap_redhat.Searcher) (<-chan ldap_redhat.SyncEvent, <-chan error) {
+	t.Helper()
+	events := make(chan ldap_redhat.SyncEvent, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- searcher.Sync(ctx, "(&(uid=*)(!(rhatTermDate=*)))", func(ev ldap_redhat.SyncEvent) error {
+			events <- ev
+			return nil
+		})
+	}()
+	return events, done
+}
+
+// nextSyncEvent returns the next event, failing t if none arrives
+func nextSyncEvent(t *testing.T, events <-chan ldap_redhat.SyncEvent) ldap_redhat.SyncEvent {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("No sync event received")
+	}
+	return ldap_redhat.SyncEvent{}
+}
+
+func TestSyncContent(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, done := startSync(t, ctx, searcher)
+
+	for i := range 5 {
+		ev := nextSyncEvent(t, events)
+		if ev.Kind != ldap_redhat.SyncPresent || ev.User.UID != testserver.UserUID(i) || ev.EntryUUID == "" {
+			t.Errorf("Initial event %d = %s %s %q, want %s present with an entry UUID", i, ev.Kind, ev.User.UID, ev.EntryUUID, testserver.UserUID(i))
+		}
+	}
+
+	srv.SetAttribute(testserver.UserDN(1), "title", "Distinguished Engineer")
+	if ev := nextSyncEvent(t, events); ev.Kind != ldap_redhat.SyncModify || ev.User.Title != "Distinguished Engineer" || len(ev.Cookie) == 0 {
+		t.Errorf("Got %s of %s with title %q and cookie %q, want the new title with a cookie", ev.Kind, ev.User.UID, ev.User.Title, ev.Cookie)
+	}
+	srv.AddEntries([]*ldapserver.Entry{testserver.NamedUser(10, "newhire")})
+	if ev := nextSyncEvent(t, events); ev.Kind != ldap_redhat.SyncAdd || ev.User.UID != "newhire" {
+		t.Errorf("Got %s of %s, want newhire added", ev.Kind, ev.User.UID)
+	}
+	srv.SetAttribute(testserver.UserDN(2), "rhatTermDate", "20250131000000Z")
+	if ev := nextSyncEvent(t, events); ev.Kind != ldap_redhat.SyncDelete || ev.DN != testserver.UserDN(2) || ev.User.UID != "" {
+		t.Errorf("Got %s of %s, want %s deleted from the results", ev.Kind, ev.DN, testserver.UserDN(2))
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Sync returned %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sync did not return after the context was cancelled")
+	}
+}
+
+func TestSyncResume(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A cookie skips the initial content
+	resumed := ldap_redhat.WithSearchOptions(ctx, ldap_redhat.SearchOptions{SyncCookie: []byte("0")})
+	events, _ := startSync(t, resumed, searcher)
+	time.Sleep(50 * time.Millisecond) // let the search reach the persist phase
+	srv.SetAttribute(testserver.UserDN(3), "title", "Manager")
+	if ev := nextSyncEvent(t, events); ev.Kind != ldap_redhat.SyncModify || ev.User.UID != testserver.UserUID(3) {
+		t.Errorf("Got %s of %s first, want only the change since the cookie", ev.Kind, ev.User.UID)
+	}
+}
+
+func TestSyncPersistentSearch(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 3)
+	srv.DisableControl(ldap.ControlTypeSyncRequest)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := startSync(t, ctx, searcher)
+
+	for i := range 3 {
+		if ev := nextSyncEvent(t, events); ev.Kind != ldap_redhat.SyncPresent || ev.User.UID != testserver.UserUID(i) {
+			t.Errorf("Initial event %d = %s %s, want %s present", i, ev.Kind, ev.User.UID, testserver.UserUID(i))
+		}
+	}
+	srv.SetAttribute(testserver.UserDN(0), "rhatCostCenter", "999")
+	if ev := nextSyncEvent(t, events); ev.Kind != ldap_redhat.SyncModify || ev.User.CostCenter != "999" || ev.Cookie != nil {
+		t.Errorf("Got %s with cost center %q and cookie %q, want the new cost center without a cookie", ev.Kind, ev.User.CostCenter, ev.Cookie)
+	}
+}
+
+func TestSyncUnsupported(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 3)
+	srv.DisableControl(ldap.ControlTypeSyncRequest)
+	srv.DisableControl(ldap_redhat.PersistentSearchControlOID)
+
+	err := searcher.Sync(context.Background(), "", func(ldap_redhat.SyncEvent) error {
+		t.Error("Handler called without change notification support")
+		return nil
+	})
+	if !errors.Is(err, ldap_redhat.ErrSyncUnsupported) {
+		t.Errorf("Sync returned %v, want ErrSyncUnsupported", err)
+	}
+}
+
+func TestSyncHandlerError(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 3)
+	stop := errors.New("stop")
+	calls := 0
+	err := searcher.Sync(context.Background(), "", func(ldap_redhat.SyncEvent) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("Sync returned %v after %d calls, want the handler's error after one", err, calls)
+	}
+}
@@ -0,0 +1,143 @@
+#!/bin/sh
+# Runs the test suite against each go-ldap version and directory server.
+#
+#   MATRIX_GO_LDAP_VERSIONS  go-ldap/v3 versions (default: "required latest",
+#                            the go.mod version and the newest release). The
+#                            go.mod version is the oldest supported one, so
+#                            "required" tests the minimum.
+#   MATRIX_SERVERS           servers (default: "embedded openldap 389ds")
+#   MATRIX_USERS             generated users seeded into real servers (1200)
+#   MATRIX_REDIS_VERSIONS    Redis images rediscache is tested against
+#                            (default: "redis:6.2 redis:7.4"; "" to skip)
+#   CONTAINER_RUNTIME        podman or docker (default: whichever is installed)
+#
+# "embedded" runs the whole suite against the in-process server. OpenLDAP and
+# 389 Directory Server are started in containers, seeded with the same
+# fixtures by test/matrix/seed, and run the integration and compatibility
+# tests. rediscache then runs TestRedisServer against each Redis version,
+# authenticating with a password and with an ACL user. Exits non-zero if any
+# combination failed.
+set -u
+
+cd "$(dirname "$0")/../.."
+
+versions=${MATRIX_GO_LDAP_VERSIONS:-"required latest"}
+servers=${MATRIX_SERVERS:-"embedded openldap 389ds"}
+users=${MATRIX_USERS:-1200}
+redis_versions=${MATRIX_REDIS_VERSIONS-"redis:6.2 redis:7.4"}
+runtime=${CONTAINER_RUNTIME:-$(command -v podman || command -v docker)}
+module=github.com/go-ldap/ldap/v3
+
+openldap_image=docker.io/osixia/openldap:1.5.0
+dirsrv_image=quay.io/389ds/dirsrv:latest
+openldap_port=13389
+dirsrv_port=13390
+redis_port=16379
+
+workdir=$(mktemp -d)
+containers=""
+cleanup() {
+	for c in $containers; do
+		"$runtime" rm -f "$c" >/dev/null 2>&1
+	done
+	rm -rf "$workdir"
+}
+trap cleanup EXIT INT TERM
+
+# start_server starts server $1 in a container named matrix-$1 and seeds it,
+# printing the variables the tests need
+start_server() {
+	case $1 in
+	openldap)
+		"$runtime" run -d --rm --name matrix-openldap -p 127.0.0.1:$openldap_port:389 \
+			-e LDAP_DOMAIN=redhat.com -e LDAP_ADMIN_PASSWORD=admin \
+			-e LDAP_CONFIG_PASSWORD=config -e LDAP_TLS=false \
+			-e LDAP_READONLY_USER=true -e LDAP_READONLY_USER_USERNAME=readonly \
+			-e LDAP_READONLY_USER_PASSWORD=readonly \
+			"$openldap_image" >/dev/null || return 1
+		url=ldap://127.0.0.1:$openldap_port
+		go run $modflag ./test/matrix/seed -server openldap -url "$url" \
+			-bind-dn cn=admin,dc=redhat,dc=com -password admin \
+			-config-password config -users "$users" >&2 || return 1
+		;;
+	389ds)
+		"$runtime" run -d --rm --name matrix-389ds -p 127.0.0.1:$dirsrv_port:3389 \
+			-e DS_DM_PASSWORD=admin -e DS_SUFFIX_NAME=dc=redhat,dc=com \
+			"$dirsrv_image" >/dev/null || return 1
+		url=ldap://127.0.0.1:$dirsrv_port
+		go run $modflag ./test/matrix/seed -server 389ds -url "$url" \
+			-bind-dn "cn=Directory Manager" -password admin \
+			-users "$users" -wait 3m >&2 || return 1
+		;;
+	*)
+		echo "unknown server $1" >&2
+		return 1
+		;;
+	esac
+	echo "LDAP_URL=$url"
+}
+
+failed=""
+for version in $versions; do
+	modflag=""
+	if [ "$version" != required ]; then
+		cp go.mod "$workdir/go.mod"
+		cp go.sum "$workdir/go.sum"
+		if ! go get -modfile="$workdir/go.mod" "$module@$version" >/dev/null; then
+			failed="$failed $version/go-get"
+			continue
+		fi
+		modflag="-modfile=$workdir/go.mod"
+	fi
+	resolved=$(go list $modflag -m -f '{{.Version}}' "$module")
+
+	for server in $servers; do
+		echo "=== go-ldap $resolved, $server"
+		if [ "$server" = embedded ]; then
+			# LDAP_URL unset: the suite starts its own embedded directory
+			if ! env -u LDAP_URL go test $modflag -count=1 ./...; then
+				failed="$failed $resolved/$server"
+			fi
+			continue
+		fi
+		containers="$containers matrix-$server"
+		if ! vars=$(start_server "$server"); then
+			failed="$failed $resolved/$server"
+		elif ! env $vars LDAP_BIND_DN=cn=readonly,dc=redhat,dc=com LDAP_PASSWORD=readonly \
+			LDAP_PASSWORD_FILE= LDAP_BASE_DN=dc=redhat,dc=com LDAP_START_TLS=false \
+			LDAP_TEST_FIXTURES="$users" INTEGRATION_TESTS=true \
+			TEST_LDAP_UID=user000001 TEST_LDAP_EMAIL=user000001@redhat.com \
+			TEST_LDAP_MANAGER_UID=user000000 \
+			go test $modflag -count=1 -run 'TestLDAPIntegration|TestDirectoryCompatibility' -v . ; then
+			failed="$failed $resolved/$server"
+		fi
+		"$runtime" rm -f "matrix-$server" >/dev/null 2>&1
+		containers=$(echo "$containers" | sed "s/ matrix-$server//")
+	done
+done
+
+for image in $redis_versions; do
+	echo "=== rediscache, $image"
+	containers="$containers matrix-redis"
+	if ! "$runtime" run -d --rm --name matrix-redis -p 127.0.0.1:$redis_port:6379 \
+		"docker.io/library/$image" redis-server --requirepass matrix \
+		--user cache on '>cache' '~*' '+@all' >/dev/null; then
+		failed="$failed $image"
+		continue
+	fi
+	sleep 2
+	for auth in "REDIS_PASSWORD=matrix" "REDIS_USERNAME=cache REDIS_PASSWORD=cache"; do
+		if ! (cd rediscache && env REDIS_ADDR=127.0.0.1:$redis_port $auth \
+			go test -count=1 -run TestRedisServer -v .); then
+			failed="$failed $image/${auth%% *}"
+		fi
+	done
+	"$runtime" rm -f matrix-redis >/dev/null 2>&1
+	containers=$(echo "$containers" | sed "s/ matrix-redis//")
+done
+
+if [ -n "$failed" ]; then
+	echo "FAILED:$failed"
+	exit 1
+fi
+echo "All combinations passed"
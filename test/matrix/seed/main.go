@@ -0,0 +1,243 @@
+// Command seed loads the test suite's fixture directory into a real LDAP
+// server, so that test/matrix/run.sh can run the integration tests against
+// OpenLDAP and 389 Directory Server. It adds the Red Hat schema the fixtures
+// need, the base and ou=users entries, a read-only account for the tests,
+// and the generated users. Entries that already exist are left alone, so it
+// can be run again against the same server.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+const baseDN = "dc=redhat,dc=com"
+
+func main() {
+	server := flag.String("server", "", "server implementation: openldap or 389ds")
+	url := flag.String("url", "", "LDAP URL of the server")
+	bindDN := flag.String("bind-dn", "", "DN of the directory administrator")
+	password := flag.String("password", "", "password of the directory administrator")
+	configDN := flag.String("config-dn", "cn=admin,cn=config", "DN of the cn=config administrator (openldap)")
+	configPassword := flag.String("config-password", "", "password of the cn=config administrator (openldap)")
+	readerDN := flag.String("reader-dn", "cn=readonly,"+baseDN, "DN of the read-only account the tests bind as")
+	readerPassword := flag.String("reader-password", "readonly", "password of the read-only account")
+	users := flag.Int("users", 1200, "number of generated users; more than 500 spans several pages")
+	wait := flag.Duration("wait", time.Minute, "how long to wait for the server to accept binds")
+	flag.Parse()
+
+	if *server != "openldap" && *server != "389ds" || *url == "" || *bindDN == "" {
+		flag.Usage()
+		log.Fatal("-server, -url and -bind-dn are required")
+	}
+
+	conn, err := bindWhenReady(*url, *bindDN, *password, *wait)
+	if err != nil {
+		log.Fatalf("Failed to bind as %s: %v", *bindDN, err)
+	}
+	defer conn.Close()
+
+	attrs, classes, err := missingSchema(conn)
+	if err != nil {
+		log.Fatalf("Failed to read the server schema: %v", err)
+	}
+	switch *server {
+	case "openldap":
+		config, err := bindWhenReady(*url, *configDN, *configPassword, *wait)
+		if err != nil {
+			log.Fatalf("Failed to bind as %s: %v", *configDN, err)
+		}
+		defer config.Close()
+		if err := addOpenLDAPSchema(config, attrs, classes); err != nil {
+			log.Fatalf("Failed to add the schema: %v", err)
+		}
+		// The default limits end paged searches after 500 entries
+		if err := raiseOpenLDAPLimits(config, *readerDN); err != nil {
+			log.Fatalf("Failed to raise the size limit of %s: %v", *readerDN, err)
+		}
+	case "389ds":
+		if err := add389DSSchema(conn, attrs, classes); err != nil {
+			log.Fatalf("Failed to add the schema: %v", err)
+		}
+	}
+
+	if err := addEntries(conn, *server, *readerDN, *readerPassword, *users); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Seeded %s with %d users under %s\n", *url, *users+1, testserver.UsersBaseDN)
+}
+
+// bindWhenReady dials and binds, retrying until wait has passed while the
+// server starts
+func bindWhenReady(url, dn, password string, wait time.Duration) (*ldap.Conn, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		conn, err := ldap.DialURL(url)
+		if err == nil {
+			if err = conn.Bind(dn, password); err == nil {
+				return conn, nil
+			}
+			conn.Close()
+		}
+		if time.Now().After(deadline) || ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// schemaName matches the first NAME of an RFC 4512 definition
+var schemaName = regexp.MustCompile(`NAME\s+\(?\s*'([^']+)'`)
+
+// missingSchema returns the fixture attribute types and object classes the
+// server's subschema does not define by name
+func missingSchema(conn *ldap.Conn) (attrs, classes []string, err error) {
+	root, err := conn.Search(ldap.NewSearchRequest(
+		"", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", []string{"subschemaSubentry"}, nil,
+	))
+	if err != nil || len(root.Entries) != 1 {
+		return nil, nil, fmt.Errorf("root DSE: %v", err)
+	}
+	subschema := root.Entries[0].GetAttributeValue("subschemaSubentry")
+	result, err := conn.Search(ldap.NewSearchRequest(
+		subschema, ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=subschema)", []string{"attributeTypes", "objectClasses"}, nil,
+	))
+	if err != nil || len(result.Entries) != 1 {
+		return nil, nil, fmt.Errorf("%s: %v", subschema, err)
+	}
+	entry := result.Entries[0]
+	missing := func(defined, wanted []string) []string {
+		names := map[string]bool{}
+		for _, d := range defined {
+			if m := schemaName.FindStringSubmatch(d); m != nil {
+				names[strings.ToLower(m[1])] = true
+			}
+		}
+		var out []string
+		for _, w := range wanted {
+			if !names[strings.ToLower(schemaName.FindStringSubmatch(w)[1])] {
+				out = append(out, w)
+			}
+		}
+		return out
+	}
+	return missing(entry.GetEqualFoldAttributeValues("attributeTypes"), ldapserver.SchemaAttributeTypes),
+		missing(entry.GetEqualFoldAttributeValues("objectClasses"), ldapserver.SchemaObjectClasses),
+		nil
+}
+
+// addOpenLDAPSchema adds the definitions as a cn=config schema entry
+func addOpenLDAPSchema(conn *ldap.Conn, attrs, classes []string) error {
+	if len(attrs) == 0 && len(classes) == 0 {
+		return nil
+	}
+	req := ldap.NewAddRequest("cn=rhat,cn=schema,cn=config", nil)
+	req.Attribute("objectClass", []string{"olcSchemaConfig"})
+	req.Attribute("cn", []string{"rhat"})
+	if len(attrs) > 0 {
+		req.Attribute("olcAttributeTypes", attrs)
+	}
+	if len(classes) > 0 {
+		req.Attribute("olcObjectClasses", classes)
+	}
+	return ignoreCode(conn.Add(req), ldap.LDAPResultEntryAlreadyExists)
+}
+
+// raiseOpenLDAPLimits lifts the size limits of dn on the first database
+func raiseOpenLDAPLimits(conn *ldap.Conn, dn string) error {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		"cn=config", ldap.ScopeSingleLevel, ldap.NeverDerefAliases,
+		0, 0, false, "(olcSuffix="+ldap.EscapeFilter(baseDN)+")", []string{"1.1"}, nil,
+	))
+	if err != nil {
+		return err
+	}
+	if len(result.Entries) != 1 {
+		return fmt.Errorf("no database with suffix %s", baseDN)
+	}
+	req := ldap.NewModifyRequest(result.Entries[0].DN, nil)
+	req.Add("olcLimits", []string{fmt.Sprintf("dn.exact=%q size=unlimited time=unlimited", dn)})
+	return ignoreCode(conn.Modify(req), ldap.LDAPResultAttributeOrValueExists)
+}
+
+// add389DSSchema adds the definitions to cn=schema
+func add389DSSchema(conn *ldap.Conn, attrs, classes []string) error {
+	if len(attrs) == 0 && len(classes) == 0 {
+		return nil
+	}
+	req := ldap.NewModifyRequest("cn=schema", nil)
+	if len(attrs) > 0 {
+		req.Add("attributeTypes", attrs)
+	}
+	if len(classes) > 0 {
+		req.Add("objectClasses", classes)
+	}
+	return conn.Modify(req)
+}
+
+// addEntries adds the base entries, the read-only account and the fixtures
+func addEntries(conn *ldap.Conn, server, readerDN, readerPassword string, users int) error {
+	base := &ldapserver.Entry{DN: baseDN, Attrs: map[string][]string{
+		"objectClass": {"top", "dcObject", "organization"},
+		"dc":          {"redhat"},
+		"o":           {"Red Hat"},
+	}}
+	reader := &ldapserver.Entry{DN: readerDN, Attrs: map[string][]string{
+		"objectClass":  {"top", "person"},
+		"cn":           {strings.TrimPrefix(strings.SplitN(readerDN, ",", 2)[0], "cn=")},
+		"sn":           {"Read-only test account"},
+		"userPassword": {readerPassword},
+	}}
+	entries := []*ldapserver.Entry{base, reader, {DN: testserver.UsersBaseDN, Attrs: map[string][]string{
+		"objectClass": {"top", "organizationalUnit"},
+		"ou":          {"users"},
+	}}}
+	entries = append(entries, testserver.GenerateUsers(users)...)
+	entries = append(entries, testserver.NamedUser(users, "jemedina"))
+
+	for _, e := range entries {
+		req := ldap.NewAddRequest(e.DN, nil)
+		for name, values := range e.Attrs {
+			// Operational, maintained by the server
+			if strings.EqualFold(name, "modifyTimestamp") {
+				continue
+			}
+			req.Attribute(name, values)
+		}
+		if err := ignoreCode(conn.Add(req), ldap.LDAPResultEntryAlreadyExists); err != nil {
+			return fmt.Errorf("failed to add %s: %w", e.DN, err)
+		}
+	}
+
+	// 389 Directory Server denies reads without an ACI
+	if server == "389ds" {
+		aci := fmt.Sprintf(`(targetattr="*")(version 3.0; acl "test/matrix read"; allow (read, search, compare) userdn="ldap:///%s";)`, readerDN)
+		req := ldap.NewModifyRequest(baseDN, nil)
+		req.Add("aci", []string{aci})
+		if err := ignoreCode(conn.Modify(req), ldap.LDAPResultAttributeOrValueExists); err != nil {
+			return fmt.Errorf("failed to grant %s read access: %w", readerDN, err)
+		}
+	}
+	return nil
+}
+
+// ignoreCode returns nil if err is an LDAP result with one of codes
+func ignoreCode(err error, codes ...uint16) error {
+	var ldapErr *ldap.Error
+	if errors.As(err, &ldapErr) && slices.Contains(codes, ldapErr.ResultCode) {
+		return nil
+	}
+	return err
+}
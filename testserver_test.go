@@ -0,0 +1,57 @@
+package ldap_redhat_test
+
+import (
+	"os"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+const (
+	embeddedBindDN   = "uid=svc,ou=users,dc=redhat,dc=com"
+	embeddedPassword = "secret"
+)
+
+// startEmbeddedServer starts an embedded LDAP server seeded with n generated
+// users, cleaned up with t. Faults listed in LDAP_TEST_FAULTS (see
+// ldapserver.ParseFaults) are injected, to run the suite against a failing
+// directory.
+func startEmbeddedServer(t testing.TB, n int) *ldapserver.Server {
+	t.Helper()
+	faults, err := ldapserver.ParseFaults(os.Getenv("LDAP_TEST_FAULTS"))
+	if err != nil {
+		t.Fatalf("Invalid LDAP_TEST_FAULTS: %v", err)
+	}
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(n))
+	srv.AddBind(embeddedBindDN, embeddedPassword)
+	for op, f := range faults {
+		srv.InjectFault(op, f)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Failed to start embedded LDAP server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// newEmbeddedSearcher starts an embedded LDAP server seeded with n generated
+// users and returns a searcher connected to it. Both are cleaned up with t.
+func newEmbeddedSearcher(t testing.TB, n int) (*ldap_redhat.Searcher, *ldapserver.Server) {
+	t.Helper()
+	srv := startEmbeddedServer(t, n)
+
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BaseDN:      testserver.UsersBaseDN,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	t.Cleanup(func() { searcher.Close() })
+	return searcher, srv
+}
@@ -0,0 +1,143 @@
+package ldap_redhat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ParseLDAPTime parses an LDAP GeneralizedTime value (RFC 4517), such as
+// rhatHireDate or modifyTimestamp, and returns the instant in UTC.
+//
+// Minutes and seconds are optional, the last component may carry a fraction
+// ("20220711070000.5Z", "2022071107,25Z"), and the zone may be "Z" or a
+// numeric offset ("+0530", "-05"). A missing zone is treated as UTC. An empty
+// value returns the zero time and no error, since optional dates such as
+// rhatTermDate are usually absent.
+func ParseLDAPTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	p := timeParser{s: value}
+	year := p.digits(4)
+	month := p.digits(2)
+	day := p.digits(2)
+	hour := p.digits(2)
+	if p.err != nil {
+		return time.Time{}, fmt.Errorf("invalid LDAP time %q: %w", value, p.err)
+	}
+
+	// Minutes and seconds are optional; the fraction applies to the last unit present
+	minute, second := 0, 0
+	unit := time.Hour
+	if p.peekDigit() {
+		minute = p.digits(2)
+		unit = time.Minute
+		if p.peekDigit() {
+			second = p.digits(2)
+			unit = time.Second
+		}
+	}
+	var frac time.Duration
+	if p.peek() == '.' || p.peek() == ',' {
+		p.pos++
+		frac = p.fraction(unit)
+	}
+
+	loc := time.UTC
+	switch c := p.peek(); c {
+	case 0:
+	case 'Z', 'z':
+		p.pos++
+	case '+', '-':
+		p.pos++
+		offHour := p.digits(2)
+		offMin := 0
+		if p.peekDigit() {
+			offMin = p.digits(2)
+		}
+		if p.err == nil && (offHour > 23 || offMin > 59) {
+			p.err = fmt.Errorf("offset out of range")
+		}
+		offset := offHour*3600 + offMin*60
+		if c == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	default:
+		p.err = fmt.Errorf("unexpected character %q", c)
+	}
+	if p.err == nil && p.pos != len(p.s) {
+		p.err = fmt.Errorf("trailing data %q", p.s[p.pos:])
+	}
+	if p.err != nil {
+		return time.Time{}, fmt.Errorf("invalid LDAP time %q: %w", value, p.err)
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	if t.Month() != time.Month(month) || t.Day() != day || t.Hour() != hour || t.Minute() != minute || t.Second() != second {
+		return time.Time{}, fmt.Errorf("invalid LDAP time %q: date out of range", value)
+	}
+	return t.Add(frac).UTC(), nil
+}
+
+// FormatLDAPTime formats t as a GeneralizedTime in UTC, the form used in
+// LDAP filters such as (modifyTimestamp>=...).
+func FormatLDAPTime(t time.Time) string {
+	return t.UTC().Format("20060102150405Z")
+}
+
+// timeParser is a small cursor over a GeneralizedTime string; the first error
+// sticks and later reads become no-ops.
+type timeParser struct {
+	s   string
+	pos int
+	err error
+}
+
+func (p *timeParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *timeParser) peekDigit() bool {
+	c := p.peek()
+	return c >= '0' && c <= '9'
+}
+
+func (p *timeParser) digits(n int) int {
+	if p.err != nil {
+		return 0
+	}
+	if p.pos+n > len(p.s) {
+		p.err = fmt.Errorf("too short")
+		return 0
+	}
+	v, err := strconv.Atoi(p.s[p.pos : p.pos+n])
+	if err != nil || p.s[p.pos] == '+' || p.s[p.pos] == '-' {
+		p.err = fmt.Errorf("expected %d digits at offset %d", n, p.pos)
+		return 0
+	}
+	p.pos += n
+	return v
+}
+
+func (p *timeParser) fraction(unit time.Duration) time.Duration {
+	if p.err != nil {
+		return 0
+	}
+	start := p.pos
+	for p.peekDigit() {
+		p.pos++
+	}
+	if p.pos == start {
+		p.err = fmt.Errorf("empty fraction")
+		return 0
+	}
+	f, _ := strconv.ParseFloat("0."+p.s[start:p.pos], 64)
+	return time.Duration(math.Round(f * float64(unit)))
+}
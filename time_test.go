@@ -0,0 +1,79 @@
+package ldap_redhat_test
+
+import (
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestParseLDAPTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"20220711070000Z", time.Date(2022, 7, 11, 7, 0, 0, 0, time.UTC)},
+		{"20220711070000z", time.Date(2022, 7, 11, 7, 0, 0, 0, time.UTC)},
+		{"20220711070000.5Z", time.Date(2022, 7, 11, 7, 0, 0, 500000000, time.UTC)},
+		{"20220711070000,123456789Z", time.Date(2022, 7, 11, 7, 0, 0, 123456789, time.UTC)},
+		{"202207110700Z", time.Date(2022, 7, 11, 7, 0, 0, 0, time.UTC)},
+		{"2022071107Z", time.Date(2022, 7, 11, 7, 0, 0, 0, time.UTC)},
+		{"2022071107.25Z", time.Date(2022, 7, 11, 7, 15, 0, 0, time.UTC)},
+		{"202207110700.5Z", time.Date(2022, 7, 11, 7, 0, 30, 0, time.UTC)},
+		{"20220711070000+0530", time.Date(2022, 7, 11, 1, 30, 0, 0, time.UTC)},
+		{"20220711070000-05", time.Date(2022, 7, 11, 12, 0, 0, 0, time.UTC)},
+		{"20220711070000.5-0800", time.Date(2022, 7, 11, 15, 0, 0, 500000000, time.UTC)},
+		{"20220711070000", time.Date(2022, 7, 11, 7, 0, 0, 0, time.UTC)},
+		{"20240229235959Z", time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC)},
+		{"", time.Time{}},
+	}
+
+	for _, test := range tests {
+		got, err := ldap_redhat.ParseLDAPTime(test.input)
+		if err != nil {
+			t.Errorf("ParseLDAPTime(%q) returned error: %v", test.input, err)
+			continue
+		}
+		if !got.Equal(test.expected) {
+			t.Errorf("ParseLDAPTime(%q) = %v, expected %v", test.input, got, test.expected)
+		}
+		if !got.IsZero() && got.Location() != time.UTC {
+			t.Errorf("ParseLDAPTime(%q) should return UTC, got %v", test.input, got.Location())
+		}
+	}
+}
+
+func TestParseLDAPTimeInvalid(t *testing.T) {
+	invalid := []string{
+		"2022",
+		"2022-07-11",
+		"20221311070000Z",
+		"20230229000000Z",
+		"20220711250000Z",
+		"20220711076000Z",
+		"20220711070000.Z",
+		"20220711070000+2500",
+		"20220711070000+05:30",
+		"20220711070000ZZ",
+		"2022071107000Q",
+		"+0220711070000Z",
+	}
+
+	for _, input := range invalid {
+		if got, err := ldap_redhat.ParseLDAPTime(input); err == nil {
+			t.Errorf("ParseLDAPTime(%q) should fail, got %v", input, got)
+		}
+	}
+}
+
+func TestFormatLDAPTime(t *testing.T) {
+	ts := time.Date(2022, 7, 11, 9, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	if got := ldap_redhat.FormatLDAPTime(ts); got != "20220711070000Z" {
+		t.Errorf("FormatLDAPTime = %q, expected 20220711070000Z", got)
+	}
+
+	parsed, err := ldap_redhat.ParseLDAPTime(ldap_redhat.FormatLDAPTime(ts))
+	if err != nil || !parsed.Equal(ts) {
+		t.Errorf("Round trip failed: %v, %v", parsed, err)
+	}
+}
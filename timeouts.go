@@ -0,0 +1,65 @@
+package ldap_redhat
+
+import (
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Timeouts used when the corresponding Config field is zero
+const (
+	DefaultDialTimeout   = 10 * time.Second
+	DefaultBindTimeout   = 10 * time.Second
+	DefaultSearchTimeout = 60 * time.Second
+)
+
+// timeoutOrDefault returns d, def when d is zero, or no limit (0) when d is
+// negative
+func timeoutOrDefault(d, def time.Duration) time.Duration {
+	switch {
+	case d == 0:
+		return def
+	case d < 0:
+		return 0
+	}
+	return d
+}
+
+func (c Config) dialTimeout() time.Duration {
+	return timeoutOrDefault(c.DialTimeout, DefaultDialTimeout)
+}
+
+func (c Config) bindTimeout() time.Duration {
+	return timeoutOrDefault(c.BindTimeout, DefaultBindTimeout)
+}
+
+func (c Config) searchTimeout() time.Duration {
+	return timeoutOrDefault(c.SearchTimeout, DefaultSearchTimeout)
+}
+
+// timeLimit returns the server-side time limit of searches: TimeLimit, or
+// SearchTimeout when it is zero. A negative TimeLimit means none (0).
+func (c Config) timeLimit() time.Duration {
+	if c.TimeLimit != 0 {
+		return timeoutOrDefault(c.TimeLimit, 0)
+	}
+	return c.searchTimeout()
+}
+
+// applyTimeLimit asks the server to give up on req after the configured
+// time limit, unless the caller set one
+func (c Config) applyTimeLimit(req *ldap.SearchRequest) {
+	setTimeLimit(req, c.timeLimit())
+}
+
+// setTimeLimit asks the server to give up on req after limit, rounded up to
+// whole seconds, unless the caller set a limit or limit is not positive
+func setTimeLimit(req *ldap.SearchRequest, limit time.Duration) {
+	if req.TimeLimit == 0 && limit > 0 {
+		req.TimeLimit = int((limit + time.Second - 1) / time.Second)
+	}
+}
+
+// errRequestTimedOut is the message go-ldap gives a request that outlived
+// the connection's timeout
+const errRequestTimedOut = "ldap: connection timed out"
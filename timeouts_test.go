@@ -0,0 +1,60 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestSearchTimeout(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:   []string{srv.URL()},
+		Username:      embeddedBindDN,
+		Password:      embeddedPassword,
+		BaseDN:        testserver.UsersBaseDN,
+		SearchTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	ctx := context.Background()
+	id := ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(1)}
+
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Delay: 2 * time.Second, Times: 2})
+	start := time.Now()
+	if _, err := searcher.GetUser(ctx, id); !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout from a slow search, got %v", err)
+	}
+	if _, err := searcher.SearchUsers(ctx, ""); !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout from a slow paged search, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the searches to give up after SearchTimeout, took %s", elapsed)
+	}
+}
+
+func TestBindTimeout(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	srv.InjectFault(ldapserver.OpBind, ldapserver.Fault{Delay: 2 * time.Second})
+
+	start := time.Now()
+	_, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		BindTimeout: 100 * time.Millisecond,
+	})
+	if !errors.Is(err, ldap_redhat.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout from a slow bind, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the bind to give up after BindTimeout, took %s", elapsed)
+	}
+}
@@ -0,0 +1,124 @@
+package ldap_redhat
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TLSConfig returns the TLS configuration the searcher uses for ldapURL, over
+// ldaps:// or with StartTLS, e.g. for tools that connect to the same server.
+func (c Config) TLSConfig(ldapURL string) (*tls.Config, error) {
+	return newTLSConfig(c, ldapURL)
+}
+
+// newTLSConfig builds the TLS configuration used for ldaps:// connections and
+// StartTLS. Certificates are verified unless VerifySSL is false; CAFile and
+// CACertPEM replace the system roots and ClientCertFile/ClientKeyFile enable
+// mutual TLS.
+func newTLSConfig(config Config, ldapURL string) (*tls.Config, error) {
+	serverName := config.TLSServerName
+	if serverName == "" {
+		serverName = ExtractHostname(ldapURL)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: !config.VerifySSL,
+		ServerName:         serverName,
+	}
+
+	if config.CAFile != "" || config.CACertPEM != "" {
+		pool, err := loadCertPool(config.CAFile, config.CACertPEM)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key must be configured together")
+		}
+		// Fail fast on unreadable files, then reload on every handshake so a
+		// rotated certificate is picked up by reconnects without a restart.
+		if _, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		certFile, keyFile := config.ClientCertFile, config.ClientKeyFile
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// loadCertPool builds a pool from a PEM file or a directory of PEM files
+// (*.pem, *.crt, *.cer) plus optional inline PEM data. Reading a directory
+// lets operators rotate CAs by dropping new files next to the old ones.
+func loadCertPool(caPath, inlinePEM string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	loaded := false
+
+	if caPath != "" {
+		info, err := os.Stat(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file %s: %w", caPath, err)
+		}
+		files := []string{caPath}
+		if info.IsDir() {
+			files, err = caFilesInDir(caPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file %s: %w", file, err)
+			}
+			if pool.AppendCertsFromPEM(data) {
+				loaded = true
+			} else if !info.IsDir() {
+				return nil, fmt.Errorf("no PEM certificates found in CA file %s", file)
+			}
+		}
+		if !loaded {
+			return nil, fmt.Errorf("no PEM certificates found in CA directory %s", caPath)
+		}
+	}
+
+	if inlinePEM != "" {
+		if !pool.AppendCertsFromPEM([]byte(inlinePEM)) {
+			return nil, fmt.Errorf("no PEM certificates found in CACertPEM")
+		}
+	}
+
+	return pool, nil
+}
+
+// caFilesInDir lists certificate files in dir in lexical order.
+func caFilesInDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA directory %s: %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".pem", ".crt", ".cer":
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
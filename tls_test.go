@@ -0,0 +1,254 @@
+package ldap_redhat_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// startLDAPSServer starts an embedded ldaps:// server with a fresh self-signed
+// certificate and returns it along with the path of the certificate PEM.
+func startLDAPSServer(t *testing.T, clientCAs *x509.CertPool) (*ldapserver.Server, string) {
+	t.Helper()
+	certPEM, keyPEM, err := testserver.NewCertificate("ldap-test")
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if clientCAs != nil {
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	srv := ldapserver.New()
+	srv.AddEntries(testserver.GenerateUsers(5))
+	srv.AddBind(embeddedBindDN, embeddedPassword)
+	if err := srv.StartLDAPS(tlsConfig); err != nil {
+		t.Fatalf("Failed to start LDAPS server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, certPEM, 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	return srv, caFile
+}
+
+func TestNewSearcherLDAPS(t *testing.T) {
+	srv, caFile := startLDAPSServer(t, nil)
+	if !strings.HasPrefix(srv.URL(), "ldaps://") {
+		t.Fatalf("Expected ldaps:// URL, got %s", srv.URL())
+	}
+
+	base := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+	}
+
+	t.Run("UntrustedCertificateRejected", func(t *testing.T) {
+		config := base
+		config.VerifySSL = true
+		searcher, err := ldap_redhat.NewSearcher(config)
+		if err == nil {
+			searcher.Close()
+			t.Fatal("Expected certificate verification error")
+		}
+	})
+
+	t.Run("CustomCAFile", func(t *testing.T) {
+		config := base
+		config.VerifySSL = true
+		config.CAFile = caFile
+		searcher, err := ldap_redhat.NewSearcher(config)
+		if err != nil {
+			t.Fatalf("NewSearcher with CA file failed: %v", err)
+		}
+		defer searcher.Close()
+		if _, ok := searcher.Conn.TLSConnectionState(); !ok {
+			t.Error("Expected a TLS connection for ldaps:// URL")
+		}
+	})
+
+	t.Run("VerificationDisabled", func(t *testing.T) {
+		config := base
+		config.VerifySSL = false
+		searcher, err := ldap_redhat.NewSearcher(config)
+		if err != nil {
+			t.Fatalf("NewSearcher without verification failed: %v", err)
+		}
+		searcher.Close()
+	})
+
+	t.Run("StartTLSRejected", func(t *testing.T) {
+		config := base
+		config.UseStartTLS = true
+		_, err := ldap_redhat.NewSearcher(config)
+		if err == nil || !strings.Contains(err.Error(), "StartTLS cannot be used with ldaps://") {
+			t.Errorf("Expected StartTLS/ldaps conflict error, got %v", err)
+		}
+	})
+
+	t.Run("MissingCAFile", func(t *testing.T) {
+		config := base
+		config.CAFile = "/nonexistent/ca.pem"
+		_, err := ldap_redhat.NewSearcher(config)
+		if err == nil || !strings.Contains(err.Error(), "failed to read CA file") {
+			t.Errorf("Expected CA file error, got %v", err)
+		}
+	})
+}
+
+func TestNewSearcherLDAPSClientCertificate(t *testing.T) {
+	clientCertPEM, clientKeyPEM, err := testserver.NewCertificate("ldap-client")
+	if err != nil {
+		t.Fatalf("Failed to create client certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(clientCertPEM)
+	srv, caFile := startLDAPSServer(t, clientCAs)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, clientCertPEM, 0600); err != nil {
+		t.Fatalf("Failed to write client cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, clientKeyPEM, 0600); err != nil {
+		t.Fatalf("Failed to write client key: %v", err)
+	}
+
+	config := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		VerifySSL:   true,
+		CAFile:      caFile,
+	}
+
+	searcher, err := ldap_redhat.NewSearcher(config)
+	if err == nil {
+		searcher.Close()
+		t.Fatal("Expected failure without a client certificate")
+	}
+
+	config.ClientCertFile = certFile
+	_, err = ldap_redhat.NewSearcher(config)
+	if err == nil || !strings.Contains(err.Error(), "must be configured together") {
+		t.Errorf("Expected incomplete client certificate error, got %v", err)
+	}
+
+	config.ClientKeyFile = keyFile
+	searcher, err = ldap_redhat.NewSearcher(config)
+	if err != nil {
+		t.Fatalf("NewSearcher with client certificate failed: %v", err)
+	}
+	searcher.Close()
+}
+
+func TestNewSearcherLDAPSCADirectoryAndInlinePEM(t *testing.T) {
+	srv, caFile := startLDAPSServer(t, nil)
+	certPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		t.Fatalf("Failed to read CA file: %v", err)
+	}
+
+	// A directory holding an unrelated CA and the server CA, as during rotation
+	otherPEM, _, err := testserver.NewCertificate("old-ca")
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	caDir := t.TempDir()
+	os.WriteFile(filepath.Join(caDir, "01-old.pem"), otherPEM, 0600)
+	os.WriteFile(filepath.Join(caDir, "02-current.crt"), certPEM, 0600)
+	os.WriteFile(filepath.Join(caDir, "README"), []byte("not a certificate"), 0600)
+
+	base := ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		VerifySSL:   true,
+	}
+
+	t.Run("Directory", func(t *testing.T) {
+		config := base
+		config.CAFile = caDir
+		searcher, err := ldap_redhat.NewSearcher(config)
+		if err != nil {
+			t.Fatalf("NewSearcher with CA directory failed: %v", err)
+		}
+		searcher.Close()
+	})
+
+	t.Run("InlinePEM", func(t *testing.T) {
+		config := base
+		config.CACertPEM = string(certPEM)
+		searcher, err := ldap_redhat.NewSearcher(config)
+		if err != nil {
+			t.Fatalf("NewSearcher with inline CA PEM failed: %v", err)
+		}
+		searcher.Close()
+	})
+
+	t.Run("EmptyDirectory", func(t *testing.T) {
+		config := base
+		config.CAFile = t.TempDir()
+		_, err := ldap_redhat.NewSearcher(config)
+		if err == nil || !strings.Contains(err.Error(), "no PEM certificates found in CA directory") {
+			t.Errorf("Expected empty CA directory error, got %v", err)
+		}
+	})
+
+	t.Run("InvalidInlinePEM", func(t *testing.T) {
+		config := base
+		config.CACertPEM = "garbage"
+		_, err := ldap_redhat.NewSearcher(config)
+		if err == nil || !strings.Contains(err.Error(), "CACertPEM") {
+			t.Errorf("Expected inline PEM error, got %v", err)
+		}
+	})
+}
+
+func TestLoadConfigTLSFromEnv(t *testing.T) {
+	vars := map[string]string{
+		"LDAP_ENV":              "test",
+		"LDAP_TLS_SERVER_NAME":  "ldap.corp.redhat.com",
+		"LDAP_CA_FILE":          "/etc/pki/ldap/ca.pem",
+		"LDAP_CLIENT_CERT_FILE": "/etc/pki/ldap/client.crt",
+		"LDAP_CLIENT_KEY_FILE":  "/etc/pki/ldap/client.key",
+	}
+	for k, v := range vars {
+		original, had := os.LookupEnv(k)
+		os.Setenv(k, v)
+		defer func(k, original string, had bool) {
+			if had {
+				os.Setenv(k, original)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k, original, had)
+	}
+
+	config := ldap_redhat.LoadConfigFromAll()
+	if config.TLSServerName != "ldap.corp.redhat.com" {
+		t.Errorf("Expected TLSServerName from env, got %q", config.TLSServerName)
+	}
+	if config.CAFile != "/etc/pki/ldap/ca.pem" {
+		t.Errorf("Expected CAFile from env, got %q", config.CAFile)
+	}
+	if config.ClientCertFile != "/etc/pki/ldap/client.crt" || config.ClientKeyFile != "/etc/pki/ldap/client.key" {
+		t.Errorf("Expected client cert/key from env, got %q/%q", config.ClientCertFile, config.ClientKeyFile)
+	}
+}
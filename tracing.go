@@ -0,0 +1,61 @@
+package ldap_redhat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName identifies the library's spans
+const tracerName = "github.com/openshift-eng/go-ldap-redhat"
+
+// noopTracer is used when Config.EnableTracing is off
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// tracer returns the tracer for spans around directory operations
+func (c Config) tracer() trace.Tracer {
+	if !c.EnableTracing {
+		return noopTracer
+	}
+	tp := c.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName, trace.WithInstrumentationVersion(Version))
+}
+
+// startSpan starts a client span for a directory operation
+func startSpan(ctx context.Context, config Config, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return config.tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// endSpan records err, if any, and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// searchSpanAttributes describes req without exposing the filter, which can
+// contain email addresses and other personal data. The hash still lets slow
+// queries with the same filter be grouped.
+func searchSpanAttributes(req *ldap.SearchRequest) []attribute.KeyValue {
+	sum := sha256.Sum256([]byte(req.Filter))
+	return []attribute.KeyValue{
+		attribute.String("ldap.base_dn", req.BaseDN),
+		attribute.String("ldap.scope", ldap.ScopeMap[req.Scope]),
+		attribute.String("ldap.filter.hash", hex.EncodeToString(sum[:8])),
+	}
+}
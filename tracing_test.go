@@ -0,0 +1,142 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordedSpan is a finished span captured by spanRecorder
+type recordedSpan struct {
+	name   string
+	parent trace.SpanContext
+	attrs  map[attribute.Key]attribute.Value
+}
+
+// spanRecorder is a minimal TracerProvider that records span names,
+// parents and attributes
+type spanRecorder struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	spans []recordedSpan
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{r: r}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	r *spanRecorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	span := &recordingSpan{r: t.r, rec: recordedSpan{
+		name:   name,
+		parent: trace.SpanContextFromContext(ctx),
+		attrs:  map[attribute.Key]attribute.Value{},
+	}}
+	span.SetAttributes(cfg.Attributes()...)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordingSpan struct {
+	noop.Span
+	r   *spanRecorder
+	rec recordedSpan
+}
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.rec.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) End(...trace.SpanEndOption) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.spans = append(s.r.spans, s.rec)
+}
+
+func TestTracing(t *testing.T) {
+	srv := startEmbeddedServer(t, 5)
+	recorder := &spanRecorder{}
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:    []string{srv.URL()},
+		Username:       embeddedBindDN,
+		Password:       embeddedPassword,
+		BaseDN:         testserver.UsersBaseDN,
+		EnableTracing:  true,
+		TracerProvider: recorder,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+	uid := testserver.UserUID(3)
+	if _, err := searcher.GetUser(ctx, ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: uid}); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+
+	names := map[string]recordedSpan{}
+	for _, s := range recorder.spans {
+		names[s.name] = s
+	}
+	for _, name := range []string{"ldap.dial", "ldap.bind", "ldap.search"} {
+		if _, ok := names[name]; !ok {
+			t.Errorf("Expected a %s span, got %v", name, recorder.spans)
+		}
+	}
+	search := names["ldap.search"]
+	if search.parent.SpanID() != parent.SpanID() {
+		t.Errorf("Expected the search span to be a child of the caller's span")
+	}
+	if got := search.attrs["ldap.result.count"].AsInt64(); got != 1 {
+		t.Errorf("Expected ldap.result.count 1, got %d", got)
+	}
+	if hash := search.attrs["ldap.filter.hash"].AsString(); hash == "" {
+		t.Error("Expected ldap.filter.hash to be set")
+	}
+	for _, a := range search.attrs {
+		if a.Type() == attribute.STRING && a.AsString() == "(uid="+uid+")" {
+			t.Error("Expected the raw filter not to be recorded")
+		}
+	}
+	if server := names["ldap.dial"].attrs["ldap.server"].AsString(); server != srv.URL() {
+		t.Errorf("Expected ldap.server %s, got %s", srv.URL(), server)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	srv := startEmbeddedServer(t, 1)
+	recorder := &spanRecorder{}
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers:    []string{srv.URL()},
+		Username:       embeddedBindDN,
+		Password:       embeddedPassword,
+		BaseDN:         testserver.UsersBaseDN,
+		TracerProvider: recorder,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create searcher: %v", err)
+	}
+	defer searcher.Close()
+	searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(0)})
+	if len(recorder.spans) != 0 {
+		t.Errorf("Expected no spans without EnableTracing, got %d", len(recorder.spans))
+	}
+}
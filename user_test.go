@@ -1,10 +1,15 @@
 package ldap_redhat_test
 
 import (
+	"context"
+	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+	"gopkg.in/yaml.v3"
 )
 
 // TestUserRecordValidation tests UserRecord field validation
@@ -36,76 +41,6 @@ func TestUserRecordValidation(t *testing.T) {
 	}
 }
 
-// Testldap_redhat.IdentifierValidation tests ldap_redhat.Identifier validation
-func TestIdentifierValidation(t *testing.T) {
-	tests := []struct {
-		name       string
-		identifier ldap_redhat.Identifier
-		valid      bool
-	}{
-		{
-			name:       "Valid UID",
-			identifier: ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "testuser"},
-			valid:      true,
-		},
-		{
-			name:       "Valid Email",
-			identifier: ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "test@redhat.com"},
-			valid:      true,
-		},
-		{
-			name:       "Empty UID",
-			identifier: ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: ""},
-			valid:      false,
-		},
-		{
-			name:       "Empty Email",
-			identifier: ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: ""},
-			valid:      false,
-		},
-		{
-			name:       "Invalid Email Format",
-			identifier: ldap_redhat.Identifier{Type: ldap_redhat.IDTEmail, Value: "notanemail"},
-			valid:      false,
-		},
-		{
-			name:       "Invalid ldap_redhat.Identifier Type",
-			identifier: ldap_redhat.Identifier{Type: 999, Value: "test"},
-			valid:      false,
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			valid := validateIdentifier(test.identifier)
-			if valid != test.valid {
-				t.Errorf("Expected validation result %v for %+v, got %v",
-					test.valid, test.identifier, valid)
-			}
-		})
-	}
-}
-
-// validateldap_redhat.Identifier validates an identifier (helper function for testing)
-func validateIdentifier(id ldap_redhat.Identifier) bool {
-	if id.Value == "" {
-		return false
-	}
-
-	switch id.Type {
-	case ldap_redhat.IDTUID:
-		// UID should not be empty and should be reasonable length
-		return len(id.Value) > 0 && len(id.Value) < 100
-	case ldap_redhat.IDTEmail:
-		// Email should contain @ and be reasonable format
-		return strings.Contains(id.Value, "@") &&
-			strings.Contains(id.Value, ".") &&
-			len(id.Value) > 3
-	default:
-		return false
-	}
-}
-
 // TestUserRecordSerialization tests that UserRecord can be properly serialized
 func TestUserRecordSerialization(t *testing.T) {
 	user := ldap_redhat.UserRecord{
@@ -179,3 +114,172 @@ func TestRedHatSpecificFields(t *testing.T) {
 		t.Errorf("CostCenterDesc should be 'Platform Engineering', got '%s'", user.CostCenterDesc)
 	}
 }
+
+// TestUserRecordIsActive tests IsActive against parsed and raw termination dates
+func TestUserRecordIsActive(t *testing.T) {
+	past := time.Now().Add(-24 * time.Hour)
+	future := time.Now().Add(30 * 24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		user     ldap_redhat.UserRecord
+		expected bool
+	}{
+		{"NoTermDate", ldap_redhat.UserRecord{}, true},
+		{"PastTermDate", ldap_redhat.UserRecord{TermDate: past}, false},
+		{"FutureTermDate", ldap_redhat.UserRecord{TermDate: future}, true},
+		{"RawTermDateOnly", ldap_redhat.UserRecord{RhatTermDate: "20200101000000Z"}, false},
+		{"InvalidRawTermDate", ldap_redhat.UserRecord{RhatTermDate: "soon"}, false},
+	}
+	for _, test := range tests {
+		if got := test.user.IsActive(); got != test.expected {
+			t.Errorf("%s: IsActive() = %v, expected %v", test.name, got, test.expected)
+		}
+	}
+}
+
+// TestGetUserTypedFields tests that dates and the manager UID are parsed on lookup
+func TestGetUserTypedFields(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 20)
+	srv.AddEntry("uid=termed,ou=users,dc=redhat,dc=com", map[string][]string{
+		"uid":            {"termed"},
+		"manager":        {"uid=user000001,ou=users,dc=redhat,dc=com"},
+		"rhatHireDate":   {"20150301120000Z"},
+		"rhatTermDate":   {"20230630000000Z"},
+		"rhatAdjSvcDate": {"not-a-date"},
+	})
+
+	user, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: "termed"})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if user.ManagerUID != "user000001" {
+		t.Errorf("ManagerUID should be parsed from the DN, got %q", user.ManagerUID)
+	}
+	if user.ManagerDN != "uid=user000001,ou=users,dc=redhat,dc=com" {
+		t.Errorf("ManagerDN should hold the raw attribute, got %q", user.ManagerDN)
+	}
+	if !user.HireDate.Equal(time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected HireDate: %v", user.HireDate)
+	}
+	if !user.TermDate.Equal(time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected TermDate: %v", user.TermDate)
+	}
+	if !user.AdjServiceDate.IsZero() || user.RhatAdjSvcDate != "not-a-date" {
+		t.Errorf("Invalid dates should stay zero with the raw value kept, got %v / %q", user.AdjServiceDate, user.RhatAdjSvcDate)
+	}
+	if user.IsActive() {
+		t.Error("User with a past term date should not be active")
+	}
+
+	root, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(0)})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if root.ManagerUID != "" || root.ManagerDN != "" {
+		t.Errorf("Top of the tree should have no manager, got %q / %q", root.ManagerUID, root.ManagerDN)
+	}
+	if !root.IsActive() {
+		t.Error("User without a term date should be active")
+	}
+}
+
+func TestGetUserOrgFields(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 10)
+	srv.SetAttribute(testserver.UserDN(9), "rhatBuilding", "RDU Tower 2")
+	srv.SetAttribute(testserver.UserDN(9), "mobile", "+1 919 555 0199")
+
+	user, err := searcher.GetUser(context.Background(), ldap_redhat.Identifier{Type: ldap_redhat.IDTUID, Value: testserver.UserUID(9)})
+	if err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	want := []struct{ name, got, want string }{
+		{"Geo", user.Geo, "EMEA"},
+		{"OrgChartTitle", user.OrgChartTitle, user.Title},
+		{"PersonType", user.PersonType, "Contractor"},
+		{"Building", user.Building, "RDU Tower 2"},
+		{"Mobile", user.Mobile, "+1 919 555 0199"},
+		{"TelephoneNumber", user.TelephoneNumber, "+1 919 555 0009"},
+	}
+	for _, f := range want {
+		if f.got != f.want {
+			t.Errorf("%s = %q, want %q", f.name, f.got, f.want)
+		}
+	}
+}
+
+// TestUserRecordJSON tests field names and omission of empty optional fields
+func TestUserRecordJSON(t *testing.T) {
+	user := ldap_redhat.UserRecord{
+		UID:          "jdoe",
+		Email:        "jdoe@redhat.com",
+		RhatHireDate: "20220711070000Z",
+		HireDate:     time.Date(2022, 7, 11, 7, 0, 0, 0, time.UTC),
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := `{"uid":"jdoe","email":"jdoe@redhat.com","display_name":"","surname":"","title":"","manager_uid":"","rhat_hire_date":"20220711070000Z","hire_date":"2022-07-11T07:00:00Z"}`
+	if string(data) != expected {
+		t.Errorf("Unexpected JSON:\n got: %s\nwant: %s", data, expected)
+	}
+
+	// Pointers and nested values use the same encoding
+	nested, err := json.Marshal(map[string]*ldap_redhat.UserRecord{"user": &user})
+	if err != nil || !strings.Contains(string(nested), `"uid":"jdoe"`) {
+		t.Errorf("Expected nested record to use json tags, got %s (%v)", nested, err)
+	}
+
+	var decoded ldap_redhat.UserRecord
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded != user {
+		t.Errorf("Round trip mismatch: got %+v, expected %+v", decoded, user)
+	}
+}
+
+// TestUserRecordYAML tests YAML field names
+func TestUserRecordYAML(t *testing.T) {
+	data, err := yaml.Marshal(ldap_redhat.UserRecord{UID: "jdoe", Country: "US"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{"uid: jdoe", "country: US", "display_name: \"\""} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"rhat_uuid", "hire_date", "stale"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("Expected YAML to omit %q, got:\n%s", unwanted, out)
+		}
+	}
+}
+
+// TestUserRecordToMap tests ToMap keys, omission and value types
+func TestUserRecordToMap(t *testing.T) {
+	hired := time.Date(2022, 7, 11, 7, 0, 0, 0, time.UTC)
+	m := ldap_redhat.UserRecord{UID: "jdoe", HireDate: hired, IsPeopleManager: true}.ToMap()
+
+	if m["uid"] != "jdoe" {
+		t.Errorf("Expected uid jdoe, got %v", m["uid"])
+	}
+	if got, ok := m["hire_date"].(time.Time); !ok || !got.Equal(hired) {
+		t.Errorf("Expected hire_date to be a time.Time, got %#v", m["hire_date"])
+	}
+	if m["is_people_manager"] != true {
+		t.Errorf("Expected is_people_manager true, got %v", m["is_people_manager"])
+	}
+	if _, ok := m["email"]; !ok {
+		t.Error("Identity fields should always be present")
+	}
+	for _, key := range []string{"rhat_uuid", "term_date", "stale", "cost_center"} {
+		if _, ok := m[key]; ok {
+			t.Errorf("Expected empty %s to be omitted", key)
+		}
+	}
+}
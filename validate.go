@@ -0,0 +1,139 @@
+package ldap_redhat
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Validate checks the configuration without contacting a server: server URL
+// schemes, hosts and ports, DN syntax, TLS settings that conflict or would be
+// ignored, auth mode and password presence, and the settings NewSearcher
+// rejects. It returns nil or a multi-error (see errors.Join) listing every
+// problem found, each matching ErrInvalidConfig and naming the setting to
+// fix.
+func (c Config) Validate() error {
+	var problems []error
+	problem := func(format string, args ...any) {
+		problems = append(problems, newError(ErrInvalidConfig, format, args...))
+	}
+
+	if len(c.LdapServers) == 0 && !c.Offline {
+		problem("no LDAP servers configured: set ldap_servers or LDAP_URL")
+	}
+	usesTLS, usesLDAPI := false, false
+	for _, server := range c.LdapServers {
+		scheme, err := c.validateServer(server)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		switch scheme {
+		case "ldaps":
+			usesTLS = true
+			if c.UseStartTLS {
+				problem("use_start_tls cannot be combined with ldaps:// URL %s: use ldap:// with StartTLS, or ldaps:// without it", server)
+			}
+		case "ldapi":
+			usesLDAPI = true
+			if c.UseStartTLS {
+				problem("use_start_tls cannot be combined with ldapi:// URL %s: the socket is local, disable use_start_tls", server)
+			}
+		case "ldap":
+			usesTLS = usesTLS || c.UseStartTLS
+		}
+	}
+
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		problem("client_cert_file and client_key_file must be set together")
+	}
+	if len(c.LdapServers) > 0 && !usesTLS {
+		for _, setting := range []struct{ name, value string }{
+			{"ca_file", c.CAFile},
+			{"ca_cert_pem", c.CACertPEM},
+			{"client_cert_file", c.ClientCertFile},
+			{"tls_server_name", c.TLSServerName},
+		} {
+			if setting.value != "" {
+				problem("%s is set but no server uses TLS: use an ldaps:// URL or set use_start_tls", setting.name)
+			}
+		}
+	}
+
+	for _, setting := range []struct{ name, dn string }{{"username", c.Username}, {"base_dn", c.BaseDN}, {"deleted_users_base_dn", c.DeletedUsersBaseDN}} {
+		if setting.dn == "" {
+			continue
+		}
+		if _, err := ldap.ParseDN(setting.dn); err != nil {
+			problem("%s %q is not a valid DN (e.g. uid=svc,ou=users,dc=redhat,dc=com): %v", setting.name, setting.dn, err)
+		}
+	}
+
+	mode, err := c.AuthMode.normalize()
+	if err != nil {
+		problems = append(problems, newError(ErrInvalidConfig, "%v", err))
+	}
+	switch {
+	case c.Offline:
+		// the searcher binds to its fixture server with generated credentials
+	case mode == AuthExternal && !usesLDAPI && c.ClientCertFile == "":
+		problem("auth_mode external needs an ldapi:// URL or a TLS client certificate (client_cert_file)")
+	case mode == AuthSimple && c.Username != "" && !c.HasCredentials():
+		problem("bind DN %s has no password: set credentials, password_file, LDAP_CREDENTIALS, LDAP_PASSWORD_FILE or LDAP_PASSWORD", c.Username)
+	case mode == AuthSimple && c.Username == "" && c.HasCredentials():
+		problem("a password is set without a bind DN: set username or LDAP_BIND_DN")
+	}
+
+	if c.OfflineFallback && c.SnapshotFile == "" {
+		problem("offline_fallback needs snapshot_file")
+	}
+	if c.Offline && c.FixturesFile != "" {
+		if _, err := LoadFixtures(c.FixturesFile); err != nil {
+			problem("%v", err)
+		}
+	}
+	if err := checkSearcherConfig(c); err != nil {
+		problems = append(problems, newError(ErrInvalidConfig, "%v", err))
+	}
+	return errors.Join(problems...)
+}
+
+// validateServer checks one server URL and returns its lowercased scheme
+func (c Config) validateServer(server string) (string, error) {
+	if !strings.Contains(server, "://") {
+		return "", newError(ErrInvalidConfig, "ldap_servers: %s has no scheme: use ldap://%s or ldaps://%s", server, server, server)
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", newError(ErrInvalidConfig, "ldap_servers: invalid URL %s: %v", server, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "ldap", "ldaps":
+	case "ldapi":
+		if _, err := LDAPISocketPath(server); err != nil {
+			return "", newError(ErrInvalidConfig, "ldap_servers: %v", err)
+		}
+		return scheme, nil
+	default:
+		return "", newError(ErrInvalidConfig, "ldap_servers: %s has unsupported scheme %q: use ldap://, ldaps:// or ldapi://", server, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", newError(ErrInvalidConfig, "ldap_servers: %s has no host", server)
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return "", newError(ErrInvalidConfig, "ldap_servers: %s has invalid port %q: expected 1-65535", server, p)
+		}
+		if c.Port != 0 && c.Port != port {
+			return "", newError(ErrInvalidConfig, "Port %d differs from the port of %s: Port is ignored, set the port in the URL", c.Port, server)
+		}
+	} else if c.Port != 0 {
+		return "", newError(ErrInvalidConfig, "Port %d is ignored: set the port in the URL, e.g. %s://%s:%d", c.Port, scheme, u.Host, c.Port)
+	}
+	return scheme, nil
+}
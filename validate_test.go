@@ -0,0 +1,65 @@
+package ldap_redhat_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := ldap_redhat.Config{
+		LdapServers: []string{"ldap://ldap.corp.redhat.com:389"},
+		Username:    "uid=svc,ou=users,dc=redhat,dc=com",
+		Credentials: ldap_redhat.StaticPassword("secret"),
+		BaseDN:      "dc=redhat,dc=com",
+		UseStartTLS: true,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate of a valid config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*ldap_redhat.Config)
+		want   []string // substrings of the expected problems, in order
+	}{
+		{"no servers", func(c *ldap_redhat.Config) { c.LdapServers = nil }, []string{"no LDAP servers configured"}},
+		{"missing scheme", func(c *ldap_redhat.Config) { c.LdapServers = []string{"ldap.corp.redhat.com:389"} }, []string{"has no scheme"}},
+		{"bad scheme", func(c *ldap_redhat.Config) { c.LdapServers = []string{"http://ldap.corp.redhat.com"} }, []string{`unsupported scheme "http"`}},
+		{"bad port", func(c *ldap_redhat.Config) { c.LdapServers = []string{"ldap://ldap.corp.redhat.com:70000"} }, []string{"invalid port"}},
+		{"ignored Port", func(c *ldap_redhat.Config) { c.Port = 636 }, []string{"Port 636 differs"}},
+		{"StartTLS with ldaps", func(c *ldap_redhat.Config) { c.LdapServers = []string{"ldaps://ldap.corp.redhat.com"} }, []string{"use_start_tls cannot be combined with ldaps://"}},
+		{"CA without TLS", func(c *ldap_redhat.Config) { c.UseStartTLS, c.CAFile = false, "/etc/ca.pem" }, []string{"ca_file is set but no server uses TLS"}},
+		{"half client cert", func(c *ldap_redhat.Config) { c.ClientCertFile = "/etc/client.pem" }, []string{"must be set together"}},
+		{"bad DNs", func(c *ldap_redhat.Config) { c.Username, c.BaseDN = "svc", "redhat.com" }, []string{"username", "base_dn"}},
+		{"no password", func(c *ldap_redhat.Config) { c.Credentials = nil }, []string{"has no password"}},
+		{"external without cert", func(c *ldap_redhat.Config) { c.AuthMode = ldap_redhat.AuthExternal }, []string{"auth_mode external needs"}},
+		{"offline without snapshot", func(c *ldap_redhat.Config) { c.OfflineFallback = true }, []string{"offline_fallback needs snapshot_file"}},
+		{"every problem", func(c *ldap_redhat.Config) {
+			c.LdapServers = []string{"ldaps://ldap.corp.redhat.com"}
+			c.Credentials = nil
+			c.BaseDN = "redhat.com"
+		}, []string{"use_start_tls", "base_dn", "has no password"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			err := config.Validate()
+			if !errors.Is(err, ldap_redhat.ErrInvalidConfig) {
+				t.Fatalf("Validate() = %v, want ErrInvalidConfig", err)
+			}
+			problems := strings.Split(err.Error(), "\n")
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Validate() reported %d problems, want %d:\n%v", len(problems), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("Problem %d = %q, want it to mention %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
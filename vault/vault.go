@@ -0,0 +1,294 @@
+// Package vault implements ldap_redhat.CredentialSource on HashiCorp Vault,
+// so the bind password is read from a Vault secret instead of living in a
+// file or environment variable. It talks to Vault's HTTP API directly and
+// needs no client library.
+//
+//	source, err := vault.New(vault.Options{
+//		Addr: "https://vault.corp.redhat.com",
+//		Path: "secret/data/ldap/bind",
+//		Role: "ldap-reader", // Kubernetes auth with the pod's service account
+//	})
+//	config.Credentials = source
+//
+// The secret is cached for its lease, or MaxAge for secrets without one such
+// as KV entries, and read again on the first bind after that, so rotated and
+// dynamic credentials are picked up on the next reconnect. The Vault token
+// from Kubernetes auth is renewed by logging in again when it expires or is
+// rejected.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/buildfeature"
+)
+
+var _ ldap_redhat.CredentialSource = (*Source)(nil)
+
+// Importing the package registers the vault credentials provider, so that
+// credentials: vault:secret/data/ldap/bind reads the secret at that path
+// with the Vault settings of FromEnv.
+func init() {
+	buildfeature.Register("vault")
+	ldap_redhat.RegisterCredentialProvider("vault", ldap_redhat.CredentialProviderFunc(func(path string) (ldap_redhat.CredentialSource, error) {
+		opts := optionsFromEnv()
+		opts.Path = path
+		return New(opts)
+	}))
+}
+
+// DefaultServiceAccountTokenFile is the pod's service account token, sent
+// to Vault's Kubernetes auth method
+const DefaultServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// DefaultMaxAge is how long a secret without a lease is cached
+const DefaultMaxAge = 5 * time.Minute
+
+// Options configures a Source
+type Options struct {
+	Addr      string // Vault address, e.g. https://vault.example.com:8200
+	Namespace string // Optional: Vault Enterprise namespace
+	Path      string // secret path, e.g. secret/data/ldap/bind (KV v2) or ldap/static-cred/svc
+	Field     string // key of the password in the secret, "password" by default
+
+	// Token authenticates with a Vault token. When empty, Role logs in with
+	// the Kubernetes auth method instead.
+	Token string
+	// Role is the Kubernetes auth role to log in as
+	Role string
+	// KubernetesMount is where the Kubernetes auth method is enabled,
+	// "kubernetes" by default
+	KubernetesMount string
+	// ServiceAccountTokenFile is the JWT sent to log in,
+	// DefaultServiceAccountTokenFile by default. It is re-read at every login
+	// since projected tokens rotate.
+	ServiceAccountTokenFile string
+
+	MaxAge     time.Duration // how long secrets without a lease are cached, DefaultMaxAge by default
+	HTTPClient *http.Client  // http.DefaultClient by default
+}
+
+// Source reads the bind password from a Vault secret. It is safe for
+// concurrent use.
+type Source struct {
+	opts Options
+
+	mu          sync.Mutex
+	token       string    // client token in use
+	tokenExpiry time.Time // zero for tokens that were given, not logged in
+	password    string
+	expiry      time.Time // when password must be read again
+}
+
+// New returns a Source for opts. It does not contact Vault; the secret is
+// read on the first bind.
+func New(opts Options) (*Source, error) {
+	if opts.Addr == "" {
+		return nil, errors.New("vault: no address")
+	}
+	if opts.Path == "" {
+		return nil, errors.New("vault: no secret path")
+	}
+	if opts.Token == "" && opts.Role == "" {
+		return nil, errors.New("vault: no token or Kubernetes auth role")
+	}
+	if opts.Field == "" {
+		opts.Field = "password"
+	}
+	if opts.KubernetesMount == "" {
+		opts.KubernetesMount = "kubernetes"
+	}
+	if opts.ServiceAccountTokenFile == "" {
+		opts.ServiceAccountTokenFile = DefaultServiceAccountTokenFile
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	opts.Addr = strings.TrimRight(opts.Addr, "/")
+	opts.Path = strings.Trim(opts.Path, "/")
+	return &Source{opts: opts, token: opts.Token}, nil
+}
+
+// FromEnv returns a Source configured from VAULT_ADDR, VAULT_NAMESPACE and
+// VAULT_TOKEN, the variables the vault CLI reads, and LDAP_VAULT_PATH,
+// LDAP_VAULT_FIELD and LDAP_VAULT_ROLE.
+func FromEnv() (*Source, error) {
+	return New(optionsFromEnv())
+}
+
+// optionsFromEnv returns the Options FromEnv uses
+func optionsFromEnv() Options {
+	return Options{
+		Addr:      os.Getenv("VAULT_ADDR"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		Path:      os.Getenv("LDAP_VAULT_PATH"),
+		Field:     os.Getenv("LDAP_VAULT_FIELD"),
+		Role:      os.Getenv("LDAP_VAULT_ROLE"),
+	}
+}
+
+// Password returns the password from the cached secret, reading the secret
+// again once its lease or MaxAge has passed.
+func (s *Source) Password(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.password != "" && time.Now().Before(s.expiry) {
+		return s.password, nil
+	}
+	secret, err := s.read(ctx)
+	if err != nil {
+		return "", err
+	}
+	password, err := secret.field(s.opts.Field)
+	if err != nil {
+		return "", fmt.Errorf("vault: %s: %w", s.opts.Path, err)
+	}
+	s.password = password
+	s.expiry = time.Now().Add(secret.ttl(s.opts.MaxAge))
+	return password, nil
+}
+
+// String names the secret without revealing it
+func (s *Source) String() string {
+	return "vault:" + s.opts.Path
+}
+
+// response is the body of a Vault API response
+type response struct {
+	Data          map[string]any `json:"data"`
+	LeaseDuration int            `json:"lease_duration"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// field returns the string value of key, from data.data for KV v2 secrets
+// and from data for every other engine
+func (r *response) field(key string) (string, error) {
+	data := r.Data
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, kv2 := data["metadata"]; kv2 {
+			data = inner
+		}
+	}
+	value, ok := data[key].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("secret has no %q field", key)
+	}
+	return value, nil
+}
+
+// ttl returns how long the secret may be cached: two thirds of its lease,
+// so that it is re-read well before it expires, or maxAge without a lease
+func (r *response) ttl(maxAge time.Duration) time.Duration {
+	if r.LeaseDuration <= 0 {
+		return maxAge
+	}
+	return time.Duration(r.LeaseDuration) * time.Second * 2 / 3
+}
+
+// read reads the secret, logging in first if needed and once more if the
+// token is rejected
+func (s *Source) read(ctx context.Context) (*response, error) {
+	if err := s.ensureToken(ctx, false); err != nil {
+		return nil, err
+	}
+	resp, status, err := s.do(ctx, http.MethodGet, "/v1/"+s.opts.Path, nil)
+	if status == http.StatusForbidden && s.opts.Role != "" {
+		if err := s.ensureToken(ctx, true); err != nil {
+			return nil, err
+		}
+		resp, _, err = s.do(ctx, http.MethodGet, "/v1/"+s.opts.Path, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vault: failed to read %s: %w", s.opts.Path, err)
+	}
+	return resp, nil
+}
+
+// ensureToken logs in with Kubernetes auth when there is no token, it has
+// expired, or force is set. Given tokens are used as they are.
+func (s *Source) ensureToken(ctx context.Context, force bool) error {
+	if s.opts.Token != "" {
+		return nil
+	}
+	if !force && s.token != "" && (s.tokenExpiry.IsZero() || time.Now().Before(s.tokenExpiry)) {
+		return nil
+	}
+	jwt, err := os.ReadFile(s.opts.ServiceAccountTokenFile)
+	if err != nil {
+		return fmt.Errorf("vault: failed to read service account token: %w", err)
+	}
+	body, _ := json.Marshal(map[string]string{"role": s.opts.Role, "jwt": strings.TrimSpace(string(jwt))})
+	s.token = ""
+	resp, _, err := s.do(ctx, http.MethodPost, "/v1/auth/"+strings.Trim(s.opts.KubernetesMount, "/")+"/login", body)
+	if err != nil {
+		return fmt.Errorf("vault: Kubernetes login as %s failed: %w", s.opts.Role, err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("vault: Kubernetes login as %s returned no token", s.opts.Role)
+	}
+	s.token = resp.Auth.ClientToken
+	s.tokenExpiry = time.Time{}
+	if resp.Auth.LeaseDuration > 0 {
+		s.tokenExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second * 2 / 3)
+	}
+	return nil
+}
+
+// do sends a request to Vault and decodes the response, returning the HTTP
+// status alongside errors so callers can tell a rejected token apart
+func (s *Source) do(ctx context.Context, method, path string, body []byte) (*response, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.opts.Addr+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.token != "" {
+		req.Header.Set("X-Vault-Token", s.token)
+	}
+	if s.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.opts.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpResp, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return nil, httpResp.StatusCode, err
+	}
+	var resp response
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, httpResp.StatusCode, fmt.Errorf("invalid response (HTTP %d): %w", httpResp.StatusCode, err)
+		}
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		if len(resp.Errors) > 0 {
+			return nil, httpResp.StatusCode, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, strings.Join(resp.Errors, "; "))
+		}
+		return nil, httpResp.StatusCode, fmt.Errorf("HTTP %d", httpResp.StatusCode)
+	}
+	return &resp, httpResp.StatusCode, nil
+}
@@ -0,0 +1,147 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/vault"
+)
+
+// fakeVault serves a KV v2 secret and the Kubernetes auth login
+type fakeVault struct {
+	mu       sync.Mutex
+	password string
+	tokens   map[string]bool // valid client tokens
+	logins   int
+	reads    int
+}
+
+func startFakeVault(t *testing.T, password string) (*fakeVault, *httptest.Server) {
+	t.Helper()
+	f := &fakeVault{password: password, tokens: map[string]bool{"root": true}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Role, JWT string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Role != "ldap-reader" || body.JWT != "sa-jwt" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":["invalid role or service account"]}`)
+			return
+		}
+		f.mu.Lock()
+		f.logins++
+		token := fmt.Sprintf("login-%d", f.logins)
+		f.tokens[token] = true
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"auth":{"client_token":%q,"lease_duration":3600}}`, token)
+	})
+	mux.HandleFunc("GET /v1/secret/data/ldap/bind", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !f.tokens[r.Header.Get("X-Vault-Token")] {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		f.reads++
+		fmt.Fprintf(w, `{"data":{"data":{"password":%q},"metadata":{"version":3}},"lease_duration":0}`, f.password)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func TestTokenAuth(t *testing.T) {
+	f, srv := startFakeVault(t, "s3cret")
+	source, err := vault.New(vault.Options{Addr: srv.URL, Token: "root", Path: "secret/data/ldap/bind"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if password, err := source.Password(context.Background()); err != nil || password != "s3cret" {
+			t.Fatalf("Password() = %q, %v, want s3cret", password, err)
+		}
+	}
+	if f.reads != 1 {
+		t.Errorf("Secret read %d times, want once while cached", f.reads)
+	}
+	if s := fmt.Sprint(source); strings.Contains(s, "s3cret") {
+		t.Errorf("Source prints the password: %s", s)
+	}
+}
+
+func TestKubernetesAuth(t *testing.T) {
+	f, srv := startFakeVault(t, "s3cret")
+	jwt := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwt, []byte("sa-jwt\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+	source, err := vault.New(vault.Options{Addr: srv.URL, Role: "ldap-reader", ServiceAccountTokenFile: jwt, Path: "/secret/data/ldap/bind", MaxAge: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx := context.Background()
+	if password, err := source.Password(ctx); err != nil || password != "s3cret" {
+		t.Fatalf("Password() = %q, %v, want s3cret", password, err)
+	}
+
+	// A revoked token is replaced by logging in again, and the rotated
+	// password read with it
+	f.mu.Lock()
+	f.tokens = map[string]bool{}
+	f.password = "rotated"
+	f.mu.Unlock()
+	if password, err := source.Password(ctx); err != nil || password != "rotated" {
+		t.Fatalf("Password() = %q, %v, want rotated", password, err)
+	}
+	if f.logins != 2 {
+		t.Errorf("Logged in %d times, want 2", f.logins)
+	}
+
+	wrong, _ := vault.New(vault.Options{Addr: srv.URL, Role: "wrong", ServiceAccountTokenFile: jwt, Path: "secret/data/ldap/bind"})
+	if _, err := wrong.Password(ctx); err == nil || !strings.Contains(err.Error(), "invalid role") {
+		t.Errorf("Expected the login error, got %v", err)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	for _, opts := range []vault.Options{
+		{Path: "secret/data/ldap", Token: "t"},
+		{Addr: "http://vault", Token: "t"},
+		{Addr: "http://vault", Path: "secret/data/ldap"},
+	} {
+		if _, err := vault.New(opts); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", opts)
+		}
+	}
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	_, srv := startFakeVault(t, "s3cret")
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	source, err := ldap_redhat.ParseCredentials("vault:secret/data/ldap/bind")
+	if err != nil {
+		t.Fatalf("ParseCredentials failed: %v", err)
+	}
+	if password, err := source.Password(context.Background()); err != nil || password != "s3cret" {
+		t.Errorf("Password() = %q, %v, want s3cret", password, err)
+	}
+}
+
+func TestBuildInfoFeature(t *testing.T) {
+	if features := ldap_redhat.BuildInfo().Features; !slices.Contains(features, "vault") {
+		t.Errorf("Expected BuildInfo to list the vault feature, got %v", features)
+	}
+}
@@ -0,0 +1,222 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Virtual list view controls (draft-ietf-ldapext-ldapv3-vlv), which 389
+// Directory Server, Red Hat Directory Server and OpenLDAP's sssvlv overlay
+// implement.
+const (
+	VLVRequestControlOID  = "2.16.840.1.113730.3.4.9"
+	VLVResponseControlOID = "2.16.840.1.113730.3.4.10"
+)
+
+// DefaultVLVPageSize is the page size of SearchUsersPage when
+// VLVOptions.PageSize is zero.
+const DefaultVLVPageSize = 50
+
+// VLVOptions selects a page of SearchUsersPage results by position.
+type VLVOptions struct {
+	Offset   int    // position of the first user of the page in the sorted list, from 0
+	PageSize int    // users per page (0 = DefaultVLVPageSize)
+	SortBy   string // attribute the list is sorted by (default uid), e.g. AttrCN for people pickers
+	Reverse  bool   // sort in descending order
+}
+
+// VLVPage is a page of users at a position in a sorted list the server
+// holds, for "page 7 of 40" navigation.
+type VLVPage struct {
+	Users    []UserRecord `json:"users" yaml:"users"`
+	Offset   int          `json:"offset" yaml:"offset"` // position of the first user, from 0, as the server placed it
+	PageSize int          `json:"page_size" yaml:"page_size"`
+	Total    int          `json:"total" yaml:"total"` // the server's estimate of the list's size
+}
+
+// Page returns the 1-based number of the page
+func (p VLVPage) Page() int {
+	if p.PageSize <= 0 {
+		return 1
+	}
+	return p.Offset/p.PageSize + 1
+}
+
+// Pages returns the number of pages of the list
+func (p VLVPage) Pages() int {
+	if p.PageSize <= 0 {
+		return 1
+	}
+	return max(1, (p.Total+p.PageSize-1)/p.PageSize)
+}
+
+// SearchUsersPage returns the page of users matching an LDAP filter (all
+// users when empty) that starts at opts.Offset in the list sorted by
+// opts.SortBy, using the server-side sort and virtual list view controls.
+// Only the page is transferred, so a people picker can jump to any page of
+// a large directory without fetching everything; Total reports the list's
+// size for page navigation. It fails with ErrVLVUnsupported when the
+// server does not support virtual list views; fall back to SearchUsers.
+// It is experimental and fails with ErrFeatureDisabled unless the FeatureVLV
+// gate is enabled.
+//
+// Each call sorts the list again, so pages reflect the directory when they
+// are fetched, and users added or removed meanwhile shift later pages.
+func (s *Searcher) SearchUsersPage(ctx context.Context, filter string, opts VLVOptions) (VLVPage, error) {
+	page, err := s.searchUsersPage(ctx, filter, opts)
+	s.auditListing(ctx, AuditSearchUsersPage, filter, len(page.Users), err)
+	return page, err
+}
+
+// searchUsersPage is SearchUsersPage without auditing
+func (s *Searcher) searchUsersPage(ctx context.Context, filter string, opts VLVOptions) (VLVPage, error) {
+	if !s.FeatureEnabled(FeatureVLV) {
+		return VLVPage{}, featureDisabled(FeatureVLV)
+	}
+	if s.disconnected() {
+		return VLVPage{}, errNotConnected()
+	}
+	if opts.Offset < 0 {
+		return VLVPage{}, fmt.Errorf("VLV offset %d is negative", opts.Offset)
+	}
+	if filter == "" {
+		filter = defaultSnapshotFilter
+	}
+	size := opts.PageSize
+	if size <= 0 {
+		size = DefaultVLVPageSize
+	}
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = AttrUID
+	}
+	baseDN, err := s.searchBase(ctx)
+	if err != nil {
+		return VLVPage{}, err
+	}
+	req := ldap.NewSearchRequest(
+		baseDN, s.searchScope(ctx), s.derefAliases(ctx),
+		0, 0, false, filter, s.attributes(ctx), []ldap.Control{
+			ldap.NewControlServerSideSortingWithSortKeys([]*ldap.SortKey{{AttributeType: sortBy, Reverse: opts.Reverse}}),
+			&vlvRequestControl{offset: opts.Offset + 1, afterCount: size - 1},
+		},
+	)
+	start := time.Now()
+	result, err := s.search(ctx, req)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultUnavailableCriticalExtension) {
+		return VLVPage{}, vlvUnsupported(err)
+	}
+	if err != nil {
+		return VLVPage{}, wrapLDAPError(err, "LDAP VLV search failed")
+	}
+	ctrl, ok := ldap.FindControl(result.Controls, VLVResponseControlOID).(*ldap.ControlString)
+	if !ok {
+		return VLVPage{}, vlvUnsupported(errors.New("no virtual list view response control"))
+	}
+	resp, err := parseVLVResponse(ctrl.ControlValue)
+	if err != nil {
+		return VLVPage{}, fmt.Errorf("invalid virtual list view response: %w", err)
+	}
+	if resp.result != ldap.LDAPResultSuccess {
+		return VLVPage{}, wrapLDAPError(ldap.NewError(resp.result, errors.New("virtual list view failed")), "LDAP VLV search failed")
+	}
+
+	page := VLVPage{
+		Users:    make([]UserRecord, 0, len(result.Entries)),
+		Offset:   max(0, resp.targetPosition-1),
+		PageSize: size,
+		Total:    resp.contentCount,
+	}
+	for _, entry := range result.Entries {
+		rec := s.userRecord(ctx, entry)
+		rec.meta = s.recordMeta(start)
+		s.redact(ctx, &rec)
+		page.Users = append(page.Users, rec)
+	}
+	return page, nil
+}
+
+// vlvUnsupported returns an ErrVLVUnsupported error for err
+func vlvUnsupported(err error) error {
+	return &libError{
+		msg:  "LDAP server does not support virtual list views: " + err.Error(),
+		kind: ErrVLVUnsupported,
+		err:  err,
+	}
+}
+
+// vlvRequestControl implements ldap.Control for a critical virtual list
+// view request of the entries from offset, a 1-based position, to
+// offset+afterCount.
+type vlvRequestControl struct {
+	offset     int
+	afterCount int
+}
+
+func (c *vlvRequestControl) GetControlType() string {
+	return VLVRequestControlOID
+}
+
+// Encode returns the control with its value,
+//
+//	SEQUENCE { beforeCount INTEGER, afterCount INTEGER,
+//	           byOffset [0] SEQUENCE { offset INTEGER, contentCount INTEGER } }
+//
+// with a zero contentCount, which has the server take offset as a position
+// in the list as it is now.
+func (c *vlvRequestControl) Encode() *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, VLVRequestControlOID, "Control Type (VLV Request)"))
+	packet.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "Criticality"))
+
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "VLV Request")
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(0), "Before Count"))
+	value.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(c.afterCount), "After Count"))
+	target := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "By Offset")
+	target.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(c.offset), "Offset"))
+	target.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(0), "Content Count"))
+	value.AppendChild(target)
+	wrapper := ber.Encode(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, nil, "Control Value (VLV Request)")
+	wrapper.AppendChild(value)
+	packet.AppendChild(wrapper)
+	return packet
+}
+
+func (c *vlvRequestControl) String() string {
+	return fmt.Sprintf("Control Type: VLV Request (%q)  Criticality: true  Offset: %d  After: %d", VLVRequestControlOID, c.offset, c.afterCount)
+}
+
+// vlvResponse is the value of a virtual list view response control
+type vlvResponse struct {
+	targetPosition int // 1-based position of the target entry
+	contentCount   int
+	result         uint16
+}
+
+// parseVLVResponse parses a virtual list view response control value,
+//
+//	SEQUENCE { targetPosition INTEGER, contentCount INTEGER,
+//	           virtualListViewResult ENUMERATED, contextID OCTET STRING OPTIONAL }
+func parseVLVResponse(value string) (vlvResponse, error) {
+	packet, err := ber.DecodePacketErr([]byte(value))
+	if err != nil {
+		return vlvResponse{}, err
+	}
+	if len(packet.Children) < 3 {
+		return vlvResponse{}, errors.New("missing fields")
+	}
+	var fields [3]int64
+	for i := range fields {
+		v, ok := packet.Children[i].Value.(int64)
+		if !ok {
+			return vlvResponse{}, fmt.Errorf("invalid field %d: %v", i, packet.Children[i].Value)
+		}
+		fields[i] = v
+	}
+	return vlvResponse{targetPosition: int(fields[0]), contentCount: int(fields[1]), result: uint16(fields[2])}, nil
+}
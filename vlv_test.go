@@ -0,0 +1,93 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// enableVLV turns the FeatureVLV gate on for searcher
+func enableVLV(searcher *ldap_redhat.Searcher) {
+	searcher.Config.FeatureGates = map[ldap_redhat.Feature]bool{ldap_redhat.FeatureVLV: true}
+}
+
+func TestSearchUsersPage(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 25)
+	enableVLV(searcher)
+	ctx := context.Background()
+
+	page, err := searcher.SearchUsersPage(ctx, "", ldap_redhat.VLVOptions{Offset: 10, PageSize: 10})
+	if err != nil {
+		t.Fatalf("SearchUsersPage failed: %v", err)
+	}
+	if len(page.Users) != 10 || page.Offset != 10 || page.Total != 25 {
+		t.Fatalf("Got %d users at offset %d of %d, want 10 at 10 of 25", len(page.Users), page.Offset, page.Total)
+	}
+	for i, u := range page.Users {
+		if u.UID != testserver.UserUID(10+i) {
+			t.Errorf("User %d of the page is %s, want %s", i, u.UID, testserver.UserUID(10+i))
+		}
+	}
+	if page.Page() != 2 || page.Pages() != 3 {
+		t.Errorf("Got page %d of %d, want 2 of 3", page.Page(), page.Pages())
+	}
+
+	last, err := searcher.SearchUsersPage(ctx, "", ldap_redhat.VLVOptions{Offset: 20, PageSize: 10})
+	if err != nil || len(last.Users) != 5 {
+		t.Errorf("The last page returned %d users and %v, want 5", len(last.Users), err)
+	}
+}
+
+func TestSearchUsersPageSortBy(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 12)
+	enableVLV(searcher)
+
+	page, err := searcher.SearchUsersPage(context.Background(), "", ldap_redhat.VLVOptions{PageSize: 3, SortBy: ldap_redhat.AttrCN, Reverse: true})
+	if err != nil {
+		t.Fatalf("SearchUsersPage failed: %v", err)
+	}
+	var uids []string
+	for _, u := range page.Users {
+		uids = append(uids, u.UID)
+	}
+	want := []string{testserver.UserUID(11), testserver.UserUID(10), testserver.UserUID(9)}
+	if len(uids) != 3 || uids[0] != want[0] || uids[1] != want[1] || uids[2] != want[2] {
+		t.Errorf("Got %v sorted by cn descending, want %v", uids, want)
+	}
+}
+
+func TestSearchUsersPageUnsupported(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	enableVLV(searcher)
+	srv.DisableControl(ldap_redhat.VLVRequestControlOID)
+
+	_, err := searcher.SearchUsersPage(context.Background(), "", ldap_redhat.VLVOptions{})
+	if !errors.Is(err, ldap_redhat.ErrVLVUnsupported) {
+		t.Errorf("SearchUsersPage against a server without VLV returned %v, want ErrVLVUnsupported", err)
+	}
+	if _, err := searcher.SearchUsersPage(context.Background(), "", ldap_redhat.VLVOptions{Offset: -1}); err == nil {
+		t.Error("SearchUsersPage accepted a negative offset")
+	}
+}
+
+func TestSearchUsersPageDisabled(t *testing.T) {
+	defer ldap_redhat.SetFeatureGates(nil)
+	searcher, _ := newEmbeddedSearcher(t, 5)
+
+	_, err := searcher.SearchUsersPage(context.Background(), "", ldap_redhat.VLVOptions{})
+	if !errors.Is(err, ldap_redhat.ErrFeatureDisabled) {
+		t.Errorf("SearchUsersPage with the VLV gate off returned %v, want ErrFeatureDisabled", err)
+	}
+
+	ldap_redhat.SetFeatureGates(map[ldap_redhat.Feature]bool{ldap_redhat.FeatureVLV: true})
+	if _, err := searcher.SearchUsersPage(context.Background(), "", ldap_redhat.VLVOptions{}); err != nil {
+		t.Errorf("SearchUsersPage with the process-wide VLV gate on failed: %v", err)
+	}
+	searcher.Config.FeatureGates = map[ldap_redhat.Feature]bool{ldap_redhat.FeatureVLV: false}
+	if _, err := searcher.SearchUsersPage(context.Background(), "", ldap_redhat.VLVOptions{}); !errors.Is(err, ldap_redhat.ErrFeatureDisabled) {
+		t.Errorf("SearchUsersPage with the VLV gate off in Config returned %v, want ErrFeatureDisabled", err)
+	}
+}
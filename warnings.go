@@ -0,0 +1,142 @@
+package ldap_redhat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// WarningKind is the kind of non-fatal condition a Warning reports.
+type WarningKind string
+
+const (
+	// WarnTruncated is a search cut short by a server size or administrative
+	// limit, whose partial results were returned.
+	WarnTruncated WarningKind = "truncated"
+	// WarnStale is records served from the offline snapshot because the
+	// directory was unreachable.
+	WarnStale WarningKind = "stale"
+	// WarnReplicaDivergence is an entry read in a consistent flow with an
+	// older modifyTimestamp than an earlier read, from a replica lagging
+	// behind the server first read.
+	WarnReplicaDivergence WarningKind = "replica_divergence"
+	// WarnAttributesDenied is an entry returned without attributes every
+	// person entry has, which access controls must have withheld.
+	WarnAttributesDenied WarningKind = "attributes_denied"
+	// WarnUnknownAttribute is an attribute the server's schema does not
+	// define, which searches silently return no values for.
+	WarnUnknownAttribute WarningKind = "unknown_attribute"
+)
+
+// requiredPersonAttributes are the attributes the person object class
+// requires, whose absence from a user entry means they were not readable
+var requiredPersonAttributes = []string{AttrCN, AttrSN}
+
+// Warning is a non-fatal condition met while serving a request.
+type Warning struct {
+	Kind       WarningKind `json:"kind" yaml:"kind"`
+	Message    string      `json:"message" yaml:"message"`
+	DN         string      `json:"dn,omitempty" yaml:"dn,omitempty"`                 // the entry, or search base, concerned
+	Attributes []string    `json:"attributes,omitempty" yaml:"attributes,omitempty"` // the attributes withheld, for WarnAttributesDenied, or undefined, for WarnUnknownAttribute
+}
+
+func (w Warning) String() string {
+	return string(w.Kind) + ": " + w.Message
+}
+
+// Warnings collects the warnings raised by the requests made with the
+// context returned by WithWarnings. It is safe for concurrent use.
+type Warnings struct {
+	mu   sync.Mutex
+	list []Warning
+}
+
+type warningsKey struct{}
+
+// WithWarnings returns a context whose requests record their warnings in the
+// returned collector. Without one, warnings are not reported, and searches
+// cut short by a server limit fail rather than return partial results.
+//
+//	ctx, warnings := ldap_redhat.WithWarnings(ctx)
+//	users, err := searcher.SearchUsers(ctx, filter)
+//	for _, w := range warnings.List() {
+//		log.Printf("LDAP warning: %s", w)
+//	}
+func WithWarnings(ctx context.Context) (context.Context, *Warnings) {
+	w := &Warnings{}
+	return context.WithValue(ctx, warningsKey{}, w), w
+}
+
+// List returns the warnings recorded so far, in the order they were raised.
+func (w *Warnings) List() []Warning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.list)
+}
+
+// Has reports whether a warning of kind was recorded.
+func (w *Warnings) Has(kind WarningKind) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.ContainsFunc(w.list, func(x Warning) bool { return x.Kind == kind })
+}
+
+// warningsFrom returns the collector of ctx, or nil
+func warningsFrom(ctx context.Context) *Warnings {
+	w, _ := ctx.Value(warningsKey{}).(*Warnings)
+	return w
+}
+
+// warn records w in the collector of ctx, if any
+func warn(ctx context.Context, w Warning) {
+	if c := warningsFrom(ctx); c != nil {
+		c.mu.Lock()
+		c.list = append(c.list, w)
+		c.mu.Unlock()
+	}
+}
+
+// truncated reports whether err ended req early at a server limit and ctx
+// collects warnings, in which case it records a WarnTruncated warning and
+// the entries returned stand as the result. Requests with their own size
+// limit, such as existence probes and searches with a SizeLimit, expect a
+// *PartialResultsError instead.
+func truncated(ctx context.Context, req *ldap.SearchRequest, entries int, err error) bool {
+	if req.SizeLimit > 0 || warningsFrom(ctx) == nil || !limitExceeded(err) {
+		return false
+	}
+	warn(ctx, Warning{
+		Kind:    WarnTruncated,
+		DN:      req.BaseDN,
+		Message: fmt.Sprintf("search %s returned %d entries before a server limit: %v", req.Filter, entries, err),
+	})
+	return true
+}
+
+// warnDeniedAttributes records a WarnAttributesDenied warning if entry lacks
+// attributes every person entry has that were requested for ctx
+func (s *Searcher) warnDeniedAttributes(ctx context.Context, entry *ldap.Entry) {
+	if warningsFrom(ctx) == nil {
+		return
+	}
+	requested := s.attributes(ctx)
+	var denied []string
+	for _, attr := range requiredPersonAttributes {
+		if slices.ContainsFunc(requested, func(a string) bool { return strings.EqualFold(a, attr) }) &&
+			len(entry.GetEqualFoldAttributeValues(attr)) == 0 {
+			denied = append(denied, attr)
+		}
+	}
+	if len(denied) > 0 {
+		warn(ctx, Warning{
+			Kind:       WarnAttributesDenied,
+			DN:         entry.DN,
+			Attributes: denied,
+			Message:    fmt.Sprintf("%s was returned without %s", entry.DN, strings.Join(denied, ", ")),
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/ldapserver"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestWarningsTruncated(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 10)
+	srv.InjectFault(ldapserver.OpSearch, ldapserver.Fault{Code: ldap.LDAPResultSizeLimitExceeded, Entries: 3})
+	ctx, warnings := ldap_redhat.WithWarnings(context.Background())
+
+	users, err := searcher.SearchUsers(ctx, "")
+	if err != nil {
+		t.Fatalf("SearchUsers failed: %v", err)
+	}
+	if len(users) != 3 {
+		t.Errorf("Got %d users, want the 3 sent before the limit", len(users))
+	}
+	list := warnings.List()
+	if len(list) != 1 || list[0].Kind != ldap_redhat.WarnTruncated || list[0].DN != testserver.UsersBaseDN {
+		t.Errorf("Got warnings %v, want one truncation of %s", list, testserver.UsersBaseDN)
+	}
+
+	reports, err := searcher.FindDirectReports(ctx, testserver.UserUID(0))
+	if err != nil || len(reports) != 3 {
+		t.Errorf("FindDirectReports returned %d reports and %v, want the 3 sent before the limit", len(reports), err)
+	}
+}
+
+func TestWarningsAttributesDenied(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	srv.SetAttribute(testserver.UserDN(1), "sn")
+	ctx, warnings := ldap_redhat.WithWarnings(context.Background())
+
+	if _, err := searcher.GetUser(ctx, byUID(testserver.UserUID(1))); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	list := warnings.List()
+	if len(list) != 1 || list[0].Kind != ldap_redhat.WarnAttributesDenied || list[0].DN != testserver.UserDN(1) || !slices.Equal(list[0].Attributes, []string{"sn"}) {
+		t.Errorf("Got warnings %v, want sn denied on %s", list, testserver.UserDN(1))
+	}
+
+	// Attributes left out by the request scope are not expected
+	scoped, warnings := ldap_redhat.WithWarnings(ldap_redhat.WithRequestScope(context.Background(), ldap_redhat.RequestScope{Attributes: []string{"uid", "cn"}}))
+	if _, err := searcher.GetUser(scoped, byUID(testserver.UserUID(1))); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if list := warnings.List(); len(list) != 0 {
+		t.Errorf("Got warnings %v for an attribute outside the scope", list)
+	}
+}
+
+func TestWarningsReplicaDivergence(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx, warnings := ldap_redhat.WithWarnings(context.Background())
+	ctx, token, err := searcher.Pin(ctx)
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	if _, err := searcher.GetUser(ctx, byUID(testserver.UserUID(2))); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	srv.SetAttribute(testserver.UserDN(2), "modifyTimestamp", "20000101000000Z")
+	if _, err := searcher.GetUser(ctx, byUID(testserver.UserUID(2))); err != nil {
+		t.Fatalf("GetUser failed: %v", err)
+	}
+	if !warnings.Has(ldap_redhat.WarnReplicaDivergence) {
+		t.Errorf("Expected a replica divergence warning, got %v", warnings.List())
+	}
+	if len(token.Changed()) != 1 {
+		t.Errorf("Expected the entry to be reported changed, got %v", token.Changed())
+	}
+}
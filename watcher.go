@@ -0,0 +1,248 @@
+package ldap_redhat
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is how often a Watcher polls when Watcher.Interval is
+// zero
+const DefaultWatchInterval = 5 * time.Minute
+
+// watchBatchSize bounds the UIDs looked up by one GetUsers call
+const watchBatchSize = 100
+
+// watchEventBuffer is the capacity of Watcher.Events
+const watchEventBuffer = 100
+
+// WatchEventKind is the kind of change a Watcher noticed.
+type WatchEventKind string
+
+const (
+	// WatchTerminated is a termination date set, or moved, on the user.
+	WatchTerminated WatchEventKind = "terminated"
+	// WatchReinstated is a termination date removed from the user.
+	WatchReinstated WatchEventKind = "reinstated"
+	// WatchManagerChanged is a change of ManagerUID.
+	WatchManagerChanged WatchEventKind = "manager_changed"
+	// WatchCostCenterChanged is a change of CostCenter.
+	WatchCostCenterChanged WatchEventKind = "cost_center_changed"
+	// WatchDeleted is the user's entry moving to Config.DeletedUsersBaseDN.
+	WatchDeleted WatchEventKind = "deleted"
+	// WatchRemoved is the user no longer being found at all.
+	WatchRemoved WatchEventKind = "removed"
+)
+
+// WatchEvent is a change to a watched user between two polls. After is
+// empty for WatchRemoved.
+type WatchEvent struct {
+	Kind   WatchEventKind `json:"kind" yaml:"kind"`
+	UID    string         `json:"uid" yaml:"uid"`
+	Before UserRecord     `json:"before" yaml:"before"`
+	After  UserRecord     `json:"after" yaml:"after"`
+	At     time.Time      `json:"at" yaml:"at"` // when the poll noticed the change
+}
+
+// Watcher polls a set of users and emits a WatchEvent on Events whenever
+// one is terminated, reinstated, changes manager or cost center, is moved to
+// the deleted users OU or disappears. The first poll of a user records its
+// state without emitting events. A user may change in several ways between
+// two polls, giving one event per kind.
+//
+// Users are looked up with SearchOptions.IncludeDeleted, in batches, so pass
+// a Searcher rather than a CachedSearcher, whose cached records would hide
+// changes. Lookups that fail, and stale records served from an offline
+// snapshot, leave the user's state unchanged until a later poll.
+//
+//	w := ldap_redhat.NewWatcher(searcher, "jdoe", "asmith")
+//	go w.Run(ctx)
+//	for ev := range w.Events() {
+//		log.Printf("%s: %s", ev.UID, ev.Kind)
+//	}
+type Watcher struct {
+	// Interval is how often the users are polled; DefaultWatchInterval when
+	// zero.
+	Interval time.Duration
+	// OnError, if set, receives poll failures. They are logged with the
+	// default slog logger otherwise.
+	OnError func(error)
+
+	searcher UserSearcher
+	events   chan WatchEvent
+
+	mu    sync.Mutex
+	uids  []string
+	state map[string]UserRecord // last record seen per UID
+}
+
+// NewWatcher returns a Watcher of uids, looked up with searcher.
+func NewWatcher(searcher UserSearcher, uids ...string) *Watcher {
+	w := &Watcher{
+		searcher: searcher,
+		events:   make(chan WatchEvent, watchEventBuffer),
+		state:    map[string]UserRecord{},
+	}
+	w.Add(uids...)
+	return w
+}
+
+// Events returns the channel events are sent on. It is closed when Run
+// returns. Polls block while it is full.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Add starts watching uids. Their state is recorded by the next poll.
+func (w *Watcher) Add(uids ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, uid := range uids {
+		if uid != "" && !slices.Contains(w.uids, uid) {
+			w.uids = append(w.uids, uid)
+		}
+	}
+}
+
+// Remove stops watching uids and forgets their state.
+func (w *Watcher) Remove(uids ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.uids = slices.DeleteFunc(w.uids, func(uid string) bool { return slices.Contains(uids, uid) })
+	for _, uid := range uids {
+		delete(w.state, uid)
+	}
+}
+
+// Run polls now and then every Interval until ctx is done, and returns
+// ctx.Err(). Poll failures are passed to OnError. Run closes Events when it
+// returns, so it may only be called once.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.report(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll looks the watched users up once and sends the events for their
+// changes. Run calls it every Interval. Users whose lookup failed are
+// reported in a *BatchError, after the events of the others are sent.
+func (w *Watcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	uids := slices.Clone(w.uids)
+	w.mu.Unlock()
+
+	ctx = WithSearchOptions(ctx, SearchOptions{IncludeDeleted: true})
+	var failed []ItemError
+	for start := 0; start < len(uids); start += watchBatchSize {
+		batch := uids[start:min(start+watchBatchSize, len(uids))]
+		ids := make([]Identifier, len(batch))
+		for i, uid := range batch {
+			ids[i] = Identifier{Type: IDTUID, Value: uid}
+		}
+		users, err := w.searcher.GetUsers(ctx, ids)
+		skip := map[int]bool{}
+		var batchErr *BatchError
+		switch {
+		case errors.As(err, &batchErr):
+			for _, item := range batchErr.Errors {
+				skip[item.Index] = true
+				item.Index += start
+				failed = append(failed, item)
+			}
+		case err != nil:
+			for i, uid := range batch {
+				failed = append(failed, ItemError{Index: start + i, Item: uid, Err: err})
+			}
+			continue
+		}
+		now := time.Now()
+		for i, uid := range batch {
+			if skip[i] || users[i].Stale {
+				continue
+			}
+			if err := w.observe(ctx, uid, users[i], now); err != nil {
+				return err
+			}
+		}
+	}
+	return newBatchError(len(uids), failed)
+}
+
+// observe records rec, found or empty, as the state of uid and sends the
+// events for its changes
+func (w *Watcher) observe(ctx context.Context, uid string, rec UserRecord, at time.Time) error {
+	w.mu.Lock()
+	before, known := w.state[uid]
+	watched := slices.Contains(w.uids, uid)
+	switch {
+	case !watched:
+	case rec.UID == "":
+		delete(w.state, uid)
+	default:
+		w.state[uid] = rec
+	}
+	w.mu.Unlock()
+	if !known || !watched {
+		return nil
+	}
+
+	for _, kind := range watchChanges(before, rec) {
+		select {
+		case w.events <- WatchEvent{Kind: kind, UID: uid, Before: before, After: rec, At: at}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// watchChanges returns the kinds of change between two records of a user;
+// after is empty if the user is no longer found
+func watchChanges(before, after UserRecord) []WatchEventKind {
+	if after.UID == "" {
+		return []WatchEventKind{WatchRemoved}
+	}
+	var kinds []WatchEventKind
+	switch {
+	case after.RhatTermDate != "" && after.RhatTermDate != before.RhatTermDate:
+		kinds = append(kinds, WatchTerminated)
+	case after.RhatTermDate == "" && before.RhatTermDate != "":
+		kinds = append(kinds, WatchReinstated)
+	}
+	if after.ManagerUID != before.ManagerUID {
+		kinds = append(kinds, WatchManagerChanged)
+	}
+	if after.CostCenter != before.CostCenter {
+		kinds = append(kinds, WatchCostCenterChanged)
+	}
+	if after.Status == StatusDeleted && before.Status != StatusDeleted {
+		kinds = append(kinds, WatchDeleted)
+	}
+	return kinds
+}
+
+// report passes err to OnError, or logs it
+func (w *Watcher) report(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+		return
+	}
+	slog.Default().Warn("watch poll failed", "error", err)
+}
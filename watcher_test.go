@@ -0,0 +1,122 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+// drainEvents returns the events already sent on w
+func drainEvents(w *ldap_redhat.Watcher) map[string][]ldap_redhat.WatchEventKind {
+	got := map[string][]ldap_redhat.WatchEventKind{}
+	for {
+		select {
+		case ev := <-w.Events():
+			got[ev.UID] = append(got[ev.UID], ev.Kind)
+		default:
+			return got
+		}
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 20)
+	ctx := context.Background()
+	uid := testserver.UserUID
+	w := ldap_redhat.NewWatcher(searcher, uid(1), uid(2), uid(3), uid(4), uid(5), "nobody")
+
+	if err := w.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if got := drainEvents(w); len(got) != 0 {
+		t.Fatalf("Expected no events on the first poll, got %v", got)
+	}
+
+	srv.SetAttribute(testserver.UserDN(1), "rhatTermDate", "20250131000000Z")
+	srv.SetAttribute(testserver.UserDN(2), "manager", testserver.UserDN(7))
+	srv.SetAttribute(testserver.UserDN(3), "rhatCostCenter", "999")
+	srv.SetAttribute(testserver.UserDN(4), "uid", "renamed")
+	srv.SetAttribute(testserver.UserDN(5), "title", "Distinguished Engineer")
+	if err := w.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	got := drainEvents(w)
+	want := map[string][]ldap_redhat.WatchEventKind{
+		uid(1): {ldap_redhat.WatchTerminated},
+		uid(2): {ldap_redhat.WatchManagerChanged},
+		uid(3): {ldap_redhat.WatchCostCenterChanged},
+		uid(4): {ldap_redhat.WatchRemoved},
+	}
+	if len(got) != len(want) {
+		t.Errorf("Got events %v, want %v", got, want)
+	}
+	for u, kinds := range want {
+		if len(got[u]) != 1 || got[u][0] != kinds[0] {
+			t.Errorf("Events of %s = %v, want %v", u, got[u], kinds)
+		}
+	}
+
+	srv.SetAttribute(testserver.UserDN(1), "rhatTermDate")
+	if err := w.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if got := drainEvents(w); len(got) != 1 || len(got[uid(1)]) != 1 || got[uid(1)][0] != ldap_redhat.WatchReinstated {
+		t.Errorf("Expected only %s reinstated, got %v", uid(1), got)
+	}
+
+	// Removed users are forgotten
+	w.Remove(uid(2))
+	srv.SetAttribute(testserver.UserDN(2), "manager", testserver.UserDN(0))
+	if err := w.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if got := drainEvents(w); len(got) != 0 {
+		t.Errorf("Expected no events for unwatched users, got %v", got)
+	}
+}
+
+func TestWatcherPollErrors(t *testing.T) {
+	searcher, _ := newEmbeddedSearcher(t, 5)
+	w := ldap_redhat.NewWatcher(searcher, testserver.UserUID(1), "not a uid")
+
+	err := w.Poll(context.Background())
+	var batchErr *ldap_redhat.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Item != "not a uid" {
+		t.Errorf("Expected the malformed uid to fail alone, got %v", err)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := ldap_redhat.NewWatcher(searcher, testserver.UserUID(1))
+	w.Interval = 10 * time.Millisecond
+	if err := w.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	srv.SetAttribute(testserver.UserDN(1), "rhatCostCenter", "999")
+	select {
+	case ev := <-w.Events():
+		if ev.Kind != ldap_redhat.WatchCostCenterChanged || ev.Before.CostCenter == "999" || ev.After.CostCenter != "999" {
+			t.Errorf("Unexpected event %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event after the cost center changed")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+	if _, open := <-w.Events(); open {
+		t.Error("Expected Events to be closed when Run returns")
+	}
+}
@@ -0,0 +1,116 @@
+package ldap_redhat
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"time"
+)
+
+// BindIdentity describes who the searcher's connection is bound as and how
+// it is secured, for diagnostics endpoints and "why can't I read this
+// attribute" investigations.
+type BindIdentity struct {
+	// AuthzID is the authorization identity the server reports for the
+	// connection, e.g. "dn:uid=svc,ou=serviceaccounts,dc=redhat,dc=com" or
+	// "u:svc". It is empty for anonymous connections.
+	AuthzID  string   `json:"authz_id"`
+	BindDN   string   `json:"bind_dn,omitempty"` // configured bind DN, empty for anonymous and SASL EXTERNAL binds
+	AuthMode AuthMode `json:"auth_mode"`
+	Server   string   `json:"server"`
+	Conn     string   `json:"conn"` // connection name, as in log records
+
+	TLS        bool   `json:"tls"`
+	TLSVersion string `json:"tls_version,omitempty"`
+	// ServerCertSubject and ServerCertNotAfter describe the server's leaf
+	// certificate, and ServerCertExpiresIn the validity it has left
+	ServerCertSubject   string        `json:"server_cert_subject,omitempty"`
+	ServerCertNotAfter  time.Time     `json:"server_cert_not_after,omitzero"`
+	ServerCertExpiresIn time.Duration `json:"server_cert_expires_in_ns,omitempty"`
+	// ClientCertNotAfter and ClientCertExpiresIn describe the configured
+	// client certificate, read from ClientCertFile, if there is one
+	ClientCertNotAfter  time.Time     `json:"client_cert_not_after,omitzero"`
+	ClientCertExpiresIn time.Duration `json:"client_cert_expires_in_ns,omitempty"`
+}
+
+// Anonymous reports whether the server considers the connection anonymous
+func (b BindIdentity) Anonymous() bool {
+	return b.AuthzID == ""
+}
+
+// DN returns the DN of a "dn:" authorization identity, or "" for other
+// forms such as "u:svc"
+func (b BindIdentity) DN() string {
+	dn, _ := strings.CutPrefix(b.AuthzID, "dn:")
+	if dn == b.AuthzID {
+		return ""
+	}
+	return dn
+}
+
+// WhoAmI asks the server which identity the searcher's connection operates
+// as, with the "Who am I?" extended operation (RFC 4532), and reports the
+// configured bind, the server and the remaining validity of the TLS
+// certificates in use. A LazyConnect searcher connects first. It fails
+// with ErrNotConnected without a connection, e.g. when serving from the
+// offline snapshot.
+func (s *Searcher) WhoAmI(ctx context.Context) (BindIdentity, error) {
+	conn, name, err := s.activeConn(ctx)
+	if err != nil {
+		return BindIdentity{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return BindIdentity{}, err
+	}
+	result, err := conn.WhoAmI(nil)
+	if err != nil {
+		return BindIdentity{}, wrapLDAPError(err, "LDAP WhoAmI failed")
+	}
+
+	config := s.config()
+	authMode, _ := config.AuthMode.normalize()
+	id := BindIdentity{
+		AuthzID:  result.AuthzID,
+		AuthMode: authMode,
+		Server:   config.LdapServers[0],
+		Conn:     name,
+	}
+	if authMode != AuthExternal && config.HasCredentials() {
+		id.BindDN = config.Username
+	}
+	now := time.Now()
+	if state, ok := conn.TLSConnectionState(); ok {
+		id.TLS = true
+		id.TLSVersion = tls.VersionName(state.Version)
+		if len(state.PeerCertificates) > 0 {
+			leaf := state.PeerCertificates[0]
+			id.ServerCertSubject = leaf.Subject.String()
+			id.ServerCertNotAfter = leaf.NotAfter
+			id.ServerCertExpiresIn = leaf.NotAfter.Sub(now)
+		}
+		if notAfter, ok := clientCertNotAfter(config); ok {
+			id.ClientCertNotAfter = notAfter
+			id.ClientCertExpiresIn = notAfter.Sub(now)
+		}
+	}
+	return id, nil
+}
+
+// clientCertNotAfter returns when the configured client certificate
+// expires. It is read from the file, as every handshake does, so a rotated
+// certificate is reported as soon as it is in place.
+func clientCertNotAfter(config Config) (time.Time, bool) {
+	if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+		return time.Time{}, false
+	}
+	pair, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+	if err != nil || len(pair.Certificate) == 0 {
+		return time.Time{}, false
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return leaf.NotAfter, true
+}
@@ -0,0 +1,82 @@
+package ldap_redhat_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ldap_redhat "github.com/openshift-eng/go-ldap-redhat"
+	"github.com/openshift-eng/go-ldap-redhat/internal/testserver"
+)
+
+func TestWhoAmI(t *testing.T) {
+	searcher, srv := newEmbeddedSearcher(t, 1)
+	id, err := searcher.WhoAmI(context.Background())
+	if err != nil {
+		t.Fatalf("WhoAmI failed: %v", err)
+	}
+	if id.AuthzID != "dn:"+embeddedBindDN || id.DN() != embeddedBindDN || id.Anonymous() {
+		t.Errorf("WhoAmI reported %q, want the bind DN", id.AuthzID)
+	}
+	if id.BindDN != embeddedBindDN || id.AuthMode != ldap_redhat.AuthSimple || id.Server != srv.URL() || id.Conn == "" {
+		t.Errorf("WhoAmI returned %+v, want the searcher's bind, server and connection", id)
+	}
+	if id.TLS || !id.ServerCertNotAfter.IsZero() {
+		t.Errorf("WhoAmI reported TLS on an ldap:// connection: %+v", id)
+	}
+}
+
+func TestWhoAmIAnonymous(t *testing.T) {
+	srv := startEmbeddedServer(t, 1)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{LdapServers: []string{srv.URL()}, BaseDN: testserver.UsersBaseDN})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	id, err := searcher.WhoAmI(context.Background())
+	if err != nil {
+		t.Fatalf("WhoAmI failed: %v", err)
+	}
+	if !id.Anonymous() || id.BindDN != "" || id.DN() != "" {
+		t.Errorf("WhoAmI returned %+v for an anonymous connection", id)
+	}
+}
+
+func TestWhoAmIReportsCertificateValidity(t *testing.T) {
+	srv, caFile := startLDAPSServer(t, nil)
+	searcher, err := ldap_redhat.NewSearcher(ldap_redhat.Config{
+		LdapServers: []string{srv.URL()},
+		Username:    embeddedBindDN,
+		Password:    embeddedPassword,
+		CAFile:      caFile,
+	})
+	if err != nil {
+		t.Fatalf("NewSearcher failed: %v", err)
+	}
+	defer searcher.Close()
+	id, err := searcher.WhoAmI(context.Background())
+	if err != nil {
+		t.Fatalf("WhoAmI failed: %v", err)
+	}
+	if !id.TLS || !strings.HasPrefix(id.TLSVersion, "TLS 1.") {
+		t.Errorf("WhoAmI reported TLS %v %q, want a TLS connection", id.TLS, id.TLSVersion)
+	}
+	if !strings.Contains(id.ServerCertSubject, "ldap-test") || id.ServerCertExpiresIn <= 0 {
+		t.Errorf("Server certificate %q expires in %v, want the test certificate's remaining validity", id.ServerCertSubject, id.ServerCertExpiresIn)
+	}
+	if d := time.Until(id.ServerCertNotAfter) - id.ServerCertExpiresIn; d > time.Minute || d < -time.Minute {
+		t.Errorf("ServerCertExpiresIn %v does not match NotAfter %v", id.ServerCertExpiresIn, id.ServerCertNotAfter)
+	}
+	if !id.ClientCertNotAfter.IsZero() {
+		t.Errorf("WhoAmI reported a client certificate that is not configured: %v", id.ClientCertNotAfter)
+	}
+}
+
+func TestWhoAmIDisconnected(t *testing.T) {
+	searcher := &ldap_redhat.Searcher{}
+	if _, err := searcher.WhoAmI(context.Background()); !errors.Is(err, ldap_redhat.ErrNotConnected) {
+		t.Errorf("WhoAmI without a connection returned %v, want ErrNotConnected", err)
+	}
+}